@@ -0,0 +1,223 @@
+package asn1
+
+import (
+	"errors"
+	"fmt"
+)
+
+// maxBERDepth is the maximum nesting depth of the constructed elements NormalizeBER converts.
+const maxBERDepth = 64
+
+// berStringTags are the universal types whose values BER permits to be split into the segments of a constructed
+// encoding, which DER requires to be encoded as a single primitive.
+var berStringTags = map[int]bool{
+	TagBitString:       true,
+	TagOctetString:     true,
+	TagUTF8String:      true,
+	18:                 true, // NumericString
+	TagPrintableString: true,
+	TagT61String:       true,
+	TagIA5String:       true,
+	TagUTCTime:         true,
+	TagGeneralizedTime: true,
+	TagGeneralString:   true,
+}
+
+// NormalizeBER returns the DER encoding of the BER encoded elements provided, so that data from peers using the
+// flexibility of BER can be decoded by Unmarshal, which accepts only DER. Indefinite and non-minimal lengths,
+// constructed encodings of string types and non-canonical boolean values are converted. The order of the elements of
+// a SET is not changed, as none of the Kerberos types are SETs.
+func NormalizeBER(b []byte) ([]byte, error) {
+	var out []byte
+	for len(b) > 0 {
+		e, rest, err := normalizeBERElement(b, 0)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e...)
+		b = rest
+	}
+	return out, nil
+}
+
+// normalizeBERElement returns the DER encoding of the element at the start of b and the bytes following it.
+func normalizeBERElement(b []byte, depth int) ([]byte, []byte, error) {
+	if depth > maxBERDepth {
+		return nil, nil, fmt.Errorf("asn1: BER nesting depth exceeds maximum of %d", maxBERDepth)
+	}
+	id, class, tag, constructed, r, err := parseBERIdentifier(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	l, indefinite, r, err := parseBERLength(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !constructed {
+		if indefinite {
+			return nil, nil, errors.New("asn1: indefinite length of primitive element")
+		}
+		if l > len(r) {
+			return nil, nil, errors.New("asn1: BER element is truncated")
+		}
+		content := r[:l]
+		if class == ClassUniversal && tag == TagBoolean && l == 1 && content[0] != 0 {
+			content = []byte{0xff}
+		}
+		return appendBERElement(id, content), r[l:], nil
+	}
+	var children [][]byte
+	var rest []byte
+	if indefinite {
+		for {
+			if len(r) >= 2 && r[0] == 0 && r[1] == 0 {
+				rest = r[2:]
+				break
+			}
+			if len(r) == 0 {
+				return nil, nil, errors.New("asn1: BER element with indefinite length is not terminated")
+			}
+			var c []byte
+			c, r, err = normalizeBERElement(r, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			children = append(children, c)
+		}
+	} else {
+		if l > len(r) {
+			return nil, nil, errors.New("asn1: BER element is truncated")
+		}
+		content := r[:l]
+		rest = r[l:]
+		for len(content) > 0 {
+			var c []byte
+			c, content, err = normalizeBERElement(content, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			children = append(children, c)
+		}
+	}
+	if class == ClassUniversal && berStringTags[tag] {
+		content, err := joinBERSegments(tag, children)
+		if err != nil {
+			return nil, nil, err
+		}
+		// The primitive encoding has the same identifier without the constructed bit.
+		pid := append([]byte{id[0] &^ 0x20}, id[1:]...)
+		return appendBERElement(pid, content), rest, nil
+	}
+	var content []byte
+	for _, c := range children {
+		content = append(content, c...)
+	}
+	return appendBERElement(id, content), rest, nil
+}
+
+// joinBERSegments returns the content of the primitive encoding of a string type from the DER encoded segments of its
+// constructed encoding.
+func joinBERSegments(tag int, segments [][]byte) ([]byte, error) {
+	var content []byte
+	for i, s := range segments {
+		_, _, stag, _, r, err := parseBERIdentifier(s)
+		if err != nil {
+			return nil, err
+		}
+		if stag != tag && !(tag != TagBitString && stag == TagOctetString) {
+			return nil, fmt.Errorf("asn1: segment of type %d within constructed string of type %d", stag, tag)
+		}
+		l, _, r, err := parseBERLength(r)
+		if err != nil {
+			return nil, err
+		}
+		seg := r[:l]
+		if tag == TagBitString {
+			if len(seg) == 0 || (i < len(segments)-1 && seg[0] != 0) {
+				return nil, errors.New("asn1: invalid segment of constructed BIT STRING")
+			}
+			if i == 0 {
+				content = append(content, 0)
+			}
+			content[0] = seg[0]
+			seg = seg[1:]
+		}
+		content = append(content, seg...)
+	}
+	if tag == TagBitString && len(content) == 0 {
+		content = []byte{0}
+	}
+	return content, nil
+}
+
+// parseBERIdentifier parses the identifier octets at the start of b.
+func parseBERIdentifier(b []byte) (id []byte, class, tag int, constructed bool, rest []byte, err error) {
+	if len(b) < 1 {
+		err = errors.New("asn1: BER element is truncated")
+		return
+	}
+	class = int(b[0] >> 6)
+	constructed = b[0]&0x20 != 0
+	tag = int(b[0] & 0x1f)
+	n := 1
+	if tag == 0x1f {
+		tag = 0
+		for {
+			if n >= len(b) || n > 4 {
+				err = errors.New("asn1: invalid BER tag")
+				return
+			}
+			tag = tag<<7 | int(b[n]&0x7f)
+			n++
+			if b[n-1]&0x80 == 0 {
+				break
+			}
+		}
+	}
+	return b[:n], class, tag, constructed, b[n:], nil
+}
+
+// parseBERLength parses the length octets at the start of b.
+func parseBERLength(b []byte) (l int, indefinite bool, rest []byte, err error) {
+	if len(b) < 1 {
+		err = errors.New("asn1: BER element is truncated")
+		return
+	}
+	if b[0] == 0x80 {
+		return 0, true, b[1:], nil
+	}
+	if b[0]&0x80 == 0 {
+		return int(b[0]), false, b[1:], nil
+	}
+	n := int(b[0] & 0x7f)
+	if n > 4 || n+1 > len(b) {
+		err = errors.New("asn1: invalid BER length")
+		return
+	}
+	for _, c := range b[1 : n+1] {
+		l = l<<8 | int(c)
+	}
+	if l < 0 {
+		err = errors.New("asn1: invalid BER length")
+		return
+	}
+	return l, false, b[n+1:], nil
+}
+
+// appendBERElement returns the DER encoding of the element with the identifier and content provided.
+func appendBERElement(id, content []byte) []byte {
+	out := append([]byte{}, id...)
+	l := len(content)
+	switch {
+	case l < 0x80:
+		out = append(out, byte(l))
+	default:
+		var lb []byte
+		for ; l > 0; l >>= 8 {
+			lb = append([]byte{byte(l)}, lb...)
+		}
+		out = append(out, 0x80|byte(len(lb)))
+		out = append(out, lb...)
+	}
+	return append(out, content...)
+}
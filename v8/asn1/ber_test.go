@@ -0,0 +1,84 @@
+package asn1
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestNormalizeBER(t *testing.T) {
+	t.Parallel()
+	var tests = []struct {
+		name string
+		ber  string
+		der  string
+	}{
+		{"indefinite length", "30800201050000", "3003020105"},
+		{"nested indefinite lengths", "a180308002010500000000", "a1053003020105"},
+		{"non-minimal length", "0481036162630201ff", "04036162630201ff"},
+		{"long form of short length", "3082000302010a", "300302010a"},
+		{"constructed octet string", "2480040161040262630000", "0403616263"},
+		{"constructed general string", "3b0a04026162040163040164", "1b0461626364"},
+		{"constructed bit string", "2308030200aa030204b0", "030304aab0"},
+		{"non-canonical boolean", "010101", "0101ff"},
+		{"high tag number", "bf8101800201010000", "bf810103020101"},
+		{"elements following", "300302010530800201060000", "30030201053003020106"},
+	}
+	for _, test := range tests {
+		b, _ := hex.DecodeString(test.ber)
+		der, err := NormalizeBER(b)
+		if err != nil {
+			t.Errorf("%s: error normalizing: %v", test.name, err)
+			continue
+		}
+		if hex.EncodeToString(der) != test.der {
+			t.Errorf("%s: got %x, want %s", test.name, der, test.der)
+		}
+		// DER is unchanged by normalization
+		b, _ = hex.DecodeString(test.der)
+		der, err = NormalizeBER(b)
+		if err != nil || hex.EncodeToString(der) != test.der {
+			t.Errorf("%s: DER changed by normalization: %x, %v", test.name, der, err)
+		}
+	}
+}
+
+func TestNormalizeBER_Marshalled(t *testing.T) {
+	t.Parallel()
+	type s struct {
+		A int       `asn1:"explicit,tag:0"`
+		B string    `asn1:"generalstring,explicit,tag:1"`
+		C []string  `asn1:"generalstring,explicit,tag:2"`
+		D BitString `asn1:"explicit,tag:3"`
+	}
+	b, err := Marshal(s{A: 1000, B: "gokrb5", C: []string{"HTTP", "host.test.gokrb5"}, D: BitString{Bytes: []byte{0x40, 0, 0, 0}, BitLength: 32}})
+	if err != nil {
+		t.Fatalf("error marshalling: %v", err)
+	}
+	der, err := NormalizeBER(b)
+	if err != nil {
+		t.Fatalf("error normalizing: %v", err)
+	}
+	if hex.EncodeToString(der) != hex.EncodeToString(b) {
+		t.Errorf("DER changed by normalization: got %x, want %x", der, b)
+	}
+}
+
+func TestNormalizeBER_Invalid(t *testing.T) {
+	t.Parallel()
+	for _, h := range []string{
+		"",
+		"3005020105",       // truncated
+		"308002010500",     // unterminated indefinite length
+		"0280",             // primitive with indefinite length
+		"3085010000000102", // length too long
+		"2404030100",       // bit string segment in octet string
+	} {
+		b, _ := hex.DecodeString(h)
+		if h == "" {
+			b = []byte{0x30}
+		}
+		if _, err := NormalizeBER(b); err == nil {
+			t.Errorf("%s: invalid BER should not be normalized", h)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package asn1tools
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jcmturner/gokrb5/v8/asn1"
+)
+
+// Universal tags not defined by the asn1 package.
+const (
+	tagNull          = 5
+	tagNumericString = 18
+)
+
+// ErrNotDER is returned when ASN.1 encoded data does not conform to the distinguished encoding rules.
+var ErrNotDER = errors.New("ASN.1 encoding is not DER")
+
+// CheckDER checks that b contains exactly one ASN.1 element, without trailing bytes, and that the identifier and length
+// octets of it and all the elements it contains conform to the distinguished encoding rules (X.690 section 10).
+// This rejects indefinite and non-minimal lengths, non-minimal tag numbers and constructed encodings of types that
+// must be primitive. The contents of primitive elements are not inspected.
+func CheckDER(b []byte) error {
+	_, hl, cl, err := parseHeader(b)
+	if err != nil {
+		return err
+	}
+	if hl+cl != len(b) {
+		return fmt.Errorf("%w: %d trailing bytes", ErrNotDER, len(b)-(hl+cl))
+	}
+	return checkDERElements(b, 1)
+}
+
+// CheckDERHeader checks that b contains exactly one ASN.1 element, without trailing bytes, and that its identifier and
+// length octets conform to the distinguished encoding rules. Its contents are not inspected, for elements whose
+// contents are not entirely ASN.1 encoded such as the GSS-API framing of a KRB5 context token.
+func CheckDERHeader(b []byte) error {
+	_, hl, cl, err := parseHeader(b)
+	if err != nil {
+		return err
+	}
+	if hl+cl != len(b) {
+		return fmt.Errorf("%w: %d trailing bytes", ErrNotDER, len(b)-(hl+cl))
+	}
+	return checkDERHeader(b[:hl], cl)
+}
+
+// checkDERElements checks the sequence of elements in b which are at the nesting depth provided.
+func checkDERElements(b []byte, depth int) error {
+	if depth > defaultLimits.MaxDepth {
+		return fmt.Errorf("%w: nesting depth exceeds maximum of %d", ErrLimitExceeded, defaultLimits.MaxDepth)
+	}
+	for len(b) > 0 {
+		compound, hl, cl, err := parseHeader(b)
+		if err != nil {
+			return err
+		}
+		if err := checkDERHeader(b[:hl], cl); err != nil {
+			return err
+		}
+		if compound {
+			if err := checkDERElements(b[hl:hl+cl], depth+1); err != nil {
+				return err
+			}
+		}
+		b = b[hl+cl:]
+	}
+	return nil
+}
+
+// checkDERHeader checks the identifier and length octets in h, for an element with contents of length cl, are DER.
+func checkDERHeader(h []byte, cl int) error {
+	class := int(h[0] >> 6)
+	compound := h[0]&0x20 == 0x20
+	tag := int(h[0] & 0x1f)
+	i := 1
+	if tag == 0x1f {
+		if h[1] == 0x80 {
+			return fmt.Errorf("%w: tag number has leading zero octet", ErrNotDER)
+		}
+		tag = 0
+		for ; h[i]&0x80 != 0; i++ {
+			tag = tag<<7 | int(h[i]&0x7f)
+		}
+		tag = tag<<7 | int(h[i])
+		i++
+		if tag < 0x1f {
+			return fmt.Errorf("%w: tag number %d should use the low tag number form", ErrNotDER, tag)
+		}
+	}
+	if h[i]&0x80 != 0 {
+		if cl < 128 {
+			return fmt.Errorf("%w: length %d should use the short form", ErrNotDER, cl)
+		}
+		if h[i+1] == 0 {
+			return fmt.Errorf("%w: length has leading zero octet", ErrNotDER)
+		}
+	}
+	if class == asn1.ClassUniversal {
+		switch tag {
+		case asn1.TagSequence, asn1.TagSet:
+			if !compound {
+				return fmt.Errorf("%w: universal tag %d must be constructed", ErrNotDER, tag)
+			}
+		case asn1.TagBoolean, asn1.TagInteger, asn1.TagBitString, asn1.TagOctetString, tagNull, asn1.TagOID,
+			asn1.TagEnum, asn1.TagUTF8String, tagNumericString, asn1.TagPrintableString, asn1.TagT61String,
+			asn1.TagIA5String, asn1.TagUTCTime, asn1.TagGeneralizedTime, asn1.TagGeneralString:
+			if compound {
+				return fmt.Errorf("%w: universal tag %d must be primitive", ErrNotDER, tag)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package asn1tools
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jcmturner/gokrb5/v8/asn1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckDER(t *testing.T) {
+	t.Parallel()
+	b, _ := asn1.Marshal(struct {
+		A int
+		B string `asn1:"generalstring,explicit,tag:31"`
+		C []byte `asn1:"explicit,tag:1"`
+	}{1, "test", make([]byte, 200)})
+	assert.NoError(t, CheckDER(b), "DER encoding should pass")
+
+	var tests = []struct {
+		name string
+		b    []byte
+	}{
+		{"trailing bytes", append(append([]byte{}, b...), 0x00)},
+		{"long form length for short length", []byte{0x30, 0x81, 0x03, 0x02, 0x01, 0x01}},
+		{"length with leading zero", []byte{0x04, 0x82, 0x00, 0x80}},
+		{"high tag form for low tag number", []byte{0xbf, 0x01, 0x03, 0x02, 0x01, 0x01}},
+		{"tag number with leading zero", []byte{0xbf, 0x80, 0x1f, 0x03, 0x02, 0x01, 0x01}},
+		{"constructed octet string", []byte{0x24, 0x03, 0x04, 0x01, 0x01}},
+		{"primitive sequence", []byte{0x10, 0x01, 0x01}},
+	}
+	for _, test := range tests {
+		if test.name == "length with leading zero" {
+			test.b = append(test.b, make([]byte, 128)...)
+		}
+		err := CheckDER(test.b)
+		assert.True(t, errors.Is(err, ErrNotDER), "non-DER encoding not detected: %s: %v", test.name, err)
+	}
+}
+
+func TestCheckDERHeader(t *testing.T) {
+	t.Parallel()
+	// The contents, here a non-ASN.1 token ID followed by an element, are not inspected
+	b := []byte{0x60, 0x05, 0x01, 0x00, 0x02, 0x01, 0x01}
+	assert.NoError(t, CheckDERHeader(b), "DER header should pass")
+	err := CheckDERHeader(append(append([]byte{}, b...), 0x00))
+	assert.True(t, errors.Is(err, ErrNotDER), "trailing bytes not detected: %v", err)
+	err = CheckDERHeader([]byte{0x60, 0x81, 0x03, 0x02, 0x01, 0x01})
+	assert.True(t, errors.Is(err, ErrNotDER), "non-DER length not detected: %v", err)
+}
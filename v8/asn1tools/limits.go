@@ -0,0 +1,119 @@
+package asn1tools
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrLimitExceeded is returned when ASN.1 encoded data exceeds the resource limits permitted for decoding.
+var ErrLimitExceeded = errors.New("ASN.1 resource limit exceeded")
+
+// Limits defines the resource limits enforced on untrusted ASN.1 encoded data before it is decoded.
+type Limits struct {
+	MaxDepth    int // Maximum nesting depth of constructed elements.
+	MaxElements int // Maximum total number of elements.
+	MaxBytes    int // Maximum size of the encoded data in bytes.
+}
+
+// defaultLimits are generous for legitimate tokens, which may carry a large PAC, whilst bounding the work a crafted
+// token causes.
+var defaultLimits = Limits{
+	MaxDepth:    32,
+	MaxElements: 16384,
+	MaxBytes:    1 << 20,
+}
+
+// DefaultLimits returns the limits applied when decoding tokens received from peers such as SPNEGO tokens and AP_REQs.
+func DefaultLimits() Limits {
+	return defaultLimits
+}
+
+// CheckLimits walks the ASN.1 encoded element at the start of b and returns an error wrapping ErrLimitExceeded if it
+// exceeds the limits. An error is also returned if the encoding of the element headers is invalid.
+// The contents of primitive elements and any bytes trailing the element are not inspected.
+func CheckLimits(b []byte, l Limits) error {
+	if l.MaxBytes > 0 && len(b) > l.MaxBytes {
+		return fmt.Errorf("%w: size of %d bytes exceeds maximum of %d", ErrLimitExceeded, len(b), l.MaxBytes)
+	}
+	_, hl, cl, err := parseHeader(b)
+	if err != nil {
+		return err
+	}
+	var n int
+	return checkElements(b[:hl+cl], 1, &n, l)
+}
+
+// checkElements checks the sequence of elements in b which are at the nesting depth provided.
+func checkElements(b []byte, depth int, n *int, l Limits) error {
+	if l.MaxDepth > 0 && depth > l.MaxDepth {
+		return fmt.Errorf("%w: nesting depth exceeds maximum of %d", ErrLimitExceeded, l.MaxDepth)
+	}
+	for len(b) > 0 {
+		*n++
+		if l.MaxElements > 0 && *n > l.MaxElements {
+			return fmt.Errorf("%w: number of elements exceeds maximum of %d", ErrLimitExceeded, l.MaxElements)
+		}
+		compound, hl, cl, err := parseHeader(b)
+		if err != nil {
+			return err
+		}
+		if compound {
+			if err := checkElements(b[hl:hl+cl], depth+1, n, l); err != nil {
+				return err
+			}
+		}
+		b = b[hl+cl:]
+	}
+	return nil
+}
+
+// parseHeader parses the identifier and length octets of the element at the start of b.
+// It returns if the element is constructed, the length of the header and the length of the contents.
+func parseHeader(b []byte) (compound bool, hl, cl int, err error) {
+	if len(b) < 2 {
+		err = errors.New("ASN.1 element header truncated")
+		return
+	}
+	compound = b[0]&0x20 == 0x20
+	hl = 1
+	if b[0]&0x1f == 0x1f {
+		// High tag number form. Bit 8 of subsequent octets indicates further octets follow.
+		for {
+			if hl >= len(b) || hl > 5 {
+				err = errors.New("ASN.1 element tag invalid")
+				return
+			}
+			hl++
+			if b[hl-1]&0x80 == 0 {
+				break
+			}
+		}
+	}
+	if hl >= len(b) {
+		err = errors.New("ASN.1 element header truncated")
+		return
+	}
+	lb := b[hl]
+	hl++
+	if lb&0x80 == 0 {
+		cl = int(lb)
+	} else {
+		nb := int(lb & 0x7f)
+		if nb == 0 {
+			err = errors.New("ASN.1 indefinite length encoding not supported")
+			return
+		}
+		if nb > 4 || hl+nb > len(b) {
+			err = errors.New("ASN.1 element length invalid")
+			return
+		}
+		for _, o := range b[hl : hl+nb] {
+			cl = cl<<8 | int(o)
+		}
+		hl += nb
+	}
+	if cl < 0 || cl > len(b)-hl {
+		err = errors.New("ASN.1 element length exceeds the data available")
+	}
+	return
+}
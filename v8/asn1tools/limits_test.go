@@ -0,0 +1,63 @@
+package asn1tools
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jcmturner/gokrb5/v8/asn1"
+	"github.com/stretchr/testify/assert"
+)
+
+func nested(depth int) []byte {
+	b, _ := asn1.Marshal(1)
+	for i := 0; i < depth; i++ {
+		b, _ = asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSequence, IsCompound: true, Bytes: b})
+	}
+	return b
+}
+
+func TestCheckLimits(t *testing.T) {
+	t.Parallel()
+	l := Limits{MaxDepth: 10, MaxElements: 20, MaxBytes: 1024}
+	assert.NoError(t, CheckLimits(nested(9), l), "data within limits should pass")
+
+	err := CheckLimits(nested(10), l)
+	assert.True(t, errors.Is(err, ErrLimitExceeded), "nesting depth limit not enforced: %v", err)
+
+	var b []byte
+	for i := 0; i < 20; i++ {
+		ib, _ := asn1.Marshal(i)
+		b = append(b, ib...)
+	}
+	b, _ = asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSequence, IsCompound: true, Bytes: b})
+	err = CheckLimits(b, l)
+	assert.True(t, errors.Is(err, ErrLimitExceeded), "element count limit not enforced: %v", err)
+
+	err = CheckLimits(make([]byte, 1025), l)
+	assert.True(t, errors.Is(err, ErrLimitExceeded), "size limit not enforced: %v", err)
+}
+
+func TestCheckLimits_InvalidEncoding(t *testing.T) {
+	t.Parallel()
+	var tests = []struct {
+		name string
+		b    []byte
+	}{
+		{"truncated header", []byte{0x30}},
+		{"length exceeds data", []byte{0x30, 0x05, 0x02, 0x01, 0x01}},
+		{"indefinite length", []byte{0x30, 0x80, 0x00, 0x00}},
+		{"oversized length", []byte{0x04, 0x85, 0xff, 0xff, 0xff, 0xff, 0xff}},
+	}
+	for _, test := range tests {
+		err := CheckLimits(test.b, DefaultLimits())
+		assert.Error(t, err, "invalid encoding not detected: %s", test.name)
+		assert.False(t, errors.Is(err, ErrLimitExceeded), "invalid encoding should not be reported as a limit: %s", test.name)
+	}
+}
+
+func TestDefaultLimits(t *testing.T) {
+	t.Parallel()
+	l := DefaultLimits()
+	l.MaxDepth = 1
+	assert.NotEqual(t, l, DefaultLimits(), "changes to the limits returned should not change the defaults")
+}
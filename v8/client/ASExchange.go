@@ -4,143 +4,171 @@ import (
 	"github.com/jcmturner/gokrb5/v8/crypto"
 	"github.com/jcmturner/gokrb5/v8/crypto/etype"
 	"github.com/jcmturner/gokrb5/v8/iana/errorcode"
-	"github.com/jcmturner/gokrb5/v8/iana/keyusage"
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
 	"github.com/jcmturner/gokrb5/v8/iana/patype"
 	"github.com/jcmturner/gokrb5/v8/krberror"
 	"github.com/jcmturner/gokrb5/v8/messages"
 	"github.com/jcmturner/gokrb5/v8/types"
 )
 
+// maxASReqAttempts is the maximum number of AS_REQs sent to the KDC within a single AS exchange while responding to
+// pre-authentication hints and clock skew errors.
+const maxASReqAttempts = 5
+
 // ASExchange performs an AS exchange for the client to retrieve a TGT.
 func (cl *Client) ASExchange(realm string, ASReq messages.ASReq, referral int) (messages.ASRep, error) {
+	return cl.asExchange(realm, ASReq, referral, !cl.settings.DisablePAFXFAST())
+}
+
+// asExchange performs an AS exchange, negotiating FAST with PA-REQ-ENC-PA-REP if indicated.
+func (cl *Client) asExchange(realm string, ASReq messages.ASReq, referral int, fast bool) (messages.ASRep, error) {
 	if ok, err := cl.IsConfigured(); !ok {
 		return messages.ASRep{}, krberror.Errorf(err, krberror.ConfigError, "AS Exchange cannot be performed")
 	}
 
-	// Set PAData if required
-	err := setPAData(cl, nil, &ASReq)
-	if err != nil {
-		return messages.ASRep{}, krberror.Errorf(err, krberror.KRBMsgError, "AS Exchange Error: issue with setting PAData on AS_REQ")
+	if fast {
+		replacePAData(&ASReq, types.PAData{PADataType: patype.PA_REQ_ENC_PA_REP})
 	}
+	cl.trace("Getting initial credentials for %s@%s", ASReq.ReqBody.CName.PrincipalNameString(), ASReq.ReqBody.Realm)
+	cl.trace("Requesting etypes: %s", traceETypes(ASReq.ReqBody.EType))
 
-	b, err := ASReq.Marshal()
-	if err != nil {
-		return messages.ASRep{}, krberror.Errorf(err, krberror.EncodingError, "AS Exchange Error: failed marshaling AS_REQ")
+	// The pre-authentication mechanism in use and the KRBError carrying the hints it was last configured with.
+	var mech *preAuthMechanism
+	var hints *messages.KRBError
+	// Mechanisms that have been used in response to hints from the KDC.
+	tried := make(map[int32]bool)
+
+	if cl.settings.AssumePreAuthentication() {
+		// Preemptively set pre-authentication data. There are no hints from the KDC so use the preferred mechanism.
+		mech = &preAuthMechanisms[0]
+		if err := mech.setPAData(cl, nil, &ASReq); err != nil {
+			return messages.ASRep{}, krberror.Errorf(err, krberror.KRBMsgError, "AS Exchange Error: issue with setting PAData on AS_REQ")
+		}
+		cl.trace("Assuming pre-authentication is required, using %s (%d)", patype.Name(mech.paType), mech.paType)
 	}
-	var ASRep messages.ASRep
 
-	rb, err := cl.sendToKDC(b, realm)
-	if err != nil {
-		if e, ok := err.(messages.KRBError); ok {
-			switch e.ErrorCode {
-			case errorcode.KDC_ERR_PREAUTH_REQUIRED, errorcode.KDC_ERR_PREAUTH_FAILED:
-				// From now on assume this client will need to do this pre-auth and set the PAData
-				cl.settings.assumePreAuthentication = true
-				err = setPAData(cl, &e, &ASReq)
-				if err != nil {
-					return messages.ASRep{}, krberror.Errorf(err, krberror.KRBMsgError, "AS Exchange Error: failed setting AS_REQ PAData for pre-authentication required")
-				}
-				b, err := ASReq.Marshal()
-				if err != nil {
-					return messages.ASRep{}, krberror.Errorf(err, krberror.EncodingError, "AS Exchange Error: failed marshaling AS_REQ with PAData")
-				}
-				rb, err = cl.sendToKDC(b, realm)
-				if err != nil {
-					if _, ok := err.(messages.KRBError); ok {
-						return messages.ASRep{}, krberror.Errorf(err, krberror.KDCError, "AS Exchange Error: kerberos error response from KDC")
-					}
-					return messages.ASRep{}, krberror.Errorf(err, krberror.NetworkingError, "AS Exchange Error: failed sending AS_REQ to KDC")
+	var rb []byte
+	for attempt := 1; ; attempt++ {
+		b, err := ASReq.Marshal()
+		if err != nil {
+			return messages.ASRep{}, krberror.Errorf(err, krberror.EncodingError, "AS Exchange Error: failed marshaling AS_REQ")
+		}
+		if mech == nil {
+			cl.trace("Sending unauthenticated request")
+		} else {
+			cl.trace("Sending request with preauth: %s", tracePATypes(ASReq.PAData))
+		}
+		rb, err = cl.sendToKDC(b, realm)
+		if err == nil {
+			break
+		}
+		e, ok := err.(messages.KRBError)
+		if ok {
+			cl.trace("Received error from KDC: %s", traceKRBError(e))
+		}
+		if !ok {
+			return messages.ASRep{}, krberror.Errorf(err, krberror.NetworkingError, "AS Exchange Error: failed sending AS_REQ to KDC")
+		}
+		if attempt >= maxASReqAttempts {
+			return messages.ASRep{}, krberror.Errorf(err, krberror.KDCError, "AS Exchange Error: maximum number of AS_REQ attempts exceeded")
+		}
+		switch e.ErrorCode {
+		case errorcode.KDC_ERR_PREAUTH_REQUIRED, errorcode.KDC_ERR_PREAUTH_FAILED:
+			// From now on assume this client will need to do this pre-auth and set the PAData
+			cl.settings.assumePreAuthentication = true
+			var pas types.PADataSequence
+			if len(e.EData) > 0 {
+				if err := pas.Unmarshal(e.EData); err != nil {
+					return messages.ASRep{}, krberror.Errorf(err, krberror.EncodingError, "AS Exchange Error: error unmarshaling pre-authentication hints from KDC")
 				}
-			case errorcode.KDC_ERR_WRONG_REALM:
-				// Client referral https://tools.ietf.org/html/rfc6806.html#section-7
-				if referral > 5 {
-					return messages.ASRep{}, krberror.Errorf(err, krberror.KRBMsgError, "maximum number of client referrals exceeded")
+			}
+			cl.trace("Processing preauth types: %s", tracePATypes(pas))
+			if info, ok := traceETypeInfo(pas); ok {
+				cl.trace("Selected etype info: %s", info)
+			}
+			mech = selectPreAuthMechanism(cl, pas, tried)
+			if mech == nil {
+				cl.trace("No further preauth mechanisms available")
+				return messages.ASRep{}, krberror.Errorf(err, krberror.KDCError, "AS Exchange Error: no further pre-authentication mechanisms available")
+			}
+			tried[mech.paType] = true
+			hints = &e
+			// The KDC may require its cookie be returned on subsequent requests within the exchange. RFC 6113 5.2
+			for _, pa := range pas {
+				if pa.PADataType == patype.PA_FX_COOKIE {
+					replacePAData(&ASReq, pa)
 				}
-				referral++
-				return cl.ASExchange(e.CRealm, ASReq, referral)
-			default:
+			}
+			cl.trace("Attempting preauth mechanism %s (%d)", patype.Name(mech.paType), mech.paType)
+			if err := mech.setPAData(cl, hints, &ASReq); err != nil {
+				return messages.ASRep{}, krberror.Errorf(err, krberror.KRBMsgError, "AS Exchange Error: failed setting AS_REQ PAData for pre-authentication required")
+			}
+			cl.trace("Produced preauth for next request: %s", tracePATypes(ASReq.PAData))
+		case errorcode.KRB_AP_ERR_SKEW:
+			// The KDC has rejected the request's timestamps. Correct for the offset with the KDC's clock
+			// and try again rather than failing until the local clock is fixed.
+			if !cl.SyncTimeFromKRBError(e) {
 				return messages.ASRep{}, krberror.Errorf(err, krberror.KDCError, "AS Exchange Error: kerberos error response from KDC")
 			}
-		} else {
-			return messages.ASRep{}, krberror.Errorf(err, krberror.NetworkingError, "AS Exchange Error: failed sending AS_REQ to KDC")
+			cl.trace("Retrying AS request with clock offset %v from the KDC's time", cl.TimeOffset())
+			if mech != nil {
+				if err := mech.setPAData(cl, hints, &ASReq); err != nil {
+					return messages.ASRep{}, krberror.Errorf(err, krberror.KRBMsgError, "AS Exchange Error: failed setting AS_REQ PAData after correcting clock skew")
+				}
+			}
+		case errorcode.KDC_ERR_WRONG_REALM:
+			// Client referral https://tools.ietf.org/html/rfc6806.html#section-7
+			if referral > 5 {
+				return messages.ASRep{}, krberror.Errorf(err, krberror.KRBMsgError, "maximum number of client referrals exceeded")
+			}
+			referral++
+			cl.trace("Received client referral to realm %s", e.CRealm)
+			// Request the TGT of the realm the client has been referred to from its KDC
+			if ASReq.ReqBody.SName.NameString[0] == "krbtgt" {
+				ASReq.ReqBody.SName = types.NewPrincipalName(nametype.KRB_NT_SRV_INST, "krbtgt/"+e.CRealm)
+			}
+			ASReq.ReqBody.Realm = e.CRealm
+			ASReq.PAData = types.PADataSequence{}
+			return cl.asExchange(e.CRealm, ASReq, referral, fast)
+		default:
+			return messages.ASRep{}, krberror.Errorf(err, krberror.KDCError, "AS Exchange Error: kerberos error response from KDC")
 		}
 	}
-	err = ASRep.Unmarshal(rb)
+
+	var ASRep messages.ASRep
+	err := ASRep.Unmarshal(rb)
 	if err != nil {
 		return messages.ASRep{}, krberror.Errorf(err, krberror.EncodingError, "AS Exchange Error: failed to process the AS_REP")
 	}
-	if ok, err := ASRep.Verify(cl.Config, cl.Credentials, ASReq); !ok {
+	if ok, err := ASRep.VerifyAt(cl.Config, cl.loginCredentials(), ASReq, cl.Now()); !ok {
 		return messages.ASRep{}, krberror.Errorf(err, krberror.KRBMsgError, "AS Exchange Error: AS_REP is not valid or client password/keytab incorrect")
 	}
+	if !ASRep.CName.Equal(ASReq.ReqBody.CName) {
+		if cl.settings.StrictClientName() {
+			return messages.ASRep{}, krberror.NewErrorf(krberror.KRBMsgError, "AS Exchange Error: CName in response does not match what was requested. Requested: %+v; Reply: %+v", ASReq.ReqBody.CName, ASRep.CName)
+		}
+		cl.Log("KDC canonicalized client name %s to %s", ASReq.ReqBody.CName.PrincipalNameString(), ASRep.CName.PrincipalNameString())
+	}
+	if mech != nil && mech.processReply != nil {
+		if err := mech.processReply(cl, &ASRep); err != nil {
+			return messages.ASRep{}, krberror.Errorf(err, krberror.KRBMsgError, "AS Exchange Error: pre-authentication mechanism rejected the AS_REP")
+		}
+	}
+	cl.trace("Decrypted AS reply; session key is: %s", traceEType(ASRep.DecryptedEncPart.Key.KeyType))
+	cl.syncTime(ASRep.DecryptedEncPart.AuthTime)
+	cl.ExportSessionKey(ASRep.Ticket.SName, ASRep.Ticket.Realm, ASRep.DecryptedEncPart.Key)
 	return ASRep, nil
 }
 
-// setPAData adds pre-authentication data to the AS_REQ.
-func setPAData(cl *Client, krberr *messages.KRBError, ASReq *messages.ASReq) error {
-	if !cl.settings.DisablePAFXFAST() {
-		pa := types.PAData{PADataType: patype.PA_REQ_ENC_PA_REP}
-		ASReq.PAData = append(ASReq.PAData, pa)
-	}
-	if cl.settings.AssumePreAuthentication() {
-		// Identify the etype to use to encrypt the PA Data
-		var et etype.EType
-		var err error
-		var key types.EncryptionKey
-		var kvno int
-		if krberr == nil {
-			// This is not in response to an error from the KDC. It is preemptive or renewal
-			// There is no KRB Error that tells us the etype to use
-			etn := cl.settings.preAuthEType // Use the etype that may have previously been negotiated
-			if etn == 0 {
-				etn = int32(cl.Config.LibDefaults.PreferredPreauthTypes[0]) // Resort to config
-			}
-			et, err = crypto.GetEtype(etn)
-			if err != nil {
-				return krberror.Errorf(err, krberror.EncryptingError, "error getting etype for pre-auth encryption")
-			}
-			key, kvno, err = cl.Key(et, 0, nil)
-			if err != nil {
-				return krberror.Errorf(err, krberror.EncryptingError, "error getting key from credentials")
-			}
-		} else {
-			// Get the etype to use from the PA data in the KRBError e-data
-			et, err = preAuthEType(krberr)
-			if err != nil {
-				return krberror.Errorf(err, krberror.EncryptingError, "error getting etype for pre-auth encryption")
-			}
-			cl.settings.preAuthEType = et.GetETypeID() // Set the etype that has been defined for potential future use
-			key, kvno, err = cl.Key(et, 0, krberr)
-			if err != nil {
-				return krberror.Errorf(err, krberror.EncryptingError, "error getting key from credentials")
-			}
-		}
-		// Generate the PA data
-		paTSb, err := types.GetPAEncTSEncAsnMarshalled()
-		if err != nil {
-			return krberror.Errorf(err, krberror.KRBMsgError, "error creating PAEncTSEnc for Pre-Authentication")
-		}
-		paEncTS, err := crypto.GetEncryptedData(paTSb, key, keyusage.AS_REQ_PA_ENC_TIMESTAMP, kvno)
-		if err != nil {
-			return krberror.Errorf(err, krberror.EncryptingError, "error encrypting pre-authentication timestamp")
-		}
-		pb, err := paEncTS.Marshal()
-		if err != nil {
-			return krberror.Errorf(err, krberror.EncodingError, "error marshaling the PAEncTSEnc encrypted data")
-		}
-		pa := types.PAData{
-			PADataType:  patype.PA_ENC_TIMESTAMP,
-			PADataValue: pb,
-		}
-		// Look for and delete any exiting patype.PA_ENC_TIMESTAMP
-		for i, pa := range ASReq.PAData {
-			if pa.PADataType == patype.PA_ENC_TIMESTAMP {
-				ASReq.PAData[i] = ASReq.PAData[len(ASReq.PAData)-1]
-				ASReq.PAData = ASReq.PAData[:len(ASReq.PAData)-1]
-			}
+// replacePAData adds the PAData to the AS_REQ replacing any existing PAData of the same type.
+func replacePAData(ASReq *messages.ASReq, pa types.PAData) {
+	for i := range ASReq.PAData {
+		if ASReq.PAData[i].PADataType == pa.PADataType {
+			ASReq.PAData[i] = pa
+			return
 		}
-		ASReq.PAData = append(ASReq.PAData, pa)
 	}
-	return nil
+	ASReq.PAData = append(ASReq.PAData, pa)
 }
 
 // preAuthEType establishes what encryption type to use for pre-authentication from the KRBError returned from the KDC.
@@ -1,6 +1,9 @@
 package client
 
 import (
+	"errors"
+
+	"github.com/jcmturner/gokrb5/v8/iana/errorcode"
 	"github.com/jcmturner/gokrb5/v8/iana/flags"
 	"github.com/jcmturner/gokrb5/v8/iana/nametype"
 	"github.com/jcmturner/gokrb5/v8/krberror"
@@ -10,7 +13,7 @@ import (
 
 // TGSREQGenerateAndExchange generates the TGS_REQ and performs a TGS exchange to retrieve a ticket to the specified SPN.
 func (cl *Client) TGSREQGenerateAndExchange(spn types.PrincipalName, kdcRealm string, tgt messages.Ticket, sessionKey types.EncryptionKey, renewal bool) (tgsReq messages.TGSReq, tgsRep messages.TGSRep, err error) {
-	tgsReq, err = messages.NewTGSReq(cl.Credentials.CName(), kdcRealm, cl.Config, tgt, sessionKey, spn, renewal)
+	tgsReq, err = messages.NewTGSReqAt(cl.CName(), kdcRealm, cl.Config, tgt, sessionKey, spn, renewal, cl.Now())
 	if err != nil {
 		return tgsReq, tgsRep, krberror.Errorf(err, krberror.KRBMsgError, "TGS Exchange Error: failed to generate a new TGS_REQ")
 	}
@@ -21,14 +24,22 @@ func (cl *Client) TGSREQGenerateAndExchange(spn types.PrincipalName, kdcRealm st
 // Referrals are automatically handled.
 // The client's cache is updated with the ticket received.
 func (cl *Client) TGSExchange(tgsReq messages.TGSReq, kdcRealm string, tgt messages.Ticket, sessionKey types.EncryptionKey, referral int) (messages.TGSReq, messages.TGSRep, error) {
+	return cl.tgsExchange(tgsReq, kdcRealm, tgt, sessionKey, referral, nil)
+}
+
+// tgsExchange performs a TGS exchange. If the request was generated with options they are applied to the requests
+// following any referrals and the ticket received is not cached, as it may differ from that for a plain request.
+func (cl *Client) tgsExchange(tgsReq messages.TGSReq, kdcRealm string, tgt messages.Ticket, sessionKey types.EncryptionKey, referral int, opts *messages.TGSReqOptions) (messages.TGSReq, messages.TGSRep, error) {
 	var tgsRep messages.TGSRep
 	b, err := tgsReq.Marshal()
 	if err != nil {
 		return tgsReq, tgsRep, krberror.Errorf(err, krberror.EncodingError, "TGS Exchange Error: failed to marshal TGS_REQ")
 	}
+	cl.trace("Requesting tickets for %s@%s from %s, renewal %t, etypes: %s", tgsReq.ReqBody.SName.PrincipalNameString(), tgsReq.ReqBody.Realm, kdcRealm, tgsReq.Renewal, traceETypes(tgsReq.ReqBody.EType))
 	r, err := cl.sendToKDC(b, kdcRealm)
 	if err != nil {
-		if _, ok := err.(messages.KRBError); ok {
+		if e, ok := err.(messages.KRBError); ok {
+			cl.trace("Received error from KDC: %s", traceKRBError(e))
 			return tgsReq, tgsRep, krberror.Errorf(err, krberror.KDCError, "TGS Exchange Error: kerberos error response from KDC when requesting for %s", tgsReq.ReqBody.SName.PrincipalNameString())
 		}
 		return tgsReq, tgsRep, krberror.Errorf(err, krberror.NetworkingError, "TGS Exchange Error: issue sending TGS_REQ to KDC")
@@ -41,9 +52,10 @@ func (cl *Client) TGSExchange(tgsReq messages.TGSReq, kdcRealm string, tgt messa
 	if err != nil {
 		return tgsReq, tgsRep, krberror.Errorf(err, krberror.EncodingError, "TGS Exchange Error: failed to process the TGS_REP")
 	}
-	if ok, err := tgsRep.Verify(cl.Config, tgsReq); !ok {
+	if ok, err := tgsRep.VerifyQuirks(cl.Config, tgsReq, cl.Now(), cl.settings.Quirks()); !ok {
 		return tgsReq, tgsRep, krberror.Errorf(err, krberror.EncodingError, "TGS Exchange Error: TGS_REP is not valid")
 	}
+	cl.ExportSessionKey(tgsRep.Ticket.SName, tgsRep.Ticket.Realm, tgsRep.DecryptedEncPart.Key)
 
 	if tgsRep.Ticket.SName.NameString[0] == "krbtgt" && !tgsRep.Ticket.SName.Equal(tgsReq.ReqBody.SName) {
 		if referral > 5 {
@@ -53,18 +65,32 @@ func (cl *Client) TGSExchange(tgsReq messages.TGSReq, kdcRealm string, tgt messa
 		// The TGS Rep contains a TGT for another domain as the service resides in that domain.
 		cl.addSession(tgsRep.Ticket, tgsRep.DecryptedEncPart)
 		realm := tgsRep.Ticket.SName.NameString[len(tgsRep.Ticket.SName.NameString)-1]
+		cl.trace("Received referral TGT for realm %s; following referral", realm)
 		referral++
 		if types.IsFlagSet(&tgsReq.ReqBody.KDCOptions, flags.EncTktInSkey) && len(tgsReq.ReqBody.AdditionalTickets) > 0 {
-			tgsReq, err = messages.NewUser2UserTGSReq(cl.Credentials.CName(), kdcRealm, cl.Config, tgt, sessionKey, tgsReq.ReqBody.SName, tgsReq.Renewal, tgsReq.ReqBody.AdditionalTickets[0])
+			tgsReq, err = messages.NewUser2UserTGSReqAt(cl.CName(), kdcRealm, cl.Config, tgt, sessionKey, tgsReq.ReqBody.SName, tgsReq.Renewal, tgsReq.ReqBody.AdditionalTickets[0], cl.Now())
 			if err != nil {
 				return tgsReq, tgsRep, err
 			}
 		}
-		tgsReq, err = messages.NewTGSReq(cl.Credentials.CName(), realm, cl.Config, tgsRep.Ticket, tgsRep.DecryptedEncPart.Key, tgsReq.ReqBody.SName, tgsReq.Renewal)
+		if opts != nil {
+			tgsReq, err = messages.NewTGSReqWithOptions(cl.CName(), realm, cl.Config, tgsRep.Ticket, tgsRep.DecryptedEncPart.Key, tgsReq.ReqBody.SName, tgsReq.Renewal, cl.Now(), *opts)
+		} else {
+			tgsReq, err = messages.NewTGSReqAt(cl.CName(), realm, cl.Config, tgsRep.Ticket, tgsRep.DecryptedEncPart.Key, tgsReq.ReqBody.SName, tgsReq.Renewal, cl.Now())
+		}
 		if err != nil {
 			return tgsReq, tgsRep, err
 		}
-		return cl.TGSExchange(tgsReq, realm, tgsRep.Ticket, tgsRep.DecryptedEncPart.Key, referral)
+		return cl.tgsExchange(tgsReq, realm, tgsRep.Ticket, tgsRep.DecryptedEncPart.Key, referral, opts)
+	}
+	cl.trace("Received creds for desired service %s@%s; session key is: %s", tgsRep.Ticket.SName.PrincipalNameString(), tgsRep.Ticket.Realm, traceEType(tgsRep.DecryptedEncPart.Key.KeyType))
+	if tgsReq.Renewal {
+		cl.ticketEvent(EventTicketRenewed, tgsRep.Ticket, tgsRep.DecryptedEncPart.EndTime, tgsRep.DecryptedEncPart.RenewTill, nil)
+	} else {
+		cl.ticketEvent(EventTicketAcquired, tgsRep.Ticket, tgsRep.DecryptedEncPart.EndTime, tgsRep.DecryptedEncPart.RenewTill, nil)
+	}
+	if opts != nil {
+		return tgsReq, tgsRep, err
 	}
 	cl.cache.addEntry(
 		tgsRep.Ticket,
@@ -72,6 +98,7 @@ func (cl *Client) TGSExchange(tgsReq messages.TGSReq, kdcRealm string, tgt messa
 		tgsRep.DecryptedEncPart.StartTime,
 		tgsRep.DecryptedEncPart.EndTime,
 		tgsRep.DecryptedEncPart.RenewTill,
+		tgsRep.DecryptedEncPart.Flags,
 		tgsRep.DecryptedEncPart.Key,
 	)
 	cl.Log("ticket added to cache for %s (EndTime: %v)", tgsRep.Ticket.SName.PrincipalNameString(), tgsRep.DecryptedEncPart.EndTime)
@@ -81,15 +108,40 @@ func (cl *Client) TGSExchange(tgsReq messages.TGSReq, kdcRealm string, tgt messa
 // GetServiceTicket makes a request to get a service ticket for the SPN specified
 // SPN format: <SERVICE>/<FQDN> Eg. HTTP/www.example.com
 // The ticket will be added to the client's ticket cache
+//
+// If the SPN is qualified with a port or instance name, Eg. MSSQLSvc/db.example.com:1433, and the KDC does not know
+// the qualified SPN a ticket for the SPN without the port is requested instead.
 func (cl *Client) GetServiceTicket(spn string) (messages.Ticket, types.EncryptionKey, error) {
-	var tkt messages.Ticket
-	var skey types.EncryptionKey
+	tkt, skey, err := cl.getServiceTicket(spn)
+	if err != nil && errors.Is(err, messages.KRBError{ErrorCode: errorcode.KDC_ERR_S_PRINCIPAL_UNKNOWN}) {
+		if s, perr := types.ParseSPN(spn); perr == nil && s.Port != "" {
+			cl.Log("%s not known to the KDC, requesting a ticket for %s", spn, s.WithoutPort().String())
+			return cl.getServiceTicket(s.WithoutPort().String())
+		}
+	}
+	return tkt, skey, err
+}
+
+// getServiceTicket returns a ticket for the SPN from the cache or the KDC.
+func (cl *Client) getServiceTicket(spn string) (messages.Ticket, types.EncryptionKey, error) {
 	if tkt, skey, ok := cl.GetCachedTicket(spn); ok {
 		// Already a valid ticket in the cache
 		return tkt, skey, nil
 	}
+	return cl.requestServiceTicket(spn)
+}
+
+// requestServiceTicket requests a ticket for the SPN from the KDC, adding it to the cache.
+func (cl *Client) requestServiceTicket(spn string) (messages.Ticket, types.EncryptionKey, error) {
+	var tkt messages.Ticket
 	princ := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, spn)
-	realm := cl.Config.ResolveRealm(princ.NameString[len(princ.NameString)-1])
+	host := princ.NameString[len(princ.NameString)-1]
+	if s, err := types.ParseSPN(spn); err == nil {
+		// Any port is not part of the domain name used to resolve the realm
+		host = s.Host
+	}
+	realm := cl.Config.ResolveRealm(host)
+	cl.trace("Getting credentials %s@%s -> %s@%s", cl.Credentials.CName().PrincipalNameString(), cl.Realm(), spn, realm)
 
 	tgt, skey, err := cl.sessionTGT(realm)
 	if err != nil {
@@ -101,3 +153,39 @@ func (cl *Client) GetServiceTicket(spn string) (messages.Ticket, types.Encryptio
 	}
 	return tgsRep.Ticket, tgsRep.DecryptedEncPart.Key, nil
 }
+
+// GetServiceTicketWithOptions requests a ticket for the SPN specified from the KDC with the optional TGS_REQ fields
+// provided, such as additional tickets or authorization data to restrict the use of the ticket.
+// The ticket is neither taken from nor added to the client's ticket cache.
+func (cl *Client) GetServiceTicketWithOptions(spn string, opts messages.TGSReqOptions) (messages.Ticket, types.EncryptionKey, error) {
+	princ := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, spn)
+	host := princ.NameString[len(princ.NameString)-1]
+	if s, err := types.ParseSPN(spn); err == nil {
+		host = s.Host
+	}
+	tgsRep, err := cl.tgsExchangeWithOptions(princ, cl.Config.ResolveRealm(host), opts)
+	if err != nil {
+		return messages.Ticket{}, types.EncryptionKey{}, err
+	}
+	return tgsRep.Ticket, tgsRep.DecryptedEncPart.Key, nil
+}
+
+// tgsExchangeWithOptions requests a ticket for the principal from the KDC of the realm with the optional TGS_REQ
+// fields provided. The ticket is not cached.
+func (cl *Client) tgsExchangeWithOptions(sname types.PrincipalName, realm string, opts messages.TGSReqOptions) (messages.TGSRep, error) {
+	tgt, skey, err := cl.sessionTGT(realm)
+	if err != nil {
+		return messages.TGSRep{}, err
+	}
+	return cl.tgsExchangeWithTGT(sname, realm, tgt, skey, opts)
+}
+
+// tgsExchangeWithTGT requests a ticket for the principal with the TGT and the optional TGS_REQ fields provided.
+func (cl *Client) tgsExchangeWithTGT(sname types.PrincipalName, realm string, tgt messages.Ticket, skey types.EncryptionKey, opts messages.TGSReqOptions) (messages.TGSRep, error) {
+	tgsReq, err := messages.NewTGSReqWithOptions(cl.CName(), realm, cl.Config, tgt, skey, sname, false, cl.Now(), opts)
+	if err != nil {
+		return messages.TGSRep{}, krberror.Errorf(err, krberror.KRBMsgError, "TGS Exchange Error: failed to generate a new TGS_REQ")
+	}
+	_, tgsRep, err := cl.tgsExchange(tgsReq, realm, tgt, skey, 0, &opts)
+	return tgsRep, err
+}
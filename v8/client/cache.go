@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/jcmturner/gokrb5/v8/asn1"
 	"github.com/jcmturner/gokrb5/v8/messages"
 	"github.com/jcmturner/gokrb5/v8/types"
 )
@@ -25,6 +26,7 @@ type CacheEntry struct {
 	StartTime  time.Time
 	EndTime    time.Time
 	RenewTill  time.Time
+	Flags      asn1.BitString      `json:"-"`
 	SessionKey types.EncryptionKey `json:"-"`
 }
 
@@ -64,7 +66,7 @@ func (c *Cache) JSON() (string, error) {
 }
 
 // addEntry adds a ticket to the cache.
-func (c *Cache) addEntry(tkt messages.Ticket, authTime, startTime, endTime, renewTill time.Time, sessionKey types.EncryptionKey) CacheEntry {
+func (c *Cache) addEntry(tkt messages.Ticket, authTime, startTime, endTime, renewTill time.Time, flags asn1.BitString, sessionKey types.EncryptionKey) CacheEntry {
 	spn := tkt.SName.PrincipalNameString()
 	c.mux.Lock()
 	defer c.mux.Unlock()
@@ -75,6 +77,7 @@ func (c *Cache) addEntry(tkt messages.Ticket, authTime, startTime, endTime, rene
 		StartTime:  startTime,
 		EndTime:    endTime,
 		RenewTill:  renewTill,
+		Flags:      flags,
 		SessionKey: sessionKey,
 	}
 	return c.Entries[spn]
@@ -101,31 +104,51 @@ func (c *Cache) RemoveEntry(spn string) {
 func (cl *Client) GetCachedTicket(spn string) (messages.Ticket, types.EncryptionKey, bool) {
 	if e, ok := cl.cache.getEntry(spn); ok {
 		//If within time window of ticket return it
-		if time.Now().UTC().After(e.StartTime) && time.Now().UTC().Before(e.EndTime) {
+		now := cl.Now()
+		if now.After(e.StartTime) && now.Before(e.EndTime) {
 			cl.Log("ticket received from cache for %s", spn)
+			cl.trace("Retrieving %s from cache: found, valid until %v", spn, e.EndTime)
 			return e.Ticket, e.SessionKey, true
-		} else if time.Now().UTC().Before(e.RenewTill) {
+		} else if now.Before(e.RenewTill) {
+			cl.trace("Retrieving %s from cache: expired, renewing until %v", spn, e.RenewTill)
 			e, err := cl.renewTicket(e)
 			if err != nil {
+				cl.trace("Renewal of %s failed: %v", spn, err)
 				return e.Ticket, e.SessionKey, false
 			}
 			return e.Ticket, e.SessionKey, true
 		}
+		cl.trace("Retrieving %s from cache: expired", spn)
+	} else {
+		cl.trace("Retrieving %s from cache: not found", spn)
 	}
 	var tkt messages.Ticket
 	var key types.EncryptionKey
 	return tkt, key, false
 }
 
+// GetCacheEntry returns the entry cached for the SPN, holding the times and flags of its ticket, whether or not the
+// ticket is currently valid.
+func (cl *Client) GetCacheEntry(spn string) (CacheEntry, bool) {
+	return cl.cache.getEntry(spn)
+}
+
+// RemoveCachedTicket removes the ticket cached for the SPN, so that the next request for a ticket to the service
+// obtains a new ticket from the KDC.
+func (cl *Client) RemoveCachedTicket(spn string) {
+	cl.cache.RemoveEntry(spn)
+}
+
 // renewTicket renews a cache entry ticket.
 // To renew from outside the client package use GetCachedTicket
 func (cl *Client) renewTicket(e CacheEntry) (CacheEntry, error) {
 	spn := e.Ticket.SName
 	_, _, err := cl.TGSREQGenerateAndExchange(spn, e.Ticket.Realm, e.Ticket, e.SessionKey, true)
 	if err != nil {
+		cl.ticketEvent(EventRenewalFailed, e.Ticket, e.EndTime, e.RenewTill, err)
 		return e, err
 	}
-	e, ok := cl.cache.getEntry(e.Ticket.SName.PrincipalNameString())
+	e, ok := cl.cache.getEntry(e.SPN)
 	if !ok {
 		return e, errors.New("ticket was not added to cache")
 	}
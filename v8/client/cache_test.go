@@ -6,6 +6,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jcmturner/gokrb5/v8/clock"
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/keytab"
 	"github.com/jcmturner/gokrb5/v8/messages"
 	"github.com/jcmturner/gokrb5/v8/types"
 	"github.com/stretchr/testify/assert"
@@ -29,7 +32,7 @@ func TestCache_addEntry_getEntry_remove_clear(t *testing.T) {
 			KeyValue: []byte{byte(i)},
 		}
 		go func(i int) {
-			e := c.addEntry(tkt, time.Unix(int64(0+i), 0).UTC(), time.Unix(int64(10+i), 0).UTC(), time.Unix(int64(20+i), 0).UTC(), time.Unix(int64(30+i), 0).UTC(), key)
+			e := c.addEntry(tkt, time.Unix(int64(0+i), 0).UTC(), time.Unix(int64(10+i), 0).UTC(), time.Unix(int64(20+i), 0).UTC(), time.Unix(int64(30+i), 0).UTC(), types.NewKrbFlags(), key)
 			assert.Equal(t, fmt.Sprintf("%d/test.cache", i), e.SPN, "SPN cache key not as expected")
 			wg.Done()
 		}(i)
@@ -97,6 +100,22 @@ func TestCache_addEntry_getEntry_remove_clear(t *testing.T) {
 	wg.Wait()
 }
 
+func TestCache_getEntry_CaseSensitive(t *testing.T) {
+	t.Parallel()
+	c := NewCache()
+	tkt := messages.Ticket{
+		SName: types.PrincipalName{
+			NameType:   1,
+			NameString: []string{"HTTP", "host.test.cache"},
+		},
+	}
+	c.addEntry(tkt, time.Time{}, time.Time{}, time.Time{}, time.Time{}, types.NewKrbFlags(), types.EncryptionKey{})
+	_, ok := c.getEntry("HTTP/host.test.cache")
+	assert.True(t, ok, "entry should be found for the SPN")
+	_, ok = c.getEntry("http/HOST.test.cache")
+	assert.False(t, ok, "entry should not be found for an SPN differing in case, which names another principal")
+}
+
 func TestCache_JSON(t *testing.T) {
 	t.Parallel()
 	c := NewCache()
@@ -112,7 +131,7 @@ func TestCache_JSON(t *testing.T) {
 			KeyType:  1,
 			KeyValue: []byte{byte(i)},
 		}
-		e := c.addEntry(tkt, time.Unix(int64(0+i), 0).UTC(), time.Unix(int64(10+i), 0).UTC(), time.Unix(int64(20+i), 0).UTC(), time.Unix(int64(30+i), 0).UTC(), key)
+		e := c.addEntry(tkt, time.Unix(int64(0+i), 0).UTC(), time.Unix(int64(10+i), 0).UTC(), time.Unix(int64(20+i), 0).UTC(), time.Unix(int64(30+i), 0).UTC(), types.NewKrbFlags(), key)
 		assert.Equal(t, fmt.Sprintf("%d/test.cache", i), e.SPN, "SPN cache key not as expected")
 	}
 	expected := `[
@@ -144,3 +163,22 @@ func TestCache_JSON(t *testing.T) {
 	}
 	assert.Equal(t, expected, j, "json output not as expected")
 }
+
+func TestGetCachedTicket_Clock(t *testing.T) {
+	t.Parallel()
+	st := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	c := clock.NewMock(st.Add(time.Minute))
+	cl := NewWithKeytab("username", "REALM", &keytab.Keytab{}, config.New(), Clock(c))
+	tkt := messages.Ticket{
+		SName: types.PrincipalName{
+			NameType:   1,
+			NameString: []string{"HTTP", "host.test.cache"},
+		},
+	}
+	cl.cache.addEntry(tkt, st, st, st.Add(time.Hour), time.Time{}, types.NewKrbFlags(), types.EncryptionKey{})
+	_, _, ok := cl.GetCachedTicket("HTTP/host.test.cache")
+	assert.True(t, ok, "ticket should be valid according to the client's clock")
+	c.Add(2 * time.Hour)
+	_, _, ok = cl.GetCachedTicket("HTTP/host.test.cache")
+	assert.False(t, ok, "ticket should have expired according to the client's clock")
+}
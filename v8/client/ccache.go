@@ -0,0 +1,85 @@
+package client
+
+import (
+	"sort"
+
+	"github.com/jcmturner/gokrb5/v8/credentials"
+)
+
+// CCache returns the client's TGTs and cached service tickets as a credential cache.
+func (cl *Client) CCache() (*credentials.CCache, error) {
+	c := credentials.NewCCache(cl.CName(), cl.Realm())
+	if d := cl.TimeOffset(); d != 0 {
+		c.SetKDCOffset(d)
+	}
+	cl.sessions.mux.RLock()
+	realms := make([]string, 0, len(cl.sessions.Entries))
+	for r := range cl.sessions.Entries {
+		realms = append(realms, r)
+	}
+	// The TGT for the client's own realm is written first
+	sort.Slice(realms, func(i, j int) bool {
+		if (realms[i] == cl.Realm()) != (realms[j] == cl.Realm()) {
+			return realms[i] == cl.Realm()
+		}
+		return realms[i] < realms[j]
+	})
+	for _, r := range realms {
+		s := cl.sessions.Entries[r]
+		s.mux.RLock()
+		b, err := s.tgt.Marshal()
+		if err != nil {
+			s.mux.RUnlock()
+			cl.sessions.mux.RUnlock()
+			return nil, err
+		}
+		c.AddCredential(s.tgt.SName, s.tgt.Realm, &credentials.Credential{
+			Key:         s.sessionKey,
+			AuthTime:    s.authTime,
+			StartTime:   s.startTime,
+			EndTime:     s.endTime,
+			RenewTill:   s.renewTill,
+			TicketFlags: s.flags,
+			Ticket:      b,
+		})
+		s.mux.RUnlock()
+	}
+	cl.sessions.mux.RUnlock()
+
+	cl.cache.mux.RLock()
+	defer cl.cache.mux.RUnlock()
+	spns := make([]string, 0, len(cl.cache.Entries))
+	for spn := range cl.cache.Entries {
+		spns = append(spns, spn)
+	}
+	sort.Strings(spns)
+	for _, spn := range spns {
+		e := cl.cache.Entries[spn]
+		b, err := e.Ticket.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		c.AddCredential(e.Ticket.SName, e.Ticket.Realm, &credentials.Credential{
+			Key:         e.SessionKey,
+			AuthTime:    e.AuthTime,
+			StartTime:   e.StartTime,
+			EndTime:     e.EndTime,
+			RenewTill:   e.RenewTill,
+			TicketFlags: e.Flags,
+			Ticket:      b,
+		})
+	}
+	return c, nil
+}
+
+// SaveToCCache writes the client's TGTs and cached service tickets to a credential cache file at the path provided
+// in the MIT format, so that they can be used by other Kerberos applications, such as kvno and curl --negotiate,
+// and loaded with NewFromCCache when the application restarts.
+// The file contains the tickets' session keys and is created readable only by its owner.
+func (cl *Client) SaveToCCache(path string) error {
+	c, err := cl.CCache()
+	if err != nil {
+		return err
+	}
+	return c.Export(path)
+}
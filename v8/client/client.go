@@ -7,7 +7,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
-	"time"
+	"sync"
 
 	"github.com/jcmturner/gokrb5/v8/config"
 	"github.com/jcmturner/gokrb5/v8/credentials"
@@ -28,16 +28,20 @@ type Client struct {
 	settings    *Settings
 	sessions    *sessions
 	cache       *Cache
+	timeOffset  timeOffset
+	cname       canonicalName
+	prefetchers prefetchers
 }
 
 // NewWithPassword creates a new client from a password credential.
 // Set the realm to empty string to use the default realm from config.
 func NewWithPassword(username, realm, password string, krb5conf *config.Config, settings ...func(*Settings)) *Client {
-	creds := credentials.New(username, realm)
+	s := NewSettings(settings...)
+	creds := newCredentials(username, krb5conf.NormalizeRealm(realm), s)
 	return &Client{
 		Credentials: creds.WithPassword(password),
 		Config:      krb5conf,
-		settings:    NewSettings(settings...),
+		settings:    s,
 		sessions: &sessions{
 			Entries: make(map[string]*session),
 		},
@@ -47,11 +51,12 @@ func NewWithPassword(username, realm, password string, krb5conf *config.Config,
 
 // NewWithKeytab creates a new client from a keytab credential.
 func NewWithKeytab(username, realm string, kt *keytab.Keytab, krb5conf *config.Config, settings ...func(*Settings)) *Client {
-	creds := credentials.New(username, realm)
+	s := NewSettings(settings...)
+	creds := newCredentials(username, krb5conf.NormalizeRealm(realm), s)
 	return &Client{
 		Credentials: creds.WithKeytab(kt),
 		Config:      krb5conf,
-		settings:    NewSettings(settings...),
+		settings:    s,
 		sessions: &sessions{
 			Entries: make(map[string]*session),
 		},
@@ -59,6 +64,42 @@ func NewWithKeytab(username, realm string, kt *keytab.Keytab, krb5conf *config.C
 	}
 }
 
+// newCredentials creates the credentials for the username, as an enterprise principal name if the settings specify.
+func newCredentials(username, realm string, s *Settings) *credentials.Credentials {
+	if s.EnterpriseName() {
+		return credentials.NewFromPrincipalName(types.NewEnterprisePrincipalName(username), realm)
+	}
+	return credentials.New(username, realm)
+}
+
+// NewWithMachinePassword creates a new client for the Active Directory computer account of the host from its
+// password. The host may be a fully qualified domain name, Eg. web01.example.com, or the account name, Eg. WEB01$.
+// Keys are derived from the password with the salt Active Directory uses for computer accounts.
+// Set the realm to empty string to use the default realm from config.
+func NewWithMachinePassword(host, realm, password string, krb5conf *config.Config, settings ...func(*Settings)) *Client {
+	if realm == "" {
+		realm = krb5conf.LibDefaults.DefaultRealm
+	}
+	name := types.MachineAccountName(host)
+	cl := NewWithPassword(name, realm, password, krb5conf, settings...)
+	cl.Credentials.WithSalt(types.MachineAccountSalt(name, realm))
+	cl.Credentials.SetHuman(false)
+	return cl
+}
+
+// NewWithMachineKeytab creates a new client for the Active Directory computer account of the host from a keytab
+// holding the account's keys, as created when joining the host to the domain.
+// The host may be a fully qualified domain name, Eg. web01.example.com, or the account name, Eg. WEB01$.
+// Set the realm to empty string to use the default realm from config.
+func NewWithMachineKeytab(host, realm string, kt *keytab.Keytab, krb5conf *config.Config, settings ...func(*Settings)) *Client {
+	if realm == "" {
+		realm = krb5conf.LibDefaults.DefaultRealm
+	}
+	cl := NewWithKeytab(types.MachineAccountName(host), realm, kt, krb5conf, settings...)
+	cl.Credentials.SetHuman(false)
+	return cl
+}
+
 // NewFromCCache create a client from a populated client cache.
 //
 // WARNING: A client created from CCache does not automatically renew TGTs and a failure will occur after the TGT expires.
@@ -83,13 +124,15 @@ func NewFromCCache(c *credentials.CCache, krb5conf *config.Config, settings ...f
 	var tgt messages.Ticket
 	err := tgt.Unmarshal(cred.Ticket)
 	if err != nil {
-		return cl, fmt.Errorf("TGT bytes in cache are not valid: %v", err)
+		return cl, fmt.Errorf("TGT bytes in cache are not valid: %w", err)
 	}
 	cl.sessions.Entries[c.DefaultPrincipal.Realm] = &session{
 		realm:      c.DefaultPrincipal.Realm,
 		authTime:   cred.AuthTime,
 		endTime:    cred.EndTime,
+		startTime:  cred.StartTime,
 		renewTill:  cred.RenewTill,
+		flags:      cred.TicketFlags,
 		tgt:        tgt,
 		sessionKey: cred.Key,
 	}
@@ -97,7 +140,7 @@ func NewFromCCache(c *credentials.CCache, krb5conf *config.Config, settings ...f
 		var tkt messages.Ticket
 		err = tkt.Unmarshal(cred.Ticket)
 		if err != nil {
-			return cl, fmt.Errorf("cache entry ticket bytes are not valid: %v", err)
+			return cl, fmt.Errorf("cache entry ticket bytes are not valid: %w", err)
 		}
 		cl.cache.addEntry(
 			tkt,
@@ -105,6 +148,7 @@ func NewFromCCache(c *credentials.CCache, krb5conf *config.Config, settings ...f
 			cred.StartTime,
 			cred.EndTime,
 			cred.RenewTill,
+			cred.TicketFlags,
 			cred.Key,
 		)
 	}
@@ -114,27 +158,47 @@ func NewFromCCache(c *credentials.CCache, krb5conf *config.Config, settings ...f
 // Key returns the client's encryption key for the specified encryption type and its kvno (kvno of zero will find latest).
 // The key can be retrieved either from the keytab or generated from the client's password.
 // If the client has both a keytab and a password defined the keytab is favoured as the source for the key
+// If the client has neither the key is taken from any client keytab configured.
 // A KRBError can be passed in the event the KDC returns one of type KDC_ERR_PREAUTH_REQUIRED and is required to derive
 // the key for pre-authentication from the client's password. If a KRBError is not available, pass nil to this argument.
 func (cl *Client) Key(etype etype.EType, kvno int, krberr *messages.KRBError) (types.EncryptionKey, int, error) {
 	if cl.Credentials.HasKeytab() && etype != nil {
 		return cl.Credentials.Keytab().GetEncryptionKey(cl.Credentials.CName(), cl.Credentials.Domain(), kvno, etype.GetETypeID())
 	} else if cl.Credentials.HasPassword() {
+		salt := cl.Credentials.Salt()
 		if krberr != nil && krberr.ErrorCode == errorcode.KDC_ERR_PREAUTH_REQUIRED {
 			var pas types.PADataSequence
 			err := pas.Unmarshal(krberr.EData)
 			if err != nil {
-				return types.EncryptionKey{}, 0, fmt.Errorf("could not get PAData from KRBError to generate key from password: %v", err)
+				return types.EncryptionKey{}, 0, fmt.Errorf("could not get PAData from KRBError to generate key from password: %w", err)
 			}
-			key, _, err := crypto.GetKeyFromPassword(cl.Credentials.Password(), krberr.CName, krberr.CRealm, etype.GetETypeID(), pas)
+			if salt == "" {
+				salt = krberr.CName.GetSalt(krberr.CRealm)
+			}
+			key, _, err := crypto.GetKeyFromPasswordSalt(cl.Credentials.Password(), salt, etype.GetETypeID(), pas)
 			return key, 0, err
 		}
-		key, _, err := crypto.GetKeyFromPassword(cl.Credentials.Password(), cl.Credentials.CName(), cl.Credentials.Domain(), etype.GetETypeID(), types.PADataSequence{})
+		if salt == "" {
+			salt = cl.Credentials.CName().GetSalt(cl.Credentials.Domain())
+		}
+		key, _, err := crypto.GetKeyFromPasswordSalt(cl.Credentials.Password(), salt, etype.GetETypeID(), types.PADataSequence{})
 		return key, 0, err
+	} else if kt := cl.settings.ClientKeytab(); kt != nil && etype != nil {
+		return kt.GetEncryptionKey(cl.Credentials.CName(), cl.Credentials.Domain(), kvno, etype.GetETypeID())
 	}
 	return types.EncryptionKey{}, 0, errors.New("credential has neither keytab or password to generate key")
 }
 
+// loginCredentials returns the credentials the client logs in with: its own or, if it has neither a password nor a
+// keytab, those of its principal with the client keytab configured.
+func (cl *Client) loginCredentials() *credentials.Credentials {
+	kt := cl.settings.ClientKeytab()
+	if kt == nil || cl.Credentials.HasPassword() || cl.Credentials.HasKeytab() {
+		return cl.Credentials
+	}
+	return credentials.NewFromPrincipalName(cl.Credentials.CName(), cl.Credentials.Domain()).WithKeytab(kt)
+}
+
 // IsConfigured indicates if the client has the values required set.
 func (cl *Client) IsConfigured() (bool, error) {
 	if cl.Credentials.UserName() == "" {
@@ -144,7 +208,7 @@ func (cl *Client) IsConfigured() (bool, error) {
 		return false, errors.New("client does not have a define realm")
 	}
 	// Client needs to have either a password, keytab or a session already (later when loading from CCache)
-	if !cl.Credentials.HasPassword() && !cl.Credentials.HasKeytab() {
+	if !cl.Credentials.HasPassword() && !cl.Credentials.HasKeytab() && cl.settings.ClientKeytab() == nil {
 		authTime, _, _, _, err := cl.sessionTimes(cl.Credentials.Domain())
 		if err != nil || authTime.IsZero() {
 			return false, errors.New("client has neither a keytab nor a password set and no session")
@@ -152,7 +216,7 @@ func (cl *Client) IsConfigured() (bool, error) {
 	}
 	if !cl.Config.LibDefaults.DNSLookupKDC {
 		for _, r := range cl.Config.Realms {
-			if r.Realm == cl.Credentials.Domain() {
+			if cl.Config.RealmEqual(r.Realm, cl.Credentials.Domain()) {
 				if len(r.KDC) > 0 {
 					return true, nil
 				}
@@ -169,15 +233,19 @@ func (cl *Client) Login() error {
 		return err
 	}
 	if !cl.Credentials.HasPassword() && !cl.Credentials.HasKeytab() {
-		_, endTime, _, _, err := cl.sessionTimes(cl.Credentials.Domain())
-		if err != nil {
-			return krberror.Errorf(err, krberror.KRBMsgError, "no user credentials available and error getting any existing session")
+		_, endTime, _, _, err := cl.sessionTimes(cl.Realm())
+		if err == nil && !cl.Now().After(endTime) {
+			// no credentials but there is a session with tgt already
+			return nil
 		}
-		if time.Now().UTC().After(endTime) {
+		if cl.settings.ClientKeytab() == nil {
+			if err != nil {
+				return krberror.Errorf(err, krberror.KRBMsgError, "no user credentials available and error getting any existing session")
+			}
 			return krberror.New(krberror.KRBMsgError, "cannot login, no user credentials available and no valid existing session")
 		}
-		// no credentials but there is a session with tgt already
-		return nil
+		// acquire initial credentials with the client keytab as there is no valid tgt
+		cl.Log("no valid TGT for %s, acquiring initial credentials with the client keytab", cl.Credentials.CName().PrincipalNameString())
 	}
 	ASReq, err := messages.NewASReqForTGT(cl.Credentials.Domain(), cl.Config, cl.Credentials.CName())
 	if err != nil {
@@ -187,17 +255,77 @@ func (cl *Client) Login() error {
 	if err != nil {
 		return err
 	}
+	cl.cname.set(ASRep.CName, ASRep.CRealm)
 	cl.addSession(ASRep.Ticket, ASRep.DecryptedEncPart)
+	cl.ticketEvent(EventTicketAcquired, ASRep.Ticket, ASRep.DecryptedEncPart.EndTime, ASRep.DecryptedEncPart.RenewTill, nil)
 	return nil
 }
 
+// canonicalName holds the client's name as returned by the KDC, which may be the KDC's canonical form of the name
+// the client logged in with.
+type canonicalName struct {
+	pn    *types.PrincipalName
+	realm string
+	mux   sync.RWMutex
+}
+
+// get returns the name returned by the KDC or nil if the client has not logged in.
+func (n *canonicalName) get() *types.PrincipalName {
+	n.mux.RLock()
+	defer n.mux.RUnlock()
+	return n.pn
+}
+
+// getRealm returns the realm returned by the KDC or an empty string if the client has not logged in.
+func (n *canonicalName) getRealm() string {
+	n.mux.RLock()
+	defer n.mux.RUnlock()
+	return n.realm
+}
+
+// set the name and realm to those provided.
+func (n *canonicalName) set(pn types.PrincipalName, realm string) {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+	n.pn = &pn
+	n.realm = realm
+}
+
+// clear the name so that of the client's credentials is used.
+func (n *canonicalName) clear() {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+	n.pn = nil
+	n.realm = ""
+}
+
+// CName returns the client's principal name as returned by the KDC when the client logged in. This will differ from
+// the name of the client's credentials if the KDC canonicalized it. Before login the name of the credentials is
+// returned.
+func (cl *Client) CName() types.PrincipalName {
+	if pn := cl.cname.get(); pn != nil {
+		return *pn
+	}
+	return cl.Credentials.CName()
+}
+
+// Realm returns the client's realm as returned by the KDC when the client logged in. This will differ from the realm
+// of the client's credentials if the KDC referred the client to another realm, as for an enterprise principal name of
+// a user in a trusted forest. Before login the realm of the credentials is returned.
+func (cl *Client) Realm() string {
+	if r := cl.cname.getRealm(); r != "" {
+		return r
+	}
+	return cl.Credentials.Domain()
+}
+
 // AffirmLogin will only perform an AS exchange with the KDC if the client does not already have a TGT.
 func (cl *Client) AffirmLogin() error {
-	_, endTime, _, _, err := cl.sessionTimes(cl.Credentials.Domain())
-	if err != nil || time.Now().UTC().After(endTime) {
+	_, endTime, _, _, err := cl.sessionTimes(cl.Realm())
+	if err != nil || cl.Now().After(endTime) {
 		err := cl.Login()
 		if err != nil {
-			return fmt.Errorf("could not get valid TGT for client's realm: %v", err)
+			return fmt.Errorf("could not get valid TGT for client's realm: %w", err)
 		}
 	}
 	return nil
@@ -205,17 +333,17 @@ func (cl *Client) AffirmLogin() error {
 
 // realmLogin obtains or renews a TGT and establishes a session for the realm specified.
 func (cl *Client) realmLogin(realm string) error {
-	if realm == cl.Credentials.Domain() {
+	if realm == cl.Realm() {
 		return cl.Login()
 	}
-	_, endTime, _, _, err := cl.sessionTimes(cl.Credentials.Domain())
-	if err != nil || time.Now().UTC().After(endTime) {
+	_, endTime, _, _, err := cl.sessionTimes(cl.Realm())
+	if err != nil || cl.Now().After(endTime) {
 		err := cl.Login()
 		if err != nil {
-			return fmt.Errorf("could not get valid TGT for client's realm: %v", err)
+			return fmt.Errorf("could not get valid TGT for client's realm: %w", err)
 		}
 	}
-	tgt, skey, err := cl.sessionTGT(cl.Credentials.Domain())
+	tgt, skey, err := cl.sessionTGT(cl.Realm())
 	if err != nil {
 		return err
 	}
@@ -225,7 +353,7 @@ func (cl *Client) realmLogin(realm string) error {
 		NameString: []string{"krbtgt", realm},
 	}
 
-	_, tgsRep, err := cl.TGSREQGenerateAndExchange(spn, cl.Credentials.Domain(), tgt, skey, false)
+	_, tgsRep, err := cl.TGSREQGenerateAndExchange(spn, cl.Realm(), tgt, skey, false)
 	if err != nil {
 		return err
 	}
@@ -234,11 +362,14 @@ func (cl *Client) realmLogin(realm string) error {
 	return nil
 }
 
-// Destroy stops the auto-renewal of all sessions and removes the sessions and cache entries from the client.
+// Destroy stops the auto-renewal of all sessions and the prefetching of tickets and removes the sessions and cache
+// entries from the client.
 func (cl *Client) Destroy() {
 	creds := credentials.New("", "")
+	cl.prefetchers.stopAll()
 	cl.sessions.destroy()
 	cl.cache.clear()
+	cl.cname.clear()
 	cl.Credentials = creds
 	cl.Log("client destroyed")
 }
@@ -250,7 +381,7 @@ func (cl *Client) Diagnostics(w io.Writer) error {
 	if cl.Credentials.HasKeytab() {
 		var loginRealmEncTypes []int32
 		for _, e := range cl.Credentials.Keytab().Entries {
-			if e.Principal.Realm == cl.Credentials.Realm() {
+			if cl.Config.RealmEqual(e.Principal.Realm, cl.Credentials.Realm()) {
 				loginRealmEncTypes = append(loginRealmEncTypes, e.Key.KeyType)
 			}
 		}
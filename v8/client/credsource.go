@@ -0,0 +1,212 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+)
+
+// Environment variables consulted by NewFromCredentialSource, as used by MIT Kerberos.
+const (
+	// EnvKRB5Config names the krb5 configuration file.
+	EnvKRB5Config = "KRB5_CONFIG"
+	// EnvKRB5CCName names the default credentials cache.
+	EnvKRB5CCName = "KRB5CCNAME"
+	// EnvKRB5ClientKTName names the client keytab.
+	EnvKRB5ClientKTName = "KRB5_CLIENT_KTNAME"
+)
+
+// Default locations used if no others are specified.
+const (
+	defaultKRB5ConfPath = "/etc/krb5.conf"
+	defaultCCacheFmt    = "/tmp/krb5cc_%d"
+)
+
+// CredentialSource describes where a client's configuration and credentials should be loaded from.
+// Fields that are not set are ignored or defaulted as described by NewFromCredentialSource.
+type CredentialSource struct {
+	// Username of the client principal. Required for password and keytab credentials.
+	Username string
+	// Realm of the client principal. Defaults to the default realm of the configuration.
+	Realm string
+	// Password to authenticate with.
+	Password string
+	// KeytabPath is the path of a keytab to authenticate with.
+	KeytabPath string
+	// CCachePath is the path, or FILE: name, of a credentials cache to take tickets from.
+	CCachePath string
+	// ClientKeytabPath is the path, or FILE: name, of a client keytab to acquire initial credentials with when the
+	// credentials cache holds no valid TGT.
+	ClientKeytabPath string
+	// ConfigPath is the path of the krb5 configuration to use.
+	ConfigPath string
+}
+
+// NewFromCredentialSource creates a new client from the first credential found in the source in the order of
+// preference: password, keytab, credentials cache.
+//
+// If no credential is specified the credentials cache named by the KRB5CCNAME environment variable is used, falling
+// back to the default /tmp/krb5cc_<uid> location as MIT applications do.
+// As with MIT Kerberos, if a client keytab exists at the ClientKeytabPath, that named by the KRB5_CLIENT_KTNAME
+// environment variable or the default_client_keytab_name of the configuration, the client acquires initial
+// credentials with it on demand when the credentials cache holds no valid TGT. If there is no credentials cache the
+// client authenticates as the Username, or the principal of the first entry of the client keytab.
+// The configuration is loaded from the ConfigPath, or that named by the KRB5_CONFIG environment variable, or
+// /etc/krb5.conf. If none of these exist a default configuration is used.
+//
+// cl, err := NewFromCredentialSource(CredentialSource{Username: "user", KeytabPath: "/etc/user.keytab"})
+func NewFromCredentialSource(src CredentialSource, settings ...func(*Settings)) (*Client, error) {
+	cfg, err := loadSourceConfig(src.ConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	realm := src.Realm
+	if realm == "" {
+		realm = cfg.LibDefaults.DefaultRealm
+	}
+	switch {
+	case src.Password != "":
+		if src.Username == "" {
+			return nil, errors.New("a username is required to authenticate with a password")
+		}
+		return NewWithPassword(src.Username, realm, src.Password, cfg, settings...), nil
+	case src.KeytabPath != "":
+		if src.Username == "" {
+			return nil, errors.New("a username is required to authenticate with a keytab")
+		}
+		kt, err := keytab.Load(src.KeytabPath)
+		if err != nil {
+			return nil, fmt.Errorf("could not load keytab %s: %w", src.KeytabPath, err)
+		}
+		return NewWithKeytab(src.Username, realm, kt, cfg, settings...), nil
+	}
+	name := src.CCachePath
+	if name == "" {
+		name = os.Getenv(EnvKRB5CCName)
+	}
+	if name == "" {
+		name = fmt.Sprintf(defaultCCacheFmt, os.Getuid())
+	}
+	p, err := ccachePath(name)
+	if err != nil {
+		return nil, err
+	}
+	ckt, err := loadClientKeytab(src.ClientKeytabPath, cfg)
+	if err != nil {
+		return nil, err
+	}
+	c, err := credentials.LoadCCache(p)
+	if err != nil {
+		if ckt == nil {
+			return nil, fmt.Errorf("could not load credentials cache %s: %w", p, err)
+		}
+		return newWithClientKeytab(src, ckt, cfg, settings...)
+	}
+	if ckt != nil {
+		settings = append(settings, ClientKeytab(ckt))
+	}
+	return NewFromCCache(c, cfg, settings...)
+}
+
+// newWithClientKeytab creates a client that acquires initial credentials with the client keytab, authenticating as
+// the username of the source or the principal of the keytab's first entry.
+func newWithClientKeytab(src CredentialSource, kt *keytab.Keytab, cfg *config.Config, settings ...func(*Settings)) (*Client, error) {
+	username, realm := src.Username, src.Realm
+	if username == "" {
+		if len(kt.Entries) < 1 {
+			return nil, errors.New("client keytab has no entries")
+		}
+		username = strings.Join(kt.Entries[0].Principal.Components, "/")
+		if realm == "" {
+			realm = kt.Entries[0].Principal.Realm
+		}
+	}
+	if realm == "" {
+		realm = cfg.LibDefaults.DefaultRealm
+	}
+	return NewWithKeytab(username, realm, kt, cfg, settings...), nil
+}
+
+// loadClientKeytab loads the client keytab from the path provided, that named by the KRB5_CLIENT_KTNAME environment
+// variable or the default_client_keytab_name of the configuration in that order. Nil is returned if the keytab does
+// not exist, as client keytabs are optional.
+func loadClientKeytab(p string, cfg *config.Config) (*keytab.Keytab, error) {
+	if p == "" {
+		p = os.Getenv(EnvKRB5ClientKTName)
+	}
+	if p == "" {
+		p = cfg.LibDefaults.DefaultClientKeytabName
+	}
+	if p == "" {
+		return nil, nil
+	}
+	p, err := keytabPath(p)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(p); err != nil {
+		return nil, nil
+	}
+	kt, err := keytab.Load(p)
+	if err != nil {
+		return nil, fmt.Errorf("could not load client keytab %s: %w", p, err)
+	}
+	return kt, nil
+}
+
+// keytabPath returns the file path of the keytab name provided, expanding the %{uid} and %{euid} tokens MIT Kerberos
+// supports in default_client_keytab_name. Only file based keytabs are supported.
+func keytabPath(name string) (string, error) {
+	name = strings.NewReplacer(
+		"%{uid}", strconv.Itoa(os.Getuid()),
+		"%{euid}", strconv.Itoa(os.Geteuid()),
+	).Replace(name)
+	i := strings.Index(name, ":")
+	if i < 0 || filepath.VolumeName(name) != "" {
+		return name, nil
+	}
+	if t := name[:i]; t != "FILE" {
+		return "", fmt.Errorf("keytab type %s is not supported", t)
+	}
+	return name[i+1:], nil
+}
+
+// loadSourceConfig loads the krb5 configuration from the path provided, that named by the KRB5_CONFIG environment
+// variable or the default location in that order.
+func loadSourceConfig(p string) (*config.Config, error) {
+	if p == "" {
+		// KRB5_CONFIG may list several files, the first is used
+		p = strings.Split(os.Getenv(EnvKRB5Config), string(os.PathListSeparator))[0]
+	}
+	if p == "" {
+		if _, err := os.Stat(defaultKRB5ConfPath); err != nil {
+			return config.New(), nil
+		}
+		p = defaultKRB5ConfPath
+	}
+	cfg, err := config.Load(p)
+	if err != nil {
+		return nil, fmt.Errorf("could not load krb5 configuration %s: %w", p, err)
+	}
+	return cfg, nil
+}
+
+// ccachePath returns the file path of the credentials cache name provided.
+// Only file based credentials caches are supported.
+func ccachePath(name string) (string, error) {
+	i := strings.Index(name, ":")
+	if i < 0 || filepath.VolumeName(name) != "" {
+		return name, nil
+	}
+	if t := name[:i]; t != "FILE" {
+		return "", fmt.Errorf("credentials cache type %s is not supported", t)
+	}
+	return name[i+1:], nil
+}
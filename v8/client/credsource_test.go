@@ -0,0 +1,119 @@
+package client
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jcmturner/gokrb5/v8/test/testdata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestFile(t *testing.T, dir, name string, b []byte) string {
+	p := filepath.Join(dir, name)
+	err := ioutil.WriteFile(p, b, 0600)
+	require.NoError(t, err, "error writing test file")
+	return p
+}
+
+func TestNewFromCredentialSource(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "gokrb5-credsource")
+	require.NoError(t, err, "error creating temp dir")
+	defer os.RemoveAll(dir)
+	cfgPath := writeTestFile(t, dir, "krb5.conf", []byte(testdata.KRB5_CONF))
+	b, _ := hex.DecodeString(testdata.KEYTAB_TESTUSER1_TEST_GOKRB5)
+	ktPath := writeTestFile(t, dir, "user.keytab", b)
+	b, _ = hex.DecodeString(testdata.CCACHE_TEST)
+	ccPath := writeTestFile(t, dir, "krb5cc", b)
+
+	cl, err := NewFromCredentialSource(CredentialSource{
+		Username:   "testuser1",
+		Password:   "passwordvalue",
+		KeytabPath: ktPath,
+		ConfigPath: cfgPath,
+	})
+	require.NoError(t, err, "error creating client with password")
+	assert.True(t, cl.Credentials.HasPassword(), "password should take precedence")
+	assert.Equal(t, "TEST.GOKRB5", cl.Credentials.Domain(), "realm should default from the configuration")
+
+	cl, err = NewFromCredentialSource(CredentialSource{
+		Username:   "testuser1",
+		KeytabPath: ktPath,
+		CCachePath: ccPath,
+		ConfigPath: cfgPath,
+	})
+	require.NoError(t, err, "error creating client with keytab")
+	assert.True(t, cl.Credentials.HasKeytab(), "keytab should take precedence over the credentials cache")
+
+	for _, name := range []string{ccPath, "FILE:" + ccPath} {
+		cl, err = NewFromCredentialSource(CredentialSource{CCachePath: name, ConfigPath: cfgPath})
+		require.NoError(t, err, "error creating client with credentials cache %s", name)
+		assert.Equal(t, "testuser1", cl.Credentials.UserName(), "username from credentials cache not as expected")
+	}
+
+	cl, err = NewFromCredentialSource(CredentialSource{CCachePath: ccPath, ClientKeytabPath: "FILE:" + ktPath, ConfigPath: cfgPath})
+	require.NoError(t, err, "error creating client with credentials cache and client keytab")
+	assert.NotNil(t, cl.settings.ClientKeytab(), "client keytab should be configured")
+	assert.False(t, cl.Credentials.HasKeytab(), "credentials cache should take precedence over the client keytab")
+
+	cl, err = NewFromCredentialSource(CredentialSource{
+		CCachePath:       filepath.Join(dir, "missing"),
+		ClientKeytabPath: ktPath,
+		ConfigPath:       cfgPath,
+	})
+	require.NoError(t, err, "error creating client with client keytab")
+	assert.True(t, cl.Credentials.HasKeytab(), "client keytab should be used without a credentials cache")
+	assert.Equal(t, "testuser1", cl.Credentials.UserName(), "username should be taken from the client keytab")
+	assert.Equal(t, "TEST.GOKRB5", cl.Credentials.Domain(), "realm should be taken from the client keytab")
+
+	_, err = NewFromCredentialSource(CredentialSource{KeytabPath: ktPath, ConfigPath: cfgPath})
+	assert.Error(t, err, "a keytab without a username should be rejected")
+	_, err = NewFromCredentialSource(CredentialSource{CCachePath: "KEYRING:persistent:1000", ConfigPath: cfgPath})
+	assert.Error(t, err, "unsupported credentials cache types should be rejected")
+	_, err = NewFromCredentialSource(CredentialSource{CCachePath: filepath.Join(dir, "missing"), ConfigPath: cfgPath})
+	assert.Error(t, err, "a missing credentials cache should be rejected")
+	_, err = NewFromCredentialSource(CredentialSource{
+		CCachePath:       filepath.Join(dir, "missing"),
+		ClientKeytabPath: filepath.Join(dir, "missing.keytab"),
+		ConfigPath:       cfgPath,
+	})
+	assert.Error(t, err, "a missing credentials cache should be rejected when the client keytab does not exist")
+}
+
+func TestKeytabPath(t *testing.T) {
+	t.Parallel()
+	p, err := keytabPath("FILE:/var/krb5/user/%{euid}/client.keytab")
+	require.NoError(t, err, "error getting keytab path")
+	assert.Equal(t, fmt.Sprintf("/var/krb5/user/%d/client.keytab", os.Geteuid()), p, "keytab path not as expected")
+	_, err = keytabPath("MEMORY:client")
+	assert.Error(t, err, "unsupported keytab types should be rejected")
+}
+
+func TestNewFromCredentialSource_Environment(t *testing.T) {
+	// Not parallel as the environment is modified
+	dir, err := ioutil.TempDir("", "gokrb5-credsource")
+	require.NoError(t, err, "error creating temp dir")
+	defer os.RemoveAll(dir)
+	cfgPath := writeTestFile(t, dir, "krb5.conf", []byte(testdata.KRB5_CONF))
+	b, _ := hex.DecodeString(testdata.CCACHE_TEST)
+	ccPath := writeTestFile(t, dir, "krb5cc", b)
+
+	for k, v := range map[string]string{EnvKRB5Config: cfgPath, EnvKRB5CCName: "FILE:" + ccPath} {
+		old, set := os.LookupEnv(k)
+		os.Setenv(k, v)
+		if set {
+			defer os.Setenv(k, old)
+		} else {
+			defer os.Unsetenv(k)
+		}
+	}
+	cl, err := NewFromCredentialSource(CredentialSource{})
+	require.NoError(t, err, "error creating client from the environment")
+	assert.Equal(t, "testuser1", cl.Credentials.UserName(), "username from credentials cache not as expected")
+	assert.Equal(t, "TEST.GOKRB5", cl.Config.LibDefaults.DefaultRealm, "configuration not loaded from the environment")
+}
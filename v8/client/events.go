@@ -0,0 +1,137 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/messages"
+)
+
+// EventType identifies a change in the health of the client's credentials.
+type EventType int
+
+// Credential event types.
+const (
+	// EventTicketAcquired is reported when a TGT is obtained by logging in or a ticket is obtained from a KDC.
+	EventTicketAcquired EventType = iota + 1
+	// EventTicketRenewed is reported when a TGT or service ticket is renewed.
+	EventTicketRenewed
+	// EventRenewalFailed is reported when a TGT or service ticket cannot be renewed, or a TGT that could not be
+	// renewed cannot be replaced by logging in again.
+	EventRenewalFailed
+	// EventPasswordChanged is reported when the client's password has been changed.
+	EventPasswordChanged
+)
+
+// String returns the name of the event type.
+func (t EventType) String() string {
+	switch t {
+	case EventTicketAcquired:
+		return "ticket_acquired"
+	case EventTicketRenewed:
+		return "ticket_renewed"
+	case EventRenewalFailed:
+		return "renewal_failed"
+	case EventPasswordChanged:
+		return "password_changed"
+	}
+	return fmt.Sprintf("unknown(%d)", int(t))
+}
+
+// MarshalJSON encodes the event type as its name.
+func (t EventType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// Event is a change in the health of the client's credentials reported to EventHooks. It holds no keys or tickets.
+type Event struct {
+	Type EventType `json:"type"`
+	Time time.Time `json:"time"`
+	// CName and Realm are the client's principal name and realm.
+	CName string `json:"cname"`
+	Realm string `json:"realm"`
+	// SName and SRealm are the server principal of the ticket for ticket events.
+	SName     string    `json:"sname,omitempty"`
+	SRealm    string    `json:"srealm,omitempty"`
+	EndTime   time.Time `json:"end_time"`
+	RenewTill time.Time `json:"renew_till"`
+	// Err is the reason for a failure.
+	Err error `json:"-"`
+}
+
+// MarshalJSON encodes the event with any error as its message.
+func (e Event) MarshalJSON() ([]byte, error) {
+	type event Event
+	j := struct {
+		event
+		Error string `json:"error,omitempty"`
+	}{event: event(e)}
+	if e.Err != nil {
+		j.Error = e.Err.Error()
+	}
+	return json.Marshal(j)
+}
+
+// EventHook is called with the credential events of the client. Hooks are called synchronously from the goroutine
+// that caused the event, including the client's background renewal, so must not block.
+type EventHook func(Event)
+
+// Webhook returns an EventHook that POSTs each event as JSON to the URL provided, for integration with systems that
+// track the health of credentials across a fleet. Events are sent from a new goroutine so that the client is not
+// delayed by the receiver. If the http.Client is nil http.DefaultClient is used. Failures to deliver an event are
+// passed to onError if it is not nil.
+func Webhook(url string, hc *http.Client, onError func(Event, error)) EventHook {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	return func(e Event) {
+		b, err := json.Marshal(e)
+		if err != nil {
+			if onError != nil {
+				onError(e, err)
+			}
+			return
+		}
+		go func() {
+			resp, err := hc.Post(url, "application/json", bytes.NewReader(b))
+			if err == nil {
+				resp.Body.Close()
+				if resp.StatusCode < 200 || resp.StatusCode > 299 {
+					err = fmt.Errorf("webhook %s returned %s", url, resp.Status)
+				}
+			}
+			if err != nil && onError != nil {
+				onError(e, err)
+			}
+		}()
+	}
+}
+
+// event reports an event to the hooks configured.
+func (cl *Client) event(e Event) {
+	hooks := cl.settings.EventHooks()
+	if len(hooks) < 1 {
+		return
+	}
+	e.Time = cl.Now()
+	e.CName = cl.CName().PrincipalNameString()
+	e.Realm = cl.Realm()
+	for _, h := range hooks {
+		h(e)
+	}
+}
+
+// ticketEvent reports an event for the ticket provided, which expires and can be renewed until the times provided.
+func (cl *Client) ticketEvent(t EventType, tkt messages.Ticket, endTime, renewTill time.Time, err error) {
+	cl.event(Event{
+		Type:      t,
+		SName:     tkt.SName.PrincipalNameString(),
+		SRealm:    tkt.Realm,
+		EndTime:   endTime,
+		RenewTill: renewTill,
+		Err:       err,
+	})
+}
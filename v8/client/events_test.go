@@ -0,0 +1,104 @@
+package client
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/test/testdata"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingTransport struct{}
+
+func (failingTransport) SendToKDC(realm string, b []byte) ([]byte, error) {
+	return nil, errors.New("KDC unreachable")
+}
+
+func TestClient_renewTicketEvent(t *testing.T) {
+	t.Parallel()
+	b, _ := hex.DecodeString(testdata.KEYTAB_TESTUSER1_TEST_GOKRB5)
+	kt := keytab.New()
+	require.NoError(t, kt.Unmarshal(b), "error unmarshaling keytab")
+	c, _ := config.NewFromString(testdata.KRB5_CONF)
+	var events []Event
+	cl := NewWithKeytab("testuser1", "TEST.GOKRB5", kt, c, KDCTransport(failingTransport{}), EventHooks(func(e Event) {
+		events = append(events, e)
+	}))
+	end := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	e := CacheEntry{
+		SPN: "HTTP/host.test.gokrb5",
+		Ticket: messages.Ticket{
+			Realm: "TEST.GOKRB5",
+			SName: types.NewPrincipalName(1, "HTTP/host.test.gokrb5"),
+		},
+		EndTime:    end,
+		RenewTill:  end.Add(time.Hour),
+		SessionKey: types.EncryptionKey{KeyType: etypeID.AES256_CTS_HMAC_SHA1_96, KeyValue: make([]byte, 32)},
+	}
+	_, err := cl.renewTicket(e)
+	require.Error(t, err, "renewal should fail")
+	require.Len(t, events, 1, "renewal failure not reported")
+	ev := events[0]
+	assert.Equal(t, EventRenewalFailed, ev.Type, "event type not as expected")
+	assert.Equal(t, "testuser1", ev.CName, "client name not as expected")
+	assert.Equal(t, "TEST.GOKRB5", ev.Realm, "client realm not as expected")
+	assert.Equal(t, "HTTP/host.test.gokrb5", ev.SName, "server name not as expected")
+	assert.Equal(t, end, ev.EndTime, "end time not as expected")
+	assert.Error(t, ev.Err, "failure not included in the event")
+}
+
+func TestWebhook(t *testing.T) {
+	t.Parallel()
+	bodies := make(chan []byte, 1)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"), "content type not as expected")
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies <- b
+	}))
+	defer s.Close()
+
+	Webhook(s.URL, nil, func(e Event, err error) {
+		t.Errorf("webhook delivery failed: %v", err)
+	})(Event{
+		Type:  EventRenewalFailed,
+		CName: "testuser1",
+		Realm: "TEST.GOKRB5",
+		SName: "krbtgt/TEST.GOKRB5",
+		Err:   errors.New("KDC unreachable"),
+	})
+	var j map[string]interface{}
+	select {
+	case b := <-bodies:
+		require.NoError(t, json.Unmarshal(b, &j), "event is not valid JSON")
+	case <-time.After(5 * time.Second):
+		t.Fatal("event not delivered")
+	}
+	assert.Equal(t, "renewal_failed", j["type"], "type not as expected")
+	assert.Equal(t, "testuser1", j["cname"], "client name not as expected")
+	assert.Equal(t, "krbtgt/TEST.GOKRB5", j["sname"], "server name not as expected")
+	assert.Equal(t, "KDC unreachable", j["error"], "error not as expected")
+
+	failed := make(chan error, 1)
+	s.Close()
+	Webhook(s.URL, nil, func(e Event, err error) {
+		failed <- err
+	})(Event{Type: EventPasswordChanged})
+	select {
+	case err := <-failed:
+		assert.Error(t, err, "delivery failure not reported")
+	case <-time.After(5 * time.Second):
+		t.Fatal("delivery failure not reported")
+	}
+}
@@ -0,0 +1,37 @@
+package client
+
+import (
+	"fmt"
+)
+
+// RequestHook is called with the marshalled bytes of each request before it is sent to a KDC of the realm.
+// The bytes returned are sent in place of the request, allowing it to be inspected or modified. If an error is
+// returned the request is not sent and the exchange fails with the error.
+type RequestHook func(realm string, b []byte) ([]byte, error)
+
+// ReplyHook is called with the bytes of a request sent to a KDC of the realm and the raw bytes of the reply received
+// before the reply is processed. Hooks are called with KRB_ERROR replies as well as successful replies and must not
+// modify the bytes.
+type ReplyHook func(realm string, req, rep []byte)
+
+// requestHooks passes the request through the request hooks configured.
+func (cl *Client) requestHooks(realm string, b []byte) ([]byte, error) {
+	for _, h := range cl.settings.KDCRequestHooks() {
+		var err error
+		b, err = h(realm, b)
+		if err != nil {
+			return nil, fmt.Errorf("request to %s rejected by hook: %w", realm, err)
+		}
+	}
+	return b, nil
+}
+
+// replyHooks passes a reply to the reply hooks configured.
+func (cl *Client) replyHooks(realm string, req, rep []byte) {
+	if len(rep) < 1 {
+		return
+	}
+	for _, h := range cl.settings.KDCReplyHooks() {
+		h(realm, req, rep)
+	}
+}
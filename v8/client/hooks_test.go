@@ -0,0 +1,59 @@
+package client
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type hookTransport struct {
+	req []byte
+	rep []byte
+}
+
+func (t *hookTransport) SendToKDC(realm string, b []byte) ([]byte, error) {
+	t.req = b
+	return t.rep, nil
+}
+
+func TestClient_sendToKDCHooks(t *testing.T) {
+	t.Parallel()
+	tr := &hookTransport{rep: []byte("reply")}
+	var calls []string
+	var seen []byte
+	cl := &Client{settings: NewSettings(
+		KDCTransport(tr),
+		KDCRequestHooks(
+			func(realm string, b []byte) ([]byte, error) {
+				calls = append(calls, "first")
+				return append(b, '1'), nil
+			},
+			func(realm string, b []byte) ([]byte, error) {
+				calls = append(calls, "second")
+				return append(b, '2'), nil
+			},
+		),
+		KDCReplyHooks(func(realm string, req, rep []byte) {
+			assert.Equal(t, "TEST.GOKRB5", realm, "realm not as expected")
+			assert.Equal(t, []byte("request12"), req, "request passed to reply hook not that sent")
+			seen = rep
+		}),
+	)}
+	rb, err := cl.sendToKDC([]byte("request"), "TEST.GOKRB5")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first", "second"}, calls, "request hooks not called in order")
+	assert.Equal(t, []byte("request12"), tr.req, "request not modified by the hooks")
+	assert.Equal(t, []byte("reply"), rb, "reply not as expected")
+	assert.Equal(t, []byte("reply"), seen, "reply hook not called with the reply")
+
+	hookErr := errors.New("rejected")
+	tr = &hookTransport{}
+	cl = &Client{settings: NewSettings(KDCTransport(tr), KDCRequestHooks(func(realm string, b []byte) ([]byte, error) {
+		return nil, hookErr
+	}))}
+	_, err = cl.sendToKDC([]byte("request"), "TEST.GOKRB5")
+	assert.True(t, errors.Is(err, hookErr), "error of the request hook not returned: %v", err)
+	assert.Nil(t, tr.req, "request should not be sent when a hook returns an error")
+}
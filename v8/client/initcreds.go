@@ -0,0 +1,139 @@
+package client
+
+import (
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/iana/flags"
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/jcmturner/gokrb5/v8/krberror"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// InitCredsOptions overrides the values from the client's configuration and settings used to request initial
+// credentials in an AS exchange, in the manner of libkrb5's krb5_get_init_creds_opt. The zero value of each field
+// leaves the configured value in place.
+type InitCredsOptions struct {
+	// TicketLifetime is the lifetime requested for the ticket.
+	TicketLifetime time.Duration
+	// RenewLifetime is the renewable lifetime requested for the ticket. A negative value requests a ticket that is
+	// not renewable.
+	RenewLifetime time.Duration
+	// Forwardable, Proxiable and Canonicalize set the KDC options of the same names if not nil.
+	Forwardable  *bool
+	Proxiable    *bool
+	Canonicalize *bool
+	// Addresses are the host addresses the ticket is requested for in place of those of the local host.
+	Addresses types.HostAddresses
+	// NoAddresses requests a ticket without host addresses.
+	NoAddresses bool
+	// ETypes are the encryption types requested, in order of preference.
+	ETypes []int32
+	// Salt overrides the salt keys are derived from the password with when the KDC does not provide one.
+	// It is set on the client's credentials so is also used by later logins.
+	Salt string
+	// DisablePAFXFAST overrides the client's DisablePAFXFAST setting if not nil.
+	DisablePAFXFAST *bool
+	// StartTime requests a postdated ticket valid from the time provided. See GetPostdatedTGT.
+	StartTime time.Time
+	// Service is the SPN of the service the initial ticket is requested for, such as kadmin/changepw, in place of
+	// the TGS of the client's realm.
+	Service string
+}
+
+// GetInitCreds performs an AS exchange with the options provided returning the AS_REP, the encrypted part of which
+// has been decrypted. The ticket obtained is not added to the client's sessions, see LoginWithOptions.
+func (cl *Client) GetInitCreds(opts InitCredsOptions) (messages.ASRep, error) {
+	ASReq, err := cl.initCredsASReq(opts)
+	if err != nil {
+		return messages.ASRep{}, krberror.Errorf(err, krberror.KRBMsgError, "error generating new AS_REQ")
+	}
+	if opts.Salt != "" {
+		cl.Credentials.WithSalt(opts.Salt)
+	}
+	fast := !cl.settings.DisablePAFXFAST()
+	if opts.DisablePAFXFAST != nil {
+		fast = !*opts.DisablePAFXFAST
+	}
+	return cl.asExchange(cl.Credentials.Domain(), ASReq, 0, fast)
+}
+
+// LoginWithOptions logs the client in with the KDC via an AS exchange with the options provided.
+// The options are used for this login only, the TGT being renewed, or the client logging in again when it cannot be,
+// with the client's configuration.
+func (cl *Client) LoginWithOptions(opts InitCredsOptions) error {
+	if ok, err := cl.IsConfigured(); !ok {
+		return err
+	}
+	ASRep, err := cl.GetInitCreds(opts)
+	if err != nil {
+		return err
+	}
+	cl.cname.set(ASRep.CName, ASRep.CRealm)
+	cl.addSession(ASRep.Ticket, ASRep.DecryptedEncPart)
+	cl.ticketEvent(EventTicketAcquired, ASRep.Ticket, ASRep.DecryptedEncPart.EndTime, ASRep.DecryptedEncPart.RenewTill, nil)
+	return nil
+}
+
+// initCredsASReq returns the AS_REQ for the options provided.
+func (cl *Client) initCredsASReq(opts InitCredsOptions) (messages.ASReq, error) {
+	realm := cl.Credentials.Domain()
+	sname := types.PrincipalName{
+		NameType:   nametype.KRB_NT_SRV_INST,
+		NameString: []string{"krbtgt", realm},
+	}
+	if opts.Service != "" {
+		sname = types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, opts.Service)
+	}
+	ASReq, err := messages.NewASReq(realm, cl.Config, cl.Credentials.CName(), sname)
+	if err != nil {
+		return ASReq, err
+	}
+	b := &ASReq.ReqBody
+	t := cl.Now()
+	if opts.TicketLifetime > 0 {
+		b.Till = t.Add(opts.TicketLifetime)
+	}
+	switch {
+	case opts.RenewLifetime > 0:
+		types.SetFlag(&b.KDCOptions, flags.Renewable)
+		b.RTime = t.Add(opts.RenewLifetime)
+	case opts.RenewLifetime < 0:
+		types.UnsetFlag(&b.KDCOptions, flags.Renewable)
+		b.RTime = time.Time{}
+	}
+	for i, o := range map[int]*bool{
+		flags.Forwardable:  opts.Forwardable,
+		flags.Proxiable:    opts.Proxiable,
+		flags.Canonicalize: opts.Canonicalize,
+	} {
+		if o == nil {
+			continue
+		}
+		if *o {
+			types.SetFlag(&b.KDCOptions, i)
+		} else {
+			types.UnsetFlag(&b.KDCOptions, i)
+		}
+	}
+	switch {
+	case opts.NoAddresses:
+		b.Addresses = nil
+	case opts.Addresses != nil:
+		b.Addresses = opts.Addresses
+	}
+	if len(opts.ETypes) > 0 {
+		b.EType = opts.ETypes
+	}
+	if !opts.StartTime.IsZero() {
+		// Postdate the request keeping the lifetimes requested
+		d := opts.StartTime.Sub(t)
+		types.SetFlag(&b.KDCOptions, flags.PostDated)
+		b.From = opts.StartTime.UTC()
+		b.Till = b.Till.Add(d)
+		if !b.RTime.IsZero() {
+			b.RTime = b.RTime.Add(d)
+		}
+	}
+	return ASReq, nil
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
+	"github.com/jcmturner/gokrb5/v8/iana/flags"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_initCredsASReq(t *testing.T) {
+	t.Parallel()
+	c := config.New()
+	c.LibDefaults.DefaultRealm = "TEST.GOKRB5"
+	c.LibDefaults.Forwardable = true
+	c.LibDefaults.RenewLifetime = 48 * time.Hour
+	cl := NewWithPassword("testuser1", "TEST.GOKRB5", "passwordvalue", c)
+
+	f, p := false, true
+	start := time.Now().UTC().Add(time.Hour)
+	req, err := cl.initCredsASReq(InitCredsOptions{
+		TicketLifetime: 2 * time.Hour,
+		RenewLifetime:  -1,
+		Forwardable:    &f,
+		Proxiable:      &p,
+		NoAddresses:    true,
+		ETypes:         []int32{etypeID.AES256_CTS_HMAC_SHA1_96},
+		StartTime:      start,
+		Service:        "kadmin/changepw",
+	})
+	require.NoError(t, err, "error creating AS_REQ")
+	b := req.ReqBody
+	assert.False(t, types.IsFlagSet(&b.KDCOptions, flags.Forwardable), "forwardable option should be unset")
+	assert.True(t, types.IsFlagSet(&b.KDCOptions, flags.Proxiable), "proxiable option should be set")
+	assert.False(t, types.IsFlagSet(&b.KDCOptions, flags.Renewable), "renewable option should be unset")
+	assert.True(t, b.RTime.IsZero(), "renew time should not be requested")
+	assert.True(t, types.IsFlagSet(&b.KDCOptions, flags.PostDated), "postdated option should be set")
+	assert.Equal(t, start, b.From, "start time not as expected")
+	assert.WithinDuration(t, start.Add(2*time.Hour), b.Till, time.Second, "till time not as expected")
+	assert.Nil(t, b.Addresses, "addresses should not be requested")
+	assert.Equal(t, []int32{etypeID.AES256_CTS_HMAC_SHA1_96}, b.EType, "etypes not as expected")
+	assert.Equal(t, "kadmin/changepw", b.SName.PrincipalNameString(), "service not as expected")
+
+	req, err = cl.initCredsASReq(InitCredsOptions{})
+	require.NoError(t, err, "error creating AS_REQ")
+	b = req.ReqBody
+	assert.True(t, types.IsFlagSet(&b.KDCOptions, flags.Forwardable), "configured forwardable option should be set")
+	assert.True(t, types.IsFlagSet(&b.KDCOptions, flags.Renewable), "configured renewable option should be set")
+	assert.Equal(t, "krbtgt/TEST.GOKRB5", b.SName.PrincipalNameString(), "service should be the TGS")
+}
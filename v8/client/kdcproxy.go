@@ -0,0 +1,149 @@
+package client
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/asn1"
+)
+
+const (
+	// kdcProxyContentType is the media type of the messages exchanged with a KDC proxy. MS-KKDCP section 2.1
+	kdcProxyContentType = "application/kerberos"
+	// kdcProxyTimeout is how long a request to a KDC proxy may take.
+	kdcProxyTimeout = 10 * time.Second
+	// kdcProxyMaxReply is the maximum size in bytes of a KDC proxy's reply.
+	kdcProxyMaxReply = 1 << 20
+)
+
+// KDCProxy is a Transport sending requests to the KDCs of each realm through a KDC proxy over HTTPS, as defined by
+// MS-KKDCP, for clients that cannot reach the KDCs directly. The TLS configuration used to connect to the proxy can be
+// set for each realm, as enterprise proxies typically have certificates issued by a private PKI or require client
+// certificates. It is safe for concurrent use.
+//
+// cl := NewWithPassword(username, realm, password, cfg, KDCTransport(NewKDCProxy(urls, ProxyTLSConfig(realm, tc))))
+type KDCProxy struct {
+	urls    map[string]string
+	tls     map[string]*tls.Config
+	clients map[string]*http.Client
+	mux     sync.Mutex
+}
+
+// kdcProxyMessage is the KDC-PROXY-MESSAGE wrapping the requests to and replies from a KDC proxy. The Kerberos message
+// is preceded by its length as it is over TCP. MS-KKDCP section 2.2.2
+type kdcProxyMessage struct {
+	KerbMessage   []byte `asn1:"explicit,tag:0"`
+	TargetDomain  string `asn1:"optional,generalstring,explicit,tag:1"`
+	DCLocatorHint int    `asn1:"optional,explicit,tag:2"`
+}
+
+// NewKDCProxy returns a KDCProxy sending the requests for each realm to the URL of its proxy, such as
+// https://proxy.example.com/KdcProxy. The URL for the empty realm is used for realms without a URL of their own.
+func NewKDCProxy(urls map[string]string, options ...func(*KDCProxy)) *KDCProxy {
+	p := &KDCProxy{
+		urls:    make(map[string]string, len(urls)),
+		tls:     make(map[string]*tls.Config),
+		clients: make(map[string]*http.Client),
+	}
+	for r, u := range urls {
+		p.urls[r] = u
+	}
+	for _, o := range options {
+		o(p)
+	}
+	return p
+}
+
+// ProxyTLSConfig used to configure the KDCProxy with the TLS configuration used to connect to the proxy of the realm,
+// such as to trust the root CAs of a private PKI, present a client certificate or require a later TLS version. The
+// configuration for the empty realm is used for realms without a configuration of their own, and the system's root
+// CAs otherwise. TLS 1.2 is required if no minimum version is configured.
+//
+// p := NewKDCProxy(urls, ProxyTLSConfig(realm, &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS13}))
+func ProxyTLSConfig(realm string, c *tls.Config) func(*KDCProxy) {
+	return func(p *KDCProxy) {
+		p.tls[realm] = c
+	}
+}
+
+// SendToKDC sends the request through the KDC proxy of the realm and returns the KDC's reply.
+func (p *KDCProxy) SendToKDC(realm string, b []byte) ([]byte, error) {
+	u, ok := p.urls[realm]
+	if !ok {
+		if u, ok = p.urls[""]; !ok {
+			return nil, fmt.Errorf("no KDC proxy configured for realm %s", realm)
+		}
+	}
+	m := kdcProxyMessage{
+		KerbMessage:  make([]byte, 4, 4+len(b)),
+		TargetDomain: realm,
+	}
+	binary.BigEndian.PutUint32(m.KerbMessage, uint32(len(b)))
+	m.KerbMessage = append(m.KerbMessage, b...)
+	mb, err := asn1.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling KDC proxy message: %w", err)
+	}
+	resp, err := p.client(realm).Post(u, kdcProxyContentType, bytes.NewReader(mb))
+	if err != nil {
+		return nil, fmt.Errorf("error sending to KDC proxy %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(ioutil.Discard, io.LimitReader(resp.Body, kdcProxyMaxReply))
+		return nil, fmt.Errorf("KDC proxy %s returned status %s", u, resp.Status)
+	}
+	rb, err := ioutil.ReadAll(io.LimitReader(resp.Body, kdcProxyMaxReply+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading reply from KDC proxy %s: %w", u, err)
+	}
+	if len(rb) > kdcProxyMaxReply {
+		return nil, fmt.Errorf("reply from KDC proxy %s exceeds %d bytes", u, kdcProxyMaxReply)
+	}
+	var r kdcProxyMessage
+	if _, err := asn1.Unmarshal(rb, &r); err != nil {
+		return nil, fmt.Errorf("error unmarshaling reply from KDC proxy %s: %w", u, err)
+	}
+	if len(r.KerbMessage) < 4 || binary.BigEndian.Uint32(r.KerbMessage[:4]) != uint32(len(r.KerbMessage)-4) {
+		return nil, errors.New("KDC proxy reply length does not match its Kerberos message")
+	}
+	return r.KerbMessage[4:], nil
+}
+
+// client returns the HTTP client connecting to the proxy of the realm with the realm's TLS configuration.
+func (p *KDCProxy) client(realm string) *http.Client {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if c, ok := p.clients[realm]; ok {
+		return c
+	}
+	tc, ok := p.tls[realm]
+	if !ok {
+		tc = p.tls[""]
+	}
+	if tc == nil {
+		tc = new(tls.Config)
+	} else {
+		tc = tc.Clone()
+	}
+	if tc.MinVersion == 0 {
+		tc.MinVersion = tls.VersionTLS12
+	}
+	c := &http.Client{
+		Timeout: kdcProxyTimeout,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tc,
+		},
+	}
+	p.clients[realm] = c
+	return c
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jcmturner/gokrb5/v8/asn1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKDCProxy_SendToKDC(t *testing.T) {
+	t.Parallel()
+	var reply []byte
+	status := http.StatusOK
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		var m kdcProxyMessage
+		if _, err := asn1.Unmarshal(b, &m); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if reply == nil {
+			// Echo the request
+			m.TargetDomain = ""
+			b, _ = asn1.Marshal(m)
+		} else {
+			b = reply
+		}
+		w.WriteHeader(status)
+		w.Write(b)
+	}))
+	defer s.Close()
+	p := NewKDCProxy(map[string]string{"TEST.GOKRB5": s.URL})
+
+	rb, err := p.SendToKDC("TEST.GOKRB5", []byte("request"))
+	require.NoError(t, err, "error sending through the proxy")
+	assert.Equal(t, "request", string(rb), "reply not as expected")
+
+	_, err = p.SendToKDC("OTHER.REALM", []byte("request"))
+	assert.Error(t, err, "realm without a proxy should fail")
+
+	l := make([]byte, 4)
+	binary.BigEndian.PutUint32(l, 100)
+	reply, _ = asn1.Marshal(kdcProxyMessage{KerbMessage: append(l, []byte("reply")...)})
+	_, err = p.SendToKDC("TEST.GOKRB5", []byte("request"))
+	assert.Error(t, err, "reply with a length not matching its message should be rejected")
+
+	reply = []byte("not a proxy message")
+	_, err = p.SendToKDC("TEST.GOKRB5", []byte("request"))
+	assert.Error(t, err, "reply that is not a proxy message should be rejected")
+
+	status = http.StatusBadGateway
+	_, err = p.SendToKDC("TEST.GOKRB5", []byte("request"))
+	assert.Error(t, err, "proxy error status should fail")
+}
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// kdcStats tracks the response latency and failures of the KDCs a client sends to so that the fastest healthy KDCs
+// can be tried first.
+type kdcStats struct {
+	reprobe time.Duration
+	mux     sync.Mutex
+	kdcs    map[string]*kdcStat
+}
+
+// kdcStat holds the measurements of a KDC.
+type kdcStat struct {
+	latency time.Duration
+	failed  bool
+	updated time.Time
+}
+
+// newKDCStats returns a kdcStats that re-measures each KDC when its measurement is older than the reprobe interval.
+func newKDCStats(reprobe time.Duration) *kdcStats {
+	return &kdcStats{
+		reprobe: reprobe,
+		kdcs:    make(map[string]*kdcStat),
+	}
+}
+
+// record the outcome of sending to a KDC. The latency is averaged with that previously measured so that a single
+// slow response does not change the order the KDCs are tried in.
+func (s *kdcStats) record(kdc string, d time.Duration, err error, now time.Time) {
+	if s == nil {
+		return
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	k, ok := s.kdcs[kdc]
+	if !ok {
+		k = &kdcStat{latency: d}
+		s.kdcs[kdc] = k
+	}
+	k.updated = now
+	if err != nil {
+		k.failed = true
+		return
+	}
+	if k.failed {
+		k.latency = d
+		k.failed = false
+	} else {
+		k.latency = (7*k.latency + 3*d) / 10
+	}
+}
+
+// order returns the KDCs in the order they should be tried in. KDCs that have not been measured within the reprobe
+// interval come first, in the order provided, so that they are measured. These are followed by the KDCs that
+// responded, fastest first, and then those that failed.
+func (s *kdcStats) order(kdcs map[int]string, now time.Time) map[int]string {
+	if s == nil || len(kdcs) < 2 {
+		return kdcs
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	type ranked struct {
+		kdc   string
+		rank  int
+		delay time.Duration
+		pref  int
+	}
+	r := make([]ranked, 0, len(kdcs))
+	for i := 1; i <= len(kdcs); i++ {
+		k := ranked{kdc: kdcs[i], pref: i}
+		if st, ok := s.kdcs[kdcs[i]]; ok && now.Sub(st.updated) < s.reprobe {
+			k.rank = 1
+			k.delay = st.latency
+			if st.failed {
+				k.rank = 2
+			}
+		}
+		r = append(r, k)
+	}
+	sort.SliceStable(r, func(i, j int) bool {
+		if r[i].rank != r[j].rank {
+			return r[i].rank < r[j].rank
+		}
+		if r[i].rank == 1 {
+			return r[i].delay < r[j].delay
+		}
+		return r[i].pref < r[j].pref
+	})
+	o := make(map[int]string, len(r))
+	for i, k := range r {
+		o[i+1] = k.kdc
+	}
+	return o
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKDCStats_Order(t *testing.T) {
+	t.Parallel()
+	kdcs := map[int]string{1: "kdc1:88", 2: "kdc2:88", 3: "kdc3:88", 4: "kdc4:88"}
+	now := time.Unix(1600000000, 0)
+	var s *kdcStats
+	assert.Equal(t, kdcs, s.order(kdcs, now), "order should not change without stats")
+
+	s = newKDCStats(time.Minute)
+	assert.Equal(t, kdcs, s.order(kdcs, now), "unmeasured KDCs should be in the order provided")
+
+	s.record("kdc1:88", 300*time.Millisecond, nil, now)
+	s.record("kdc2:88", time.Second, errors.New("timeout"), now)
+	s.record("kdc3:88", 100*time.Millisecond, nil, now)
+	s.record("kdc4:88", 200*time.Millisecond, nil, now)
+	assert.Equal(t, map[int]string{1: "kdc3:88", 2: "kdc4:88", 3: "kdc1:88", 4: "kdc2:88"}, s.order(kdcs, now), "fastest KDCs should be first and failed last")
+
+	// A single slow response is averaged with the previous measurements
+	s.record("kdc3:88", 250*time.Millisecond, nil, now)
+	assert.Equal(t, "kdc3:88", s.order(kdcs, now)[1], "a single slow response should not change the order")
+
+	// Stale measurements are probed first
+	later := now.Add(30 * time.Second)
+	s.record("kdc3:88", 100*time.Millisecond, nil, later)
+	s.record("kdc4:88", 200*time.Millisecond, nil, later)
+	o := s.order(kdcs, now.Add(time.Minute))
+	assert.Equal(t, map[int]string{1: "kdc1:88", 2: "kdc2:88", 3: "kdc3:88", 4: "kdc4:88"}, o, "stale KDCs should be probed first")
+
+	// A failed KDC that recovers is measured afresh
+	s.record("kdc2:88", 50*time.Millisecond, nil, later)
+	assert.Equal(t, "kdc2:88", s.order(kdcs, later)[1], "recovered KDC should be preferred")
+}
+
+func TestSettings_LatencyAwareKDCs(t *testing.T) {
+	t.Parallel()
+	s := NewSettings()
+	assert.Equal(t, time.Duration(0), s.LatencyAwareKDCs(), "KDCs should be tried in the configured order by default")
+	s = NewSettings(LatencyAwareKDCs(time.Minute))
+	assert.Equal(t, time.Minute, s.LatencyAwareKDCs(), "reprobe interval not as expected")
+	s = NewSettings(LatencyAwareKDCs(time.Minute), LatencyAwareKDCs(0))
+	assert.Equal(t, time.Duration(0), s.LatencyAwareKDCs(), "setting should be disabled")
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// keyExport writes session keys to a writer as a keytab that grows with each key written.
+type keyExport struct {
+	w       io.Writer
+	mux     sync.Mutex
+	started bool
+}
+
+// write appends an entry for the key to the keytab, writing the keytab header first if this is the first entry.
+func (e *keyExport) write(pn types.PrincipalName, realm string, key types.EncryptionKey, ts time.Time) error {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	kt := keytab.New()
+	hb, err := kt.Marshal()
+	if err != nil {
+		return err
+	}
+	kt.AddKey(pn, realm, key, ts, 0)
+	b, err := kt.Marshal()
+	if err != nil {
+		return err
+	}
+	if e.started {
+		b = b[len(hb):]
+	}
+	if _, err = e.w.Write(b); err != nil {
+		return err
+	}
+	e.started = true
+	return nil
+}
+
+// ExportSessionKey writes the session key or subkey used with the service principal provided to the writer the client
+// was configured with using UnsafeSessionKeyExport. If the client is not configured to export keys this does nothing.
+func (cl *Client) ExportSessionKey(sname types.PrincipalName, realm string, key types.EncryptionKey) {
+	if cl.settings == nil || cl.settings.keyExport == nil || len(key.KeyValue) == 0 {
+		return
+	}
+	if err := cl.settings.keyExport.write(sname, realm, key, cl.Now()); err != nil {
+		cl.Log("error exporting session key for %s: %v", sname.PrincipalNameString(), err)
+		return
+	}
+	cl.Log("UNSAFE: exported session key for %s", sname.PrincipalNameString())
+}
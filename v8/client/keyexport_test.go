@@ -0,0 +1,46 @@
+package client
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnsafeSessionKeyExport(t *testing.T) {
+	t.Parallel()
+	b := new(bytes.Buffer)
+	cl := NewWithPassword("testuser1", "TEST.GOKRB5", "passwordvalue", nil, UnsafeSessionKeyExport(b))
+	assert.True(t, cl.settings.UnsafeSessionKeyExport(), "session key export should be enabled")
+
+	keys := []types.EncryptionKey{
+		{KeyType: 18, KeyValue: bytes.Repeat([]byte{1}, 32)},
+		{KeyType: 17, KeyValue: bytes.Repeat([]byte{2}, 16)},
+	}
+	snames := []types.PrincipalName{
+		types.NewPrincipalName(nametype.KRB_NT_SRV_INST, "krbtgt/TEST.GOKRB5"),
+		types.NewPrincipalName(nametype.KRB_NT_SRV_HST, "HTTP/host.test.gokrb5"),
+	}
+	for i := range keys {
+		cl.ExportSessionKey(snames[i], "TEST.GOKRB5", keys[i])
+	}
+	cl.ExportSessionKey(snames[1], "TEST.GOKRB5", types.EncryptionKey{})
+
+	kt := keytab.New()
+	err := kt.Unmarshal(b.Bytes())
+	require.NoError(t, err, "exported keys are not a valid keytab")
+	require.Len(t, kt.Entries, len(keys), "number of exported keys not as expected")
+	for i := range keys {
+		k, _, err := kt.GetEncryptionKey(snames[i], "TEST.GOKRB5", 0, keys[i].KeyType)
+		require.NoError(t, err, "exported key for %s not found", snames[i].PrincipalNameString())
+		assert.Equal(t, keys[i], k, "exported key for %s not as expected", snames[i].PrincipalNameString())
+	}
+
+	cl = NewWithPassword("testuser1", "TEST.GOKRB5", "passwordvalue", nil)
+	assert.False(t, cl.settings.UnsafeSessionKeyExport(), "session key export should not be enabled by default")
+	cl.ExportSessionKey(snames[0], "TEST.GOKRB5", keys[0])
+}
@@ -0,0 +1,140 @@
+package client
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/jcmturner/gokrb5/v8/krberror"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// MultiRealm holds clients logged in to several realms simultaneously, keyed by realm, for gateways that serve users
+// across Kerberos realms that do not trust each other. The client for the realm of each target SPN is selected when
+// a service ticket is requested. Each client renews its own TGTs independently of the others.
+// It is safe for concurrent use.
+type MultiRealm struct {
+	Config  *config.Config
+	clients map[string]*Client
+	mux     sync.RWMutex
+}
+
+// NewMultiRealm returns a MultiRealm that resolves the realms of SPNs using the configuration provided.
+// The configuration's domain_realm mappings should map the domains of the services in each realm.
+func NewMultiRealm(krb5conf *config.Config) *MultiRealm {
+	return &MultiRealm{
+		Config:  krb5conf,
+		clients: make(map[string]*Client),
+	}
+}
+
+// Add logs the client in, if it does not already have a TGT, and adds it as the client for its realm.
+// Any client previously added for the realm is destroyed.
+func (m *MultiRealm) Add(cl *Client) error {
+	if err := cl.AffirmLogin(); err != nil {
+		return err
+	}
+	realm := cl.Realm()
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if k, ok := m.key(realm); ok {
+		if c := m.clients[k]; c != cl {
+			c.Destroy()
+		}
+		delete(m.clients, k)
+	}
+	m.clients[realm] = cl
+	return nil
+}
+
+// Remove destroys the client for the realm and removes it.
+func (m *MultiRealm) Remove(realm string) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if k, ok := m.key(realm); ok {
+		m.clients[k].Destroy()
+		delete(m.clients, k)
+	}
+}
+
+// Client returns the client for the realm.
+func (m *MultiRealm) Client(realm string) (*Client, bool) {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	return m.get(realm)
+}
+
+// Realms returns the realms there are clients for in sorted order.
+func (m *MultiRealm) Realms() []string {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	r := make([]string, 0, len(m.clients))
+	for k := range m.clients {
+		r = append(r, k)
+	}
+	sort.Strings(r)
+	return r
+}
+
+// ClientForSPN returns the client to request a ticket for the SPN with. The realm of the SPN's host is resolved from
+// the configuration's domain_realm mappings and the client for that realm returned. If there is no client for the
+// realm the client for the default realm is returned, as it may be able to obtain the ticket across a trust.
+func (m *MultiRealm) ClientForSPN(spn string) (*Client, error) {
+	princ := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, spn)
+	host := princ.NameString[len(princ.NameString)-1]
+	if s, err := types.ParseSPN(spn); err == nil {
+		host = s.Host
+	}
+	realm := m.Config.ResolveRealm(host)
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	if cl, ok := m.get(realm); ok {
+		return cl, nil
+	}
+	if cl, ok := m.get(m.Config.LibDefaults.DefaultRealm); ok {
+		return cl, nil
+	}
+	return nil, krberror.NewErrorf(krberror.ConfigError, "no client for realm %s of %s", realm, spn)
+}
+
+// GetServiceTicket gets a ticket for the SPN using the client for the SPN's realm selected by ClientForSPN.
+func (m *MultiRealm) GetServiceTicket(spn string) (messages.Ticket, types.EncryptionKey, error) {
+	cl, err := m.ClientForSPN(spn)
+	if err != nil {
+		return messages.Ticket{}, types.EncryptionKey{}, err
+	}
+	return cl.GetServiceTicket(spn)
+}
+
+// Destroy destroys all the clients and removes them.
+func (m *MultiRealm) Destroy() {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	for r, cl := range m.clients {
+		cl.Destroy()
+		delete(m.clients, r)
+	}
+}
+
+// get returns the client for the realm. The caller must hold the lock.
+func (m *MultiRealm) get(realm string) (*Client, bool) {
+	if k, ok := m.key(realm); ok {
+		return m.clients[k], true
+	}
+	return nil, false
+}
+
+// key returns the key of the client for the realm, comparing realms as configured. The caller must hold the lock.
+func (m *MultiRealm) key(realm string) (string, bool) {
+	if _, ok := m.clients[realm]; ok {
+		return realm, true
+	}
+	for r := range m.clients {
+		if m.Config.RealmEqual(r, realm) {
+			return r, true
+		}
+	}
+	return "", false
+}
@@ -6,15 +6,42 @@ import (
 	"fmt"
 	"io"
 	"net"
-	"strings"
 	"time"
 
+	"github.com/jcmturner/gokrb5/v8/config"
 	"github.com/jcmturner/gokrb5/v8/iana/errorcode"
 	"github.com/jcmturner/gokrb5/v8/messages"
 )
 
+// Transport sends the bytes of a request to a KDC for the realm and returns the bytes of the reply.
+// Implementations may be used in place of the network transports, for example to route requests through a proxy.
+type Transport interface {
+	SendToKDC(realm string, b []byte) ([]byte, error)
+}
+
 // SendToKDC performs network actions to send data to the KDC.
+// The request and reply are passed through the hooks the client is configured with.
 func (cl *Client) sendToKDC(b []byte, realm string) ([]byte, error) {
+	b, err := cl.requestHooks(realm, b)
+	if err != nil {
+		return nil, err
+	}
+	rb, err := cl.sendToKDCTransport(b, realm)
+	cl.replyHooks(realm, b, rb)
+	return rb, err
+}
+
+// sendToKDCTransport sends data to the KDC with the configured transport or over the network.
+func (cl *Client) sendToKDCTransport(b []byte, realm string) ([]byte, error) {
+	cl.trace("Sending request (%d bytes) to %s", len(b), realm)
+	if t := cl.settings.KDCTransport(); t != nil {
+		cl.trace("Sending request to %s using the configured transport", realm)
+		rb, err := t.SendToKDC(realm, b)
+		if err != nil {
+			return rb, err
+		}
+		return checkForKRBError(rb)
+	}
 	var rb []byte
 	if cl.Config.LibDefaults.UDPPreferenceLimit == 1 {
 		//1 means we should always use TCP
@@ -23,7 +50,7 @@ func (cl *Client) sendToKDC(b []byte, realm string) ([]byte, error) {
 			if e, ok := errtcp.(messages.KRBError); ok {
 				return rb, e
 			}
-			return rb, fmt.Errorf("communication error with KDC via TCP: %v", errtcp)
+			return rb, fmt.Errorf("communication error with KDC via TCP: %w", errtcp)
 		}
 		return rb, nil
 	}
@@ -36,6 +63,7 @@ func (cl *Client) sendToKDC(b []byte, realm string) ([]byte, error) {
 				// If this is not a KRB_ERR_RESPONSE_TOO_BIG we will return immediately otherwise will try TCP.
 				return rb, e
 			}
+			cl.trace("Retrying request to %s over TCP after UDP failed: %v", realm, errudp)
 			// Try TCP
 			r, errtcp := cl.sendKDCTCP(realm, b)
 			if errtcp != nil {
@@ -43,7 +71,7 @@ func (cl *Client) sendToKDC(b []byte, realm string) ([]byte, error) {
 					// Got a KRBError
 					return r, e
 				}
-				return r, fmt.Errorf("failed to communicate with KDC. Attempts made with UDP (%v) and then TCP (%v)", errudp, errtcp)
+				return r, fmt.Errorf("failed to communicate with KDC. Attempts made with UDP (%v) and then TCP (%w)", errudp, errtcp)
 			}
 			rb = r
 		}
@@ -56,13 +84,14 @@ func (cl *Client) sendToKDC(b []byte, realm string) ([]byte, error) {
 			// Got a KRBError from KDC so returning and not trying UDP.
 			return rb, e
 		}
+		cl.trace("Retrying request to %s over UDP after TCP failed: %v", realm, errtcp)
 		rb, errudp := cl.sendKDCUDP(realm, b)
 		if errudp != nil {
 			if e, ok := errudp.(messages.KRBError); ok {
 				// Got a KRBError
 				return rb, e
 			}
-			return rb, fmt.Errorf("failed to communicate with KDC. Attempts made with TCP (%v) and then UDP (%v)", errtcp, errudp)
+			return rb, fmt.Errorf("failed to communicate with KDC. Attempts made with TCP (%v) and then UDP (%w)", errtcp, errudp)
 		}
 	}
 	return rb, nil
@@ -75,41 +104,61 @@ func (cl *Client) sendKDCUDP(realm string, b []byte) ([]byte, error) {
 	if err != nil {
 		return r, err
 	}
-	r, err = dialSendUDP(kdcs, b)
+	kdcs = cl.settings.kdcStats.order(kdcs, cl.Now())
+	cl.traceKDCs(realm, kdcs)
+	r, err = dialSendUDP(cl.Config, kdcs, b, cl.trace, cl.recordKDC)
 	if err != nil {
 		return r, err
 	}
 	return checkForKRBError(r)
 }
 
-// dialSendUDP establishes a UDP connection to a KDC.
-func dialSendUDP(kdcs map[int]string, b []byte) ([]byte, error) {
-	var errs []string
+// dialSendUDP establishes a UDP connection to a KDC, resolving its address with the configuration's Resolver.
+// Each attempt is reported to the trace function and, if it is not nil, its outcome to the record function.
+func dialSendUDP(cfg *config.Config, kdcs map[int]string, b []byte, trace func(string, ...interface{}), record func(string, time.Duration, error)) ([]byte, error) {
+	var errs []error
 	for i := 1; i <= len(kdcs); i++ {
-		udpAddr, err := net.ResolveUDPAddr("udp", kdcs[i])
-		if err != nil {
-			errs = append(errs, fmt.Sprintf("error resolving KDC address: %v", err))
-			continue
+		start := time.Now()
+		rb, err := dialSendUDPKDC(cfg, kdcs[i], b, trace)
+		if record != nil {
+			record(kdcs[i], time.Since(start), err)
 		}
-
-		conn, err := net.DialTimeout("udp", udpAddr.String(), 5*time.Second)
 		if err != nil {
-			errs = append(errs, fmt.Sprintf("error setting dial timeout on connection to %s: %v", kdcs[i], err))
-			continue
-		}
-		if err := conn.SetDeadline(time.Now().Add(5 * time.Second)); err != nil {
-			errs = append(errs, fmt.Sprintf("error setting deadline on connection to %s: %v", kdcs[i], err))
-			continue
-		}
-		// conn is guaranteed to be a UDPConn
-		rb, err := sendUDP(conn.(*net.UDPConn), b)
-		if err != nil {
-			errs = append(errs, fmt.Sprintf("error sneding to %s: %v", kdcs[i], err))
+			errs = append(errs, err)
 			continue
 		}
 		return rb, nil
 	}
-	return nil, fmt.Errorf("error sending to a KDC: %s", strings.Join(errs, "; "))
+	return nil, sendErrors("error sending to a KDC", errs)
+}
+
+// dialSendUDPKDC sends bytes to a KDC via UDP.
+func dialSendUDPKDC(cfg *config.Config, kdc string, b []byte, trace func(string, ...interface{})) ([]byte, error) {
+	addr, err := cfg.ResolveHostPort(kdc)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving KDC address: %w", err)
+	}
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving KDC address: %w", err)
+	}
+
+	trace("Sending initial UDP request to dgram %s", udpAddr)
+	conn, err := net.DialTimeout("udp", udpAddr.String(), 5*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("error setting dial timeout on connection to %s: %w", kdc, err)
+	}
+	if err := conn.SetDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		return nil, fmt.Errorf("error setting deadline on connection to %s: %w", kdc, err)
+	}
+	// conn is guaranteed to be a UDPConn
+	rb, err := sendUDP(conn.(*net.UDPConn), b)
+	if err != nil {
+		trace("Error sending to dgram %s: %v", udpAddr, err)
+		return nil, fmt.Errorf("error sneding to %s: %w", kdc, err)
+	}
+	trace("Received answer (%d bytes) from dgram %s", len(rb), udpAddr)
+	return rb, nil
 }
 
 // sendUDP sends bytes to connection over UDP.
@@ -118,13 +167,13 @@ func sendUDP(conn *net.UDPConn, b []byte) ([]byte, error) {
 	defer conn.Close()
 	_, err := conn.Write(b)
 	if err != nil {
-		return r, fmt.Errorf("error sending to (%s): %v", conn.RemoteAddr().String(), err)
+		return r, fmt.Errorf("error sending to (%s): %w", conn.RemoteAddr().String(), err)
 	}
 	udpbuf := make([]byte, 4096)
 	n, _, err := conn.ReadFrom(udpbuf)
 	r = udpbuf[:n]
 	if err != nil {
-		return r, fmt.Errorf("sending over UDP failed to %s: %v", conn.RemoteAddr().String(), err)
+		return r, fmt.Errorf("sending over UDP failed to %s: %w", conn.RemoteAddr().String(), err)
 	}
 	if len(r) < 1 {
 		return r, fmt.Errorf("no response data from %s", conn.RemoteAddr().String())
@@ -139,41 +188,77 @@ func (cl *Client) sendKDCTCP(realm string, b []byte) ([]byte, error) {
 	if err != nil {
 		return r, err
 	}
-	r, err = dialSendTCP(kdcs, b)
+	kdcs = cl.settings.kdcStats.order(kdcs, cl.Now())
+	cl.traceKDCs(realm, kdcs)
+	r, err = dialSendTCP(cl.Config, kdcs, b, cl.trace, cl.recordKDC)
 	if err != nil {
 		return r, err
 	}
 	return checkForKRBError(r)
 }
 
-// dialKDCTCP establishes a TCP connection to a KDC.
-func dialSendTCP(kdcs map[int]string, b []byte) ([]byte, error) {
-	var errs []string
+// dialKDCTCP establishes a TCP connection to a KDC, resolving its address with the configuration's Resolver.
+// Each attempt is reported to the trace function and, if it is not nil, its outcome to the record function.
+func dialSendTCP(cfg *config.Config, kdcs map[int]string, b []byte, trace func(string, ...interface{}), record func(string, time.Duration, error)) ([]byte, error) {
+	var errs []error
 	for i := 1; i <= len(kdcs); i++ {
-		tcpAddr, err := net.ResolveTCPAddr("tcp", kdcs[i])
-		if err != nil {
-			errs = append(errs, fmt.Sprintf("error resolving KDC address: %v", err))
-			continue
-		}
-
-		conn, err := net.DialTimeout("tcp", tcpAddr.String(), 5*time.Second)
-		if err != nil {
-			errs = append(errs, fmt.Sprintf("error setting dial timeout on connection to %s: %v", kdcs[i], err))
-			continue
+		start := time.Now()
+		rb, err := dialSendTCPKDC(cfg, kdcs[i], b, trace)
+		if record != nil {
+			record(kdcs[i], time.Since(start), err)
 		}
-		if err := conn.SetDeadline(time.Now().Add(5 * time.Second)); err != nil {
-			errs = append(errs, fmt.Sprintf("error setting deadline on connection to %s: %v", kdcs[i], err))
-			continue
-		}
-		// conn is guaranteed to be a TCPConn
-		rb, err := sendTCP(conn.(*net.TCPConn), b)
 		if err != nil {
-			errs = append(errs, fmt.Sprintf("error sneding to %s: %v", kdcs[i], err))
+			errs = append(errs, err)
 			continue
 		}
 		return rb, nil
 	}
-	return nil, errors.New("error in getting a TCP connection to any of the KDCs")
+	return nil, sendErrors("error in getting a TCP connection to any of the KDCs", errs)
+}
+
+// dialSendTCPKDC sends bytes to a KDC via TCP.
+func dialSendTCPKDC(cfg *config.Config, kdc string, b []byte, trace func(string, ...interface{})) ([]byte, error) {
+	addr, err := cfg.ResolveHostPort(kdc)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving KDC address: %w", err)
+	}
+	tcpAddr, err := net.ResolveTCPAddr("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving KDC address: %w", err)
+	}
+
+	trace("Initiating TCP connection to stream %s", tcpAddr)
+	conn, err := net.DialTimeout("tcp", tcpAddr.String(), 5*time.Second)
+	if err != nil {
+		trace("Error connecting to stream %s: %v", tcpAddr, err)
+		return nil, fmt.Errorf("error setting dial timeout on connection to %s: %w", kdc, err)
+	}
+	if err := conn.SetDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		return nil, fmt.Errorf("error setting deadline on connection to %s: %w", kdc, err)
+	}
+	// conn is guaranteed to be a TCPConn
+	trace("Sending TCP request to stream %s", tcpAddr)
+	rb, err := sendTCP(conn.(*net.TCPConn), b)
+	trace("Terminating TCP connection to stream %s", tcpAddr)
+	if err != nil {
+		trace("Error sending to stream %s: %v", tcpAddr, err)
+		return nil, fmt.Errorf("error sneding to %s: %w", kdc, err)
+	}
+	trace("Received answer (%d bytes) from stream %s", len(rb), tcpAddr)
+	return rb, nil
+}
+
+// sendErrors combines the errors from the attempts to send to each KDC.
+// The last error is wrapped so that its cause can be inspected with errors.Is and errors.As.
+func sendErrors(msg string, errs []error) error {
+	if len(errs) < 1 {
+		return errors.New(msg)
+	}
+	var s string
+	for _, err := range errs[:len(errs)-1] {
+		s += err.Error() + "; "
+	}
+	return fmt.Errorf("%s: %s%w", msg, s, errs[len(errs)-1])
 }
 
 // sendTCP sends bytes to connection over TCP.
@@ -187,20 +272,20 @@ func sendTCP(conn *net.TCPConn, b []byte) ([]byte, error) {
 
 	_, err := conn.Write(b)
 	if err != nil {
-		return r, fmt.Errorf("error sending to KDC (%s): %v", conn.RemoteAddr().String(), err)
+		return r, fmt.Errorf("error sending to KDC (%s): %w", conn.RemoteAddr().String(), err)
 	}
 
 	sh := make([]byte, 4, 4)
 	_, err = conn.Read(sh)
 	if err != nil {
-		return r, fmt.Errorf("error reading response size header: %v", err)
+		return r, fmt.Errorf("error reading response size header: %w", err)
 	}
 	s := binary.BigEndian.Uint32(sh)
 
 	rb := make([]byte, s, s)
 	_, err = io.ReadFull(conn, rb)
 	if err != nil {
-		return r, fmt.Errorf("error reading response: %v", err)
+		return r, fmt.Errorf("error reading response: %w", err)
 	}
 	if len(rb) < 1 {
 		return r, fmt.Errorf("no response data from KDC %s", conn.RemoteAddr().String())
@@ -208,6 +293,11 @@ func sendTCP(conn *net.TCPConn, b []byte) ([]byte, error) {
 	return rb, nil
 }
 
+// recordKDC records the outcome of sending to a KDC if the client is configured to try the fastest KDCs first.
+func (cl *Client) recordKDC(kdc string, d time.Duration, err error) {
+	cl.settings.kdcStats.record(kdc, d, err, cl.Now())
+}
+
 // checkForKRBError checks if the response bytes from the KDC are a KRBError.
 func checkForKRBError(b []byte) ([]byte, error) {
 	var KRBErr messages.KRBError
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"errors"
+	"log"
+
+	"github.com/jcmturner/gokrb5/v8/clock"
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+)
+
+// Option configures a client created with NewClient.
+type Option func(*options)
+
+// options holds the values provided to NewClient.
+type options struct {
+	username string
+	realm    string
+	password *string
+	keytab   *keytab.Keytab
+	ccache   *credentials.CCache
+	config   *config.Config
+	settings []func(*Settings)
+}
+
+// NewClient creates a new client from the options provided.
+// Exactly one of WithPassword, WithKeytab or WithCCache must be provided.
+// If no configuration is provided a default configuration is used.
+//
+// cl, err := NewClient(WithKeytab("user", "REALM", kt), WithConfig(cfg), WithLogger(l))
+func NewClient(opts ...Option) (*Client, error) {
+	o := new(options)
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.config == nil {
+		o.config = config.New()
+	}
+	var n int
+	for _, set := range []bool{o.password != nil, o.keytab != nil, o.ccache != nil} {
+		if set {
+			n++
+		}
+	}
+	if n != 1 {
+		return nil, errors.New("exactly one of a password, keytab or credentials cache must be provided")
+	}
+	switch {
+	case o.ccache != nil:
+		return NewFromCCache(o.ccache, o.config, o.settings...)
+	case o.keytab != nil:
+		return NewWithKeytab(o.username, o.realm, o.keytab, o.config, o.settings...), nil
+	default:
+		return NewWithPassword(o.username, o.realm, *o.password, o.config, o.settings...), nil
+	}
+}
+
+// WithPassword configures the client to authenticate with a password.
+// Set the realm to empty string to use the default realm from config.
+func WithPassword(username, realm, password string) Option {
+	return func(o *options) {
+		o.username = username
+		o.realm = realm
+		o.password = &password
+	}
+}
+
+// WithKeytab configures the client to authenticate with a keytab.
+func WithKeytab(username, realm string, kt *keytab.Keytab) Option {
+	return func(o *options) {
+		o.username = username
+		o.realm = realm
+		o.keytab = kt
+	}
+}
+
+// WithCCache configures the client to use the tickets from a populated credentials cache.
+func WithCCache(c *credentials.CCache) Option {
+	return func(o *options) {
+		o.ccache = c
+	}
+}
+
+// WithConfig configures the client's krb5 configuration.
+func WithConfig(cfg *config.Config) Option {
+	return func(o *options) {
+		o.config = cfg
+	}
+}
+
+// WithLogger configures the client's logger.
+func WithLogger(l *log.Logger) Option {
+	return WithSettings(Logger(l))
+}
+
+// WithClock configures the clock the client reads the current time from.
+func WithClock(c clock.Clock) Option {
+	return WithSettings(Clock(c))
+}
+
+// WithTransport configures the transport the client uses to send messages to the KDC.
+func WithTransport(t Transport) Option {
+	return WithSettings(KDCTransport(t))
+}
+
+// WithSettings configures the client with the settings provided.
+func WithSettings(settings ...func(*Settings)) Option {
+	return func(o *options) {
+		o.settings = append(o.settings, settings...)
+	}
+}
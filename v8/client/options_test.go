@@ -0,0 +1,74 @@
+package client
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/clock"
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/iana/errorcode"
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/test/testdata"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type testTransport struct {
+	realm string
+	reply []byte
+}
+
+func (t *testTransport) SendToKDC(realm string, b []byte) ([]byte, error) {
+	t.realm = realm
+	return t.reply, nil
+}
+
+func TestNewClient(t *testing.T) {
+	t.Parallel()
+	_, err := NewClient()
+	assert.Error(t, err, "client without credentials should not be created")
+	_, err = NewClient(WithPassword("username", "REALM", "password"), WithKeytab("username", "REALM", keytab.New()))
+	assert.Error(t, err, "client with multiple credentials should not be created")
+
+	c := clock.NewMock(time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC))
+	cl, err := NewClient(WithPassword("username", "REALM", "password"), WithClock(c))
+	if assert.NoError(t, err, "error creating client") {
+		assert.True(t, cl.Credentials.HasPassword(), "client should have a password")
+		assert.NotNil(t, cl.Config, "client should have a default config")
+		assert.Equal(t, c.Now(), cl.Now(), "client clock not as expected")
+	}
+
+	b, _ := hex.DecodeString(testdata.KEYTAB_TESTUSER1_TEST_GOKRB5)
+	kt := keytab.New()
+	kt.Unmarshal(b)
+	cfg := config.New()
+	cl, err = NewClient(WithKeytab("testuser1", "TEST.GOKRB5", kt), WithConfig(cfg), WithSettings(AssumePreAuthentication(true)))
+	if assert.NoError(t, err, "error creating client") {
+		assert.True(t, cl.Credentials.HasKeytab(), "client should have a keytab")
+		assert.Equal(t, cfg, cl.Config, "client config not as expected")
+		assert.True(t, cl.settings.AssumePreAuthentication(), "client settings not applied")
+	}
+}
+
+func TestNewClient_WithTransport(t *testing.T) {
+	t.Parallel()
+	e := messages.NewKRBError(types.NewPrincipalName(nametype.KRB_NT_SRV_INST, "krbtgt/REALM"), "REALM", errorcode.KDC_ERR_C_PRINCIPAL_UNKNOWN, "unknown")
+	b, _ := e.Marshal()
+	tr := &testTransport{reply: b}
+	cfg := config.New()
+	cfg.LibDefaults.DefaultRealm = "REALM"
+	cl, err := NewClient(WithPassword("username", "REALM", "password"), WithConfig(cfg), WithTransport(tr))
+	if !assert.NoError(t, err, "error creating client") {
+		return
+	}
+	err = cl.Login()
+	var krberr messages.KRBError
+	if assert.True(t, errors.As(err, &krberr), "expected KRBError from the transport's reply: %v", err) {
+		assert.Equal(t, errorcode.KDC_ERR_C_PRINCIPAL_UNKNOWN, krberr.ErrorCode, "error code not as expected")
+	}
+	assert.Equal(t, "REALM", tr.realm, "request not sent via the transport")
+}
@@ -9,14 +9,14 @@ import (
 
 // Kpasswd server response codes.
 const (
-	KRB5_KPASSWD_SUCCESS             = 0
-	KRB5_KPASSWD_MALFORMED           = 1
-	KRB5_KPASSWD_HARDERROR           = 2
-	KRB5_KPASSWD_AUTHERROR           = 3
-	KRB5_KPASSWD_SOFTERROR           = 4
-	KRB5_KPASSWD_ACCESSDENIED        = 5
-	KRB5_KPASSWD_BAD_VERSION         = 6
-	KRB5_KPASSWD_INITIAL_FLAG_NEEDED = 7
+	KRB5_KPASSWD_SUCCESS             = kadmin.KRB5_KPASSWD_SUCCESS
+	KRB5_KPASSWD_MALFORMED           = kadmin.KRB5_KPASSWD_MALFORMED
+	KRB5_KPASSWD_HARDERROR           = kadmin.KRB5_KPASSWD_HARDERROR
+	KRB5_KPASSWD_AUTHERROR           = kadmin.KRB5_KPASSWD_AUTHERROR
+	KRB5_KPASSWD_SOFTERROR           = kadmin.KRB5_KPASSWD_SOFTERROR
+	KRB5_KPASSWD_ACCESSDENIED        = kadmin.KRB5_KPASSWD_ACCESSDENIED
+	KRB5_KPASSWD_BAD_VERSION         = kadmin.KRB5_KPASSWD_BAD_VERSION
+	KRB5_KPASSWD_INITIAL_FLAG_NEEDED = kadmin.KRB5_KPASSWD_INITIAL_FLAG_NEEDED
 )
 
 // ChangePasswd changes the password of the client to the value provided.
@@ -43,9 +43,10 @@ func (cl *Client) ChangePasswd(newPasswd string) (bool, error) {
 		return false, err
 	}
 	if r.ResultCode != KRB5_KPASSWD_SUCCESS {
-		return false, fmt.Errorf("error response from kadmin: code: %d; result: %s; krberror: %v", r.ResultCode, r.Result, r.KRBError)
+		return false, fmt.Errorf("error response from kadmin: code: %d; result: %s; krberror: %w", r.ResultCode, r.Result, r.KRBError)
 	}
 	cl.Credentials.WithPassword(newPasswd)
+	cl.event(Event{Type: EventPasswordChanged})
 	return true, nil
 }
 
@@ -60,12 +61,12 @@ func (cl *Client) sendToKPasswd(msg kadmin.Request) (r kadmin.Reply, err error)
 	}
 	var rb []byte
 	if len(b) <= cl.Config.LibDefaults.UDPPreferenceLimit {
-		rb, err = dialSendUDP(kps, b)
+		rb, err = dialSendUDP(cl.Config, kps, b, cl.trace, nil)
 		if err != nil {
 			return
 		}
 	} else {
-		rb, err = dialSendTCP(kps, b)
+		rb, err = dialSendTCP(cl.Config, kps, b, cl.trace, nil)
 		if err != nil {
 			return
 		}
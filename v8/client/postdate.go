@@ -0,0 +1,43 @@
+package client
+
+import (
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/krberror"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// GetPostdatedTGT obtains a TGT for the client's realm that is valid from the time provided, for jobs that are to run
+// later. The TGT is issued invalid and is not added to the client's sessions. Once its start time has passed it must
+// be validated with ValidateTicket before it can be used.
+func (cl *Client) GetPostdatedTGT(from time.Time) (messages.Ticket, types.EncryptionKey, error) {
+	ASReq, err := messages.NewASReqForTGT(cl.Credentials.Domain(), cl.Config, cl.Credentials.CName())
+	if err != nil {
+		return messages.Ticket{}, types.EncryptionKey{}, krberror.Errorf(err, krberror.KRBMsgError, "error generating new AS_REQ")
+	}
+	ASReq.ReqBody.Postdate(from.UTC(), cl.Config)
+	cl.trace("Requesting ticket postdated to %s", from.UTC().Format(time.RFC3339))
+	ASRep, err := cl.ASExchange(cl.Credentials.Domain(), ASReq, 0)
+	if err != nil {
+		return messages.Ticket{}, types.EncryptionKey{}, err
+	}
+	cl.cname.set(ASRep.CName, ASRep.CRealm)
+	return ASRep.Ticket, ASRep.DecryptedEncPart.Key, nil
+}
+
+// ValidateTicket validates a postdated ticket with the KDC that issued it once the ticket's start time has passed and
+// returns the valid ticket. A validated TGT is added to the client's sessions so that it is used to obtain service
+// tickets.
+func (cl *Client) ValidateTicket(tkt messages.Ticket, sessionKey types.EncryptionKey) (messages.Ticket, types.EncryptionKey, error) {
+	tgsReq, err := messages.NewValidateTGSReq(cl.CName(), cl.Config, tkt, sessionKey, cl.Now())
+	if err != nil {
+		return messages.Ticket{}, types.EncryptionKey{}, krberror.Errorf(err, krberror.KRBMsgError, "TGS Exchange Error: failed to generate a TGS_REQ to validate the ticket")
+	}
+	_, tgsRep, err := cl.TGSExchange(tgsReq, tkt.Realm, tkt, sessionKey, 0)
+	if err != nil {
+		return messages.Ticket{}, types.EncryptionKey{}, err
+	}
+	cl.addSession(tgsRep.Ticket, tgsRep.DecryptedEncPart)
+	return tgsRep.Ticket, tgsRep.DecryptedEncPart.Key, nil
+}
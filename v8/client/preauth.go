@@ -0,0 +1,160 @@
+package client
+
+import (
+	"github.com/jcmturner/gokrb5/v8/crypto"
+	"github.com/jcmturner/gokrb5/v8/crypto/etype"
+	"github.com/jcmturner/gokrb5/v8/iana/keyusage"
+	"github.com/jcmturner/gokrb5/v8/iana/patype"
+	"github.com/jcmturner/gokrb5/v8/krberror"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// preAuthMechanism is a pre-authentication mechanism the client can use in response to the hints from the KDC.
+type preAuthMechanism struct {
+	// paType is the PA-DATA type the mechanism adds to the AS_REQ.
+	paType int32
+	// offered indicates if the hints within the KDC's KRBError e-data permit the mechanism to be used.
+	offered func(cl *Client, hints types.PADataSequence) bool
+	// setPAData adds the mechanism's PA-DATA to the AS_REQ.
+	// The KRBError is nil when the mechanism is used preemptively without hints from the KDC.
+	setPAData func(cl *Client, krberr *messages.KRBError, ASReq *messages.ASReq) error
+	// processReply, if set, is called with the AS_REP once it has been verified.
+	processReply func(cl *Client, ASRep *messages.ASRep) error
+}
+
+// PreAuthPlugin is a pre-authentication mechanism provided by an application, such as a vendor specific mechanism,
+// that the client can use in addition to those it supports itself.
+// Plugins are configured on the client with the PreAuthPlugins setting.
+type PreAuthPlugin interface {
+	// PAType returns the PA-DATA type the mechanism adds to the AS_REQ.
+	PAType() int32
+	// Offered indicates if the hints within the KDC's KRBError e-data permit the mechanism to be used.
+	Offered(hints types.PADataSequence) bool
+	// PAData returns the PA-DATA to add to the AS_REQ in response to the KRBError from the KDC. Any existing PA-DATA
+	// of the same type is replaced.
+	PAData(cl *Client, krberr messages.KRBError, ASReq messages.ASReq) (types.PAData, error)
+	// ProcessReply is called with the AS_REP from the KDC once it has been verified. An error fails the AS exchange.
+	ProcessReply(cl *Client, ASRep *messages.ASRep) error
+}
+
+// pluginPreAuthMechanism returns the pre-authentication mechanism implemented by the plugin.
+func pluginPreAuthMechanism(p PreAuthPlugin) preAuthMechanism {
+	return preAuthMechanism{
+		paType: p.PAType(),
+		offered: func(cl *Client, hints types.PADataSequence) bool {
+			return p.Offered(hints)
+		},
+		setPAData: func(cl *Client, krberr *messages.KRBError, ASReq *messages.ASReq) error {
+			if krberr == nil {
+				return krberror.NewErrorf(krberror.KRBMsgError, "pre-authentication plugin for PA type %d cannot be used without hints from the KDC", p.PAType())
+			}
+			pa, err := p.PAData(cl, *krberr, *ASReq)
+			if err != nil {
+				return krberror.Errorf(err, krberror.KRBMsgError, "pre-authentication plugin for PA type %d failed", p.PAType())
+			}
+			replacePAData(ASReq, pa)
+			return nil
+		},
+		processReply: func(cl *Client, ASRep *messages.ASRep) error {
+			return p.ProcessReply(cl, ASRep)
+		},
+	}
+}
+
+// preAuthMechanisms is the registry of pre-authentication mechanisms supported by the client in order of preference.
+var preAuthMechanisms = []preAuthMechanism{
+	{
+		paType:    patype.PA_ENC_TIMESTAMP,
+		offered:   encTimestampOffered,
+		setPAData: setPAEncTimestamp,
+	},
+}
+
+// selectPreAuthMechanism returns the most preferred mechanism offered by the KDC that has not already been tried.
+// Mechanisms provided by plugins are preferred over those supported by the client itself.
+// nil is returned if there are no further mechanisms available.
+func selectPreAuthMechanism(cl *Client, hints types.PADataSequence, tried map[int32]bool) *preAuthMechanism {
+	for _, p := range cl.settings.PreAuthPlugins() {
+		if !tried[p.PAType()] && p.Offered(hints) {
+			m := pluginPreAuthMechanism(p)
+			return &m
+		}
+	}
+	for i := range preAuthMechanisms {
+		m := &preAuthMechanisms[i]
+		if !tried[m.paType] && m.offered(cl, hints) {
+			return m
+		}
+	}
+	return nil
+}
+
+// encTimestampOffered indicates if the encrypted timestamp mechanism can be used.
+// KDCs do not always include hints so their absence is taken to permit encrypted timestamp.
+func encTimestampOffered(cl *Client, hints types.PADataSequence) bool {
+	if len(hints) == 0 {
+		return true
+	}
+	for _, pa := range hints {
+		switch pa.PADataType {
+		case patype.PA_ENC_TIMESTAMP, patype.PA_ETYPE_INFO2, patype.PA_ETYPE_INFO:
+			return true
+		}
+	}
+	return false
+}
+
+// setPAEncTimestamp adds encrypted timestamp pre-authentication data to the AS_REQ.
+func setPAEncTimestamp(cl *Client, krberr *messages.KRBError, ASReq *messages.ASReq) error {
+	// Identify the etype to use to encrypt the PA Data
+	var et etype.EType
+	var err error
+	var key types.EncryptionKey
+	var kvno int
+	if krberr == nil || len(krberr.EData) == 0 {
+		// This is not in response to an error from the KDC with hints. It is preemptive or renewal
+		// There is no KRB Error that tells us the etype to use
+		etn := cl.settings.preAuthEType // Use the etype that may have previously been negotiated
+		if etn == 0 {
+			etn = int32(cl.Config.LibDefaults.PreferredPreauthTypes[0]) // Resort to config
+		}
+		et, err = crypto.GetEtype(etn)
+		if err != nil {
+			return krberror.Errorf(err, krberror.EncryptingError, "error getting etype for pre-auth encryption")
+		}
+		key, kvno, err = cl.Key(et, 0, nil)
+		if err != nil {
+			return krberror.Errorf(err, krberror.EncryptingError, "error getting key from credentials")
+		}
+	} else {
+		// Get the etype to use from the PA data in the KRBError e-data
+		et, err = preAuthEType(krberr)
+		if err != nil {
+			return krberror.Errorf(err, krberror.EncryptingError, "error getting etype for pre-auth encryption")
+		}
+		cl.settings.preAuthEType = et.GetETypeID() // Set the etype that has been defined for potential future use
+		key, kvno, err = cl.Key(et, 0, krberr)
+		if err != nil {
+			return krberror.Errorf(err, krberror.EncryptingError, "error getting key from credentials")
+		}
+	}
+	// Generate the PA data
+	paTSb, err := types.GetPAEncTSEncAsnMarshalledAt(cl.Now())
+	if err != nil {
+		return krberror.Errorf(err, krberror.KRBMsgError, "error creating PAEncTSEnc for Pre-Authentication")
+	}
+	paEncTS, err := crypto.GetEncryptedData(paTSb, key, keyusage.AS_REQ_PA_ENC_TIMESTAMP, kvno)
+	if err != nil {
+		return krberror.Errorf(err, krberror.EncryptingError, "error encrypting pre-authentication timestamp")
+	}
+	pb, err := paEncTS.Marshal()
+	if err != nil {
+		return krberror.Errorf(err, krberror.EncodingError, "error marshaling the PAEncTSEnc encrypted data")
+	}
+	replacePAData(ASReq, types.PAData{
+		PADataType:  patype.PA_ENC_TIMESTAMP,
+		PADataValue: pb,
+	})
+	return nil
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/iana/patype"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectPreAuthMechanism(t *testing.T) {
+	t.Parallel()
+
+	cl := NewWithKeytab("username", "REALM", &keytab.Keytab{}, config.New())
+	tried := make(map[int32]bool)
+
+	m := selectPreAuthMechanism(cl, nil, tried)
+	if assert.NotNil(t, m, "mechanism should be selected when the KDC provides no hints") {
+		assert.Equal(t, patype.PA_ENC_TIMESTAMP, m.paType, "encrypted timestamp not selected")
+	}
+	hints := types.PADataSequence{{PADataType: patype.PA_ETYPE_INFO2}}
+	m = selectPreAuthMechanism(cl, hints, tried)
+	if assert.NotNil(t, m, "mechanism should be selected from ETYPE-INFO2 hint") {
+		assert.Equal(t, patype.PA_ENC_TIMESTAMP, m.paType, "encrypted timestamp not selected")
+	}
+	assert.Nil(t, selectPreAuthMechanism(cl, types.PADataSequence{{PADataType: patype.PA_OTP_CHALLENGE}}, tried), "no mechanism should be selected for unsupported hints")
+	tried[patype.PA_ENC_TIMESTAMP] = true
+	assert.Nil(t, selectPreAuthMechanism(cl, hints, tried), "mechanism already tried should not be selected")
+}
+
+func TestReplacePAData(t *testing.T) {
+	t.Parallel()
+
+	var a messages.ASReq
+	replacePAData(&a, types.PAData{PADataType: patype.PA_REQ_ENC_PA_REP})
+	replacePAData(&a, types.PAData{PADataType: patype.PA_ENC_TIMESTAMP, PADataValue: []byte{1}})
+	replacePAData(&a, types.PAData{PADataType: patype.PA_ENC_TIMESTAMP, PADataValue: []byte{2}})
+	assert.Len(t, a.PAData, 2, "PAData not replaced")
+	assert.Equal(t, []byte{2}, a.PAData[1].PADataValue, "PAData value not replaced")
+}
+
+type offeredPreAuthPlugin int32
+
+func (p offeredPreAuthPlugin) PAType() int32 {
+	return int32(p)
+}
+
+func (p offeredPreAuthPlugin) Offered(hints types.PADataSequence) bool {
+	return hints.Contains(int32(p))
+}
+
+func (p offeredPreAuthPlugin) PAData(cl *Client, krberr messages.KRBError, ASReq messages.ASReq) (types.PAData, error) {
+	return types.PAData{PADataType: int32(p)}, nil
+}
+
+func (p offeredPreAuthPlugin) ProcessReply(cl *Client, ASRep *messages.ASRep) error {
+	return nil
+}
+
+func TestSelectPreAuthMechanism_Plugin(t *testing.T) {
+	t.Parallel()
+
+	cl := NewWithKeytab("username", "REALM", &keytab.Keytab{}, config.New(), PreAuthPlugins(offeredPreAuthPlugin(patype.PA_OTP_CHALLENGE)))
+	tried := make(map[int32]bool)
+	hints := types.PADataSequence{{PADataType: patype.PA_ETYPE_INFO2}, {PADataType: patype.PA_OTP_CHALLENGE}}
+
+	m := selectPreAuthMechanism(cl, hints, tried)
+	if assert.NotNil(t, m, "plugin mechanism should be selected") {
+		assert.Equal(t, patype.PA_OTP_CHALLENGE, m.paType, "plugin should be preferred over built in mechanisms")
+		var a messages.ASReq
+		assert.Error(t, m.setPAData(cl, nil, &a), "plugin should not be used without hints")
+		assert.NoError(t, m.setPAData(cl, &messages.KRBError{}, &a), "error setting plugin PA-DATA")
+		assert.True(t, a.PAData.Contains(patype.PA_OTP_CHALLENGE), "plugin PA-DATA not added to the AS_REQ")
+	}
+	tried[patype.PA_OTP_CHALLENGE] = true
+	m = selectPreAuthMechanism(cl, hints, tried)
+	if assert.NotNil(t, m, "built in mechanism should be selected once the plugin has been tried") {
+		assert.Equal(t, patype.PA_ENC_TIMESTAMP, m.paType, "encrypted timestamp not selected")
+	}
+}
@@ -0,0 +1,148 @@
+package client
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// prefetchRetry is how long a Prefetcher waits before requesting a ticket again after a request has failed.
+	prefetchRetry = time.Minute
+	// prefetchMinWait is the least time a Prefetcher waits between requests for a ticket, should the KDC issue
+	// tickets that are close to expiry.
+	prefetchMinWait = time.Second
+)
+
+// Prefetcher obtains tickets for a set of SPNs in the background and refreshes them before they expire, so that the
+// client's requests for tickets to these services are answered from its cache without waiting for the KDC.
+type Prefetcher struct {
+	client *Client
+	spns   []string
+	errs   map[string]error
+	ready  chan struct{}
+	stop   chan struct{}
+	once   sync.Once
+	wg     sync.WaitGroup
+	mux    sync.RWMutex
+}
+
+// prefetchers are the Prefetchers of a client, which are stopped when it is destroyed.
+type prefetchers struct {
+	set map[*Prefetcher]struct{}
+	mux sync.Mutex
+}
+
+// PrefetchTickets starts obtaining tickets for the SPNs in the background, requesting them from the KDC concurrently,
+// and keeps each refreshed once five sixths of its lifetime has passed. The Prefetcher should be stopped when the
+// tickets are no longer needed, it is stopped when the client is destroyed.
+func (cl *Client) PrefetchTickets(spns ...string) *Prefetcher {
+	p := &Prefetcher{
+		client: cl,
+		spns:   append([]string{}, spns...),
+		errs:   make(map[string]error),
+		ready:  make(chan struct{}),
+		stop:   make(chan struct{}),
+	}
+	cl.prefetchers.add(p)
+	var first sync.WaitGroup
+	for _, spn := range p.spns {
+		first.Add(1)
+		p.wg.Add(1)
+		go p.run(spn, &first)
+	}
+	go func() {
+		first.Wait()
+		close(p.ready)
+	}()
+	return p
+}
+
+// Ready returns a channel that is closed once the first request for each of the SPNs' tickets has completed.
+func (p *Prefetcher) Ready() <-chan struct{} {
+	return p.ready
+}
+
+// Errors returns the errors of the latest requests for the SPNs' tickets that failed.
+func (p *Prefetcher) Errors() map[string]error {
+	p.mux.RLock()
+	defer p.mux.RUnlock()
+	m := make(map[string]error, len(p.errs))
+	for k, v := range p.errs {
+		m[k] = v
+	}
+	return m
+}
+
+// Stop stops refreshing the tickets and waits for any requests in progress to complete. The tickets already obtained
+// remain in the client's cache.
+func (p *Prefetcher) Stop() {
+	p.once.Do(func() {
+		close(p.stop)
+	})
+	p.wg.Wait()
+	p.client.prefetchers.remove(p)
+}
+
+// run obtains and refreshes the ticket for the SPN until the Prefetcher is stopped.
+func (p *Prefetcher) run(spn string, first *sync.WaitGroup) {
+	defer p.wg.Done()
+	_, _, err := p.client.GetServiceTicket(spn)
+	for {
+		if first != nil {
+			first.Done()
+			first = nil
+		}
+		p.mux.Lock()
+		if err != nil {
+			p.client.Log("error prefetching ticket for %s: %v", spn, err)
+			p.errs[spn] = err
+		} else {
+			delete(p.errs, spn)
+		}
+		p.mux.Unlock()
+		w := prefetchRetry
+		if e, ok := p.client.cache.getEntry(spn); ok && err == nil {
+			w = e.StartTime.Add(e.EndTime.Sub(e.StartTime) * 5 / 6).Sub(p.client.Now())
+		}
+		if w < prefetchMinWait {
+			w = prefetchMinWait
+		}
+		timer := time.NewTimer(w)
+		select {
+		case <-timer.C:
+			p.client.trace("Refreshing prefetched ticket for %s", spn)
+			_, _, err = p.client.requestServiceTicket(spn)
+		case <-p.stop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// add records the Prefetcher.
+func (ps *prefetchers) add(p *Prefetcher) {
+	ps.mux.Lock()
+	defer ps.mux.Unlock()
+	if ps.set == nil {
+		ps.set = make(map[*Prefetcher]struct{})
+	}
+	ps.set[p] = struct{}{}
+}
+
+// remove forgets the Prefetcher.
+func (ps *prefetchers) remove(p *Prefetcher) {
+	ps.mux.Lock()
+	defer ps.mux.Unlock()
+	delete(ps.set, p)
+}
+
+// stopAll stops all the Prefetchers.
+func (ps *prefetchers) stopAll() {
+	ps.mux.Lock()
+	set := ps.set
+	ps.set = nil
+	ps.mux.Unlock()
+	for p := range set {
+		p.Stop()
+	}
+}
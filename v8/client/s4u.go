@@ -0,0 +1,164 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/asn1"
+	"github.com/jcmturner/gokrb5/v8/iana/errorcode"
+	"github.com/jcmturner/gokrb5/v8/iana/flags"
+	"github.com/jcmturner/gokrb5/v8/krberror"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// EvidenceTicket is a ticket to the client's own service on behalf of a user, obtained with S4U2Self.
+// If it is forwardable it can be presented with S4U2Proxy for tickets to other services as the user.
+type EvidenceTicket struct {
+	CName   types.PrincipalName
+	CRealm  string
+	Ticket  messages.Ticket
+	Key     types.EncryptionKey
+	Flags   asn1.BitString
+	EndTime time.Time
+}
+
+// Forwardable reports if the evidence ticket can be used with S4U2Proxy.
+func (e EvidenceTicket) Forwardable() bool {
+	return types.IsFlagSet(&e.Flags, flags.Forwardable)
+}
+
+// S4U2SelfOptions are the optional PAC controls of an S4U2Self request [MS-KILE].
+type S4U2SelfOptions struct {
+	// NoPAC requests an evidence ticket without a PAC with a PA-PAC-REQUEST.
+	NoPAC bool
+	// PACOptions are the flags.PACOption values to request with a PA-PAC-OPTIONS, such as flags.PACOptionClaims.
+	PACOptions []int
+}
+
+// S4UPolicyError is returned when the KDC's policy refuses an S4U request, for example as the service is not trusted
+// to delegate to the target service, the user cannot be delegated or the KDC requires a PAC that was not requested.
+// The KDC's KRBError is kept as the cause so its error code can be inspected with errors.As.
+type S4UPolicyError struct {
+	Request  string
+	KRBError messages.KRBError
+}
+
+// Error implements the error interface.
+func (e S4UPolicyError) Error() string {
+	return fmt.Sprintf("%s refused by KDC policy: %s", e.Request, e.KRBError.Error())
+}
+
+// Unwrap returns the KDC's KRBError.
+func (e S4UPolicyError) Unwrap() error {
+	return e.KRBError
+}
+
+// s4uError returns an S4UPolicyError if the error of the S4U request is a KDC policy refusal.
+func s4uError(request string, err error) error {
+	var krberr messages.KRBError
+	if errors.As(err, &krberr) {
+		switch krberr.ErrorCode {
+		case errorcode.KDC_ERR_POLICY, errorcode.KDC_ERR_BADOPTION:
+			return S4UPolicyError{Request: request, KRBError: krberr}
+		}
+	}
+	return err
+}
+
+// S4U2Self requests a ticket to the client's own service on behalf of the user, for a service that has authenticated
+// the user by other means (protocol transition). The client must be logged in as the service.
+// The KDC issues a forwardable ticket only if it trusts the service to delegate on behalf of users.
+// The ticket is not cached. https://docs.microsoft.com/en-us/openspecs/windows_protocols/ms-sfu
+func (cl *Client) S4U2Self(user types.PrincipalName, userRealm string) (EvidenceTicket, error) {
+	return cl.S4U2SelfWithOptions(user, userRealm, S4U2SelfOptions{})
+}
+
+// S4U2SelfWithOptions requests a ticket on behalf of the user as S4U2Self, with the PAC controls of the options.
+// Refusals by the KDC's policy, such as for an evidence ticket without a PAC, are returned as an S4UPolicyError.
+func (cl *Client) S4U2SelfWithOptions(user types.PrincipalName, userRealm string, opts S4U2SelfOptions) (EvidenceTicket, error) {
+	realm := cl.Realm()
+	tgt, skey, err := cl.sessionTGT(realm)
+	if err != nil {
+		return EvidenceTicket{}, err
+	}
+	pa, err := types.NewPAForUser(user, userRealm, skey)
+	if err != nil {
+		return EvidenceTicket{}, krberror.Errorf(err, krberror.ChksumError, "S4U2Self: error generating PA-FOR-USER")
+	}
+	pad, err := pa.PAData()
+	if err != nil {
+		return EvidenceTicket{}, krberror.Errorf(err, krberror.EncodingError, "S4U2Self: error generating PA-FOR-USER")
+	}
+	pas := types.PADataSequence{pad}
+	if opts.NoPAC {
+		pr := types.PACRequest{IncludePAC: false}
+		pad, err := pr.PAData()
+		if err != nil {
+			return EvidenceTicket{}, krberror.Errorf(err, krberror.EncodingError, "S4U2Self: error generating PA-PAC-REQUEST")
+		}
+		pas = append(pas, pad)
+	}
+	if len(opts.PACOptions) > 0 {
+		po := types.NewPACOptions(opts.PACOptions)
+		pad, err := po.PAData()
+		if err != nil {
+			return EvidenceTicket{}, krberror.Errorf(err, krberror.EncodingError, "S4U2Self: error generating PA-PAC-OPTIONS")
+		}
+		pas = append(pas, pad)
+	}
+	cl.trace("Requesting S4U2Self ticket for %s@%s, PAC requested %t", user.PrincipalNameString(), userRealm, !opts.NoPAC)
+	tgsRep, err := cl.tgsExchangeWithTGT(cl.CName(), realm, tgt, skey, messages.TGSReqOptions{
+		KDCOptions: []int{flags.Forwardable},
+		PAData:     pas,
+	})
+	if err != nil {
+		return EvidenceTicket{}, s4uError("S4U2Self", err)
+	}
+	return EvidenceTicket{
+		CName:   tgsRep.CName,
+		CRealm:  tgsRep.CRealm,
+		Ticket:  tgsRep.Ticket,
+		Key:     tgsRep.DecryptedEncPart.Key,
+		Flags:   tgsRep.DecryptedEncPart.Flags,
+		EndTime: tgsRep.DecryptedEncPart.EndTime,
+	}, nil
+}
+
+// S4U2Proxy requests a ticket for the SPN on behalf of the user of the evidence ticket (constrained delegation).
+// The evidence ticket must be forwardable and the KDC must allow the client's service to delegate to the SPN, which
+// is expected to be in the client's realm. Refusals by the KDC's policy are returned as an S4UPolicyError.
+// The ticket is not cached.
+func (cl *Client) S4U2Proxy(evidence EvidenceTicket, spn string) (messages.Ticket, types.EncryptionKey, error) {
+	if !evidence.Forwardable() {
+		return messages.Ticket{}, types.EncryptionKey{}, krberror.NewErrorf(krberror.KRBMsgError, "S4U2Proxy: evidence ticket for %s@%s is not forwardable", evidence.CName.PrincipalNameString(), evidence.CRealm)
+	}
+	princ, _ := types.ParseSPNString(spn)
+	cl.trace("Requesting S4U2Proxy ticket for %s on behalf of %s@%s", spn, evidence.CName.PrincipalNameString(), evidence.CRealm)
+	tgsRep, err := cl.tgsExchangeWithOptions(princ, cl.Realm(), messages.TGSReqOptions{
+		KDCOptions:        []int{flags.Forwardable, flags.CNameInAdditionalTicket},
+		AdditionalTickets: []messages.Ticket{evidence.Ticket},
+	})
+	if err != nil {
+		return messages.Ticket{}, types.EncryptionKey{}, s4uError("S4U2Proxy", err)
+	}
+	if !tgsRep.CName.Equal(evidence.CName) || !cl.Config.RealmEqual(tgsRep.CRealm, evidence.CRealm) {
+		return messages.Ticket{}, types.EncryptionKey{}, krberror.NewErrorf(krberror.KRBMsgError, "S4U2Proxy: ticket issued for %s@%s rather than %s@%s", tgsRep.CName.PrincipalNameString(), tgsRep.CRealm, evidence.CName.PrincipalNameString(), evidence.CRealm)
+	}
+	return tgsRep.Ticket, tgsRep.DecryptedEncPart.Key, nil
+}
+
+// GetServiceTicketForUser requests a ticket for the SPN on behalf of the user, which is of the form user@REALM or is
+// in the client's realm, with S4U2Self followed by S4U2Proxy. See S4U2Self and S4U2Proxy.
+func (cl *Client) GetServiceTicketForUser(user, spn string) (messages.Ticket, types.EncryptionKey, error) {
+	pn, realm := types.ParseSPNString(user)
+	if realm == "" {
+		realm = cl.Realm()
+	}
+	evidence, err := cl.S4U2Self(pn, realm)
+	if err != nil {
+		return messages.Ticket{}, types.EncryptionKey{}, err
+	}
+	return cl.S4U2Proxy(evidence, spn)
+}
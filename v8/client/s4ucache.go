@@ -0,0 +1,172 @@
+package client
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// S4UCacheStats are the counts of the lookups and removals of an S4UCache's entries since it was created.
+type S4UCacheStats struct {
+	Hits        uint64 // Lookups answered from the cache.
+	Misses      uint64 // Lookups requiring an S4U request to the KDC.
+	Evictions   uint64 // Entries removed, least recently used first, to keep within the maximum number of entries.
+	Expirations uint64 // Entries removed as their TTL or ticket had expired.
+	Entries     int    // The number of entries currently cached.
+}
+
+// S4UCache caches the evidence tickets obtained with S4U2Self and the service tickets obtained with S4U2Proxy for each
+// user a gateway acts on behalf of, so that the S4U exchanges are not repeated for every request the gateway serves.
+// Entries are kept until their TTL or ticket expires, whichever is first, and the least recently used entries are
+// evicted once the maximum number of entries is reached. It is safe for concurrent use.
+type S4UCache struct {
+	client  *Client
+	ttl     time.Duration
+	max     int
+	entries map[string]*list.Element
+	lru     *list.List
+	stats   S4UCacheStats
+	mux     sync.Mutex
+}
+
+// s4uCacheEntry is a cached evidence ticket or a service ticket obtained with it.
+type s4uCacheEntry struct {
+	key      string
+	evidence EvidenceTicket
+	ticket   messages.Ticket
+	skey     types.EncryptionKey
+	expires  time.Time
+}
+
+// NewS4UCache returns an S4UCache obtaining tickets with the client, which must be logged in as the gateway's service
+// principal. Entries are kept for no longer than the TTL, or for as long as their tickets are valid if it is not
+// positive, and no more than the maximum number of entries are kept, without limit if it is not positive.
+func NewS4UCache(cl *Client, ttl time.Duration, maxEntries int) *S4UCache {
+	return &S4UCache{
+		client:  cl,
+		ttl:     ttl,
+		max:     maxEntries,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// S4U2Self returns the evidence ticket on behalf of the user, from the cache or requested with the client's S4U2Self.
+func (c *S4UCache) S4U2Self(user types.PrincipalName, userRealm string) (EvidenceTicket, error) {
+	k := user.PrincipalNameString() + "@" + userRealm
+	if e, ok := c.get(k); ok {
+		return e.evidence, nil
+	}
+	evidence, err := c.client.S4U2Self(user, userRealm)
+	if err != nil {
+		return EvidenceTicket{}, err
+	}
+	c.add(&s4uCacheEntry{key: k, evidence: evidence})
+	return evidence, nil
+}
+
+// GetServiceTicketForUser returns a ticket for the SPN on behalf of the user, which is of the form user@REALM or is in
+// the client's realm, as the client's GetServiceTicketForUser does. The ticket, and the evidence ticket it is obtained
+// with, are taken from the cache where possible.
+func (c *S4UCache) GetServiceTicketForUser(user, spn string) (messages.Ticket, types.EncryptionKey, error) {
+	pn, realm := types.ParseSPNString(user)
+	if realm == "" {
+		realm = c.client.Realm()
+	}
+	k := pn.PrincipalNameString() + "@" + realm + " " + spn
+	if e, ok := c.get(k); ok {
+		return e.ticket, e.skey, nil
+	}
+	evidence, err := c.S4U2Self(pn, realm)
+	if err != nil {
+		return messages.Ticket{}, types.EncryptionKey{}, err
+	}
+	tkt, skey, err := c.client.S4U2Proxy(evidence, spn)
+	if err != nil {
+		return messages.Ticket{}, types.EncryptionKey{}, err
+	}
+	// The KDC does not issue a ticket with S4U2Proxy that outlives the evidence ticket.
+	c.add(&s4uCacheEntry{key: k, evidence: evidence, ticket: tkt, skey: skey})
+	return tkt, skey, nil
+}
+
+// RemoveUser removes the entries of the user, of the form user@REALM, such as when the user logs out of the gateway.
+func (c *S4UCache) RemoveUser(user string) {
+	pn, realm := types.ParseSPNString(user)
+	if realm == "" {
+		realm = c.client.Realm()
+	}
+	u := pn.PrincipalNameString() + "@" + realm
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for k, el := range c.entries {
+		if k == u || strings.HasPrefix(k, u+" ") {
+			c.remove(el)
+		}
+	}
+}
+
+// Stats returns the counts of the cache's lookups and removals.
+func (c *S4UCache) Stats() S4UCacheStats {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	s := c.stats
+	s.Entries = c.lru.Len()
+	return s
+}
+
+// get returns the unexpired entry for the key, removing it if it has expired.
+func (c *S4UCache) get(k string) (*s4uCacheEntry, bool) {
+	now := c.client.Now()
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	el, ok := c.entries[k]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	e := el.Value.(*s4uCacheEntry)
+	if !now.Before(e.expires) {
+		c.remove(el)
+		c.stats.Expirations++
+		c.stats.Misses++
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	c.stats.Hits++
+	return e, true
+}
+
+// add caches the entry, evicting the least recently used entries to keep within the maximum number of entries.
+func (c *S4UCache) add(e *s4uCacheEntry) {
+	now := c.client.Now()
+	e.expires = e.evidence.EndTime
+	if c.ttl > 0 && now.Add(c.ttl).Before(e.expires) {
+		e.expires = now.Add(c.ttl)
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if el, ok := c.entries[e.key]; ok {
+		c.remove(el)
+	}
+	c.entries[e.key] = c.lru.PushFront(e)
+	for c.max > 0 && c.lru.Len() > c.max {
+		el := c.lru.Back()
+		if now.Before(el.Value.(*s4uCacheEntry).expires) {
+			c.stats.Evictions++
+		} else {
+			c.stats.Expirations++
+		}
+		c.remove(el)
+	}
+}
+
+// remove removes the entry of the list element. The caller must hold the lock.
+func (c *S4UCache) remove(el *list.Element) {
+	delete(c.entries, el.Value.(*s4uCacheEntry).key)
+	c.lru.Remove(el)
+}
@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/jcmturner/gokrb5/v8/asn1"
 	"github.com/jcmturner/gokrb5/v8/iana/nametype"
 	"github.com/jcmturner/gokrb5/v8/krberror"
 	"github.com/jcmturner/gokrb5/v8/messages"
@@ -41,7 +42,10 @@ func (s *sessions) update(sess *session) {
 			// Cancel the one in the cache and add this one.
 			i.mux.Lock()
 			defer i.mux.Unlock()
-			i.cancel <- true
+			// Sessions loaded from a CCache are not automatically renewed so have no cancel channel
+			if i.cancel != nil {
+				i.cancel <- true
+			}
 			s.Entries[sess.realm] = sess
 			return
 		}
@@ -62,8 +66,10 @@ func (s *sessions) get(realm string) (*session, bool) {
 type session struct {
 	realm                string
 	authTime             time.Time
+	startTime            time.Time
 	endTime              time.Time
 	renewTill            time.Time
+	flags                asn1.BitString
 	tgt                  messages.Ticket
 	sessionKey           types.EncryptionKey
 	sessionKeyExpiration time.Time
@@ -83,7 +89,7 @@ type jsonSession struct {
 // AddSession adds a session for a realm with a TGT to the client's session cache.
 // A goroutine is started to automatically renew the TGT before expiry.
 func (cl *Client) addSession(tgt messages.Ticket, dep messages.EncKDCRepPart) {
-	if strings.ToLower(tgt.SName.NameString[0]) != "krbtgt" {
+	if !strings.EqualFold(tgt.SName.NameString[0], "krbtgt") {
 		// Not a TGT
 		return
 	}
@@ -91,8 +97,10 @@ func (cl *Client) addSession(tgt messages.Ticket, dep messages.EncKDCRepPart) {
 	s := &session{
 		realm:                realm,
 		authTime:             dep.AuthTime,
+		startTime:            dep.StartTime,
 		endTime:              dep.EndTime,
 		renewTill:            dep.RenewTill,
+		flags:                dep.Flags,
 		tgt:                  tgt,
 		sessionKey:           dep.Key,
 		sessionKeyExpiration: dep.KeyExpiration,
@@ -107,8 +115,10 @@ func (s *session) update(tgt messages.Ticket, dep messages.EncKDCRepPart) {
 	s.mux.Lock()
 	defer s.mux.Unlock()
 	s.authTime = dep.AuthTime
+	s.startTime = dep.StartTime
 	s.endTime = dep.EndTime
 	s.renewTill = dep.RenewTill
+	s.flags = dep.Flags
 	s.tgt = tgt
 	s.sessionKey = dep.Key
 	s.sessionKeyExpiration = dep.KeyExpiration
@@ -188,7 +198,7 @@ func (cl *Client) enableAutoSessionRenewal(s *session) {
 	go func(s *session) {
 		for {
 			s.mux.RLock()
-			w := (s.endTime.Sub(time.Now().UTC()) * 5) / 6
+			w := (s.endTime.Sub(cl.Now()) * 5) / 6
 			s.mux.RUnlock()
 			if w < 0 {
 				return
@@ -220,7 +230,7 @@ func (cl *Client) renewTGT(s *session) error {
 		NameType:   nametype.KRB_NT_SRV_INST,
 		NameString: []string{"krbtgt", realm},
 	}
-	_, tgsRep, err := cl.TGSREQGenerateAndExchange(spn, cl.Credentials.Domain(), tgt, skey, true)
+	_, tgsRep, err := cl.TGSREQGenerateAndExchange(spn, tgt.Realm, tgt, skey, true)
 	if err != nil {
 		return krberror.Errorf(err, krberror.KRBMsgError, "error renewing TGT for %s", realm)
 	}
@@ -238,21 +248,35 @@ func (cl *Client) refreshSession(s *session) (bool, error) {
 	renewTill := s.renewTill
 	s.mux.RUnlock()
 	cl.Log("refreshing TGT session for %s", realm)
-	if time.Now().UTC().Before(renewTill) {
+	if cl.Now().Before(renewTill) {
 		err := cl.renewTGT(s)
+		if err != nil {
+			cl.sessionEvent(EventRenewalFailed, s, err)
+		}
 		return true, err
 	}
 	err := cl.realmLogin(realm)
+	if err != nil {
+		cl.sessionEvent(EventRenewalFailed, s, err)
+	}
 	return false, err
 }
 
+// sessionEvent reports an event for the TGT of the session.
+func (cl *Client) sessionEvent(t EventType, s *session, err error) {
+	s.mux.RLock()
+	tgt, endTime, renewTill := s.tgt, s.endTime, s.renewTill
+	s.mux.RUnlock()
+	cl.ticketEvent(t, tgt, endTime, renewTill, err)
+}
+
 // ensureValidSession makes sure there is a valid session for the realm
 func (cl *Client) ensureValidSession(realm string) error {
 	s, ok := cl.sessions.get(realm)
 	if ok {
 		s.mux.RLock()
 		d := s.endTime.Sub(s.authTime) / 6
-		if s.endTime.Sub(time.Now().UTC()) > d {
+		if s.endTime.Sub(cl.Now()) > d {
 			s.mux.RUnlock()
 			return nil
 		}
@@ -3,7 +3,13 @@ package client
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/clock"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/messages"
 )
 
 // Settings holds optional client settings.
@@ -12,12 +18,32 @@ type Settings struct {
 	assumePreAuthentication bool
 	preAuthEType            int32
 	logger                  *log.Logger
+	clock                   clock.Clock
+	transport               Transport
+	strictClientName        bool
+	heimdalCompat           bool
+	enterpriseName          bool
+	keyExport               *keyExport
+	preAuthPlugins          []PreAuthPlugin
+	requestHooks            []RequestHook
+	replyHooks              []ReplyHook
+	eventHooks              []EventHook
+	kdcStats                *kdcStats
+	tracer                  *tracer
+	clientKeytab            *keytab.Keytab
 }
 
 // jsonSettings is used when marshaling the Settings details to JSON format.
 type jsonSettings struct {
 	DisablePAFXFast         bool
 	AssumePreAuthentication bool
+	StrictClientName        bool
+	HeimdalCompat           bool
+	EnterpriseName          bool
+	LatencyAwareKDCs        bool
+	UnsafeSessionKeyExport  bool
+	Trace                   bool
+	ClientKeytab            bool
 }
 
 // NewSettings creates a new client settings struct.
@@ -71,6 +97,253 @@ func (s *Settings) Logger() *log.Logger {
 	return s.logger
 }
 
+// Clock used to configure the client with the clock it reads the current time from.
+// This is intended to enable deterministic testing of time dependent behaviour.
+//
+// s := NewSettings(Clock(c))
+func Clock(c clock.Clock) func(*Settings) {
+	return func(s *Settings) {
+		s.clock = c
+	}
+}
+
+// Clock returns the clock the client reads the current time from.
+func (s *Settings) Clock() clock.Clock {
+	if s == nil || s.clock == nil {
+		return clock.System
+	}
+	return s.clock
+}
+
+// KDCTransport used to configure the client with the transport it sends messages to the KDC with in place of the
+// UDP and TCP transports defined by the krb5 configuration.
+//
+// s := NewSettings(KDCTransport(t))
+func KDCTransport(t Transport) func(*Settings) {
+	return func(s *Settings) {
+		s.transport = t
+	}
+}
+
+// KDCTransport returns the transport the client is configured to send messages to the KDC with.
+// Nil is returned if the client uses the network transports defined by the krb5 configuration.
+func (s *Settings) KDCTransport() Transport {
+	if s == nil {
+		return nil
+	}
+	return s.transport
+}
+
+// StrictClientName used to configure the client to reject an AS_REP with a client name other than that requested,
+// even if the KDC was asked to canonicalize the name.
+//
+// s := NewSettings(StrictClientName(true))
+func StrictClientName(b bool) func(*Settings) {
+	return func(s *Settings) {
+		s.strictClientName = b
+	}
+}
+
+// StrictClientName indicates if the client will reject an AS_REP with a client name other than that requested.
+func (s *Settings) StrictClientName() bool {
+	return s.strictClientName
+}
+
+// HeimdalCompat used to configure the client to accept the known deviations of Heimdal KDCs from the checks the
+// client makes of their replies, such as host addresses copied from the TGT into service tickets.
+// Defaults to the strict checks if not specified.
+//
+// s := NewSettings(HeimdalCompat(true))
+func HeimdalCompat(b bool) func(*Settings) {
+	return func(s *Settings) {
+		s.heimdalCompat = b
+	}
+}
+
+// HeimdalCompat indicates if the client accepts the known deviations of Heimdal KDCs.
+func (s *Settings) HeimdalCompat() bool {
+	return s.heimdalCompat
+}
+
+// Quirks returns the relaxations of the checks of messages the client applies.
+func (s *Settings) Quirks() messages.Quirks {
+	if s != nil && s.heimdalCompat {
+		return messages.HeimdalQuirks
+	}
+	return messages.Quirks{}
+}
+
+// EnterpriseName used to configure the client to log in with its username as an enterprise principal name, such as
+// an Active Directory user principal name user@example.com. The KDC of the realm provided will refer the client to
+// the realm of the principal, which may be in another forest, and the client's realm will be that of its TGT.
+//
+// s := NewSettings(EnterpriseName(true))
+func EnterpriseName(b bool) func(*Settings) {
+	return func(s *Settings) {
+		s.enterpriseName = b
+	}
+}
+
+// EnterpriseName indicates if the client's username is an enterprise principal name.
+func (s *Settings) EnterpriseName() bool {
+	return s.enterpriseName
+}
+
+// PreAuthPlugins used to configure the client with pre-authentication mechanisms provided by the application.
+// When the KDC requires pre-authentication the plugins are tried, in the order provided, before the mechanisms the
+// client supports itself.
+//
+// s := NewSettings(PreAuthPlugins(p))
+func PreAuthPlugins(p ...PreAuthPlugin) func(*Settings) {
+	return func(s *Settings) {
+		s.preAuthPlugins = append(s.preAuthPlugins, p...)
+	}
+}
+
+// PreAuthPlugins returns the pre-authentication plugins the client is configured with.
+func (s *Settings) PreAuthPlugins() []PreAuthPlugin {
+	if s == nil {
+		return nil
+	}
+	return s.preAuthPlugins
+}
+
+// KDCRequestHooks used to configure the client with hooks that are called, in the order provided, with each request
+// before it is sent to the KDC.
+//
+// s := NewSettings(KDCRequestHooks(h))
+func KDCRequestHooks(h ...RequestHook) func(*Settings) {
+	return func(s *Settings) {
+		s.requestHooks = append(s.requestHooks, h...)
+	}
+}
+
+// KDCRequestHooks returns the hooks the client is configured to call with each request before it is sent.
+func (s *Settings) KDCRequestHooks() []RequestHook {
+	if s == nil {
+		return nil
+	}
+	return s.requestHooks
+}
+
+// KDCReplyHooks used to configure the client with hooks that are called, in the order provided, with each reply
+// received from the KDC.
+//
+// s := NewSettings(KDCReplyHooks(h))
+func KDCReplyHooks(h ...ReplyHook) func(*Settings) {
+	return func(s *Settings) {
+		s.replyHooks = append(s.replyHooks, h...)
+	}
+}
+
+// KDCReplyHooks returns the hooks the client is configured to call with each reply received from the KDC.
+func (s *Settings) KDCReplyHooks() []ReplyHook {
+	if s == nil {
+		return nil
+	}
+	return s.replyHooks
+}
+
+// EventHooks used to configure the client with hooks that are called, in the order provided, with the events of the
+// client's credentials such as the acquisition of tickets, renewal failures and password changes.
+//
+// s := NewSettings(EventHooks(Webhook(url, nil, nil)))
+func EventHooks(h ...EventHook) func(*Settings) {
+	return func(s *Settings) {
+		s.eventHooks = append(s.eventHooks, h...)
+	}
+}
+
+// EventHooks returns the hooks the client is configured to call with the events of its credentials.
+func (s *Settings) EventHooks() []EventHook {
+	if s == nil {
+		return nil
+	}
+	return s.eventHooks
+}
+
+// ClientKeytab used to configure a client keytab from which a client without a password or keytab of its own, such
+// as one created from a credentials cache, acquires initial credentials on demand when it has no valid TGT. This is
+// the client keytab of MIT Kerberos, named by KRB5_CLIENT_KTNAME, which daemons rely on to keep their credentials
+// without a separate process renewing the credentials cache.
+//
+// s := NewSettings(ClientKeytab(kt))
+func ClientKeytab(kt *keytab.Keytab) func(*Settings) {
+	return func(s *Settings) {
+		s.clientKeytab = kt
+	}
+}
+
+// ClientKeytab returns any client keytab configured.
+func (s *Settings) ClientKeytab() *keytab.Keytab {
+	if s == nil {
+		return nil
+	}
+	return s.clientKeytab
+}
+
+// LatencyAwareKDCs used to configure the client to track the response latency and failures of the KDCs it sends to
+// and to try the fastest KDC that is responding first, in place of the order of the krb5 configuration or DNS SRV
+// records. Each KDC is measured again once its measurement is older than the reprobe interval, so that the client
+// notices KDCs that have recovered or become faster.
+//
+// s := NewSettings(LatencyAwareKDCs(5 * time.Minute))
+func LatencyAwareKDCs(reprobe time.Duration) func(*Settings) {
+	return func(s *Settings) {
+		if reprobe <= 0 {
+			s.kdcStats = nil
+			return
+		}
+		s.kdcStats = newKDCStats(reprobe)
+	}
+}
+
+// LatencyAwareKDCs returns the interval after which the client measures a KDC again if it is configured to try the
+// fastest KDCs first. Zero is returned if the client tries the KDCs in the configured order.
+func (s *Settings) LatencyAwareKDCs() time.Duration {
+	if s == nil || s.kdcStats == nil {
+		return 0
+	}
+	return s.kdcStats.reprobe
+}
+
+// UnsafeSessionKeyExport used to configure the client to write the session keys and subkeys it negotiates to w in
+// the keytab format, so that captured traffic can be decrypted with Wireshark when diagnosing interoperability issues.
+//
+// This is unsafe: the keys written allow anyone holding them to decrypt the client's traffic and to use its tickets
+// until they expire. It must not be enabled in production.
+//
+// s := NewSettings(UnsafeSessionKeyExport(w))
+func UnsafeSessionKeyExport(w io.Writer) func(*Settings) {
+	return func(s *Settings) {
+		s.keyExport = &keyExport{w: w}
+	}
+}
+
+// UnsafeSessionKeyExport indicates if the client is configured to export the session keys it negotiates.
+func (s *Settings) UnsafeSessionKeyExport() bool {
+	return s.keyExport != nil
+}
+
+// Trace used to configure the client to write a trace of the protocol decisions it makes, such as the KDCs
+// selected, the etypes negotiated, the pre-authentication data processed and the use of cached tickets, to the
+// writer provided. The format is that of the trace output of MIT's libkrb5 so the two can be compared when
+// debugging interoperability issues.
+// If the client is not configured to trace and the KRB5_TRACE environment variable is set the trace is appended to
+// the file it names.
+//
+// s := NewSettings(Trace(os.Stderr))
+func Trace(w io.Writer) func(*Settings) {
+	return func(s *Settings) {
+		s.tracer = &tracer{w: w}
+	}
+}
+
+// Trace indicates if the client is configured to write a trace of the protocol decisions it makes.
+func (s *Settings) Trace() bool {
+	return s.tracer != nil
+}
+
 // Log will write to the service's logger if it is configured.
 func (cl *Client) Log(format string, v ...interface{}) {
 	if cl.settings.Logger() != nil {
@@ -83,6 +356,13 @@ func (s *Settings) JSON() (string, error) {
 	js := jsonSettings{
 		DisablePAFXFast:         s.disablePAFXFast,
 		AssumePreAuthentication: s.assumePreAuthentication,
+		StrictClientName:        s.strictClientName,
+		HeimdalCompat:           s.heimdalCompat,
+		EnterpriseName:          s.enterpriseName,
+		LatencyAwareKDCs:        s.kdcStats != nil,
+		UnsafeSessionKeyExport:  s.keyExport != nil,
+		Trace:                   s.tracer != nil,
+		ClientKeytab:            s.clientKeytab != nil,
 	}
 	b, err := json.MarshalIndent(js, "", "  ")
 	if err != nil {
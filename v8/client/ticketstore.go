@@ -0,0 +1,179 @@
+package client
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/asn1"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// TicketStore persists the service tickets of a client so that they can be reloaded when an application restarts.
+// The bytes contain the tickets' session keys and must be stored securely.
+type TicketStore interface {
+	// Load returns the bytes last saved to the store. If nothing has been saved an error satisfying
+	// errors.Is(err, os.ErrNotExist) should be returned.
+	Load() ([]byte, error)
+	// Save replaces the bytes held by the store.
+	Save(b []byte) error
+}
+
+// storedTickets is the format the service tickets of a client are exported in.
+type storedTickets struct {
+	CName   types.PrincipalName
+	CRealm  string
+	Entries []storedTicket
+}
+
+// storedTicket holds a service ticket and its details as exported.
+type storedTicket struct {
+	Ticket    []byte
+	AuthTime  time.Time
+	StartTime time.Time
+	EndTime   time.Time
+	RenewTill time.Time
+	Flags     asn1.BitString
+	KeyType   int32
+	KeyValue  []byte
+}
+
+// ExportServiceTickets returns the client's cached service tickets that are yet to expire, or that can be renewed,
+// in a form that can be imported with ImportServiceTickets.
+// The bytes contain the tickets' session keys and must be stored securely.
+func (cl *Client) ExportServiceTickets() ([]byte, error) {
+	s := storedTickets{
+		CName:  cl.CName(),
+		CRealm: cl.Realm(),
+	}
+	now := cl.Now()
+	cl.cache.mux.RLock()
+	spns := make([]string, 0, len(cl.cache.Entries))
+	for spn := range cl.cache.Entries {
+		spns = append(spns, spn)
+	}
+	sort.Strings(spns)
+	for _, spn := range spns {
+		e := cl.cache.Entries[spn]
+		if !now.Before(e.EndTime) && !now.Before(e.RenewTill) {
+			continue
+		}
+		b, err := e.Ticket.Marshal()
+		if err != nil {
+			cl.cache.mux.RUnlock()
+			return nil, fmt.Errorf("error marshaling ticket for %s: %w", e.SPN, err)
+		}
+		s.Entries = append(s.Entries, storedTicket{
+			Ticket:    b,
+			AuthTime:  e.AuthTime,
+			StartTime: e.StartTime,
+			EndTime:   e.EndTime,
+			RenewTill: e.RenewTill,
+			Flags:     e.Flags,
+			KeyType:   e.SessionKey.KeyType,
+			KeyValue:  e.SessionKey.KeyValue,
+		})
+	}
+	cl.cache.mux.RUnlock()
+	return json.Marshal(s)
+}
+
+// ImportServiceTickets adds the service tickets exported with ExportServiceTickets to the client's cache.
+// Tickets that have expired and can no longer be renewed are ignored. An error is returned if the tickets were
+// exported by a client with a different principal.
+func (cl *Client) ImportServiceTickets(b []byte) error {
+	var s storedTickets
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("error unmarshaling service tickets: %w", err)
+	}
+	if !s.CName.Equal(cl.CName()) || !cl.Config.RealmEqual(s.CRealm, cl.Realm()) {
+		return fmt.Errorf("service tickets are for %s@%s not %s@%s", s.CName.PrincipalNameString(), s.CRealm, cl.CName().PrincipalNameString(), cl.Realm())
+	}
+	now := cl.Now()
+	for _, e := range s.Entries {
+		if !now.Before(e.EndTime) && !now.Before(e.RenewTill) {
+			continue
+		}
+		var tkt messages.Ticket
+		if err := tkt.Unmarshal(e.Ticket); err != nil {
+			return fmt.Errorf("service ticket bytes are not valid: %w", err)
+		}
+		key := types.EncryptionKey{KeyType: e.KeyType, KeyValue: e.KeyValue}
+		cl.cache.addEntry(tkt, e.AuthTime, e.StartTime, e.EndTime, e.RenewTill, e.Flags, key)
+		cl.trace("Imported ticket for %s from store, valid until %v", tkt.SName.PrincipalNameString(), e.EndTime)
+	}
+	return nil
+}
+
+// FileTicketStore is a TicketStore that saves the tickets to a file encrypted with AES-GCM.
+type FileTicketStore struct {
+	path string
+	aead cipher.AEAD
+}
+
+// NewFileTicketStore returns a TicketStore that saves to the file at the path provided, encrypted with the key.
+// The key must be 16, 24 or 32 bytes long to select AES-128, AES-192 or AES-256.
+func NewFileTicketStore(path string, key []byte) (*FileTicketStore, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ticket store key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &FileTicketStore{
+		path: path,
+		aead: aead,
+	}, nil
+}
+
+// Load reads and decrypts the file.
+func (f *FileTicketStore) Load() ([]byte, error) {
+	b, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return nil, err
+	}
+	n := f.aead.NonceSize()
+	if len(b) < n {
+		return nil, errors.New("ticket store file is too short")
+	}
+	pt, err := f.aead.Open(nil, b[:n], b[n:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt ticket store file: %w", err)
+	}
+	return pt, nil
+}
+
+// Save encrypts the bytes and replaces the file with them. The file is written to a temporary file first and renamed
+// so that a partially written file is never loaded.
+func (f *FileTicketStore) Save(b []byte) error {
+	nonce := make([]byte, f.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	ct := f.aead.Seal(nonce, nonce, b, nil)
+	tmp, err := ioutil.TempFile(filepath.Dir(f.path), filepath.Base(f.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(ct); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
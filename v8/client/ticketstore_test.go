@@ -0,0 +1,96 @@
+package client
+
+import (
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/test/testdata"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testStoreTicket(t *testing.T, spn string, endTime time.Time) (messages.Ticket, types.EncryptionKey) {
+	b, _ := hex.DecodeString(testdata.HTTP_KEYTAB)
+	kt := keytab.New()
+	require.NoError(t, kt.Unmarshal(b), "error unmarshaling keytab")
+	sname := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, spn)
+	aliasKt := keytab.New()
+	for _, e := range kt.Entries {
+		aliasKt.AddKey(sname, "TEST.GOKRB5", e.Key, e.Timestamp, e.KVNO8)
+	}
+	cname := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "testuser1")
+	st := endTime.Add(-time.Hour)
+	tkt, key, err := messages.NewTicket(cname, "TEST.GOKRB5", sname, "TEST.GOKRB5", types.NewKrbFlags(), aliasKt, 18, 1, st, st, endTime, time.Time{})
+	require.NoError(t, err, "error getting test ticket")
+	return tkt, key
+}
+
+func TestClient_ExportServiceTickets(t *testing.T) {
+	t.Parallel()
+	cfg := config.New()
+	cl := NewWithPassword("testuser1", "TEST.GOKRB5", "passwordvalue", cfg)
+	now := time.Now().UTC()
+	tkt, key := testStoreTicket(t, "HTTP/host.test.gokrb5", now.Add(time.Hour))
+	cl.cache.addEntry(tkt, now, now, now.Add(time.Hour), time.Time{}, types.NewKrbFlags(), key)
+	expired, ekey := testStoreTicket(t, "HTTP/expired.test.gokrb5", now.Add(-time.Minute))
+	cl.cache.addEntry(expired, now, now, now.Add(-time.Minute), time.Time{}, types.NewKrbFlags(), ekey)
+
+	b, err := cl.ExportServiceTickets()
+	require.NoError(t, err, "error exporting service tickets")
+	b2, err := cl.ExportServiceTickets()
+	require.NoError(t, err, "error exporting service tickets")
+	assert.Equal(t, b, b2, "export of the same tickets should not change")
+
+	cl2 := NewWithPassword("testuser1", "TEST.GOKRB5", "passwordvalue", cfg)
+	require.NoError(t, cl2.ImportServiceTickets(b), "error importing service tickets")
+	itkt, ikey, ok := cl2.GetCachedTicket("HTTP/host.test.gokrb5")
+	require.True(t, ok, "imported ticket not in cache")
+	assert.Equal(t, tkt.EncPart.Cipher, itkt.EncPart.Cipher, "imported ticket not as expected")
+	assert.Equal(t, key, ikey, "imported session key not as expected")
+	_, ok = cl2.cache.getEntry("HTTP/expired.test.gokrb5")
+	assert.False(t, ok, "expired ticket should not be exported")
+
+	other := NewWithPassword("testuser2", "TEST.GOKRB5", "passwordvalue", cfg)
+	assert.Error(t, other.ImportServiceTickets(b), "tickets of another client should not be imported")
+	assert.Error(t, cl2.ImportServiceTickets([]byte("invalid")), "invalid bytes should not be imported")
+}
+
+func TestFileTicketStore(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "ticketstore")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tickets")
+	key := make([]byte, 32)
+
+	s, err := NewFileTicketStore(path, key)
+	require.NoError(t, err, "error creating store")
+	_, err = s.Load()
+	assert.True(t, errors.Is(err, os.ErrNotExist), "loading an unsaved store should return a not exists error: %v", err)
+	require.NoError(t, s.Save([]byte("tickets")), "error saving to store")
+	b, err := s.Load()
+	require.NoError(t, err, "error loading from store")
+	assert.Equal(t, []byte("tickets"), b, "loaded bytes not as expected")
+	fb, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(fb), "tickets", "file should be encrypted")
+
+	key[0] = 1
+	s, err = NewFileTicketStore(path, key)
+	require.NoError(t, err, "error creating store")
+	_, err = s.Load()
+	assert.Error(t, err, "file should not be decrypted with another key")
+
+	_, err = NewFileTicketStore(path, []byte("short"))
+	assert.Error(t, err, "invalid key length should be rejected")
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/iana/errorcode"
+	"github.com/jcmturner/gokrb5/v8/messages"
+)
+
+// timeOffset holds the difference between the KDC's clock and the local clock.
+// This implements the kdc_timesync behaviour of MIT's libkrb5 so that a client on a host with an inaccurate clock
+// can continue to authenticate.
+type timeOffset struct {
+	d   time.Duration
+	mux sync.RWMutex
+}
+
+// get returns the current offset.
+func (o *timeOffset) get() time.Duration {
+	o.mux.RLock()
+	defer o.mux.RUnlock()
+	return o.d
+}
+
+// set the offset to the duration provided.
+func (o *timeOffset) set(d time.Duration) {
+	o.mux.Lock()
+	defer o.mux.Unlock()
+	o.d = d
+}
+
+// timeSync indicates if the client is configured to correct for clock offsets with the KDC.
+func (cl *Client) timeSync() bool {
+	return cl.Config != nil && cl.Config.LibDefaults.KDCTimeSync == 1
+}
+
+// TimeOffset returns the offset between the KDC's clock and the local clock that the client is correcting for.
+// This is zero unless kdc_timesync is enabled in the client's configuration and an offset has been observed.
+func (cl *Client) TimeOffset() time.Duration {
+	return cl.timeOffset.get()
+}
+
+// Now returns the current time according to the client's clock, corrected for any offset with the KDC's clock.
+func (cl *Client) Now() time.Time {
+	return cl.settings.Clock().Now().UTC().Add(cl.timeOffset.get())
+}
+
+// syncTime records the offset between the time provided, as reported by the KDC, and the local clock.
+// The offset is truncated to whole seconds, the precision of the KDC's timestamps, so that the microseconds of the
+// client's timestamps continue to come from the local clock. Otherwise the sub-second offset cancels them out and
+// authenticators created within the same second carry near identical microseconds and may be taken to be replays.
+func (cl *Client) syncTime(kdcTime time.Time) {
+	if !cl.timeSync() || kdcTime.IsZero() {
+		return
+	}
+	d := kdcTime.Sub(cl.settings.Clock().Now().UTC()).Truncate(time.Second)
+	cl.timeOffset.set(d)
+	cl.Log("clock offset with KDC set to %v", d)
+}
+
+// SyncTimeFromKRBError records the offset with the KDC's clock using the server time within a KRB_AP_ERR_SKEW error.
+// The boolean returned indicates if the error could be used to correct the client's time.
+// Errors of this type received from a service may also be passed to this method so that a subsequent attempt
+// uses corrected timestamps.
+func (cl *Client) SyncTimeFromKRBError(e messages.KRBError) bool {
+	if !cl.timeSync() || e.ErrorCode != errorcode.KRB_AP_ERR_SKEW || e.STime.IsZero() {
+		return false
+	}
+	cl.syncTime(e.STime.Add(time.Duration(e.Susec) * time.Microsecond))
+	return true
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/iana/errorcode"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncTimeFromKRBError(t *testing.T) {
+	t.Parallel()
+
+	c := config.New()
+	cl := NewWithKeytab("username", "REALM", &keytab.Keytab{}, c)
+	e := messages.KRBError{
+		ErrorCode: errorcode.KRB_AP_ERR_SKEW,
+		STime:     time.Now().UTC().Add(time.Hour).Truncate(time.Second),
+	}
+	assert.True(t, cl.SyncTimeFromKRBError(e), "skew error should have been used to sync time")
+	assert.InDelta(t, float64(time.Hour), float64(cl.TimeOffset()), float64(2*time.Second), "offset not as expected")
+	assert.WithinDuration(t, e.STime, cl.Now(), 2*time.Second, "corrected time not as expected")
+
+	e.ErrorCode = errorcode.KDC_ERR_PREAUTH_FAILED
+	assert.False(t, cl.SyncTimeFromKRBError(e), "only skew errors should be used to sync time")
+
+	c.LibDefaults.KDCTimeSync = 0
+	cl = NewWithKeytab("username", "REALM", &keytab.Keytab{}, c)
+	e.ErrorCode = errorcode.KRB_AP_ERR_SKEW
+	assert.False(t, cl.SyncTimeFromKRBError(e), "time should not be synced when kdc_timesync is disabled")
+	assert.Equal(t, time.Duration(0), cl.TimeOffset(), "offset should be zero")
+}
+
+func TestSyncTime_WholeSeconds(t *testing.T) {
+	t.Parallel()
+	cl := NewWithKeytab("username", "REALM", &keytab.Keytab{}, config.New())
+	cl.syncTime(time.Now().UTC().Truncate(time.Second))
+	assert.Equal(t, time.Duration(0), cl.TimeOffset(), "an offset of less than a second should be ignored")
+	cl.syncTime(time.Now().UTC().Add(90 * time.Second).Truncate(time.Second))
+	assert.Equal(t, time.Duration(0), cl.TimeOffset()%time.Second, "offset should be whole seconds")
+	assert.InDelta(t, float64(90*time.Second), float64(cl.TimeOffset()), float64(time.Second), "offset not as expected")
+}
@@ -0,0 +1,143 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/iana/errorcode"
+	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
+	"github.com/jcmturner/gokrb5/v8/iana/patype"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// traceEnvVar is the environment variable naming the file trace output is written to, as used by MIT's libkrb5.
+const traceEnvVar = "KRB5_TRACE"
+
+// krb5ErrorBase is the base of the com_err error table MIT's libkrb5 reports Kerberos protocol error codes within.
+const krb5ErrorBase = -1765328384
+
+// tracer writes trace lines to a writer, serializing writes so lines from concurrent exchanges are not interleaved.
+type tracer struct {
+	w   io.Writer
+	mux sync.Mutex
+}
+
+// write writes a line of trace output in the format of MIT's KRB5_TRACE: the process ID and the time to the
+// microsecond followed by the message.
+func (t *tracer) write(msg string) {
+	now := time.Now()
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	fmt.Fprintf(t.w, "[%d] %d.%06d: %s\n", os.Getpid(), now.Unix(), now.Nanosecond()/1000, msg)
+}
+
+var (
+	envTracer     *tracer
+	envTracerOnce sync.Once
+)
+
+// traceFromEnv returns a tracer writing to the file named by KRB5_TRACE or nil if it is not set or cannot be opened.
+// The file is opened once and shared by all clients in the process.
+func traceFromEnv() *tracer {
+	envTracerOnce.Do(func() {
+		p := os.Getenv(traceEnvVar)
+		if p == "" {
+			return
+		}
+		f, err := os.OpenFile(p, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return
+		}
+		envTracer = &tracer{w: f}
+	})
+	return envTracer
+}
+
+// trace writes a line of trace output if the client is configured to trace or KRB5_TRACE is set.
+func (cl *Client) trace(format string, v ...interface{}) {
+	if cl.settings == nil {
+		return
+	}
+	t := cl.settings.tracer
+	if t == nil {
+		t = traceFromEnv()
+	}
+	if t == nil {
+		return
+	}
+	t.write(fmt.Sprintf(format, v...))
+}
+
+// tracing indicates if trace output is enabled so the formatting of expensive trace arguments can be avoided.
+func (cl *Client) tracing() bool {
+	return cl.settings != nil && (cl.settings.tracer != nil || traceFromEnv() != nil)
+}
+
+// traceETypes formats the etypes as a comma separated list of their names.
+func traceETypes(ids []int32) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = traceEType(id)
+	}
+	return strings.Join(s, ", ")
+}
+
+// traceEType formats the etype as its name or number if it is not assigned.
+func traceEType(id int32) string {
+	if n := etypeID.EtypeName(id); n != "" {
+		return n
+	}
+	return fmt.Sprintf("%d", id)
+}
+
+// tracePATypes formats the types of the PA-DATA as a comma separated list of their names and numbers.
+func tracePATypes(pas types.PADataSequence) string {
+	s := make([]string, len(pas))
+	for i, pa := range pas {
+		s[i] = fmt.Sprintf("%s (%d)", patype.Name(pa.PADataType), pa.PADataType)
+	}
+	return strings.Join(s, ", ")
+}
+
+// traceKRBError formats the KRBError as libkrb5 reports errors received from the KDC: the com_err code and message.
+func traceKRBError(e messages.KRBError) string {
+	msg := e.EText
+	if msg == "" {
+		msg = errorcode.Lookup(e.ErrorCode)
+	}
+	return fmt.Sprintf("%d/%s", krb5ErrorBase+int(e.ErrorCode), msg)
+}
+
+// traceETypeInfo formats the etype and salt the KDC has advised for pre-authentication.
+func traceETypeInfo(pas types.PADataSequence) (string, bool) {
+	for _, pa := range pas {
+		switch pa.PADataType {
+		case patype.PA_ETYPE_INFO2:
+			if info, err := pa.GetETypeInfo2(); err == nil && len(info) > 0 {
+				return fmt.Sprintf("etype %s, salt \"%s\", params \"%x\"", traceEType(info[0].EType), info[0].Salt, info[0].S2KParams), true
+			}
+		case patype.PA_ETYPE_INFO:
+			if info, err := pa.GetETypeInfo(); err == nil && len(info) > 0 {
+				return fmt.Sprintf("etype %s, salt \"%s\"", traceEType(info[0].EType), info[0].Salt), true
+			}
+		}
+	}
+	return "", false
+}
+
+// traceKDCs reports the KDCs selected for the realm in the order they will be tried.
+func (cl *Client) traceKDCs(realm string, kdcs map[int]string) {
+	if !cl.tracing() {
+		return
+	}
+	s := make([]string, len(kdcs))
+	for i := range s {
+		s[i] = kdcs[i+1]
+	}
+	cl.trace("Selected KDCs for %s: %s", realm, strings.Join(s, ", "))
+}
@@ -0,0 +1,26 @@
+package client
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jcmturner/gokrb5/v8/iana/errorcode"
+	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
+	"github.com/jcmturner/gokrb5/v8/iana/patype"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceFormat(t *testing.T) {
+	t.Parallel()
+	var b bytes.Buffer
+	cl := &Client{settings: NewSettings(Trace(&b))}
+	assert.True(t, cl.settings.Trace(), "trace should be configured")
+	cl.trace("Sending request (%d bytes) to %s", 10, "EXAMPLE.COM")
+	assert.Regexp(t, `^\[\d+\] \d+\.\d{6}: Sending request \(10 bytes\) to EXAMPLE.COM\n$`, b.String(), "trace line not as expected")
+
+	assert.Equal(t, "-1765328359/Additional pre-authentication required", traceKRBError(messages.KRBError{ErrorCode: errorcode.KDC_ERR_PREAUTH_REQUIRED, EText: "Additional pre-authentication required"}), "KRBError not as expected")
+	assert.Equal(t, "aes256-cts-hmac-sha1-96, 99", traceETypes([]int32{etypeID.AES256_CTS_HMAC_SHA1_96, 99}), "etypes not as expected")
+	assert.Equal(t, "PA-ENC-TIMESTAMP (2)", tracePATypes(types.PADataSequence{{PADataType: patype.PA_ENC_TIMESTAMP}}), "PA types not as expected")
+}
@@ -0,0 +1,64 @@
+// Package clock provides an abstraction of the current time so that time dependent behaviour such as ticket validity,
+// authenticator timestamps and clock skew checks can be tested deterministically.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock provides the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// System is the Clock that reads the local system time.
+var System Clock = systemClock{}
+
+type systemClock struct{}
+
+// Now returns the local system time.
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Func is an adapter to allow the use of an ordinary function as a Clock.
+type Func func() time.Time
+
+// Now returns f().
+func (f Func) Now() time.Time {
+	return f()
+}
+
+// Mock is a Clock that returns a set time which only advances when instructed to.
+// It is safe for concurrent use.
+type Mock struct {
+	t   time.Time
+	mux sync.RWMutex
+}
+
+// NewMock returns a Mock clock set to the time provided.
+func NewMock(t time.Time) *Mock {
+	return &Mock{t: t}
+}
+
+// Now returns the mock's current time.
+func (m *Mock) Now() time.Time {
+	m.mux.RLock()
+	defer m.mux.RUnlock()
+	return m.t
+}
+
+// Set the mock's current time.
+func (m *Mock) Set(t time.Time) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.t = t
+}
+
+// Add advances the mock's current time by the duration provided.
+func (m *Mock) Add(d time.Duration) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.t = m.t.Add(d)
+}
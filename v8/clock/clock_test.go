@@ -0,0 +1,31 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSystem(t *testing.T) {
+	t.Parallel()
+	assert.WithinDuration(t, time.Now(), System.Now(), time.Second, "system clock not as expected")
+}
+
+func TestFunc(t *testing.T) {
+	t.Parallel()
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	var c Clock = Func(func() time.Time { return ts })
+	assert.Equal(t, ts, c.Now(), "func clock not as expected")
+}
+
+func TestMock(t *testing.T) {
+	t.Parallel()
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	m := NewMock(ts)
+	assert.Equal(t, ts, m.Now(), "mock clock not as expected")
+	m.Add(time.Hour)
+	assert.Equal(t, ts.Add(time.Hour), m.Now(), "mock clock not advanced")
+	m.Set(ts)
+	assert.Equal(t, ts, m.Now(), "mock clock not set")
+}
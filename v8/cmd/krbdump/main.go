@@ -0,0 +1,93 @@
+// Command krbdump prints a decoded description of a SPNEGO or Kerberos token, or of the entries of a credentials
+// cache, for diagnosing interoperability problems.
+//
+// The token is read base64 encoded from the argument, or from standard input if there is none, and may include the
+// "Negotiate " prefix of an HTTP authorization header. With -hex the token is hex encoded instead.
+//
+//	krbdump -keytab http.keytab 'Negotiate YIIC...'
+//	krbdump -ccache /tmp/krb5cc_1000
+//
+// If the service's keytab is provided the tickets are decrypted and their PAC described.
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/krbdump"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+)
+
+func main() {
+	ktPath := flag.String("keytab", "", "keytab with which to decrypt tickets")
+	ccPath := flag.String("ccache", "", "credentials cache to describe instead of a token")
+	isHex := flag.Bool("hex", false, "the token is hex rather than base64 encoded")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-keytab file] [-ccache file | [-hex] [token]]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var kt *keytab.Keytab
+	if *ktPath != "" {
+		var err error
+		kt, err = keytab.Load(*ktPath)
+		if err != nil {
+			fail("could not load keytab: %v", err)
+		}
+	}
+
+	if *ccPath != "" {
+		c, err := credentials.LoadCCache(*ccPath)
+		if err != nil {
+			fail("could not load credentials cache: %v", err)
+		}
+		fmt.Println(krbdump.CCache(c, kt))
+		return
+	}
+
+	var s string
+	if flag.NArg() > 0 {
+		s = strings.Join(flag.Args(), " ")
+	} else {
+		b, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			fail("could not read token: %v", err)
+		}
+		s = string(b)
+	}
+	b, err := decode(s, *isHex)
+	if err != nil {
+		fail("could not decode token: %v", err)
+	}
+	d, err := krbdump.Token(b, kt)
+	if err != nil {
+		fail("%v", err)
+	}
+	fmt.Println(d)
+}
+
+// decode returns the token encoded in the string, removing any Negotiate prefix.
+func decode(s string, isHex bool) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if p := spnego.HTTPHeaderAuthResponseValueKey + " "; strings.HasPrefix(strings.ToLower(s), strings.ToLower(p)) {
+		s = strings.TrimSpace(s[len(p):])
+	}
+	if isHex {
+		return hex.DecodeString(s)
+	}
+	return base64.StdEncoding.DecodeString(s)
+}
+
+// fail prints the error and exits.
+func fail(format string, v ...interface{}) {
+	fmt.Fprintf(os.Stderr, "krbdump: "+format+"\n", v...)
+	os.Exit(1)
+}
@@ -0,0 +1,76 @@
+// Package client provides the client API of jcmturner/gokrb5 v8 backed by the client package of this module.
+package client
+
+import (
+	"log"
+
+	"github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+)
+
+// Kpasswd server response codes.
+const (
+	KRB5_KPASSWD_SUCCESS             = client.KRB5_KPASSWD_SUCCESS
+	KRB5_KPASSWD_MALFORMED           = client.KRB5_KPASSWD_MALFORMED
+	KRB5_KPASSWD_HARDERROR           = client.KRB5_KPASSWD_HARDERROR
+	KRB5_KPASSWD_AUTHERROR           = client.KRB5_KPASSWD_AUTHERROR
+	KRB5_KPASSWD_SOFTERROR           = client.KRB5_KPASSWD_SOFTERROR
+	KRB5_KPASSWD_ACCESSDENIED        = client.KRB5_KPASSWD_ACCESSDENIED
+	KRB5_KPASSWD_BAD_VERSION         = client.KRB5_KPASSWD_BAD_VERSION
+	KRB5_KPASSWD_INITIAL_FLAG_NEEDED = client.KRB5_KPASSWD_INITIAL_FLAG_NEEDED
+)
+
+// Client side configuration and state.
+type Client = client.Client
+
+// Settings holds optional client settings.
+type Settings = client.Settings
+
+// Cache for service tickets held by the client.
+type Cache = client.Cache
+
+// CacheEntry holds details for a cache entry.
+type CacheEntry = client.CacheEntry
+
+// NewWithPassword creates a new client from a password credential.
+// Set the realm to empty string to use the default realm from config.
+func NewWithPassword(username, realm, password string, krb5conf *config.Config, settings ...func(*Settings)) *Client {
+	return client.NewWithPassword(username, realm, password, krb5conf, settings...)
+}
+
+// NewWithKeytab creates a new client from a keytab credential.
+func NewWithKeytab(username, realm string, kt *keytab.Keytab, krb5conf *config.Config, settings ...func(*Settings)) *Client {
+	return client.NewWithKeytab(username, realm, kt, krb5conf, settings...)
+}
+
+// NewFromCCache create a client from a populated client cache.
+func NewFromCCache(c *credentials.CCache, krb5conf *config.Config, settings ...func(*Settings)) (*Client, error) {
+	return client.NewFromCCache(c, krb5conf, settings...)
+}
+
+// NewCache creates a new client ticket cache instance.
+func NewCache() *Cache {
+	return client.NewCache()
+}
+
+// NewSettings creates a new client settings struct.
+func NewSettings(settings ...func(*Settings)) *Settings {
+	return client.NewSettings(settings...)
+}
+
+// DisablePAFXFAST used to configure the client to not use PA_FX_FAST.
+func DisablePAFXFAST(b bool) func(*Settings) {
+	return client.DisablePAFXFAST(b)
+}
+
+// AssumePreAuthentication used to configure the client to assume pre-authentication is required.
+func AssumePreAuthentication(b bool) func(*Settings) {
+	return client.AssumePreAuthentication(b)
+}
+
+// Logger used to configure client with a logger.
+func Logger(l *log.Logger) func(*Settings) {
+	return client.Logger(l)
+}
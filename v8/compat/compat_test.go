@@ -0,0 +1,154 @@
+package compat_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/jcmturner/goidentity/v6"
+
+	"github.com/jcmturner/gokrb5/v8/asn1"
+	"github.com/jcmturner/gokrb5/v8/compat/client"
+	"github.com/jcmturner/gokrb5/v8/compat/service"
+	"github.com/jcmturner/gokrb5/v8/compat/spnego"
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/crypto/etype"
+	"github.com/jcmturner/gokrb5/v8/gssapi"
+	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/krbtest"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The client, service and spnego APIs of jcmturner/gokrb5 v8 are pinned here so that applications written against
+// them build unchanged with their imports switched to the compat packages. A change breaking one of these signatures
+// fails to compile.
+var (
+	_ func(string, string, string, *config.Config, ...func(*client.Settings)) *client.Client                                                  = client.NewWithPassword
+	_ func(string, string, *keytab.Keytab, *config.Config, ...func(*client.Settings)) *client.Client                                          = client.NewWithKeytab
+	_ func(*credentials.CCache, *config.Config, ...func(*client.Settings)) (*client.Client, error)                                            = client.NewFromCCache
+	_ func(*client.Client, string, messages.ASReq, int) (messages.ASRep, error)                                                               = (*client.Client).ASExchange
+	_ func(*client.Client, types.PrincipalName, string, messages.Ticket, types.EncryptionKey, bool) (messages.TGSReq, messages.TGSRep, error) = (*client.Client).TGSREQGenerateAndExchange
+	_ func(*client.Client, messages.TGSReq, string, messages.Ticket, types.EncryptionKey, int) (messages.TGSReq, messages.TGSRep, error)      = (*client.Client).TGSExchange
+	_ func(*client.Client, string) (messages.Ticket, types.EncryptionKey, error)                                                              = (*client.Client).GetServiceTicket
+	_ func(*client.Client, string) (messages.Ticket, types.EncryptionKey, bool)                                                               = (*client.Client).GetCachedTicket
+	_ func(*client.Client, etype.EType, int, *messages.KRBError) (types.EncryptionKey, int, error)                                            = (*client.Client).Key
+	_ func(*client.Client) (bool, error)                                                                                                      = (*client.Client).IsConfigured
+	_ func(*client.Client) error                                                                                                              = (*client.Client).Login
+	_ func(*client.Client) error                                                                                                              = (*client.Client).AffirmLogin
+	_ func(*client.Client)                                                                                                                    = (*client.Client).Destroy
+	_ func(*client.Client, io.Writer) error                                                                                                   = (*client.Client).Diagnostics
+	_ func(*client.Client, io.Writer)                                                                                                         = (*client.Client).Print
+	_ func(*client.Client, string) (bool, error)                                                                                              = (*client.Client).ChangePasswd
+	_ func(*client.Client, string, ...interface{})                                                                                            = (*client.Client).Log
+	_ func() *client.Cache                                                                                                                    = client.NewCache
+	_ func(*client.Cache) (string, error)                                                                                                     = (*client.Cache).JSON
+	_ func(*client.Cache, string)                                                                                                             = (*client.Cache).RemoveEntry
+	_ func(...func(*client.Settings)) *client.Settings                                                                                        = client.NewSettings
+	_ func(bool) func(*client.Settings)                                                                                                       = client.DisablePAFXFAST
+	_ func(*client.Settings) bool                                                                                                             = (*client.Settings).DisablePAFXFAST
+	_ func(bool) func(*client.Settings)                                                                                                       = client.AssumePreAuthentication
+	_ func(*client.Settings) bool                                                                                                             = (*client.Settings).AssumePreAuthentication
+	_ func(*log.Logger) func(*client.Settings)                                                                                                = client.Logger
+	_ func(*client.Settings) *log.Logger                                                                                                      = (*client.Settings).Logger
+	_ func(*client.Settings) (string, error)                                                                                                  = (*client.Settings).JSON
+
+	_ func(*messages.APReq, *service.Settings) (bool, *credentials.Credentials, error)                   = service.VerifyAPREQ
+	_ func(string, *config.Config, *service.Settings, *client.Settings) service.KRB5BasicAuthenticator   = service.NewKRB5BasicAuthenticator
+	_ func(time.Duration) *service.Cache                                                                 = service.GetReplayCache
+	_ func(*service.Cache, types.PrincipalName, types.Authenticator)                                     = (*service.Cache).AddEntry
+	_ func(*service.Cache, time.Duration)                                                                = (*service.Cache).ClearOldEntries
+	_ func(*service.Cache, types.PrincipalName, types.Authenticator) bool                                = (*service.Cache).IsReplay
+	_ func(*keytab.Keytab, ...func(*service.Settings)) *service.Settings                                 = service.NewSettings
+	_ func(bool) func(*service.Settings)                                                                 = service.RequireHostAddr
+	_ func(*service.Settings) bool                                                                       = (*service.Settings).RequireHostAddr
+	_ func(bool) func(*service.Settings)                                                                 = service.DecodePAC
+	_ func(*service.Settings) bool                                                                       = (*service.Settings).DecodePAC
+	_ func(types.HostAddress) func(*service.Settings)                                                    = service.ClientAddress
+	_ func(*service.Settings) types.HostAddress                                                          = (*service.Settings).ClientAddress
+	_ func(*log.Logger) func(*service.Settings)                                                          = service.Logger
+	_ func(*service.Settings) *log.Logger                                                                = (*service.Settings).Logger
+	_ func(string) func(*service.Settings)                                                               = service.KeytabPrincipal
+	_ func(*service.Settings) *types.PrincipalName                                                       = (*service.Settings).KeytabPrincipal
+	_ func(time.Duration) func(*service.Settings)                                                        = service.MaxClockSkew
+	_ func(*service.Settings) time.Duration                                                              = (*service.Settings).MaxClockSkew
+	_ func(string) func(*service.Settings)                                                               = service.SName
+	_ func(*service.Settings) string                                                                     = (*service.Settings).SName
+	_ func(service.SessionMgr) func(*service.Settings)                                                   = service.SessionManager
+	_ func(*service.Settings) service.SessionMgr                                                         = (*service.Settings).SessionManager
+	_ func(*spnego.Client, *http.Request) (*http.Response, error)                                        = (*spnego.Client).Do
+	_ func(*spnego.Client, string) (*http.Response, error)                                               = (*spnego.Client).Get
+	_ func(*spnego.Client, string, string, io.Reader) (*http.Response, error)                            = (*spnego.Client).Post
+	_ func(*spnego.Client, string, url.Values) (*http.Response, error)                                   = (*spnego.Client).PostForm
+	_ func(*spnego.Client, string) (*http.Response, error)                                               = (*spnego.Client).Head
+	_ func(*client.Client, *http.Request, string) error                                                  = spnego.SetSPNEGOHeader
+	_ func(http.Handler, *keytab.Keytab, ...func(*service.Settings)) http.Handler                        = spnego.SPNEGOKRB5Authenticate
+	_ func(*client.Client, messages.Ticket, types.EncryptionKey, []int, []int) (spnego.KRB5Token, error) = spnego.NewKRB5TokenAPREQ
+	_ func([]byte) (bool, interface{}, error)                                                            = spnego.UnmarshalNegToken
+	_ func(*client.Client, messages.Ticket, types.EncryptionKey) (spnego.NegTokenInit, error)            = spnego.NewNegTokenInitKRB5
+	_ func(*spnego.NegTokenInit) ([]byte, error)                                                         = (*spnego.NegTokenInit).Marshal
+	_ func(*spnego.NegTokenInit, []byte) error                                                           = (*spnego.NegTokenInit).Unmarshal
+	_ func(*spnego.NegTokenInit) (bool, gssapi.Status)                                                   = (*spnego.NegTokenInit).Verify
+	_ func(*spnego.NegTokenInit) context.Context                                                         = (*spnego.NegTokenInit).Context
+	_ func(*spnego.NegTokenResp) ([]byte, error)                                                         = (*spnego.NegTokenResp).Marshal
+	_ func(*spnego.NegTokenResp, []byte) error                                                           = (*spnego.NegTokenResp).Unmarshal
+	_ func(*spnego.NegTokenResp) (bool, gssapi.Status)                                                   = (*spnego.NegTokenResp).Verify
+	_ func(*spnego.NegTokenResp) spnego.NegState                                                         = (*spnego.NegTokenResp).State
+	_ func(*spnego.NegTokenResp) context.Context                                                         = (*spnego.NegTokenResp).Context
+	_ func(*client.Client, string) *spnego.SPNEGO                                                        = spnego.SPNEGOClient
+	_ func(*keytab.Keytab, ...func(*service.Settings)) *spnego.SPNEGO                                    = spnego.SPNEGOService
+	_ func(*spnego.SPNEGO) asn1.ObjectIdentifier                                                         = (*spnego.SPNEGO).OID
+	_ func(*spnego.SPNEGO) error                                                                         = (*spnego.SPNEGO).AcquireCred
+	_ func(*spnego.SPNEGO) (gssapi.ContextToken, error)                                                  = (*spnego.SPNEGO).InitSecContext
+	_ func(*spnego.SPNEGO, gssapi.ContextToken) (bool, context.Context, gssapi.Status)                   = (*spnego.SPNEGO).AcceptSecContext
+	_ func(*spnego.SPNEGO, string, ...interface{})                                                       = (*spnego.SPNEGO).Log
+	_ func(*spnego.SPNEGOToken) ([]byte, error)                                                          = (*spnego.SPNEGOToken).Marshal
+	_ func(*spnego.SPNEGOToken, []byte) error                                                            = (*spnego.SPNEGOToken).Unmarshal
+	_ func(*spnego.SPNEGOToken) (bool, gssapi.Status)                                                    = (*spnego.SPNEGOToken).Verify
+	_ func(*spnego.SPNEGOToken) context.Context                                                          = (*spnego.SPNEGOToken).Context
+	_ gssapi.ContextToken                                                                                = &spnego.SPNEGOToken{}
+	_ func(*client.Client, *http.Client, string) *spnego.Client                                          = spnego.NewClient
+	_                                                                                                    = []string{spnego.HTTPHeaderAuthRequest, spnego.HTTPHeaderAuthResponse, spnego.HTTPHeaderAuthResponseValueKey, spnego.UnauthorizedMsg}
+	_                                                                                                    = []string{spnego.TOK_ID_KRB_AP_REQ, spnego.TOK_ID_KRB_AP_REP, spnego.TOK_ID_KRB_ERROR}
+	_                                                                                                    = []spnego.NegState{spnego.NegStateAcceptCompleted, spnego.NegStateAcceptIncomplete, spnego.NegStateReject, spnego.NegStateRequestMIC}
+	_                                                                                                    = []int{client.KRB5_KPASSWD_SUCCESS, client.KRB5_KPASSWD_MALFORMED, client.KRB5_KPASSWD_HARDERROR, client.KRB5_KPASSWD_AUTHERROR, client.KRB5_KPASSWD_SOFTERROR, client.KRB5_KPASSWD_ACCESSDENIED, client.KRB5_KPASSWD_BAD_VERSION, client.KRB5_KPASSWD_INITIAL_FLAG_NEEDED}
+)
+
+func TestSPNEGO(t *testing.T) {
+	t.Parallel()
+	kt := keytab.New()
+	err := kt.AddEntry("HTTP/127.0.0.1", krbtest.Realm, "servicepassword", time.Now(), 1, etypeID.AES256_CTS_HMAC_SHA1_96)
+	require.NoError(t, err, "error creating service keytab")
+	kdcKt := krbtest.KDCKeytab()
+	kdcKt.Entries = append(kdcKt.Entries, kt.Entries...)
+	kdc, err := krbtest.NewKDC(krbtest.Realm, kdcKt)
+	require.NoError(t, err, "error starting mock KDC")
+	defer kdc.Close()
+
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, goidentity.FromHTTPRequestContext(r).UserName())
+	})
+	s := httptest.NewServer(spnego.SPNEGOKRB5Authenticate(inner, kt, service.MaxClockSkew(time.Minute)))
+	defer s.Close()
+
+	cl := client.NewWithKeytab(krbtest.ClientPrincipal, krbtest.Realm, krbtest.ClientKeytab(), kdc.Config(), client.DisablePAFXFAST(true))
+	defer cl.Destroy()
+	resp, err := spnego.NewClient(cl, nil, "").Get(s.URL)
+	require.NoError(t, err, "error on GET")
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "status code not as expected")
+	b, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err, "error reading response body")
+	assert.Equal(t, krbtest.ClientPrincipal, string(b), "user name not as expected")
+}
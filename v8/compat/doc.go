@@ -0,0 +1,8 @@
+// Package compat holds packages with the client, service and spnego APIs of jcmturner/gokrb5 v8, backed by this
+// module's implementation. Applications written against those APIs switch by changing their imports of
+// github.com/jcmturner/gokrb5/v8/client, service and spnego to github.com/jcmturner/gokrb5/v8/compat/client, service
+// and spnego, without other changes to their code.
+//
+// The types are aliases of those of this module so values can be passed to its other packages, and the features of
+// this module are available through their methods and the options of the underlying packages.
+package compat
@@ -0,0 +1,91 @@
+// Package service provides the service API of jcmturner/gokrb5 v8 backed by the service package of this module.
+package service
+
+import (
+	"log"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/service"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// Settings defines service side configuration settings.
+type Settings = service.Settings
+
+// SessionMgr must provide the ability to get and set session values from a http request.
+type SessionMgr = service.SessionMgr
+
+// Cache for tickets received from clients keyed by fully qualified client name.
+type Cache = service.Cache
+
+// KRB5BasicAuthenticator implements gokrb5.com/jcmturner/goidentity.Authenticator interface.
+// It takes username and password so can be used for basic authentication.
+type KRB5BasicAuthenticator = service.KRB5BasicAuthenticator
+
+// NewSettings creates a new service Settings.
+func NewSettings(kt *keytab.Keytab, settings ...func(*Settings)) *Settings {
+	return service.NewSettings(kt, settings...)
+}
+
+// VerifyAPREQ verifies an AP_REQ sent to the service. Returns a boolean for if the AP_REQ is valid and the client's
+// principal name and realm.
+func VerifyAPREQ(APReq *messages.APReq, s *Settings) (bool, *credentials.Credentials, error) {
+	return service.VerifyAPREQ(APReq, s)
+}
+
+// NewKRB5BasicAuthenticator creates a new NewKRB5BasicAuthenticator.
+func NewKRB5BasicAuthenticator(headerVal string, krb5conf *config.Config, serviceSettings *Settings, clientSettings *client.Settings) KRB5BasicAuthenticator {
+	return service.NewKRB5BasicAuthenticator(headerVal, krb5conf, serviceSettings, clientSettings)
+}
+
+// GetReplayCache returns a pointer to the Cache singleton.
+func GetReplayCache(d time.Duration) *Cache {
+	return service.GetReplayCache(d)
+}
+
+// RequireHostAddr used to configure service side to required host addresses to be specified in Kerberos tickets.
+func RequireHostAddr(b bool) func(*Settings) {
+	return service.RequireHostAddr(b)
+}
+
+// DecodePAC used to configure service side to enable/disable PAC decoding if the PAC is present.
+func DecodePAC(b bool) func(*Settings) {
+	return service.DecodePAC(b)
+}
+
+// ClientAddress used to configure service side with the clients host address to be used during validation.
+func ClientAddress(h types.HostAddress) func(*Settings) {
+	return service.ClientAddress(h)
+}
+
+// Logger used to configure service side with a logger.
+func Logger(l *log.Logger) func(*Settings) {
+	return service.Logger(l)
+}
+
+// KeytabPrincipal used to override the principal name used to find the key in the keytab.
+func KeytabPrincipal(p string) func(*Settings) {
+	return service.KeytabPrincipal(p)
+}
+
+// MaxClockSkew used to configure service side with the maximum acceptable clock skew between the service and the
+// issue time of kerberos tickets.
+func MaxClockSkew(d time.Duration) func(*Settings) {
+	return service.MaxClockSkew(d)
+}
+
+// SName used provide a specific service name to the service settings.
+func SName(sname string) func(*Settings) {
+	return service.SName(sname)
+}
+
+// SessionManager configures a session manager to establish sessions with clients to avoid excessive authentication
+// challenges.
+func SessionManager(sm SessionMgr) func(*Settings) {
+	return service.SessionManager(sm)
+}
@@ -0,0 +1,111 @@
+// Package spnego provides the spnego API of jcmturner/gokrb5 v8 backed by the spnego package of this module.
+package spnego
+
+import (
+	"net/http"
+
+	"github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/service"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+const (
+	// HTTPHeaderAuthRequest is the header that will hold authn/z information.
+	HTTPHeaderAuthRequest = spnego.HTTPHeaderAuthRequest
+	// HTTPHeaderAuthResponse is the header that will hold SPNEGO data from the server.
+	HTTPHeaderAuthResponse = spnego.HTTPHeaderAuthResponse
+	// HTTPHeaderAuthResponseValueKey is the key in the auth header for SPNEGO.
+	HTTPHeaderAuthResponseValueKey = spnego.HTTPHeaderAuthResponseValueKey
+	// UnauthorizedMsg is the message returned in the body when authentication fails.
+	UnauthorizedMsg = spnego.UnauthorizedMsg
+)
+
+// GSSAPI KRB5 MechToken IDs.
+const (
+	TOK_ID_KRB_AP_REQ = spnego.TOK_ID_KRB_AP_REQ
+	TOK_ID_KRB_AP_REP = spnego.TOK_ID_KRB_AP_REP
+	TOK_ID_KRB_ERROR  = spnego.TOK_ID_KRB_ERROR
+)
+
+// Negotiation state values.
+const (
+	NegStateAcceptCompleted  = spnego.NegStateAcceptCompleted
+	NegStateAcceptIncomplete = spnego.NegStateAcceptIncomplete
+	NegStateReject           = spnego.NegStateReject
+	NegStateRequestMIC       = spnego.NegStateRequestMIC
+)
+
+// Client side encapsulation of a standard HTTP client with SPNEGO authentication.
+type Client = spnego.Client
+
+// SPNEGO implements the GSS-API mechanism for RFC 4178
+type SPNEGO = spnego.SPNEGO
+
+// SPNEGOToken is a GSS-API context token
+type SPNEGOToken = spnego.SPNEGOToken
+
+// KRB5Token context token implementation for GSSAPI.
+type KRB5Token = spnego.KRB5Token
+
+// NegState is a type to indicate the SPNEGO negotiation state.
+type NegState = spnego.NegState
+
+// NegTokenInit implements Negotiation Token of type Init.
+type NegTokenInit = spnego.NegTokenInit
+
+// NegTokenResp implements Negotiation Token of type Resp/Targ
+type NegTokenResp = spnego.NegTokenResp
+
+// NegTokenTarg implements Negotiation Token of type Resp/Targ
+type NegTokenTarg = spnego.NegTokenTarg
+
+// NewClient returns a SPNEGO enabled HTTP client.
+// Be careful when passing in the *http.Client if it is beginning reused in multiple calls to this function.
+// Ensure reuse of the provided *http.Client is for the same user as a session cookie may have been added to
+// http.Client's cookie jar.
+// Incorrect reuse of the provided *http.Client could lead to access to the wrong user's session.
+func NewClient(krb5Cl *client.Client, httpCl *http.Client, spn string) *Client {
+	return spnego.NewClient(krb5Cl, httpCl, spn)
+}
+
+// SetSPNEGOHeader gets the service ticket and sets it as the SPNEGO authorization header on HTTP request object.
+// To auto generate the SPN from the request object pass a null string "".
+func SetSPNEGOHeader(cl *client.Client, r *http.Request, spn string) error {
+	return spnego.SetSPNEGOHeader(cl, r, spn)
+}
+
+// SPNEGOKRB5Authenticate is a Kerberos SPNEGO authentication HTTP handler wrapper.
+func SPNEGOKRB5Authenticate(inner http.Handler, kt *keytab.Keytab, settings ...func(*service.Settings)) http.Handler {
+	return spnego.SPNEGOKRB5Authenticate(inner, kt, settings...)
+}
+
+// SPNEGOClient configures the SPNEGO mechanism suitable for client side use.
+func SPNEGOClient(cl *client.Client, spn string) *SPNEGO {
+	return spnego.SPNEGOClient(cl, spn)
+}
+
+// SPNEGOService configures the SPNEGO mechanism suitable for service side use.
+func SPNEGOService(kt *keytab.Keytab, options ...func(*service.Settings)) *SPNEGO {
+	return spnego.SPNEGOService(kt, options...)
+}
+
+// NewKRB5TokenAPREQ creates a new KRB5 token with AP_REQ
+func NewKRB5TokenAPREQ(cl *client.Client, tkt messages.Ticket, sessionKey types.EncryptionKey, GSSAPIFlags []int, APOptions []int) (KRB5Token, error) {
+	return spnego.NewKRB5TokenAPREQ(cl, tkt, sessionKey, GSSAPIFlags, APOptions)
+}
+
+// NewNegTokenInitKRB5 creates new Init negotiation token for Kerberos 5
+func NewNegTokenInitKRB5(cl *client.Client, tkt messages.Ticket, sessionKey types.EncryptionKey) (NegTokenInit, error) {
+	return spnego.NewNegTokenInitKRB5(cl, tkt, sessionKey)
+}
+
+// UnmarshalNegToken umarshals and returns either a NegTokenInit or a NegTokenResp.
+//
+// The boolean indicates if the response is a NegTokenInit.
+// If error is nil and the boolean is false the response is a NegTokenResp.
+func UnmarshalNegToken(b []byte) (bool, interface{}, error) {
+	return spnego.UnmarshalNegToken(b)
+}
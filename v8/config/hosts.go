@@ -6,14 +6,12 @@ import (
 	"net"
 	"strconv"
 	"strings"
-
-	"github.com/jcmturner/dnsutils/v2"
 )
 
 // GetKDCs returns the count of KDCs available and a map of KDC host names keyed on preference order.
 func (c *Config) GetKDCs(realm string, tcp bool) (int, map[int]string, error) {
 	if realm == "" {
-		realm = c.LibDefaults.DefaultRealm
+		realm = c.NormalizeRealm(c.LibDefaults.DefaultRealm)
 	}
 	kdcs := make(map[int]string)
 	var count int
@@ -21,7 +19,7 @@ func (c *Config) GetKDCs(realm string, tcp bool) (int, map[int]string, error) {
 	// Get the KDCs from the krb5.conf.
 	var ks []string
 	for _, r := range c.Realms {
-		if r.Realm != realm {
+		if !c.RealmEqual(r.Realm, realm) {
 			continue
 		}
 		ks = r.KDC
@@ -43,7 +41,7 @@ func (c *Config) GetKDCs(realm string, tcp bool) (int, map[int]string, error) {
 	if tcp {
 		proto = "tcp"
 	}
-	index, addrs, err := dnsutils.OrderedSRV("kerberos", proto, realm)
+	index, addrs, err := c.orderedSRV("kerberos", proto, realm)
 	if err != nil {
 		return count, kdcs, err
 	}
@@ -69,12 +67,10 @@ func (c *Config) GetKpasswdServers(realm string, tcp bool) (int, map[int]string,
 		if tcp {
 			proto = "tcp"
 		}
-		c, addrs, err := dnsutils.OrderedSRV("kpasswd", proto, realm)
-		if err != nil {
-			return count, kdcs, err
-		}
-		if c < 1 {
-			c, addrs, err = dnsutils.OrderedSRV("kerberos-adm", proto, realm)
+		n, addrs, err := c.orderedSRV("kpasswd", proto, realm)
+		if err != nil || n < 1 {
+			// Fall back to the kadmin records if there are no kpasswd records
+			n, addrs, err = c.orderedSRV("kerberos-adm", proto, realm)
 			if err != nil {
 				return count, kdcs, err
 			}
@@ -82,7 +78,7 @@ func (c *Config) GetKpasswdServers(realm string, tcp bool) (int, map[int]string,
 		if len(addrs) < 1 {
 			return count, kdcs, fmt.Errorf("no kpasswd or kadmin SRV records found for realm %s", realm)
 		}
-		count = c
+		count = n
 		for k, v := range addrs {
 			kdcs[k] = strings.TrimRight(v.Target, ".") + ":" + strconv.Itoa(int(v.Port))
 		}
@@ -91,7 +87,7 @@ func (c *Config) GetKpasswdServers(realm string, tcp bool) (int, map[int]string,
 		var ks []string
 		var ka []string
 		for _, r := range c.Realms {
-			if r.Realm == realm {
+			if c.RealmEqual(r.Realm, realm) {
 				ks = r.KPasswdServer
 				ka = r.AdminServer
 				break
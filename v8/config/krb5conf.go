@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net"
 	"os"
 	"os/user"
@@ -16,7 +17,7 @@ import (
 	"strings"
 	"time"
 
-	"github.com/jcmturner/gofork/encoding/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1"
 	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
 )
 
@@ -25,6 +26,7 @@ type Config struct {
 	LibDefaults LibDefaults
 	Realms      []Realm
 	DomainRealm DomainRealm
+	resolver    Resolver
 	//CaPaths
 	//AppDefaults
 	//Plugins
@@ -75,11 +77,13 @@ type LibDefaults struct {
 	PreferredPreauthTypes []int         //default “17, 16, 15, 14”, which forces libkrb5 to attempt to use PKINIT if it is supported
 	Proxiable             bool          //default false
 	RDNS                  bool          //default true
+	RealmCaseInsensitive  bool          //gokrb5 specific, default false. Compare realm names case insensitively
 	RealmTryDomains       int           //default -1
 	RenewLifetime         time.Duration //default 0
 	SafeChecksumType      int           //default 8
 	TicketLifetime        time.Duration //default 1 day
 	UDPPreferenceLimit    int           // 1 means to always use tcp. MIT krb5 has a default value of 1465, and it prevents user setting more than 32700.
+	UpperCaseRealms       bool          //gokrb5 specific, default false. Upper case realms derived from host names, configuration and user input
 	VerifyAPReqNofail     bool          //default false
 }
 
@@ -155,7 +159,7 @@ func (l *LibDefaults) parseLines(lines []string) error {
 			}
 			l.CCacheType = int(v)
 		case "clockskew":
-			d, err := parseDuration(p[1])
+			d, err := ParseDuration(p[1])
 			if err != nil {
 				return InvalidErrorf("libdefaults section line (%s): %v", line, err)
 			}
@@ -271,7 +275,7 @@ func (l *LibDefaults) parseLines(lines []string) error {
 			}
 			l.RealmTryDomains = int(v)
 		case "renew_lifetime":
-			d, err := parseDuration(p[1])
+			d, err := ParseDuration(p[1])
 			if err != nil {
 				return InvalidErrorf("libdefaults section line (%s): %v", line, err)
 			}
@@ -284,7 +288,7 @@ func (l *LibDefaults) parseLines(lines []string) error {
 			}
 			l.SafeChecksumType = int(v)
 		case "ticket_lifetime":
-			d, err := parseDuration(p[1])
+			d, err := ParseDuration(p[1])
 			if err != nil {
 				return InvalidErrorf("libdefaults section line (%s): %v", line, err)
 			}
@@ -496,7 +500,7 @@ func (c *Config) ResolveRealm(domainName string) string {
 
 	// Try to match the entire hostname first
 	if r, ok := c.DomainRealm[domainName]; ok {
-		return r
+		return c.NormalizeRealm(r)
 	}
 
 	// Try to match all DNS domain parts
@@ -504,10 +508,36 @@ func (c *Config) ResolveRealm(domainName string) string {
 	for i := 2; i <= periods; i++ {
 		z := strings.SplitN(domainName, ".", i)
 		if r, ok := c.DomainRealm["."+z[len(z)-1]]; ok {
-			return r
+			return c.NormalizeRealm(r)
 		}
 	}
-	return c.LibDefaults.DefaultRealm
+
+	// Look up the realm from the _kerberos TXT records of the domain if configured
+	if c.LibDefaults.DNSLookupRealm {
+		if r, ok := c.lookupRealm(domainName); ok {
+			return c.NormalizeRealm(r)
+		}
+	}
+	return c.NormalizeRealm(c.LibDefaults.DefaultRealm)
+}
+
+// NormalizeRealm returns the realm name in upper case if the UpperCaseRealms libdefault is set, otherwise the realm
+// name is returned unchanged.
+func (c *Config) NormalizeRealm(realm string) string {
+	if c != nil && c.LibDefaults.UpperCaseRealms {
+		return strings.ToUpper(realm)
+	}
+	return realm
+}
+
+// RealmEqual tests if the realm names are equal.
+// Realm names are case sensitive (RFC 4120 section 6.1) unless the RealmCaseInsensitive libdefault is set in which case
+// they are compared under Unicode case-folding.
+func (c *Config) RealmEqual(a, b string) bool {
+	if c != nil && c.LibDefaults.RealmCaseInsensitive {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
 }
 
 // Load the KRB5 configuration from the specified file path.
@@ -629,37 +659,26 @@ func parseETypes(s []string, w bool) []int32 {
 	return eti
 }
 
-// Parse a time duration string in the configuration to a golang time.Duration.
-func parseDuration(s string) (time.Duration, error) {
-	s = strings.Replace(strings.TrimSpace(s), " ", "", -1)
-
-	// handle Nd[NmNs]
-	if strings.Contains(s, "d") {
-		ds := strings.SplitN(s, "d", 2)
-		dn, err := strconv.ParseUint(ds[0], 10, 32)
-		if err != nil {
-			return time.Duration(0), errors.New("invalid time duration")
-		}
-		d := time.Duration(dn*24) * time.Hour
-		if ds[1] != "" {
-			dp, err := time.ParseDuration(ds[1])
-			if err != nil {
-				return time.Duration(0), errors.New("invalid time duration")
-			}
-			d = d + dp
-		}
-		return d, nil
-	}
-
-	// handle Nm[Ns]
-	d, err := time.ParseDuration(s)
-	if err == nil {
-		return d, nil
+// ParseDuration parses a duration in the syntax of the krb5.conf relations that hold them, such as ticket_lifetime,
+// renew_lifetime and clockskew, so that other relations, such as those of appdefaults, can be parsed the same way.
+// The forms accepted are:
+//
+// - N seconds, Eg. 300
+//
+// - h:m[:s] hours, minutes and optionally seconds, Eg. 10:30
+//
+// - NdNhNmNs days, hours, minutes and seconds, any of which may be omitted but which must be in this order and may be
+// separated by spaces, Eg. 10h or 7d 0h 0m 0s
+//
+// https://web.mit.edu/kerberos/krb5-latest/doc/basic/date_format.html#duration
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.Join(strings.Fields(s), "")
+	if s == "" {
+		return time.Duration(0), errors.New("invalid time duration value")
 	}
 
 	// handle N
-	v, err := strconv.ParseUint(s, 10, 32)
-	if err == nil && v > 0 {
+	if v, err := strconv.ParseUint(s, 10, 32); err == nil {
 		return time.Duration(v) * time.Second, nil
 	}
 
@@ -669,21 +688,40 @@ func parseDuration(s string) (time.Duration, error) {
 		if 2 > len(t) || len(t) > 3 {
 			return time.Duration(0), errors.New("invalid time duration value")
 		}
-		var i []int
-		for _, n := range t {
-			j, err := strconv.ParseInt(n, 10, 16)
+		var d time.Duration
+		for i, n := range t {
+			v, err := strconv.ParseUint(n, 10, 16)
 			if err != nil {
 				return time.Duration(0), errors.New("invalid time duration value")
 			}
-			i = append(i, int(j))
-		}
-		d := time.Duration(i[0])*time.Hour + time.Duration(i[1])*time.Minute
-		if len(i) == 3 {
-			d = d + time.Duration(i[2])*time.Second
+			d += time.Duration(v) * []time.Duration{time.Hour, time.Minute, time.Second}[i]
 		}
 		return d, nil
 	}
-	return time.Duration(0), errors.New("invalid time duration value")
+
+	// handle NdNhNmNs
+	const units = "dhms"
+	scale := []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	last := -1
+	for s != "" {
+		i := strings.IndexAny(s, units)
+		if i < 1 {
+			return time.Duration(0), errors.New("invalid time duration value")
+		}
+		u := strings.IndexByte(units, s[i])
+		if u <= last {
+			return time.Duration(0), errors.New("invalid time duration value: units out of order")
+		}
+		last = u
+		v, err := strconv.ParseUint(s[:i], 10, 32)
+		if err != nil || v > uint64((math.MaxInt64-d)/scale[u]) {
+			return time.Duration(0), errors.New("invalid time duration value")
+		}
+		d += time.Duration(v) * scale[u]
+		s = s[i+1:]
+	}
+	return d, nil
 }
 
 // Parse possible boolean values to golang bool.
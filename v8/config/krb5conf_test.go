@@ -150,11 +150,13 @@ const (
     ],
     "Proxiable": false,
     "RDNS": true,
+    "RealmCaseInsensitive": false,
     "RealmTryDomains": -1,
     "RenewLifetime": 0,
     "SafeChecksumType": 8,
     "TicketLifetime": 36000000000000,
     "UDPPreferenceLimit": 1465,
+    "UpperCaseRealms": false,
     "VerifyAPReqNofail": false
   },
   "Realms": [
@@ -625,9 +627,14 @@ func TestParseDuration(t *testing.T) {
 		{"1d12h30m", time.Duration(24)*time.Hour + hm},
 		{"1d12h", time.Duration(24)*time.Hour + h},
 		{"1d", time.Duration(24) * time.Hour},
+		{"0", 0},
+		{"10h", time.Duration(10) * time.Hour},
+		{"7d 0h 0m 0s", time.Duration(7*24) * time.Hour},
+		{" 1h 30m ", time.Duration(90) * time.Minute},
+		{"45s", time.Duration(45) * time.Second},
 	}
 	for _, test := range tests {
-		d, err := parseDuration(test.timeStr)
+		d, err := ParseDuration(test.timeStr)
 		if err != nil {
 			t.Errorf("error parsing %s: %v", test.timeStr, err)
 		}
@@ -635,6 +642,27 @@ func TestParseDuration(t *testing.T) {
 
 	}
 
+	for _, s := range []string{"", "-5", "1.5h", "10ms", "-5m", "1h1d", "1h1h", "d", "1x", "1:2:3:4", "1:-2", "99999999999d"} {
+		_, err := ParseDuration(s)
+		assert.Error(t, err, "%q should not be a valid duration", s)
+	}
+}
+
+func TestLibDefaults_Durations(t *testing.T) {
+	t.Parallel()
+	c, err := NewFromString(`[libdefaults]
+ ticket_lifetime = 7d 0h 0m 0s
+ renew_lifetime = 0
+ clockskew = 2m
+`)
+	if err != nil {
+		t.Fatalf("error parsing config: %v", err)
+	}
+	assert.Equal(t, time.Duration(7*24)*time.Hour, c.LibDefaults.TicketLifetime, "ticket_lifetime not as expected")
+	assert.Equal(t, time.Duration(0), c.LibDefaults.RenewLifetime, "renew_lifetime not as expected")
+	assert.Equal(t, time.Duration(2)*time.Minute, c.LibDefaults.Clockskew, "clockskew not as expected")
+	_, err = NewFromString("[libdefaults]\n ticket_lifetime = 1.5h\n")
+	assert.Error(t, err, "an invalid ticket_lifetime should be rejected")
 }
 
 func TestResolveRealm(t *testing.T) {
@@ -664,6 +692,40 @@ func TestResolveRealm(t *testing.T) {
 	}
 }
 
+func TestResolveRealm_UpperCaseRealms(t *testing.T) {
+	t.Parallel()
+	c, err := NewFromString(krb5Conf)
+	if err != nil {
+		t.Fatalf("Error loading config: %v", err)
+	}
+	c.LibDefaults.UpperCaseRealms = true
+	assert.Equal(t, "LOWERCASE.ORG", c.ResolveRealm("foo.testlowercase.org"), "realm not upper cased")
+	assert.Equal(t, "EXAMPLE.COM", c.NormalizeRealm("example.com"), "realm not upper cased")
+	c.LibDefaults.UpperCaseRealms = false
+	assert.Equal(t, "example.com", c.NormalizeRealm("example.com"), "realm should be unchanged")
+}
+
+func TestRealmEqual(t *testing.T) {
+	t.Parallel()
+	c := New()
+	assert.True(t, c.RealmEqual("TEST.GOKRB5", "TEST.GOKRB5"), "identical realms should be equal")
+	assert.False(t, c.RealmEqual("TEST.GOKRB5", "test.gokrb5"), "realms should be case sensitive by default")
+	c.LibDefaults.RealmCaseInsensitive = true
+	assert.True(t, c.RealmEqual("TEST.GOKRB5", "test.gokrb5"), "realms should be compared case insensitively")
+	assert.False(t, c.RealmEqual("TEST.GOKRB5", "OTHER.GOKRB5"), "different realms should not be equal")
+
+	c, err := NewFromString(krb5Conf)
+	if err != nil {
+		t.Fatalf("Error loading config: %v", err)
+	}
+	c.LibDefaults.RealmCaseInsensitive = true
+	n, _, err := c.GetKDCs("test.gokrb5", true)
+	if err != nil {
+		t.Fatalf("error getting KDCs: %v", err)
+	}
+	assert.True(t, n > 0, "KDCs should be found for a realm differing only in case")
+}
+
 func TestJSON(t *testing.T) {
 	t.Parallel()
 	c, err := NewFromString(krb5Conf)
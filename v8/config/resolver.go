@@ -0,0 +1,122 @@
+package config
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"sort"
+	"strings"
+)
+
+// Resolver performs the DNS lookups used to discover KDCs, kpasswd servers and realms and to canonicalize host
+// names. *net.Resolver implements Resolver.
+type Resolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupCNAME(ctx context.Context, host string) (string, error)
+}
+
+// WithResolver sets the Resolver used for the DNS lookups made with the configuration, for environments with
+// split-horizon DNS or their own service discovery and to stub lookups in tests. Lookups are made with
+// net.DefaultResolver if a Resolver is not set.
+func (c *Config) WithResolver(r Resolver) *Config {
+	c.resolver = r
+	return c
+}
+
+// Resolver returns the Resolver used for the DNS lookups made with the configuration.
+func (c *Config) Resolver() Resolver {
+	if c == nil || c.resolver == nil {
+		return net.DefaultResolver
+	}
+	return c.resolver
+}
+
+// ResolveHostPort resolves the host of a host:port address to an IP address with the configuration's Resolver.
+// Addresses whose host is already an IP address are returned unchanged.
+func (c *Config) ResolveHostPort(hostport string) (string, error) {
+	h, p, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return "", err
+	}
+	if net.ParseIP(h) != nil {
+		return hostport, nil
+	}
+	addrs, err := c.Resolver().LookupHost(context.Background(), h)
+	if err != nil {
+		return "", err
+	}
+	if len(addrs) < 1 {
+		return "", &net.DNSError{Err: "no addresses found", Name: h, IsNotFound: true}
+	}
+	return net.JoinHostPort(addrs[0], p), nil
+}
+
+// lookupRealm returns the realm of the domain from the _kerberos TXT records of the domain and its parent domains.
+func (c *Config) lookupRealm(domainName string) (string, bool) {
+	d := domainName
+	for d != "" {
+		txt, err := c.Resolver().LookupTXT(context.Background(), "_kerberos."+d)
+		if err == nil && len(txt) > 0 && txt[0] != "" {
+			return txt[0], true
+		}
+		i := strings.IndexByte(d, '.')
+		if i < 0 {
+			break
+		}
+		d = d[i+1:]
+	}
+	return "", false
+}
+
+// orderedSRV looks up the SRV records of the service with the configuration's Resolver and returns the count of the
+// records and a map of them keyed on the order they should be tried in, from 1. The records are ordered by priority
+// and randomly within each priority by their relative weights. A record with the target "." indicates the service is
+// not available and is not returned. RFC 2782
+func (c *Config) orderedSRV(service, proto, name string) (int, map[int]*net.SRV, error) {
+	o := make(map[int]*net.SRV)
+	_, addrs, err := c.Resolver().LookupSRV(context.Background(), service, proto, name)
+	if err != nil {
+		return 0, o, err
+	}
+	var srvs []*net.SRV
+	for _, a := range addrs {
+		if a.Target != "." && a.Target != "" {
+			srvs = append(srvs, a)
+		}
+	}
+	sort.SliceStable(srvs, func(i, j int) bool { return srvs[i].Priority < srvs[j].Priority })
+	i := 1
+	for len(srvs) > 0 {
+		// Select the records of the lowest remaining priority
+		n := 1
+		for n < len(srvs) && srvs[n].Priority == srvs[0].Priority {
+			n++
+		}
+		p := srvs[:n]
+		for len(p) > 0 {
+			var tw int
+			for _, s := range p {
+				tw += int(s.Weight)
+			}
+			j := 0
+			if tw > 0 {
+				r := rand.Intn(tw + 1)
+				for ; j < len(p)-1; j++ {
+					r -= int(p[j].Weight)
+					if r <= 0 {
+						break
+					}
+				}
+			} else {
+				j = rand.Intn(len(p))
+			}
+			o[i] = p[j]
+			i++
+			p = append(p[:j], p[j+1:]...)
+		}
+		srvs = srvs[n:]
+	}
+	return len(o), o, nil
+}
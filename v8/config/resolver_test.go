@@ -0,0 +1,142 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubResolver struct {
+	srv  map[string][]*net.SRV
+	txt  map[string][]string
+	host map[string][]string
+}
+
+func (r stubResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	n := "_" + service + "._" + proto + "." + name
+	if s, ok := r.srv[n]; ok {
+		return n, s, nil
+	}
+	return "", nil, &net.DNSError{Err: "no such host", Name: n, IsNotFound: true}
+}
+
+func (r stubResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if s, ok := r.txt[name]; ok {
+		return s, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (r stubResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if s, ok := r.host[host]; ok {
+		return s, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func (r stubResolver) LookupCNAME(ctx context.Context, host string) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+func TestConfig_Resolver(t *testing.T) {
+	t.Parallel()
+	c := New()
+	assert.Equal(t, net.DefaultResolver, c.Resolver(), "default resolver not used")
+	var n *Config
+	assert.Equal(t, net.DefaultResolver, n.Resolver(), "default resolver not used for nil config")
+	r := stubResolver{}
+	assert.Equal(t, r, c.WithResolver(r).Resolver(), "resolver not set")
+}
+
+func TestConfig_GetKDCsWithResolver(t *testing.T) {
+	t.Parallel()
+	c := New()
+	c.LibDefaults.DNSLookupKDC = true
+	c.WithResolver(stubResolver{srv: map[string][]*net.SRV{
+		"_kerberos._tcp.TEST.GOKRB5": {
+			{Target: "kdc2.test.gokrb5.", Port: 88, Priority: 2, Weight: 10},
+			{Target: "kdc1a.test.gokrb5.", Port: 88, Priority: 1, Weight: 0},
+			{Target: "kdc3.test.gokrb5.", Port: 8888, Priority: 3, Weight: 0},
+			{Target: "kdc1b.test.gokrb5.", Port: 88, Priority: 1, Weight: 0},
+		},
+	}})
+	count, kdcs, err := c.GetKDCs("TEST.GOKRB5", true)
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+	assert.ElementsMatch(t, []string{"kdc1a.test.gokrb5:88", "kdc1b.test.gokrb5:88"}, []string{kdcs[1], kdcs[2]}, "lowest priority records not first")
+	assert.Equal(t, "kdc2.test.gokrb5:88", kdcs[3])
+	assert.Equal(t, "kdc3.test.gokrb5:8888", kdcs[4])
+
+	_, _, err = c.GetKDCs("TEST.GOKRB5", false)
+	assert.Error(t, err, "expected error when there are no UDP records")
+}
+
+func TestConfig_GetKDCsWithResolver_NotAvailable(t *testing.T) {
+	t.Parallel()
+	c := New()
+	c.LibDefaults.DNSLookupKDC = true
+	c.WithResolver(stubResolver{srv: map[string][]*net.SRV{
+		"_kerberos._udp.TEST.GOKRB5": {
+			{Target: ".", Port: 0, Priority: 0, Weight: 0},
+		},
+		"_kerberos._tcp.TEST.GOKRB5": {
+			{Target: ".", Port: 0, Priority: 0, Weight: 0},
+			{Target: "kdc1.test.gokrb5.", Port: 88, Priority: 1, Weight: 0},
+		},
+	}})
+	_, _, err := c.GetKDCs("TEST.GOKRB5", false)
+	assert.Error(t, err, "expected error when the service is not available over UDP")
+	count, kdcs, err := c.GetKDCs("TEST.GOKRB5", true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, "kdc1.test.gokrb5:88", kdcs[1])
+}
+
+func TestConfig_GetKpasswdServersWithResolver(t *testing.T) {
+	t.Parallel()
+	c := New()
+	c.LibDefaults.DNSLookupKDC = true
+	c.WithResolver(stubResolver{srv: map[string][]*net.SRV{
+		"_kerberos-adm._tcp.TEST.GOKRB5": {
+			{Target: "kadmin.test.gokrb5.", Port: 749},
+		},
+	}})
+	count, kdcs, err := c.GetKpasswdServers("TEST.GOKRB5", true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, "kadmin.test.gokrb5:749", kdcs[1])
+}
+
+func TestConfig_ResolveRealmWithResolver(t *testing.T) {
+	t.Parallel()
+	c := New()
+	c.LibDefaults.DefaultRealm = "DEFAULT.GOKRB5"
+	c.WithResolver(stubResolver{txt: map[string][]string{
+		"_kerberos.test.gokrb5": {"TEST.GOKRB5"},
+	}})
+	assert.Equal(t, "DEFAULT.GOKRB5", c.ResolveRealm("host.test.gokrb5"), "realm looked up when dns_lookup_realm is not set")
+	c.LibDefaults.DNSLookupRealm = true
+	assert.Equal(t, "TEST.GOKRB5", c.ResolveRealm("host.sub.test.gokrb5"), "realm not looked up from parent domain")
+	assert.Equal(t, "DEFAULT.GOKRB5", c.ResolveRealm("host.other.gokrb5"), "default realm not used")
+}
+
+func TestConfig_ResolveHostPort(t *testing.T) {
+	t.Parallel()
+	c := New().WithResolver(stubResolver{host: map[string][]string{
+		"kdc.test.gokrb5": {"10.80.88.88", "10.80.88.89"},
+	}})
+	addr, err := c.ResolveHostPort("kdc.test.gokrb5:88")
+	require.NoError(t, err)
+	assert.Equal(t, "10.80.88.88:88", addr)
+	addr, err = c.ResolveHostPort("[::1]:88")
+	require.NoError(t, err)
+	assert.Equal(t, "[::1]:88", addr)
+	_, err = c.ResolveHostPort("unknown.test.gokrb5:88")
+	assert.Error(t, err)
+	_, err = c.ResolveHostPort("kdc.test.gokrb5")
+	assert.Error(t, err, "expected error for address without port")
+}
@@ -4,12 +4,16 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 	"unsafe"
 
-	"github.com/jcmturner/gofork/encoding/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1"
+	"github.com/jcmturner/gokrb5/v8/iana/flags"
 	"github.com/jcmturner/gokrb5/v8/types"
 )
 
@@ -71,9 +75,151 @@ func LoadCCache(cpath string) (*CCache, error) {
 	return c, err
 }
 
+// NewCCache returns an empty version 4 credential cache for the client principal provided.
+func NewCCache(cname types.PrincipalName, realm string) *CCache {
+	return &CCache{
+		Version: 4,
+		DefaultPrincipal: principal{
+			Realm:         realm,
+			PrincipalName: cname,
+		},
+	}
+}
+
+// AddCredential adds a credential for the client principal of the cache to the server principal provided.
+// The credential's client and server principals are set by this method.
+func (c *CCache) AddCredential(sname types.PrincipalName, srealm string, cred *Credential) {
+	cred.Client = c.DefaultPrincipal
+	cred.Server = principal{
+		Realm:         srealm,
+		PrincipalName: sname,
+	}
+	c.Credentials = append(c.Credentials, cred)
+}
+
+// SetKDCOffset sets the offset of the KDC's clock from the local clock in the cache's header, so that the MIT
+// libraries correct for it when using the credentials.
+func (c *CCache) SetKDCOffset(d time.Duration) {
+	v := make([]byte, 8)
+	binary.BigEndian.PutUint32(v[:4], uint32(int32(d/time.Second)))
+	binary.BigEndian.PutUint32(v[4:], uint32(int32((d%time.Second)/time.Microsecond)))
+	f := headerField{
+		tag:    headerFieldTagKDCOffset,
+		length: 8,
+		value:  v,
+	}
+	for i := range c.Header.fields {
+		if c.Header.fields[i].tag == headerFieldTagKDCOffset {
+			c.Header.fields[i] = f
+			return
+		}
+	}
+	c.Header.fields = append(c.Header.fields, f)
+}
+
+// Export writes the credential cache to a file at the path provided, readable only by its owner, in version 4 of
+// the file format so that it can be used by the MIT tools and libraries.
+// The file is written to a temporary file first and renamed so that a partially written cache is never read.
+func (c *CCache) Export(cpath string) error {
+	b, err := c.Marshal()
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(cpath), filepath.Base(cpath)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), cpath); err != nil {
+		return err
+	}
+	c.Path = cpath
+	return nil
+}
+
+// Marshal the CCache into bytes of version 4 of the credential cache file format.
+func (c *CCache) Marshal() ([]byte, error) {
+	var b bytes.Buffer
+	e := binary.BigEndian
+	b.Write([]byte{5, 4})
+	var hb bytes.Buffer
+	for _, f := range c.Header.fields {
+		if !f.valid() {
+			return nil, errors.New("invalid credential cache header field")
+		}
+		binary.Write(&hb, e, f.tag)
+		binary.Write(&hb, e, uint16(len(f.value)))
+		hb.Write(f.value)
+	}
+	binary.Write(&b, e, uint16(hb.Len()))
+	b.Write(hb.Bytes())
+	writePrincipal(&b, c.DefaultPrincipal)
+	for _, cred := range c.Credentials {
+		if err := writeCredential(&b, cred); err != nil {
+			return nil, err
+		}
+	}
+	return b.Bytes(), nil
+}
+
+func writePrincipal(b *bytes.Buffer, princ principal) {
+	e := binary.BigEndian
+	binary.Write(b, e, princ.PrincipalName.NameType)
+	binary.Write(b, e, int32(len(princ.PrincipalName.NameString)))
+	writeData(b, []byte(princ.Realm))
+	for _, n := range princ.PrincipalName.NameString {
+		writeData(b, []byte(n))
+	}
+}
+
+func writeCredential(b *bytes.Buffer, cred *Credential) error {
+	e := binary.BigEndian
+	writePrincipal(b, cred.Client)
+	writePrincipal(b, cred.Server)
+	binary.Write(b, e, uint16(cred.Key.KeyType))
+	writeData(b, cred.Key.KeyValue)
+	for _, t := range []time.Time{cred.AuthTime, cred.StartTime, cred.EndTime, cred.RenewTill} {
+		writeTimestamp(b, t)
+	}
+	if cred.IsSKey {
+		b.WriteByte(1)
+	} else {
+		b.WriteByte(0)
+	}
+	if len(cred.TicketFlags.Bytes) > 4 {
+		return errors.New("ticket flags of credential are longer than 32 bits")
+	}
+	f := make([]byte, 4)
+	copy(f, cred.TicketFlags.Bytes)
+	b.Write(f)
+	binary.Write(b, e, int32(len(cred.Addresses)))
+	for _, a := range cred.Addresses {
+		binary.Write(b, e, uint16(a.AddrType))
+		writeData(b, a.Address)
+	}
+	binary.Write(b, e, int32(len(cred.AuthData)))
+	for _, a := range cred.AuthData {
+		binary.Write(b, e, uint16(a.ADType))
+		writeData(b, a.ADData)
+	}
+	writeData(b, cred.Ticket)
+	writeData(b, cred.SecondTicket)
+	return nil
+}
+
 // Unmarshal a byte slice of credential cache data into CCache type.
 func (c *CCache) Unmarshal(b []byte) error {
 	p := 0
+	if len(b) < 2 {
+		return errors.New("Invalid credential cache data. Too short to contain the format version")
+	}
 	//The first byte of the file always has the value 5
 	if int8(b[p]) != 5 {
 		return errors.New("Invalid credential cache data. First byte does not equal 5")
@@ -247,6 +393,40 @@ func (c *CCache) GetEntries() []*Credential {
 	return creds
 }
 
+// String returns a description of the credential for diagnostics. The session key is redacted and the tickets are
+// described only by their length.
+func (cred *Credential) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Credential\n  Client: %s@%s", cred.Client.PrincipalName.PrincipalNameString(), cred.Client.Realm)
+	fmt.Fprintf(&b, "\n  Server: %s@%s", cred.Server.PrincipalName.PrincipalNameString(), cred.Server.Realm)
+	fmt.Fprintf(&b, "\n  Key: %v", cred.Key)
+	for _, t := range []struct {
+		name string
+		t    time.Time
+	}{
+		{"AuthTime", cred.AuthTime},
+		{"StartTime", cred.StartTime},
+		{"EndTime", cred.EndTime},
+		{"RenewTill", cred.RenewTill},
+	} {
+		if !t.t.IsZero() {
+			fmt.Fprintf(&b, "\n  %s: %s", t.name, t.t.UTC().Format(time.RFC3339))
+		}
+	}
+	fmt.Fprintf(&b, "\n  TicketFlags: %s", types.FlagNames(cred.TicketFlags, flags.TicketFlagNames))
+	if len(cred.Addresses) > 0 {
+		fmt.Fprintf(&b, "\n  Addresses: %s", types.HostAddresses(cred.Addresses))
+	}
+	for _, ad := range cred.AuthData {
+		fmt.Fprintf(&b, "\n  AuthData: type %d, %d bytes", ad.ADType, len(ad.ADData))
+	}
+	fmt.Fprintf(&b, "\n  Ticket: %d bytes", len(cred.Ticket))
+	if len(cred.SecondTicket) > 0 {
+		fmt.Fprintf(&b, "\n  SecondTicket: %d bytes", len(cred.SecondTicket))
+	}
+	return b.String()
+}
+
 func (h *headerField) valid() bool {
 	// See https://web.mit.edu/kerberos/krb5-latest/doc/formats/ccache_file_format.html - Header format
 	switch h.tag {
@@ -259,6 +439,20 @@ func (h *headerField) valid() bool {
 	return false
 }
 
+func writeData(b *bytes.Buffer, d []byte) {
+	binary.Write(b, binary.BigEndian, int32(len(d)))
+	b.Write(d)
+}
+
+// Write the bytes representing a timestamp. The zero time is written as zero.
+func writeTimestamp(b *bytes.Buffer, t time.Time) {
+	var ts uint32
+	if !t.IsZero() {
+		ts = uint32(t.Unix())
+	}
+	binary.Write(b, binary.BigEndian, ts)
+}
+
 func readData(b []byte, p *int, e *binary.ByteOrder) []byte {
 	l := readInt32(b, p, e)
 	return readBytes(b, p, int(l), e)
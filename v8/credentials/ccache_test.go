@@ -2,8 +2,13 @@ package credentials
 
 import (
 	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/jcmturner/gokrb5/v8/iana/flags"
 	"github.com/jcmturner/gokrb5/v8/iana/nametype"
 	"github.com/jcmturner/gokrb5/v8/test/testdata"
 	"github.com/jcmturner/gokrb5/v8/types"
@@ -129,3 +134,110 @@ func TestCCache_GetEntries(t *testing.T) {
 	creds := c.GetEntries()
 	assert.Equal(t, 2, len(creds), "Number of credentials entries not as expected")
 }
+
+func TestCCache_Marshal(t *testing.T) {
+	t.Parallel()
+	b, err := hex.DecodeString(testdata.CCACHE_TEST)
+	if err != nil {
+		t.Fatal("Error decoding test data")
+	}
+	c := new(CCache)
+	err = c.Unmarshal(b)
+	if err != nil {
+		t.Fatalf("Error parsing cache: %v", err)
+	}
+	mb, err := c.Marshal()
+	if err != nil {
+		t.Fatalf("Error marshaling cache: %v", err)
+	}
+	assert.Equal(t, b, mb, "Marshaled cache not as the original")
+}
+
+func TestCCache_Export(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "ccache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cpath := filepath.Join(dir, "krb5cc")
+
+	cname := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "testuser1")
+	c := NewCCache(cname, "TEST.GOKRB5")
+	sname := types.NewPrincipalName(nametype.KRB_NT_SRV_INST, "krbtgt/TEST.GOKRB5")
+	st := time.Unix(1600000000, 0)
+	f := types.NewKrbFlags()
+	types.SetFlag(&f, flags.Forwardable)
+	c.AddCredential(sname, "TEST.GOKRB5", &Credential{
+		Key:         types.EncryptionKey{KeyType: 18, KeyValue: []byte("0123456789abcdef0123456789abcdef")},
+		AuthTime:    st,
+		StartTime:   st,
+		EndTime:     st.Add(time.Hour),
+		TicketFlags: f,
+		Ticket:      []byte("ticket"),
+	})
+	if err := c.Export(cpath); err != nil {
+		t.Fatalf("Error exporting cache: %v", err)
+	}
+	fi, err := os.Stat(cpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm(), "cache should only be readable by its owner")
+
+	l, err := LoadCCache(cpath)
+	if err != nil {
+		t.Fatalf("Error loading exported cache: %v", err)
+	}
+	assert.Equal(t, uint8(4), l.Version, "Version not as expected")
+	assert.Equal(t, "testuser1", l.GetClientPrincipalName().PrincipalNameString(), "client principal not as expected")
+	assert.Equal(t, "TEST.GOKRB5", l.GetClientRealm(), "client realm not as expected")
+	cred, ok := l.GetEntry(sname)
+	if !ok {
+		t.Fatal("TGT credential not found in exported cache")
+	}
+	assert.Equal(t, "testuser1", cred.Client.PrincipalName.PrincipalNameString(), "credential client not as expected")
+	assert.Equal(t, c.Credentials[0].Key, cred.Key, "key not as expected")
+	assert.True(t, cred.EndTime.Equal(st.Add(time.Hour)), "end time not as expected")
+	assert.Equal(t, int64(0), cred.RenewTill.Unix(), "zero renew till should be written as zero")
+	assert.True(t, types.IsFlagSet(&cred.TicketFlags, flags.Forwardable), "ticket flags not as expected")
+	assert.Equal(t, []byte("ticket"), cred.Ticket, "ticket not as expected")
+}
+
+func TestCredential_String(t *testing.T) {
+	t.Parallel()
+	b, err := hex.DecodeString(testdata.CCACHE_TEST)
+	if err != nil {
+		t.Fatal("Error decoding test data")
+	}
+	c := new(CCache)
+	err = c.Unmarshal(b)
+	if err != nil {
+		t.Fatalf("Error parsing cache: %v", err)
+	}
+	cred, ok := c.GetEntry(types.PrincipalName{
+		NameType:   nametype.KRB_NT_PRINCIPAL,
+		NameString: []string{"HTTP", "host.test.gokrb5"},
+	})
+	if !ok {
+		t.Fatal("Could not get entry from CCache as not found")
+	}
+	str := cred.String()
+	for _, want := range []string{
+		"Client: testuser1@TEST.GOKRB5",
+		"Server: HTTP/host.test.gokrb5@TEST.GOKRB5",
+		"Key: aes256-cts-hmac-sha1-96(18) <redacted>",
+		"TicketFlags: forwardable",
+	} {
+		assert.Contains(t, str, want, "description of credential not as expected")
+	}
+	assert.NotContains(t, str, hex.EncodeToString(cred.Key.KeyValue), "session key should not be included")
+}
+
+func TestCCache_Unmarshal_Short(t *testing.T) {
+	t.Parallel()
+	for _, b := range [][]byte{nil, {5}} {
+		c := new(CCache)
+		assert.Error(t, c.Unmarshal(b), "data too short to be a cache should be rejected")
+	}
+}
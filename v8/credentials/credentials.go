@@ -5,9 +5,12 @@ import (
 	"bytes"
 	"encoding/gob"
 	"encoding/json"
+	"sort"
 	"time"
 
 	"github.com/hashicorp/go-uuid"
+	"github.com/jcmturner/gokrb5/v8/asn1"
+	"github.com/jcmturner/gokrb5/v8/iana/flags"
 	"github.com/jcmturner/gokrb5/v8/iana/nametype"
 	"github.com/jcmturner/gokrb5/v8/keytab"
 	"github.com/jcmturner/gokrb5/v8/types"
@@ -16,6 +19,10 @@ import (
 const (
 	// AttributeKeyADCredentials assigned number for AD credentials.
 	AttributeKeyADCredentials = "gokrb5AttributeKeyADCredentials"
+	// AttributeKeyPACUnavailable assigned number for the reason a PAC could not be processed.
+	AttributeKeyPACUnavailable = "gokrb5AttributeKeyPACUnavailable"
+	// AttributeKeyTicketInfo assigned number for the times and flags of the ticket authenticated with.
+	AttributeKeyTicketInfo = "gokrb5AttributeKeyTicketInfo"
 )
 
 // Credentials struct for a user.
@@ -28,6 +35,7 @@ type Credentials struct {
 	cname           types.PrincipalName
 	keytab          *keytab.Keytab
 	password        string
+	salt            string
 	attributes      map[string]interface{}
 	validUntil      time.Time
 	authenticated   bool
@@ -68,6 +76,31 @@ type ADCredentials struct {
 	LogonDomainName     string
 	LogonDomainID       string
 	LogonServer         string
+	UserPrincipalName   string
+	DNSDomainName       string
+}
+
+// TicketInfo contains the times and flags of the ticket a service was presented with, so that applications can align
+// the lifetime of their sessions with the ticket's.
+type TicketInfo struct {
+	// AuthTime is the time of the initial authentication of the client to the KDC.
+	AuthTime time.Time
+	// StartTime is the time from which the ticket is valid.
+	StartTime time.Time
+	// EndTime is the time the ticket expires.
+	EndTime time.Time
+	// RenewTill is the time until which the ticket may be renewed. It is zero if the ticket is not renewable.
+	RenewTill time.Time
+	// Flags are the ticket flags.
+	Flags asn1.BitString
+	// AuthIndicators are the authentication indicators of the ticket, recording how the client authenticated to the
+	// KDC. RFC 8129
+	AuthIndicators []string
+}
+
+// IsFlagSet reports if the ticket flag provided is set. Flag values are defined in the iana/flags package.
+func (t TicketInfo) IsFlagSet(i int) bool {
+	return types.IsFlagSet(&t.Flags, i)
 }
 
 // New creates a new Credentials instance.
@@ -128,6 +161,21 @@ func (c *Credentials) Password() string {
 	return c.password
 }
 
+// WithSalt sets the salt used to derive keys from the password if the KDC does not provide one.
+// It is only needed for principals whose salt is not derived from the principal name, such as Active Directory
+// computer accounts.
+func (c *Credentials) WithSalt(salt string) *Credentials {
+	c.salt = salt
+	return c
+}
+
+// Salt returns the salt used to derive keys from the password if the KDC does not provide one.
+// An empty string is returned if the salt has not been set and the default derived from the principal name is to be
+// used.
+func (c *Credentials) Salt() string {
+	return c.salt
+}
+
 // HasPassword queries if the Credentials has a password defined.
 func (c *Credentials) HasPassword() bool {
 	if c.password != "" {
@@ -163,6 +211,33 @@ func (c *Credentials) GetADCredentials() ADCredentials {
 	return ADCredentials{}
 }
 
+// SetTicketInfo adds the details of the ticket authenticated with to the credentials.
+func (c *Credentials) SetTicketInfo(t TicketInfo) {
+	c.SetAttribute(AttributeKeyTicketInfo, t)
+}
+
+// GetTicketInfo returns the details of the ticket authenticated with stored in the credential.
+// The boolean returned is false if the credential was not authenticated by a service.
+func (c *Credentials) GetTicketInfo() (TicketInfo, bool) {
+	t, ok := c.attributes[AttributeKeyTicketInfo].(TicketInfo)
+	return t, ok
+}
+
+// SetPACUnavailable marks the credentials as authenticated without the authorization data from the PAC
+// recording the error that prevented the PAC from being processed.
+func (c *Credentials) SetPACUnavailable(err error) {
+	c.SetAttribute(AttributeKeyPACUnavailable, err.Error())
+}
+
+// PACUnavailable indicates if the credentials were authenticated without the authorization data from a PAC
+// that could not be processed. The reason the PAC could not be processed is also returned.
+func (c *Credentials) PACUnavailable() (bool, string) {
+	if r, ok := c.attributes[AttributeKeyPACUnavailable].(string); ok {
+		return true, r
+	}
+	return false, ""
+}
+
 // Methods to implement goidentity.Identity interface
 
 // UserName returns the credential's username.
@@ -333,6 +408,7 @@ func (c *Credentials) RemoveAttribute(k string) {
 func (c *Credentials) Marshal() ([]byte, error) {
 	gob.Register(map[string]interface{}{})
 	gob.Register(ADCredentials{})
+	gob.Register(TicketInfo{})
 	buf := new(bytes.Buffer)
 	enc := gob.NewEncoder(buf)
 	mc := marshalCredentials{
@@ -361,6 +437,7 @@ func (c *Credentials) Marshal() ([]byte, error) {
 func (c *Credentials) Unmarshal(b []byte) error {
 	gob.Register(map[string]interface{}{})
 	gob.Register(ADCredentials{})
+	gob.Register(TicketInfo{})
 	mc := new(marshalCredentials)
 	buf := bytes.NewBuffer(b)
 	dec := gob.NewDecoder(buf)
@@ -382,6 +459,115 @@ func (c *Credentials) Unmarshal(b []byte) error {
 	return nil
 }
 
+// jsonCredentials is used when marshaling the Credentials details to JSON format.
+// Secrets are not included, only whether a keytab or password is held.
+type jsonCredentials struct {
+	Username        string         `json:"username"`
+	DisplayName     string         `json:"displayName,omitempty"`
+	Realm           string         `json:"realm"`
+	CName           string         `json:"cname,omitempty"`
+	Keytab          bool           `json:"keytab"`
+	Password        bool           `json:"password"`
+	ValidUntil      string         `json:"validUntil,omitempty"`
+	Authenticated   bool           `json:"authenticated"`
+	Human           bool           `json:"human"`
+	AuthTime        string         `json:"authTime,omitempty"`
+	GroupMembership []string       `json:"groupMembership,omitempty"`
+	SessionID       string         `json:"sessionID"`
+	ADCredentials   *ADCredentials `json:"adCredentials,omitempty"`
+	PACUnavailable  string         `json:"pacUnavailable,omitempty"`
+	Ticket          *TicketInfo    `json:"ticket,omitempty"`
+}
+
+// jsonTicketInfo is used when marshaling the TicketInfo details to JSON format.
+type jsonTicketInfo struct {
+	AuthTime       string   `json:"authTime,omitempty"`
+	StartTime      string   `json:"startTime,omitempty"`
+	EndTime        string   `json:"endTime,omitempty"`
+	RenewTill      string   `json:"renewTill,omitempty"`
+	Flags          string   `json:"flags,omitempty"`
+	AuthIndicators []string `json:"authIndicators,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface. Times are rendered in RFC3339 format and the flags by name.
+func (t TicketInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonTicketInfo{
+		AuthTime:       jsonTime(t.AuthTime),
+		StartTime:      jsonTime(t.StartTime),
+		EndTime:        jsonTime(t.EndTime),
+		RenewTill:      jsonTime(t.RenewTill),
+		Flags:          types.FlagNames(t.Flags, flags.TicketFlagNames),
+		AuthIndicators: t.AuthIndicators,
+	})
+}
+
+// jsonADCredentials is used when marshaling the ADCredentials details to JSON format.
+type jsonADCredentials struct {
+	EffectiveName       string   `json:"effectiveName"`
+	FullName            string   `json:"fullName,omitempty"`
+	UserID              int      `json:"userID"`
+	PrimaryGroupID      int      `json:"primaryGroupID"`
+	LogOnTime           string   `json:"logOnTime,omitempty"`
+	LogOffTime          string   `json:"logOffTime,omitempty"`
+	PasswordLastSet     string   `json:"passwordLastSet,omitempty"`
+	GroupMembershipSIDs []string `json:"groupMembershipSIDs,omitempty"`
+	LogonDomainName     string   `json:"logonDomainName"`
+	LogonDomainID       string   `json:"logonDomainID"`
+	LogonServer         string   `json:"logonServer,omitempty"`
+	UserPrincipalName   string   `json:"userPrincipalName,omitempty"`
+	DNSDomainName       string   `json:"dnsDomainName,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface. Times are rendered in RFC3339 format and omitted if not set
+// or never expiring.
+func (a ADCredentials) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonADCredentials{
+		EffectiveName:       a.EffectiveName,
+		FullName:            a.FullName,
+		UserID:              a.UserID,
+		PrimaryGroupID:      a.PrimaryGroupID,
+		LogOnTime:           jsonTime(a.LogOnTime),
+		LogOffTime:          jsonTime(a.LogOffTime),
+		PasswordLastSet:     jsonTime(a.PasswordLastSet),
+		GroupMembershipSIDs: a.GroupMembershipSIDs,
+		LogonDomainName:     a.LogonDomainName,
+		LogonDomainID:       a.LogonDomainID,
+		LogonServer:         a.LogonServer,
+		UserPrincipalName:   a.UserPrincipalName,
+		DNSDomainName:       a.DNSDomainName,
+	})
+}
+
+// MarshalJSON implements the json.Marshaler interface so that the identity can be included in audit logs.
+// The keytab and password are not included.
+func (c *Credentials) MarshalJSON() ([]byte, error) {
+	jc := jsonCredentials{
+		Username:        c.username,
+		DisplayName:     c.displayName,
+		Realm:           c.realm,
+		CName:           c.cname.PrincipalNameString(),
+		Keytab:          c.HasKeytab(),
+		Password:        c.HasPassword(),
+		ValidUntil:      jsonTime(c.validUntil),
+		Authenticated:   c.authenticated,
+		Human:           c.human,
+		AuthTime:        jsonTime(c.authTime),
+		GroupMembership: c.AuthzAttributes(),
+		SessionID:       c.sessionID,
+	}
+	sort.Strings(jc.GroupMembership)
+	if a, ok := c.attributes[AttributeKeyADCredentials].(ADCredentials); ok {
+		jc.ADCredentials = &a
+	}
+	if ok, reason := c.PACUnavailable(); ok {
+		jc.PACUnavailable = reason
+	}
+	if t, ok := c.GetTicketInfo(); ok {
+		jc.Ticket = &t
+	}
+	return json.Marshal(jc)
+}
+
 // JSON return details of the Credentials in a JSON format.
 func (c *Credentials) JSON() (string, error) {
 	mc := marshalCredentials{
@@ -403,3 +589,22 @@ func (c *Credentials) JSON() (string, error) {
 	}
 	return string(b), nil
 }
+
+// AuditJSON returns details of the Credentials in the JSON format of MarshalJSON, including the AD credentials from
+// the PAC and the details of the ticket, for audit logging.
+func (c *Credentials) AuditJSON() (string, error) {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// jsonTime formats the time in RFC3339 format for JSON. An empty string is returned for times that are not set or
+// are beyond the range of RFC3339, such as the never expiring times found in a PAC.
+func jsonTime(t time.Time) string {
+	if t.IsZero() || t.Year() > 9999 {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -1,9 +1,13 @@
 package credentials
 
 import (
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/jcmturner/goidentity/v6"
+	"github.com/jcmturner/gokrb5/v8/iana/flags"
+	"github.com/jcmturner/gokrb5/v8/types"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -26,3 +30,84 @@ func TestCredentials_Marshal(t *testing.T) {
 		t.Fatalf("could not unmarshal credetials: %v", err)
 	}
 }
+
+func TestCredentials_JSON(t *testing.T) {
+	t.Parallel()
+	cred := New("user", "EXAMPLE.COM").WithPassword("secret")
+	cred.SetAuthTime(time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC))
+	cred.SetADCredentials(ADCredentials{
+		EffectiveName:       "user",
+		LogOnTime:           time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC),
+		LogOffTime:          time.Date(30828, 9, 14, 2, 48, 5, 0, time.UTC),
+		GroupMembershipSIDs: []string{"S-1-5-21-1-2-3-513", "S-1-5-21-1-2-3-512"},
+		LogonDomainID:       "S-1-5-21-1-2-3",
+	})
+	f := types.NewKrbFlags()
+	types.SetFlags(&f, []int{flags.Forwardable, flags.Renewable})
+	cred.SetTicketInfo(TicketInfo{
+		AuthTime:  time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		EndTime:   time.Date(2020, 1, 2, 13, 4, 5, 0, time.UTC),
+		RenewTill: time.Date(2020, 1, 9, 3, 4, 5, 0, time.UTC),
+		Flags:     f,
+	})
+	b, err := json.Marshal(cred)
+	if err != nil {
+		t.Fatalf("could not marshal credentials to JSON: %v", err)
+	}
+	s := string(b)
+	assert.NotContains(t, s, "secret", "the password should not be included")
+	var j map[string]interface{}
+	if err := json.Unmarshal(b, &j); err != nil {
+		t.Fatalf("could not unmarshal JSON: %v", err)
+	}
+	assert.Equal(t, "user", j["username"], "username not as expected")
+	assert.Equal(t, "user", j["cname"], "cname not as expected")
+	assert.Equal(t, true, j["password"], "password indicator not as expected")
+	assert.Equal(t, "2020-01-02T03:04:05Z", j["authTime"], "auth time not as expected")
+	assert.Equal(t, []interface{}{"S-1-5-21-1-2-3-512", "S-1-5-21-1-2-3-513"}, j["groupMembership"], "group membership not as expected")
+	ad, ok := j["adCredentials"].(map[string]interface{})
+	if assert.True(t, ok, "AD credentials not included") {
+		assert.Equal(t, "S-1-5-21-1-2-3", ad["logonDomainID"], "logon domain ID not as expected")
+		assert.Equal(t, "2020-01-02T03:04:05Z", ad["logOnTime"], "log on time not as expected")
+		assert.NotContains(t, ad, "logOffTime", "a never expiring time should be omitted")
+	}
+	tkt, ok := j["ticket"].(map[string]interface{})
+	if assert.True(t, ok, "ticket details not included") {
+		assert.Equal(t, "2020-01-02T13:04:05Z", tkt["endTime"], "end time not as expected")
+		assert.Equal(t, "2020-01-09T03:04:05Z", tkt["renewTill"], "renew till not as expected")
+		assert.Equal(t, "forwardable, renewable", tkt["flags"], "flags not as expected")
+	}
+}
+
+func TestCredentials_JSON_Format(t *testing.T) {
+	t.Parallel()
+	cred := New("user", "EXAMPLE.COM").WithPassword("secret")
+	cred.SetAuthTime(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+	cred.SetADCredentials(ADCredentials{EffectiveName: "user"})
+
+	s, err := cred.JSON()
+	if err != nil {
+		t.Fatalf("could not get JSON of credentials: %v", err)
+	}
+	var j map[string]interface{}
+	if err := json.Unmarshal([]byte(s), &j); err != nil {
+		t.Fatalf("could not unmarshal JSON: %v", err)
+	}
+	assert.Equal(t, "user", j["Username"], "username not as expected")
+	assert.Equal(t, "EXAMPLE.COM", j["Realm"], "realm not as expected")
+	assert.Equal(t, true, j["Password"], "password indicator not as expected")
+	assert.Equal(t, "2020-01-02T03:04:05Z", j["AuthTime"], "auth time not as expected")
+	assert.NotContains(t, j, "adCredentials", "AD credentials should not be included")
+
+	s, err = cred.AuditJSON()
+	if err != nil {
+		t.Fatalf("could not get audit JSON of credentials: %v", err)
+	}
+	j = map[string]interface{}{}
+	if err := json.Unmarshal([]byte(s), &j); err != nil {
+		t.Fatalf("could not unmarshal JSON: %v", err)
+	}
+	assert.Equal(t, "user", j["username"], "username not as expected")
+	assert.Contains(t, j, "adCredentials", "AD credentials should be included")
+	assert.NotContains(t, s, "secret", "the password should not be included")
+}
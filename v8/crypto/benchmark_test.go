@@ -0,0 +1,86 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/jcmturner/gokrb5/v8/crypto/etype"
+	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
+	"github.com/jcmturner/gokrb5/v8/iana/keyusage"
+)
+
+var benchETypes = []struct {
+	name string
+	id   int32
+}{
+	{"aes128-cts-hmac-sha1-96", etypeID.AES128_CTS_HMAC_SHA1_96},
+	{"aes256-cts-hmac-sha1-96", etypeID.AES256_CTS_HMAC_SHA1_96},
+	{"aes128-cts-hmac-sha256-128", etypeID.AES128_CTS_HMAC_SHA256_128},
+	{"aes256-cts-hmac-sha384-192", etypeID.AES256_CTS_HMAC_SHA384_192},
+	{"des3-cbc-sha1-kd", etypeID.DES3_CBC_SHA1_KD},
+	{"rc4-hmac", etypeID.RC4_HMAC},
+}
+
+func benchEType(b *testing.B, id int32) (etype.EType, []byte) {
+	et, err := GetEtype(id)
+	if err != nil {
+		b.Fatalf("error getting etype: %v", err)
+	}
+	key, err := et.StringToKey("benchmark", "TEST.GOKRB5benchmark", et.GetDefaultStringToKeyParams())
+	if err != nil {
+		b.Fatalf("error deriving key: %v", err)
+	}
+	return et, key
+}
+
+func BenchmarkStringToKey(b *testing.B) {
+	for _, e := range benchETypes {
+		b.Run(e.name, func(b *testing.B) {
+			et, _ := benchEType(b, e.id)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := et.StringToKey("benchmark", "TEST.GOKRB5benchmark", et.GetDefaultStringToKeyParams()); err != nil {
+					b.Fatalf("error deriving key: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkEncryptMessage(b *testing.B) {
+	msg := make([]byte, 1024)
+	for _, e := range benchETypes {
+		b.Run(e.name, func(b *testing.B) {
+			et, key := benchEType(b, e.id)
+			b.SetBytes(int64(len(msg)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := et.EncryptMessage(key, msg, keyusage.GSSAPI_INITIATOR_SEAL); err != nil {
+					b.Fatalf("error encrypting: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDecryptMessage(b *testing.B) {
+	msg := make([]byte, 1024)
+	for _, e := range benchETypes {
+		b.Run(e.name, func(b *testing.B) {
+			et, key := benchEType(b, e.id)
+			_, ct, err := et.EncryptMessage(key, msg, keyusage.GSSAPI_INITIATOR_SEAL)
+			if err != nil {
+				b.Fatalf("error encrypting: %v", err)
+			}
+			b.SetBytes(int64(len(msg)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := et.DecryptMessage(key, ct, keyusage.GSSAPI_INITIATOR_SEAL); err != nil {
+					b.Fatalf("error decrypting: %v", err)
+				}
+			}
+		})
+	}
+}
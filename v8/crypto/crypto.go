@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"fmt"
 
+	"github.com/jcmturner/gokrb5/v8/crypto/common"
 	"github.com/jcmturner/gokrb5/v8/crypto/etype"
 	"github.com/jcmturner/gokrb5/v8/iana/chksumtype"
 	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
@@ -69,10 +70,17 @@ func GetChksumEtype(id int32) (etype.EType, error) {
 
 // GetKeyFromPassword generates an encryption key from the principal's password.
 func GetKeyFromPassword(passwd string, cname types.PrincipalName, realm string, etypeID int32, pas types.PADataSequence) (types.EncryptionKey, etype.EType, error) {
+	return GetKeyFromPasswordSalt(passwd, cname.GetSalt(realm), etypeID, pas)
+}
+
+// GetKeyFromPasswordSalt generates an encryption key from a password using the salt provided if the PAData does not
+// specify one. This is required for principals whose salt is not the default derived from the principal name, such
+// as Active Directory computer accounts.
+func GetKeyFromPasswordSalt(passwd, defaultSalt string, etypeID int32, pas types.PADataSequence) (types.EncryptionKey, etype.EType, error) {
 	var key types.EncryptionKey
 	et, err := GetEtype(etypeID)
 	if err != nil {
-		return key, et, fmt.Errorf("error getting encryption type: %v", err)
+		return key, et, fmt.Errorf("error getting encryption type: %w", err)
 	}
 	sk2p := et.GetDefaultStringToKeyParams()
 	var salt string
@@ -91,12 +99,12 @@ func GetKeyFromPassword(passwd string, cname types.PrincipalName, realm string,
 			var eti types.ETypeInfo
 			err := eti.Unmarshal(pa.PADataValue)
 			if err != nil {
-				return key, et, fmt.Errorf("error unmashaling PA Data to PA-ETYPE-INFO2: %v", err)
+				return key, et, fmt.Errorf("error unmashaling PA Data to PA-ETYPE-INFO2: %w", err)
 			}
 			if etypeID != eti[0].EType {
 				et, err = GetEtype(eti[0].EType)
 				if err != nil {
-					return key, et, fmt.Errorf("error getting encryption type: %v", err)
+					return key, et, fmt.Errorf("error getting encryption type: %w", err)
 				}
 			}
 			salt = string(eti[0].Salt)
@@ -107,12 +115,12 @@ func GetKeyFromPassword(passwd string, cname types.PrincipalName, realm string,
 			var et2 types.ETypeInfo2
 			err := et2.Unmarshal(pa.PADataValue)
 			if err != nil {
-				return key, et, fmt.Errorf("error unmashalling PA Data to PA-ETYPE-INFO2: %v", err)
+				return key, et, fmt.Errorf("error unmashalling PA Data to PA-ETYPE-INFO2: %w", err)
 			}
 			if etypeID != et2[0].EType {
 				et, err = GetEtype(et2[0].EType)
 				if err != nil {
-					return key, et, fmt.Errorf("error getting encryption type: %v", err)
+					return key, et, fmt.Errorf("error getting encryption type: %w", err)
 				}
 			}
 			if len(et2[0].S2KParams) == 4 {
@@ -122,7 +130,7 @@ func GetKeyFromPassword(passwd string, cname types.PrincipalName, realm string,
 		}
 	}
 	if salt == "" {
-		salt = cname.GetSalt(realm)
+		salt = defaultSalt
 	}
 	k, err := et.StringToKey(passwd, salt, sk2p)
 	if err != nil {
@@ -141,7 +149,7 @@ func GetEncryptedData(plainBytes []byte, key types.EncryptionKey, usage uint32,
 	var ed types.EncryptedData
 	et, err := GetEtype(key.KeyType)
 	if err != nil {
-		return ed, fmt.Errorf("error getting etype: %v", err)
+		return ed, fmt.Errorf("error getting etype: %w", err)
 	}
 	_, b, err := et.EncryptMessage(key.KeyValue, plainBytes, usage)
 	if err != nil {
@@ -165,11 +173,33 @@ func DecryptEncPart(ed types.EncryptedData, key types.EncryptionKey, usage uint3
 func DecryptMessage(ciphertext []byte, key types.EncryptionKey, usage uint32) ([]byte, error) {
 	et, err := GetEtype(key.KeyType)
 	if err != nil {
-		return []byte{}, fmt.Errorf("error decrypting: %v", err)
+		return []byte{}, fmt.Errorf("error decrypting: %w", err)
 	}
 	b, err := et.DecryptMessage(key.KeyValue, ciphertext, usage)
 	if err != nil {
-		return nil, fmt.Errorf("error decrypting: %v", err)
+		return nil, fmt.Errorf("error decrypting: %w", err)
 	}
 	return b, nil
 }
+
+// DeriveKey returns a key of the encryption type of the key provided derived from it with the RFC 3961 key derivation
+// function for the key usage number, for protocols that need keys in addition to the session key or subkey of a
+// security context. Usage numbers below 1024 are used by Kerberos itself and are rejected, RFC 4120 reserving 1024 to
+// 2047 for application use.
+func DeriveKey(key types.EncryptionKey, usage uint32) (types.EncryptionKey, error) {
+	if usage < 1024 {
+		return types.EncryptionKey{}, fmt.Errorf("key usage %d is reserved for Kerberos", usage)
+	}
+	et, err := GetEtype(key.KeyType)
+	if err != nil {
+		return types.EncryptionKey{}, fmt.Errorf("error deriving key: %w", err)
+	}
+	k, err := et.DeriveKey(key.KeyValue, common.GetUsageKe(usage))
+	if err != nil {
+		return types.EncryptionKey{}, fmt.Errorf("error deriving key: %w", err)
+	}
+	return types.EncryptionKey{
+		KeyType:  key.KeyType,
+		KeyValue: k,
+	}, nil
+}
@@ -0,0 +1,44 @@
+package crypto
+
+import (
+	"testing"
+
+	"github.com/jcmturner/gokrb5/v8/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeriveKey(t *testing.T) {
+	t.Parallel()
+	for _, e := range benchETypes {
+		et, err := GetEtype(e.id)
+		require.NoError(t, err, "error getting etype %s", e.name)
+		kv, err := et.StringToKey("password", "TEST.GOKRB5testuser1", et.GetDefaultStringToKeyParams())
+		require.NoError(t, err, "error deriving key for %s", e.name)
+		key := types.EncryptionKey{KeyType: e.id, KeyValue: kv}
+
+		k1, err := DeriveKey(key, 1024)
+		require.NoError(t, err, "error deriving key for %s", e.name)
+		assert.Equal(t, e.id, k1.KeyType, "derived key type not as expected for %s", e.name)
+		assert.Len(t, k1.KeyValue, len(kv), "derived key length not as expected for %s", e.name)
+		assert.NotEqual(t, key.KeyValue, k1.KeyValue, "derived key should differ from the base key for %s", e.name)
+		again, err := DeriveKey(key, 1024)
+		require.NoError(t, err, "error deriving key for %s", e.name)
+		assert.Equal(t, k1, again, "derivation should be deterministic for %s", e.name)
+		k2, err := DeriveKey(key, 1025)
+		require.NoError(t, err, "error deriving key for %s", e.name)
+		assert.NotEqual(t, k1.KeyValue, k2.KeyValue, "keys of different usages should differ for %s", e.name)
+
+		// The derived key is usable with its encryption type
+		_, ct, err := et.EncryptMessage(k1.KeyValue, []byte("application data"), 1)
+		require.NoError(t, err, "error encrypting with derived key for %s", e.name)
+		pt, err := et.DecryptMessage(k1.KeyValue, ct, 1)
+		require.NoError(t, err, "error decrypting with derived key for %s", e.name)
+		assert.Equal(t, []byte("application data"), pt, "decrypted data not as expected for %s", e.name)
+	}
+
+	_, err := DeriveKey(types.EncryptionKey{KeyType: 18, KeyValue: make([]byte, 32)}, 22)
+	assert.Error(t, err, "usages reserved for Kerberos should be rejected")
+	_, err = DeriveKey(types.EncryptionKey{KeyType: 99, KeyValue: make([]byte, 32)}, 1024)
+	assert.Error(t, err, "unknown encryption types should be rejected")
+}
@@ -4,20 +4,18 @@ import (
 	"encoding/hex"
 	"testing"
 
+	"github.com/jcmturner/gokrb5/v8/test/testdata"
 	"github.com/stretchr/testify/assert"
 )
 
-const (
-	testPassword = "foo"
-	testKey      = "ac8e657f83df82beea5d43bdaf7800cc"
-)
-
 func TestStringToKey(t *testing.T) {
 	t.Parallel()
-	kb, err := StringToKey(testPassword)
-	if err != nil {
-		t.Fatalf("Error deriving key from string: %v", err)
+	for _, test := range testdata.RFC4757StringToKeyVectors {
+		kb, err := StringToKey(test.Phrase)
+		if err != nil {
+			t.Fatalf("Error deriving key from string: %v", err)
+		}
+		k := hex.EncodeToString(kb)
+		assert.Equal(t, test.Key, k, "Key not as expected")
 	}
-	k := hex.EncodeToString(kb)
-	assert.Equal(t, testKey, k, "Key not as expected")
 }
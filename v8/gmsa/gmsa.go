@@ -0,0 +1,255 @@
+// Package gmsa provides the derivation of the keys of Active Directory group Managed Service Accounts.
+package gmsa
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf16"
+
+	"github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/crypto"
+	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"golang.org/x/crypto/md4"
+)
+
+// managedPasswordBlobVersion is the only version of the MSDS-MANAGEDPASSWORD_BLOB structure defined.
+const managedPasswordBlobVersion = 1
+
+// managedPasswordHeaderLen is the length of the fixed fields at the start of a MSDS-MANAGEDPASSWORD_BLOB.
+const managedPasswordHeaderLen = 16
+
+// ETypes are the encryption types keys are derived for by default.
+var ETypes = []int32{
+	etypeID.AES256_CTS_HMAC_SHA1_96,
+	etypeID.AES128_CTS_HMAC_SHA1_96,
+	etypeID.RC4_HMAC,
+}
+
+// ManagedPassword is the password of a group Managed Service Account as returned in the msDS-ManagedPassword
+// attribute. Passwords are held as the UTF-16 little endian bytes provided by Active Directory.
+//
+// https://docs.microsoft.com/en-us/openspecs/windows_protocols/ms-adts/a9019740-3d73-46ef-a9ae-3ea8eb86ac2e
+type ManagedPassword struct {
+	Current  []byte
+	Previous []byte
+	// QueryPasswordInterval is the time after which the password should be queried again.
+	QueryPasswordInterval time.Duration
+	// UnchangedPasswordInterval is the time during which the current password will not change.
+	UnchangedPasswordInterval time.Duration
+}
+
+// ParseManagedPassword parses the MSDS-MANAGEDPASSWORD_BLOB value of the msDS-ManagedPassword attribute.
+func ParseManagedPassword(b []byte) (*ManagedPassword, error) {
+	if len(b) < managedPasswordHeaderLen {
+		return nil, errors.New("managed password blob too short")
+	}
+	if v := binary.LittleEndian.Uint16(b[0:2]); v != managedPasswordBlobVersion {
+		return nil, fmt.Errorf("managed password blob version %d not supported", v)
+	}
+	if l := binary.LittleEndian.Uint32(b[4:8]); int(l) != len(b) {
+		return nil, fmt.Errorf("managed password blob length %d does not match the %d bytes provided", l, len(b))
+	}
+	cur := int(binary.LittleEndian.Uint16(b[8:10]))
+	prev := int(binary.LittleEndian.Uint16(b[10:12]))
+	query := int(binary.LittleEndian.Uint16(b[12:14]))
+	unchanged := int(binary.LittleEndian.Uint16(b[14:16]))
+	for _, o := range []int{cur, query, unchanged} {
+		if o < managedPasswordHeaderLen || o+8 > len(b) {
+			return nil, fmt.Errorf("managed password blob offset %d out of range", o)
+		}
+	}
+	if prev != 0 && (prev < managedPasswordHeaderLen || prev >= len(b)) {
+		return nil, fmt.Errorf("managed password blob offset %d out of range", prev)
+	}
+	m := &ManagedPassword{
+		QueryPasswordInterval:     fileTimeInterval(b[query : query+8]),
+		UnchangedPasswordInterval: fileTimeInterval(b[unchanged : unchanged+8]),
+	}
+	var err error
+	m.Current, err = utf16String(b[cur:])
+	if err != nil {
+		return nil, fmt.Errorf("managed password blob current password malformed: %v", err)
+	}
+	if prev != 0 {
+		m.Previous, err = utf16String(b[prev:])
+		if err != nil {
+			return nil, fmt.Errorf("managed password blob previous password malformed: %v", err)
+		}
+	}
+	return m, nil
+}
+
+// fileTimeInterval converts an interval in 100 nanosecond units to a duration.
+func fileTimeInterval(b []byte) time.Duration {
+	v := binary.LittleEndian.Uint64(b)
+	if v > uint64(1<<63-1)/100 {
+		return time.Duration(1<<63 - 1)
+	}
+	return time.Duration(v * 100)
+}
+
+// utf16String returns the bytes of the null terminated UTF-16 string at the start of b.
+func utf16String(b []byte) ([]byte, error) {
+	for i := 0; i+1 < len(b); i += 2 {
+		if b[i] == 0 && b[i+1] == 0 {
+			if i == 0 {
+				return nil, errors.New("empty password")
+			}
+			return b[:i], nil
+		}
+	}
+	return nil, errors.New("password is not null terminated")
+}
+
+// Key derives the key of the encryption type for the account name and realm from the password provided.
+// The name is the account's sAMAccountName, Eg. SVC$, the salt of which is constructed as for computer accounts.
+func Key(password []byte, name, realm string, etype int32) (types.EncryptionKey, error) {
+	if etype == etypeID.RC4_HMAC {
+		// The RC4 key is the MD4 hash of the UTF-16 password as provided
+		h := md4.New()
+		h.Write(password)
+		return types.EncryptionKey{KeyType: etype, KeyValue: h.Sum(nil)}, nil
+	}
+	// Other encryption types use the password converted to UTF-8, with any invalid UTF-16 replaced as Windows does
+	u := make([]uint16, len(password)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(password[i*2:])
+	}
+	key, _, err := crypto.GetKeyFromPasswordSalt(string(utf16.Decode(u)), types.MachineAccountSalt(name, realm), etype, types.PADataSequence{})
+	return key, err
+}
+
+// Keytab returns a keytab holding the keys of the account derived from the current password with the key version
+// number provided and, if available, from the previous password with the preceding key version number.
+// The previous keys allow services to continue to accept tickets issued before the password changed.
+// If no encryption types are specified ETypes are used.
+func (m *ManagedPassword) Keytab(name, realm string, kvno uint32, etypes ...int32) (*keytab.Keytab, error) {
+	if len(etypes) == 0 {
+		etypes = ETypes
+	}
+	name = types.MachineAccountName(name)
+	pn := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, name)
+	now := time.Now().UTC()
+	kt := keytab.New()
+	add := func(p []byte, kvno uint32, ts time.Time) error {
+		for _, e := range etypes {
+			key, err := Key(p, name, realm, e)
+			if err != nil {
+				return err
+			}
+			kt.AddKey(pn, realm, key, ts, uint8(kvno))
+			kt.Entries[len(kt.Entries)-1].KVNO = kvno
+		}
+		return nil
+	}
+	if len(m.Previous) > 0 && kvno > 1 {
+		// Given an earlier timestamp so the current keys are preferred where the key version number is not specified
+		if err := add(m.Previous, kvno-1, now.Add(-time.Second)); err != nil {
+			return nil, err
+		}
+	}
+	if err := add(m.Current, kvno, now); err != nil {
+		return nil, err
+	}
+	return kt, nil
+}
+
+// Source returns the current msDS-ManagedPassword attribute value of a group Managed Service Account and its key
+// version number from the msDS-KeyVersionNumber attribute, typically by querying a domain controller over LDAP.
+type Source func() (blob []byte, kvno uint32, err error)
+
+// Account is a group Managed Service Account whose keys are rolled automatically as its password is changed by
+// Active Directory. It is safe for concurrent use.
+type Account struct {
+	name    string
+	realm   string
+	source  Source
+	etypes  []int32
+	mux     sync.Mutex
+	kt      *keytab.Keytab
+	kvno    uint32
+	refresh time.Time
+	cl      *client.Client
+	clKVNO  uint32
+}
+
+// NewAccount returns an Account for the gMSA name, Eg. SVC$, in the realm whose password is obtained from the source.
+// Keys are derived for the encryption types specified or ETypes if none are.
+func NewAccount(name, realm string, source Source, etypes ...int32) *Account {
+	return &Account{
+		name:   types.MachineAccountName(name),
+		realm:  realm,
+		source: source,
+		etypes: etypes,
+	}
+}
+
+// Name returns the account name.
+func (a *Account) Name() string {
+	return a.name
+}
+
+// Keytab returns the keytab of the account's current and previous keys.
+// The source is queried again for the password once the query interval of the last password obtained has elapsed
+// and a new keytab is returned. Keytabs returned are not modified afterwards.
+func (a *Account) Keytab() (*keytab.Keytab, error) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	err := a.update()
+	return a.kt, err
+}
+
+// Client returns a client logged in as the account. The same client is returned until the account's password has
+// changed, then a new client with the new keys is created with the configuration and settings provided. Callers
+// should obtain the client from the account each time one is needed rather than retaining it.
+// The client replaced is not destroyed as callers may still be using it. It continues to hold the previous key, which
+// remains valid until the password is next changed.
+func (a *Account) Client(krb5conf *config.Config, settings ...func(*client.Settings)) (*client.Client, error) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	err := a.update()
+	if err != nil && a.kt == nil {
+		return nil, err
+	}
+	if a.cl != nil && a.clKVNO == a.kvno {
+		return a.cl, nil
+	}
+	cl := client.NewWithMachineKeytab(a.name, a.realm, a.kt, krb5conf, settings...)
+	if err := cl.Login(); err != nil {
+		return nil, err
+	}
+	a.cl = cl
+	a.clKVNO = a.kvno
+	return cl, nil
+}
+
+// update derives the keytab from the source if it is due to be refreshed.
+// If the source fails the existing keytab is retained and the error returned. The caller must hold the lock.
+func (a *Account) update() error {
+	if a.kt != nil && time.Now().UTC().Before(a.refresh) {
+		return nil
+	}
+	b, kvno, err := a.source()
+	if err != nil {
+		return fmt.Errorf("could not get managed password of %s: %w", a.name, err)
+	}
+	m, err := ParseManagedPassword(b)
+	if err != nil {
+		return err
+	}
+	kt, err := m.Keytab(a.name, a.realm, kvno, a.etypes...)
+	if err != nil {
+		return fmt.Errorf("could not derive keys of %s: %w", a.name, err)
+	}
+	a.kt = kt
+	a.kvno = kvno
+	a.refresh = time.Now().UTC().Add(m.QueryPasswordInterval)
+	return nil
+}
@@ -0,0 +1,161 @@
+package gmsa
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/crypto"
+	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/jcmturner/gokrb5/v8/krbtest"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/md4"
+)
+
+// testPassword returns a 256 byte UTF-16 password as generated for a gMSA, seeded so passwords differ.
+func testPassword(seed byte) []byte {
+	b := make([]byte, 256)
+	for i := range b {
+		b[i] = seed + byte(i%200) + 1
+	}
+	return b
+}
+
+// testBlob returns a MSDS-MANAGEDPASSWORD_BLOB holding the passwords and intervals provided.
+func testBlob(current, previous []byte, query, unchanged time.Duration) []byte {
+	b := make([]byte, managedPasswordHeaderLen)
+	binary.LittleEndian.PutUint16(b[0:2], managedPasswordBlobVersion)
+	binary.LittleEndian.PutUint16(b[8:10], uint16(len(b)))
+	b = append(append(b, current...), 0, 0)
+	if previous != nil {
+		binary.LittleEndian.PutUint16(b[10:12], uint16(len(b)))
+		b = append(append(b, previous...), 0, 0)
+	}
+	i := make([]byte, 8)
+	binary.LittleEndian.PutUint16(b[12:14], uint16(len(b)))
+	binary.LittleEndian.PutUint64(i, uint64(query/100))
+	b = append(b, i...)
+	binary.LittleEndian.PutUint16(b[14:16], uint16(len(b)))
+	binary.LittleEndian.PutUint64(i, uint64(unchanged/100))
+	b = append(b, i...)
+	binary.LittleEndian.PutUint32(b[4:8], uint32(len(b)))
+	return b
+}
+
+func TestParseManagedPassword(t *testing.T) {
+	t.Parallel()
+	cur, prev := testPassword(1), testPassword(2)
+	m, err := ParseManagedPassword(testBlob(cur, prev, time.Hour, 24*time.Hour))
+	require.NoError(t, err, "error parsing managed password blob")
+	assert.Equal(t, cur, m.Current, "current password not as expected")
+	assert.Equal(t, prev, m.Previous, "previous password not as expected")
+	assert.Equal(t, time.Hour, m.QueryPasswordInterval, "query password interval not as expected")
+	assert.Equal(t, 24*time.Hour, m.UnchangedPasswordInterval, "unchanged password interval not as expected")
+
+	m, err = ParseManagedPassword(testBlob(cur, nil, time.Hour, time.Hour))
+	require.NoError(t, err, "error parsing managed password blob without a previous password")
+	assert.Nil(t, m.Previous, "there should be no previous password")
+
+	b := testBlob(cur, nil, time.Hour, time.Hour)
+	for name, bad := range map[string][]byte{
+		"short":     b[:10],
+		"truncated": b[:len(b)-1],
+		"version":   append([]byte{2, 0}, b[2:]...),
+	} {
+		_, err := ParseManagedPassword(bad)
+		assert.Error(t, err, "%s blob should not parse", name)
+	}
+}
+
+func TestKey(t *testing.T) {
+	t.Parallel()
+	// An unpaired surrogate is replaced when converting to UTF-8
+	p := []byte{'a', 0, 0x00, 0xd8, 'b', 0}
+	k, err := Key(p, "svc$", "EXAMPLE.COM", etypeID.AES256_CTS_HMAC_SHA1_96)
+	require.NoError(t, err, "error deriving AES key")
+	exp, _, err := crypto.GetKeyFromPasswordSalt("a�b", "EXAMPLE.COMhostsvc.example.com", etypeID.AES256_CTS_HMAC_SHA1_96, types.PADataSequence{})
+	require.NoError(t, err, "error deriving expected AES key")
+	assert.Equal(t, exp, k, "AES key not as expected")
+
+	k, err = Key(p, "svc$", "EXAMPLE.COM", etypeID.RC4_HMAC)
+	require.NoError(t, err, "error deriving RC4 key")
+	h := md4.New()
+	h.Write(p)
+	assert.Equal(t, h.Sum(nil), k.KeyValue, "RC4 key should be the MD4 of the UTF-16 password")
+}
+
+func TestManagedPassword_Keytab(t *testing.T) {
+	t.Parallel()
+	m := &ManagedPassword{Current: testPassword(1), Previous: testPassword(2)}
+	kt, err := m.Keytab("svc$", krbtest.Realm, 300)
+	require.NoError(t, err, "error creating keytab")
+	assert.Len(t, kt.Entries, 2*len(ETypes), "keytab should hold the current and previous keys")
+	pn := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "SVC$")
+	cur, kvno, err := kt.GetEncryptionKey(pn, krbtest.Realm, 0, etypeID.AES256_CTS_HMAC_SHA1_96)
+	require.NoError(t, err, "current key not found")
+	assert.Equal(t, 300, kvno, "the current key should be preferred")
+	prev, _, err := kt.GetEncryptionKey(pn, krbtest.Realm, 299, etypeID.AES256_CTS_HMAC_SHA1_96)
+	require.NoError(t, err, "previous key not found")
+	assert.NotEqual(t, cur, prev, "current and previous keys should differ")
+}
+
+func TestAccount(t *testing.T) {
+	t.Parallel()
+	cur := testPassword(1)
+	m := &ManagedPassword{Current: cur}
+	kt, err := m.Keytab("svc$", krbtest.Realm, 2)
+	require.NoError(t, err, "error creating keytab")
+	kdcKt := krbtest.KDCKeytab()
+	kdcKt.Entries = append(kdcKt.Entries, kt.Entries...)
+	kdc, err := krbtest.NewKDC(krbtest.Realm, kdcKt)
+	require.NoError(t, err, "error starting mock KDC")
+	defer kdc.Close()
+
+	var kvno, queries uint32 = 2, 0
+	interval := time.Hour
+	a := NewAccount("svc$", krbtest.Realm, func() ([]byte, uint32, error) {
+		atomic.AddUint32(&queries, 1)
+		if kvno == 0 {
+			return nil, 0, errors.New("source unavailable")
+		}
+		return testBlob(testPassword(byte(kvno-1)), nil, interval, interval), kvno, nil
+	})
+	assert.Equal(t, "SVC$", a.Name(), "account name not as expected")
+
+	cl, err := a.Client(kdc.Config())
+	require.NoError(t, err, "error logging in as the account")
+	assert.Equal(t, "SVC$", cl.Credentials.UserName(), "client username not as expected")
+	cl2, err := a.Client(kdc.Config())
+	require.NoError(t, err, "error getting the account's client")
+	assert.True(t, cl == cl2, "the same client should be returned until the password changes")
+	assert.Equal(t, uint32(1), atomic.LoadUint32(&queries), "the source should not be queried within the query interval")
+
+	// Expire the query interval and roll the password
+	a.refresh = time.Time{}
+	kvno = 3
+	kt2, err := a.Keytab()
+	require.NoError(t, err, "error getting rolled keytab")
+	assert.Equal(t, uint32(3), kt2.Entries[0].KVNO, "keytab should hold the new keys")
+
+	// A new client is logged in with the new keys while the one replaced remains usable
+	rkt, err := (&ManagedPassword{Current: testPassword(2)}).Keytab("svc$", krbtest.Realm, 3)
+	require.NoError(t, err, "error creating rolled keytab")
+	kdcKt.Entries = append(rkt.Entries, kdcKt.Entries...)
+	cl3, err := a.Client(kdc.Config())
+	require.NoError(t, err, "error logging in with the rolled keys")
+	assert.False(t, cl == cl3, "a new client should be returned once the password changes")
+	assert.Equal(t, "SVC$", cl.Credentials.UserName(), "the replaced client should not be destroyed")
+	_, _, err = cl.GetServiceTicket(krbtest.ServicePrincipal)
+	assert.NoError(t, err, "the replaced client should remain usable")
+
+	a.refresh = time.Time{}
+	kvno = 0
+	kt3, err := a.Keytab()
+	assert.Error(t, err, "the source error should be returned")
+	assert.True(t, kt2 == kt3, "the existing keytab should be retained if the source fails")
+}
@@ -6,16 +6,17 @@ import (
 	"testing"
 
 	"github.com/jcmturner/gokrb5/v8/iana/keyusage"
+	"github.com/jcmturner/gokrb5/v8/test/testdata"
 	"github.com/jcmturner/gokrb5/v8/types"
 	"github.com/stretchr/testify/assert"
 )
 
 const (
-	testMICPayload = "deadbeef"
+	testMICPayload = testdata.GSSAPI_MIC_PAYLOAD
 	// What a kerberized server might send
-	testMICChallengeFromAcceptor = "040401ffffffffff00000000575e85d6c34d12ba3e5b1b1310cd9cb3"
+	testMICChallengeFromAcceptor = testdata.GSSAPI_MIC_TOKEN_ACCEPTOR
 	// What an initiator client could reply
-	testMICChallengeReplyFromInitiator = "040400ffffffffff00000000000000009649ca09d2f1bc51ff6e5ca3"
+	testMICChallengeReplyFromInitiator = testdata.GSSAPI_MIC_TOKEN_INITIATOR
 
 	acceptorSign  = keyusage.GSSAPI_ACCEPTOR_SIGN
 	initiatorSign = keyusage.GSSAPI_INITIATOR_SIGN
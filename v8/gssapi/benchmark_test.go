@@ -0,0 +1,64 @@
+package gssapi
+
+import (
+	"testing"
+)
+
+func BenchmarkSecContext_Wrap(b *testing.B) {
+	msg := make([]byte, 1024)
+	for _, bm := range []struct {
+		name string
+		conf bool
+	}{{"integrity", false}, {"confidentiality", true}} {
+		b.Run(bm.name, func(b *testing.B) {
+			i, _ := testSecContexts(ContextFlagReplay | ContextFlagSequence)
+			b.SetBytes(int64(len(msg)))
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				if _, err := i.Wrap(msg, bm.conf); err != nil {
+					b.Fatalf("error wrapping message: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSecContext_Unwrap(b *testing.B) {
+	msg := make([]byte, 1024)
+	for _, bm := range []struct {
+		name string
+		conf bool
+	}{{"integrity", false}, {"confidentiality", true}} {
+		b.Run(bm.name, func(b *testing.B) {
+			i, a := testSecContexts(ContextFlagReplay | ContextFlagSequence)
+			tokens := make([][]byte, b.N)
+			for n := range tokens {
+				t, err := i.Wrap(msg, bm.conf)
+				if err != nil {
+					b.Fatalf("error wrapping message: %v", err)
+				}
+				tokens[n] = t
+			}
+			b.SetBytes(int64(len(msg)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				if _, _, err := a.Unwrap(tokens[n]); err != nil {
+					b.Fatalf("error unwrapping message: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSecContext_GetMIC(b *testing.B) {
+	msg := make([]byte, 1024)
+	i, _ := testSecContexts(ContextFlagReplay | ContextFlagSequence)
+	b.SetBytes(int64(len(msg)))
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		if _, err := i.GetMIC(msg); err != nil {
+			b.Fatalf("error creating MIC: %v", err)
+		}
+	}
+}
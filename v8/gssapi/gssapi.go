@@ -5,7 +5,7 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/jcmturner/gofork/encoding/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1"
 )
 
 // GSS-API OID names
@@ -15,6 +15,12 @@ const (
 	OIDMSLegacyKRB5 OIDName = "MSLegacyKRB5" // MechType OID for Kerberos 5
 	OIDSPNEGO       OIDName = "SPNEGO"
 	OIDGSSIAKerb    OIDName = "GSSIAKerb" // Indicates the client cannot get a service ticket and asks the server to serve as an intermediate to the target KDC. http://k5wiki.kerberos.org/wiki/Projects/IAKERB#IAKERB_mech
+
+	// GSS-API name type OID names, RFC 2743 section 4 and RFC 1964 section 2.1
+	OIDNTUserName          OIDName = "NTUserName"          // GSS_C_NT_USER_NAME
+	OIDNTHostbasedService  OIDName = "NTHostbasedService"  // GSS_C_NT_HOSTBASED_SERVICE
+	OIDNTExportName        OIDName = "NTExportName"        // GSS_C_NT_EXPORT_NAME
+	OIDNTKRB5PrincipalName OIDName = "NTKRB5PrincipalName" // GSS_KRB5_NT_PRINCIPAL_NAME
 )
 
 // GSS-API status values
@@ -128,6 +134,14 @@ func (o OIDName) OID() asn1.ObjectIdentifier {
 		return asn1.ObjectIdentifier{1, 2, 840, 48018, 1, 2, 2}
 	case OIDGSSIAKerb:
 		return asn1.ObjectIdentifier{1, 3, 6, 1, 5, 2, 5}
+	case OIDNTUserName:
+		return asn1.ObjectIdentifier{1, 2, 840, 113554, 1, 2, 1, 1}
+	case OIDNTHostbasedService:
+		return asn1.ObjectIdentifier{1, 2, 840, 113554, 1, 2, 1, 4}
+	case OIDNTExportName:
+		return asn1.ObjectIdentifier{1, 3, 6, 1, 5, 6, 4}
+	case OIDNTKRB5PrincipalName:
+		return asn1.ObjectIdentifier{1, 2, 840, 113554, 1, 2, 2, 1}
 	}
 	return asn1.ObjectIdentifier{}
 }
@@ -3,7 +3,7 @@ package gssapi
 import (
 	"testing"
 
-	"github.com/jcmturner/gofork/encoding/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -16,6 +16,10 @@ func TestOID(t *testing.T) {
 		{OIDKRB5, []int{1, 2, 840, 113554, 1, 2, 2}},
 		{OIDSPNEGO, []int{1, 3, 6, 1, 5, 5, 2}},
 		{OIDGSSIAKerb, []int{1, 3, 6, 1, 5, 2, 5}},
+		{OIDNTUserName, []int{1, 2, 840, 113554, 1, 2, 1, 1}},
+		{OIDNTHostbasedService, []int{1, 2, 840, 113554, 1, 2, 1, 4}},
+		{OIDNTExportName, []int{1, 3, 6, 1, 5, 6, 4}},
+		{OIDNTKRB5PrincipalName, []int{1, 2, 840, 113554, 1, 2, 2, 1}},
 	}
 
 	for _, tst := range tests {
@@ -0,0 +1,270 @@
+package gssapi
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jcmturner/gokrb5/v8/asn1"
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// exportNameTokenID is the token ID of an exported name, RFC 2743 section 3.2.
+const exportNameTokenID = "\x04\x01"
+
+// exportNameTokenHeaderLen is the length of the token ID and mechanism OID length of an exported name.
+const exportNameTokenHeaderLen = len(exportNameTokenID) + 2
+
+// Name is a GSS-API internal name for the Kerberos 5 mechanism: a principal name and, once canonicalized, its realm.
+type Name struct {
+	PrincipalName types.PrincipalName
+	Realm         string
+}
+
+// ImportName converts the string form of a name of the type provided to an internal name, as GSS_Import_name.
+//
+// Host based service names, GSS_C_NT_HOSTBASED_SERVICE, have the form service@host. If the host is omitted the local
+// host name is used. The host is converted to lower case.
+// User names, GSS_C_NT_USER_NAME, and Kerberos principal names, GSS_KRB5_NT_PRINCIPAL_NAME, have the Kerberos form
+// name/instance@REALM where the realm is optional and separators within components are escaped with a backslash.
+// Exported names, GSS_C_NT_EXPORT_NAME, are the bytes produced by Export as a string.
+func ImportName(name string, nameType OIDName) (Name, error) {
+	switch nameType {
+	case OIDNTHostbasedService:
+		service, host := name, ""
+		if i := strings.IndexByte(name, '@'); i >= 0 {
+			service, host = name[:i], name[i+1:]
+		}
+		if service == "" {
+			return Name{}, Status{Code: StatusBadName, Message: fmt.Sprintf("host based service name %q has no service", name)}
+		}
+		if host == "" {
+			h, err := os.Hostname()
+			if err != nil {
+				return Name{}, Status{Code: StatusFailure, Message: fmt.Sprintf("could not get local host name: %v", err)}
+			}
+			host = h
+		}
+		return Name{PrincipalName: types.PrincipalName{
+			NameType:   nametype.KRB_NT_SRV_HST,
+			NameString: []string{service, strings.ToLower(strings.TrimSuffix(host, "."))},
+		}}, nil
+	case OIDNTUserName, OIDNTKRB5PrincipalName:
+		n, err := parsePrincipal(name)
+		if err != nil {
+			return Name{}, Status{Code: StatusBadName, Message: err.Error()}
+		}
+		return n, nil
+	case OIDNTExportName:
+		return importExportedName([]byte(name))
+	}
+	return Name{}, Status{Code: StatusBadNameType, Message: fmt.Sprintf("name type %s not supported", nameType)}
+}
+
+// parsePrincipal parses a Kerberos principal name in the form name/instance@REALM with backslash escaping.
+func parsePrincipal(s string) (Name, error) {
+	var n Name
+	var c []byte
+	var realm bool
+	for i := 0; i < len(s); i++ {
+		switch b := s[i]; {
+		case b == '\\' && i+1 < len(s):
+			i++
+			c = append(c, unescape(s[i]))
+		case b == '/' && !realm:
+			n.PrincipalName.NameString = append(n.PrincipalName.NameString, string(c))
+			c = c[:0]
+		case b == '@' && !realm:
+			n.PrincipalName.NameString = append(n.PrincipalName.NameString, string(c))
+			c = c[:0]
+			realm = true
+		default:
+			c = append(c, b)
+		}
+	}
+	if realm {
+		if len(c) == 0 {
+			return Name{}, fmt.Errorf("principal name %q has an empty realm", s)
+		}
+		n.Realm = string(c)
+	} else {
+		n.PrincipalName.NameString = append(n.PrincipalName.NameString, string(c))
+	}
+	for _, c := range n.PrincipalName.NameString {
+		if c == "" {
+			return Name{}, fmt.Errorf("principal name %q has an empty component", s)
+		}
+	}
+	n.PrincipalName.NameType = nametype.KRB_NT_PRINCIPAL
+	if len(n.PrincipalName.NameString) > 1 {
+		n.PrincipalName.NameType = nametype.KRB_NT_SRV_INST
+	}
+	return n, nil
+}
+
+// unescape returns the character represented by the backslash escape sequence ending in b.
+func unescape(b byte) byte {
+	switch b {
+	case 'n':
+		return '\n'
+	case 't':
+		return '\t'
+	case 'b':
+		return '\b'
+	case '0':
+		return 0
+	}
+	return b
+}
+
+// escape returns the principal name component with separators escaped.
+func escape(s string) string {
+	if !strings.ContainsAny(s, "/@\\\n\t\b\x00") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '/', '@', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\b':
+			b.WriteString(`\b`)
+		case 0:
+			b.WriteString(`\0`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// String returns the name in the Kerberos form name/instance@REALM, as GSS_Display_name. The realm is omitted if the
+// name has not been canonicalized.
+func (n Name) String() string {
+	c := make([]string, len(n.PrincipalName.NameString))
+	for i, s := range n.PrincipalName.NameString {
+		c[i] = escape(s)
+	}
+	s := strings.Join(c, "/")
+	if n.Realm != "" {
+		s += "@" + escape(n.Realm)
+	}
+	return s
+}
+
+// IsMechanismName reports if the name has been canonicalized to a Kerberos 5 mechanism name, so a realm is set.
+func (n Name) IsMechanismName() bool {
+	return n.Realm != "" && len(n.PrincipalName.NameString) > 0
+}
+
+// Canonicalize returns the mechanism name of the name, as GSS_Canonicalize_name, using the configuration provided.
+// The host of a host based service name is canonicalized through DNS if the configuration's dns_canonicalize_hostname
+// is set, and its realm resolved from the configuration's domain_realm mappings. Other names without a realm are
+// given the configuration's default realm.
+func (n Name) Canonicalize(cfg *config.Config) (Name, error) {
+	if len(n.PrincipalName.NameString) == 0 {
+		return Name{}, Status{Code: StatusBadName, Message: "empty name"}
+	}
+	m := Name{
+		PrincipalName: types.PrincipalName{
+			NameType:   n.PrincipalName.NameType,
+			NameString: append([]string{}, n.PrincipalName.NameString...),
+		},
+		Realm: n.Realm,
+	}
+	if m.PrincipalName.NameType == nametype.KRB_NT_SRV_HST && len(m.PrincipalName.NameString) == 2 {
+		h := m.PrincipalName.NameString[1]
+		if cfg.LibDefaults.DNSCanonicalizeHostname {
+			if cname, err := cfg.Resolver().LookupCNAME(context.Background(), h); err == nil {
+				h = cname
+			}
+		}
+		h = strings.ToLower(strings.TrimSuffix(h, "."))
+		m.PrincipalName.NameString[1] = h
+		if m.Realm == "" {
+			m.Realm = cfg.ResolveRealm(h)
+		}
+	}
+	if m.Realm == "" {
+		m.Realm = cfg.LibDefaults.DefaultRealm
+	}
+	if m.Realm == "" {
+		return Name{}, Status{Code: StatusBadName, Message: fmt.Sprintf("could not determine the realm of %s", n)}
+	}
+	return m, nil
+}
+
+// Equal reports if the names are the same, as GSS_Compare_name. Name components are compared exactly. If either name
+// has not been canonicalized the realms are not compared.
+func (n Name) Equal(m Name) bool {
+	if n.Realm != "" && m.Realm != "" && n.Realm != m.Realm {
+		return false
+	}
+	return n.PrincipalName.Equal(m.PrincipalName)
+}
+
+// Export returns the exported form of the mechanism name, as GSS_Export_name, which may be compared byte for byte
+// with other exported names. The name must have been canonicalized.
+func (n Name) Export() ([]byte, error) {
+	if !n.IsMechanismName() {
+		return nil, Status{Code: StatusNameNotMN, Message: fmt.Sprintf("%s is not a mechanism name", n)}
+	}
+	oid, err := asn1.Marshal(OIDKRB5.OID())
+	if err != nil {
+		return nil, err
+	}
+	s := n.String()
+	b := make([]byte, 0, exportNameTokenHeaderLen+len(oid)+4+len(s))
+	b = append(b, exportNameTokenID...)
+	b = append(b, byte(len(oid)>>8), byte(len(oid)))
+	b = append(b, oid...)
+	l := make([]byte, 4)
+	binary.BigEndian.PutUint32(l, uint32(len(s)))
+	b = append(b, l...)
+	return append(b, s...), nil
+}
+
+// importExportedName parses a name exported by Export.
+func importExportedName(b []byte) (Name, error) {
+	bad := func(err error) (Name, error) {
+		return Name{}, Status{Code: StatusBadName, Message: fmt.Sprintf("malformed exported name: %v", err)}
+	}
+	if len(b) < exportNameTokenHeaderLen || string(b[:2]) != exportNameTokenID {
+		return bad(errors.New("invalid token header"))
+	}
+	l := int(binary.BigEndian.Uint16(b[2:4]))
+	b = b[exportNameTokenHeaderLen:]
+	if len(b) < l+4 {
+		return bad(errors.New("token too short"))
+	}
+	var oid asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(b[:l], &oid); err != nil {
+		return bad(err)
+	}
+	if !oid.Equal(OIDKRB5.OID()) {
+		return Name{}, Status{Code: StatusBadMech, Message: fmt.Sprintf("exported name is for mechanism %v", oid)}
+	}
+	b = b[l:]
+	nl := int(binary.BigEndian.Uint32(b[:4]))
+	if len(b)-4 != nl {
+		return bad(errors.New("name length does not match token"))
+	}
+	n, err := parsePrincipal(string(b[4:]))
+	if err != nil {
+		return bad(err)
+	}
+	if n.Realm == "" {
+		return bad(errors.New("name has no realm"))
+	}
+	return n, nil
+}
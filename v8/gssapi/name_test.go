@@ -0,0 +1,118 @@
+package gssapi
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testNameConf = `[libdefaults]
+  default_realm = EXAMPLE.COM
+  dns_canonicalize_hostname = false
+
+[domain_realm]
+  .other.example.org = OTHER.EXAMPLE.ORG
+`
+
+func TestImportName(t *testing.T) {
+	t.Parallel()
+	var tests = []struct {
+		name     string
+		nameType OIDName
+		strs     []string
+		nt       int32
+		realm    string
+	}{
+		{"HTTP@Web.Example.COM.", OIDNTHostbasedService, []string{"HTTP", "web.example.com"}, nametype.KRB_NT_SRV_HST, ""},
+		{"user", OIDNTUserName, []string{"user"}, nametype.KRB_NT_PRINCIPAL, ""},
+		{"user@EXAMPLE.COM", OIDNTUserName, []string{"user"}, nametype.KRB_NT_PRINCIPAL, "EXAMPLE.COM"},
+		{"HTTP/host.example.com@EXAMPLE.COM", OIDNTKRB5PrincipalName, []string{"HTTP", "host.example.com"}, nametype.KRB_NT_SRV_INST, "EXAMPLE.COM"},
+		{`user\@other@EXAMPLE.COM`, OIDNTKRB5PrincipalName, []string{"user@other"}, nametype.KRB_NT_PRINCIPAL, "EXAMPLE.COM"},
+		{`a\/b`, OIDNTKRB5PrincipalName, []string{"a/b"}, nametype.KRB_NT_PRINCIPAL, ""},
+	}
+	for _, test := range tests {
+		n, err := ImportName(test.name, test.nameType)
+		require.NoError(t, err, "error importing %s", test.name)
+		assert.Equal(t, test.strs, n.PrincipalName.NameString, "name strings not as expected for %s", test.name)
+		assert.Equal(t, test.nt, n.PrincipalName.NameType, "name type not as expected for %s", test.name)
+		assert.Equal(t, test.realm, n.Realm, "realm not as expected for %s", test.name)
+	}
+
+	h, err := os.Hostname()
+	require.NoError(t, err, "error getting host name")
+	n, err := ImportName("host", OIDNTHostbasedService)
+	require.NoError(t, err, "error importing service without a host")
+	assert.Equal(t, []string{"host", strings.ToLower(h)}, n.PrincipalName.NameString, "the local host should be used")
+
+	for _, bad := range []string{"@host", "user@", "a//b", ""} {
+		nt := OIDNTUserName
+		if strings.HasPrefix(bad, "@") {
+			nt = OIDNTHostbasedService
+		}
+		_, err := ImportName(bad, nt)
+		assert.Error(t, err, "%q should not import", bad)
+	}
+	_, err = ImportName("user", OIDSPNEGO)
+	require.Error(t, err, "a mechanism OID is not a name type")
+	assert.Equal(t, StatusBadNameType, err.(Status).Code, "status not as expected")
+}
+
+func TestName_Canonicalize(t *testing.T) {
+	t.Parallel()
+	cfg, err := config.NewFromString(testNameConf)
+	require.NoError(t, err, "error loading config")
+
+	n, _ := ImportName("HTTP@web.other.example.org", OIDNTHostbasedService)
+	m, err := n.Canonicalize(cfg)
+	require.NoError(t, err, "error canonicalizing name")
+	assert.Equal(t, "HTTP/web.other.example.org@OTHER.EXAMPLE.ORG", m.String(), "mechanism name not as expected")
+	assert.True(t, m.IsMechanismName(), "canonicalized name should be a mechanism name")
+	assert.False(t, n.IsMechanismName(), "imported host based name should not be a mechanism name")
+
+	n, _ = ImportName("user", OIDNTUserName)
+	m, err = n.Canonicalize(cfg)
+	require.NoError(t, err, "error canonicalizing name")
+	assert.Equal(t, "user@EXAMPLE.COM", m.String(), "the default realm should be used")
+
+	_, err = n.Canonicalize(config.New())
+	assert.Error(t, err, "a name should not canonicalize without a realm")
+}
+
+func TestName_Equal(t *testing.T) {
+	t.Parallel()
+	a, _ := ImportName("HTTP@Host.Example.com", OIDNTHostbasedService)
+	b, _ := ImportName("HTTP/host.example.com@EXAMPLE.COM", OIDNTKRB5PrincipalName)
+	c, _ := ImportName("HTTP/host.example.com@OTHER.COM", OIDNTKRB5PrincipalName)
+	d, _ := ImportName("http/host.example.com@EXAMPLE.COM", OIDNTKRB5PrincipalName)
+	assert.True(t, a.Equal(b), "names should be equal when one has no realm")
+	assert.False(t, b.Equal(c), "names in different realms should not be equal")
+	assert.False(t, b.Equal(d), "name components should be compared exactly")
+}
+
+func TestName_Export(t *testing.T) {
+	t.Parallel()
+	n, _ := ImportName(`HTTP/host\@x@EXAMPLE.COM`, OIDNTKRB5PrincipalName)
+	b, err := n.Export()
+	require.NoError(t, err, "error exporting name")
+	exp := append([]byte{0x04, 0x01, 0x00, 0x0b, 0x06, 0x09, 0x2a, 0x86, 0x48, 0x86, 0xf7, 0x12, 0x01, 0x02, 0x02, 0x00, 0x00, 0x00, 0x18},
+		`HTTP/host\@x@EXAMPLE.COM`...)
+	assert.Equal(t, exp, b, "exported name not as expected")
+
+	m, err := ImportName(string(b), OIDNTExportName)
+	require.NoError(t, err, "error importing exported name")
+	assert.True(t, n.Equal(m), "imported name should equal the exported name")
+	assert.Equal(t, "EXAMPLE.COM", m.Realm, "realm not as expected")
+
+	_, err = ImportName(string(b[:len(b)-1]), OIDNTExportName)
+	assert.Error(t, err, "a truncated exported name should not import")
+
+	u, _ := ImportName("user", OIDNTUserName)
+	_, err = u.Export()
+	require.Error(t, err, "a name without a realm should not export")
+	assert.Equal(t, StatusNameNotMN, err.(Status).Code, "status not as expected")
+}
@@ -0,0 +1,154 @@
+package gssapi
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/jcmturner/gokrb5/v8/credentials"
+)
+
+// NameAttrAuthIndicators is the name attribute of the authentication indicators of the ticket, RFC 8129, as named by
+// MIT Kerberos.
+const NameAttrAuthIndicators = "auth-indicators"
+
+// Name attributes of authenticated names, RFC 6680. The names of Microsoft PAC attributes follow those used by MIT
+// Kerberos, prefixed with urn:mspac:.
+const (
+	NameAttrMSPACGroupSIDs       = "urn:mspac:group-sids"
+	NameAttrMSPACUserSID         = "urn:mspac:user-sid"
+	NameAttrMSPACPrimaryGroupSID = "urn:mspac:primary-group-sid"
+	NameAttrMSPACEffectiveName   = "urn:mspac:effective-name"
+	NameAttrMSPACFullName        = "urn:mspac:full-name"
+	NameAttrMSPACLogonDomainName = "urn:mspac:logon-domain-name"
+	NameAttrMSPACLogonDomainSID  = "urn:mspac:logon-domain-sid"
+	NameAttrMSPACLogonServer     = "urn:mspac:logon-server"
+	NameAttrMSPACUPN             = "urn:mspac:upn"
+	NameAttrMSPACDNSDomainName   = "urn:mspac:dns-domain-name"
+)
+
+// NameAttribute is an attribute of an authenticated name and its values, as returned by GSS_Get_name_attribute.
+type NameAttribute struct {
+	// Name is the name of the attribute.
+	Name string
+	// Authenticated indicates that the values were verified by the mechanism, such as by the checksums of the PAC.
+	Authenticated bool
+	// Complete indicates that all the values of the attribute are present.
+	Complete bool
+	// Values are the values of the attribute in their display form.
+	Values []string
+}
+
+// AuthenticatedName is the mechanism name of an authenticated initiator with the attributes of the name sourced from
+// the authorization data of the ticket it was authenticated with, RFC 6680. It provides access to the attributes
+// without knowledge of the structures they were obtained from.
+type AuthenticatedName struct {
+	Name
+	attrs map[string]NameAttribute
+}
+
+// nameAttrSource returns name attributes from the credentials of an authenticated client.
+type nameAttrSource func(creds *credentials.Credentials) []NameAttribute
+
+// nameAttrSources are the sources of the attributes of authenticated names.
+var nameAttrSources = []nameAttrSource{
+	mspacNameAttrs,
+	authIndicatorNameAttrs,
+}
+
+// NewAuthenticatedName returns the AuthenticatedName of the credentials a service authenticated a client with.
+// Credentials that are not authenticated have no attributes.
+func NewAuthenticatedName(creds *credentials.Credentials) AuthenticatedName {
+	n := AuthenticatedName{
+		Name: Name{
+			PrincipalName: creds.CName(),
+			Realm:         creds.Realm(),
+		},
+		attrs: make(map[string]NameAttribute),
+	}
+	if !creds.Authenticated() {
+		return n
+	}
+	for _, src := range nameAttrSources {
+		for _, a := range src(creds) {
+			if len(a.Values) > 0 {
+				n.attrs[a.Name] = a
+			}
+		}
+	}
+	return n
+}
+
+// InquireName returns the names of the attributes of the name in sorted order, as GSS_Inquire_name.
+func (n AuthenticatedName) InquireName() []string {
+	s := make([]string, 0, len(n.attrs))
+	for k := range n.attrs {
+		s = append(s, k)
+	}
+	sort.Strings(s)
+	return s
+}
+
+// GetNameAttribute returns the attribute of the name provided, as GSS_Get_name_attribute.
+// The boolean returned is false if the name does not have the attribute.
+func (n AuthenticatedName) GetNameAttribute(attr string) (NameAttribute, bool) {
+	a, ok := n.attrs[attr]
+	if !ok {
+		return a, false
+	}
+	a.Values = append([]string(nil), a.Values...)
+	return a, true
+}
+
+// authIndicatorNameAttrs returns the name attribute of the authentication indicators of the ticket the credentials
+// were authenticated with.
+func authIndicatorNameAttrs(creds *credentials.Credentials) []NameAttribute {
+	ti, ok := creds.GetTicketInfo()
+	if !ok {
+		return nil
+	}
+	return []NameAttribute{{
+		Name:          NameAttrAuthIndicators,
+		Authenticated: true,
+		Complete:      true,
+		Values:        ti.AuthIndicators,
+	}}
+}
+
+// mspacNameAttrs returns the name attributes from the PAC of the credentials, if it was processed.
+func mspacNameAttrs(creds *credentials.Credentials) []NameAttribute {
+	if _, ok := creds.Attributes()[credentials.AttributeKeyADCredentials]; !ok {
+		return nil
+	}
+	ad := creds.GetADCredentials()
+	attr := func(name string, values ...string) NameAttribute {
+		v := make([]string, 0, len(values))
+		for _, s := range values {
+			if s != "" {
+				v = append(v, s)
+			}
+		}
+		return NameAttribute{
+			Name:          name,
+			Authenticated: true,
+			Complete:      true,
+			Values:        v,
+		}
+	}
+	var userSID, primaryGroupSID string
+	if ad.LogonDomainID != "" {
+		userSID = ad.LogonDomainID + "-" + strconv.Itoa(ad.UserID)
+		primaryGroupSID = ad.LogonDomainID + "-" + strconv.Itoa(ad.PrimaryGroupID)
+	}
+	return []NameAttribute{
+		attr(NameAttrMSPACGroupSIDs, ad.GroupMembershipSIDs...),
+		attr(NameAttrMSPACUserSID, userSID),
+		attr(NameAttrMSPACPrimaryGroupSID, primaryGroupSID),
+		attr(NameAttrMSPACEffectiveName, ad.EffectiveName),
+		attr(NameAttrMSPACFullName, ad.FullName),
+		attr(NameAttrMSPACLogonDomainName, ad.LogonDomainName),
+		attr(NameAttrMSPACLogonDomainSID, ad.LogonDomainID),
+		attr(NameAttrMSPACLogonServer, ad.LogonServer),
+		attr(NameAttrMSPACUPN, ad.UserPrincipalName),
+		attr(NameAttrMSPACDNSDomainName, ad.DNSDomainName),
+	}
+}
@@ -0,0 +1,72 @@
+package gssapi
+
+import (
+	"testing"
+
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAuthenticatedName(t *testing.T) {
+	t.Parallel()
+	creds := credentials.New("testuser1", "TEST.GOKRB5")
+	creds.SetAuthenticated(true)
+	creds.SetADCredentials(credentials.ADCredentials{
+		EffectiveName:       "testuser1",
+		UserID:              1105,
+		PrimaryGroupID:      513,
+		GroupMembershipSIDs: []string{"S-1-5-21-1-2-3-513", "S-1-5-21-1-2-3-1108"},
+		LogonDomainName:     "TEST",
+		LogonDomainID:       "S-1-5-21-1-2-3",
+		UserPrincipalName:   "testuser1@test.gokrb5",
+	})
+	n := NewAuthenticatedName(creds)
+	assert.Equal(t, "testuser1@TEST.GOKRB5", n.String(), "name not as expected")
+	assert.Equal(t, []string{
+		NameAttrMSPACEffectiveName,
+		NameAttrMSPACGroupSIDs,
+		NameAttrMSPACLogonDomainName,
+		NameAttrMSPACLogonDomainSID,
+		NameAttrMSPACPrimaryGroupSID,
+		NameAttrMSPACUPN,
+		NameAttrMSPACUserSID,
+	}, n.InquireName(), "attributes not as expected")
+
+	a, ok := n.GetNameAttribute(NameAttrMSPACGroupSIDs)
+	assert.True(t, ok, "group SIDs attribute not found")
+	assert.True(t, a.Authenticated, "PAC attributes should be authenticated")
+	assert.Equal(t, []string{"S-1-5-21-1-2-3-513", "S-1-5-21-1-2-3-1108"}, a.Values, "group SIDs not as expected")
+	a.Values[0] = "changed"
+	a, _ = n.GetNameAttribute(NameAttrMSPACGroupSIDs)
+	assert.Equal(t, "S-1-5-21-1-2-3-513", a.Values[0], "values returned should be a copy")
+
+	a, _ = n.GetNameAttribute(NameAttrMSPACUserSID)
+	assert.Equal(t, []string{"S-1-5-21-1-2-3-1105"}, a.Values, "user SID not as expected")
+	a, _ = n.GetNameAttribute(NameAttrMSPACUPN)
+	assert.Equal(t, []string{"testuser1@test.gokrb5"}, a.Values, "UPN not as expected")
+	_, ok = n.GetNameAttribute(NameAttrMSPACFullName)
+	assert.False(t, ok, "empty attributes should not be present")
+}
+
+func TestNewAuthenticatedName_AuthIndicators(t *testing.T) {
+	t.Parallel()
+	creds := credentials.New("testuser1", "TEST.GOKRB5")
+	creds.SetAuthenticated(true)
+	creds.SetTicketInfo(credentials.TicketInfo{AuthIndicators: []string{"pkinit", "otp"}})
+	n := NewAuthenticatedName(creds)
+	assert.Equal(t, []string{NameAttrAuthIndicators}, n.InquireName(), "attributes not as expected")
+	a, ok := n.GetNameAttribute(NameAttrAuthIndicators)
+	assert.True(t, ok, "authentication indicators attribute not found")
+	assert.Equal(t, []string{"pkinit", "otp"}, a.Values, "authentication indicators not as expected")
+}
+
+func TestNewAuthenticatedName_NoPAC(t *testing.T) {
+	t.Parallel()
+	creds := credentials.New("testuser1", "TEST.GOKRB5")
+	creds.SetAuthenticated(true)
+	assert.Empty(t, NewAuthenticatedName(creds).InquireName(), "credentials without a PAC should have no attributes")
+
+	creds = credentials.New("testuser1", "TEST.GOKRB5")
+	creds.SetADCredentials(credentials.ADCredentials{EffectiveName: "testuser1"})
+	assert.Empty(t, NewAuthenticatedName(creds).InquireName(), "unauthenticated credentials should have no attributes")
+}
@@ -0,0 +1,337 @@
+package gssapi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync"
+
+	"github.com/jcmturner/gokrb5/v8/crypto"
+	"github.com/jcmturner/gokrb5/v8/iana/keyusage"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// RFC 4121, section 4.2
+
+// seqWindow is the number of sequence numbers before the highest received that are tracked for replay detection.
+const seqWindow = 64
+
+// SecContext is an established Kerberos 5 security context providing the per-message protection of RFC 4121: Wrap
+// and Unwrap to protect messages with integrity and optionally confidentiality, and GetMIC and VerifyMIC to protect
+// their integrity with a separate token. It is safe for concurrent use.
+//
+// Messages are protected with the subkey of the initiator's authenticator, or the session key of the ticket if it has
+// none, until a subkey asserted by the acceptor in the AP_REP of a mutual exchange is set with SetAcceptorSubkey.
+type SecContext struct {
+	acceptor       bool
+	flags          int
+	mux            sync.Mutex
+	key            types.EncryptionKey
+	acceptorSubkey bool
+	sendSeq        uint64
+	recvSeq        uint64 // next sequence number expected
+	recvSeen       uint64 // bitmap of the sequence numbers received before recvSeq
+	recvAny        bool
+}
+
+// NewInitiatorSecContext returns the security context of the initiator of an AP exchange, protecting messages with
+// the key and initial sequence number of its authenticator. The context flags determine whether replayed
+// (ContextFlagReplay) and out of sequence (ContextFlagSequence) tokens are detected.
+func NewInitiatorSecContext(key types.EncryptionKey, seqNumber int64, flags int) *SecContext {
+	return newSecContext(false, key, seqNumber, flags)
+}
+
+// NewAcceptorSecContext returns the security context of the acceptor of an AP exchange, protecting messages with the
+// key and initial sequence number of the initiator's authenticator. The context flags are as for
+// NewInitiatorSecContext.
+func NewAcceptorSecContext(key types.EncryptionKey, seqNumber int64, flags int) *SecContext {
+	return newSecContext(true, key, seqNumber, flags)
+}
+
+func newSecContext(acceptor bool, key types.EncryptionKey, seqNumber int64, flags int) *SecContext {
+	// Sequence numbers are 32 bits in the authenticator and 64 bits in tokens
+	seq := uint64(uint32(seqNumber))
+	return &SecContext{
+		acceptor: acceptor,
+		flags:    flags,
+		key:      key,
+		sendSeq:  seq,
+		recvSeq:  seq,
+	}
+}
+
+// SetAcceptorSubkey protects subsequent messages with the subkey asserted by the acceptor in the AP_REP of a mutual
+// exchange. The sequence numbers of the acceptor's tokens start from the sequence number of the AP_REP.
+func (c *SecContext) SetAcceptorSubkey(key types.EncryptionKey, seqNumber int64) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.key = key
+	c.acceptorSubkey = true
+	seq := uint64(uint32(seqNumber))
+	if c.acceptor {
+		c.sendSeq = seq
+	} else {
+		c.recvSeq, c.recvSeen, c.recvAny = seq, 0, false
+	}
+}
+
+// DeriveKey returns a key derived from the context's current key, the acceptor's subkey once it has been set, for the
+// application key usage number provided, so that the initiator and acceptor derive the same keys for protocols that
+// need additional keys. See crypto.DeriveKey.
+func (c *SecContext) DeriveKey(usage uint32) (types.EncryptionKey, error) {
+	c.mux.Lock()
+	key := c.key
+	c.mux.Unlock()
+	return crypto.DeriveKey(key, usage)
+}
+
+// Wrap protects the message, encrypting it if conf is true, and returns the bytes of the Wrap token to send to the
+// peer.
+func (c *SecContext) Wrap(msg []byte, conf bool) ([]byte, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	et, err := crypto.GetEtype(c.key.KeyType)
+	if err != nil {
+		return nil, Status{Code: StatusFailure, Message: err.Error()}
+	}
+	flags := c.sendFlags()
+	usage := c.usage(true, c.acceptor)
+	seq := c.sendSeq
+	if !conf {
+		wt := WrapToken{
+			Flags:     flags,
+			EC:        uint16(et.GetHMACBitLength() / 8),
+			SndSeqNum: seq,
+			Payload:   append([]byte{}, msg...),
+		}
+		if err := wt.SetCheckSum(c.key, usage); err != nil {
+			return nil, Status{Code: StatusFailure, Message: err.Error()}
+		}
+		b, err := wt.Marshal()
+		if err != nil {
+			return nil, Status{Code: StatusFailure, Message: err.Error()}
+		}
+		c.sendSeq++
+		return b, nil
+	}
+	// Pad the plaintext to the etype's message block size
+	bs := et.GetMessageBlockByteSize()
+	ec := 0
+	if bs > 1 {
+		ec = (bs - (len(msg)+HdrLen)%bs) % bs
+	}
+	hdr := wrapHeader(flags|MICTokenFlagSealed, uint16(ec), 0, seq)
+	pt := make([]byte, 0, len(msg)+ec+HdrLen)
+	pt = append(pt, msg...)
+	pt = append(pt, bytes.Repeat([]byte{FillerByte}, ec)...)
+	pt = append(pt, hdr...)
+	_, ct, err := et.EncryptMessage(c.key.KeyValue, pt, usage)
+	if err != nil {
+		return nil, Status{Code: StatusFailure, Message: err.Error()}
+	}
+	c.sendSeq++
+	return append(hdr, ct...), nil
+}
+
+// Unwrap verifies the Wrap token received from the peer and returns the message it protects and whether it was
+// encrypted.
+//
+// Tokens that fail verification or are replayed are rejected with a Status error. If the context detects out of
+// sequence tokens, the message of a token received early or late is returned with a Status error of StatusGapToken
+// or StatusUnseqToken.
+func (c *SecContext) Unwrap(b []byte) ([]byte, bool, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if len(b) < HdrLen || !bytes.Equal(b[0:2], getGssWrapTokenId()[:]) || b[3] != FillerByte {
+		return nil, false, Status{Code: StatusDefectiveToken, Message: "not a wrap token"}
+	}
+	flags := b[2]
+	if err := c.checkRecvFlags(flags); err != nil {
+		return nil, false, err
+	}
+	ec := binary.BigEndian.Uint16(b[4:6])
+	rrc := binary.BigEndian.Uint16(b[6:8])
+	seq := binary.BigEndian.Uint64(b[8:16])
+	usage := c.usage(true, !c.acceptor)
+	body := rotateLeft(b[HdrLen:], int(rrc))
+	conf := flags&MICTokenFlagSealed != 0
+	var msg []byte
+	if conf {
+		et, err := crypto.GetEtype(c.key.KeyType)
+		if err != nil {
+			return nil, false, Status{Code: StatusFailure, Message: err.Error()}
+		}
+		pt, err := et.DecryptMessage(c.key.KeyValue, body, usage)
+		if err != nil {
+			return nil, false, Status{Code: StatusBadSig, Message: err.Error()}
+		}
+		if len(pt) < int(ec)+HdrLen {
+			return nil, false, Status{Code: StatusDefectiveToken, Message: "decrypted wrap token too short"}
+		}
+		// The encrypted copy of the header must match that received, with a right rotation count of zero
+		if !bytes.Equal(pt[len(pt)-HdrLen:], wrapHeader(flags, ec, 0, seq)) {
+			return nil, false, Status{Code: StatusBadSig, Message: "wrap token header does not match its encrypted copy"}
+		}
+		msg = pt[:len(pt)-HdrLen-int(ec)]
+	} else {
+		var wt WrapToken
+		if err := wt.Unmarshal(append(append([]byte(nil), b[:HdrLen]...), body...), !c.acceptor); err != nil {
+			return nil, false, Status{Code: StatusDefectiveToken, Message: err.Error()}
+		}
+		if ok, err := wt.Verify(c.key, usage); !ok {
+			return nil, false, Status{Code: StatusBadSig, Message: err.Error()}
+		}
+		msg = wt.Payload
+	}
+	ok, err := c.checkSeq(seq)
+	if !ok {
+		return nil, false, err
+	}
+	return msg, conf, err
+}
+
+// GetMIC returns the bytes of a MIC token protecting the integrity of the message, to send to the peer with it.
+func (c *SecContext) GetMIC(msg []byte) ([]byte, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	mt := MICToken{
+		Flags:     c.sendFlags(),
+		SndSeqNum: c.sendSeq,
+		Payload:   append([]byte{}, msg...),
+	}
+	if err := mt.SetChecksum(c.key, c.usage(false, c.acceptor)); err != nil {
+		return nil, Status{Code: StatusFailure, Message: err.Error()}
+	}
+	b, err := mt.Marshal()
+	if err != nil {
+		return nil, Status{Code: StatusFailure, Message: err.Error()}
+	}
+	c.sendSeq++
+	return b, nil
+}
+
+// VerifyMIC verifies the MIC token received from the peer for the message. Tokens are rejected and reported as for
+// Unwrap.
+func (c *SecContext) VerifyMIC(msg, token []byte) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	var mt MICToken
+	if err := mt.Unmarshal(token, !c.acceptor); err != nil {
+		return Status{Code: StatusDefectiveToken, Message: err.Error()}
+	}
+	if err := c.checkRecvFlags(mt.Flags); err != nil {
+		return err
+	}
+	mt.Payload = append([]byte{}, msg...)
+	if ok, err := mt.Verify(c.key, c.usage(false, !c.acceptor)); !ok {
+		return Status{Code: StatusBadMIC, Message: err.Error()}
+	}
+	_, err := c.checkSeq(mt.SndSeqNum)
+	return err
+}
+
+// sendFlags returns the token flags of the tokens the context sends.
+func (c *SecContext) sendFlags() byte {
+	var f byte
+	if c.acceptor {
+		f |= MICTokenFlagSentByAcceptor
+	}
+	if c.acceptorSubkey {
+		f |= MICTokenFlagAcceptorSubkey
+	}
+	return f
+}
+
+// checkRecvFlags checks the token flags of a token received are those of the peer.
+func (c *SecContext) checkRecvFlags(f byte) error {
+	if (f&MICTokenFlagSentByAcceptor != 0) == c.acceptor {
+		return Status{Code: StatusDefectiveToken, Message: "token not sent by the peer"}
+	}
+	if (f&MICTokenFlagAcceptorSubkey != 0) != c.acceptorSubkey {
+		return Status{Code: StatusDefectiveToken, Message: "token not protected with the context's key"}
+	}
+	return nil
+}
+
+// usage returns the key usage of wrap (seal) or MIC (sign) tokens sent by the acceptor or initiator.
+func (c *SecContext) usage(wrap, acceptor bool) uint32 {
+	switch {
+	case wrap && acceptor:
+		return keyusage.GSSAPI_ACCEPTOR_SEAL
+	case wrap:
+		return keyusage.GSSAPI_INITIATOR_SEAL
+	case acceptor:
+		return keyusage.GSSAPI_ACCEPTOR_SIGN
+	default:
+		return keyusage.GSSAPI_INITIATOR_SIGN
+	}
+}
+
+// checkSeq records the sequence number of a verified token received. The boolean is false if the token is rejected
+// as replayed or too old. The Status error reports tokens rejected and, when sequence detection is requested, tokens
+// accepted out of sequence.
+func (c *SecContext) checkSeq(seq uint64) (bool, error) {
+	if c.flags&(ContextFlagReplay|ContextFlagSequence) == 0 {
+		return true, nil
+	}
+	sequence := c.flags&ContextFlagSequence != 0
+	switch {
+	case seq == c.recvSeq:
+		c.advance(seq)
+		return true, nil
+	case seq > c.recvSeq:
+		c.advance(seq)
+		if sequence {
+			return true, Status{Code: StatusGapToken}
+		}
+		return true, nil
+	}
+	d := c.recvSeq - seq
+	if !c.recvAny || d > seqWindow {
+		return false, Status{Code: StatusOldToken}
+	}
+	if c.recvSeen&(1<<(d-1)) != 0 {
+		return false, Status{Code: StatusDuplicateToken}
+	}
+	c.recvSeen |= 1 << (d - 1)
+	if sequence {
+		return true, Status{Code: StatusUnseqToken}
+	}
+	return true, nil
+}
+
+// advance moves the next sequence number expected beyond that received, recording it as seen.
+func (c *SecContext) advance(seq uint64) {
+	shift := seq - c.recvSeq + 1
+	if shift >= seqWindow {
+		c.recvSeen = 0
+	} else {
+		c.recvSeen <<= shift
+	}
+	c.recvSeen |= 1
+	c.recvSeq = seq + 1
+	c.recvAny = true
+}
+
+// wrapHeader returns the header of a wrap token.
+func wrapHeader(flags byte, ec, rrc uint16, seq uint64) []byte {
+	h := make([]byte, HdrLen)
+	copy(h, getGssWrapTokenId()[:])
+	h[2] = flags
+	h[3] = FillerByte
+	binary.BigEndian.PutUint16(h[4:6], ec)
+	binary.BigEndian.PutUint16(h[6:8], rrc)
+	binary.BigEndian.PutUint64(h[8:16], seq)
+	return h
+}
+
+// rotateLeft reverses the right rotation of a token's body by the rotation count. RFC 4121 section 4.2.5
+func rotateLeft(b []byte, rrc int) []byte {
+	r := make([]byte, len(b))
+	if len(b) == 0 {
+		return r
+	}
+	rrc %= len(b)
+	copy(r, b[rrc:])
+	copy(r[len(b)-rrc:], b[:rrc])
+	return r
+}
@@ -0,0 +1,214 @@
+package gssapi
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
+	"github.com/jcmturner/gokrb5/v8/iana/keyusage"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSecContexts(flags int) (*SecContext, *SecContext) {
+	key := getSessionKey()
+	return NewInitiatorSecContext(key, 1234, flags), NewAcceptorSecContext(key, 1234, flags)
+}
+
+func assertStatus(t *testing.T, code int, err error, msg string) {
+	s, ok := err.(Status)
+	if assert.True(t, ok, "%s: error not a Status: %v", msg, err) {
+		assert.Equal(t, code, s.Code, msg)
+	}
+}
+
+func TestSecContext_Wrap(t *testing.T) {
+	t.Parallel()
+	for _, conf := range []bool{false, true} {
+		i, a := testSecContexts(ContextFlagReplay | ContextFlagSequence)
+		for _, msg := range [][]byte{[]byte("hello acceptor"), {}, make([]byte, 100)} {
+			b, err := i.Wrap(msg, conf)
+			require.NoError(t, err, "error wrapping message")
+			m, c, err := a.Unwrap(b)
+			require.NoError(t, err, "error unwrapping message")
+			assert.Equal(t, msg, m, "unwrapped message not as expected")
+			assert.Equal(t, conf, c, "confidentiality not as expected")
+			if conf {
+				assert.NotContains(t, string(b), "hello", "sealed token contains the plaintext")
+			}
+
+			b, err = a.Wrap(msg, conf)
+			require.NoError(t, err, "error wrapping reply")
+			m, _, err = i.Unwrap(b)
+			require.NoError(t, err, "error unwrapping reply")
+			assert.Equal(t, msg, m, "unwrapped reply not as expected")
+		}
+	}
+}
+
+func TestSecContext_Unwrap_Rejected(t *testing.T) {
+	t.Parallel()
+	for _, conf := range []bool{false, true} {
+		i, a := testSecContexts(0)
+		b, err := i.Wrap([]byte("hello acceptor"), conf)
+		require.NoError(t, err, "error wrapping message")
+
+		_, _, err = i.Unwrap(b)
+		assertStatus(t, StatusDefectiveToken, err, "token unwrapped by its sender")
+
+		tampered := append([]byte(nil), b...)
+		tampered[HdrLen] ^= 0xff
+		_, _, err = a.Unwrap(tampered)
+		assertStatus(t, StatusBadSig, err, "tampered token")
+
+		_, _, err = a.Unwrap(b[:HdrLen-1])
+		assertStatus(t, StatusDefectiveToken, err, "truncated token")
+
+		other := NewAcceptorSecContext(types.EncryptionKey{KeyType: etypeID.AES256_CTS_HMAC_SHA1_96, KeyValue: make([]byte, 32)}, 1234, 0)
+		_, _, err = other.Unwrap(b)
+		assertStatus(t, StatusBadSig, err, "token unwrapped with another key")
+	}
+}
+
+func TestSecContext_Unwrap_Rotated(t *testing.T) {
+	t.Parallel()
+	for _, conf := range []bool{false, true} {
+		i, a := testSecContexts(0)
+		msg := []byte("hello acceptor")
+		b, err := i.Wrap(msg, conf)
+		require.NoError(t, err, "error wrapping message")
+		// Rotate the body right by the checksum length, as some peers do
+		body := b[HdrLen:]
+		rrc := 12
+		rotated := append(append(append([]byte(nil), b[:HdrLen]...), body[len(body)-rrc:]...), body[:len(body)-rrc]...)
+		binary.BigEndian.PutUint16(rotated[6:8], uint16(rrc))
+		m, _, err := a.Unwrap(rotated)
+		require.NoError(t, err, "error unwrapping rotated token")
+		assert.Equal(t, msg, m, "unwrapped message not as expected")
+	}
+}
+
+func TestSecContext_MIC(t *testing.T) {
+	t.Parallel()
+	i, a := testSecContexts(ContextFlagReplay)
+	msg := []byte("hello acceptor")
+	b, err := i.GetMIC(msg)
+	require.NoError(t, err, "error getting MIC")
+	assert.NoError(t, a.VerifyMIC(msg, b), "MIC should verify")
+	assertStatus(t, StatusBadMIC, a.VerifyMIC([]byte("hello attacker"), b), "MIC of another message")
+
+	b, err = a.GetMIC(msg)
+	require.NoError(t, err, "error getting MIC")
+	assert.NoError(t, i.VerifyMIC(msg, b), "acceptor's MIC should verify")
+	assertStatus(t, StatusDefectiveToken, a.VerifyMIC(msg, b), "MIC verified by its sender")
+}
+
+func TestSecContext_Replay(t *testing.T) {
+	t.Parallel()
+	i, a := testSecContexts(ContextFlagReplay)
+	b1, err := i.Wrap([]byte("one"), false)
+	require.NoError(t, err, "error wrapping message")
+	b2, err := i.Wrap([]byte("two"), false)
+	require.NoError(t, err, "error wrapping message")
+
+	_, _, err = a.Unwrap(b2)
+	assert.NoError(t, err, "out of sequence token should be accepted without sequence detection")
+	m, _, err := a.Unwrap(b1)
+	assert.NoError(t, err, "late token should be accepted without sequence detection")
+	assert.Equal(t, []byte("one"), m)
+	m, _, err = a.Unwrap(b1)
+	assertStatus(t, StatusDuplicateToken, err, "replayed token")
+	assert.Nil(t, m, "message of replayed token returned")
+
+	// Tokens beyond the replay window are reported as old
+	for n := 0; n < seqWindow+1; n++ {
+		b, err := i.Wrap([]byte("more"), false)
+		require.NoError(t, err, "error wrapping message")
+		_, _, err = a.Unwrap(b)
+		require.NoError(t, err, "error unwrapping message")
+	}
+	_, _, err = a.Unwrap(b2)
+	assertStatus(t, StatusOldToken, err, "token beyond the replay window")
+
+	// Without replay detection tokens may be replayed
+	i, a = testSecContexts(0)
+	b1, err = i.Wrap([]byte("one"), false)
+	require.NoError(t, err, "error wrapping message")
+	for n := 0; n < 2; n++ {
+		_, _, err = a.Unwrap(b1)
+		assert.NoError(t, err, "token should be accepted without replay detection")
+	}
+}
+
+func TestSecContext_Sequence(t *testing.T) {
+	t.Parallel()
+	i, a := testSecContexts(ContextFlagSequence)
+	b1, err := i.Wrap([]byte("one"), false)
+	require.NoError(t, err, "error wrapping message")
+	b2, err := i.Wrap([]byte("two"), false)
+	require.NoError(t, err, "error wrapping message")
+
+	m, _, err := a.Unwrap(b2)
+	assertStatus(t, StatusGapToken, err, "token received early")
+	assert.Equal(t, []byte("two"), m, "message of early token should be returned")
+	m, _, err = a.Unwrap(b1)
+	assertStatus(t, StatusUnseqToken, err, "token received late")
+	assert.Equal(t, []byte("one"), m, "message of late token should be returned")
+	_, _, err = a.Unwrap(b1)
+	assertStatus(t, StatusDuplicateToken, err, "replayed token")
+}
+
+func TestSecContext_SetAcceptorSubkey(t *testing.T) {
+	t.Parallel()
+	i, a := testSecContexts(ContextFlagReplay | ContextFlagSequence)
+	subkey := types.EncryptionKey{KeyType: etypeID.AES256_CTS_HMAC_SHA1_96, KeyValue: make([]byte, 32)}
+	subkey.KeyValue[0] = 1
+	i.SetAcceptorSubkey(subkey, 5678)
+	a.SetAcceptorSubkey(subkey, 5678)
+
+	seq := uint64(5678)
+	for _, conf := range []bool{false, true} {
+		b, err := a.Wrap([]byte("hello initiator"), conf)
+		require.NoError(t, err, "error wrapping message")
+		assert.NotZero(t, b[2]&MICTokenFlagAcceptorSubkey, "acceptor subkey flag not set")
+		assert.Equal(t, seq, binary.BigEndian.Uint64(b[8:16]), "acceptor sequence number not as expected")
+		seq++
+		m, _, err := i.Unwrap(b)
+		require.NoError(t, err, "error unwrapping message")
+		assert.Equal(t, []byte("hello initiator"), m)
+
+		b, err = i.Wrap([]byte("hello acceptor"), conf)
+		require.NoError(t, err, "error wrapping message")
+		m, _, err = a.Unwrap(b)
+		require.NoError(t, err, "error unwrapping message")
+		assert.Equal(t, []byte("hello acceptor"), m)
+	}
+
+	// Tokens protected with the session key are no longer accepted
+	ni, _ := testSecContexts(0)
+	b, err := ni.Wrap([]byte("hello acceptor"), false)
+	require.NoError(t, err, "error wrapping message")
+	_, _, err = a.Unwrap(b)
+	assertStatus(t, StatusDefectiveToken, err, "token protected with the session key")
+}
+
+func TestSecContext_DeriveKey(t *testing.T) {
+	t.Parallel()
+	i, a := testSecContexts(0)
+	ik, err := i.DeriveKey(1024)
+	require.NoError(t, err, "error deriving initiator key")
+	ak, err := a.DeriveKey(1024)
+	require.NoError(t, err, "error deriving acceptor key")
+	assert.Equal(t, ik, ak, "initiator and acceptor should derive the same key")
+	assert.NotEqual(t, getSessionKey().KeyValue, ik.KeyValue, "derived key should differ from the context key")
+
+	subkey := types.EncryptionKey{KeyType: etypeID.AES256_CTS_HMAC_SHA1_96, KeyValue: make([]byte, 32)}
+	i.SetAcceptorSubkey(subkey, 5678)
+	sk, err := i.DeriveKey(1024)
+	require.NoError(t, err, "error deriving key from the acceptor subkey")
+	assert.NotEqual(t, ik, sk, "key should be derived from the acceptor subkey once set")
+
+	_, err = i.DeriveKey(keyusage.GSSAPI_ACCEPTOR_SEAL)
+	assert.Error(t, err, "usages reserved for Kerberos should be rejected")
+}
@@ -6,18 +6,19 @@ import (
 	"testing"
 
 	"github.com/jcmturner/gokrb5/v8/iana/keyusage"
+	"github.com/jcmturner/gokrb5/v8/test/testdata"
 	"github.com/jcmturner/gokrb5/v8/types"
 	"github.com/stretchr/testify/assert"
 )
 
 const (
 	// What a kerberized server might send
-	testChallengeFromAcceptor = "050401ff000c000000000000575e85d601010000853b728d5268525a1386c19f"
+	testChallengeFromAcceptor = testdata.GSSAPI_WRAP_TOKEN_ACCEPTOR
 	// What an initiator client could reply
-	testChallengeReplyFromInitiator = "050400ff000c000000000000000000000101000079a033510b6f127212242b97"
+	testChallengeReplyFromInitiator = testdata.GSSAPI_WRAP_TOKEN_INITIATOR
 	// session key used to sign the tokens above
-	sessionKey     = "14f9bde6b50ec508201a97f74c4e5bd3"
-	sessionKeyType = 17
+	sessionKey     = testdata.GSSAPI_SESSION_KEY
+	sessionKeyType = testdata.GSSAPI_SESSION_KEY_TYPE
 
 	acceptorSeal  = keyusage.GSSAPI_ACCEPTOR_SEAL
 	initiatorSeal = keyusage.GSSAPI_INITIATOR_SEAL
@@ -13,3 +13,22 @@ const (
 	NetBios       int32 = 20
 	IPv6          int32 = 24
 )
+
+// names maps the address types to their names.
+var names = map[int32]string{
+	IPv4:          "IPv4",
+	Directional:   "Directional",
+	ChaosNet:      "ChaosNet",
+	XNS:           "XNS",
+	ISO:           "ISO",
+	DECNETPhaseIV: "DECnet Phase IV",
+	AppleTalkDDP:  "AppleTalk DDP",
+	NetBios:       "NetBIOS",
+	IPv6:          "IPv6",
+}
+
+// Name returns the name of the address type.
+// An empty string is returned if the type is not assigned.
+func Name(t int32) string {
+	return names[t]
+}
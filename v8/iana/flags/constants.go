@@ -3,34 +3,89 @@ package flags
 
 // Flag values for KRB5 messages and tickets.
 const (
-	Reserved               = 0
-	Forwardable            = 1
-	Forwarded              = 2
-	Proxiable              = 3
-	Proxy                  = 4
-	AllowPostDate          = 5
-	MayPostDate            = 5
-	PostDated              = 6
-	Invalid                = 7
-	Renewable              = 8
-	Initial                = 9
-	PreAuthent             = 10
-	HWAuthent              = 11
-	OptHardwareAuth        = 11
-	RequestAnonymous       = 12
-	TransitedPolicyChecked = 12
-	OKAsDelegate           = 13
-	EncPARep               = 15
-	Canonicalize           = 15
-	DisableTransitedCheck  = 26
-	RenewableOK            = 27
-	EncTktInSkey           = 28
-	Renew                  = 30
-	Validate               = 31
+	Reserved                = 0
+	Forwardable             = 1
+	Forwarded               = 2
+	Proxiable               = 3
+	Proxy                   = 4
+	AllowPostDate           = 5
+	MayPostDate             = 5
+	PostDated               = 6
+	Invalid                 = 7
+	Renewable               = 8
+	Initial                 = 9
+	PreAuthent              = 10
+	HWAuthent               = 11
+	OptHardwareAuth         = 11
+	RequestAnonymous        = 12
+	TransitedPolicyChecked  = 12
+	OKAsDelegate            = 13
+	Anonymous               = 14
+	CNameInAdditionalTicket = 14 // KDC option of S4U2Proxy requests [MS-SFU]
+	EncPARep                = 15
+	Canonicalize            = 15
+	DisableTransitedCheck   = 26
+	RenewableOK             = 27
+	EncTktInSkey            = 28
+	Renew                   = 30
+	Validate                = 31
 
 	// AP Option Flags
 	// 0 Reserved for future use.
 	APOptionUseSessionKey  = 1
 	APOptionMutualRequired = 2
 	// 3-31 Reserved for future use.
+
+	// PA-PAC-OPTIONS Flags [MS-KILE]
+	PACOptionClaims                             = 0
+	PACOptionBranchAware                        = 1
+	PACOptionForwardToFullDC                    = 2
+	PACOptionResourceBasedConstrainedDelegation = 3
 )
+
+// TicketFlagNames maps the ticket flag bit positions to their names.
+var TicketFlagNames = map[int]string{
+	Reserved:               "reserved",
+	Forwardable:            "forwardable",
+	Forwarded:              "forwarded",
+	Proxiable:              "proxiable",
+	Proxy:                  "proxy",
+	MayPostDate:            "may-postdate",
+	PostDated:              "postdated",
+	Invalid:                "invalid",
+	Renewable:              "renewable",
+	Initial:                "initial",
+	PreAuthent:             "pre-authent",
+	HWAuthent:              "hw-authent",
+	TransitedPolicyChecked: "transited-policy-checked",
+	OKAsDelegate:           "ok-as-delegate",
+	Anonymous:              "anonymous",
+	EncPARep:               "enc-pa-rep",
+}
+
+// KDCOptionNames maps the KDC option bit positions to their names.
+var KDCOptionNames = map[int]string{
+	Reserved:              "reserved",
+	Forwardable:           "forwardable",
+	Forwarded:             "forwarded",
+	Proxiable:             "proxiable",
+	Proxy:                 "proxy",
+	AllowPostDate:         "allow-postdate",
+	PostDated:             "postdated",
+	Renewable:             "renewable",
+	OptHardwareAuth:       "opt-hardware-auth",
+	RequestAnonymous:      "request-anonymous",
+	Canonicalize:          "canonicalize",
+	DisableTransitedCheck: "disable-transited-check",
+	RenewableOK:           "renewable-ok",
+	EncTktInSkey:          "enc-tkt-in-skey",
+	Renew:                 "renew",
+	Validate:              "validate",
+}
+
+// APOptionNames maps the AP option bit positions to their names.
+var APOptionNames = map[int]string{
+	Reserved:               "reserved",
+	APOptionUseSessionKey:  "use-session-key",
+	APOptionMutualRequired: "mutual-required",
+}
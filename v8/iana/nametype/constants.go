@@ -12,4 +12,25 @@ const (
 	KRB_NT_X500_PRINCIPAL int32 = 6  //Encoded X.509 Distinguished name [RFC2253]
 	KRB_NT_SMTP_NAME      int32 = 7  //Name in form of SMTP email name (e.g., user@example.com)
 	KRB_NT_ENTERPRISE     int32 = 10 //Enterprise name; may be mapped to principal name
+	KRB_NT_WELLKNOWN      int32 = 11 //Well-known principal name, such as the anonymous principal [RFC6111]
 )
+
+// names maps name type IDs to their names.
+var names = map[int32]string{
+	KRB_NT_UNKNOWN:        "KRB_NT_UNKNOWN",
+	KRB_NT_PRINCIPAL:      "KRB_NT_PRINCIPAL",
+	KRB_NT_SRV_INST:       "KRB_NT_SRV_INST",
+	KRB_NT_SRV_HST:        "KRB_NT_SRV_HST",
+	KRB_NT_SRV_XHST:       "KRB_NT_SRV_XHST",
+	KRB_NT_UID:            "KRB_NT_UID",
+	KRB_NT_X500_PRINCIPAL: "KRB_NT_X500_PRINCIPAL",
+	KRB_NT_SMTP_NAME:      "KRB_NT_SMTP_NAME",
+	KRB_NT_ENTERPRISE:     "KRB_NT_ENTERPRISE",
+	KRB_NT_WELLKNOWN:      "KRB_NT_WELLKNOWN",
+}
+
+// Name returns the name of the name type ID.
+// An empty string is returned if the ID is not assigned.
+func Name(t int32) string {
+	return names[t]
+}
@@ -74,4 +74,77 @@ const (
 	//UNASSIGNED : 151-164
 	PA_SUPPORTED_ETYPES int32 = 165
 	PA_EXTENDED_ERROR   int32 = 166
+	PA_PAC_OPTIONS      int32 = 167
 )
+
+// names maps pre-authentication type numbers to their names.
+var names = map[int32]string{
+	PA_TGS_REQ:                 "PA-TGS-REQ",
+	PA_ENC_TIMESTAMP:           "PA-ENC-TIMESTAMP",
+	PA_PW_SALT:                 "PA-PW-SALT",
+	PA_ENC_UNIX_TIME:           "PA-ENC-UNIX-TIME",
+	PA_SANDIA_SECUREID:         "PA-SANDIA-SECUREID",
+	PA_SESAME:                  "PA-SESAME",
+	PA_OSF_DCE:                 "PA-OSF-DCE",
+	PA_CYBERSAFE_SECUREID:      "PA-CYBERSAFE-SECUREID",
+	PA_AFS3_SALT:               "PA-AFS3-SALT",
+	PA_ETYPE_INFO:              "PA-ETYPE-INFO",
+	PA_SAM_CHALLENGE:           "PA-SAM-CHALLENGE",
+	PA_SAM_RESPONSE:            "PA-SAM-RESPONSE",
+	PA_PK_AS_REQ_OLD:           "PA-PK-AS-REQ-OLD",
+	PA_PK_AS_REP_OLD:           "PA-PK-AS-REP-OLD",
+	PA_PK_AS_REQ:               "PA-PK-AS-REQ",
+	PA_PK_AS_REP:               "PA-PK-AS-REP",
+	PA_PK_OCSP_RESPONSE:        "PA-PK-OCSP-RESPONSE",
+	PA_ETYPE_INFO2:             "PA-ETYPE-INFO2",
+	PA_USE_SPECIFIED_KVNO:      "PA-USE-SPECIFIED-KVNO",
+	PA_SAM_REDIRECT:            "PA-SAM-REDIRECT",
+	PA_GET_FROM_TYPED_DATA:     "PA-GET-FROM-TYPED-DATA",
+	PA_SAM_ETYPE_INFO:          "PA-SAM-ETYPE-INFO",
+	PA_ALT_PRINC:               "PA-ALT-PRINC",
+	PA_SERVER_REFERRAL:         "PA-SERVER-REFERRAL",
+	PA_SAM_CHALLENGE2:          "PA-SAM-CHALLENGE2",
+	PA_SAM_RESPONSE2:           "PA-SAM-RESPONSE2",
+	PA_EXTRA_TGT:               "PA-EXTRA-TGT",
+	TD_PKINIT_CMS_CERTIFICATES: "TD-PKINIT-CMS-CERTIFICATES",
+	TD_KRB_PRINCIPAL:           "TD-KRB-PRINCIPAL",
+	TD_KRB_REALM:               "TD-KRB-REALM",
+	TD_TRUSTED_CERTIFIERS:      "TD-TRUSTED-CERTIFIERS",
+	TD_CERTIFICATE_INDEX:       "TD-CERTIFICATE-INDEX",
+	TD_APP_DEFINED_ERROR:       "TD-APP-DEFINED-ERROR",
+	TD_REQ_NONCE:               "TD-REQ-NONCE",
+	TD_REQ_SEQ:                 "TD-REQ-SEQ",
+	TD_DH_PARAMETERS:           "TD-DH-PARAMETERS",
+	TD_CMS_DIGEST_ALGORITHMS:   "TD-CMS-DIGEST-ALGORITHMS",
+	TD_CERT_DIGEST_ALGORITHMS:  "TD-CERT-DIGEST-ALGORITHMS",
+	PA_PAC_REQUEST:             "PA-PAC-REQUEST",
+	PA_FOR_USER:                "PA-FOR-USER",
+	PA_FOR_X509_USER:           "PA-FOR-X509-USER",
+	PA_FOR_CHECK_DUPS:          "PA-FOR-CHECK-DUPS",
+	PA_AS_CHECKSUM:             "PA-AS-CHECKSUM",
+	PA_FX_COOKIE:               "PA-FX-COOKIE",
+	PA_AUTHENTICATION_SET:      "PA-AUTHENTICATION-SET",
+	PA_AUTH_SET_SELECTED:       "PA-AUTH-SET-SELECTED",
+	PA_FX_FAST:                 "PA-FX-FAST",
+	PA_FX_ERROR:                "PA-FX-ERROR",
+	PA_ENCRYPTED_CHALLENGE:     "PA-ENCRYPTED-CHALLENGE",
+	PA_OTP_CHALLENGE:           "PA-OTP-CHALLENGE",
+	PA_OTP_REQUEST:             "PA-OTP-REQUEST",
+	PA_OTP_CONFIRM:             "PA-OTP-CONFIRM",
+	PA_OTP_PIN_CHANGE:          "PA-OTP-PIN-CHANGE",
+	PA_EPAK_AS_REQ:             "PA-EPAK-AS-REQ",
+	PA_EPAK_AS_REP:             "PA-EPAK-AS-REP",
+	PA_PKINIT_KX:               "PA-PKINIT-KX",
+	PA_PKU2U_NAME:              "PA-PKU2U-NAME",
+	PA_REQ_ENC_PA_REP:          "PA-REQ-ENC-PA-REP",
+	PA_AS_FRESHNESS:            "PA-AS-FRESHNESS",
+	PA_SUPPORTED_ETYPES:        "PA-SUPPORTED-ETYPES",
+	PA_EXTENDED_ERROR:          "PA-EXTENDED-ERROR",
+	PA_PAC_OPTIONS:             "PA-PAC-OPTIONS",
+}
+
+// Name returns the name of the pre-authentication type.
+// An empty string is returned if the type is not assigned.
+func Name(t int32) string {
+	return names[t]
+}
@@ -1,7 +1,7 @@
 package kadmin
 
 import (
-	"github.com/jcmturner/gofork/encoding/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1"
 	"github.com/jcmturner/gokrb5/v8/types"
 )
 
@@ -21,3 +21,9 @@ func (c *ChangePasswdData) Marshal() ([]byte, error) {
 	//b = asn1tools.AddASNAppTag(b, asnAppTag.)
 	return b, nil
 }
+
+// Unmarshal a byte slice into ChangePasswdData.
+func (c *ChangePasswdData) Unmarshal(b []byte) error {
+	_, err := asn1.Unmarshal(b, c)
+	return err
+}
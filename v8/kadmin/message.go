@@ -15,8 +15,18 @@ const (
 	verisonHex = "ff80"
 )
 
+// Protocol version numbers of kpasswd messages.
+const (
+	// ChangePasswdVersion is the version of requests to change the password of the client. RFC 3244
+	ChangePasswdVersion = 0x0001
+	// SetPasswdVersion is the version of requests to set the password of a target principal. RFC 3244
+	SetPasswdVersion = 0xff80
+)
+
 // Request message for changing password.
 type Request struct {
+	// Version is set when the request is unmarshaled. Requests are marshaled as SetPasswdVersion.
+	Version int
 	APREQ   messages.APReq
 	KRBPriv messages.KRBPriv
 }
@@ -66,6 +76,61 @@ func (m *Request) Marshal() (b []byte, err error) {
 	return
 }
 
+// Unmarshal a byte slice into a Request.
+func (m *Request) Unmarshal(b []byte) error {
+	if len(b) < 6 {
+		return errors.New("kpasswd request is too short")
+	}
+	l := int(binary.BigEndian.Uint16(b[0:2]))
+	if l != len(b) {
+		return fmt.Errorf("kpasswd request length %d does not match the bytes received (%d)", l, len(b))
+	}
+	m.Version = int(binary.BigEndian.Uint16(b[2:4]))
+	if m.Version != ChangePasswdVersion && m.Version != SetPasswdVersion {
+		return fmt.Errorf("kpasswd request has incorrect protocol version number: %d", m.Version)
+	}
+	al := int(binary.BigEndian.Uint16(b[4:6]))
+	if 6+al > l {
+		return errors.New("kpasswd request AP_REQ length exceeds the message length")
+	}
+	if err := m.APREQ.Unmarshal(b[6 : 6+al]); err != nil {
+		return err
+	}
+	return m.KRBPriv.Unmarshal(b[6+al : l])
+}
+
+// Marshal a Reply into a byte slice. The KRBError is marshaled in place of the AP_REP and KRBPriv if IsKRBError is
+// set. The AP_REP and KRBPriv must have been encrypted.
+func (m *Reply) Marshal() ([]byte, error) {
+	var ab, pb []byte
+	var err error
+	if m.IsKRBError {
+		pb, err = m.KRBError.Marshal()
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling KRBError: %v", err)
+		}
+	} else {
+		ab, err = m.APREP.Marshal()
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling AP_REP: %v", err)
+		}
+		pb, err = m.KRBPriv.Marshal()
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling KRB_Priv: %v", err)
+		}
+	}
+	l := 6 + len(ab) + len(pb)
+	if l > math.MaxUint16 {
+		return nil, errors.New("length of message greater then max Uint16 size")
+	}
+	b := make([]byte, 6, l)
+	binary.BigEndian.PutUint16(b[0:2], uint16(l))
+	binary.BigEndian.PutUint16(b[2:4], ChangePasswdVersion)
+	binary.BigEndian.PutUint16(b[4:6], uint16(len(ab)))
+	b = append(b, ab...)
+	return append(b, pb...), nil
+}
+
 // Unmarshal a byte slice into a Reply.
 func (m *Reply) Unmarshal(b []byte) error {
 	m.MessageLength = int(binary.BigEndian.Uint16(b[0:2]))
@@ -33,3 +33,30 @@ func TestUnmarshalReply(t *testing.T) {
 }
 
 // Request marshal is tested via integration test in the client package due to the dynamic keys and encryption.
+
+func TestReply_Marshal(t *testing.T) {
+	t.Parallel()
+	var a Reply
+	b, err := hex.DecodeString(testdata.MarshaledKpasswd_Rep)
+	if err != nil {
+		t.Fatalf("Test vector read error: %v", err)
+	}
+	err = a.Unmarshal(b)
+	if err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	mb, err := a.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal error: %v", err)
+	}
+	assert.Equal(t, b, mb, "marshaled reply not as expected")
+}
+
+func TestRequest_Unmarshal_Invalid(t *testing.T) {
+	t.Parallel()
+	var r Request
+	assert.Error(t, r.Unmarshal([]byte{0, 6, 0, 1}), "truncated request should not unmarshal")
+	assert.Error(t, r.Unmarshal([]byte{0, 6, 0, 2, 0, 0}), "request with an unknown version should not unmarshal")
+	assert.Error(t, r.Unmarshal([]byte{0, 7, 0, 1, 0, 0}), "request with an incorrect length should not unmarshal")
+	assert.Error(t, r.Unmarshal([]byte{0, 6, 0xff, 0x80, 0, 9}), "request with an AP_REQ beyond its end should not unmarshal")
+}
@@ -0,0 +1,75 @@
+package kadmin
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/iana/errorcode"
+	"github.com/jcmturner/gokrb5/v8/krberror"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// Kpasswd server result codes.
+const (
+	KRB5_KPASSWD_SUCCESS             = 0
+	KRB5_KPASSWD_MALFORMED           = 1
+	KRB5_KPASSWD_HARDERROR           = 2
+	KRB5_KPASSWD_AUTHERROR           = 3
+	KRB5_KPASSWD_SOFTERROR           = 4
+	KRB5_KPASSWD_ACCESSDENIED        = 5
+	KRB5_KPASSWD_BAD_VERSION         = 6
+	KRB5_KPASSWD_INITIAL_FLAG_NEEDED = 7
+)
+
+// NewReply returns the reply to a request whose AP_REQ has been verified, with an AP_REP encrypted with the session
+// key of the request's ticket and the result in a KRB_PRIV encrypted with the key provided, which is the subkey of
+// the request's authenticator if it has one.
+func NewReply(req Request, key types.EncryptionKey, code uint16, result string, t time.Time) (Reply, error) {
+	a := req.APREQ.Authenticator
+	aprep := messages.NewAPRep(messages.EncAPRepPart{
+		CTime:          a.CTime,
+		Cusec:          a.Cusec,
+		SequenceNumber: a.SeqNumber,
+	})
+	if err := aprep.EncryptEncPart(req.APREQ.Ticket.DecryptedEncPart.Key); err != nil {
+		return Reply{}, krberror.Errorf(err, krberror.EncryptingError, "error encrypting kpasswd AP_REP")
+	}
+	priv := messages.NewKRBPriv(messages.EncKrbPrivPart{
+		UserData:       resultData(code, result),
+		Timestamp:      t,
+		Usec:           int((t.UnixNano() / int64(time.Microsecond)) - (t.Unix() * 1e6)),
+		SequenceNumber: a.SeqNumber,
+	})
+	if err := priv.EncryptEncPart(key); err != nil {
+		return Reply{}, krberror.Errorf(err, krberror.EncryptingError, "error encrypting kpasswd result")
+	}
+	return Reply{
+		Version:    ChangePasswdVersion,
+		APREP:      aprep,
+		KRBPriv:    priv,
+		ResultCode: code,
+		Result:     result,
+	}, nil
+}
+
+// NewErrorReply returns a reply with a KRB_ERROR carrying the result, for requests that could not be verified or
+// decrypted.
+func NewErrorReply(sname types.PrincipalName, realm string, code uint16, result string) Reply {
+	e := messages.NewKRBError(sname, realm, errorcode.KRB_ERR_GENERIC, result)
+	e.EData = resultData(code, result)
+	return Reply{
+		Version:    ChangePasswdVersion,
+		KRBError:   e,
+		IsKRBError: true,
+		ResultCode: code,
+		Result:     result,
+	}
+}
+
+// resultData returns the result code and string as the user data of a reply.
+func resultData(c uint16, s string) []byte {
+	b := make([]byte, 2, 2+len(s))
+	binary.BigEndian.PutUint16(b, c)
+	return append(b, s...)
+}
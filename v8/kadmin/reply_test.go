@@ -0,0 +1,26 @@
+package kadmin
+
+import (
+	"testing"
+
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewErrorReply(t *testing.T) {
+	t.Parallel()
+	sname := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "kadmin/changepw")
+	r := NewErrorReply(sname, "TEST.GOKRB5", KRB5_KPASSWD_AUTHERROR, "authentication failed")
+	b, err := r.Marshal()
+	require.NoError(t, err, "error marshaling error reply")
+
+	var u Reply
+	require.NoError(t, u.Unmarshal(b), "error unmarshaling error reply")
+	assert.True(t, u.IsKRBError, "reply should be a KRB_ERROR")
+	assert.Equal(t, 0, u.APREPLength)
+	assert.Equal(t, uint16(KRB5_KPASSWD_AUTHERROR), u.ResultCode)
+	assert.Equal(t, "authentication failed", u.Result)
+	assert.Equal(t, len(b), u.MessageLength)
+}
@@ -14,6 +14,7 @@ import (
 	"unsafe"
 
 	"github.com/jcmturner/gokrb5/v8/crypto"
+	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
 	"github.com/jcmturner/gokrb5/v8/types"
 )
 
@@ -54,6 +55,19 @@ type principal struct {
 	NameType      int32
 }
 
+// matches reports if the principal has the name strings of the principal name.
+func (p principal) matches(princName types.PrincipalName) bool {
+	if len(p.Components) != len(princName.NameString) {
+		return false
+	}
+	for i, n := range p.Components {
+		if princName.NameString[i] != n {
+			return false
+		}
+	}
+	return true
+}
+
 func (p principal) String() string {
 	return fmt.Sprintf("%s@%s", strings.Join(p.Components, "/"), p.Realm)
 }
@@ -69,27 +83,19 @@ func New() *Keytab {
 
 // GetEncryptionKey returns the EncryptionKey from the Keytab for the newest entry with the required kvno, etype and matching principal.
 // If the kvno is zero then the latest kvno will be returned. The kvno is also returned for
+// Of the newest entries with the same timestamp that with the highest kvno is returned.
 func (kt *Keytab) GetEncryptionKey(princName types.PrincipalName, realm string, kvno int, etype int32) (types.EncryptionKey, int, error) {
 	var key types.EncryptionKey
 	var t time.Time
 	var kv int
 	for _, k := range kt.Entries {
-		if k.Principal.Realm == realm && len(k.Principal.Components) == len(princName.NameString) &&
+		if k.Principal.Realm == realm && k.Principal.matches(princName) &&
 			k.Key.KeyType == etype &&
 			(k.KVNO == uint32(kvno) || kvno == 0) &&
-			k.Timestamp.After(t) {
-			p := true
-			for i, n := range k.Principal.Components {
-				if princName.NameString[i] != n {
-					p = false
-					break
-				}
-			}
-			if p {
-				key = k.Key
-				kv = int(k.KVNO)
-				t = k.Timestamp
-			}
+			(k.Timestamp.After(t) || k.Timestamp.Equal(t) && int(k.KVNO) > kv) {
+			key = k.Key
+			kv = int(k.KVNO)
+			t = k.Timestamp
 		}
 	}
 	if len(key.KeyValue) < 1 {
@@ -133,6 +139,53 @@ func (kt *Keytab) AddEntry(principalName, realm, password string, ts time.Time,
 		return err
 	}
 
+	kt.AddKey(princ, realm, key, ts, KVNO)
+	return nil
+}
+
+// AddEntryWithSalt adds an entry to the keytab with the key derived from the password using the salt provided rather
+// than the default derived from the principal name. For example to create a keytab for an Active Directory computer
+// account use the salt returned by types.MachineAccountSalt.
+func (kt *Keytab) AddEntryWithSalt(principalName, realm, password, salt string, ts time.Time, KVNO uint8, encType int32) error {
+	princ, _ := types.ParseSPNString(principalName)
+	key, _, err := crypto.GetKeyFromPasswordSalt(password, salt, encType, types.PADataSequence{})
+	if err != nil {
+		return err
+	}
+	kt.AddKey(princ, realm, key, ts, KVNO)
+	return nil
+}
+
+// AddEntryWithKVNO adds an entry to the keytab as AddEntry does, with a key version number that may exceed 255, as
+// those of Active Directory accounts whose password has been changed many times can. The key version is written to
+// the keytab both as the 32-bit extension and truncated to the 8-bit field, as MIT Kerberos does.
+func (kt *Keytab) AddEntryWithKVNO(principalName, realm, password string, ts time.Time, KVNO uint32, encType int32) error {
+	princ, _ := types.ParseSPNString(principalName)
+	key, _, err := crypto.GetKeyFromPassword(password, princ, realm, encType, types.PADataSequence{})
+	if err != nil {
+		return err
+	}
+	kt.AddKeyWithKVNO(princ, realm, key, ts, KVNO)
+	return nil
+}
+
+// AddKey adds an entry with the encryption key provided to the keytab.
+func (kt *Keytab) AddKey(princ types.PrincipalName, realm string, key types.EncryptionKey, ts time.Time, KVNO uint8) {
+	kt.addKey(princ, realm, key, ts, uint32(KVNO))
+}
+
+// AddKeyWithKVNO adds an entry with the encryption key provided to the keytab, with a key version number that may
+// exceed 255. The timestamp is held to the second as in the keytab format and the current time is used if it is
+// zero.
+func (kt *Keytab) AddKeyWithKVNO(princ types.PrincipalName, realm string, key types.EncryptionKey, ts time.Time, KVNO uint32) {
+	if ts.IsZero() {
+		ts = time.Now().UTC()
+	}
+	kt.addKey(princ, realm, key, ts.Truncate(time.Second), KVNO)
+}
+
+// addKey adds an entry with the encryption key, timestamp and key version number provided to the keytab.
+func (kt *Keytab) addKey(princ types.PrincipalName, realm string, key types.EncryptionKey, ts time.Time, KVNO uint32) {
 	// Populate the keytab entry principal
 	ktep := newPrincipal()
 	ktep.NumComponents = int16(len(princ.NameString))
@@ -148,12 +201,136 @@ func (kt *Keytab) AddEntry(principalName, realm, password string, ts time.Time,
 	e := newEntry()
 	e.Principal = ktep
 	e.Timestamp = ts
-	e.KVNO8 = KVNO
-	e.KVNO = uint32(KVNO)
+	e.KVNO8 = uint8(KVNO)
+	e.KVNO = KVNO
 	e.Key = key
 
 	kt.Entries = append(kt.Entries, e)
-	return nil
+}
+
+// RemoveEntries removes the entries of the principal in the realm with the key version number provided, or of all
+// versions if it is zero, and returns the number of entries removed.
+func (kt *Keytab) RemoveEntries(princName types.PrincipalName, realm string, kvno int) int {
+	var n int
+	entries := kt.Entries[:0]
+	for _, e := range kt.Entries {
+		if e.Principal.Realm == realm && e.Principal.matches(princName) && (kvno == 0 || e.KVNO == uint32(kvno)) {
+			n++
+			continue
+		}
+		entries = append(entries, e)
+	}
+	kt.Entries = entries
+	return n
+}
+
+// ErrSaltNotFound is returned when adding etypes to a keytab if none of the salts tried derive the keys the keytab
+// already holds from the password, either as the password is wrong or the principal's keys use another salt.
+var ErrSaltNotFound = errors.New("salt of the keys in the keytab not found, check the password or provide the salt")
+
+// AddEtypes adds entries with keys of the etypes provided, derived from the password, for the latest key version of
+// the principal held in the keytab. This eases migrating to new etypes, such as adding AES-SHA2 keys alongside
+// AES-SHA1 keys, without access to kadmin or Active Directory.
+//
+// The keys are derived with the same salt as the entries already held, found by deriving their keys with the default
+// salt of the principal name and the salt of an Active Directory computer account in turn. ErrSaltNotFound is
+// returned if neither derives them, AddEtypesWithSalt should be used for principals with other salts. Etypes for
+// which the key version already has an entry are skipped and the number of entries added is returned.
+func (kt *Keytab) AddEtypes(princName types.PrincipalName, realm, password string, etypes ...int32) (int, error) {
+	salts := []string{princName.GetSalt(realm)}
+	if n := len(princName.NameString); n > 0 {
+		salts = append(salts, types.MachineAccountSalt(princName.NameString[n-1], realm))
+	}
+	return kt.addEtypes(princName, realm, password, salts, etypes)
+}
+
+// AddEtypesWithSalt adds entries with keys of the etypes provided as AddEtypes does, deriving the keys with the salt
+// provided, which must derive the keys already held from the password.
+func (kt *Keytab) AddEtypesWithSalt(princName types.PrincipalName, realm, password, salt string, etypes ...int32) (int, error) {
+	return kt.addEtypes(princName, realm, password, []string{salt}, etypes)
+}
+
+// addEtypes adds entries with keys of the etypes for the latest key version of the principal, derived from the
+// password with the first of the salts that derives the keys already held.
+func (kt *Keytab) addEtypes(princName types.PrincipalName, realm, password string, salts []string, etypes []int32) (int, error) {
+	var held []entry
+	for _, e := range kt.Entries {
+		if e.Principal.Realm != realm || !e.Principal.matches(princName) {
+			continue
+		}
+		if len(held) > 0 && e.KVNO > held[0].KVNO {
+			held = held[:0]
+		}
+		if len(held) == 0 || e.KVNO == held[0].KVNO {
+			held = append(held, e)
+		}
+	}
+	if len(held) == 0 {
+		return 0, fmt.Errorf("keytab has no entries for %s@%s", strings.Join(princName.NameString, "/"), realm)
+	}
+	salt, err := keySalt(held, password, salts)
+	if err != nil {
+		return 0, err
+	}
+	pn := types.PrincipalName{NameType: held[0].Principal.NameType, NameString: held[0].Principal.Components}
+	var n int
+	for _, et := range etypes {
+		if hasEtype(held, et) {
+			continue
+		}
+		key, _, err := crypto.GetKeyFromPasswordSalt(password, salt, et, types.PADataSequence{})
+		if err != nil {
+			return n, err
+		}
+		kt.AddKeyWithKVNO(pn, realm, key, time.Time{}, held[0].KVNO)
+		held = append(held, kt.Entries[len(kt.Entries)-1])
+		n++
+	}
+	return n, nil
+}
+
+// keySalt returns the first of the salts that derives the keys of the entries from the password. RC4 keys are not
+// salted, so if only they are held the password is verified against them and the first salt returned.
+func keySalt(entries []entry, password string, salts []string) (string, error) {
+	var verified bool
+	for _, e := range entries {
+		if _, err := crypto.GetEtype(e.Key.KeyType); err != nil {
+			continue
+		}
+		if e.Key.KeyType == etypeID.RC4_HMAC {
+			if !derivesKey(e.Key, password, salts[0]) {
+				return "", ErrSaltNotFound
+			}
+			verified = true
+			continue
+		}
+		for _, s := range salts {
+			if derivesKey(e.Key, password, s) {
+				return s, nil
+			}
+		}
+		return "", ErrSaltNotFound
+	}
+	if !verified {
+		return "", errors.New("keytab has no keys of a supported etype to verify the password with")
+	}
+	return salts[0], nil
+}
+
+// derivesKey reports if the key is derived from the password with the salt.
+func derivesKey(key types.EncryptionKey, password, salt string) bool {
+	k, _, err := crypto.GetKeyFromPasswordSalt(password, salt, key.KeyType, types.PADataSequence{})
+	return err == nil && bytes.Equal(k.KeyValue, key.KeyValue)
+}
+
+// hasEtype reports if any of the entries holds a key of the etype.
+func hasEtype(entries []entry, et int32) bool {
+	for _, e := range entries {
+		if e.Key.KeyType == et {
+			return true
+		}
+	}
+	return false
 }
 
 // Create a new principal.
@@ -4,6 +4,7 @@ import (
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -14,6 +15,7 @@ import (
 	"github.com/jcmturner/gokrb5/v8/test/testdata"
 	"github.com/jcmturner/gokrb5/v8/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestUnmarshal(t *testing.T) {
@@ -249,3 +251,187 @@ func TestKeytab_GetEncryptionKey(t *testing.T) {
 	}
 	assert.Equal(t, 3, kvno)
 }
+
+func TestKeytab_AddKey(t *testing.T) {
+	t.Parallel()
+	pn := types.NewPrincipalName(nametype.KRB_NT_SRV_INST, "krbtgt/TEST.GOKRB5")
+	key := types.EncryptionKey{KeyType: 18, KeyValue: []byte("0123456789abcdef0123456789abcdef")}
+	kt := New()
+	kt.AddKey(pn, "TEST.GOKRB5", key, time.Unix(100, 0), 0)
+	b, err := kt.Marshal()
+	if err != nil {
+		t.Fatalf("Error marshalling keytab: %v", err)
+	}
+	kt2 := New()
+	if err := kt2.Unmarshal(b); err != nil {
+		t.Fatalf("Error unmarshalling keytab: %v", err)
+	}
+	k, _, err := kt2.GetEncryptionKey(pn, "TEST.GOKRB5", 0, 18)
+	if err != nil {
+		t.Fatalf("Error getting key added: %v", err)
+	}
+	assert.Equal(t, key, k, "key not as expected")
+}
+
+func TestKeytab_AddKey_Timestamp(t *testing.T) {
+	t.Parallel()
+	pn := types.NewPrincipalName(nametype.KRB_NT_SRV_INST, "HTTP/host.test.gokrb5")
+	key := types.EncryptionKey{KeyType: 18, KeyValue: []byte("0123456789abcdef0123456789abcdef")}
+	ts := time.Unix(100, 500)
+	kt := New()
+	kt.AddKey(pn, "TEST.GOKRB5", key, ts, 1)
+	kt.AddKey(pn, "TEST.GOKRB5", key, time.Time{}, 2)
+	kt.AddKeyWithKVNO(pn, "TEST.GOKRB5", key, ts, 3)
+	kt.AddKeyWithKVNO(pn, "TEST.GOKRB5", key, time.Time{}, 4)
+	assert.True(t, kt.Entries[0].Timestamp.Equal(ts), "AddKey should keep the timestamp provided")
+	assert.True(t, kt.Entries[1].Timestamp.IsZero(), "AddKey should keep a zero timestamp")
+	assert.True(t, kt.Entries[2].Timestamp.Equal(time.Unix(100, 0)), "AddKeyWithKVNO should hold the timestamp to the second")
+	assert.False(t, kt.Entries[3].Timestamp.IsZero(), "AddKeyWithKVNO should use the current time for a zero timestamp")
+}
+
+func TestKeytab_GetEncryptionKey_SameTimestamp(t *testing.T) {
+	t.Parallel()
+	pn := types.NewPrincipalName(nametype.KRB_NT_SRV_INST, "HTTP/host.test.gokrb5")
+	ts := time.Unix(100, 0)
+	kt := New()
+	for _, kvno := range []uint32{2, 7, 5} {
+		kt.AddKeyWithKVNO(pn, "TEST.GOKRB5", types.EncryptionKey{KeyType: 18, KeyValue: []byte{byte(kvno)}}, ts, kvno)
+	}
+	k, kvno, err := kt.GetEncryptionKey(pn, "TEST.GOKRB5", 0, 18)
+	if err != nil {
+		t.Fatalf("Error getting key: %v", err)
+	}
+	assert.Equal(t, 7, kvno, "highest kvno of entries with the same timestamp should be returned")
+	assert.Equal(t, []byte{7}, k.KeyValue, "key not as expected")
+}
+
+func TestKeytab_AddEntryWithKVNO(t *testing.T) {
+	t.Parallel()
+	princ := "HTTP/princ.test.gokrb5"
+	realm := "TEST.GOKRB5"
+	kt := New()
+	if err := kt.AddEntryWithKVNO(princ, realm, "abcdefg", time.Unix(100, 500), 300, etypeID.AES256_CTS_HMAC_SHA1_96); err != nil {
+		t.Fatalf("Error adding entry: %v", err)
+	}
+	if err := kt.AddEntryWithKVNO(princ, realm, "abcdefg", time.Time{}, 301, etypeID.AES128_CTS_HMAC_SHA1_96); err != nil {
+		t.Fatalf("Error adding entry: %v", err)
+	}
+	assert.Equal(t, time.Unix(100, 0), kt.Entries[0].Timestamp, "timestamp should be held to the second")
+	assert.False(t, kt.Entries[1].Timestamp.IsZero(), "zero timestamp should be replaced with the current time")
+
+	b, err := kt.Marshal()
+	if err != nil {
+		t.Fatalf("Error marshalling keytab: %v", err)
+	}
+	kt2 := New()
+	if err := kt2.Unmarshal(b); err != nil {
+		t.Fatalf("Error unmarshalling keytab: %v", err)
+	}
+	require.Len(t, kt2.Entries, 2)
+	for i, e := range kt2.Entries {
+		assert.Equal(t, kt.Entries[i].Principal, e.Principal, "principal not as expected after round trip")
+		assert.Equal(t, kt.Entries[i].Key, e.Key, "key not as expected after round trip")
+		assert.Equal(t, kt.Entries[i].KVNO, e.KVNO, "key version not as expected after round trip")
+		assert.True(t, kt.Entries[i].Timestamp.Equal(e.Timestamp), "timestamp not as expected after round trip")
+	}
+	assert.Equal(t, uint8(300%256), kt2.Entries[0].KVNO8, "8-bit key version should be truncated")
+	pn := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, princ)
+	_, kvno, err := kt2.GetEncryptionKey(pn, realm, 300, etypeID.AES256_CTS_HMAC_SHA1_96)
+	if err != nil {
+		t.Fatalf("Error getting key: %v", err)
+	}
+	assert.Equal(t, 300, kvno)
+}
+
+func TestKeytab_RemoveEntries(t *testing.T) {
+	t.Parallel()
+	princ := "HTTP/princ.test.gokrb5"
+	realm := "TEST.GOKRB5"
+	kt := New()
+	kt.AddEntry(princ, realm, "abcdefg", time.Unix(100, 0), 1, etypeID.AES256_CTS_HMAC_SHA1_96)
+	kt.AddEntry(princ, realm, "abcdefg", time.Unix(100, 0), 1, etypeID.AES128_CTS_HMAC_SHA1_96)
+	kt.AddEntry(princ, realm, "abcdefg", time.Unix(200, 0), 2, etypeID.AES256_CTS_HMAC_SHA1_96)
+	kt.AddEntry(princ, "OTHER.GOKRB5", "abcdefg", time.Unix(200, 0), 2, etypeID.AES256_CTS_HMAC_SHA1_96)
+	kt.AddEntry("HTTP/other.test.gokrb5", realm, "abcdefg", time.Unix(200, 0), 1, etypeID.AES256_CTS_HMAC_SHA1_96)
+	pn := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, princ)
+
+	assert.Equal(t, 2, kt.RemoveEntries(pn, realm, 1), "entries of the key version not removed")
+	assert.Len(t, kt.Entries, 3)
+	_, _, err := kt.GetEncryptionKey(pn, realm, 1, etypeID.AES256_CTS_HMAC_SHA1_96)
+	assert.Error(t, err, "removed key should not be found")
+	_, _, err = kt.GetEncryptionKey(pn, realm, 2, etypeID.AES256_CTS_HMAC_SHA1_96)
+	assert.NoError(t, err, "key of another version should not be removed")
+
+	assert.Equal(t, 1, kt.RemoveEntries(pn, realm, 0), "entries of all key versions not removed")
+	assert.Len(t, kt.Entries, 2, "entries of other principals and realms should not be removed")
+	assert.Equal(t, 0, kt.RemoveEntries(pn, realm, 0))
+}
+
+func TestKeytab_AddEtypes(t *testing.T) {
+	t.Parallel()
+	realm := "TEST.GOKRB5"
+	pn := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "HTTP/princ.test.gokrb5")
+	kt := New()
+	kt.AddEntryWithKVNO("HTTP/princ.test.gokrb5", realm, "old", time.Unix(100, 0), 1, etypeID.AES256_CTS_HMAC_SHA1_96)
+	kt.AddEntryWithKVNO("HTTP/princ.test.gokrb5", realm, "abcdefg", time.Unix(200, 0), 2, etypeID.AES256_CTS_HMAC_SHA1_96)
+	kt.AddEntryWithKVNO("HTTP/princ.test.gokrb5", realm, "abcdefg", time.Unix(200, 0), 2, etypeID.AES128_CTS_HMAC_SHA1_96)
+
+	_, err := kt.AddEtypes(pn, realm, "wrong", etypeID.AES256_CTS_HMAC_SHA384_192)
+	assert.True(t, errors.Is(err, ErrSaltNotFound), "wrong password should not derive the keys held")
+	_, err = kt.AddEtypes(pn, "OTHER.GOKRB5", "abcdefg", etypeID.AES256_CTS_HMAC_SHA384_192)
+	assert.Error(t, err, "principal without entries should be rejected")
+
+	n, err := kt.AddEtypes(pn, realm, "abcdefg", etypeID.AES128_CTS_HMAC_SHA256_128, etypeID.AES256_CTS_HMAC_SHA384_192, etypeID.AES256_CTS_HMAC_SHA1_96)
+	require.NoError(t, err, "error adding etypes")
+	assert.Equal(t, 2, n, "etypes already held should be skipped")
+	require.Len(t, kt.Entries, 5)
+	expected := New()
+	expected.AddEntryWithKVNO("HTTP/princ.test.gokrb5", realm, "abcdefg", time.Time{}, 2, etypeID.AES256_CTS_HMAC_SHA384_192)
+	key, kvno, err := kt.GetEncryptionKey(pn, realm, 0, etypeID.AES256_CTS_HMAC_SHA384_192)
+	require.NoError(t, err, "error getting key added")
+	assert.Equal(t, 2, kvno, "key should be added to the latest key version")
+	assert.Equal(t, expected.Entries[0].Key, key, "key not derived with the default salt")
+	assert.Equal(t, kt.Entries[1].Principal, kt.Entries[4].Principal, "principal of the entry added not as expected")
+
+	n, err = kt.AddEtypes(pn, realm, "abcdefg", etypeID.AES256_CTS_HMAC_SHA384_192)
+	require.NoError(t, err, "error adding etypes")
+	assert.Equal(t, 0, n, "etypes should only be added once")
+}
+
+func TestKeytab_AddEtypes_Salts(t *testing.T) {
+	t.Parallel()
+	realm := "TEST.GOKRB5"
+	pn := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "WEB01$")
+	salt := types.MachineAccountSalt("WEB01$", realm)
+
+	// Active Directory computer accounts are salted with the host name
+	kt := New()
+	kt.AddEntryWithSalt("WEB01$", realm, "abcdefg", salt, time.Unix(100, 0), 3, etypeID.AES256_CTS_HMAC_SHA1_96)
+	_, err := kt.AddEtypes(pn, realm, "abcdefg", etypeID.AES128_CTS_HMAC_SHA1_96)
+	require.NoError(t, err, "error adding etypes")
+	expected := New()
+	expected.AddEntryWithSalt("WEB01$", realm, "abcdefg", salt, time.Time{}, 3, etypeID.AES128_CTS_HMAC_SHA1_96)
+	assert.Equal(t, expected.Entries[0].Key, kt.Entries[1].Key, "key not derived with the machine account salt")
+
+	// Other salts must be provided
+	kt = New()
+	kt.AddEntryWithSalt("WEB01$", realm, "abcdefg", "CUSTOMsalt", time.Unix(100, 0), 3, etypeID.AES256_CTS_HMAC_SHA1_96)
+	_, err = kt.AddEtypes(pn, realm, "abcdefg", etypeID.AES128_CTS_HMAC_SHA1_96)
+	assert.True(t, errors.Is(err, ErrSaltNotFound), "unknown salt should not be found")
+	n, err := kt.AddEtypesWithSalt(pn, realm, "abcdefg", "CUSTOMsalt", etypeID.AES128_CTS_HMAC_SHA1_96)
+	require.NoError(t, err, "error adding etypes with salt")
+	assert.Equal(t, 1, n)
+
+	// RC4 keys are unsalted so only verify the password
+	kt = New()
+	kt.AddEntry("HTTP/princ.test.gokrb5", realm, "abcdefg", time.Unix(100, 0), 1, etypeID.RC4_HMAC)
+	rpn := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "HTTP/princ.test.gokrb5")
+	_, err = kt.AddEtypes(rpn, realm, "wrong", etypeID.AES256_CTS_HMAC_SHA1_96)
+	assert.True(t, errors.Is(err, ErrSaltNotFound), "wrong password should not derive the RC4 key")
+	n, err = kt.AddEtypes(rpn, realm, "abcdefg", etypeID.AES256_CTS_HMAC_SHA1_96)
+	require.NoError(t, err, "error adding etypes to RC4 keytab")
+	assert.Equal(t, 1, n)
+	expected = New()
+	expected.AddEntry("HTTP/princ.test.gokrb5", realm, "abcdefg", time.Time{}, 1, etypeID.AES256_CTS_HMAC_SHA1_96)
+	assert.Equal(t, expected.Entries[0].Key, kt.Entries[1].Key, "key not derived with the default salt")
+}
@@ -0,0 +1,128 @@
+// Package krbdump decodes SPNEGO and Kerberos tokens and credentials caches into descriptions for diagnosing
+// interoperability problems. Given the service's keytab, tickets are decrypted and the PAC they carry is described.
+// Keys and cipher text are never included in the descriptions.
+package krbdump
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+)
+
+// Token returns a description of the token, which may be a SPNEGO token, a GSS-API KRB5 token or a bare AP_REQ,
+// AP_REP, KRB_ERROR or Ticket. If a keytab is provided the tickets within the token are decrypted with it and their
+// encrypted parts, the authenticator and any PAC are described too.
+func Token(b []byte, kt *keytab.Keytab) (string, error) {
+	var st spnego.SPNEGOToken
+	if err := st.Unmarshal(b); err == nil {
+		s := st.String()
+		if st.Init && kt != nil {
+			var m spnego.KRB5Token
+			if err := m.Unmarshal(st.NegTokenInit.MechTokenBytes); err == nil && m.IsAPReq() {
+				s += "\n" + decryptedAPReq(m.APReq, kt)
+			}
+		}
+		return s, nil
+	}
+	var m spnego.KRB5Token
+	if err := m.Unmarshal(b); err == nil {
+		s := m.String()
+		if m.IsAPReq() && kt != nil {
+			s += "\n" + decryptedAPReq(m.APReq, kt)
+		}
+		return s, nil
+	}
+	var apReq messages.APReq
+	if err := apReq.Unmarshal(b); err == nil {
+		s := apReq.String()
+		if kt != nil {
+			s += "\n" + decryptedAPReq(apReq, kt)
+		}
+		return s, nil
+	}
+	var apRep messages.APRep
+	if err := apRep.Unmarshal(b); err == nil {
+		return apRep.String(), nil
+	}
+	var krbErr messages.KRBError
+	if err := krbErr.Unmarshal(b); err == nil {
+		return "KRB_ERROR: " + krbErr.Error(), nil
+	}
+	var tkt messages.Ticket
+	if err := tkt.Unmarshal(b); err == nil {
+		if kt != nil {
+			return decryptedTicket(tkt, kt), nil
+		}
+		return tkt.String(), nil
+	}
+	return "", errors.New("not a SPNEGO token, KRB5 token, AP_REQ, AP_REP, KRB_ERROR or Ticket")
+}
+
+// CCache returns a description of the entries of the credentials cache. Configuration entries are not included. If a
+// keytab is provided the tickets of the entries are decrypted with it, which requires the keys of the services they
+// are for, such as the krbtgt principal's key for a TGT.
+func CCache(c *credentials.CCache, kt *keytab.Keytab) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "CCache\n  Version: %d", c.Version)
+	fmt.Fprintf(&b, "\n  DefaultPrincipal: %s@%s", c.GetClientPrincipalName().PrincipalNameString(), c.GetClientRealm())
+	for _, cred := range c.GetEntries() {
+		s := cred.String()
+		var tkt messages.Ticket
+		if err := tkt.Unmarshal(cred.Ticket); err != nil {
+			s += fmt.Sprintf("\n  Ticket: could not be unmarshaled: %v", err)
+		} else if kt != nil {
+			s += "\n" + nested(decryptedTicket(tkt, kt))
+		} else {
+			s += "\n" + nested(tkt.String())
+		}
+		b.WriteString("\n" + nested(s))
+	}
+	return b.String()
+}
+
+// decryptedAPReq returns a description of the AP_REQ with the ticket and authenticator decrypted using the keytab,
+// or of why they could not be.
+func decryptedAPReq(a messages.APReq, kt *keytab.Keytab) string {
+	if err := a.Ticket.DecryptEncPart(kt, nil); err != nil {
+		return fmt.Sprintf("Ticket could not be decrypted: %v", err)
+	}
+	s := "Decrypted "
+	if err := a.DecryptAuthenticator(a.Ticket.DecryptedEncPart.Key); err != nil {
+		s = fmt.Sprintf("Authenticator could not be decrypted: %v\n", err) + s
+	}
+	return s + a.String() + pacString(a.Ticket, kt)
+}
+
+// decryptedTicket returns a description of the ticket with its encrypted part decrypted using the keytab, or of why
+// it could not be.
+func decryptedTicket(t messages.Ticket, kt *keytab.Keytab) string {
+	if err := t.DecryptEncPart(kt, nil); err != nil {
+		return t.String() + fmt.Sprintf("\nTicket could not be decrypted: %v", err)
+	}
+	return "Decrypted " + t.String() + pacString(t, kt)
+}
+
+// pacString returns a description of the PAC within the decrypted ticket, or an empty string if there is none.
+func pacString(t messages.Ticket, kt *keytab.Keytab) string {
+	isPAC, p, err := t.GetPACType(kt, nil, log.New(ioutil.Discard, "", 0))
+	if !isPAC {
+		return ""
+	}
+	s := "\n" + p.String()
+	if err != nil {
+		s += fmt.Sprintf("\nPAC could not be processed: %v", err)
+	}
+	return s
+}
+
+// nested indents each line of the description.
+func nested(s string) string {
+	return "  " + strings.Replace(s, "\n", "\n  ", -1)
+}
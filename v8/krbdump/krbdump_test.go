@@ -0,0 +1,89 @@
+package krbdump
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/krbtest"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+	"github.com/jcmturner/gokrb5/v8/test/testdata"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToken(t *testing.T) {
+	t.Parallel()
+	s, err := krbtest.SPNEGOToken(krbtest.ClientPrincipal, krbtest.Realm, krbtest.ServicePrincipal, krbtest.ServiceKeytab())
+	require.NoError(t, err, "error creating SPNEGO token")
+	b, err := base64.StdEncoding.DecodeString(s)
+	require.NoError(t, err, "error decoding SPNEGO token")
+
+	str, err := Token(b, nil)
+	require.NoError(t, err, "error describing SPNEGO token")
+	assert.Contains(t, str, "SPNEGO NegTokenInit", "description not as expected")
+	assert.Contains(t, str, "MechToken: KRB5Token AP_REQ", "description not as expected")
+	assert.NotContains(t, str, "Decrypted", "token should not be decrypted without a keytab")
+
+	str, err = Token(b, krbtest.ServiceKeytab())
+	require.NoError(t, err, "error describing SPNEGO token")
+	for _, want := range []string{
+		"Decrypted AP_REQ",
+		"DecryptedEncPart: EncTicketPart",
+		"CName: " + krbtest.ClientPrincipal,
+		"Authenticator: Authenticator",
+	} {
+		assert.Contains(t, str, want, "description of decrypted token not as expected")
+	}
+
+	var st spnego.SPNEGOToken
+	require.NoError(t, st.Unmarshal(b), "error unmarshaling SPNEGO token")
+	str, err = Token(st.NegTokenInit.MechTokenBytes, krbtest.ServiceKeytab())
+	require.NoError(t, err, "error describing KRB5 token")
+	assert.Contains(t, str, "KRB5Token AP_REQ", "description not as expected")
+	assert.Contains(t, str, "Decrypted AP_REQ", "description not as expected")
+
+	str, err = Token(b, keytab.New())
+	require.NoError(t, err, "error describing SPNEGO token")
+	assert.Contains(t, str, "Ticket could not be decrypted", "decryption without the service's key should be reported")
+
+	_, err = Token([]byte("not a token"), nil)
+	assert.Error(t, err, "bytes that are not a token should be rejected")
+}
+
+func TestCCache(t *testing.T) {
+	t.Parallel()
+	tkt, key, err := krbtest.NewServiceTicket(krbtest.ClientPrincipal, krbtest.Realm, krbtest.ServicePrincipal, krbtest.ServiceKeytab(), time.Hour)
+	require.NoError(t, err, "error creating ticket")
+	tb, err := tkt.Marshal()
+	require.NoError(t, err, "error marshaling ticket")
+	c := credentials.NewCCache(types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, krbtest.ClientPrincipal), krbtest.Realm)
+	c.AddCredential(tkt.SName, tkt.Realm, &credentials.Credential{
+		Key:       key,
+		StartTime: time.Now(),
+		EndTime:   time.Now().Add(time.Hour),
+		Ticket:    tb,
+	})
+
+	str := CCache(c, nil)
+	assert.Contains(t, str, "DefaultPrincipal: "+krbtest.ClientPrincipal+"@"+krbtest.Realm, "description not as expected")
+	assert.Contains(t, str, "Server: "+krbtest.ServicePrincipal+"@"+krbtest.Realm, "description not as expected")
+	assert.Contains(t, str, "Ticket\n", "description not as expected")
+	assert.NotContains(t, str, "Decrypted", "ticket should not be decrypted without a keytab")
+
+	str = CCache(c, krbtest.ServiceKeytab())
+	assert.Contains(t, str, "Decrypted Ticket", "description of decrypted ticket not as expected")
+	assert.Contains(t, str, "CName: "+krbtest.ClientPrincipal, "description of decrypted ticket not as expected")
+
+	b, err := hex.DecodeString(testdata.CCACHE_TEST)
+	require.NoError(t, err, "error decoding test data")
+	c = new(credentials.CCache)
+	require.NoError(t, c.Unmarshal(b), "error parsing cache")
+	str = CCache(c, krbtest.ServiceKeytab())
+	assert.Contains(t, str, "Ticket could not be decrypted", "failed decryption should be reported")
+}
@@ -23,6 +23,7 @@ const (
 type Krberror struct {
 	RootCause string
 	EText     []string
+	cause     error
 }
 
 // Error function to implement the error interface.
@@ -30,6 +31,12 @@ func (e Krberror) Error() string {
 	return fmt.Sprintf("[Root cause: %s] ", e.RootCause) + strings.Join(e.EText, separator)
 }
 
+// Unwrap returns the underlying error that caused the Krberror, if there is one.
+// This allows the cause to be inspected with errors.Is and errors.As.
+func (e Krberror) Unwrap() error {
+	return e.cause
+}
+
 // Add another error statement to the error.
 func (e *Krberror) Add(et string, s string) {
 	e.EText = append([]string{fmt.Sprintf("%s: %s", et, s)}, e.EText...)
@@ -49,7 +56,9 @@ func Errorf(err error, et, format string, a ...interface{}) Krberror {
 		e.Add(et, fmt.Sprintf(format, a...))
 		return e
 	}
-	return NewErrorf(et, format+": %s", append(a, err)...)
+	e := NewErrorf(et, format+": %s", append(a, err)...)
+	e.cause = err
+	return e
 }
 
 // NewErrorf creates a new Krberror from a formatted string.
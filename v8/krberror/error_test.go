@@ -1,7 +1,9 @@
 package krberror
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -21,3 +23,12 @@ func TestErrorf(t *testing.T) {
 	a = Errorf(err, "cause", "arg1=%d arg2=%s", 123, "arg")
 	assert.Equal(t, "[Root cause: another error] cause: arg1=123 arg2=arg < another error: some text", a.Error())
 }
+
+func TestErrorfUnwrap(t *testing.T) {
+	cause := io.ErrUnexpectedEOF
+	err := Errorf(fmt.Errorf("reading response: %w", cause), NetworkingError, "some text")
+	assert.True(t, errors.Is(err, cause), "cause not found in error chain")
+	err = Errorf(err, KRBMsgError, "more text")
+	assert.True(t, errors.Is(err, cause), "cause not found in error chain after further wrapping")
+	assert.Nil(t, NewErrorf(KRBMsgError, "no cause").Unwrap(), "error without a cause should unwrap to nil")
+}
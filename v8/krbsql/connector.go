@@ -0,0 +1,149 @@
+// Package krbsql provides a database/sql connector for databases that authenticate clients with Kerberos, ensuring
+// each new connection is opened with a valid service ticket however long after the client logged in it is created.
+package krbsql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/iana/errorcode"
+	"github.com/jcmturner/gokrb5/v8/messages"
+)
+
+// DefaultMinTicketLifetime is the least remaining lifetime of the service ticket a connection is opened with if none
+// is configured.
+const DefaultMinTicketLifetime = 5 * time.Minute
+
+// ConnectFunc opens a connection to the database authenticating as the client, such as by creating the driver's
+// connection with a Kerberos provider using the client. When it is called the ticket for the database's SPN is
+// cached by the client and valid for at least the minimum ticket lifetime.
+type ConnectFunc func(ctx context.Context, cl *client.Client) (driver.Conn, error)
+
+// Connector is a driver.Connector opening connections to a Kerberos authenticated database. Before each connection is
+// opened the client's TGT is renewed if it has expired and its ticket for the database is replaced if it expires
+// within the minimum ticket lifetime. If opening the connection fails with an error indicating the credentials have
+// expired the client logs in again and the connection is retried once. It is safe for concurrent use.
+//
+// db := sql.OpenDB(krbsql.NewConnector(cl, "MSSQLSvc/db.example.com:1433", drv, connect))
+type Connector struct {
+	client      *client.Client
+	spn         string
+	driver      driver.Driver
+	connect     ConnectFunc
+	minLifetime time.Duration
+	retry       func(error) bool
+	mux         sync.Mutex
+}
+
+// NewConnector returns a Connector opening connections to the database of the SPN with the connect function, for use
+// with sql.OpenDB. The driver is returned by the Connector's Driver method.
+func NewConnector(cl *client.Client, spn string, d driver.Driver, connect ConnectFunc, options ...func(*Connector)) *Connector {
+	c := &Connector{
+		client:      cl,
+		spn:         spn,
+		driver:      d,
+		connect:     connect,
+		minLifetime: DefaultMinTicketLifetime,
+		retry:       credentialsExpired,
+	}
+	for _, o := range options {
+		o(c)
+	}
+	return c
+}
+
+// MinTicketLifetime used to configure the Connector with the least remaining lifetime of the service ticket a
+// connection is opened with, so that tickets do not expire while the database authenticates the connection.
+//
+// c := NewConnector(cl, spn, drv, connect, MinTicketLifetime(time.Minute))
+func MinTicketLifetime(d time.Duration) func(*Connector) {
+	return func(c *Connector) {
+		c.minLifetime = d
+	}
+}
+
+// RetryIf used to configure the Connector with the function deciding if a failure to open a connection is due to
+// expired credentials, in which case the client logs in again and the connection is retried once. The default
+// retries if the error is a KRBError indicating the ticket or TGT has expired, drivers that do not wrap Kerberos
+// errors may need a function recognising their own errors.
+//
+// c := NewConnector(cl, spn, drv, connect, RetryIf(f))
+func RetryIf(f func(error) bool) func(*Connector) {
+	return func(c *Connector) {
+		c.retry = f
+	}
+}
+
+// Connect opens a connection to the database with a valid service ticket.
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	if err := c.refresh(false); err != nil {
+		return nil, err
+	}
+	conn, err := c.connect(ctx, c.client)
+	if err == nil || c.retry == nil || !c.retry(err) {
+		return conn, err
+	}
+	c.client.Log("opening connection to %s failed as the credentials have expired, logging in again: %v", c.spn, err)
+	if rerr := c.refresh(true); rerr != nil {
+		return nil, fmt.Errorf("%v; could not renew credentials: %w", err, rerr)
+	}
+	return c.connect(ctx, c.client)
+}
+
+// Driver returns the driver the Connector was created with.
+func (c *Connector) Driver() driver.Driver {
+	return c.driver
+}
+
+// refresh ensures the client has a ticket for the SPN valid for the minimum lifetime, logging in again if the TGT
+// has expired, cannot provide such a ticket or if forced.
+func (c *Connector) refresh(force bool) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if force {
+		c.client.RemoveCachedTicket(c.spn)
+		if err := c.client.Login(); err != nil {
+			return err
+		}
+	} else if err := c.client.AffirmLogin(); err != nil {
+		return err
+	}
+	if c.fresh() {
+		return nil
+	}
+	c.client.RemoveCachedTicket(c.spn)
+	if _, _, err := c.client.GetServiceTicket(c.spn); err != nil {
+		return err
+	}
+	if c.fresh() || force {
+		return nil
+	}
+	// The ticket is limited by the lifetime of the TGT
+	c.client.RemoveCachedTicket(c.spn)
+	if err := c.client.Login(); err != nil {
+		return err
+	}
+	_, _, err := c.client.GetServiceTicket(c.spn)
+	return err
+}
+
+// fresh reports if the client has a ticket for the SPN cached that is valid for the minimum lifetime.
+func (c *Connector) fresh() bool {
+	e, ok := c.client.GetCacheEntry(c.spn)
+	return ok && e.EndTime.Sub(c.client.Now()) >= c.minLifetime
+}
+
+// credentialsExpired reports if the error is a KRBError indicating the ticket or TGT has expired.
+func credentialsExpired(err error) bool {
+	for _, code := range []int32{errorcode.KRB_AP_ERR_TKT_EXPIRED, errorcode.KDC_ERR_TGT_REVOKED} {
+		if errors.Is(err, messages.KRBError{ErrorCode: code}) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package krbsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/iana/errorcode"
+	"github.com/jcmturner/gokrb5/v8/krbtest"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testDriver struct{}
+
+func (testDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("connections are opened by the connector")
+}
+
+type testConn struct {
+	ticket messages.Ticket
+}
+
+func (c *testConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *testConn) Close() error {
+	return nil
+}
+
+func (c *testConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+// connectWithTicket returns a ConnectFunc that records the ticket each connection was authenticated with.
+func connectWithTicket(t *testing.T, tkts chan<- messages.Ticket) ConnectFunc {
+	return func(ctx context.Context, cl *client.Client) (driver.Conn, error) {
+		tkt, _, err := cl.GetServiceTicket(krbtest.ServicePrincipal)
+		if err != nil {
+			return nil, err
+		}
+		e, ok := cl.GetCacheEntry(krbtest.ServicePrincipal)
+		require.True(t, ok, "ticket should be cached")
+		assert.True(t, e.EndTime.Sub(time.Now()) > time.Second, "ticket should be valid for the minimum lifetime")
+		tkts <- tkt
+		return &testConn{ticket: tkt}, nil
+	}
+}
+
+func TestConnector_RefreshesTicket(t *testing.T) {
+	t.Parallel()
+	k, err := krbtest.NewKDC(krbtest.Realm, krbtest.KDCKeytab(), krbtest.TicketLifetime(3*time.Second))
+	require.NoError(t, err, "error starting KDC")
+	defer k.Close()
+	cl := krbtest.NewClient(k.Config())
+	defer cl.Destroy()
+
+	tkts := make(chan messages.Ticket, 3)
+	c := NewConnector(cl, krbtest.ServicePrincipal, testDriver{}, connectWithTicket(t, tkts), MinTicketLifetime(2*time.Second))
+	assert.Equal(t, testDriver{}, c.Driver())
+
+	conn, err := c.Connect(context.Background())
+	require.NoError(t, err, "error connecting")
+	conn.Close()
+	first := <-tkts
+	conn, err = c.Connect(context.Background())
+	require.NoError(t, err, "error connecting")
+	conn.Close()
+	assert.Equal(t, first.EncPart.Cipher, (<-tkts).EncPart.Cipher, "ticket valid for the minimum lifetime should be reused")
+
+	time.Sleep(1500 * time.Millisecond)
+	conn, err = c.Connect(context.Background())
+	require.NoError(t, err, "error connecting")
+	conn.Close()
+	assert.NotEqual(t, first.EncPart.Cipher, (<-tkts).EncPart.Cipher, "ticket expiring within the minimum lifetime should be replaced")
+}
+
+func TestConnector_RetriesExpired(t *testing.T) {
+	t.Parallel()
+	k, err := krbtest.NewKDC(krbtest.Realm, krbtest.KDCKeytab())
+	require.NoError(t, err, "error starting KDC")
+	defer k.Close()
+	cl := krbtest.NewClient(k.Config())
+	defer cl.Destroy()
+
+	var calls int
+	expired := messages.KRBError{ErrorCode: errorcode.KRB_AP_ERR_TKT_EXPIRED}
+	c := NewConnector(cl, krbtest.ServicePrincipal, testDriver{}, func(ctx context.Context, cl *client.Client) (driver.Conn, error) {
+		calls++
+		if calls == 1 {
+			return nil, expired
+		}
+		return &testConn{}, nil
+	})
+	db := sql.OpenDB(c)
+	defer db.Close()
+	require.NoError(t, db.Ping(), "connection should be retried once the credentials are renewed")
+	assert.Equal(t, 2, calls, "connection should be retried once")
+
+	calls = 0
+	other := errors.New("connection refused")
+	c = NewConnector(cl, krbtest.ServicePrincipal, testDriver{}, func(ctx context.Context, cl *client.Client) (driver.Conn, error) {
+		calls++
+		return nil, other
+	})
+	_, err = c.Connect(context.Background())
+	assert.Equal(t, other, err)
+	assert.Equal(t, 1, calls, "other errors should not be retried")
+
+	calls = 0
+	c = NewConnector(cl, krbtest.ServicePrincipal, testDriver{}, func(ctx context.Context, cl *client.Client) (driver.Conn, error) {
+		calls++
+		return nil, expired
+	}, RetryIf(func(error) bool { return false }))
+	_, err = c.Connect(context.Background())
+	assert.True(t, errors.Is(err, expired))
+	assert.Equal(t, 1, calls, "errors should only be retried if the retry function matches them")
+}
@@ -0,0 +1,844 @@
+package krbtest
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1tools"
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/crypto"
+	"github.com/jcmturner/gokrb5/v8/iana"
+	"github.com/jcmturner/gokrb5/v8/iana/asnAppTag"
+	"github.com/jcmturner/gokrb5/v8/iana/errorcode"
+	"github.com/jcmturner/gokrb5/v8/iana/flags"
+	"github.com/jcmturner/gokrb5/v8/iana/keyusage"
+	"github.com/jcmturner/gokrb5/v8/iana/msgtype"
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/jcmturner/gokrb5/v8/iana/patype"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+const (
+	// maxMessageSize is the largest request the mock KDC will accept.
+	maxMessageSize = 1 << 20
+	// defaultTicketLifetime is the lifetime of tickets issued by the mock KDC unless configured otherwise.
+	defaultTicketLifetime = 10 * time.Hour
+)
+
+// KDC is a minimal in-process Kerberos KDC that issues tickets for the principals within its keytab.
+// It supports AS and TGS exchanges over TCP and is only intended for use in tests.
+type KDC struct {
+	realm      string
+	kt         *keytab.Keytab
+	tgtKeytab  *keytab.Keytab
+	preAuth    bool
+	lifetime   time.Duration
+	clockSkew  time.Duration
+	aliases    map[string]string
+	salts      map[string]string
+	trusts     map[string]string
+	routes     map[string]string
+	referrals  map[string]string
+	delegation map[string][]string
+	requirePAC bool
+	listener   net.Listener
+	wg         sync.WaitGroup
+	closeOnce  sync.Once
+	requestsMu sync.Mutex
+	requests   int
+}
+
+// RequirePreAuth configures the KDC to require PA-ENC-TIMESTAMP pre-authentication from clients.
+//
+// k, err := NewKDC(realm, kt, RequirePreAuth(true))
+func RequirePreAuth(b bool) func(*KDC) {
+	return func(k *KDC) {
+		k.preAuth = b
+	}
+}
+
+// TicketLifetime configures the lifetime of tickets issued by the KDC.
+//
+// k, err := NewKDC(realm, kt, TicketLifetime(time.Hour))
+func TicketLifetime(d time.Duration) func(*KDC) {
+	return func(k *KDC) {
+		k.lifetime = d
+	}
+}
+
+// ClockSkew configures the offset of the KDC's clock from the local clock.
+// This can be used to test a client's handling of clock skew.
+//
+// k, err := NewKDC(realm, kt, ClockSkew(time.Hour))
+func ClockSkew(d time.Duration) func(*KDC) {
+	return func(k *KDC) {
+		k.clockSkew = d
+	}
+}
+
+// ClientAlias configures the KDC to treat the client name alias as an alias of the canonical client name. If a client
+// logs in with the alias and asks for its name to be canonicalized the canonical name is returned, as Active
+// Directory does for user principal names. The key of the canonical name is used.
+//
+// k, err := NewKDC(realm, kt, ClientAlias("alias", "testuser1"))
+func ClientAlias(alias, canonical string) func(*KDC) {
+	return func(k *KDC) {
+		if k.aliases == nil {
+			k.aliases = make(map[string]string)
+		}
+		k.aliases[alias] = canonical
+	}
+}
+
+// PrincipalSalt configures the salt the KDC advertises to clients pre-authenticating as the principal, in place of the
+// default salt derived from the principal name. The principal's keys in the KDC's keytab must be derived with it, as
+// for Active Directory computer accounts.
+//
+// k, err := NewKDC(realm, kt, PrincipalSalt("WEB01$", types.MachineAccountSalt("WEB01$", realm)))
+func PrincipalSalt(principal, salt string) func(*KDC) {
+	return func(k *KDC) {
+		if k.salts == nil {
+			k.salts = make(map[string]string)
+		}
+		k.salts[principal] = salt
+	}
+}
+
+// CrossRealmTrust configures a two way trust between the KDC's realm and the realm provided, with inter-realm keys
+// derived from the password. The KDC of the other realm must be configured with the same trust.
+//
+// k, err := NewKDC(realm, kt, CrossRealmTrust("OTHER.GOKRB5", "trustpassword"))
+func CrossRealmTrust(realm, password string) func(*KDC) {
+	return func(k *KDC) {
+		if k.trusts == nil {
+			k.trusts = make(map[string]string)
+		}
+		k.trusts[realm] = password
+	}
+}
+
+// NameSuffixRoute configures the KDC to refer requests for service principals whose host ends with the DNS suffix to
+// the trusted realm provided, as Active Directory does using the name suffixes of a forest trust. The KDC must also
+// be configured with a CrossRealmTrust with the realm.
+//
+// k, err := NewKDC(realm, kt, NameSuffixRoute(".other.gokrb5", "OTHER.GOKRB5"))
+func NameSuffixRoute(suffix, realm string) func(*KDC) {
+	return func(k *KDC) {
+		if k.routes == nil {
+			k.routes = make(map[string]string)
+		}
+		k.routes[suffix] = realm
+	}
+}
+
+// ClientReferral configures the KDC to refer a client logging in with the name provided to the realm of its account,
+// as Active Directory does for the enterprise names of users in a trusted forest.
+//
+// k, err := NewKDC(realm, kt, ClientReferral("user@other.gokrb5", "OTHER.GOKRB5"))
+func ClientReferral(name, realm string) func(*KDC) {
+	return func(k *KDC) {
+		if k.referrals == nil {
+			k.referrals = make(map[string]string)
+		}
+		k.referrals[name] = realm
+	}
+}
+
+// ConstrainedDelegation configures the KDC to trust the service to obtain tickets on behalf of users to the SPNs
+// provided with S4U2Self and S4U2Proxy, as Active Directory does for accounts trusted for delegation to specified
+// services with any authentication protocol.
+//
+// k, err := NewKDC(realm, kt, ConstrainedDelegation("testuser1", "HTTP/host.test.gokrb5"))
+func ConstrainedDelegation(service string, spns ...string) func(*KDC) {
+	return func(k *KDC) {
+		if k.delegation == nil {
+			k.delegation = make(map[string][]string)
+		}
+		k.delegation[service] = append(k.delegation[service], spns...)
+	}
+}
+
+// RequirePAC configures the KDC to refuse S4U2Self requests for tickets without a PAC, as Active Directory domain
+// controllers hardened against PAC spoofing do.
+//
+// k, err := NewKDC(realm, kt, RequirePAC(true))
+func RequirePAC(b bool) func(*KDC) {
+	return func(k *KDC) {
+		k.requirePAC = b
+	}
+}
+
+// NewKDC starts a KDC for the realm listening on a random loopback port.
+// The keytab provided must contain the keys of all client and service principals the KDC is to issue tickets for.
+// The KDC should be closed when it is no longer needed.
+func NewKDC(realm string, kt *keytab.Keytab, options ...func(*KDC)) (*KDC, error) {
+	k := &KDC{
+		realm:    realm,
+		kt:       kt,
+		lifetime: defaultTicketLifetime,
+	}
+	for _, o := range options {
+		o(k)
+	}
+	if len(k.trusts) > 0 {
+		// Hold the inter-realm keys alongside, rather than within, the keytab provided
+		k.kt = keytab.New()
+		k.kt.Entries = append(k.kt.Entries, kt.Entries...)
+		for realm, password := range k.trusts {
+			for _, e := range []string{"krbtgt/" + realm + "@" + k.realm, "krbtgt/" + k.realm + "@" + realm} {
+				i := strings.LastIndexByte(e, '@')
+				for _, et := range etypePreference {
+					if err := k.kt.AddEntry(e[:i], e[i+1:], password, time.Unix(0, 0), 1, et); err != nil {
+						return nil, fmt.Errorf("error creating inter-realm keys: %w", err)
+					}
+				}
+			}
+		}
+	}
+	tgtKeytab, err := newRandomKeytab("krbtgt/"+realm, realm)
+	if err != nil {
+		return nil, fmt.Errorf("error creating krbtgt keys: %w", err)
+	}
+	k.tgtKeytab = tgtKeytab
+	k.listener, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("error starting KDC listener: %w", err)
+	}
+	k.wg.Add(1)
+	go k.serve()
+	return k, nil
+}
+
+// Addr returns the address the KDC is listening on.
+func (k *KDC) Addr() string {
+	return k.listener.Addr().String()
+}
+
+// Realm returns the realm the KDC issues tickets for.
+func (k *KDC) Realm() string {
+	return k.realm
+}
+
+// Requests returns the number of requests the KDC has processed.
+func (k *KDC) Requests() int {
+	k.requestsMu.Lock()
+	defer k.requestsMu.Unlock()
+	return k.requests
+}
+
+// Config returns a krb5 configuration for a client of the KDC.
+func (k *KDC) Config() *config.Config {
+	c, err := config.NewFromString(fmt.Sprintf(`[libdefaults]
+  default_realm = %[1]s
+  dns_lookup_realm = false
+  dns_lookup_kdc = false
+  udp_preference_limit = 1
+  default_tkt_enctypes = aes256-cts-hmac-sha1-96 aes128-cts-hmac-sha1-96
+  default_tgs_enctypes = aes256-cts-hmac-sha1-96 aes128-cts-hmac-sha1-96
+  permitted_enctypes = aes256-cts-hmac-sha1-96 aes128-cts-hmac-sha1-96
+
+[realms]
+  %[1]s = {
+    kdc = %[2]s
+  }
+`, k.realm, k.Addr()))
+	if err != nil {
+		// The template is fixed so this indicates a programming error.
+		panic("krbtest: invalid KDC configuration: " + err.Error())
+	}
+	return c
+}
+
+// Close stops the KDC.
+func (k *KDC) Close() error {
+	var err error
+	k.closeOnce.Do(func() {
+		err = k.listener.Close()
+		k.wg.Wait()
+	})
+	return err
+}
+
+func (k *KDC) now() time.Time {
+	return time.Now().UTC().Add(k.clockSkew).Truncate(time.Second)
+}
+
+func (k *KDC) serve() {
+	defer k.wg.Done()
+	for {
+		conn, err := k.listener.Accept()
+		if err != nil {
+			return
+		}
+		k.wg.Add(1)
+		go func() {
+			defer k.wg.Done()
+			k.handle(conn)
+		}()
+	}
+}
+
+// handle processes the length prefixed messages sent on the connection as defined in RFC 4120 7.2.2.
+func (k *KDC) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		conn.SetDeadline(time.Now().Add(10 * time.Second))
+		hb := make([]byte, 4)
+		if _, err := io.ReadFull(conn, hb); err != nil {
+			return
+		}
+		s := binary.BigEndian.Uint32(hb)
+		if s > maxMessageSize {
+			return
+		}
+		b := make([]byte, s)
+		if _, err := io.ReadFull(conn, b); err != nil {
+			return
+		}
+		rb := k.process(b)
+		binary.BigEndian.PutUint32(hb, uint32(len(rb)))
+		if _, err := conn.Write(append(hb, rb...)); err != nil {
+			return
+		}
+	}
+}
+
+// process a request and return the bytes of the reply.
+func (k *KDC) process(b []byte) []byte {
+	k.requestsMu.Lock()
+	k.requests++
+	k.requestsMu.Unlock()
+	var rb []byte
+	var err error
+	if len(b) > 0 {
+		switch b[0] {
+		case 0x60 + asnAppTag.ASREQ:
+			rb, err = k.processASReq(b)
+		case 0x60 + asnAppTag.TGSREQ:
+			rb, err = k.processTGSReq(b)
+		default:
+			err = k.krbError(errorcode.KRB_AP_ERR_MSG_TYPE, "unsupported message type")
+		}
+	} else {
+		err = k.krbError(errorcode.KRB_ERR_GENERIC, "empty request")
+	}
+	if err != nil {
+		e, ok := err.(messages.KRBError)
+		if !ok {
+			e = k.krbError(errorcode.KRB_ERR_GENERIC, err.Error())
+		}
+		rb, _ = e.Marshal()
+	}
+	return rb
+}
+
+func (k *KDC) krbError(code int32, etext string) messages.KRBError {
+	e := messages.NewKRBError(k.tgsName(), k.realm, code, etext)
+	e.STime = time.Now().UTC().Add(k.clockSkew)
+	return e
+}
+
+func (k *KDC) tgsName() types.PrincipalName {
+	return types.NewPrincipalName(nametype.KRB_NT_SRV_INST, "krbtgt/"+k.realm)
+}
+
+func (k *KDC) processASReq(b []byte) ([]byte, error) {
+	var req messages.ASReq
+	if err := req.Unmarshal(b); err != nil {
+		return nil, k.krbError(errorcode.KRB_ERR_GENERIC, "could not unmarshal AS_REQ")
+	}
+	cname := req.ReqBody.CName
+	if realm, ok := k.referrals[cname.PrincipalNameString()]; ok {
+		e := k.krbError(errorcode.KDC_ERR_WRONG_REALM, "client principal is in realm "+realm)
+		e.CRealm = realm
+		return nil, e
+	}
+	kname := cname
+	if c, ok := k.aliases[cname.PrincipalNameString()]; ok {
+		kname = types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, c)
+		if types.IsFlagSet(&req.ReqBody.KDCOptions, flags.Canonicalize) {
+			cname = kname
+		}
+	}
+	ckey, etype, err := k.selectKey(k.kt, kname, req.ReqBody.EType)
+	if err != nil {
+		return nil, err
+	}
+	if k.preAuth {
+		if err := k.verifyPreAuth(req, kname, etype); err != nil {
+			return nil, err
+		}
+	}
+	skt := k.tgtKeytab
+	if !req.ReqBody.SName.Equal(k.tgsName()) {
+		skt = k.kt
+	}
+	tkt, sessionKey, err := k.newTicket(cname, k.realm, req.ReqBody, skt, req.ReqBody.EType, true)
+	if err != nil {
+		return nil, err
+	}
+	encPart, err := k.encPart(tkt, sessionKey, req.ReqBody, asnAppTag.EncASRepPart, ckey, keyusage.AS_REP_ENCPART)
+	if err != nil {
+		return nil, err
+	}
+	rep := messages.ASRep{
+		KDCRepFields: messages.KDCRepFields{
+			PVNO:    iana.PVNO,
+			MsgType: msgtype.KRB_AS_REP,
+			CRealm:  k.realm,
+			CName:   cname,
+			Ticket:  tkt,
+			EncPart: encPart,
+		},
+	}
+	return rep.Marshal()
+}
+
+func (k *KDC) processTGSReq(b []byte) ([]byte, error) {
+	var req messages.TGSReq
+	if err := req.Unmarshal(b); err != nil {
+		return nil, k.krbError(errorcode.KRB_ERR_GENERIC, "could not unmarshal TGS_REQ")
+	}
+	var apReq messages.APReq
+	var found bool
+	for _, pa := range req.PAData {
+		if pa.PADataType == patype.PA_TGS_REQ {
+			if err := apReq.Unmarshal(pa.PADataValue); err != nil {
+				return nil, k.krbError(errorcode.KRB_ERR_GENERIC, "could not unmarshal PA-TGS-REQ")
+			}
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, k.krbError(errorcode.KDC_ERR_PADATA_TYPE_NOSUPP, "PA-TGS-REQ not provided")
+	}
+	// Renewal and validation requests present the ticket being renewed or validated rather than a TGT.
+	skt := k.tgtKeytab
+	if err := apReq.Ticket.DecryptEncPart(skt, nil); err != nil {
+		skt = k.kt
+		if err := apReq.Ticket.DecryptEncPart(skt, nil); err != nil {
+			return nil, k.krbError(errorcode.KRB_AP_ERR_BAD_INTEGRITY, "could not decrypt ticket")
+		}
+	}
+	tgtKey := apReq.Ticket.DecryptedEncPart.Key
+	if err := apReq.DecryptAuthenticator(tgtKey); err != nil {
+		return nil, k.krbError(errorcode.KRB_AP_ERR_BAD_INTEGRITY, "could not decrypt authenticator")
+	}
+	if types.IsFlagSet(&req.ReqBody.KDCOptions, flags.Validate) {
+		return k.validate(req, apReq, skt)
+	}
+	if ok, _ := apReq.Ticket.Valid(5 * time.Minute); !ok {
+		return nil, k.krbError(errorcode.KRB_AP_ERR_TKT_EXPIRED, "ticket has expired")
+	}
+	if !apReq.Authenticator.CName.Equal(apReq.Ticket.DecryptedEncPart.CName) {
+		return nil, k.krbError(errorcode.KRB_AP_ERR_BADMATCH, "authenticator client name does not match ticket")
+	}
+	for _, pa := range req.PAData {
+		if pa.PADataType == patype.PA_FOR_USER {
+			return k.s4u2Self(req, apReq, pa)
+		}
+	}
+	if types.IsFlagSet(&req.ReqBody.KDCOptions, flags.CNameInAdditionalTicket) {
+		return k.s4u2Proxy(req, apReq)
+	}
+	body := req.ReqBody
+	if realm, ok := k.route(body.SName); ok {
+		// Refer the client to the trusted realm with a TGT for it
+		body.SName = types.NewPrincipalName(nametype.KRB_NT_SRV_INST, "krbtgt/"+realm)
+	}
+	return k.tgsRep(apReq.Ticket.DecryptedEncPart.CName, apReq.Ticket.DecryptedEncPart.CRealm, body, req, apReq)
+}
+
+// tgsRep issues a ticket for the client to the service of the request body.
+func (k *KDC) tgsRep(cname types.PrincipalName, crealm string, body messages.KDCReqBody, req messages.TGSReq, apReq messages.APReq) ([]byte, error) {
+	tgtKey := apReq.Ticket.DecryptedEncPart.Key
+	tkt, sessionKey, err := k.newTicket(cname, crealm, body, k.kt, req.ReqBody.EType, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.ReqBody.EncAuthData.Cipher) > 0 {
+		if err := k.copyAuthorizationData(&tkt, req.ReqBody.EncAuthData, apReq.Authenticator.SubKey, tgtKey); err != nil {
+			return nil, err
+		}
+	}
+	encPart, err := k.encPart(tkt, sessionKey, body, asnAppTag.EncTGSRepPart, tgtKey, keyusage.TGS_REP_ENCPART_SESSION_KEY)
+	if err != nil {
+		return nil, err
+	}
+	rep := messages.TGSRep{
+		KDCRepFields: messages.KDCRepFields{
+			PVNO:    iana.PVNO,
+			MsgType: msgtype.KRB_TGS_REP,
+			CRealm:  crealm,
+			CName:   cname,
+			Ticket:  tkt,
+			EncPart: encPart,
+		},
+	}
+	return rep.Marshal()
+}
+
+// s4u2Self issues a ticket to the service presenting the TGT on behalf of the user named in the PA-FOR-USER. The
+// ticket is forwardable only if the service is configured with ConstrainedDelegation.
+func (k *KDC) s4u2Self(req messages.TGSReq, apReq messages.APReq, pa types.PAData) ([]byte, error) {
+	u, err := pa.GetPAForUser()
+	if err != nil {
+		return nil, k.krbError(errorcode.KRB_ERR_GENERIC, "could not unmarshal PA-FOR-USER")
+	}
+	if !u.Verify(apReq.Ticket.DecryptedEncPart.Key) {
+		return nil, k.krbError(errorcode.KRB_AP_ERR_MODIFIED, "PA-FOR-USER checksum is not valid")
+	}
+	service := apReq.Ticket.DecryptedEncPart.CName.PrincipalNameString()
+	if req.ReqBody.SName.PrincipalNameString() != service {
+		return nil, k.krbError(errorcode.KDC_ERR_BADOPTION, "S4U2Self ticket must be for the requesting service")
+	}
+	for _, pa := range req.PAData {
+		switch pa.PADataType {
+		case patype.PA_PAC_REQUEST:
+			pr, err := pa.GetPACRequest()
+			if err != nil {
+				return nil, k.krbError(errorcode.KRB_ERR_GENERIC, "could not unmarshal PA-PAC-REQUEST")
+			}
+			if !pr.IncludePAC && k.requirePAC {
+				return nil, k.krbError(errorcode.KDC_ERR_POLICY, "S4U2Self tickets must include a PAC")
+			}
+		case patype.PA_PAC_OPTIONS:
+			if _, err := pa.GetPACOptions(); err != nil {
+				return nil, k.krbError(errorcode.KRB_ERR_GENERIC, "could not unmarshal PA-PAC-OPTIONS")
+			}
+		}
+	}
+	body := req.ReqBody
+	if _, ok := k.delegation[service]; !ok {
+		types.UnsetFlag(&body.KDCOptions, flags.Forwardable)
+	}
+	return k.tgsRep(u.UserName, u.UserRealm, body, req, apReq)
+}
+
+// s4u2Proxy issues a ticket on behalf of the client of the evidence ticket, if the service presenting the TGT is
+// configured with ConstrainedDelegation to the service requested.
+func (k *KDC) s4u2Proxy(req messages.TGSReq, apReq messages.APReq) ([]byte, error) {
+	if len(req.ReqBody.AdditionalTickets) < 1 {
+		return nil, k.krbError(errorcode.KDC_ERR_BADOPTION, "evidence ticket not provided")
+	}
+	e := req.ReqBody.AdditionalTickets[0]
+	service := apReq.Ticket.DecryptedEncPart.CName.PrincipalNameString()
+	if e.SName.PrincipalNameString() != service {
+		return nil, k.krbError(errorcode.KDC_ERR_BADOPTION, "evidence ticket is not for the requesting service")
+	}
+	if err := e.DecryptEncPart(k.kt, nil); err != nil {
+		return nil, k.krbError(errorcode.KRB_AP_ERR_BAD_INTEGRITY, "could not decrypt evidence ticket")
+	}
+	if !types.IsFlagSet(&e.DecryptedEncPart.Flags, flags.Forwardable) {
+		return nil, k.krbError(errorcode.KDC_ERR_BADOPTION, "evidence ticket is not forwardable")
+	}
+	var allowed bool
+	for _, spn := range k.delegation[service] {
+		if req.ReqBody.SName.PrincipalNameString() == spn {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, k.krbError(errorcode.KDC_ERR_BADOPTION, service+" is not allowed to delegate to "+req.ReqBody.SName.PrincipalNameString())
+	}
+	return k.tgsRep(e.DecryptedEncPart.CName, e.DecryptedEncPart.CRealm, req.ReqBody, req, apReq)
+}
+
+// validate reissues the postdated ticket presented in a VALIDATE request as a valid ticket once its start time has
+// passed. The ticket keeps the times it was issued with.
+func (k *KDC) validate(req messages.TGSReq, apReq messages.APReq, skt *keytab.Keytab) ([]byte, error) {
+	e := apReq.Ticket.DecryptedEncPart
+	if !types.IsFlagSet(&e.Flags, flags.Invalid) {
+		return nil, k.krbError(errorcode.KDC_ERR_BADOPTION, "ticket presented for validation is not invalid")
+	}
+	now := k.now()
+	if e.StartTime.After(now) {
+		return nil, k.krbError(errorcode.KRB_AP_ERR_TKT_NYV, "ticket is not yet valid")
+	}
+	if now.After(e.EndTime) {
+		return nil, k.krbError(errorcode.KRB_AP_ERR_TKT_EXPIRED, "ticket has expired")
+	}
+	sname := apReq.Ticket.SName
+	_, kvno, err := skt.GetEncryptionKey(sname, k.realm, 0, apReq.Ticket.EncPart.EType)
+	if err != nil {
+		return nil, err
+	}
+	f := asn1.BitString{Bytes: append([]byte(nil), e.Flags.Bytes...), BitLength: e.Flags.BitLength}
+	types.UnsetFlag(&f, flags.Invalid)
+	tkt, sessionKey, err := messages.NewTicket(e.CName, e.CRealm, sname, k.realm, f, skt, apReq.Ticket.EncPart.EType, kvno, e.AuthTime, e.StartTime, e.EndTime, e.RenewTill)
+	if err != nil {
+		return nil, err
+	}
+	encPart, err := sealEncPart(messages.EncKDCRepPart{
+		Key:       sessionKey,
+		LastReqs:  []messages.LastReq{},
+		Nonce:     req.ReqBody.Nonce,
+		Flags:     f,
+		AuthTime:  e.AuthTime,
+		StartTime: e.StartTime,
+		EndTime:   e.EndTime,
+		RenewTill: e.RenewTill,
+		SRealm:    tkt.Realm,
+		SName:     tkt.SName,
+		CAddr:     e.CAddr,
+	}, asnAppTag.EncTGSRepPart, e.Key, keyusage.TGS_REP_ENCPART_SESSION_KEY)
+	if err != nil {
+		return nil, err
+	}
+	rep := messages.TGSRep{
+		KDCRepFields: messages.KDCRepFields{
+			PVNO:    iana.PVNO,
+			MsgType: msgtype.KRB_TGS_REP,
+			CRealm:  e.CRealm,
+			CName:   e.CName,
+			Ticket:  tkt,
+			EncPart: encPart,
+		},
+	}
+	return rep.Marshal()
+}
+
+// copyAuthorizationData decrypts the authorization data of a TGS_REQ, with the authenticator's subkey if there is
+// one or otherwise the TGT's session key, and adds it to the ticket issued.
+func (k *KDC) copyAuthorizationData(tkt *messages.Ticket, ed types.EncryptedData, subKey, sessionKey types.EncryptionKey) error {
+	key, usage := sessionKey, uint32(keyusage.TGS_REQ_KDC_REQ_BODY_AUTHDATA_SESSION_KEY)
+	if len(subKey.KeyValue) > 0 {
+		key, usage = subKey, keyusage.TGS_REQ_KDC_REQ_BODY_AUTHDATA_SUB_KEY
+	}
+	b, err := crypto.DecryptEncPart(ed, key, usage)
+	if err != nil {
+		return k.krbError(errorcode.KRB_AP_ERR_BAD_INTEGRITY, "could not decrypt authorization data")
+	}
+	var ad types.AuthorizationData
+	if err := ad.Unmarshal(b); err != nil {
+		return k.krbError(errorcode.KRB_ERR_GENERIC, "could not unmarshal authorization data")
+	}
+	if err := tkt.DecryptEncPart(k.kt, nil); err != nil {
+		return err
+	}
+	tkt.DecryptedEncPart.AuthorizationData = append(tkt.DecryptedEncPart.AuthorizationData, ad...)
+	b, err = asn1.Marshal(tkt.DecryptedEncPart)
+	if err != nil {
+		return err
+	}
+	b = asn1tools.AddASNAppTag(b, asnAppTag.EncTicketPart)
+	skey, _, err := k.kt.GetEncryptionKey(tkt.SName, tkt.Realm, tkt.EncPart.KVNO, tkt.EncPart.EType)
+	if err != nil {
+		return err
+	}
+	tkt.EncPart, err = crypto.GetEncryptedData(b, skey, keyusage.KDC_REP_TICKET, tkt.EncPart.KVNO)
+	tkt.DecryptedEncPart = messages.EncTicketPart{}
+	return err
+}
+
+// route returns the trusted realm that requests for the service principal are to be referred to, if any.
+// Principals with keys held by the KDC are not referred.
+func (k *KDC) route(sname types.PrincipalName) (string, bool) {
+	if len(k.routes) == 0 || len(sname.NameString) < 2 {
+		return "", false
+	}
+	for _, e := range etypePreference {
+		if _, _, err := k.kt.GetEncryptionKey(sname, k.realm, 0, e); err == nil {
+			return "", false
+		}
+	}
+	host := strings.ToLower(sname.NameString[len(sname.NameString)-1])
+	for suffix, realm := range k.routes {
+		if strings.HasSuffix(host, strings.ToLower(suffix)) {
+			return realm, true
+		}
+	}
+	return "", false
+}
+
+// selectKey returns the key for the principal of the first of the requested etypes found in the keytab.
+func (k *KDC) selectKey(kt *keytab.Keytab, pn types.PrincipalName, etypes []int32) (types.EncryptionKey, int32, error) {
+	var known bool
+	for _, et := range etypes {
+		key, _, err := kt.GetEncryptionKey(pn, k.realm, 0, et)
+		if err == nil {
+			return key, et, nil
+		}
+		if !known {
+			// Check if the principal is known at all.
+			for _, e := range etypePreference {
+				if _, _, err := kt.GetEncryptionKey(pn, k.realm, 0, e); err == nil {
+					known = true
+					break
+				}
+			}
+		}
+	}
+	if !known {
+		return types.EncryptionKey{}, 0, k.krbError(errorcode.KDC_ERR_C_PRINCIPAL_UNKNOWN, "principal unknown: "+pn.PrincipalNameString())
+	}
+	return types.EncryptionKey{}, 0, k.krbError(errorcode.KDC_ERR_ETYPE_NOSUPP, "no supported etype")
+}
+
+// verifyPreAuth checks the PA-ENC-TIMESTAMP within the request.
+func (k *KDC) verifyPreAuth(req messages.ASReq, cname types.PrincipalName, etype int32) error {
+	for _, pa := range req.PAData {
+		if pa.PADataType != patype.PA_ENC_TIMESTAMP {
+			continue
+		}
+		var ed types.EncryptedData
+		if err := ed.Unmarshal(pa.PADataValue); err != nil {
+			return k.krbError(errorcode.KDC_ERR_PREAUTH_FAILED, "could not unmarshal PA-ENC-TIMESTAMP")
+		}
+		key, _, err := k.kt.GetEncryptionKey(cname, k.realm, 0, ed.EType)
+		if err != nil {
+			return k.krbError(errorcode.KDC_ERR_PREAUTH_FAILED, "no key for PA-ENC-TIMESTAMP etype")
+		}
+		b, err := crypto.DecryptEncPart(ed, key, keyusage.AS_REQ_PA_ENC_TIMESTAMP)
+		if err != nil {
+			return k.krbError(errorcode.KDC_ERR_PREAUTH_FAILED, "could not decrypt PA-ENC-TIMESTAMP")
+		}
+		var ts types.PAEncTSEnc
+		if err := ts.Unmarshal(b); err != nil {
+			return k.krbError(errorcode.KDC_ERR_PREAUTH_FAILED, "could not unmarshal PA-ENC-TS-ENC")
+		}
+		d := k.now().Sub(ts.PATimestamp)
+		if d < 0 {
+			d = -d
+		}
+		if d > 5*time.Minute {
+			return k.krbError(errorcode.KRB_AP_ERR_SKEW, "clock skew too great")
+		}
+		return nil
+	}
+	e := k.krbError(errorcode.KDC_ERR_PREAUTH_REQUIRED, "pre-authentication required")
+	salt, ok := k.salts[cname.PrincipalNameString()]
+	if !ok {
+		salt = cname.GetSalt(k.realm)
+	}
+	info, err := asn1.Marshal(types.ETypeInfo2{{EType: etype, Salt: salt}})
+	if err != nil {
+		return err
+	}
+	e.EData, err = asn1.Marshal(types.PADataSequence{
+		{PADataType: patype.PA_ETYPE_INFO2, PADataValue: info},
+		{PADataType: patype.PA_ENC_TIMESTAMP},
+	})
+	if err != nil {
+		return err
+	}
+	return e
+}
+
+// newTicket issues a ticket to the client for the service principal requested, encrypted with its key from the keytab provided.
+// Tickets issued by the AS exchange are marked initial.
+func (k *KDC) newTicket(cname types.PrincipalName, crealm string, body messages.KDCReqBody, skt *keytab.Keytab, etypes []int32, initial bool) (messages.Ticket, types.EncryptionKey, error) {
+	_, etype, err := k.selectKey(skt, body.SName, etypes)
+	if err != nil {
+		if e, ok := err.(messages.KRBError); ok && e.ErrorCode == errorcode.KDC_ERR_C_PRINCIPAL_UNKNOWN {
+			return messages.Ticket{}, types.EncryptionKey{}, k.krbError(errorcode.KDC_ERR_S_PRINCIPAL_UNKNOWN, "principal unknown: "+body.SName.PrincipalNameString())
+		}
+		return messages.Ticket{}, types.EncryptionKey{}, err
+	}
+	_, kvno, err := skt.GetEncryptionKey(body.SName, k.realm, 0, etype)
+	if err != nil {
+		return messages.Ticket{}, types.EncryptionKey{}, err
+	}
+	now := k.now()
+	start := startTime(now, body)
+	f := ticketFlags(body, now, initial)
+	return messages.NewTicket(cname, crealm, body.SName, k.realm, f, skt, etype, kvno, now, start, k.endTime(start, body.Till), renewTill(start, body))
+}
+
+// encPart creates the encrypted part of a KDC reply. The flags of the reply to an AS_REQ include the initial flag.
+func (k *KDC) encPart(tkt messages.Ticket, sessionKey types.EncryptionKey, body messages.KDCReqBody, tag int, key types.EncryptionKey, usage uint32) (types.EncryptedData, error) {
+	now := k.now()
+	start := startTime(now, body)
+	return sealEncPart(messages.EncKDCRepPart{
+		Key:       sessionKey,
+		LastReqs:  []messages.LastReq{},
+		Nonce:     body.Nonce,
+		Flags:     ticketFlags(body, now, tag == asnAppTag.EncASRepPart),
+		AuthTime:  now,
+		StartTime: start,
+		EndTime:   k.endTime(start, body.Till),
+		RenewTill: renewTill(start, body),
+		SRealm:    tkt.Realm,
+		SName:     tkt.SName,
+		CAddr:     body.Addresses,
+	}, tag, key, usage)
+}
+
+// sealEncPart marshals and encrypts the encrypted part of a KDC reply.
+func sealEncPart(e messages.EncKDCRepPart, tag int, key types.EncryptionKey, usage uint32) (types.EncryptedData, error) {
+	b, err := asn1.Marshal(e)
+	if err != nil {
+		return types.EncryptedData{}, err
+	}
+	b = asn1tools.AddASNAppTag(b, tag)
+	return crypto.GetEncryptedData(b, key, usage, 0)
+}
+
+func (k *KDC) endTime(start, till time.Time) time.Time {
+	end := start.Add(k.lifetime)
+	if !till.IsZero() && till.Before(end) {
+		end = till
+	}
+	return end
+}
+
+func renewTill(start time.Time, body messages.KDCReqBody) time.Time {
+	if !types.IsFlagSet(&body.KDCOptions, flags.Renewable) {
+		return time.Time{}
+	}
+	return start.Add(7 * 24 * time.Hour)
+}
+
+// startTime returns the time the ticket issued for the request starts, which is the time requested for postdated
+// tickets.
+func startTime(now time.Time, body messages.KDCReqBody) time.Time {
+	if types.IsFlagSet(&body.KDCOptions, flags.PostDated) && body.From.After(now) {
+		return body.From
+	}
+	return now
+}
+
+func ticketFlags(body messages.KDCReqBody, now time.Time, initial bool) asn1.BitString {
+	f := types.NewKrbFlags()
+	if initial {
+		types.SetFlag(&f, flags.Initial)
+	}
+	for _, i := range []int{flags.Forwardable, flags.Proxiable, flags.Renewable, flags.AllowPostDate} {
+		if types.IsFlagSet(&body.KDCOptions, i) {
+			types.SetFlag(&f, i)
+		}
+	}
+	if startTime(now, body).After(now) {
+		// Postdated tickets are issued invalid and must be validated once they have started
+		types.SetFlag(&f, flags.PostDated)
+		types.SetFlag(&f, flags.Invalid)
+	}
+	return f
+}
+
+// newRandomKeytab returns a keytab with random keys for the principal.
+func newRandomKeytab(princ, realm string) (*keytab.Keytab, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	kt := keytab.New()
+	for _, et := range etypePreference {
+		if err := kt.AddEntry(princ, realm, hex.EncodeToString(b), time.Now().UTC(), 1, et); err != nil {
+			return nil, err
+		}
+	}
+	return kt, nil
+}
@@ -0,0 +1,972 @@
+package krbtest
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/asn1"
+	"github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/clock"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/crypto"
+	"github.com/jcmturner/gokrb5/v8/iana/adtype"
+	"github.com/jcmturner/gokrb5/v8/iana/errorcode"
+	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
+	"github.com/jcmturner/gokrb5/v8/iana/flags"
+	"github.com/jcmturner/gokrb5/v8/iana/keyusage"
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/jcmturner/gokrb5/v8/iana/patype"
+	"github.com/jcmturner/gokrb5/v8/kadmin"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/service"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKDC(t *testing.T) {
+	t.Parallel()
+	for _, preAuth := range []bool{false, true} {
+		k, err := NewKDC(Realm, KDCKeytab(), RequirePreAuth(preAuth))
+		require.NoError(t, err, "error starting KDC")
+		defer k.Close()
+
+		clients := map[string]*client.Client{
+			"keytab":   NewClient(k.Config()),
+			"password": client.NewWithPassword(ClientPrincipal, Realm, ClientPassword, k.Config()),
+		}
+		for name, cl := range clients {
+			err = cl.Login()
+			require.NoError(t, err, "%s client login failed (pre-auth %v)", name, preAuth)
+			tkt, _, err := cl.GetServiceTicket(ServicePrincipal)
+			require.NoError(t, err, "%s client could not get service ticket (pre-auth %v)", name, preAuth)
+			err = tkt.DecryptEncPart(ServiceKeytab(), nil)
+			require.NoError(t, err, "service ticket could not be decrypted with the service keytab")
+			assert.Equal(t, ClientPrincipal, tkt.DecryptedEncPart.CName.PrincipalNameString(), "client name in ticket not as expected")
+			assert.Equal(t, Realm, tkt.DecryptedEncPart.CRealm, "client realm in ticket not as expected")
+			cl.Destroy()
+		}
+	}
+}
+
+func TestKDC_WrongPassword(t *testing.T) {
+	t.Parallel()
+	k, err := NewKDC(Realm, KDCKeytab(), RequirePreAuth(true))
+	require.NoError(t, err, "error starting KDC")
+	defer k.Close()
+
+	cl := client.NewWithPassword(ClientPrincipal, Realm, "wrong", k.Config())
+	err = cl.Login()
+	var e messages.KRBError
+	if assert.True(t, errors.As(err, &e), "expected a KRBError: %v", err) {
+		assert.Equal(t, errorcode.KDC_ERR_PREAUTH_FAILED, e.ErrorCode, "error code not as expected")
+	}
+}
+
+func TestKDC_UnknownService(t *testing.T) {
+	t.Parallel()
+	k, err := NewKDC(Realm, KDCKeytab())
+	require.NoError(t, err, "error starting KDC")
+	defer k.Close()
+
+	cl := NewClient(k.Config())
+	require.NoError(t, cl.Login(), "client login failed")
+	_, _, err = cl.GetServiceTicket("HTTP/unknown.test.gokrb5")
+	var e messages.KRBError
+	if assert.True(t, errors.As(err, &e), "expected a KRBError: %v", err) {
+		assert.Equal(t, errorcode.KDC_ERR_S_PRINCIPAL_UNKNOWN, e.ErrorCode, "error code not as expected")
+	}
+}
+
+func TestKDC_PortQualifiedSPN(t *testing.T) {
+	t.Parallel()
+	kt := KDCKeytab()
+	err := kt.AddEntry("MSSQLSvc/db.test.gokrb5:1433", Realm, "dbpassword", time.Now(), 1, etypeID.AES256_CTS_HMAC_SHA1_96)
+	require.NoError(t, err, "error adding port qualified SPN to KDC keytab")
+	k, err := NewKDC(Realm, kt)
+	require.NoError(t, err, "error starting KDC")
+	defer k.Close()
+
+	cl := NewClient(k.Config())
+	require.NoError(t, cl.Login(), "client login failed")
+	tkt, _, err := cl.GetServiceTicket("MSSQLSvc/db.test.gokrb5:1433")
+	require.NoError(t, err, "error getting ticket for port qualified SPN")
+	assert.Equal(t, "MSSQLSvc/db.test.gokrb5:1433", tkt.SName.PrincipalNameString(), "ticket SPN not as expected")
+
+	tkt, _, err = cl.GetServiceTicket(ServicePrincipal + ":8443")
+	require.NoError(t, err, "error getting ticket with fallback to the SPN without the port")
+	assert.Equal(t, ServicePrincipal, tkt.SName.PrincipalNameString(), "ticket should be for the SPN without the port")
+
+	_, _, err = cl.GetServiceTicket("HTTP/unknown.test.gokrb5:8443")
+	assert.Error(t, err, "an unknown SPN should fail with and without the port")
+}
+
+func TestKDC_MachineAccount(t *testing.T) {
+	t.Parallel()
+	name := types.MachineAccountName("web01." + Realm)
+	salt := types.MachineAccountSalt(name, Realm)
+	kt := KDCKeytab()
+	err := kt.AddEntryWithSalt(name, Realm, "machinepassword", salt, time.Now(), 1, etypeID.AES256_CTS_HMAC_SHA1_96)
+	require.NoError(t, err, "error adding machine account to KDC keytab")
+
+	for _, preAuth := range []bool{false, true} {
+		k, err := NewKDC(Realm, kt, RequirePreAuth(preAuth), PrincipalSalt(name, salt))
+		require.NoError(t, err, "error starting KDC")
+		defer k.Close()
+
+		cl := client.NewWithMachinePassword("web01."+Realm, Realm, "machinepassword", k.Config())
+		assert.Equal(t, name, cl.Credentials.UserName(), "machine account name not as expected")
+		assert.False(t, cl.Credentials.Human(), "machine account should not be human")
+		require.NoError(t, cl.Login(), "machine account login with password failed (pre-auth %v)", preAuth)
+		_, _, err = cl.GetServiceTicket(ServicePrincipal)
+		assert.NoError(t, err, "error getting service ticket as machine account")
+
+		cl = client.NewWithMachineKeytab(name, "", kt, k.Config())
+		require.NoError(t, cl.Login(), "machine account login with keytab failed (pre-auth %v)", preAuth)
+	}
+}
+
+func TestKDC_CrossRealm(t *testing.T) {
+	t.Parallel()
+	const other = "OTHER.GOKRB5"
+	const upn = "testuser2@other.gokrb5"
+	otherKt := keytab.New()
+	for _, p := range []string{"testuser2", "HTTP/host.other.gokrb5"} {
+		err := otherKt.AddEntry(p, other, "otherpassword", time.Now(), 1, etypeID.AES256_CTS_HMAC_SHA1_96)
+		require.NoError(t, err, "error creating keytab for %s", other)
+	}
+	a, err := NewKDC(Realm, KDCKeytab(), CrossRealmTrust(other, "trustpassword"),
+		NameSuffixRoute(".other.gokrb5", other), ClientReferral(upn, other))
+	require.NoError(t, err, "error starting KDC")
+	defer a.Close()
+	b, err := NewKDC(other, otherKt, CrossRealmTrust(Realm, "trustpassword"), ClientAlias(upn, "testuser2"))
+	require.NoError(t, err, "error starting KDC for %s", other)
+	defer b.Close()
+	cfg := a.Config()
+	cfg.Realms = append(cfg.Realms, b.Config().Realms...)
+
+	// A client in the local realm is referred to the realm of the service by the name suffix route
+	cl := NewClient(cfg)
+	tkt, _, err := cl.GetServiceTicket("HTTP/host.other.gokrb5")
+	require.NoError(t, err, "error getting ticket for service in the trusted realm")
+	assert.Equal(t, other, tkt.Realm, "ticket should be issued by the trusted realm")
+	require.NoError(t, tkt.DecryptEncPart(otherKt, nil), "service ticket could not be decrypted")
+	assert.Equal(t, Realm, tkt.DecryptedEncPart.CRealm, "client realm in ticket not as expected")
+
+	// A user of the trusted realm logs in with its enterprise name and is referred to its realm
+	kt := keytab.New()
+	kt.Entries = append(kt.Entries, otherKt.Entries[0])
+	kt.Entries[0].Principal.Components = []string{upn}
+	cl = client.NewWithKeytab(upn, Realm, kt, cfg, client.EnterpriseName(true))
+	assert.Equal(t, nametype.KRB_NT_ENTERPRISE, cl.Credentials.CName().NameType, "client name should be an enterprise name")
+	require.NoError(t, cl.Login(), "enterprise name login failed")
+	assert.Equal(t, other, cl.Realm(), "client realm should be that it was referred to")
+	tkt, _, err = cl.GetServiceTicket("HTTP/host.other.gokrb5")
+	require.NoError(t, err, "error getting ticket as a user of the trusted realm")
+	require.NoError(t, tkt.DecryptEncPart(otherKt, nil), "service ticket could not be decrypted")
+	assert.Equal(t, other, tkt.DecryptedEncPart.CRealm, "client realm in ticket not as expected")
+	tkt, _, err = cl.GetServiceTicket(ServicePrincipal)
+	require.NoError(t, err, "error getting ticket for local realm service as a user of the trusted realm")
+	assert.Equal(t, Realm, tkt.Realm, "ticket should be issued by the service's realm")
+}
+
+func TestKDC_MultiRealm(t *testing.T) {
+	t.Parallel()
+	const other = "UNRELATED.GOKRB5"
+	otherKt := keytab.New()
+	for _, p := range []string{"gateway", "HTTP/host.unrelated.gokrb5"} {
+		err := otherKt.AddEntry(p, other, "otherpassword", time.Now(), 1, etypeID.AES256_CTS_HMAC_SHA1_96)
+		require.NoError(t, err, "error creating keytab for %s", other)
+	}
+	a, err := NewKDC(Realm, KDCKeytab())
+	require.NoError(t, err, "error starting KDC")
+	defer a.Close()
+	b, err := NewKDC(other, otherKt)
+	require.NoError(t, err, "error starting KDC for %s", other)
+	defer b.Close()
+	cfg := a.Config()
+	cfg.Realms = append(cfg.Realms, b.Config().Realms...)
+	cfg.DomainRealm[".unrelated.gokrb5"] = other
+
+	m := client.NewMultiRealm(cfg)
+	defer m.Destroy()
+	require.NoError(t, m.Add(NewClient(cfg)), "error adding client for %s", Realm)
+	require.NoError(t, m.Add(client.NewWithPassword("gateway", other, "otherpassword", cfg)), "error adding client for %s", other)
+	assert.Equal(t, []string{Realm, other}, m.Realms(), "realms not as expected")
+
+	tkt, _, err := m.GetServiceTicket("HTTP/host.unrelated.gokrb5")
+	require.NoError(t, err, "error getting ticket for service in %s", other)
+	require.NoError(t, tkt.DecryptEncPart(otherKt, nil), "service ticket could not be decrypted")
+	assert.Equal(t, "gateway", tkt.DecryptedEncPart.CName.PrincipalNameString(), "ticket should be for the client of the service's realm")
+	tkt, _, err = m.GetServiceTicket(ServicePrincipal)
+	require.NoError(t, err, "error getting ticket for service in %s", Realm)
+	require.NoError(t, tkt.DecryptEncPart(ServiceKeytab(), nil), "service ticket could not be decrypted")
+	assert.Equal(t, ClientPrincipal, tkt.DecryptedEncPart.CName.PrincipalNameString(), "ticket should be for the client of the service's realm")
+
+	m.Remove(Realm)
+	cfg.LibDefaults.DefaultRealm = "NONE.GOKRB5"
+	_, err = m.ClientForSPN(ServicePrincipal)
+	assert.Error(t, err, "there should be no client for a removed realm")
+	cl, ok := m.Client(other)
+	require.True(t, ok, "client for %s should remain", other)
+	assert.Equal(t, other, cl.Realm(), "client realm not as expected")
+}
+
+func TestKDC_ClockSkew(t *testing.T) {
+	t.Parallel()
+	k, err := NewKDC(Realm, KDCKeytab(), RequirePreAuth(true), ClockSkew(time.Hour))
+	require.NoError(t, err, "error starting KDC")
+	defer k.Close()
+
+	cl := NewClient(k.Config())
+	require.NoError(t, cl.Login(), "client login should have corrected for the KDC's clock")
+	assert.InDelta(t, float64(time.Hour), float64(cl.TimeOffset()), float64(5*time.Second), "client time offset not as expected")
+
+	// Without pre-authentication the KDC does not report the skew, so the reply is checked against the client's clock
+	k2, err := NewKDC(Realm, KDCKeytab(), ClockSkew(time.Hour))
+	require.NoError(t, err, "error starting KDC")
+	defer k2.Close()
+	cl = NewClient(k2.Config())
+	require.Equal(t, 1, cl.Config.LibDefaults.KDCTimeSync, "kdc_timesync should be enabled by default")
+	assert.Error(t, cl.Login(), "reply outside the clock skew should be rejected")
+}
+
+func TestKDC_Trace(t *testing.T) {
+	t.Parallel()
+	k, err := NewKDC(Realm, KDCKeytab(), RequirePreAuth(true))
+	require.NoError(t, err, "error starting KDC")
+	defer k.Close()
+
+	var b bytes.Buffer
+	cl := NewClient(k.Config(), client.Trace(&b))
+	require.NoError(t, cl.Login(), "client login failed")
+	_, _, err = cl.GetServiceTicket(ServicePrincipal)
+	require.NoError(t, err, "could not get service ticket")
+	_, _, err = cl.GetServiceTicket(ServicePrincipal)
+	require.NoError(t, err, "could not get cached service ticket")
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	line := regexp.MustCompile(`^\[\d+\] \d+\.\d{6}: .+$`)
+	for _, l := range lines {
+		assert.Regexp(t, line, l, "trace line not in the KRB5_TRACE format")
+	}
+	trace := b.String()
+	for _, exp := range []string{
+		"Getting initial credentials for " + ClientPrincipal + "@" + Realm,
+		"Sending unauthenticated request",
+		"Selected KDCs for " + Realm,
+		"Received error from KDC: -1765328359/",
+		"Processing preauth types: ",
+		"Selected etype info: etype aes256-cts-hmac-sha1-96",
+		"Produced preauth for next request: ",
+		"Decrypted AS reply; session key is: ",
+		"Retrieving " + ServicePrincipal + " from cache: not found",
+		"Received creds for desired service " + ServicePrincipal + "@" + Realm,
+		"Retrieving " + ServicePrincipal + " from cache: found",
+	} {
+		assert.Contains(t, trace, exp, "trace output not as expected")
+	}
+}
+
+func TestKDC_ClientAlias(t *testing.T) {
+	t.Parallel()
+	k, err := NewKDC(Realm, KDCKeytab(), ClientAlias("alias1", ClientPrincipal))
+	require.NoError(t, err, "error starting KDC")
+	defer k.Close()
+
+	// The alias's keytab holds the same keys as the canonical name
+	kt := ClientKeytab()
+	for i := range kt.Entries {
+		kt.Entries[i].Principal.Components = []string{"alias1"}
+	}
+	cfg := k.Config()
+	cfg.LibDefaults.Canonicalize = true
+
+	cl := client.NewWithKeytab("alias1", Realm, kt, cfg)
+	require.NoError(t, cl.Login(), "client login with an alias failed")
+	assert.Equal(t, ClientPrincipal, cl.CName().PrincipalNameString(), "canonical client name not recorded")
+	tkt, _, err := cl.GetServiceTicket(ServicePrincipal)
+	require.NoError(t, err, "client could not get service ticket using its canonical name")
+	require.NoError(t, tkt.DecryptEncPart(ServiceKeytab(), nil), "service ticket could not be decrypted")
+	assert.Equal(t, ClientPrincipal, tkt.DecryptedEncPart.CName.PrincipalNameString(), "client name in ticket not as expected")
+
+	cl = client.NewWithKeytab("alias1", Realm, kt, cfg, client.StrictClientName(true))
+	assert.Error(t, cl.Login(), "a canonicalized name should be rejected in strict mode")
+
+	cfg = k.Config()
+	cl = client.NewWithKeytab("alias1", Realm, kt, cfg)
+	require.NoError(t, cl.Login(), "client login with an alias failed")
+	assert.Equal(t, "alias1", cl.CName().PrincipalNameString(), "name should not be canonicalized unless requested")
+}
+
+// testPreAuthPlugin is a pre-authentication plugin that sends an encrypted timestamp using the PA type configured.
+type testPreAuthPlugin struct {
+	paType   int32
+	paData   int32
+	replies  int32
+	replyErr error
+}
+
+func (p *testPreAuthPlugin) PAType() int32 {
+	return p.paType
+}
+
+func (p *testPreAuthPlugin) Offered(hints types.PADataSequence) bool {
+	return true
+}
+
+func (p *testPreAuthPlugin) PAData(cl *client.Client, krberr messages.KRBError, ASReq messages.ASReq) (types.PAData, error) {
+	atomic.AddInt32(&p.paData, 1)
+	et, err := crypto.GetEtype(etypeID.AES256_CTS_HMAC_SHA1_96)
+	if err != nil {
+		return types.PAData{}, err
+	}
+	key, kvno, err := cl.Key(et, 0, &krberr)
+	if err != nil {
+		return types.PAData{}, err
+	}
+	b, err := types.GetPAEncTSEncAsnMarshalledAt(cl.Now())
+	if err != nil {
+		return types.PAData{}, err
+	}
+	ed, err := crypto.GetEncryptedData(b, key, keyusage.AS_REQ_PA_ENC_TIMESTAMP, kvno)
+	if err != nil {
+		return types.PAData{}, err
+	}
+	b, err = ed.Marshal()
+	return types.PAData{PADataType: p.paType, PADataValue: b}, err
+}
+
+func (p *testPreAuthPlugin) ProcessReply(cl *client.Client, ASRep *messages.ASRep) error {
+	atomic.AddInt32(&p.replies, 1)
+	return p.replyErr
+}
+
+func TestKDC_PreAuthPlugin(t *testing.T) {
+	t.Parallel()
+	k, err := NewKDC(Realm, KDCKeytab(), RequirePreAuth(true))
+	require.NoError(t, err, "error starting KDC")
+	defer k.Close()
+
+	p := &testPreAuthPlugin{paType: patype.PA_ENC_TIMESTAMP}
+	cl := NewClient(k.Config(), client.PreAuthPlugins(p))
+	require.NoError(t, cl.Login(), "client login using the pre-authentication plugin failed")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&p.paData), "plugin should have provided the PA-DATA")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&p.replies), "plugin should have processed the reply")
+
+	p = &testPreAuthPlugin{paType: patype.PA_ENC_TIMESTAMP, replyErr: errors.New("rejected")}
+	cl = NewClient(k.Config(), client.PreAuthPlugins(p))
+	assert.Error(t, cl.Login(), "login should fail when the plugin rejects the reply")
+
+	// A mechanism the KDC does not support falls back to those built in to the client
+	p = &testPreAuthPlugin{paType: patype.PA_OTP_REQUEST}
+	cl = NewClient(k.Config(), client.PreAuthPlugins(p))
+	require.NoError(t, cl.Login(), "client login should fall back to encrypted timestamp")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&p.paData), "plugin should have provided the PA-DATA")
+	assert.Equal(t, int32(0), atomic.LoadInt32(&p.replies), "plugin should not process a reply to another mechanism")
+}
+
+func TestKDC_Postdated(t *testing.T) {
+	t.Parallel()
+	k, err := NewKDC(Realm, KDCKeytab())
+	require.NoError(t, err, "error starting KDC")
+	defer k.Close()
+
+	cl := NewClient(k.Config())
+	defer cl.Destroy()
+	from := time.Now().UTC().Add(2 * time.Second).Truncate(time.Second)
+	tgt, skey, err := cl.GetPostdatedTGT(from)
+	require.NoError(t, err, "error getting postdated TGT")
+	dtgt := tgt
+	require.NoError(t, dtgt.DecryptEncPart(k.tgtKeytab, nil), "could not decrypt postdated TGT")
+	assert.True(t, types.IsFlagSet(&dtgt.DecryptedEncPart.Flags, flags.PostDated), "postdated flag not set on TGT")
+	assert.True(t, types.IsFlagSet(&dtgt.DecryptedEncPart.Flags, flags.Invalid), "invalid flag not set on TGT")
+	assert.Equal(t, from, dtgt.DecryptedEncPart.StartTime, "TGT start time not as expected")
+
+	_, _, err = cl.ValidateTicket(tgt, skey)
+	assert.True(t, errors.Is(err, messages.KRBError{ErrorCode: errorcode.KRB_AP_ERR_TKT_NYV}), "validation before the start time should fail: %v", err)
+
+	time.Sleep(time.Until(from.Add(100 * time.Millisecond)))
+	vtgt, _, err := cl.ValidateTicket(tgt, skey)
+	require.NoError(t, err, "error validating TGT")
+	require.NoError(t, vtgt.DecryptEncPart(k.tgtKeytab, nil), "could not decrypt validated TGT")
+	assert.False(t, types.IsFlagSet(&vtgt.DecryptedEncPart.Flags, flags.Invalid), "invalid flag set on validated TGT")
+	assert.Equal(t, from, vtgt.DecryptedEncPart.StartTime, "validated TGT start time not as expected")
+
+	_, _, err = cl.GetServiceTicket(ServicePrincipal)
+	assert.NoError(t, err, "could not get a service ticket with the validated TGT")
+}
+
+func TestKDC_InitCredsOptions(t *testing.T) {
+	t.Parallel()
+	k, err := NewKDC(Realm, KDCKeytab(), RequirePreAuth(true))
+	require.NoError(t, err, "error starting KDC")
+	defer k.Close()
+
+	cl := NewClient(k.Config())
+	defer cl.Destroy()
+	fwd := true
+	rep, err := cl.GetInitCreds(client.InitCredsOptions{TicketLifetime: time.Hour, Forwardable: &fwd, RenewLifetime: -1})
+	require.NoError(t, err, "error getting initial credentials")
+	assert.True(t, types.IsFlagSet(&rep.DecryptedEncPart.Flags, flags.Forwardable), "ticket should be forwardable")
+	assert.False(t, types.IsFlagSet(&rep.DecryptedEncPart.Flags, flags.Renewable), "ticket should not be renewable")
+	assert.WithinDuration(t, time.Now().Add(time.Hour), rep.DecryptedEncPart.EndTime, 5*time.Second, "ticket end time not as expected")
+
+	require.NoError(t, cl.LoginWithOptions(client.InitCredsOptions{TicketLifetime: time.Hour}), "error logging in with options")
+	_, _, err = cl.GetServiceTicket(ServicePrincipal)
+	assert.NoError(t, err, "could not get a service ticket after logging in with options")
+}
+
+func TestKDC_TGSReqOptions(t *testing.T) {
+	t.Parallel()
+	k, err := NewKDC(Realm, KDCKeytab())
+	require.NoError(t, err, "error starting KDC")
+	defer k.Close()
+
+	cl := NewClient(k.Config())
+	defer cl.Destroy()
+	require.NoError(t, cl.Login(), "client login failed")
+	restriction := types.AuthorizationDataEntry{ADType: adtype.ADKDCIssued, ADData: []byte("restricted")}
+	ad, err := types.NewADIfRelevant(restriction)
+	require.NoError(t, err, "error creating AD-IF-RELEVANT")
+	tkt, _, err := cl.GetServiceTicketWithOptions(ServicePrincipal, messages.TGSReqOptions{
+		AuthorizationData: types.AuthorizationData{ad},
+	})
+	require.NoError(t, err, "could not get service ticket with options")
+	require.NoError(t, tkt.DecryptEncPart(ServiceKeytab(), nil), "service ticket could not be decrypted")
+	assert.Equal(t, types.AuthorizationData{ad}, tkt.DecryptedEncPart.AuthorizationData, "authorization data not copied into the ticket")
+
+	_, _, ok := cl.GetCachedTicket(ServicePrincipal)
+	assert.False(t, ok, "ticket requested with options should not be cached")
+}
+
+func TestKDC_S4U(t *testing.T) {
+	t.Parallel()
+	k, err := NewKDC(Realm, KDCKeytab(), ConstrainedDelegation(ClientPrincipal, ServicePrincipal))
+	require.NoError(t, err, "error starting KDC")
+	defer k.Close()
+
+	cl := NewClient(k.Config())
+	defer cl.Destroy()
+	require.NoError(t, cl.Login(), "client login failed")
+	tkt, _, err := cl.GetServiceTicketForUser("delegated@"+Realm, ServicePrincipal)
+	require.NoError(t, err, "could not get service ticket for user")
+	require.NoError(t, tkt.DecryptEncPart(ServiceKeytab(), nil), "service ticket could not be decrypted")
+	assert.Equal(t, "delegated", tkt.DecryptedEncPart.CName.PrincipalNameString(), "ticket not issued for the user")
+	assert.Equal(t, Realm, tkt.DecryptedEncPart.CRealm, "ticket not issued for the user's realm")
+	_, _, ok := cl.GetCachedTicket(ServicePrincipal)
+	assert.False(t, ok, "ticket for a user should not be cached")
+
+	_, _, err = cl.GetServiceTicketForUser("delegated", "HTTP/other.test.gokrb5")
+	var krberr messages.KRBError
+	require.True(t, errors.As(err, &krberr), "delegation to a service not allowed should be refused by the KDC: %v", err)
+	assert.Equal(t, errorcode.KDC_ERR_BADOPTION, krberr.ErrorCode)
+}
+
+func TestKDC_Delegator(t *testing.T) {
+	t.Parallel()
+	k, err := NewKDC(Realm, KDCKeytab(), ConstrainedDelegation(ClientPrincipal, ServicePrincipal, "HTTP/other.test.gokrb5"))
+	require.NoError(t, err, "error starting KDC")
+	defer k.Close()
+	cl := NewClient(k.Config())
+	defer cl.Destroy()
+	require.NoError(t, cl.Login(), "client login failed")
+
+	// The KDC allows delegation to both services but the gateway's policy only to one
+	d := service.NewDelegator(cl, service.DelegationPolicy{TargetSPNs: []string{ServicePrincipal}})
+	user := credentials.NewFromPrincipalName(types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "delegated"), Realm)
+	user.SetAuthenticated(true)
+	tkt, _, err := d.GetServiceTicket(user, ServicePrincipal)
+	require.NoError(t, err, "could not get service ticket for user")
+	require.NoError(t, tkt.DecryptEncPart(ServiceKeytab(), nil), "service ticket could not be decrypted")
+	assert.Equal(t, "delegated", tkt.DecryptedEncPart.CName.PrincipalNameString(), "ticket not issued for the user")
+
+	n := k.Requests()
+	_, _, err = d.GetServiceTicket(user, "HTTP/other.test.gokrb5")
+	assert.True(t, errors.Is(err, service.ErrDelegationNotAllowed), "delegation outside the policy should be refused: %v", err)
+	assert.Equal(t, n, k.Requests(), "KDC should not be asked for a ticket refused by the policy")
+}
+
+func TestKDC_S4UCache(t *testing.T) {
+	t.Parallel()
+	k, err := NewKDC(Realm, KDCKeytab(), ConstrainedDelegation(ClientPrincipal, ServicePrincipal))
+	require.NoError(t, err, "error starting KDC")
+	defer k.Close()
+	cl := NewClient(k.Config())
+	defer cl.Destroy()
+	require.NoError(t, cl.Login(), "client login failed")
+
+	c := client.NewS4UCache(cl, time.Hour, 2)
+	n := k.Requests()
+	tkt, _, err := c.GetServiceTicketForUser("delegated", ServicePrincipal)
+	require.NoError(t, err, "could not get service ticket for user")
+	require.NoError(t, tkt.DecryptEncPart(ServiceKeytab(), nil), "service ticket could not be decrypted")
+	assert.Equal(t, "delegated", tkt.DecryptedEncPart.CName.PrincipalNameString(), "ticket not issued for the user")
+	assert.Equal(t, n+2, k.Requests(), "S4U2Self and S4U2Proxy should be requested")
+	_, _, err = c.GetServiceTicketForUser("delegated@"+Realm, ServicePrincipal)
+	require.NoError(t, err, "could not get service ticket for user")
+	assert.Equal(t, n+2, k.Requests(), "cached ticket should be used")
+	assert.Equal(t, client.S4UCacheStats{Hits: 1, Misses: 2, Entries: 2}, c.Stats())
+
+	// The entries of the first user are evicted to make room for those of the second
+	_, _, err = c.GetServiceTicketForUser("other", ServicePrincipal)
+	require.NoError(t, err, "could not get service ticket for user")
+	assert.Equal(t, client.S4UCacheStats{Hits: 1, Misses: 4, Evictions: 2, Entries: 2}, c.Stats())
+	c.RemoveUser("other")
+	assert.Equal(t, 0, c.Stats().Entries, "entries of the user should be removed")
+
+	// Entries are not used once their TTL has passed
+	c = client.NewS4UCache(cl, time.Nanosecond, 0)
+	d := service.NewCachingDelegator(c, service.DelegationPolicy{TargetSPNs: []string{ServicePrincipal}})
+	user := credentials.NewFromPrincipalName(types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "delegated"), Realm)
+	user.SetAuthenticated(true)
+	for i := 0; i < 2; i++ {
+		_, _, err = d.GetServiceTicket(user, ServicePrincipal)
+		require.NoError(t, err, "could not get service ticket for user")
+	}
+	assert.Equal(t, client.S4UCacheStats{Misses: 4, Expirations: 2, Entries: 2}, c.Stats())
+}
+
+func TestKDC_PrefetchTickets(t *testing.T) {
+	t.Parallel()
+	k, err := NewKDC(Realm, KDCKeytab(), TicketLifetime(3*time.Second))
+	require.NoError(t, err, "error starting KDC")
+	defer k.Close()
+	cl := NewClient(k.Config())
+	defer cl.Destroy()
+	require.NoError(t, cl.Login(), "client login failed")
+
+	p := cl.PrefetchTickets(ServicePrincipal, "HTTP/unknown.test.gokrb5")
+	defer p.Stop()
+	select {
+	case <-p.Ready():
+	case <-time.After(10 * time.Second):
+		t.Fatal("prefetching did not complete")
+	}
+	errs := p.Errors()
+	assert.Len(t, errs, 1, "only the unknown SPN should fail")
+	assert.Error(t, errs["HTTP/unknown.test.gokrb5"], "ticket for an unknown SPN should not be obtained")
+	n := k.Requests()
+	tkt, _, err := cl.GetServiceTicket(ServicePrincipal)
+	require.NoError(t, err, "could not get service ticket")
+	assert.Equal(t, n, k.Requests(), "prefetched ticket should be used")
+
+	// The ticket is replaced before it expires
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if t2, _, ok := cl.GetCachedTicket(ServicePrincipal); ok && !bytes.Equal(t2.EncPart.Cipher, tkt.EncPart.Cipher) {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	assert.True(t, time.Now().Before(deadline), "prefetched ticket was not refreshed")
+}
+
+func TestKDC_S4U_NotTrusted(t *testing.T) {
+	t.Parallel()
+	k, err := NewKDC(Realm, KDCKeytab())
+	require.NoError(t, err, "error starting KDC")
+	defer k.Close()
+
+	cl := NewClient(k.Config())
+	defer cl.Destroy()
+	require.NoError(t, cl.Login(), "client login failed")
+	evidence, err := cl.S4U2Self(types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "delegated"), Realm)
+	require.NoError(t, err, "S4U2Self should succeed for a service not trusted for delegation")
+	assert.Equal(t, "delegated", evidence.CName.PrincipalNameString())
+	assert.False(t, evidence.Forwardable(), "evidence ticket of a service not trusted for delegation should not be forwardable")
+	_, _, err = cl.S4U2Proxy(evidence, ServicePrincipal)
+	assert.Error(t, err, "S4U2Proxy should fail with an evidence ticket that is not forwardable")
+}
+
+func TestKDC_S4U2Self_NoPAC(t *testing.T) {
+	t.Parallel()
+	user := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "delegated")
+	opts := client.S4U2SelfOptions{NoPAC: true, PACOptions: []int{flags.PACOptionClaims}}
+	k, err := NewKDC(Realm, KDCKeytab(), ConstrainedDelegation(ClientPrincipal, ServicePrincipal))
+	require.NoError(t, err, "error starting KDC")
+	defer k.Close()
+	cl := NewClient(k.Config())
+	defer cl.Destroy()
+	require.NoError(t, cl.Login(), "client login failed")
+	evidence, err := cl.S4U2SelfWithOptions(user, Realm, opts)
+	require.NoError(t, err, "S4U2Self without a PAC should succeed")
+	assert.Equal(t, "delegated", evidence.CName.PrincipalNameString())
+	_, _, err = cl.S4U2Proxy(evidence, "HTTP/other.test.gokrb5")
+	var perr client.S4UPolicyError
+	require.True(t, errors.As(err, &perr), "delegation to a service not allowed should be a policy error: %v", err)
+	assert.Equal(t, "S4U2Proxy", perr.Request)
+	assert.Equal(t, errorcode.KDC_ERR_BADOPTION, perr.KRBError.ErrorCode)
+
+	k, err = NewKDC(Realm, KDCKeytab(), RequirePAC(true))
+	require.NoError(t, err, "error starting KDC")
+	defer k.Close()
+	cl = NewClient(k.Config())
+	defer cl.Destroy()
+	require.NoError(t, cl.Login(), "client login failed")
+	_, err = cl.S4U2SelfWithOptions(user, Realm, opts)
+	require.True(t, errors.As(err, &perr), "S4U2Self without a PAC should be refused by KDC policy: %v", err)
+	assert.Equal(t, "S4U2Self", perr.Request)
+	assert.Equal(t, errorcode.KDC_ERR_POLICY, perr.KRBError.ErrorCode)
+	assert.True(t, errors.Is(err, messages.KRBError{ErrorCode: errorcode.KDC_ERR_POLICY}), "KDC error code should be inspectable")
+	_, err = cl.S4U2SelfWithOptions(user, Realm, client.S4U2SelfOptions{PACOptions: []int{flags.PACOptionClaims}})
+	assert.NoError(t, err, "S4U2Self with a PAC should succeed")
+}
+
+func TestKDC_UDPResponseTooBig(t *testing.T) {
+	t.Parallel()
+	k, err := NewKDC(Realm, KDCKeytab())
+	require.NoError(t, err, "error starting KDC")
+	defer k.Close()
+
+	// A KDC on the same port over UDP whose replies are too big for UDP
+	conn, err := net.ListenPacket("udp", k.Addr())
+	require.NoError(t, err, "error listening for UDP requests")
+	defer conn.Close()
+	udpRequests := make(chan struct{}, 10)
+	go func() {
+		b := make([]byte, 65535)
+		for {
+			_, addr, err := conn.ReadFrom(b)
+			if err != nil {
+				return
+			}
+			udpRequests <- struct{}{}
+			e := messages.NewKRBError(types.NewPrincipalName(nametype.KRB_NT_SRV_INST, "krbtgt/"+Realm), Realm, errorcode.KRB_ERR_RESPONSE_TOO_BIG, "response too big")
+			eb, _ := e.Marshal()
+			conn.WriteTo(eb, addr)
+		}
+	}()
+
+	cfg := k.Config()
+	cfg.LibDefaults.UDPPreferenceLimit = 1465
+	cl := client.NewWithPassword(ClientPrincipal, Realm, ClientPassword, cfg)
+	defer cl.Destroy()
+	require.NoError(t, cl.Login(), "login should be retried over TCP")
+	assert.NotZero(t, len(udpRequests), "request not sent over UDP first")
+	assert.NotZero(t, k.Requests(), "request not retried over TCP")
+}
+
+func TestKDC_KpasswdServer(t *testing.T) {
+	t.Parallel()
+	kt := KDCKeytab()
+	require.NoError(t, kt.AddEntry("kadmin/changepw", Realm, "changepwpassword", time.Unix(0, 0), 1, etypeID.AES256_CTS_HMAC_SHA1_96), "error adding kadmin/changepw keys")
+	k, err := NewKDC(Realm, kt)
+	require.NoError(t, err, "error starting KDC")
+	defer k.Close()
+
+	changes := make(chan string, 2)
+	srv := service.NewKpasswdServer(kt, func(c *credentials.Credentials, target types.PrincipalName, targetRealm, newPasswd string) (uint16, string) {
+		changes <- c.UserName() + " " + target.PrincipalNameString() + "@" + targetRealm + " " + newPasswd
+		if newPasswd == "weak" {
+			return kadmin.KRB5_KPASSWD_SOFTERROR, "password is too weak"
+		}
+		return kadmin.KRB5_KPASSWD_SUCCESS, "password changed"
+	})
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "error listening for kpasswd requests")
+	defer l.Close()
+	go srv.Serve(l)
+
+	cfg := k.Config()
+	cfg.Realms[0].KPasswdServer = []string{l.Addr().String()}
+	cl := client.NewWithPassword(ClientPrincipal, Realm, ClientPassword, cfg)
+	ok, err := cl.ChangePasswd("n3wPassw0rd")
+	require.NoError(t, err, "password change failed")
+	assert.True(t, ok, "password change should succeed")
+	select {
+	case c := <-changes:
+		assert.Equal(t, ClientPrincipal+" "+ClientPrincipal+"@"+Realm+" n3wPassw0rd", c, "backend not called with the change requested")
+	default:
+		t.Error("backend not called for the password change")
+	}
+
+	// The KDC's keys are not changed by the backend so the client must log in with its original password again
+	cl = client.NewWithPassword(ClientPrincipal, Realm, ClientPassword, cfg)
+	ok, err = cl.ChangePasswd("weak")
+	assert.False(t, ok, "password change refused by the backend should fail")
+	assert.Error(t, err, "password change refused by the backend should return an error")
+	select {
+	case c := <-changes:
+		assert.Equal(t, ClientPrincipal+" "+ClientPrincipal+"@"+Realm+" weak", c)
+	default:
+		t.Error("backend not called for the password change refused")
+	}
+}
+
+func TestKDC_VerifyUserPassword(t *testing.T) {
+	t.Parallel()
+	k, err := NewKDC(Realm, KDCKeytab(), RequirePreAuth(true))
+	require.NoError(t, err, "error starting KDC")
+	defer k.Close()
+	v := service.NewPasswordVerifier(k.Config(), service.NewSettings(ServiceKeytab(), service.SName(ServicePrincipal)))
+
+	for _, username := range []string{ClientPrincipal, ClientPrincipal + "@" + Realm, Realm + `\` + ClientPrincipal} {
+		creds, err := v.VerifyUserPassword(username, ClientPassword)
+		require.NoError(t, err, "password of %s should be verified", username)
+		assert.True(t, creds.Authenticated(), "credentials should be authenticated")
+		assert.Equal(t, ClientPrincipal, creds.CName().PrincipalNameString(), "client name not as expected")
+		assert.Equal(t, Realm, creds.Realm(), "client realm not as expected")
+	}
+	_, err = v.VerifyUserPassword(ClientPrincipal, "wrong")
+	assert.Error(t, err, "wrong password should not be verified")
+
+	// A KDC that does not share the service's key, as one spoofed by an attacker, cannot issue a ticket the service
+	// can decrypt so the password is not verified even though the login succeeds.
+	spoofed := ClientKeytab()
+	require.NoError(t, spoofed.AddEntry(ServicePrincipal, Realm, "spoofed", time.Unix(0, 0), 1, etypeID.AES256_CTS_HMAC_SHA1_96), "error creating spoofed service key")
+	sk, err := NewKDC(Realm, spoofed)
+	require.NoError(t, err, "error starting KDC")
+	defer sk.Close()
+	v = service.NewPasswordVerifier(sk.Config(), service.NewSettings(ServiceKeytab(), service.SName(ServicePrincipal)))
+	_, err = v.VerifyUserPassword(ClientPrincipal, ClientPassword)
+	assert.Error(t, err, "password should not be verified with a ticket the service cannot decrypt")
+}
+
+func TestKDC_Hooks(t *testing.T) {
+	t.Parallel()
+	k, err := NewKDC(Realm, KDCKeytab(), RequirePreAuth(true))
+	require.NoError(t, err, "error starting KDC")
+	defer k.Close()
+
+	var reqs, reps [][]byte
+	var krbErrs int
+	cl := NewClient(k.Config(),
+		client.KDCRequestHooks(func(realm string, b []byte) ([]byte, error) {
+			assert.Equal(t, Realm, realm, "realm passed to request hook not as expected")
+			reqs = append(reqs, b)
+			return b, nil
+		}),
+		client.KDCReplyHooks(func(realm string, req, rep []byte) {
+			assert.Equal(t, reqs[len(reqs)-1], req, "request passed to reply hook not that sent")
+			var e messages.KRBError
+			if e.Unmarshal(rep) == nil {
+				krbErrs++
+			}
+			reps = append(reps, rep)
+		}),
+	)
+	require.NoError(t, cl.Login(), "client login failed")
+	_, _, err = cl.GetServiceTicket(ServicePrincipal)
+	require.NoError(t, err, "client could not get service ticket")
+	// The pre-authentication required error, the AS exchange with pre-authentication and the TGS exchange
+	assert.Len(t, reqs, 3, "request hook not called for each request")
+	assert.Len(t, reps, 3, "reply hook not called for each reply")
+	assert.Equal(t, 1, krbErrs, "reply hook not called with the KRB_ERROR reply")
+	var asReq messages.ASReq
+	assert.NoError(t, asReq.Unmarshal(reqs[0]), "request hook not passed the marshalled AS_REQ")
+	var tgsRep messages.TGSRep
+	assert.NoError(t, tgsRep.Unmarshal(reps[2]), "reply hook not passed the marshalled TGS_REP")
+
+	hookErr := errors.New("rejected")
+	cl = NewClient(k.Config(), client.KDCRequestHooks(func(realm string, b []byte) ([]byte, error) {
+		return nil, hookErr
+	}))
+	err = cl.Login()
+	assert.True(t, errors.Is(err, hookErr), "login should fail with the error of the request hook: %v", err)
+}
+
+func TestKDC_Events(t *testing.T) {
+	t.Parallel()
+	k, err := NewKDC(Realm, KDCKeytab())
+	require.NoError(t, err, "error starting KDC")
+	defer k.Close()
+
+	var events []client.Event
+	cl := NewClient(k.Config(), client.EventHooks(func(e client.Event) {
+		events = append(events, e)
+	}))
+	require.NoError(t, cl.Login(), "client login failed")
+	_, _, err = cl.GetServiceTicket(ServicePrincipal)
+	require.NoError(t, err, "client could not get service ticket")
+	require.Len(t, events, 2, "ticket acquisitions not reported")
+	for i, sname := range []string{"krbtgt/" + Realm, ServicePrincipal} {
+		assert.Equal(t, client.EventTicketAcquired, events[i].Type, "event type not as expected")
+		assert.Equal(t, ClientPrincipal, events[i].CName, "client name not as expected")
+		assert.Equal(t, sname, events[i].SName, "server name not as expected")
+		assert.False(t, events[i].EndTime.IsZero(), "end time not set")
+	}
+}
+
+func TestKDC_SaveToCCache(t *testing.T) {
+	t.Parallel()
+	k, err := NewKDC(Realm, KDCKeytab())
+	require.NoError(t, err, "error starting KDC")
+	defer k.Close()
+	dir, err := ioutil.TempDir("", "ccache")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cpath := filepath.Join(dir, "krb5cc")
+
+	cl := NewClient(k.Config())
+	require.NoError(t, cl.Login(), "client login failed")
+	tkt, key, err := cl.GetServiceTicket(ServicePrincipal)
+	require.NoError(t, err, "client could not get service ticket")
+	require.NoError(t, cl.SaveToCCache(cpath), "error saving to ccache")
+
+	c, err := credentials.LoadCCache(cpath)
+	require.NoError(t, err, "error loading saved ccache")
+	assert.Len(t, c.GetEntries(), 2, "ccache should hold the TGT and the service ticket")
+
+	var tgsReqs int32
+	cl2, err := client.NewFromCCache(c, k.Config(), client.KDCRequestHooks(func(realm string, b []byte) ([]byte, error) {
+		atomic.AddInt32(&tgsReqs, 1)
+		return b, nil
+	}))
+	require.NoError(t, err, "error creating client from saved ccache")
+	ctkt, ckey, err := cl2.GetServiceTicket(ServicePrincipal)
+	require.NoError(t, err, "client could not get service ticket from ccache")
+	assert.Equal(t, int32(0), atomic.LoadInt32(&tgsReqs), "service ticket should be taken from the ccache")
+	assert.Equal(t, tkt.EncPart.Cipher, ctkt.EncPart.Cipher, "service ticket not as saved")
+	assert.Equal(t, key, ckey, "session key not as saved")
+
+	// The TGT saved can be used to obtain further tickets
+	_, _, err = cl2.GetServiceTicketWithOptions(ServicePrincipal, messages.TGSReqOptions{})
+	require.NoError(t, err, "client could not get service ticket with the saved TGT")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&tgsReqs), "service ticket should be requested with the saved TGT")
+}
+
+func TestKDC_KDCProxy(t *testing.T) {
+	t.Parallel()
+	k, err := NewKDC(Realm, KDCKeytab())
+	require.NoError(t, err, "error starting KDC")
+	defer k.Close()
+
+	// A KDC proxy forwarding the requests it receives to the KDC over TCP. MS-KKDCP
+	type proxyMessage struct {
+		KerbMessage  []byte `asn1:"explicit,tag:0"`
+		TargetDomain string `asn1:"optional,generalstring,explicit,tag:1"`
+	}
+	var domains []string
+	var mux sync.Mutex
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		var m proxyMessage
+		if _, err := asn1.Unmarshal(b, &m); err != nil || r.Header.Get("Content-Type") != "application/kerberos" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		mux.Lock()
+		domains = append(domains, m.TargetDomain)
+		mux.Unlock()
+		conn, err := net.Dial("tcp", k.Addr())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer conn.Close()
+		conn.Write(m.KerbMessage)
+		l := make([]byte, 4)
+		io.ReadFull(conn, l)
+		rb := make([]byte, binary.BigEndian.Uint32(l))
+		io.ReadFull(conn, rb)
+		b, _ = asn1.Marshal(proxyMessage{KerbMessage: append(l, rb...)})
+		w.Header().Set("Content-Type", "application/kerberos")
+		w.Write(b)
+	})
+	// The handshakes rejected by the tests are not logged
+	quiet := log.New(ioutil.Discard, "", 0)
+	proxy := httptest.NewUnstartedServer(handler)
+	proxy.Config.ErrorLog = quiet
+	proxy.StartTLS()
+	defer proxy.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(proxy.Certificate())
+	urls := map[string]string{Realm: proxy.URL + "/KdcProxy"}
+	// The configuration holds no KDCs so requests can only reach the KDC through the proxy
+	cfg := k.Config()
+	cfg.Realms = nil
+
+	p := client.NewKDCProxy(urls, client.ProxyTLSConfig(Realm, &tls.Config{RootCAs: roots}))
+	cl := client.NewWithPassword(ClientPrincipal, Realm, ClientPassword, cfg, client.KDCTransport(p))
+	defer cl.Destroy()
+	require.NoError(t, cl.Login(), "login through the KDC proxy failed")
+	_, _, err = cl.GetServiceTicket(ServicePrincipal)
+	require.NoError(t, err, "error getting service ticket through the KDC proxy")
+	mux.Lock()
+	assert.Equal(t, []string{Realm, Realm}, domains[len(domains)-2:], "target domain of the requests not as expected")
+	mux.Unlock()
+
+	// The default configuration applies to realms without their own
+	p = client.NewKDCProxy(urls, client.ProxyTLSConfig("", &tls.Config{RootCAs: roots}))
+	cl = client.NewWithPassword(ClientPrincipal, Realm, ClientPassword, cfg, client.KDCTransport(p))
+	defer cl.Destroy()
+	assert.NoError(t, cl.Login(), "login with the default TLS configuration failed")
+
+	// The proxy's certificate is not trusted by the system roots or the configuration of another realm
+	for _, p := range []*client.KDCProxy{
+		client.NewKDCProxy(urls),
+		client.NewKDCProxy(urls, client.ProxyTLSConfig("OTHER.REALM", &tls.Config{RootCAs: roots})),
+	} {
+		cl := client.NewWithPassword(ClientPrincipal, Realm, ClientPassword, cfg, client.KDCTransport(p))
+		assert.Error(t, cl.Login(), "proxy with an untrusted certificate should not be used")
+	}
+
+	// The minimum TLS version configured is enforced
+	tls12 := httptest.NewUnstartedServer(handler)
+	tls12.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	tls12.Config.ErrorLog = quiet
+	tls12.StartTLS()
+	defer tls12.Close()
+	roots.AddCert(tls12.Certificate())
+	tls12URLs := map[string]string{Realm: tls12.URL + "/KdcProxy"}
+	p = client.NewKDCProxy(tls12URLs, client.ProxyTLSConfig(Realm, &tls.Config{RootCAs: roots}))
+	cl = client.NewWithPassword(ClientPrincipal, Realm, ClientPassword, cfg, client.KDCTransport(p))
+	defer cl.Destroy()
+	assert.NoError(t, cl.Login(), "proxy supporting TLS 1.2 should be used by default")
+	p = client.NewKDCProxy(tls12URLs, client.ProxyTLSConfig(Realm, &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS13}))
+	cl = client.NewWithPassword(ClientPrincipal, Realm, ClientPassword, cfg, client.KDCTransport(p))
+	assert.Error(t, cl.Login(), "proxy not supporting the minimum TLS version should not be used")
+
+	cl = client.NewWithPassword(ClientPrincipal, "OTHER.REALM", ClientPassword, cfg, client.KDCTransport(p))
+	err = cl.Login()
+	assert.Error(t, err, "realm without a KDC proxy should fail")
+}
+
+func TestKDC_ClientKeytab(t *testing.T) {
+	t.Parallel()
+	// A KDC with a clock two hours behind issues a TGT that has expired by the time the credentials cache is used
+	old, err := NewKDC(Realm, KDCKeytab(), ClockSkew(-2*time.Hour), TicketLifetime(time.Hour))
+	require.NoError(t, err, "error starting KDC")
+	defer old.Close()
+	k, err := NewKDC(Realm, KDCKeytab())
+	require.NoError(t, err, "error starting KDC")
+	defer k.Close()
+
+	cl := NewClient(old.Config(), client.Clock(clock.NewMock(time.Now().Add(-2*time.Hour))))
+	require.NoError(t, cl.Login(), "client login failed")
+	c, err := cl.CCache()
+	require.NoError(t, err, "error getting ccache")
+
+	cl2, err := client.NewFromCCache(c, k.Config())
+	require.NoError(t, err, "error creating client from ccache")
+	_, _, err = cl2.GetServiceTicket(ServicePrincipal)
+	assert.Error(t, err, "a client without a client keytab cannot replace an expired TGT")
+
+	cl2, err = client.NewFromCCache(c, k.Config(), client.ClientKeytab(ClientKeytab()))
+	require.NoError(t, err, "error creating client from ccache")
+	ok, err := cl2.IsConfigured()
+	assert.True(t, ok, "client with a client keytab should be configured: %v", err)
+	_, _, err = cl2.GetServiceTicket(ServicePrincipal)
+	require.NoError(t, err, "client should acquire initial credentials with the client keytab")
+	assert.Equal(t, 2, k.Requests(), "client should have sent an AS_REQ and a TGS_REQ")
+	assert.False(t, cl2.Credentials.HasKeytab(), "client keytab should not be added to the credentials")
+}
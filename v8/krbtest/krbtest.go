@@ -0,0 +1,71 @@
+// Package krbtest provides a mock KDC, canned principals and keytabs, ticket minting and a fake SPNEGO initiator
+// so that Kerberos handling can be unit tested without a live realm.
+package krbtest
+
+import (
+	"encoding/hex"
+
+	"github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/test/testdata"
+)
+
+// Canned principals for which keytabs are provided.
+const (
+	// Realm is the realm of the canned principals.
+	Realm = "TEST.GOKRB5"
+	// ClientPrincipal is the canned client principal's name.
+	ClientPrincipal = "testuser1"
+	// ClientPassword is the canned client principal's password.
+	ClientPassword = testdata.TESTUSER_PASSWORD
+	// ServicePrincipal is the canned service principal's name.
+	ServicePrincipal = "HTTP/host.test.gokrb5"
+)
+
+// etypePreference is the order of preference for encryption types when minting tickets.
+var etypePreference = []int32{
+	etypeID.AES256_CTS_HMAC_SHA1_96,
+	etypeID.AES128_CTS_HMAC_SHA1_96,
+	etypeID.AES256_CTS_HMAC_SHA384_192,
+	etypeID.AES128_CTS_HMAC_SHA256_128,
+	etypeID.DES3_CBC_SHA1_KD,
+	etypeID.RC4_HMAC,
+}
+
+// ClientKeytab returns a keytab containing the keys of the canned client principal.
+func ClientKeytab() *keytab.Keytab {
+	return mustKeytab(testdata.KEYTAB_TESTUSER1_TEST_GOKRB5)
+}
+
+// ServiceKeytab returns a keytab containing the keys of the canned service principal.
+func ServiceKeytab() *keytab.Keytab {
+	return mustKeytab(testdata.HTTP_KEYTAB)
+}
+
+// KDCKeytab returns a keytab containing the keys of both the canned client and service principals,
+// suitable for use with NewKDC.
+func KDCKeytab() *keytab.Keytab {
+	kt := ClientKeytab()
+	kt.Entries = append(kt.Entries, ServiceKeytab().Entries...)
+	return kt
+}
+
+// NewClient returns a client for the canned client principal using its keytab and the configuration provided.
+// The client is not logged in.
+func NewClient(cfg *config.Config, settings ...func(*client.Settings)) *client.Client {
+	return client.NewWithKeytab(ClientPrincipal, Realm, ClientKeytab(), cfg, settings...)
+}
+
+func mustKeytab(h string) *keytab.Keytab {
+	b, err := hex.DecodeString(h)
+	if err != nil {
+		panic("krbtest: invalid canned keytab: " + err.Error())
+	}
+	kt := keytab.New()
+	if err := kt.Unmarshal(b); err != nil {
+		panic("krbtest: invalid canned keytab: " + err.Error())
+	}
+	return kt
+}
@@ -0,0 +1,47 @@
+package krbtest
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+)
+
+// SPNEGOToken returns a base64 encoded SPNEGO NegTokenInit, as sent by an HTTP client, for the client principal to
+// the service principal. The service ticket within it is minted using the keytab provided so no KDC is required.
+func SPNEGOToken(cname, realm, spn string, kt *keytab.Keytab) (string, error) {
+	tkt, key, err := NewServiceTicket(cname, realm, spn, kt, time.Hour)
+	if err != nil {
+		return "", err
+	}
+	cl := client.NewWithPassword(cname, realm, "", config.New())
+	nt, err := spnego.NewNegTokenInitKRB5(cl, tkt, key)
+	if err != nil {
+		return "", fmt.Errorf("could not create NegTokenInit: %w", err)
+	}
+	st := spnego.SPNEGOToken{
+		Init:         true,
+		NegTokenInit: nt,
+	}
+	b, err := st.Marshal()
+	if err != nil {
+		return "", fmt.Errorf("could not marshal SPNEGO token: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// SetSPNEGOHeader sets a SPNEGO authorization header on the HTTP request as an authenticated client principal would.
+// The service ticket within it is minted using the keytab provided so no KDC is required.
+func SetSPNEGOHeader(r *http.Request, cname, realm, spn string, kt *keytab.Keytab) error {
+	t, err := SPNEGOToken(cname, realm, spn, kt)
+	if err != nil {
+		return err
+	}
+	r.Header.Set(spnego.HTTPHeaderAuthRequest, spnego.HTTPHeaderAuthResponseValueKey+" "+t)
+	return nil
+}
@@ -0,0 +1,34 @@
+package krbtest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jcmturner/goidentity/v6"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetSPNEGOHeader(t *testing.T) {
+	t.Parallel()
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := goidentity.FromHTTPRequestContext(r)
+		fmt.Fprint(w, id.UserName())
+	})
+	s := httptest.NewServer(spnego.SPNEGOKRB5Authenticate(inner, ServiceKeytab()))
+	defer s.Close()
+
+	r, _ := http.NewRequest("GET", s.URL, nil)
+	err := SetSPNEGOHeader(r, ClientPrincipal, Realm, ServicePrincipal, ServiceKeytab())
+	require.NoError(t, err, "error setting SPNEGO header")
+	resp, err := http.DefaultClient.Do(r)
+	require.NoError(t, err, "error sending request")
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "status code not as expected")
+
+	_, err = SPNEGOToken(ClientPrincipal, Realm, "HTTP/unknown.test.gokrb5", ServiceKeytab())
+	assert.Error(t, err, "minting a ticket for a service not in the keytab should fail")
+}
@@ -0,0 +1,31 @@
+package krbtest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/iana/flags"
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// NewServiceTicket mints a ticket for the client principal to the service principal without a KDC.
+// The ticket is encrypted with the strongest of the service's keys found in the keytab provided.
+// The ticket and its session key are returned.
+func NewServiceTicket(cname, realm, spn string, kt *keytab.Keytab, lifetime time.Duration) (messages.Ticket, types.EncryptionKey, error) {
+	cn := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, cname)
+	sn := types.NewPrincipalName(nametype.KRB_NT_SRV_INST, spn)
+	for _, et := range etypePreference {
+		_, kvno, err := kt.GetEncryptionKey(sn, realm, 0, et)
+		if err != nil {
+			continue
+		}
+		now := time.Now().UTC().Truncate(time.Second)
+		f := types.NewKrbFlags()
+		types.SetFlag(&f, flags.Forwardable)
+		return messages.NewTicket(cn, realm, sn, realm, f, kt, et, kvno, now, now, now.Add(lifetime), time.Time{})
+	}
+	return messages.Ticket{}, types.EncryptionKey{}, fmt.Errorf("no supported key for %s@%s in keytab", spn, realm)
+}
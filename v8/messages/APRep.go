@@ -4,8 +4,12 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/jcmturner/gofork/encoding/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1tools"
+	"github.com/jcmturner/gokrb5/v8/crypto"
+	"github.com/jcmturner/gokrb5/v8/iana"
 	"github.com/jcmturner/gokrb5/v8/iana/asnAppTag"
+	"github.com/jcmturner/gokrb5/v8/iana/keyusage"
 	"github.com/jcmturner/gokrb5/v8/iana/msgtype"
 	"github.com/jcmturner/gokrb5/v8/krberror"
 	"github.com/jcmturner/gokrb5/v8/types"
@@ -13,9 +17,10 @@ import (
 
 // APRep implements RFC 4120 KRB_AP_REP: https://tools.ietf.org/html/rfc4120#section-5.5.2.
 type APRep struct {
-	PVNO    int                 `asn1:"explicit,tag:0"`
-	MsgType int                 `asn1:"explicit,tag:1"`
-	EncPart types.EncryptedData `asn1:"explicit,tag:2"`
+	PVNO             int                 `asn1:"explicit,tag:0"`
+	MsgType          int                 `asn1:"explicit,tag:1"`
+	EncPart          types.EncryptedData `asn1:"explicit,tag:2"`
+	DecryptedEncPart EncAPRepPart        `asn1:"optional,omitempty"` // Not part of ASN1 bytes so marked as optional so unmarshalling works
 }
 
 // EncAPRepPart is the encrypted part of KRB_AP_REP.
@@ -26,6 +31,15 @@ type EncAPRepPart struct {
 	SequenceNumber int64               `asn1:"optional,explicit,tag:3"`
 }
 
+// NewAPRep returns a new APRep type.
+func NewAPRep(part EncAPRepPart) APRep {
+	return APRep{
+		PVNO:             iana.PVNO,
+		MsgType:          msgtype.KRB_AP_REP,
+		DecryptedEncPart: part,
+	}
+}
+
 // Unmarshal bytes b into the APRep struct.
 func (a *APRep) Unmarshal(b []byte) error {
 	_, err := asn1.UnmarshalWithParams(b, a, fmt.Sprintf("application,explicit,tag:%v", asnAppTag.APREP))
@@ -47,3 +61,85 @@ func (a *EncAPRepPart) Unmarshal(b []byte) error {
 	}
 	return nil
 }
+
+// Marshal the APRep.
+func (a *APRep) Marshal() ([]byte, error) {
+	ta := APRep{
+		PVNO:    a.PVNO,
+		MsgType: a.MsgType,
+		EncPart: a.EncPart,
+	}
+	b, err := asn1.Marshal(ta)
+	if err != nil {
+		return []byte{}, krberror.Errorf(err, krberror.EncodingError, "error marshaling AP_REP")
+	}
+	b = asn1tools.AddASNAppTag(b, asnAppTag.APREP)
+	return b, nil
+}
+
+// Marshal the APRep encrypted part.
+func (a *EncAPRepPart) Marshal() ([]byte, error) {
+	b, err := asn1.Marshal(*a)
+	if err != nil {
+		return []byte{}, krberror.Errorf(err, krberror.EncodingError, "error marshaling AP_REP encrypted part")
+	}
+	b = asn1tools.AddASNAppTag(b, asnAppTag.EncAPRepPart)
+	return b, nil
+}
+
+// EncryptEncPart encrypts the DecryptedEncPart within the APRep using the session key of the AP exchange.
+// Use to prepare for marshaling.
+func (a *APRep) EncryptEncPart(sessionKey types.EncryptionKey) error {
+	b, err := a.DecryptedEncPart.Marshal()
+	if err != nil {
+		return err
+	}
+	a.EncPart, err = crypto.GetEncryptedData(b, sessionKey, keyusage.AP_REP_ENCPART, 0)
+	if err != nil {
+		return krberror.Errorf(err, krberror.EncryptingError, "error encrypting AP_REP encrypted part")
+	}
+	return nil
+}
+
+// DecryptEncPart decrypts the encrypted part of the APRep using the session key of the AP exchange.
+func (a *APRep) DecryptEncPart(sessionKey types.EncryptionKey) error {
+	b, err := crypto.DecryptEncPart(a.EncPart, sessionKey, keyusage.AP_REP_ENCPART)
+	if err != nil {
+		return fmt.Errorf("error decrypting AP_REP EncPart: %w", err)
+	}
+	err = a.DecryptedEncPart.Unmarshal(b)
+	if err != nil {
+		return fmt.Errorf("error unmarshaling encrypted part: %w", err)
+	}
+	return nil
+}
+
+// VerifyAuthenticator checks that the decrypted encrypted part of the APRep echoes the time fields of the
+// authenticator sent in the AP_REQ, as required for mutual authentication.
+// The CTime is compared to the second as this is the precision it is transmitted with.
+func (a *APRep) VerifyAuthenticator(auth types.Authenticator) error {
+	if a.DecryptedEncPart.CTime.Unix() != auth.CTime.Unix() || a.DecryptedEncPart.Cusec != auth.Cusec {
+		return krberror.NewErrorf(krberror.KRBMsgError, "AP_REP does not match the authenticator sent, mutual authentication failed")
+	}
+	return nil
+}
+
+// String returns a description of the AP_REP for diagnostics. Secret values are not included.
+func (a APRep) String() string {
+	d := newDump("AP_REP")
+	d.field("PVNO", a.PVNO)
+	d.field("EncPart", a.EncPart)
+	if !a.DecryptedEncPart.CTime.IsZero() {
+		p := newDump("EncAPRepPart")
+		p.time("CTime", a.DecryptedEncPart.CTime)
+		p.field("Cusec", a.DecryptedEncPart.Cusec)
+		if a.DecryptedEncPart.Subkey.KeyType != 0 {
+			p.field("Subkey", a.DecryptedEncPart.Subkey)
+		}
+		if a.DecryptedEncPart.SequenceNumber != 0 {
+			p.field("SequenceNumber", a.DecryptedEncPart.SequenceNumber)
+		}
+		d.nested("DecryptedEncPart", p.String())
+	}
+	return d.String()
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/jcmturner/gokrb5/v8/iana"
 	"github.com/jcmturner/gokrb5/v8/iana/msgtype"
 	"github.com/jcmturner/gokrb5/v8/test/testdata"
+	"github.com/jcmturner/gokrb5/v8/types"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -67,3 +68,70 @@ func TestUnmarshalEncAPRepPart_optionalsNULL(t *testing.T) {
 	assert.Equal(t, tt, a.CTime, "CTime not as expected")
 	assert.Equal(t, 123456, a.Cusec, "Client microseconds not as expected")
 }
+
+func TestMarshalAPRep(t *testing.T) {
+	t.Parallel()
+	var a APRep
+	b, err := hex.DecodeString(testdata.MarshaledKRB5ap_rep)
+	if err != nil {
+		t.Fatalf("Test vector read error: %v", err)
+	}
+	err = a.Unmarshal(b)
+	if err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	mb, err := a.Marshal()
+	if err != nil {
+		t.Fatalf("error marshaling APRep: %v", err)
+	}
+	assert.Equal(t, b, mb, "marshaled bytes not as expected")
+
+	be, err := hex.DecodeString(testdata.MarshaledKRB5ap_rep_enc_part)
+	if err != nil {
+		t.Fatalf("Test vector read error: %v", err)
+	}
+	var e EncAPRepPart
+	err = e.Unmarshal(be)
+	if err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	mb, err = e.Marshal()
+	if err != nil {
+		t.Fatalf("error marshaling EncAPRepPart: %v", err)
+	}
+	assert.Equal(t, be, mb, "marshaled encrypted part bytes not as expected")
+}
+
+func TestAPRep_EncryptDecryptEncPart(t *testing.T) {
+	t.Parallel()
+	key := types.EncryptionKey{
+		KeyType:  int32(18),
+		KeyValue: []byte("12345678901234567890123456789012"),
+	}
+	auth := types.Authenticator{
+		CTime: time.Now().UTC(),
+		Cusec: 123456,
+	}
+	a := NewAPRep(EncAPRepPart{CTime: auth.CTime, Cusec: auth.Cusec})
+	err := a.EncryptEncPart(key)
+	if err != nil {
+		t.Fatalf("error encrypting encpart: %v", err)
+	}
+	b, err := a.Marshal()
+	if err != nil {
+		t.Fatalf("error marshaling APRep: %v", err)
+	}
+	var r APRep
+	err = r.Unmarshal(b)
+	if err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	err = r.DecryptEncPart(key)
+	if err != nil {
+		t.Fatalf("error decrypting encpart: %v", err)
+	}
+	assert.NoError(t, r.VerifyAuthenticator(auth), "AP_REP should match the authenticator")
+
+	auth.Cusec++
+	assert.Error(t, r.VerifyAuthenticator(auth), "AP_REP should not match a different authenticator")
+}
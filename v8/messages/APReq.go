@@ -4,12 +4,13 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/jcmturner/gofork/encoding/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1"
 	"github.com/jcmturner/gokrb5/v8/asn1tools"
 	"github.com/jcmturner/gokrb5/v8/crypto"
 	"github.com/jcmturner/gokrb5/v8/iana"
 	"github.com/jcmturner/gokrb5/v8/iana/asnAppTag"
 	"github.com/jcmturner/gokrb5/v8/iana/errorcode"
+	"github.com/jcmturner/gokrb5/v8/iana/flags"
 	"github.com/jcmturner/gokrb5/v8/iana/keyusage"
 	"github.com/jcmturner/gokrb5/v8/iana/msgtype"
 	"github.com/jcmturner/gokrb5/v8/keytab"
@@ -74,11 +75,11 @@ func (a *APReq) DecryptAuthenticator(sessionKey types.EncryptionKey) error {
 	usage := authenticatorKeyUsage(a.Ticket.SName)
 	ab, e := crypto.DecryptEncPart(a.EncryptedAuthenticator, sessionKey, uint32(usage))
 	if e != nil {
-		return fmt.Errorf("error decrypting authenticator: %v", e)
+		return fmt.Errorf("error decrypting authenticator: %w", e)
 	}
 	err := a.Authenticator.Unmarshal(ab)
 	if err != nil {
-		return fmt.Errorf("error unmarshaling authenticator: %v", err)
+		return fmt.Errorf("error unmarshaling authenticator: %w", err)
 	}
 	return nil
 }
@@ -92,6 +93,9 @@ func authenticatorKeyUsage(pn types.PrincipalName) int {
 
 // Unmarshal bytes b into the APReq struct.
 func (a *APReq) Unmarshal(b []byte) error {
+	if err := asn1tools.CheckLimits(b, asn1tools.DefaultLimits()); err != nil {
+		return krberror.Errorf(err, krberror.EncodingError, "unmarshal error of AP_REQ")
+	}
 	var m marshalAPReq
 	_, err := asn1.UnmarshalWithParams(b, &m, fmt.Sprintf("application,explicit,tag:%v", asnAppTag.APREQ))
 	if err != nil {
@@ -141,6 +145,12 @@ func (a *APReq) Marshal() ([]byte, error) {
 // Verify an AP_REQ using service's keytab, spn and max acceptable clock skew duration.
 // The service ticket encrypted part and authenticator will be decrypted as part of this operation.
 func (a *APReq) Verify(kt *keytab.Keytab, d time.Duration, cAddr types.HostAddress, snameOverride *types.PrincipalName) (bool, error) {
+	return a.VerifyAt(kt, d, cAddr, snameOverride, time.Now().UTC())
+}
+
+// VerifyAt verifies an AP_REQ as Verify does but checks the ticket's validity and the clock skew with the client
+// against the time provided rather than the local clock.
+func (a *APReq) VerifyAt(kt *keytab.Keytab, d time.Duration, cAddr types.HostAddress, snameOverride *types.PrincipalName, t time.Time) (bool, error) {
 	// Decrypt ticket's encrypted part with service key
 	//TODO decrypt with service's session key from its TGT is use-to-user. Need to figure out how to get TGT.
 	//if types.IsFlagSet(&a.APOptions, flags.APOptionUseSessionKey) {
@@ -164,7 +174,7 @@ func (a *APReq) Verify(kt *keytab.Keytab, d time.Duration, cAddr types.HostAddre
 	}
 
 	// Check time validity of ticket
-	ok, err := a.Ticket.Valid(d)
+	ok, err := a.Ticket.ValidAt(d, t)
 	if err != nil || !ok {
 		return ok, err
 	}
@@ -191,9 +201,44 @@ func (a *APReq) Verify(kt *keytab.Keytab, d time.Duration, cAddr types.HostAddre
 
 	// Check the clock skew between the client and the service server
 	ct := a.Authenticator.CTime.Add(time.Duration(a.Authenticator.Cusec) * time.Microsecond)
-	t := time.Now().UTC()
 	if t.Sub(ct) > d || ct.Sub(t) > d {
 		return false, NewKRBError(a.Ticket.SName, a.Ticket.Realm, errorcode.KRB_AP_ERR_SKEW, fmt.Sprintf("clock skew with client too large. greater than %v seconds", d))
 	}
 	return true, nil
 }
+
+// String returns a description of the AP_REQ for diagnostics. Secret values are not included.
+func (a APReq) String() string {
+	d := newDump("AP_REQ")
+	d.field("PVNO", a.PVNO)
+	d.field("APOptions", types.FlagNames(a.APOptions, flags.APOptionNames))
+	d.nested("Ticket", a.Ticket.String())
+	d.field("EncryptedAuthenticator", a.EncryptedAuthenticator)
+	if !a.Authenticator.CTime.IsZero() {
+		d.nested("Authenticator", authenticatorString(a.Authenticator))
+	}
+	return d.String()
+}
+
+// authenticatorString returns a description of the Authenticator for diagnostics. Any subkey is redacted.
+func authenticatorString(a types.Authenticator) string {
+	d := newDump("Authenticator")
+	d.field("AVNO", a.AVNO)
+	d.field("CRealm", a.CRealm)
+	d.principal("CName", a.CName)
+	if a.Cksum.CksumType != 0 {
+		d.field("Cksum", fmt.Sprintf("type %d, %d bytes", a.Cksum.CksumType, len(a.Cksum.Checksum)))
+	}
+	d.time("CTime", a.CTime)
+	d.field("Cusec", a.Cusec)
+	if a.SubKey.KeyType != 0 {
+		d.field("SubKey", a.SubKey)
+	}
+	if a.SeqNumber != 0 {
+		d.field("SeqNumber", a.SeqNumber)
+	}
+	for _, ad := range a.AuthorizationData {
+		d.field("AuthorizationData", fmt.Sprintf("type %d, %d bytes", ad.ADType, len(ad.ADData)))
+	}
+	return d.String()
+}
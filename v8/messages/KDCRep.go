@@ -7,7 +7,7 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/jcmturner/gofork/encoding/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1"
 	"github.com/jcmturner/gokrb5/v8/asn1tools"
 	"github.com/jcmturner/gokrb5/v8/config"
 	"github.com/jcmturner/gokrb5/v8/credentials"
@@ -217,13 +217,18 @@ func (k *ASRep) DecryptEncPart(c *credentials.Credentials) (types.EncryptionKey,
 	var key types.EncryptionKey
 	var err error
 	if c.HasKeytab() {
-		key, _, err = c.Keytab().GetEncryptionKey(k.CName, k.CRealm, k.EncPart.KVNO, k.EncPart.EType)
+		// The keys are held under the name requested, which the KDC may have replied with a canonical form of
+		key, _, err = c.Keytab().GetEncryptionKey(c.CName(), k.CRealm, k.EncPart.KVNO, k.EncPart.EType)
 		if err != nil {
 			return key, krberror.Errorf(err, krberror.DecryptingError, "error decrypting AS_REP encrypted part")
 		}
 	}
 	if c.HasPassword() {
-		key, _, err = crypto.GetKeyFromPassword(c.Password(), k.CName, k.CRealm, k.EncPart.EType, k.PAData)
+		salt := c.Salt()
+		if salt == "" {
+			salt = k.CName.GetSalt(k.CRealm)
+		}
+		key, _, err = crypto.GetKeyFromPasswordSalt(c.Password(), salt, k.EncPart.EType, k.PAData)
 		if err != nil {
 			return key, krberror.Errorf(err, krberror.DecryptingError, "error decrypting AS_REP encrypted part")
 		}
@@ -246,11 +251,21 @@ func (k *ASRep) DecryptEncPart(c *credentials.Credentials) (types.EncryptionKey,
 
 // Verify checks the validity of AS_REP message.
 func (k *ASRep) Verify(cfg *config.Config, creds *credentials.Credentials, asReq ASReq) (bool, error) {
+	return k.VerifyAt(cfg, creds, asReq, time.Now().UTC())
+}
+
+// VerifyAt checks the validity of AS_REP message, checking the clock skew with the KDC against the time provided. When
+// kdc_timesync is enabled the time should be corrected for the offset with the KDC's clock.
+//
+// The CName in the response may differ from that requested if the AS_REQ asked the KDC to canonicalize the client's
+// name, or the name requested is an enterprise name. The name is then accepted as the KDC's canonical form of the
+// client's name as it is only trusted once the encrypted part has been decrypted with the client's key.
+func (k *ASRep) VerifyAt(cfg *config.Config, creds *credentials.Credentials, asReq ASReq, t time.Time) (bool, error) {
 	//Ref RFC 4120 Section 3.1.5
-	if !k.CName.Equal(asReq.ReqBody.CName) {
+	if !k.CName.Equal(asReq.ReqBody.CName) && !asReq.CanonicalizeCName() {
 		return false, krberror.NewErrorf(krberror.KRBMsgError, "CName in response does not match what was requested. Requested: %+v; Reply: %+v", asReq.ReqBody.CName, k.CName)
 	}
-	if k.CRealm != asReq.ReqBody.Realm {
+	if !cfg.RealmEqual(k.CRealm, asReq.ReqBody.Realm) {
 		return false, krberror.NewErrorf(krberror.KRBMsgError, "CRealm in response does not match what was requested. Requested: %s; Reply: %s", asReq.ReqBody.Realm, k.CRealm)
 	}
 	key, err := k.DecryptEncPart(creds)
@@ -263,7 +278,7 @@ func (k *ASRep) Verify(cfg *config.Config, creds *credentials.Credentials, asReq
 	if !k.DecryptedEncPart.SName.Equal(asReq.ReqBody.SName) {
 		return false, krberror.NewErrorf(krberror.KRBMsgError, "SName in response does not match what was requested. Requested: %v; Reply: %v", asReq.ReqBody.SName, k.DecryptedEncPart.SName)
 	}
-	if k.DecryptedEncPart.SRealm != asReq.ReqBody.Realm {
+	if !cfg.RealmEqual(k.DecryptedEncPart.SRealm, asReq.ReqBody.Realm) {
 		return false, krberror.NewErrorf(krberror.KRBMsgError, "SRealm in response does not match what was requested. Requested: %s; Reply: %s", asReq.ReqBody.Realm, k.DecryptedEncPart.SRealm)
 	}
 	if len(asReq.ReqBody.Addresses) > 0 {
@@ -271,7 +286,6 @@ func (k *ASRep) Verify(cfg *config.Config, creds *credentials.Credentials, asReq
 			return false, krberror.NewErrorf(krberror.KRBMsgError, "addresses listed in the AS_REP does not match those listed in the AS_REQ")
 		}
 	}
-	t := time.Now().UTC()
 	if t.Sub(k.DecryptedEncPart.AuthTime) > cfg.LibDefaults.Clockskew || k.DecryptedEncPart.AuthTime.Sub(t) > cfg.LibDefaults.Clockskew {
 		return false, krberror.NewErrorf(krberror.KRBMsgError, "clock skew with KDC too large. Greater than %v seconds", cfg.LibDefaults.Clockskew.Seconds())
 	}
@@ -318,10 +332,39 @@ func (k *TGSRep) DecryptEncPart(key types.EncryptionKey) error {
 
 // Verify checks the validity of the TGS_REP message.
 func (k *TGSRep) Verify(cfg *config.Config, tgsReq TGSReq) (bool, error) {
-	if !k.CName.Equal(tgsReq.ReqBody.CName) {
-		return false, krberror.NewErrorf(krberror.KRBMsgError, "CName in response does not match what was requested. Requested: %+v; Reply: %+v", tgsReq.ReqBody.CName, k.CName)
+	return k.VerifyAt(cfg, tgsReq, time.Now().UTC())
+}
+
+// VerifyAt checks the validity of the TGS_REP message, checking the clock skew with the KDC against the time provided.
+// When kdc_timesync is enabled the time should be corrected for the offset with the KDC's clock.
+func (k *TGSRep) VerifyAt(cfg *config.Config, tgsReq TGSReq, t time.Time) (bool, error) {
+	return k.VerifyQuirks(cfg, tgsReq, t, Quirks{})
+}
+
+// VerifyQuirks checks the validity of the TGS_REP message as VerifyAt, relaxing the checks as the quirks provided
+// allow.
+//
+// The reply to an S4U2Self request must be for the user named in its PA-FOR-USER. The client of the reply to an
+// S4U2Proxy request is that of the evidence ticket, which the caller must check.
+func (k *TGSRep) VerifyQuirks(cfg *config.Config, tgsReq TGSReq, t time.Time, q Quirks) (bool, error) {
+	cname := tgsReq.ReqBody.CName
+	for _, pa := range tgsReq.PAData {
+		if pa.PADataType != patype.PA_FOR_USER {
+			continue
+		}
+		u, err := pa.GetPAForUser()
+		if err != nil {
+			return false, krberror.Errorf(err, krberror.EncodingError, "error unmarshaling PA-FOR-USER of request")
+		}
+		if !cfg.RealmEqual(k.CRealm, u.UserRealm) {
+			return false, krberror.NewErrorf(krberror.KRBMsgError, "CRealm in response does not match the user requested. Requested: %s; Reply: %s", u.UserRealm, k.CRealm)
+		}
+		cname = u.UserName
+	}
+	if !types.IsFlagSet(&tgsReq.ReqBody.KDCOptions, flags.CNameInAdditionalTicket) && !k.CName.Equal(cname) {
+		return false, krberror.NewErrorf(krberror.KRBMsgError, "CName in response does not match what was requested. Requested: %+v; Reply: %+v", cname, k.CName)
 	}
-	if k.Ticket.Realm != tgsReq.ReqBody.Realm {
+	if !cfg.RealmEqual(k.Ticket.Realm, tgsReq.ReqBody.Realm) {
 		return false, krberror.NewErrorf(krberror.KRBMsgError, "realm in response ticket does not match what was requested. Requested: %s; Reply: %s", tgsReq.ReqBody.Realm, k.Ticket.Realm)
 	}
 	if k.DecryptedEncPart.Nonce != tgsReq.ReqBody.Nonce {
@@ -343,18 +386,76 @@ func (k *TGSRep) Verify(cfg *config.Config, tgsReq TGSReq) (bool, error) {
 	//		return false, krberror.NewErrorf(krberror.KRBMsgError, "SName in response does not match what was requested. Requested: %+v; Reply: %+v", tgsReq.ReqBody.SName, k.DecryptedEncPart.SName)
 	//	}
 	//}
-	if k.DecryptedEncPart.SRealm != tgsReq.ReqBody.Realm {
+	if !cfg.RealmEqual(k.DecryptedEncPart.SRealm, tgsReq.ReqBody.Realm) {
 		return false, krberror.NewErrorf(krberror.KRBMsgError, "SRealm in response does not match what was requested. Requested: %s; Reply: %s", tgsReq.ReqBody.Realm, k.DecryptedEncPart.SRealm)
 	}
-	if len(k.DecryptedEncPart.CAddr) > 0 {
+	if len(k.DecryptedEncPart.CAddr) > 0 && !(q.CopiedAddresses && len(tgsReq.ReqBody.Addresses) == 0) {
 		if !types.HostAddressesEqual(k.DecryptedEncPart.CAddr, tgsReq.ReqBody.Addresses) {
 			return false, krberror.NewErrorf(krberror.KRBMsgError, "addresses listed in the TGS_REP does not match those listed in the TGS_REQ")
 		}
 	}
-	if time.Since(k.DecryptedEncPart.StartTime) > cfg.LibDefaults.Clockskew || k.DecryptedEncPart.StartTime.Sub(time.Now().UTC()) > cfg.LibDefaults.Clockskew {
-		if time.Since(k.DecryptedEncPart.AuthTime) > cfg.LibDefaults.Clockskew || k.DecryptedEncPart.AuthTime.Sub(time.Now().UTC()) > cfg.LibDefaults.Clockskew {
+	if types.IsFlagSet(&tgsReq.ReqBody.KDCOptions, flags.Validate) {
+		// A validated ticket keeps the times it was issued with, which may be long past, but it must have started.
+		if k.DecryptedEncPart.StartTime.Sub(t) > cfg.LibDefaults.Clockskew {
+			return false, krberror.NewErrorf(krberror.KRBMsgError, "validated ticket is not valid until %v", k.DecryptedEncPart.StartTime)
+		}
+		return true, nil
+	}
+	if types.IsFlagSet(&tgsReq.ReqBody.KDCOptions, flags.PostDated) && !tgsReq.ReqBody.From.IsZero() {
+		// The ticket starts at the time requested rather than when it was issued
+		t = tgsReq.ReqBody.From
+	}
+	if t.Sub(k.DecryptedEncPart.StartTime) > cfg.LibDefaults.Clockskew || k.DecryptedEncPart.StartTime.Sub(t) > cfg.LibDefaults.Clockskew {
+		if t.Sub(k.DecryptedEncPart.AuthTime) > cfg.LibDefaults.Clockskew || k.DecryptedEncPart.AuthTime.Sub(t) > cfg.LibDefaults.Clockskew {
 			return false, krberror.NewErrorf(krberror.KRBMsgError, "clock skew with KDC too large. Greater than %v seconds.", cfg.LibDefaults.Clockskew.Seconds())
 		}
 	}
 	return true, nil
 }
+
+// String returns a description of the AS_REP for diagnostics. Secret values are not included.
+func (k ASRep) String() string {
+	return k.KDCRepFields.describe("AS_REP")
+}
+
+// String returns a description of the TGS_REP for diagnostics. Secret values are not included.
+func (k TGSRep) String() string {
+	return k.KDCRepFields.describe("TGS_REP")
+}
+
+// describe returns a description of the KDC_REP fields with the title provided.
+func (k KDCRepFields) describe(title string) string {
+	d := newDump(title)
+	d.field("PVNO", k.PVNO)
+	d.field("PAData", paDataString(k.PAData))
+	d.field("CRealm", k.CRealm)
+	d.principal("CName", k.CName)
+	d.nested("Ticket", k.Ticket.String())
+	d.field("EncPart", k.EncPart)
+	if !k.DecryptedEncPart.EndTime.IsZero() {
+		d.nested("DecryptedEncPart", k.DecryptedEncPart.String())
+	}
+	return d.String()
+}
+
+// String returns a description of the encrypted part of a KDC_REP for diagnostics. The session key is redacted.
+func (e EncKDCRepPart) String() string {
+	d := newDump("EncKDCRepPart")
+	d.field("Key", e.Key)
+	d.field("Nonce", e.Nonce)
+	d.time("KeyExpiration", e.KeyExpiration)
+	d.field("Flags", types.FlagNames(e.Flags, flags.TicketFlagNames))
+	d.time("AuthTime", e.AuthTime)
+	d.time("StartTime", e.StartTime)
+	d.time("EndTime", e.EndTime)
+	d.time("RenewTill", e.RenewTill)
+	d.field("SRealm", e.SRealm)
+	d.principal("SName", e.SName)
+	if len(e.CAddr) > 0 {
+		d.field("CAddr", addressesString(e.CAddr))
+	}
+	if len(e.EncPAData) > 0 {
+		d.field("EncPAData", paDataString(e.EncPAData))
+	}
+	return d.String()
+}
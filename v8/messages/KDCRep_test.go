@@ -3,9 +3,11 @@ package messages
 import (
 	"encoding/hex"
 	"fmt"
+	"net"
 	"testing"
 	"time"
 
+	"github.com/jcmturner/gokrb5/v8/config"
 	"github.com/jcmturner/gokrb5/v8/credentials"
 	"github.com/jcmturner/gokrb5/v8/iana"
 	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
@@ -14,7 +16,9 @@ import (
 	"github.com/jcmturner/gokrb5/v8/iana/patype"
 	"github.com/jcmturner/gokrb5/v8/keytab"
 	"github.com/jcmturner/gokrb5/v8/test/testdata"
+	"github.com/jcmturner/gokrb5/v8/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -349,3 +353,63 @@ func TestUnmarshalASRepDecodeAndDecrypt_withPassword(t *testing.T) {
 	assert.Equal(t, nametype.KRB_NT_SRV_INST, asRep.DecryptedEncPart.SName.NameType, "Name type for AS_REP not as expected")
 	assert.Equal(t, []string{"krbtgt", testRealm}, asRep.DecryptedEncPart.SName.NameString, "Service name string not as expected")
 }
+
+func TestTGSRep_VerifyQuirks(t *testing.T) {
+	t.Parallel()
+	c := config.New()
+	now := time.Now().UTC()
+	cname := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, testUser)
+	var tgsReq TGSReq
+	tgsReq.ReqBody.CName = cname
+	tgsReq.ReqBody.Realm = testRealm
+	tgsReq.ReqBody.Nonce = 42
+	var tgsRep TGSRep
+	tgsRep.CName = cname
+	tgsRep.Ticket.Realm = testRealm
+	tgsRep.DecryptedEncPart = EncKDCRepPart{
+		Nonce:     42,
+		SRealm:    testRealm,
+		AuthTime:  now,
+		StartTime: now,
+		CAddr:     types.HostAddresses{types.HostAddressFromNetIP(net.ParseIP("192.0.2.1"))},
+	}
+	ok, err := tgsRep.VerifyAt(c, tgsReq, now)
+	assert.False(t, ok, "addresses not requested should not be accepted by default")
+	assert.Error(t, err, "addresses not requested should not be accepted by default")
+	ok, err = tgsRep.VerifyQuirks(c, tgsReq, now, HeimdalQuirks)
+	assert.NoError(t, err, "addresses copied from the TGT should be accepted with the Heimdal quirks")
+	assert.True(t, ok, "addresses copied from the TGT should be accepted with the Heimdal quirks")
+
+	tgsReq.ReqBody.Addresses = types.HostAddresses{types.HostAddressFromNetIP(net.ParseIP("192.0.2.2"))}
+	ok, _ = tgsRep.VerifyQuirks(c, tgsReq, now, HeimdalQuirks)
+	assert.False(t, ok, "addresses other than those requested should not be accepted")
+}
+
+func TestTGSRep_VerifyAt_ClockSkew(t *testing.T) {
+	t.Parallel()
+	c := config.New()
+	require.Equal(t, 1, c.LibDefaults.KDCTimeSync, "kdc_timesync should be enabled by default")
+	now := time.Now().UTC()
+	cname := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, testUser)
+	var tgsReq TGSReq
+	tgsReq.ReqBody.CName = cname
+	tgsReq.ReqBody.Realm = testRealm
+	tgsReq.ReqBody.Nonce = 42
+	var tgsRep TGSRep
+	tgsRep.CName = cname
+	tgsRep.Ticket.Realm = testRealm
+	tgsRep.DecryptedEncPart = EncKDCRepPart{
+		Nonce:     42,
+		SRealm:    testRealm,
+		AuthTime:  now.Add(-time.Hour),
+		StartTime: now.Add(-time.Hour),
+	}
+	ok, err := tgsRep.VerifyAt(c, tgsReq, now)
+	assert.False(t, ok, "reply outside the clock skew should be rejected with kdc_timesync enabled")
+	assert.Error(t, err, "reply outside the clock skew should be rejected with kdc_timesync enabled")
+
+	// The time provided is corrected for the offset with the KDC's clock
+	ok, err = tgsRep.VerifyAt(c, tgsReq, now.Add(-time.Hour))
+	assert.NoError(t, err, "reply within the clock skew of the corrected time should be accepted")
+	assert.True(t, ok, "reply within the clock skew of the corrected time should be accepted")
+}
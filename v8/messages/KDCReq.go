@@ -10,7 +10,7 @@ import (
 	"math/big"
 	"time"
 
-	"github.com/jcmturner/gofork/encoding/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1"
 	"github.com/jcmturner/gokrb5/v8/asn1tools"
 	"github.com/jcmturner/gokrb5/v8/config"
 	"github.com/jcmturner/gokrb5/v8/crypto"
@@ -145,7 +145,7 @@ func NewASReq(realm string, c *config.Config, cname, sname types.PrincipalName)
 	if !c.LibDefaults.NoAddresses {
 		ha, err := types.LocalHostAddresses()
 		if err != nil {
-			return a, fmt.Errorf("could not get local addresses: %v", err)
+			return a, fmt.Errorf("could not get local addresses: %w", err)
 		}
 		ha = append(ha, types.HostAddressesFromNetIPs(c.LibDefaults.ExtraAddresses)...)
 		a.ReqBody.Addresses = ha
@@ -155,33 +155,108 @@ func NewASReq(realm string, c *config.Config, cname, sname types.PrincipalName)
 
 // NewTGSReq generates a new KRB_TGS_REQ struct.
 func NewTGSReq(cname types.PrincipalName, kdcRealm string, c *config.Config, tgt Ticket, sessionKey types.EncryptionKey, sname types.PrincipalName, renewal bool) (TGSReq, error) {
-	a, err := tgsReq(cname, sname, kdcRealm, renewal, c)
+	return NewTGSReqAt(cname, kdcRealm, c, tgt, sessionKey, sname, renewal, time.Now().UTC())
+}
+
+// NewTGSReqAt generates a new KRB_TGS_REQ struct using the time provided rather than the local clock for the
+// request's timestamps. This allows a client to correct for a known offset between its clock and the KDC's.
+func NewTGSReqAt(cname types.PrincipalName, kdcRealm string, c *config.Config, tgt Ticket, sessionKey types.EncryptionKey, sname types.PrincipalName, renewal bool, t time.Time) (TGSReq, error) {
+	a, err := tgsReq(cname, sname, kdcRealm, renewal, c, t)
+	if err != nil {
+		return a, err
+	}
+	err = a.setPAData(tgt, sessionKey, t)
+	return a, err
+}
+
+// TGSReqOptions are optional fields of a TGS_REQ.
+type TGSReqOptions struct {
+	// KDCOptions are set on the request in addition to those from the configuration.
+	KDCOptions []int
+	// AdditionalTickets are included in the request, as the TGT of the service for user-to-user authentication or
+	// the client's evidence ticket for S4U2Proxy.
+	AdditionalTickets []Ticket
+	// AuthorizationData is encrypted with the session key of the TGT and included in the request for the KDC to
+	// copy into the ticket issued, for example AD-IF-RELEVANT restrictions on the use of the ticket.
+	AuthorizationData types.AuthorizationData
+	// PAData is included in the request after the PA-TGS-REQ, for example the PA-FOR-USER of an S4U2Self request.
+	PAData types.PADataSequence
+}
+
+// NewTGSReqWithOptions generates a new KRB_TGS_REQ struct with the optional fields provided, using the time provided
+// for the request's timestamps.
+func NewTGSReqWithOptions(cname types.PrincipalName, kdcRealm string, c *config.Config, tgt Ticket, sessionKey types.EncryptionKey, sname types.PrincipalName, renewal bool, t time.Time, opts TGSReqOptions) (TGSReq, error) {
+	a, err := tgsReq(cname, sname, kdcRealm, renewal, c, t)
 	if err != nil {
 		return a, err
 	}
-	err = a.setPAData(tgt, sessionKey)
+	types.SetFlags(&a.ReqBody.KDCOptions, opts.KDCOptions)
+	a.ReqBody.AdditionalTickets = opts.AdditionalTickets
+	if len(opts.AuthorizationData) > 0 {
+		b, err := asn1.Marshal(opts.AuthorizationData)
+		if err != nil {
+			return a, krberror.Errorf(err, krberror.EncodingError, "error marshaling TGS_REQ authorization data")
+		}
+		a.ReqBody.EncAuthData, err = crypto.GetEncryptedData(b, sessionKey, keyusage.TGS_REQ_KDC_REQ_BODY_AUTHDATA_SESSION_KEY, 0)
+		if err != nil {
+			return a, krberror.Errorf(err, krberror.EncryptingError, "error encrypting TGS_REQ authorization data")
+		}
+	}
+	err = a.setPAData(tgt, sessionKey, t)
+	a.PAData = append(a.PAData, opts.PAData...)
 	return a, err
 }
 
 // NewUser2UserTGSReq returns a TGS-REQ suitable for user-to-user authentication (https://tools.ietf.org/html/rfc4120#section-3.7)
 func NewUser2UserTGSReq(cname types.PrincipalName, kdcRealm string, c *config.Config, clientTGT Ticket, sessionKey types.EncryptionKey, sname types.PrincipalName, renewal bool, verifyingTGT Ticket) (TGSReq, error) {
-	a, err := tgsReq(cname, sname, kdcRealm, renewal, c)
+	return NewUser2UserTGSReqAt(cname, kdcRealm, c, clientTGT, sessionKey, sname, renewal, verifyingTGT, time.Now().UTC())
+}
+
+// NewUser2UserTGSReqAt returns a TGS-REQ suitable for user-to-user authentication using the time provided rather than
+// the local clock for the request's timestamps.
+func NewUser2UserTGSReqAt(cname types.PrincipalName, kdcRealm string, c *config.Config, clientTGT Ticket, sessionKey types.EncryptionKey, sname types.PrincipalName, renewal bool, verifyingTGT Ticket, t time.Time) (TGSReq, error) {
+	a, err := tgsReq(cname, sname, kdcRealm, renewal, c, t)
 	if err != nil {
 		return a, err
 	}
 	a.ReqBody.AdditionalTickets = []Ticket{verifyingTGT}
 	types.SetFlag(&a.ReqBody.KDCOptions, flags.EncTktInSkey)
-	err = a.setPAData(clientTGT, sessionKey)
+	err = a.setPAData(clientTGT, sessionKey, t)
 	return a, err
 }
 
+// NewValidateTGSReq returns a TGS_REQ to validate the postdated ticket provided once its start time has passed.
+// The ticket is presented to the KDC in place of a TGT and a valid ticket with the same times returned.
+// https://tools.ietf.org/html/rfc4120#section-2.2
+func NewValidateTGSReq(cname types.PrincipalName, c *config.Config, tkt Ticket, sessionKey types.EncryptionKey, t time.Time) (TGSReq, error) {
+	a, err := tgsReq(cname, tkt.SName, tkt.Realm, false, c, t)
+	if err != nil {
+		return a, err
+	}
+	types.SetFlag(&a.ReqBody.KDCOptions, flags.Validate)
+	err = a.setPAData(tkt, sessionKey, t)
+	return a, err
+}
+
+// Postdate requests a ticket valid from the time provided rather than from when it is issued, for jobs that are to
+// run later. The configured lifetimes are requested from the time provided. The KDC issues postdated tickets invalid
+// and they must be validated, see NewValidateTGSReq, before use.
+// A TGS_REQ must be postdated before its PAData is set as the body is checksummed.
+func (k *KDCReqBody) Postdate(from time.Time, c *config.Config) {
+	types.SetFlag(&k.KDCOptions, flags.PostDated)
+	k.From = from
+	k.Till = from.Add(c.LibDefaults.TicketLifetime)
+	if types.IsFlagSet(&k.KDCOptions, flags.Renewable) {
+		k.RTime = from.Add(c.LibDefaults.RenewLifetime)
+	}
+}
+
 // tgsReq populates the fields for a TGS_REQ
-func tgsReq(cname, sname types.PrincipalName, kdcRealm string, renewal bool, c *config.Config) (TGSReq, error) {
+func tgsReq(cname, sname types.PrincipalName, kdcRealm string, renewal bool, c *config.Config, t time.Time) (TGSReq, error) {
 	nonce, err := rand.Int(rand.Reader, big.NewInt(math.MaxInt32))
 	if err != nil {
 		return TGSReq{}, err
 	}
-	t := time.Now().UTC()
 	k := KDCReqFields{
 		PVNO:    iana.PVNO,
 		MsgType: msgtype.KRB_TGS_REQ,
@@ -212,7 +287,7 @@ func tgsReq(cname, sname types.PrincipalName, kdcRealm string, renewal bool, c *
 	if !c.LibDefaults.NoAddresses {
 		ha, err := types.LocalHostAddresses()
 		if err != nil {
-			return TGSReq{}, fmt.Errorf("could not get local addresses: %v", err)
+			return TGSReq{}, fmt.Errorf("could not get local addresses: %w", err)
 		}
 		ha = append(ha, types.HostAddressesFromNetIPs(c.LibDefaults.ExtraAddresses)...)
 		k.ReqBody.Addresses = ha
@@ -226,7 +301,7 @@ func tgsReq(cname, sname types.PrincipalName, kdcRealm string, renewal bool, c *
 	}, nil
 }
 
-func (k *TGSReq) setPAData(tgt Ticket, sessionKey types.EncryptionKey) error {
+func (k *TGSReq) setPAData(tgt Ticket, sessionKey types.EncryptionKey, t time.Time) error {
 	// Marshal the request and calculate checksum
 	b, err := k.ReqBody.Marshal()
 	if err != nil {
@@ -243,7 +318,7 @@ func (k *TGSReq) setPAData(tgt Ticket, sessionKey types.EncryptionKey) error {
 
 	// Form PAData for TGS_REQ
 	// Create authenticator
-	auth, err := types.NewAuthenticator(tgt.Realm, k.ReqBody.CName)
+	auth, err := types.NewAuthenticatorAt(tgt.Realm, k.ReqBody.CName, t)
 	if err != nil {
 		return krberror.Errorf(err, krberror.KRBMsgError, "error generating new authenticator")
 	}
@@ -269,6 +344,12 @@ func (k *TGSReq) setPAData(tgt Ticket, sessionKey types.EncryptionKey) error {
 	return nil
 }
 
+// CanonicalizeCName reports if the KDC may return a different, canonical, form of the client name requested in the
+// AS_REQ. This is the case if the canonicalize KDC option is set or the name is an enterprise name. RFC 6806
+func (k *ASReq) CanonicalizeCName() bool {
+	return types.IsFlagSet(&k.ReqBody.KDCOptions, flags.Canonicalize) || k.ReqBody.CName.NameType == nametype.KRB_NT_ENTERPRISE
+}
+
 // Unmarshal bytes b into the ASReq struct.
 func (k *ASReq) Unmarshal(b []byte) error {
 	var m marshalKDCReq
@@ -430,3 +511,41 @@ func (k *KDCReqBody) Marshal() ([]byte, error) {
 	}
 	return b, nil
 }
+
+// String returns a description of the AS_REQ for diagnostics. Secret values are not included.
+func (k ASReq) String() string {
+	return k.KDCReqFields.describe("AS_REQ")
+}
+
+// String returns a description of the TGS_REQ for diagnostics. Secret values are not included.
+func (k TGSReq) String() string {
+	return k.KDCReqFields.describe("TGS_REQ")
+}
+
+// describe returns a description of the KDC_REQ fields with the title provided.
+func (k KDCReqFields) describe(title string) string {
+	d := newDump(title)
+	d.field("PVNO", k.PVNO)
+	d.field("PAData", paDataString(k.PAData))
+	d.field("KDCOptions", types.FlagNames(k.ReqBody.KDCOptions, flags.KDCOptionNames))
+	if len(k.ReqBody.CName.NameString) > 0 {
+		d.principal("CName", k.ReqBody.CName)
+	}
+	d.field("Realm", k.ReqBody.Realm)
+	d.principal("SName", k.ReqBody.SName)
+	d.time("From", k.ReqBody.From)
+	d.time("Till", k.ReqBody.Till)
+	d.time("RTime", k.ReqBody.RTime)
+	d.field("Nonce", k.ReqBody.Nonce)
+	d.field("EType", etypesString(k.ReqBody.EType))
+	if len(k.ReqBody.Addresses) > 0 {
+		d.field("Addresses", addressesString(k.ReqBody.Addresses))
+	}
+	if len(k.ReqBody.EncAuthData.Cipher) > 0 {
+		d.field("EncAuthData", k.ReqBody.EncAuthData)
+	}
+	for _, tkt := range k.ReqBody.AdditionalTickets {
+		d.nested("AdditionalTicket", tkt.String())
+	}
+	return d.String()
+}
@@ -6,12 +6,19 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/crypto"
 	"github.com/jcmturner/gokrb5/v8/iana"
 	"github.com/jcmturner/gokrb5/v8/iana/addrtype"
+	"github.com/jcmturner/gokrb5/v8/iana/adtype"
+	"github.com/jcmturner/gokrb5/v8/iana/flags"
+	"github.com/jcmturner/gokrb5/v8/iana/keyusage"
 	"github.com/jcmturner/gokrb5/v8/iana/msgtype"
 	"github.com/jcmturner/gokrb5/v8/iana/nametype"
 	"github.com/jcmturner/gokrb5/v8/iana/patype"
+	"github.com/jcmturner/gokrb5/v8/keytab"
 	"github.com/jcmturner/gokrb5/v8/test/testdata"
+	"github.com/jcmturner/gokrb5/v8/types"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -424,3 +431,103 @@ func TestMarshalTGSReq(t *testing.T) {
 	}
 	assert.Equal(t, b, mb, "Marshal bytes of TGSReq not as expected")
 }
+
+func TestASReq_CanonicalizeCName(t *testing.T) {
+	t.Parallel()
+	c := config.New()
+	cname := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "user")
+	a, err := NewASReqForTGT("TEST.GOKRB5", c, cname)
+	if err != nil {
+		t.Fatalf("error creating AS_REQ: %v", err)
+	}
+	assert.False(t, a.CanonicalizeCName(), "canonicalization not requested")
+
+	c.LibDefaults.Canonicalize = true
+	a, err = NewASReqForTGT("TEST.GOKRB5", c, cname)
+	if err != nil {
+		t.Fatalf("error creating AS_REQ: %v", err)
+	}
+	assert.True(t, a.CanonicalizeCName(), "canonicalize option set")
+
+	c.LibDefaults.Canonicalize = false
+	a, err = NewASReqForTGT("TEST.GOKRB5", c, types.NewPrincipalName(nametype.KRB_NT_ENTERPRISE, "user@test.gokrb5"))
+	if err != nil {
+		t.Fatalf("error creating AS_REQ: %v", err)
+	}
+	assert.True(t, a.CanonicalizeCName(), "enterprise names may be canonicalized")
+}
+
+func TestKDCReqBody_Postdate(t *testing.T) {
+	t.Parallel()
+	c := config.New()
+	c.LibDefaults.RenewLifetime = 48 * time.Hour
+	a, err := NewASReqForTGT("TEST.GOKRB5", c, types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "user"))
+	if err != nil {
+		t.Fatalf("error creating AS_REQ: %v", err)
+	}
+	from := time.Now().UTC().Add(6 * time.Hour)
+	a.ReqBody.Postdate(from, c)
+	assert.True(t, types.IsFlagSet(&a.ReqBody.KDCOptions, flags.PostDated), "postdated option not set")
+	assert.Equal(t, from, a.ReqBody.From, "from time not as expected")
+	assert.Equal(t, from.Add(c.LibDefaults.TicketLifetime), a.ReqBody.Till, "till time not as expected")
+	assert.Equal(t, from.Add(c.LibDefaults.RenewLifetime), a.ReqBody.RTime, "renew time not as expected")
+}
+
+func TestNewTGSReqWithOptions(t *testing.T) {
+	t.Parallel()
+	c := config.New()
+	c.LibDefaults.NoAddresses = true
+	b, _ := hex.DecodeString(testdata.HTTP_KEYTAB)
+	kt := keytab.New()
+	kt.Unmarshal(b)
+	cname := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "testuser1")
+	sname := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "HTTP/host.test.gokrb5")
+	st := time.Now().UTC()
+	tgt, sessionKey, err := NewTicket(cname, "TEST.GOKRB5", sname, "TEST.GOKRB5", types.NewKrbFlags(), kt, 18, 1, st, st, st.Add(time.Hour), time.Time{})
+	if err != nil {
+		t.Fatalf("error creating ticket: %v", err)
+	}
+	ad := types.AuthorizationData{{ADType: adtype.ADIfRelevant, ADData: []byte("foobar")}}
+	a, err := NewTGSReqWithOptions(cname, "TEST.GOKRB5", c, tgt, sessionKey, sname, false, st, TGSReqOptions{
+		KDCOptions:        []int{flags.EncTktInSkey},
+		AdditionalTickets: []Ticket{tgt},
+		AuthorizationData: ad,
+	})
+	if err != nil {
+		t.Fatalf("error creating TGS_REQ: %v", err)
+	}
+	assert.True(t, types.IsFlagSet(&a.ReqBody.KDCOptions, flags.EncTktInSkey), "KDC option not set")
+	assert.Equal(t, 1, len(a.ReqBody.AdditionalTickets), "additional tickets not as expected")
+	db, err := crypto.DecryptEncPart(a.ReqBody.EncAuthData, sessionKey, keyusage.TGS_REQ_KDC_REQ_BODY_AUTHDATA_SESSION_KEY)
+	if err != nil {
+		t.Fatalf("error decrypting authorization data: %v", err)
+	}
+	var dad types.AuthorizationData
+	if err := dad.Unmarshal(db); err != nil {
+		t.Fatalf("error unmarshaling authorization data: %v", err)
+	}
+	assert.Equal(t, ad, dad, "authorization data not as expected")
+}
+
+func TestNewUser2UserTGSReqAt(t *testing.T) {
+	t.Parallel()
+	c := config.New()
+	c.LibDefaults.NoAddresses = true
+	b, _ := hex.DecodeString(testdata.HTTP_KEYTAB)
+	kt := keytab.New()
+	kt.Unmarshal(b)
+	cname := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "testuser1")
+	sname := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "HTTP/host.test.gokrb5")
+	st := time.Now().UTC().Add(-time.Hour * 3).Truncate(time.Second)
+	tgt, sessionKey, err := NewTicket(cname, "TEST.GOKRB5", sname, "TEST.GOKRB5", types.NewKrbFlags(), kt, 18, 1, st, st, st.Add(time.Hour), time.Time{})
+	if err != nil {
+		t.Fatalf("error creating ticket: %v", err)
+	}
+	a, err := NewUser2UserTGSReqAt(cname, "TEST.GOKRB5", c, tgt, sessionKey, sname, false, tgt, st)
+	if err != nil {
+		t.Fatalf("error creating TGS_REQ: %v", err)
+	}
+	assert.True(t, types.IsFlagSet(&a.ReqBody.KDCOptions, flags.EncTktInSkey), "KDC option not set")
+	assert.Equal(t, 1, len(a.ReqBody.AdditionalTickets), "additional tickets not as expected")
+	assert.Equal(t, st.Add(c.LibDefaults.TicketLifetime), a.ReqBody.Till, "till time not taken from the time provided")
+}
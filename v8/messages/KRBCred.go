@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/jcmturner/gofork/encoding/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1"
 	"github.com/jcmturner/gokrb5/v8/crypto"
 	"github.com/jcmturner/gokrb5/v8/iana/asnAppTag"
 	"github.com/jcmturner/gokrb5/v8/iana/keyusage"
@@ -79,9 +79,20 @@ func (k *KRBCred) Unmarshal(b []byte) error {
 
 // DecryptEncPart decrypts the encrypted part of a KRB_CRED.
 func (k *KRBCred) DecryptEncPart(key types.EncryptionKey) error {
-	b, err := crypto.DecryptEncPart(k.EncPart, key, keyusage.KRB_CRED_ENCPART)
-	if err != nil {
-		return krberror.Errorf(err, krberror.DecryptingError, "error decrypting KRB_CRED EncPart")
+	return k.DecryptEncPartQuirks(key, Quirks{})
+}
+
+// DecryptEncPartQuirks decrypts the encrypted part of a KRB_CRED relaxing the checks as the quirks provided allow.
+func (k *KRBCred) DecryptEncPartQuirks(key types.EncryptionKey, q Quirks) error {
+	var b []byte
+	var err error
+	if q.UnencryptedKRBCred && k.EncPart.EType == 0 {
+		b = k.EncPart.Cipher
+	} else {
+		b, err = crypto.DecryptEncPart(k.EncPart, key, keyusage.KRB_CRED_ENCPART)
+		if err != nil {
+			return krberror.Errorf(err, krberror.DecryptingError, "error decrypting KRB_CRED EncPart")
+		}
 	}
 	var denc EncKrbCredPart
 	err = denc.Unmarshal(b)
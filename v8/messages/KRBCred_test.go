@@ -11,6 +11,7 @@ import (
 	"github.com/jcmturner/gokrb5/v8/iana/msgtype"
 	"github.com/jcmturner/gokrb5/v8/iana/nametype"
 	"github.com/jcmturner/gokrb5/v8/test/testdata"
+	"github.com/jcmturner/gokrb5/v8/types"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -130,3 +131,19 @@ func TestUnmarshalEncCredPart_optionalsNULL(t *testing.T) {
 		assert.Equal(t, "12d00023", hex.EncodeToString(addr.Address), fmt.Sprintf("Host address not as expected for address item %d within ticket info %d", j+1, i+1))
 	}
 }
+
+func TestKRBCred_DecryptEncPartQuirks(t *testing.T) {
+	t.Parallel()
+	b, err := hex.DecodeString(testdata.MarshaledKRB5enc_cred_part)
+	if err != nil {
+		t.Fatalf("Test vector read error: %v", err)
+	}
+	k := KRBCred{EncPart: types.EncryptedData{EType: 0, Cipher: b}}
+	err = k.DecryptEncPart(types.EncryptionKey{})
+	assert.Error(t, err, "unencrypted KRB_CRED should not be accepted by default")
+	err = k.DecryptEncPartQuirks(types.EncryptionKey{}, HeimdalQuirks)
+	if err != nil {
+		t.Fatalf("error decoding unencrypted KRB_CRED: %v", err)
+	}
+	assert.Equal(t, 2, len(k.DecryptedEncPart.TicketInfo), "Number of ticket info items not as expected")
+}
@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/jcmturner/gofork/encoding/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1"
 	"github.com/jcmturner/gokrb5/v8/asn1tools"
 	"github.com/jcmturner/gokrb5/v8/iana"
 	"github.com/jcmturner/gokrb5/v8/iana/asnAppTag"
@@ -79,6 +79,17 @@ func (k KRBError) Error() string {
 	return etxt
 }
 
+// Is reports if the target is a KRBError with the same error code.
+// This allows callers to test for specific KDC error codes within wrapped errors:
+//
+// errors.Is(err, messages.KRBError{ErrorCode: errorcode.KDC_ERR_PREAUTH_FAILED})
+func (k KRBError) Is(target error) bool {
+	if t, ok := target.(KRBError); ok {
+		return k.ErrorCode == t.ErrorCode
+	}
+	return false
+}
+
 func processUnmarshalReplyError(b []byte, err error) error {
 	switch err.(type) {
 	case asn1.StructuralError:
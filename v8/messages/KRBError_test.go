@@ -2,6 +2,8 @@ package messages
 
 import (
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -10,6 +12,7 @@ import (
 	"github.com/jcmturner/gokrb5/v8/iana/msgtype"
 	"github.com/jcmturner/gokrb5/v8/iana/nametype"
 	"github.com/jcmturner/gokrb5/v8/test/testdata"
+	"github.com/jcmturner/gokrb5/v8/types"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -83,3 +86,14 @@ func TestUnmarshalMarshalKRBError_optionalsNULL(t *testing.T) {
 	}
 	assert.Equal(t, b, b2, "marshalled bytes not as expected")
 }
+
+func TestKRBErrorIs(t *testing.T) {
+	t.Parallel()
+	e := NewKRBError(types.PrincipalName{}, "REALM", errorcode.KDC_ERR_PREAUTH_FAILED, "")
+	err := fmt.Errorf("wrapped: %w", e)
+	assert.True(t, errors.Is(err, KRBError{ErrorCode: errorcode.KDC_ERR_PREAUTH_FAILED}), "error code not matched in error chain")
+	assert.False(t, errors.Is(err, KRBError{ErrorCode: errorcode.KDC_ERR_C_PRINCIPAL_UNKNOWN}), "different error code should not match")
+	var k KRBError
+	assert.True(t, errors.As(err, &k), "KRBError not found in error chain")
+	assert.Equal(t, errorcode.KDC_ERR_PREAUTH_FAILED, k.ErrorCode, "error code not as expected")
+}
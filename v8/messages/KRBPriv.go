@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/jcmturner/gofork/encoding/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1"
 	"github.com/jcmturner/gokrb5/v8/asn1tools"
 	"github.com/jcmturner/gokrb5/v8/crypto"
 	"github.com/jcmturner/gokrb5/v8/iana"
@@ -98,11 +98,11 @@ func (k *KRBPriv) EncryptEncPart(key types.EncryptionKey) error {
 func (k *KRBPriv) DecryptEncPart(key types.EncryptionKey) error {
 	b, err := crypto.DecryptEncPart(k.EncPart, key, keyusage.KRB_PRIV_ENCPART)
 	if err != nil {
-		return fmt.Errorf("error decrypting KRBPriv EncPart: %v", err)
+		return fmt.Errorf("error decrypting KRBPriv EncPart: %w", err)
 	}
 	err = k.DecryptedEncPart.Unmarshal(b)
 	if err != nil {
-		return fmt.Errorf("error unmarshaling encrypted part: %v", err)
+		return fmt.Errorf("error unmarshaling encrypted part: %w", err)
 	}
 	return nil
 }
@@ -5,7 +5,7 @@ import (
 	"log"
 	"time"
 
-	"github.com/jcmturner/gofork/encoding/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1"
 	"github.com/jcmturner/gokrb5/v8/asn1tools"
 	"github.com/jcmturner/gokrb5/v8/crypto"
 	"github.com/jcmturner/gokrb5/v8/iana"
@@ -137,11 +137,11 @@ func unmarshalTicketsSequence(in asn1.RawValue) ([]Ticket, error) {
 	for p < (len(b)) {
 		_, err := asn1.UnmarshalWithParams(b[p:], &raw, fmt.Sprintf("application,tag:%d", asnAppTag.Ticket))
 		if err != nil {
-			return nil, fmt.Errorf("unmarshaling sequence of tickets failed getting length of ticket: %v", err)
+			return nil, fmt.Errorf("unmarshaling sequence of tickets failed getting length of ticket: %w", err)
 		}
 		t, err := unmarshalTicket(b[p:])
 		if err != nil {
-			return nil, fmt.Errorf("unmarshaling sequence of tickets failed: %v", err)
+			return nil, fmt.Errorf("unmarshaling sequence of tickets failed: %w", err)
 		}
 		p += len(raw.FullBytes)
 		tkts = append(tkts, t)
@@ -201,12 +201,12 @@ func (t *Ticket) DecryptEncPart(keytab *keytab.Keytab, sname *types.PrincipalNam
 func (t *Ticket) Decrypt(key types.EncryptionKey) error {
 	b, err := crypto.DecryptEncPart(t.EncPart, key, keyusage.KDC_REP_TICKET)
 	if err != nil {
-		return fmt.Errorf("error decrypting Ticket EncPart: %v", err)
+		return fmt.Errorf("error decrypting Ticket EncPart: %w", err)
 	}
 	var denc EncTicketPart
 	err = denc.Unmarshal(b)
 	if err != nil {
-		return fmt.Errorf("error unmarshaling encrypted part: %v", err)
+		return fmt.Errorf("error unmarshaling encrypted part: %w", err)
 	}
 	t.DecryptedEncPart = denc
 	return nil
@@ -228,7 +228,7 @@ func (t *Ticket) GetPACType(keytab *keytab.Keytab, sname *types.PrincipalName, l
 				var p pac.PACType
 				err = p.Unmarshal(ad2[0].ADData)
 				if err != nil {
-					return isPAC, p, fmt.Errorf("error unmarshaling PAC: %v", err)
+					return isPAC, p, fmt.Errorf("error unmarshaling PAC: %w", err)
 				}
 				if sname == nil {
 					sname = &t.SName
@@ -247,8 +247,12 @@ func (t *Ticket) GetPACType(keytab *keytab.Keytab, sname *types.PrincipalName, l
 
 // Valid checks it the ticket is currently valid. Max duration passed endtime passed in as argument.
 func (t *Ticket) Valid(d time.Duration) (bool, error) {
+	return t.ValidAt(d, time.Now().UTC())
+}
+
+// ValidAt checks if the ticket is valid at the time provided. Max duration passed endtime passed in as argument.
+func (t *Ticket) ValidAt(d time.Duration, time time.Time) (bool, error) {
 	// Check for future tickets or invalid tickets
-	time := time.Now().UTC()
 	if t.DecryptedEncPart.StartTime.Sub(time) > d || types.IsFlagSet(&t.DecryptedEncPart.Flags, flags.Invalid) {
 		return false, NewKRBError(t.SName, t.Realm, errorcode.KRB_AP_ERR_TKT_NYV, "service ticket provided is not yet valid")
 	}
@@ -260,3 +264,37 @@ func (t *Ticket) Valid(d time.Duration) (bool, error) {
 
 	return true, nil
 }
+
+// String returns a description of the Ticket for diagnostics. Secret values are not included.
+func (t Ticket) String() string {
+	d := newDump("Ticket")
+	d.field("TktVNO", t.TktVNO)
+	d.field("Realm", t.Realm)
+	d.principal("SName", t.SName)
+	d.field("EncPart", t.EncPart)
+	if !t.DecryptedEncPart.EndTime.IsZero() {
+		d.nested("DecryptedEncPart", t.DecryptedEncPart.String())
+	}
+	return d.String()
+}
+
+// String returns a description of the encrypted part of a Ticket for diagnostics. The session key is redacted.
+func (t EncTicketPart) String() string {
+	d := newDump("EncTicketPart")
+	d.field("Flags", types.FlagNames(t.Flags, flags.TicketFlagNames))
+	d.field("Key", t.Key)
+	d.field("CRealm", t.CRealm)
+	d.principal("CName", t.CName)
+	d.field("Transited", fmt.Sprintf("type %d, %d bytes", t.Transited.TRType, len(t.Transited.Contents)))
+	d.time("AuthTime", t.AuthTime)
+	d.time("StartTime", t.StartTime)
+	d.time("EndTime", t.EndTime)
+	d.time("RenewTill", t.RenewTill)
+	if len(t.CAddr) > 0 {
+		d.field("CAddr", addressesString(t.CAddr))
+	}
+	for _, ad := range t.AuthorizationData {
+		d.field("AuthorizationData", fmt.Sprintf("type %d, %d bytes", ad.ADType, len(ad.ADData)))
+	}
+	return d.String()
+}
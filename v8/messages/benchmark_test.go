@@ -0,0 +1,128 @@
+package messages
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/crypto"
+	"github.com/jcmturner/gokrb5/v8/iana/keyusage"
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/test/testdata"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// benchTicket returns a ticket for HTTP/host.test.gokrb5 and its session key.
+func benchTicket(b *testing.B) (Ticket, types.EncryptionKey) {
+	kb, _ := hex.DecodeString(testdata.HTTP_KEYTAB)
+	kt := keytab.New()
+	kt.Unmarshal(kb)
+	cname := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "testuser1")
+	sname := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "HTTP/host.test.gokrb5")
+	st := time.Now().UTC()
+	tkt, key, err := NewTicket(cname, "TEST.GOKRB5", sname, "TEST.GOKRB5", types.NewKrbFlags(), kt, 18, 1, st, st, st.Add(time.Hour), time.Time{})
+	if err != nil {
+		b.Fatalf("error creating ticket: %v", err)
+	}
+	return tkt, key
+}
+
+func BenchmarkASReq_Marshal(b *testing.B) {
+	c := config.New()
+	cname := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "testuser1")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		a, err := NewASReqForTGT("TEST.GOKRB5", c, cname)
+		if err != nil {
+			b.Fatalf("error creating AS_REQ: %v", err)
+		}
+		if _, err := a.Marshal(); err != nil {
+			b.Fatalf("error marshaling AS_REQ: %v", err)
+		}
+	}
+}
+
+func BenchmarkASRep_Unmarshal(b *testing.B) {
+	v, _ := hex.DecodeString(testdata.MarshaledKRB5as_rep)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var a ASRep
+		if err := a.Unmarshal(v); err != nil {
+			b.Fatalf("error unmarshaling AS_REP: %v", err)
+		}
+	}
+}
+
+func BenchmarkTGSReq_Marshal(b *testing.B) {
+	c := config.New()
+	c.LibDefaults.NoAddresses = true
+	tgt, key := benchTicket(b)
+	cname := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "testuser1")
+	sname := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "HTTP/host.test.gokrb5")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		a, err := NewTGSReq(cname, "TEST.GOKRB5", c, tgt, key, sname, false)
+		if err != nil {
+			b.Fatalf("error creating TGS_REQ: %v", err)
+		}
+		if _, err := a.Marshal(); err != nil {
+			b.Fatalf("error marshaling TGS_REQ: %v", err)
+		}
+	}
+}
+
+func BenchmarkTGSRep_Decrypt(b *testing.B) {
+	v, _ := hex.DecodeString(testdata.MarshaledKRB5tgs_rep)
+	var rep TGSRep
+	if err := rep.Unmarshal(v); err != nil {
+		b.Fatalf("error unmarshaling TGS_REP: %v", err)
+	}
+	_, key := benchTicket(b)
+	pt, _ := hex.DecodeString(testdata.MarshaledKRB5enc_kdc_rep_part)
+	ed, err := crypto.GetEncryptedData(pt, key, keyusage.TGS_REP_ENCPART_SESSION_KEY, 0)
+	if err != nil {
+		b.Fatalf("error encrypting TGS_REP part: %v", err)
+	}
+	rep.EncPart = ed
+	v, err = rep.Marshal()
+	if err != nil {
+		b.Fatalf("error marshaling TGS_REP: %v", err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var r TGSRep
+		if err := r.Unmarshal(v); err != nil {
+			b.Fatalf("error unmarshaling TGS_REP: %v", err)
+		}
+		if err := r.DecryptEncPart(key); err != nil {
+			b.Fatalf("error decrypting TGS_REP: %v", err)
+		}
+	}
+}
+
+func BenchmarkTicket_Decrypt(b *testing.B) {
+	kb, _ := hex.DecodeString(testdata.HTTP_KEYTAB)
+	kt := keytab.New()
+	kt.Unmarshal(kb)
+	tkt, _ := benchTicket(b)
+	v, err := tkt.Marshal()
+	if err != nil {
+		b.Fatalf("error marshaling ticket: %v", err)
+	}
+	sname := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "HTTP/host.test.gokrb5")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var t Ticket
+		if err := t.Unmarshal(v); err != nil {
+			b.Fatalf("error unmarshaling ticket: %v", err)
+		}
+		if err := t.DecryptEncPart(kt, &sname); err != nil {
+			b.Fatalf("error decrypting ticket: %v", err)
+		}
+	}
+}
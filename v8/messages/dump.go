@@ -0,0 +1,83 @@
+package messages
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/jcmturner/gokrb5/v8/iana/patype"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// dump builds the multi-line descriptions returned by the String methods of the messages.
+// Keys and cipher text are never written, only their types and lengths.
+type dump struct {
+	strings.Builder
+}
+
+// newDump starts a description with the title provided.
+func newDump(title string) *dump {
+	d := new(dump)
+	d.WriteString(title)
+	return d
+}
+
+// field writes a named value on its own indented line.
+func (d *dump) field(name string, v interface{}) {
+	fmt.Fprintf(d, "\n  %s: %v", name, v)
+}
+
+// time writes a named time, omitting it if it is not set.
+func (d *dump) time(name string, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	d.field(name, t.UTC().Format(time.RFC3339))
+}
+
+// principal writes a named principal name along with its name type.
+func (d *dump) principal(name string, pn types.PrincipalName) {
+	d.field(name, principalString(pn))
+}
+
+// nested writes the description of an inner message indented under the name provided.
+func (d *dump) nested(name string, s string) {
+	fmt.Fprintf(d, "\n  %s: %s", name, strings.Replace(s, "\n", "\n  ", -1))
+}
+
+// principalString returns the principal name in the form name (name type).
+func principalString(pn types.PrincipalName) string {
+	nt := nametype.Name(pn.NameType)
+	if nt == "" {
+		nt = fmt.Sprintf("%d", pn.NameType)
+	}
+	return fmt.Sprintf("%s (%s)", pn.PrincipalNameString(), nt)
+}
+
+// etypesString returns the names of the etype IDs separated by commas.
+func etypesString(ids []int32) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = types.ETypeString(id)
+	}
+	return strings.Join(s, ", ")
+}
+
+// paDataString returns the types of the PAData separated by commas. The values are not included.
+func paDataString(pas []types.PAData) string {
+	s := make([]string, len(pas))
+	for i, pa := range pas {
+		n := patype.Name(pa.PADataType)
+		if n == "" {
+			n = "unknown"
+		}
+		s[i] = fmt.Sprintf("%s(%d)", n, pa.PADataType)
+	}
+	return strings.Join(s, ", ")
+}
+
+// addressesString returns the host addresses separated by commas.
+func addressesString(addrs []types.HostAddress) string {
+	return types.HostAddresses(addrs).String()
+}
@@ -0,0 +1,88 @@
+package messages
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/test/testdata"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestASRep_String(t *testing.T) {
+	t.Parallel()
+	var asRep ASRep
+	b, _ := hex.DecodeString(testuser1EType18ASREP)
+	err := asRep.Unmarshal(b)
+	if err != nil {
+		t.Fatalf("AS REP Unmarshal error: %v\n", err)
+	}
+	ktb, _ := hex.DecodeString(testuser1EType18Keytab)
+	kt := keytab.New()
+	err = kt.Unmarshal(ktb)
+	if err != nil {
+		t.Fatalf("keytab parse error: %v\n", err)
+	}
+	_, err = asRep.DecryptEncPart(credentials.New(testUser, testRealm).WithKeytab(kt))
+	if err != nil {
+		t.Fatalf("Decryption of AS_REP EncPart failed: %v", err)
+	}
+	s := asRep.String()
+	for _, want := range []string{
+		"AS_REP",
+		"PAData: PA-ETYPE-INFO2(19)",
+		"CName: testuser1 (KRB_NT_PRINCIPAL)",
+		"SName: krbtgt/TEST.GOKRB5 (KRB_NT_SRV_INST)",
+		"EncPart: aes256-cts-hmac-sha1-96(18) kvno 0",
+		"Key: aes256-cts-hmac-sha1-96(18) <redacted>",
+		"Nonce: 2069991465",
+		"Flags: ",
+	} {
+		assert.Contains(t, s, want, "description of AS_REP not as expected")
+	}
+	assert.NotContains(t, s, fmt.Sprintf("%x", asRep.DecryptedEncPart.Key.KeyValue), "session key should not be included")
+	assert.NotContains(t, fmt.Sprintf("%+v", asRep.DecryptedEncPart), fmt.Sprintf("%v", asRep.DecryptedEncPart.Key.KeyValue), "session key should not be included when formatted")
+}
+
+func TestKDCReq_String(t *testing.T) {
+	t.Parallel()
+	var a TGSReq
+	b, _ := hex.DecodeString(testdata.MarshaledKRB5tgs_req)
+	err := a.Unmarshal(b)
+	if err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	s := a.String()
+	for _, want := range []string{
+		"TGS_REQ",
+		"KDCOptions: ",
+		"SName: hftsai/extra (KRB_NT_PRINCIPAL)",
+		"Realm: ATHENA.MIT.EDU",
+		"Till: 1994-06-10T06:03:17Z",
+		"EType: unknown(0), des-cbc-crc(1)",
+		"AdditionalTicket: Ticket",
+	} {
+		assert.Contains(t, s, want, "description of TGS_REQ not as expected")
+	}
+}
+
+func TestAPReq_String(t *testing.T) {
+	t.Parallel()
+	var a APReq
+	b, _ := hex.DecodeString(testdata.MarshaledKRB5ap_req)
+	err := a.Unmarshal(b)
+	if err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	s := a.String()
+	for _, want := range []string{
+		"AP_REQ",
+		"APOptions: ",
+		"Ticket: Ticket",
+		"EncryptedAuthenticator: unknown(0) kvno 5",
+	} {
+		assert.Contains(t, s, want, "description of AP_REQ not as expected")
+	}
+}
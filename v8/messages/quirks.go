@@ -0,0 +1,20 @@
+package messages
+
+// Quirks relax checks on messages that implementations other than MIT Kerberos are known to deviate from, so that
+// clients and services can interoperate in environments mixing MIT, Heimdal and Active Directory. The zero value
+// applies the strict checks.
+type Quirks struct {
+	// UnencryptedKRBCred accepts KRB_CRED messages with an encrypted part of etype 0 that holds the encoding of the
+	// EncKrbCredPart in the clear, as sent by Heimdal when forwarding credentials. RFC 6448
+	// It applies to applications decoding forwarded credentials with KRBCred.DecryptEncPartQuirks.
+	UnencryptedKRBCred bool
+	// CopiedAddresses accepts TGS_REPs with host addresses the TGS_REQ did not request. When a TGS_REQ has no
+	// addresses Heimdal KDCs copy those of the TGT presented into the ticket issued.
+	CopiedAddresses bool
+}
+
+// HeimdalQuirks are the Quirks of Heimdal clients and KDCs.
+var HeimdalQuirks = Quirks{
+	UnencryptedKRBCred: true,
+	CopiedAddresses:    true,
+}
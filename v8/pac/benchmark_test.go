@@ -0,0 +1,58 @@
+package pac
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"log"
+	"testing"
+
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/test/testdata"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+func BenchmarkPACType_Unmarshal(b *testing.B) {
+	v, _ := hex.DecodeString(testdata.MarshaledPAC_AD_WIN2K_PAC)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var p PACType
+		if err := p.Unmarshal(v); err != nil {
+			b.Fatalf("error unmarshaling PAC: %v", err)
+		}
+	}
+}
+
+func BenchmarkPACType_Process(b *testing.B) {
+	v, _ := hex.DecodeString(testdata.MarshaledPAC_AD_WIN2K_PAC)
+	kb, _ := hex.DecodeString(testdata.KEYTAB_SYSHTTP_TEST_GOKRB5)
+	kt := keytab.New()
+	kt.Unmarshal(kb)
+	pn, _ := types.ParseSPNString("sysHTTP")
+	key, _, err := kt.GetEncryptionKey(pn, "TEST.GOKRB5", 2, 18)
+	if err != nil {
+		b.Fatalf("error getting key: %v", err)
+	}
+	l := log.New(ioutil.Discard, "", 0)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var p PACType
+		if err := p.Unmarshal(v); err != nil {
+			b.Fatalf("error unmarshaling PAC: %v", err)
+		}
+		if err := p.ProcessPACInfoBuffers(key, l); err != nil {
+			b.Fatalf("error processing PAC: %v", err)
+		}
+	}
+}
+
+func BenchmarkKerbValidationInfo_Unmarshal(b *testing.B) {
+	v, _ := hex.DecodeString(testdata.MarshaledPAC_Kerb_Validation_Info_Trust)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var k KerbValidationInfo
+		if err := k.Unmarshal(v); err != nil {
+			b.Fatalf("error unmarshaling KerbValidationInfo: %v", err)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package pac
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 
 	"github.com/jcmturner/rpc/v2/mstypes"
@@ -32,3 +33,46 @@ func (k *ClientClaimsInfo) Unmarshal(b []byte) (err error) {
 	}
 	return
 }
+
+// Claim is a claim ID and its values, which are one of []int64, []uint64, []string or []bool depending on the type
+// of the claim.
+type Claim struct {
+	ID     string      `json:"id"`
+	Values interface{} `json:"values"`
+}
+
+// Claims returns the claims of the client.
+func (k *ClientClaimsInfo) Claims() []Claim {
+	return claims(k.ClaimsSet)
+}
+
+// MarshalJSON implements the json.Marshaler interface rendering the claims as a list of IDs and their values.
+func (k ClientClaimsInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(claims(k.ClaimsSet))
+}
+
+// claims returns the claims of the ClaimsSet with their values.
+func claims(cs mstypes.ClaimsSet) []Claim {
+	var c []Claim
+	for _, ca := range cs.ClaimsArrays {
+		for _, ce := range ca.ClaimEntries {
+			j := Claim{ID: ce.ID}
+			switch ce.Type {
+			case mstypes.ClaimTypeIDInt64:
+				j.Values = ce.TypeInt64.Value
+			case mstypes.ClaimTypeIDUInt64:
+				j.Values = ce.TypeUInt64.Value
+			case mstypes.ClaimTypeIDString:
+				s := make([]string, len(ce.TypeString.Value))
+				for i := range ce.TypeString.Value {
+					s[i] = ce.TypeString.Value[i].Value
+				}
+				j.Values = s
+			case mstypes.ClaimsTypeIDBoolean:
+				j.Values = ce.TypeBool.Value
+			}
+			c = append(c, j)
+		}
+	}
+	return c
+}
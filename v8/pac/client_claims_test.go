@@ -125,6 +125,10 @@ func TestPAC_ClientClaimsInfoMultiEntry_Unmarshal(t *testing.T) {
 	assert.Equal(t, ClaimsEntryIDStr, k.ClaimsSet.ClaimsArrays[0].ClaimEntries[1].ID, "claims entry ID not as expected")
 	assert.Equal(t, []mstypes.LPWSTR{{Value: ClaimsEntryValueStr}}, k.ClaimsSet.ClaimsArrays[0].ClaimEntries[1].TypeString.Value, "claims value not as expected")
 	assert.Equal(t, mstypes.CompressionFormatNone, k.ClaimsSetMetadata.CompressionFormat, "compression format not as expected")
+	assert.Equal(t, []Claim{
+		{ID: ClaimsEntryIDInt64, Values: []int64{ClaimsEntryValueInt64}},
+		{ID: ClaimsEntryIDStr, Values: []string{ClaimsEntryValueStr}},
+	}, k.Claims(), "claims not as expected")
 }
 
 // Compressed claims not yet supported.
@@ -2,6 +2,7 @@ package pac
 
 import (
 	"bytes"
+	"encoding/json"
 
 	"github.com/jcmturner/rpc/v2/mstypes"
 )
@@ -29,3 +30,14 @@ func (k *ClientInfo) Unmarshal(b []byte) (err error) {
 	k.Name, err = r.UTF16String(int(k.NameLength))
 	return
 }
+
+// MarshalJSON implements the json.Marshaler interface. The client ID is rendered as the RFC3339 time it contains.
+func (k ClientInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		ClientID string `json:"clientID,omitempty"`
+		Name     string `json:"name"`
+	}{
+		ClientID: jsonFileTime(k.ClientID),
+		Name:     k.Name,
+	})
+}
@@ -2,6 +2,7 @@ package pac
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 
 	"github.com/jcmturner/rpc/v2/mstypes"
@@ -32,3 +33,13 @@ func (k *DeviceClaimsInfo) Unmarshal(b []byte) (err error) {
 	}
 	return
 }
+
+// Claims returns the claims of the device.
+func (k *DeviceClaimsInfo) Claims() []Claim {
+	return claims(k.ClaimsSet)
+}
+
+// MarshalJSON implements the json.Marshaler interface rendering the claims as a list of IDs and their values.
+func (k DeviceClaimsInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(claims(k.ClaimsSet))
+}
@@ -0,0 +1,65 @@
+package pac
+
+import (
+	"fmt"
+	"strings"
+)
+
+// infoTypeNames maps the PAC info buffer types to their names.
+var infoTypeNames = map[uint32]string{
+	infoTypeKerbValidationInfo:     "KERB_VALIDATION_INFO",
+	infoTypeCredentials:            "PAC_CREDENTIAL_INFO",
+	infoTypePACServerSignatureData: "SERVER_CHECKSUM",
+	infoTypePACKDCSignatureData:    "KDC_CHECKSUM",
+	infoTypePACClientInfo:          "PAC_CLIENT_INFO",
+	infoTypeS4UDelegationInfo:      "S4U_DELEGATION_INFO",
+	infoTypeUPNDNSInfo:             "UPN_DNS_INFO",
+	infoTypePACClientClaimsInfo:    "PAC_CLIENT_CLAIMS_INFO",
+	infoTypePACDeviceInfo:          "PAC_DEVICE_INFO",
+	infoTypePACDeviceClaimsInfo:    "PAC_DEVICE_CLAIMS_INFO",
+}
+
+// String returns a description of the PAC for diagnostics, listing its info buffers and the details of those that
+// have been processed. Signatures and credentials are not included.
+func (pac PACType) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "PAC\n  Version: %d", pac.Version)
+	for _, buf := range pac.Buffers {
+		n, ok := infoTypeNames[buf.ULType]
+		if !ok {
+			n = "unknown"
+		}
+		fmt.Fprintf(&b, "\n  Buffer: %s(%d), %d bytes", n, buf.ULType, buf.CBBufferSize)
+	}
+	if k := pac.KerbValidationInfo; k != nil {
+		fmt.Fprintf(&b, "\n  EffectiveName: %s", k.EffectiveName.Value)
+		if k.FullName.Value != "" {
+			fmt.Fprintf(&b, "\n  FullName: %s", k.FullName.Value)
+		}
+		fmt.Fprintf(&b, "\n  LogonDomainName: %s", k.LogonDomainName.Value)
+		fmt.Fprintf(&b, "\n  LogonDomainSID: %s", k.LogonDomainID.String())
+		fmt.Fprintf(&b, "\n  UserID: %d", k.UserID)
+		fmt.Fprintf(&b, "\n  PrimaryGroupID: %d", k.PrimaryGroupID)
+		fmt.Fprintf(&b, "\n  UserAccountControl: 0x%08x", k.UserAccountControl)
+		for _, sid := range k.GetGroupMembershipSIDs() {
+			fmt.Fprintf(&b, "\n  GroupMembershipSID: %s", sid)
+		}
+	}
+	if c := pac.ClientInfo; c != nil {
+		fmt.Fprintf(&b, "\n  ClientName: %s", c.Name)
+		if t := jsonFileTime(c.ClientID); t != "" {
+			fmt.Fprintf(&b, "\n  ClientAuthTime: %s", t)
+		}
+	}
+	if u := pac.UPNDNSInfo; u != nil {
+		fmt.Fprintf(&b, "\n  UPN: %s", u.UPN)
+		fmt.Fprintf(&b, "\n  DNSDomain: %s", u.DNSDomain)
+	}
+	if d := pac.S4UDelegationInfo; d != nil {
+		fmt.Fprintf(&b, "\n  S4U2ProxyTarget: %s", d.S4U2proxyTarget.Value)
+		for _, s := range d.S4UTransitedServices {
+			fmt.Fprintf(&b, "\n  S4UTransitedService: %s", s.Value)
+		}
+	}
+	return b.String()
+}
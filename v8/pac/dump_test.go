@@ -0,0 +1,43 @@
+package pac
+
+import (
+	"bytes"
+	"encoding/hex"
+	"log"
+	"testing"
+
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/test/testdata"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPACType_String(t *testing.T) {
+	t.Parallel()
+	b, err := hex.DecodeString(testdata.MarshaledPAC_AD_WIN2K_PAC)
+	require.NoError(t, err, "test vector read error")
+	var pac PACType
+	require.NoError(t, pac.Unmarshal(b), "error unmarshaling test data")
+	assert.Contains(t, pac.String(), "Buffer: KERB_VALIDATION_INFO(1)", "description of unprocessed PAC not as expected")
+	assert.NotContains(t, pac.String(), "EffectiveName", "unprocessed PAC should not describe the client")
+
+	b, _ = hex.DecodeString(testdata.KEYTAB_SYSHTTP_TEST_GOKRB5)
+	kt := keytab.New()
+	kt.Unmarshal(b)
+	pn, _ := types.ParseSPNString("sysHTTP")
+	key, _, err := kt.GetEncryptionKey(pn, "TEST.GOKRB5", 2, 18)
+	require.NoError(t, err, "error getting key")
+	require.NoError(t, pac.ProcessPACInfoBuffers(key, log.New(&bytes.Buffer{}, "", 0)), "error processing PAC")
+	str := pac.String()
+	for _, want := range []string{
+		"Buffer: SERVER_CHECKSUM(6)",
+		"Buffer: KDC_CHECKSUM(7)",
+		"EffectiveName: testuser1",
+		"LogonDomainName: TEST",
+		"ClientName: testuser1",
+		"UPN: testuser1@test.gokrb5",
+	} {
+		assert.Contains(t, str, want, "description of PAC not as expected")
+	}
+}
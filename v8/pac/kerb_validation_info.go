@@ -3,6 +3,7 @@ package pac
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 
 	"github.com/jcmturner/rpc/v2/mstypes"
@@ -108,3 +109,70 @@ func (k *KerbValidationInfo) GetGroupMembershipSIDs() []string {
 	}
 	return g
 }
+
+// jsonKerbValidationInfo is used when marshaling the KerbValidationInfo to JSON format.
+type jsonKerbValidationInfo struct {
+	LogOnTime              string   `json:"logOnTime,omitempty"`
+	LogOffTime             string   `json:"logOffTime,omitempty"`
+	KickOffTime            string   `json:"kickOffTime,omitempty"`
+	PasswordLastSet        string   `json:"passwordLastSet,omitempty"`
+	PasswordCanChange      string   `json:"passwordCanChange,omitempty"`
+	PasswordMustChange     string   `json:"passwordMustChange,omitempty"`
+	EffectiveName          string   `json:"effectiveName"`
+	FullName               string   `json:"fullName,omitempty"`
+	LogonScript            string   `json:"logonScript,omitempty"`
+	ProfilePath            string   `json:"profilePath,omitempty"`
+	HomeDirectory          string   `json:"homeDirectory,omitempty"`
+	HomeDirectoryDrive     string   `json:"homeDirectoryDrive,omitempty"`
+	LogonCount             uint16   `json:"logonCount"`
+	BadPasswordCount       uint16   `json:"badPasswordCount"`
+	UserSID                string   `json:"userSID"`
+	PrimaryGroupSID        string   `json:"primaryGroupSID"`
+	GroupMembershipSIDs    []string `json:"groupMembershipSIDs,omitempty"`
+	UserFlags              uint32   `json:"userFlags"`
+	LogonServer            string   `json:"logonServer,omitempty"`
+	LogonDomainName        string   `json:"logonDomainName"`
+	LogonDomainSID         string   `json:"logonDomainSID"`
+	UserAccountControl     uint32   `json:"userAccountControl"`
+	LastSuccessfulILogon   string   `json:"lastSuccessfulILogon,omitempty"`
+	LastFailedILogon       string   `json:"lastFailedILogon,omitempty"`
+	FailedILogonCount      uint32   `json:"failedILogonCount"`
+	ResourceGroupDomainSID string   `json:"resourceGroupDomainSID,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface. SIDs are rendered in the S-1-... string form and times in
+// RFC3339 format, omitted if not set or never expiring. The user session key is not included.
+func (k KerbValidationInfo) MarshalJSON() ([]byte, error) {
+	lSID := k.LogonDomainID.String()
+	j := jsonKerbValidationInfo{
+		LogOnTime:            jsonFileTime(k.LogOnTime),
+		LogOffTime:           jsonFileTime(k.LogOffTime),
+		KickOffTime:          jsonFileTime(k.KickOffTime),
+		PasswordLastSet:      jsonFileTime(k.PasswordLastSet),
+		PasswordCanChange:    jsonFileTime(k.PasswordCanChange),
+		PasswordMustChange:   jsonFileTime(k.PasswordMustChange),
+		EffectiveName:        k.EffectiveName.Value,
+		FullName:             k.FullName.Value,
+		LogonScript:          k.LogonScript.Value,
+		ProfilePath:          k.ProfilePath.Value,
+		HomeDirectory:        k.HomeDirectory.Value,
+		HomeDirectoryDrive:   k.HomeDirectoryDrive.Value,
+		LogonCount:           k.LogonCount,
+		BadPasswordCount:     k.BadPasswordCount,
+		UserSID:              fmt.Sprintf("%s-%d", lSID, k.UserID),
+		PrimaryGroupSID:      fmt.Sprintf("%s-%d", lSID, k.PrimaryGroupID),
+		GroupMembershipSIDs:  k.GetGroupMembershipSIDs(),
+		UserFlags:            k.UserFlags,
+		LogonServer:          k.LogonServer.Value,
+		LogonDomainName:      k.LogonDomainName.Value,
+		LogonDomainSID:       lSID,
+		UserAccountControl:   k.UserAccountControl,
+		LastSuccessfulILogon: jsonFileTime(k.LastSuccessfulILogon),
+		LastFailedILogon:     jsonFileTime(k.LastFailedILogon),
+		FailedILogonCount:    k.FailedILogonCount,
+	}
+	if len(k.ResourceGroupIDs) > 0 {
+		j.ResourceGroupDomainSID = k.ResourceGroupDomainSID.String()
+	}
+	return json.Marshal(j)
+}
@@ -2,6 +2,7 @@ package pac
 
 import (
 	"encoding/hex"
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -266,3 +267,35 @@ func TestKerbValidationInfo_Unmarshal_DomainTrust(t *testing.T) {
 		"S-1-5-21-3062750306-1230139592-1973306805-1108"}
 	assert.Equal(t, groupSids, k.GetGroupMembershipSIDs(), "GroupMembershipSIDs not as expected")
 }
+
+func TestKerbValidationInfo_MarshalJSON(t *testing.T) {
+	t.Parallel()
+	b, err := hex.DecodeString(testdata.MarshaledPAC_Kerb_Validation_Info_MS)
+	if err != nil {
+		t.Fatal("Could not decode test data hex string")
+	}
+	var k KerbValidationInfo
+	err = k.Unmarshal(b)
+	if err != nil {
+		t.Fatalf("Error unmarshaling KerbValidationInfo: %v", err)
+	}
+	jb, err := json.Marshal(PACType{Version: 0, KerbValidationInfo: &k})
+	if err != nil {
+		t.Fatalf("Error marshaling KerbValidationInfo to JSON: %v", err)
+	}
+	var j struct {
+		KerbValidationInfo map[string]interface{} `json:"kerbValidationInfo"`
+	}
+	err = json.Unmarshal(jb, &j)
+	if err != nil {
+		t.Fatalf("Error unmarshaling JSON: %v", err)
+	}
+	v := j.KerbValidationInfo
+	assert.Equal(t, "2006-04-28T01:42:50Z", v["logOnTime"], "logOnTime not as expected")
+	assert.Equal(t, "lzhu", v["effectiveName"], "effectiveName not as expected")
+	assert.Equal(t, "S-1-5-21-397955417-626881126-188441444", v["logonDomainSID"], "logonDomainSID not as expected")
+	assert.Equal(t, "S-1-5-21-397955417-626881126-188441444-2914711", v["userSID"], "userSID not as expected")
+	assert.Equal(t, "S-1-5-21-397955417-626881126-188441444-513", v["primaryGroupSID"], "primaryGroupSID not as expected")
+	assert.Len(t, v["groupMembershipSIDs"], len(k.GetGroupMembershipSIDs()), "number of group SIDs not as expected")
+	assert.NotContains(t, v, "UserSessionKey", "the user session key should not be included")
+}
@@ -2,9 +2,11 @@ package pac
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/jcmturner/gokrb5/v8/crypto"
 	"github.com/jcmturner/gokrb5/v8/iana/keyusage"
@@ -66,6 +68,11 @@ func (pac *PACType) Unmarshal(b []byte) (err error) {
 	if err != nil {
 		return
 	}
+	// Each info buffer entry is 16 bytes and follows the 8 byte header.
+	// Check the PAC is large enough before allocating for the number of buffers it claims to have.
+	if uint64(pac.CBuffers) > uint64(len(b)-8)/16 {
+		return fmt.Errorf("PAC buffer count of %d exceeds the size of the PAC", pac.CBuffers)
+	}
 	buf := make([]InfoBuffer, pac.CBuffers, pac.CBuffers)
 	for i := range buf {
 		buf[i].ULType, err = r.Uint32()
@@ -80,6 +87,9 @@ func (pac *PACType) Unmarshal(b []byte) (err error) {
 		if err != nil {
 			return
 		}
+		if buf[i].Offset > uint64(len(b)) || uint64(buf[i].CBBufferSize) > uint64(len(b))-buf[i].Offset {
+			return fmt.Errorf("PAC info buffer %d exceeds the bounds of the PAC", i)
+		}
 	}
 	pac.Buffers = buf
 	return nil
@@ -249,3 +259,41 @@ func (pac *PACType) verify(key types.EncryptionKey) (bool, error) {
 
 	return true, nil
 }
+
+// MarshalJSON implements the json.Marshaler interface including the processed info buffers that describe the
+// client. Signatures and credentials are not included.
+func (pac PACType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Version            uint32              `json:"version"`
+		KerbValidationInfo *KerbValidationInfo `json:"kerbValidationInfo,omitempty"`
+		ClientInfo         *ClientInfo         `json:"clientInfo,omitempty"`
+		S4UDelegationInfo  *S4UDelegationInfo  `json:"s4uDelegationInfo,omitempty"`
+		UPNDNSInfo         *UPNDNSInfo         `json:"upnDNSInfo,omitempty"`
+		ClientClaimsInfo   *ClientClaimsInfo   `json:"clientClaims,omitempty"`
+		DeviceClaimsInfo   *DeviceClaimsInfo   `json:"deviceClaims,omitempty"`
+	}{
+		Version:            pac.Version,
+		KerbValidationInfo: pac.KerbValidationInfo,
+		ClientInfo:         pac.ClientInfo,
+		S4UDelegationInfo:  pac.S4UDelegationInfo,
+		UPNDNSInfo:         pac.UPNDNSInfo,
+		ClientClaimsInfo:   pac.ClientClaimsInfo,
+		DeviceClaimsInfo:   pac.DeviceClaimsInfo,
+	})
+}
+
+// jsonFileTime formats the FileTime in RFC3339 format for JSON. An empty string is returned for times that are not
+// set or that indicate a value never expires.
+func jsonFileTime(ft mstypes.FileTime) string {
+	if ft.LowDateTime == 0 && ft.HighDateTime == 0 {
+		return ""
+	}
+	if ft.HighDateTime == 0x7FFFFFFF && ft.LowDateTime == 0xFFFFFFFF {
+		return ""
+	}
+	t := ft.Time()
+	if t.Year() > 9999 {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
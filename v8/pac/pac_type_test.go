@@ -67,3 +67,23 @@ func TestPACTypeVerify(t *testing.T) {
 	}
 
 }
+
+func TestPACTypeUnmarshal_Bounds(t *testing.T) {
+	t.Parallel()
+	var tests = []struct {
+		name string
+		b    []byte
+	}{
+		// Claims a very large number of buffers
+		{"buffer count", []byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0, 1, 0, 0, 0, 8, 0, 0, 0, 24, 0, 0, 0, 0, 0, 0, 0}},
+		// Single buffer with an offset beyond the end of the data
+		{"buffer offset", []byte{1, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 8, 0, 0, 0, 0xff, 0, 0, 0, 0, 0, 0, 0}},
+		// Single buffer with a size beyond the end of the data
+		{"buffer size", []byte{1, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0xff, 0, 0, 0, 16, 0, 0, 0, 0, 0, 0, 0}},
+	}
+	for _, test := range tests {
+		var pac PACType
+		err := pac.Unmarshal(test.b)
+		assert.Error(t, err, "PAC exceeding bounds should not unmarshal: %s", test.name)
+	}
+}
@@ -2,6 +2,7 @@ package pac
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 
 	"github.com/jcmturner/rpc/v2/mstypes"
@@ -24,3 +25,18 @@ func (k *S4UDelegationInfo) Unmarshal(b []byte) (err error) {
 	}
 	return
 }
+
+// MarshalJSON implements the json.Marshaler interface rendering the service names as strings.
+func (k S4UDelegationInfo) MarshalJSON() ([]byte, error) {
+	s := make([]string, len(k.S4UTransitedServices))
+	for i := range k.S4UTransitedServices {
+		s[i] = k.S4UTransitedServices[i].Value
+	}
+	return json.Marshal(struct {
+		S4U2proxyTarget      string   `json:"s4u2proxyTarget"`
+		S4UTransitedServices []string `json:"s4uTransitedServices,omitempty"`
+	}{
+		S4U2proxyTarget:      k.S4U2proxyTarget.Value,
+		S4UTransitedServices: s,
+	})
+}
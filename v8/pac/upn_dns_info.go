@@ -2,6 +2,7 @@ package pac
 
 import (
 	"bytes"
+	"encoding/json"
 
 	"github.com/jcmturner/rpc/v2/mstypes"
 )
@@ -71,3 +72,16 @@ func (k *UPNDNSInfo) Unmarshal(b []byte) (err error) {
 
 	return
 }
+
+// MarshalJSON implements the json.Marshaler interface omitting the buffer lengths and offsets.
+func (k UPNDNSInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		UPN       string `json:"upn"`
+		DNSDomain string `json:"dnsDomain"`
+		Flags     uint32 `json:"flags"`
+	}{
+		UPN:       k.UPN,
+		DNSDomain: k.DNSDomain,
+		Flags:     k.Flags,
+	})
+}
@@ -3,59 +3,166 @@ package service
 import (
 	"time"
 
+	"github.com/jcmturner/gokrb5/v8/asn1"
 	"github.com/jcmturner/gokrb5/v8/credentials"
 	"github.com/jcmturner/gokrb5/v8/iana/errorcode"
 	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/pac"
+	"github.com/jcmturner/gokrb5/v8/types"
 )
 
 // VerifyAPREQ verifies an AP_REQ sent to the service. Returns a boolean for if the AP_REQ is valid and the client's principal name and realm.
 func VerifyAPREQ(APReq *messages.APReq, s *Settings) (bool, *credentials.Credentials, error) {
 	var creds *credentials.Credentials
-	ok, err := APReq.Verify(s.Keytab, s.MaxClockSkew(), s.ClientAddress(), s.KeytabPrincipal())
+	if err := s.checkReplayWindow(); err != nil {
+		return false, creds, err
+	}
+	now := s.Clock().Now().UTC()
+	kt, ktprinc := s.ticketKeytab(&APReq.Ticket)
+	if s.KeyRotation() > 0 {
+		kt = s.rotationKeytab(kt, ktprinc, &APReq.Ticket)
+	}
+	ok, err := APReq.VerifyAt(kt, s.MaxClockSkew(), s.ClientAddress(), ktprinc, now)
+	if a := APReq.Authenticator; s.metrics.ClockSkew != nil && !a.CTime.IsZero() {
+		// The authenticator was decrypted so the client's clock can be measured, even if it is too far out
+		ct := a.CTime.Add(time.Duration(a.Cusec) * time.Microsecond)
+		s.clockSkew(a.CName.PrincipalNameString()+"@"+a.CRealm, ct.Sub(now))
+	}
 	if err != nil || !ok {
 		return false, creds, err
 	}
 
 	if s.RequireHostAddr() && len(APReq.Ticket.DecryptedEncPart.CAddr) < 1 {
-		return false, creds,
-			messages.NewKRBError(APReq.Ticket.SName, APReq.Ticket.Realm, errorcode.KRB_AP_ERR_BADADDR, "ticket does not contain HostAddress values required")
+		return false, creds, newPolicyError(ErrNoHostAddress, APReq, errorcode.KRB_AP_ERR_BADADDR)
+	}
+	if !s.AcceptAnonymous() && isAnonymous(APReq.Ticket.DecryptedEncPart) {
+		return false, creds, newPolicyError(ErrAnonymousClient, APReq, errorcode.KDC_ERR_POLICY)
+	}
+	if authTooOld(APReq.Ticket.DecryptedEncPart, now, s.MaxAuthAge(), s.MaxClockSkew()) {
+		return false, creds, newPolicyError(ErrAuthTooOld, APReq, errorcode.KDC_ERR_POLICY)
+	}
+	indicators, err := APReq.Ticket.DecryptedEncPart.AuthorizationData.AuthIndicators()
+	if err != nil {
+		if len(s.RequireAuthIndicators()) > 0 {
+			return false, creds, err
+		}
+		s.Log("authentication indicators of the ticket could not be processed: %v", err)
+	}
+	if len(s.RequireAuthIndicators()) > 0 && !hasAuthIndicator(indicators, s.RequireAuthIndicators()) {
+		return false, creds, newPolicyError(ErrAuthIndicator, APReq, errorcode.KDC_ERR_POLICY)
 	}
 
 	// Check for replay
-	rc := GetReplayCache(s.MaxClockSkew())
-	if rc.IsReplay(APReq.Ticket.SName, APReq.Authenticator) {
+	replay, err := s.isReplay(APReq)
+	if err != nil {
+		return false, creds, err
+	}
+	if replay {
 		return false, creds,
 			messages.NewKRBError(APReq.Ticket.SName, APReq.Ticket.Realm, errorcode.KRB_AP_ERR_REPEAT, "replay detected")
 	}
 
 	c := credentials.NewFromPrincipalName(APReq.Authenticator.CName, APReq.Authenticator.CRealm)
 	creds = c
-	creds.SetAuthTime(time.Now().UTC())
+	creds.SetAuthTime(now)
 	creds.SetAuthenticated(true)
 	creds.SetValidUntil(APReq.Ticket.DecryptedEncPart.EndTime)
+	e := APReq.Ticket.DecryptedEncPart
+	ti := credentials.TicketInfo{
+		AuthTime:       e.AuthTime,
+		StartTime:      e.StartTime,
+		EndTime:        e.EndTime,
+		RenewTill:      e.RenewTill,
+		Flags:          asn1.BitString{Bytes: copyBytes(e.Flags.Bytes), BitLength: e.Flags.BitLength},
+		AuthIndicators: indicators,
+	}
+	if ti.StartTime.IsZero() {
+		// The start time is optional and the ticket valid from the auth time if it is absent
+		ti.StartTime = ti.AuthTime
+	}
+	creds.SetTicketInfo(ti)
 
 	//PAC decoding
+	var authz *pac.PACType
 	if !s.disablePACDecoding {
-		isPAC, pac, err := APReq.Ticket.GetPACType(s.Keytab, s.KeytabPrincipal(), s.Logger())
+		isPAC, pac, err := APReq.Ticket.GetPACType(kt, ktprinc, s.Logger())
 		if isPAC && err != nil {
-			return false, creds, err
-		}
-		if isPAC {
+			if !s.TolerateInvalidPAC() {
+				return false, creds, err
+			}
+			s.Log("PAC could not be processed, authenticating %s without its authorization data: %v", creds.CName().PrincipalNameString(), err)
+			creds.SetPACUnavailable(err)
+		} else if isPAC {
 			// There is a valid PAC. Adding attributes to creds
-			creds.SetADCredentials(credentials.ADCredentials{
-				GroupMembershipSIDs: pac.KerbValidationInfo.GetGroupMembershipSIDs(),
-				LogOnTime:           pac.KerbValidationInfo.LogOnTime.Time(),
-				LogOffTime:          pac.KerbValidationInfo.LogOffTime.Time(),
-				PasswordLastSet:     pac.KerbValidationInfo.PasswordLastSet.Time(),
-				EffectiveName:       pac.KerbValidationInfo.EffectiveName.Value,
-				FullName:            pac.KerbValidationInfo.FullName.Value,
-				UserID:              int(pac.KerbValidationInfo.UserID),
-				PrimaryGroupID:      int(pac.KerbValidationInfo.PrimaryGroupID),
-				LogonServer:         pac.KerbValidationInfo.LogonServer.Value,
-				LogonDomainName:     pac.KerbValidationInfo.LogonDomainName.Value,
-				LogonDomainID:       pac.KerbValidationInfo.LogonDomainID.String(),
-			})
+			creds.SetADCredentials(adCredentials(pac))
+			authz = &pac
 		}
 	}
+	if err := mapRoles(s, authz, creds); err != nil {
+		return false, creds, err
+	}
 	return true, creds, nil
 }
+
+// VerifiedAPReq provides read only access to the decrypted parts of an AP_REQ once it has been verified, for
+// applications that need to process authorization data themselves or log the details of the exchange.
+type VerifiedAPReq struct {
+	apReq *messages.APReq
+}
+
+// NewVerifiedAPReq returns a VerifiedAPReq for the AP_REQ provided, which must have been verified with VerifyAPREQ.
+func NewVerifiedAPReq(APReq *messages.APReq) VerifiedAPReq {
+	return VerifiedAPReq{apReq: APReq}
+}
+
+// Ticket returns a copy of the decrypted encrypted part of the AP_REQ's ticket.
+func (v VerifiedAPReq) Ticket() messages.EncTicketPart {
+	if v.apReq == nil {
+		return messages.EncTicketPart{}
+	}
+	t := v.apReq.Ticket.DecryptedEncPart
+	t.Flags.Bytes = copyBytes(t.Flags.Bytes)
+	t.Key.KeyValue = copyBytes(t.Key.KeyValue)
+	t.CName.NameString = append([]string(nil), t.CName.NameString...)
+	t.Transited.Contents = copyBytes(t.Transited.Contents)
+	if t.CAddr != nil {
+		addrs := make(types.HostAddresses, len(t.CAddr))
+		for i, a := range t.CAddr {
+			addrs[i] = types.HostAddress{AddrType: a.AddrType, Address: copyBytes(a.Address)}
+		}
+		t.CAddr = addrs
+	}
+	t.AuthorizationData = copyAuthorizationData(t.AuthorizationData)
+	return t
+}
+
+// Authenticator returns a copy of the decrypted authenticator of the AP_REQ.
+func (v VerifiedAPReq) Authenticator() types.Authenticator {
+	if v.apReq == nil {
+		return types.Authenticator{}
+	}
+	a := v.apReq.Authenticator
+	a.CName.NameString = append([]string(nil), a.CName.NameString...)
+	a.Cksum.Checksum = copyBytes(a.Cksum.Checksum)
+	a.SubKey.KeyValue = copyBytes(a.SubKey.KeyValue)
+	a.AuthorizationData = copyAuthorizationData(a.AuthorizationData)
+	return a
+}
+
+func copyAuthorizationData(ad types.AuthorizationData) types.AuthorizationData {
+	if ad == nil {
+		return nil
+	}
+	c := make(types.AuthorizationData, len(ad))
+	for i, e := range ad {
+		c[i] = types.AuthorizationDataEntry{ADType: e.ADType, ADData: copyBytes(e.ADData)}
+	}
+	return c
+}
+
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte(nil), b...)
+}
@@ -6,17 +6,25 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jcmturner/gokrb5/v8/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1tools"
 	"github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/clock"
 	"github.com/jcmturner/gokrb5/v8/config"
 	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/crypto"
+	"github.com/jcmturner/gokrb5/v8/iana/adtype"
+	"github.com/jcmturner/gokrb5/v8/iana/asnAppTag"
 	"github.com/jcmturner/gokrb5/v8/iana/errorcode"
 	"github.com/jcmturner/gokrb5/v8/iana/flags"
+	"github.com/jcmturner/gokrb5/v8/iana/keyusage"
 	"github.com/jcmturner/gokrb5/v8/iana/nametype"
 	"github.com/jcmturner/gokrb5/v8/keytab"
 	"github.com/jcmturner/gokrb5/v8/messages"
 	"github.com/jcmturner/gokrb5/v8/test/testdata"
 	"github.com/jcmturner/gokrb5/v8/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestVerifyAPREQ(t *testing.T) {
@@ -30,9 +38,11 @@ func TestVerifyAPREQ(t *testing.T) {
 	kt := keytab.New()
 	kt.Unmarshal(b)
 	st := time.Now().UTC()
+	f := types.NewKrbFlags()
+	types.SetFlag(&f, flags.Renewable)
 	tkt, sessionKey, err := messages.NewTicket(cl.Credentials.CName(), cl.Credentials.Domain(),
 		sname, "TEST.GOKRB5",
-		types.NewKrbFlags(),
+		f,
 		kt,
 		18,
 		1,
@@ -55,10 +65,19 @@ func TestVerifyAPREQ(t *testing.T) {
 
 	h, _ := types.GetHostAddress("127.0.0.1:1234")
 	s := NewSettings(kt, ClientAddress(h))
-	ok, _, err := VerifyAPREQ(&APReq, s)
+	ok, creds, err := VerifyAPREQ(&APReq, s)
 	if !ok || err != nil {
 		t.Fatalf("Validation of AP_REQ failed when it should not have: %v", err)
 	}
+	ti, ok := creds.GetTicketInfo()
+	if assert.True(t, ok, "credentials should have the ticket details") {
+		assert.WithinDuration(t, st, ti.AuthTime, time.Second, "auth time not as expected")
+		assert.WithinDuration(t, st, ti.StartTime, time.Second, "start time not as expected")
+		assert.WithinDuration(t, st.Add(24*time.Hour), ti.EndTime, time.Second, "end time not as expected")
+		assert.WithinDuration(t, st.Add(48*time.Hour), ti.RenewTill, time.Second, "renew till not as expected")
+		assert.True(t, ti.IsFlagSet(flags.Renewable), "renewable flag should be set")
+		assert.False(t, ti.IsFlagSet(flags.Forwardable), "forwardable flag should not be set")
+	}
 }
 
 func TestVerifyAPREQWithPrincipalOverride(t *testing.T) {
@@ -400,6 +419,140 @@ func TestVerifyAPREQ_ExpiredTicket(t *testing.T) {
 	}
 }
 
+func TestVerifyAPREQ_InvalidPAC(t *testing.T) {
+	t.Parallel()
+	cl := getClient()
+	sname := types.PrincipalName{
+		NameType:   nametype.KRB_NT_PRINCIPAL,
+		NameString: []string{"HTTP", "host.test.gokrb5"},
+	}
+	b, _ := hex.DecodeString(testdata.HTTP_KEYTAB)
+	kt := keytab.New()
+	kt.Unmarshal(b)
+	st := time.Now().UTC()
+	tkt, sessionKey, err := messages.NewTicket(cl.Credentials.CName(), cl.Credentials.Domain(),
+		sname, "TEST.GOKRB5",
+		types.NewKrbFlags(),
+		kt,
+		18,
+		1,
+		st,
+		st,
+		st.Add(time.Duration(24)*time.Hour),
+		st.Add(time.Duration(48)*time.Hour),
+	)
+	if err != nil {
+		t.Fatalf("Error getting test ticket: %v", err)
+	}
+	// Add a PAC that cannot be unmarshaled to the ticket
+	pacb, _ := asn1.Marshal(types.AuthorizationData{{ADType: adtype.ADWin2KPAC, ADData: []byte{1, 2, 3, 4}}})
+	addTestAuthorizationData(t, &tkt, kt, types.AuthorizationDataEntry{ADType: adtype.ADIfRelevant, ADData: pacb})
+
+	h, _ := types.GetHostAddress("127.0.0.1:1234")
+	APReq, err := messages.NewAPReq(tkt, sessionKey, newTestAuthenticator(*cl.Credentials))
+	if err != nil {
+		t.Fatalf("Error getting test AP_REQ: %v", err)
+	}
+	ok, _, err := VerifyAPREQ(&APReq, NewSettings(kt, ClientAddress(h)))
+	if ok || err == nil {
+		t.Fatal("Validation of AP_REQ with invalid PAC passed when it should not have")
+	}
+
+	APReq, err = messages.NewAPReq(tkt, sessionKey, newTestAuthenticator(*cl.Credentials))
+	if err != nil {
+		t.Fatalf("Error getting test AP_REQ: %v", err)
+	}
+	ok, creds, err := VerifyAPREQ(&APReq, NewSettings(kt, ClientAddress(h), TolerateInvalidPAC(true)))
+	if !ok || err != nil {
+		t.Fatalf("Validation of AP_REQ with invalid PAC failed when it should have been tolerated: %v", err)
+	}
+	unavailable, reason := creds.PACUnavailable()
+	assert.True(t, unavailable, "credentials should be marked as PAC unavailable")
+	assert.NotEmpty(t, reason, "reason PAC was unavailable not recorded")
+	assert.Equal(t, credentials.ADCredentials{}, creds.GetADCredentials(), "credentials should not have AD attributes")
+}
+
+// addTestAuthorizationData adds the authorization data entry to the ticket's encrypted part.
+func addTestAuthorizationData(t *testing.T, tkt *messages.Ticket, kt *keytab.Keytab, ad types.AuthorizationDataEntry) {
+	err := tkt.DecryptEncPart(kt, nil)
+	if err != nil {
+		t.Fatalf("Error decrypting test ticket: %v", err)
+	}
+	tkt.DecryptedEncPart.AuthorizationData = append(tkt.DecryptedEncPart.AuthorizationData, ad)
+	b, err := asn1.Marshal(tkt.DecryptedEncPart)
+	if err != nil {
+		t.Fatalf("Error marshaling test ticket encpart: %v", err)
+	}
+	b = asn1tools.AddASNAppTag(b, asnAppTag.EncTicketPart)
+	key, _, err := kt.GetEncryptionKey(tkt.SName, tkt.Realm, tkt.EncPart.KVNO, tkt.EncPart.EType)
+	if err != nil {
+		t.Fatalf("Error getting key for test ticket: %v", err)
+	}
+	tkt.EncPart, err = crypto.GetEncryptedData(b, key, keyusage.KDC_REP_TICKET, tkt.EncPart.KVNO)
+	if err != nil {
+		t.Fatalf("Error encrypting test ticket encpart: %v", err)
+	}
+}
+
+func TestVerifyAPREQ_Clock(t *testing.T) {
+	t.Parallel()
+	cl := getClient()
+	sname := types.PrincipalName{
+		NameType:   nametype.KRB_NT_PRINCIPAL,
+		NameString: []string{"HTTP", "host.test.gokrb5"},
+	}
+	b, _ := hex.DecodeString(testdata.HTTP_KEYTAB)
+	kt := keytab.New()
+	kt.Unmarshal(b)
+	st := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	tkt, sessionKey, err := messages.NewTicket(cl.Credentials.CName(), cl.Credentials.Domain(),
+		sname, "TEST.GOKRB5",
+		types.NewKrbFlags(),
+		kt,
+		18,
+		1,
+		st,
+		st,
+		st.Add(time.Hour),
+		st.Add(time.Duration(48)*time.Hour),
+	)
+	if err != nil {
+		t.Fatalf("Error getting test ticket: %v", err)
+	}
+	h, _ := types.GetHostAddress("127.0.0.1:1234")
+	c := clock.NewMock(st.Add(time.Minute))
+	s := NewSettings(kt, ClientAddress(h), Clock(c))
+
+	var tests = []struct {
+		offset time.Duration
+		code   int32
+	}{
+		{time.Minute, 0},
+		{-time.Hour, errorcode.KRB_AP_ERR_TKT_NYV},
+		{2 * time.Hour, errorcode.KRB_AP_ERR_TKT_EXPIRED},
+	}
+	for _, test := range tests {
+		c.Set(st.Add(test.offset))
+		a, _ := types.NewAuthenticatorAt(cl.Credentials.Domain(), cl.Credentials.CName(), c.Now())
+		APReq, err := messages.NewAPReq(tkt, sessionKey, a)
+		if err != nil {
+			t.Fatalf("Error getting test AP_REQ: %v", err)
+		}
+		ok, creds, err := VerifyAPREQ(&APReq, s)
+		if test.code == 0 {
+			if !ok || err != nil {
+				t.Fatalf("Validation of AP_REQ failed at %v when it should not have: %v", c.Now(), err)
+			}
+			assert.Equal(t, c.Now(), creds.AuthTime(), "auth time should be read from the clock")
+			continue
+		}
+		assert.False(t, ok, "Validation of AP_REQ passed at %v when it should not have", c.Now())
+		if e, ok := err.(messages.KRBError); assert.True(t, ok, "error should be a KRBError") {
+			assert.Equal(t, test.code, e.ErrorCode, "Error code not as expected")
+		}
+	}
+}
+
 func newTestAuthenticator(creds credentials.Credentials) types.Authenticator {
 	auth, _ := types.NewAuthenticator(creds.Domain(), creds.CName())
 	auth.GenerateSeqNumberAndSubKey(18, 32)
@@ -418,3 +571,161 @@ func getClient() *client.Client {
 	cl := client.NewWithKeytab("testuser1", "TEST.GOKRB5", kt, c)
 	return cl
 }
+
+// testAPReqConfig describes the AP_REQ created by testAPReq and is set with its options.
+type testAPReqConfig struct {
+	cname      types.PrincipalName
+	crealm     string
+	sname      string
+	realm      string
+	flags      asn1.BitString
+	authTime   time.Time
+	indicators []string
+}
+
+// testClient sets the client the ticket is issued to.
+func testClient(cname types.PrincipalName, crealm string) func(*testAPReqConfig) {
+	return func(c *testAPReqConfig) {
+		c.cname = cname
+		c.crealm = crealm
+	}
+}
+
+// testService sets the service name and realm the ticket is issued for, such as an alias of the service.
+func testService(sname, realm string) func(*testAPReqConfig) {
+	return func(c *testAPReqConfig) {
+		c.sname = sname
+		c.realm = realm
+	}
+}
+
+// testTicketFlags sets the flags of the ticket.
+func testTicketFlags(f asn1.BitString) func(*testAPReqConfig) {
+	return func(c *testAPReqConfig) {
+		c.flags = f
+	}
+}
+
+// testAuthTime sets the time the client authenticated to obtain the ticket.
+func testAuthTime(at time.Time) func(*testAPReqConfig) {
+	return func(c *testAPReqConfig) {
+		c.authTime = at
+	}
+}
+
+// testAuthIndicators sets the authentication indicators held in the ticket's authorization data.
+func testAuthIndicators(indicators ...string) func(*testAPReqConfig) {
+	return func(c *testAPReqConfig) {
+		c.indicators = indicators
+	}
+}
+
+// testAPReq returns the keytab of HTTP/host.test.gokrb5 and an AP_REQ from testuser1 with a ticket for the service
+// valid for an hour, encrypted with the service's key, as changed by the options provided. The authenticator holds a
+// sequence number and subkey.
+func testAPReq(t *testing.T, opts ...func(*testAPReqConfig)) (*keytab.Keytab, messages.APReq) {
+	c := testAPReqConfig{
+		cname:  types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "testuser1"),
+		crealm: "TEST.GOKRB5",
+		sname:  "HTTP/host.test.gokrb5",
+		realm:  "TEST.GOKRB5",
+		flags:  types.NewKrbFlags(),
+	}
+	for _, o := range opts {
+		o(&c)
+	}
+	b, _ := hex.DecodeString(testdata.HTTP_KEYTAB)
+	kt := keytab.New()
+	require.NoError(t, kt.Unmarshal(b), "error unmarshaling keytab")
+	// The ticket is encrypted with the service's keys held under the name and realm it is issued for
+	sname := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, c.sname)
+	tktKt := keytab.New()
+	for _, e := range kt.Entries {
+		tktKt.AddKey(sname, c.realm, e.Key, e.Timestamp, e.KVNO8)
+	}
+	st := time.Now().UTC()
+	if c.authTime.IsZero() {
+		c.authTime = st
+	}
+	tkt, sessionKey, err := messages.NewTicket(c.cname, c.crealm, sname, c.realm, c.flags, tktKt, 18, 1, c.authTime, st, st.Add(time.Hour), time.Time{})
+	require.NoError(t, err, "error getting test ticket")
+	if len(c.indicators) > 0 {
+		require.NoError(t, tkt.DecryptEncPart(tktKt, nil), "error decrypting test ticket")
+		ind, err := types.NewADAuthenticationIndicator(c.indicators...)
+		require.NoError(t, err, "error creating authentication indicator")
+		ifr, err := types.NewADIfRelevant(ind)
+		require.NoError(t, err, "error creating AD-IF-RELEVANT")
+		e := tkt.DecryptedEncPart
+		e.AuthorizationData = types.AuthorizationData{ifr}
+		b, err := asn1.Marshal(e)
+		require.NoError(t, err, "error marshaling ticket encpart")
+		b = asn1tools.AddASNAppTag(b, asnAppTag.EncTicketPart)
+		key, kvno, err := tktKt.GetEncryptionKey(sname, c.realm, 1, 18)
+		require.NoError(t, err, "error getting service key")
+		tkt.EncPart, err = crypto.GetEncryptedData(b, key, keyusage.KDC_REP_TICKET, kvno)
+		require.NoError(t, err, "error encrypting ticket encpart")
+		tkt.DecryptedEncPart = messages.EncTicketPart{}
+	}
+	auth, err := types.NewAuthenticator(c.crealm, c.cname)
+	require.NoError(t, err, "error getting test authenticator")
+	auth.GenerateSeqNumberAndSubKey(18, 32)
+	APReq, err := messages.NewAPReq(tkt, sessionKey, auth)
+	require.NoError(t, err, "error getting test AP_REQ")
+	return kt, APReq
+}
+
+func TestVerifiedAPReq(t *testing.T) {
+	t.Parallel()
+	cl := getClient()
+	sname := types.PrincipalName{
+		NameType:   nametype.KRB_NT_PRINCIPAL,
+		NameString: []string{"HTTP", "host.test.gokrb5"},
+	}
+	b, _ := hex.DecodeString(testdata.HTTP_KEYTAB)
+	kt := keytab.New()
+	kt.Unmarshal(b)
+	st := time.Now().UTC()
+	tkt, sessionKey, err := messages.NewTicket(cl.Credentials.CName(), cl.Credentials.Domain(),
+		sname, "TEST.GOKRB5",
+		types.NewKrbFlags(),
+		kt,
+		18,
+		1,
+		st,
+		st,
+		st.Add(time.Duration(24)*time.Hour),
+		st.Add(time.Duration(48)*time.Hour),
+	)
+	if err != nil {
+		t.Fatalf("Error getting test ticket: %v", err)
+	}
+	APReq, err := messages.NewAPReq(
+		tkt,
+		sessionKey,
+		newTestAuthenticator(*cl.Credentials),
+	)
+	if err != nil {
+		t.Fatalf("Error getting test AP_REQ: %v", err)
+	}
+
+	h, _ := types.GetHostAddress("127.0.0.1:1234")
+	s := NewSettings(kt, ClientAddress(h))
+	ok, _, err := VerifyAPREQ(&APReq, s)
+	if !ok || err != nil {
+		t.Fatalf("Validation of AP_REQ failed when it should not have: %v", err)
+	}
+	v := NewVerifiedAPReq(&APReq)
+	et := v.Ticket()
+	assert.Equal(t, cl.Credentials.CName().NameString, et.CName.NameString, "ticket client name not as expected")
+	assert.Equal(t, sessionKey.KeyValue, et.Key.KeyValue, "ticket session key not as expected")
+	a := v.Authenticator()
+	assert.Equal(t, cl.Credentials.CName().NameString, a.CName.NameString, "authenticator client name not as expected")
+
+	// Modifying the values returned must not affect the AP_REQ
+	et.Key.KeyValue[0] ^= 0xff
+	et.CName.NameString[0] = "modified"
+	a.CName.NameString[0] = "modified"
+	assert.Equal(t, sessionKey.KeyValue, v.Ticket().Key.KeyValue, "ticket session key should not be modifiable")
+	assert.Equal(t, cl.Credentials.CName().NameString, v.Ticket().CName.NameString, "ticket client name should not be modifiable")
+	assert.Equal(t, cl.Credentials.CName().NameString, v.Authenticator().CName.NameString, "authenticator client name should not be modifiable")
+}
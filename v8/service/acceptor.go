@@ -0,0 +1,88 @@
+package service
+
+import (
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1tools"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/iana/flags"
+	"github.com/jcmturner/gokrb5/v8/krberror"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// Acceptance is the outcome of accepting an AP_REQ sent to a service that is not HTTP based: the authenticated
+// client, the key and initial sequence number with which subsequent messages of the exchange are protected and, if
+// the client requested mutual authentication, the AP_REP to send to the client.
+type Acceptance struct {
+	Credentials *credentials.Credentials
+	Key         types.EncryptionKey
+	SeqNumber   int64
+	APRep       []byte
+}
+
+// Accept verifies the bytes of an AP_REQ sent to the service as VerifyAPREQ does, including the detection of its
+// replay, and returns the client it authenticates. If the AP_REQ has the mutual-required AP option set the AP_REP for
+// the client is generated. The error of an AP_REQ that is not valid is a messages.KRBError where one should be
+// returned to the client.
+func (s *Service) Accept(b []byte) (Acceptance, error) {
+	if s.settings.TolerateBER() {
+		der, err := asn1.NormalizeBER(b)
+		if err != nil {
+			return Acceptance{}, krberror.Errorf(err, krberror.EncodingError, "could not unmarshal AP_REQ")
+		}
+		b = der
+	}
+	if s.settings.StrictDecoding() {
+		if err := asn1tools.CheckDER(b); err != nil {
+			return Acceptance{}, krberror.Errorf(err, krberror.EncodingError, "could not unmarshal AP_REQ")
+		}
+	}
+	var APReq messages.APReq
+	if err := APReq.Unmarshal(b); err != nil {
+		return Acceptance{}, krberror.Errorf(err, krberror.EncodingError, "could not unmarshal AP_REQ")
+	}
+	ok, creds, err := s.VerifyAPREQ(&APReq)
+	if err != nil {
+		return Acceptance{}, err
+	}
+	if !ok {
+		return Acceptance{}, krberror.New(krberror.KRBMsgError, "AP_REQ is not valid")
+	}
+	a := Acceptance{
+		Credentials: creds,
+		Key:         APReq.Authenticator.SubKey,
+		SeqNumber:   APReq.Authenticator.SeqNumber,
+	}
+	if len(a.Key.KeyValue) == 0 {
+		a.Key = APReq.Ticket.DecryptedEncPart.Key
+	}
+	if types.IsFlagSet(&APReq.APOptions, flags.APOptionMutualRequired) {
+		rep, err := NewAPRep(&APReq)
+		if err != nil {
+			return Acceptance{}, err
+		}
+		a.APRep, err = rep.Marshal()
+		if err != nil {
+			return Acceptance{}, err
+		}
+	}
+	return a, nil
+}
+
+// NewAPRep returns the AP_REP with which the service mutually authenticates itself to the client of the verified
+// AP_REQ, echoing the time of the client's authenticator and its sequence number, encrypted with the session key of
+// the ticket. RFC 4120 section 3.2.4
+func NewAPRep(APReq *messages.APReq) (messages.APRep, error) {
+	a := APReq.Authenticator
+	rep := messages.NewAPRep(messages.EncAPRepPart{
+		CTime:          a.CTime.Truncate(time.Second),
+		Cusec:          a.Cusec,
+		SequenceNumber: a.SeqNumber,
+	})
+	if err := rep.EncryptEncPart(APReq.Ticket.DecryptedEncPart.Key); err != nil {
+		return rep, err
+	}
+	return rep, nil
+}
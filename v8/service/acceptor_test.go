@@ -0,0 +1,107 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jcmturner/gokrb5/v8/iana/errorcode"
+	"github.com/jcmturner/gokrb5/v8/iana/flags"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_Accept(t *testing.T) {
+	t.Parallel()
+	kt, APReq := testAPReq(t)
+	s, err := NewService(WithKeytab(kt), WithSettings(ClientAddress(testHostAddr()), ReplayCache(NewMemoryReplayStore())))
+	require.NoError(t, err, "error creating service")
+
+	b, err := APReq.Marshal()
+	require.NoError(t, err, "error marshaling AP_REQ")
+	require.NoError(t, APReq.Ticket.DecryptEncPart(kt, nil), "error decrypting ticket")
+	require.NoError(t, APReq.DecryptAuthenticator(APReq.Ticket.DecryptedEncPart.Key), "error decrypting authenticator")
+	a, err := s.Accept(b)
+	require.NoError(t, err, "AP_REQ not accepted")
+	assert.Equal(t, "testuser1", a.Credentials.UserName(), "client name not as expected")
+	assert.Equal(t, APReq.Authenticator.SubKey, a.Key, "authenticator subkey should be used")
+	assert.Equal(t, APReq.Authenticator.SeqNumber, a.SeqNumber, "sequence number not as expected")
+	assert.Nil(t, a.APRep, "AP_REP should not be generated without mutual authentication")
+
+	_, err = s.Accept(b)
+	var e messages.KRBError
+	if assert.True(t, errors.As(err, &e), "expected a KRBError for a replay: %v", err) {
+		assert.Equal(t, errorcode.KRB_AP_ERR_REPEAT, e.ErrorCode, "error code not as expected")
+	}
+
+	_, err = s.Accept(b[:len(b)/2])
+	assert.Error(t, err, "truncated AP_REQ should not be accepted")
+}
+
+func TestService_Accept_Mutual(t *testing.T) {
+	t.Parallel()
+	kt, APReq := testAPReq(t)
+	types.SetFlag(&APReq.APOptions, flags.APOptionMutualRequired)
+	s, err := NewService(WithKeytab(kt), WithSettings(ClientAddress(testHostAddr())))
+	require.NoError(t, err, "error creating service")
+	b, err := APReq.Marshal()
+	require.NoError(t, err, "error marshaling AP_REQ")
+	require.NoError(t, APReq.Ticket.DecryptEncPart(kt, nil), "error decrypting ticket")
+	sessionKey := APReq.Ticket.DecryptedEncPart.Key
+	require.NoError(t, APReq.DecryptAuthenticator(sessionKey), "error decrypting authenticator")
+	auth := APReq.Authenticator
+	a, err := s.Accept(b)
+	require.NoError(t, err, "AP_REQ not accepted")
+	require.NotNil(t, a.APRep, "AP_REP should be generated for mutual authentication")
+
+	var rep messages.APRep
+	require.NoError(t, rep.Unmarshal(a.APRep), "error unmarshaling AP_REP")
+	require.NoError(t, rep.DecryptEncPart(sessionKey), "error decrypting AP_REP")
+	assert.NoError(t, rep.VerifyAuthenticator(auth), "AP_REP should echo the authenticator")
+	assert.Equal(t, auth.SeqNumber, rep.DecryptedEncPart.SequenceNumber, "sequence number not as expected")
+}
+
+func TestService_Accept_TolerateBER(t *testing.T) {
+	t.Parallel()
+	kt, APReq := testAPReq(t)
+	b, err := APReq.Marshal()
+	require.NoError(t, err, "error marshaling AP_REQ")
+	// Encode the outer APPLICATION 14 element with an indefinite length
+	content := b[2:]
+	if b[1]&0x80 != 0 {
+		content = b[2+int(b[1]&0x7f):]
+	}
+	ber := append(append([]byte{b[0], 0x80}, content...), 0x00, 0x00)
+
+	s, err := NewService(WithKeytab(kt), WithSettings(ClientAddress(testHostAddr())))
+	require.NoError(t, err, "error creating service")
+	_, err = s.Accept(ber)
+	assert.Error(t, err, "BER encoded AP_REQ should not be accepted by default")
+
+	s, err = NewService(WithKeytab(kt), WithSettings(ClientAddress(testHostAddr()), TolerateBER(true)))
+	require.NoError(t, err, "error creating service")
+	a, err := s.Accept(ber)
+	require.NoError(t, err, "BER encoded AP_REQ should be accepted when tolerated")
+	assert.Equal(t, "testuser1", a.Credentials.UserName(), "client name not as expected")
+	assert.False(t, NewSettings(kt, TolerateBER(true), StrictDecoding(true)).TolerateBER(), "BER should not be tolerated when decoding strictly")
+}
+
+func TestService_Accept_StrictDecoding(t *testing.T) {
+	t.Parallel()
+	kt, APReq := testAPReq(t)
+	b, err := APReq.Marshal()
+	require.NoError(t, err, "error marshaling AP_REQ")
+	trailing := append(b, 0x00, 0x00)
+
+	s, err := NewService(WithKeytab(kt), WithSettings(ClientAddress(testHostAddr()), StrictDecoding(true)))
+	require.NoError(t, err, "error creating service")
+	_, err = s.Accept(trailing)
+	assert.Error(t, err, "AP_REQ with trailing bytes should not be accepted when decoding strictly")
+
+	s, err = NewService(WithKeytab(kt), WithSettings(ClientAddress(testHostAddr())))
+	require.NoError(t, err, "error creating service")
+	a, err := s.Accept(trailing)
+	require.NoError(t, err, "trailing bytes should be accepted when decoding leniently")
+	assert.Equal(t, "testuser1", a.Credentials.UserName(), "client name not as expected")
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/jcmturner/gokrb5/v8/credentials"
+)
+
+var (
+	// ErrPrincipalNotAllowed is the reason a client is denied access when its principal does not match any of the
+	// patterns of the AccessRule.
+	ErrPrincipalNotAllowed = errors.New("client principal is not allowed")
+	// ErrSIDNotAllowed is the reason a client is denied access when neither its user SID nor any of its group SIDs
+	// are among those of the AccessRule.
+	ErrSIDNotAllowed = errors.New("client is not a member of an allowed group")
+	// ErrAuthIndicatorNotAllowed is the reason a client is denied access when its ticket does not carry any of the
+	// authentication indicators of the AccessRule.
+	ErrAuthIndicatorNotAllowed = errors.New("client did not authenticate with an allowed method")
+)
+
+// AccessRule declares the clients allowed to access the paths under a prefix once they are authenticated. Each of its
+// lists that is not empty must be satisfied by at least one of its entries for the client to be allowed.
+type AccessRule struct {
+	// PathPrefix is the path prefix of the requests the rule applies to, such as "/admin/". Where the prefixes of
+	// several rules match a path the rule with the longest prefix applies.
+	PathPrefix string
+	// Principals are patterns, in the syntax of path.Match, of the client principals allowed in the form
+	// name@REALM. As with paths * does not match a /, so "*@EXAMPLE.COM" matches users of the realm but not
+	// services such as "HTTP/host@EXAMPLE.COM".
+	Principals []string
+	// SIDs are the user or group SIDs from the PAC of the clients allowed.
+	SIDs []string
+	// AuthIndicators are the authentication indicators, such as "pkinit" or "otp", the tickets of the clients
+	// allowed must carry.
+	AuthIndicators []string
+}
+
+// Allows returns nil if the client with the credentials provided is allowed by the rule, otherwise an error wrapping
+// the ErrPrincipalNotAllowed, ErrSIDNotAllowed or ErrAuthIndicatorNotAllowed reason.
+func (a AccessRule) Allows(creds *credentials.Credentials) error {
+	if len(a.Principals) > 0 {
+		p := creds.CName().PrincipalNameString() + "@" + creds.Domain()
+		var ok bool
+		for _, pattern := range a.Principals {
+			if m, err := path.Match(pattern, p); err == nil && m {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return fmt.Errorf("%s: %w", p, ErrPrincipalNotAllowed)
+		}
+	}
+	if len(a.SIDs) > 0 {
+		ad := creds.GetADCredentials()
+		sids := ad.GroupMembershipSIDs
+		if ad.LogonDomainID != "" {
+			sids = append([]string{fmt.Sprintf("%s-%d", ad.LogonDomainID, ad.UserID)}, sids...)
+		}
+		if !intersects(sids, a.SIDs) {
+			return fmt.Errorf("%s: %w", creds.UserName(), ErrSIDNotAllowed)
+		}
+	}
+	if len(a.AuthIndicators) > 0 {
+		ti, _ := creds.GetTicketInfo()
+		if !intersects(ti.AuthIndicators, a.AuthIndicators) {
+			return fmt.Errorf("%s: %w", creds.UserName(), ErrAuthIndicatorNotAllowed)
+		}
+	}
+	return nil
+}
+
+// intersects reports if any of the values of a is also in b.
+func intersects(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AccessRule returns the configured AccessRule that applies to the path provided, that with the longest prefix of
+// the path. False is returned if no rule applies.
+func (s *Settings) AccessRule(p string) (AccessRule, bool) {
+	var rule AccessRule
+	var ok bool
+	for _, r := range s.accessRules {
+		if strings.HasPrefix(p, r.PathPrefix) && (!ok || len(r.PathPrefix) > len(rule.PathPrefix)) {
+			rule = r
+			ok = true
+		}
+	}
+	return rule, ok
+}
+
+// CheckAccess returns nil if the client with the credentials provided is allowed to access the path by the rule that
+// applies to it, or if no rule applies.
+func (s *Settings) CheckAccess(p string, creds *credentials.Credentials) error {
+	if r, ok := s.AccessRule(p); ok {
+		return r.Allows(creds)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessRule_Allows(t *testing.T) {
+	t.Parallel()
+	creds := credentials.New("testuser1", "TEST.GOKRB5")
+	creds.SetADCredentials(credentials.ADCredentials{
+		LogonDomainID:       "S-1-5-21-1-2-3",
+		UserID:              1105,
+		GroupMembershipSIDs: []string{testGroupSID},
+	})
+	creds.SetTicketInfo(credentials.TicketInfo{AuthIndicators: []string{"otp"}})
+
+	var tests = []struct {
+		rule AccessRule
+		err  error
+	}{
+		{AccessRule{}, nil},
+		{AccessRule{Principals: []string{"*@TEST.GOKRB5"}}, nil},
+		{AccessRule{Principals: []string{"admin@TEST.GOKRB5", "testuser?@TEST.GOKRB5"}}, nil},
+		{AccessRule{Principals: []string{"*@OTHER.GOKRB5"}}, ErrPrincipalNotAllowed},
+		{AccessRule{Principals: []string{"*/*@TEST.GOKRB5"}}, ErrPrincipalNotAllowed},
+		{AccessRule{SIDs: []string{testOtherSID, testGroupSID}}, nil},
+		{AccessRule{SIDs: []string{"S-1-5-21-1-2-3-1105"}}, nil},
+		{AccessRule{SIDs: []string{testOtherSID}}, ErrSIDNotAllowed},
+		{AccessRule{AuthIndicators: []string{"pkinit", "otp"}}, nil},
+		{AccessRule{AuthIndicators: []string{"pkinit"}}, ErrAuthIndicatorNotAllowed},
+		{AccessRule{Principals: []string{"*@TEST.GOKRB5"}, SIDs: []string{testOtherSID}}, ErrSIDNotAllowed},
+	}
+	for i, test := range tests {
+		err := test.rule.Allows(creds)
+		if test.err == nil {
+			assert.NoError(t, err, "rule %d should allow the client", i)
+		} else {
+			assert.True(t, errors.Is(err, test.err), "rule %d should deny the client with %v: %v", i, test.err, err)
+		}
+	}
+
+	// Clients without a PAC or ticket details are denied by rules requiring them
+	creds = credentials.New("testuser1", "TEST.GOKRB5")
+	assert.True(t, errors.Is(AccessRule{SIDs: []string{testGroupSID}}.Allows(creds), ErrSIDNotAllowed), "client without a PAC should be denied")
+	assert.True(t, errors.Is(AccessRule{AuthIndicators: []string{"otp"}}.Allows(creds), ErrAuthIndicatorNotAllowed), "client without indicators should be denied")
+}
+
+func TestSettings_AccessRule(t *testing.T) {
+	t.Parallel()
+	s := NewSettings(keytab.New(), AccessRules(
+		AccessRule{PathPrefix: "/", Principals: []string{"*@TEST.GOKRB5"}},
+		AccessRule{PathPrefix: "/admin/", Principals: []string{"admin@TEST.GOKRB5"}},
+		AccessRule{PathPrefix: "/api/", AuthIndicators: []string{"pkinit"}},
+	))
+	r, ok := s.AccessRule("/admin/users")
+	assert.True(t, ok, "a rule should apply")
+	assert.Equal(t, "/admin/", r.PathPrefix, "the rule with the longest prefix should apply")
+	r, ok = s.AccessRule("/index.html")
+	assert.True(t, ok, "a rule should apply")
+	assert.Equal(t, "/", r.PathPrefix, "rule not as expected")
+
+	creds := credentials.New("testuser1", "TEST.GOKRB5")
+	assert.NoError(t, s.CheckAccess("/index.html", creds), "client should be allowed")
+	assert.True(t, errors.Is(s.CheckAccess("/admin/users", creds), ErrPrincipalNotAllowed), "client should be denied")
+	assert.True(t, errors.Is(s.CheckAccess("/api/v1", creds), ErrAuthIndicatorNotAllowed), "client should be denied")
+
+	s = NewSettings(keytab.New(), AccessRules(AccessRule{PathPrefix: "/admin/"}))
+	_, ok = s.AccessRule("/index.html")
+	assert.False(t, ok, "no rule should apply")
+	assert.NoError(t, s.CheckAccess("/index.html", creds), "client should be allowed where no rule applies")
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// ticketKeytab returns the keytab and principal override to decrypt the ticket with.
+// If the ticket is for an alias of a principal in the keytab the keys of the principal are returned in a keytab under
+// the ticket's principal name and realm.
+func (s *Settings) ticketKeytab(t *messages.Ticket) (*keytab.Keytab, *types.PrincipalName) {
+	if len(s.aliases) < 1 {
+		return s.Keytab, s.KeytabPrincipal()
+	}
+	name := t.SName.PrincipalNameString()
+	target, ok := s.aliases[name+"@"+t.Realm]
+	if !ok {
+		target, ok = s.aliases[name]
+	}
+	if !ok {
+		return s.Keytab, s.KeytabPrincipal()
+	}
+	pn, realm := types.ParseSPNString(target)
+	if realm == "" {
+		realm = t.Realm
+	}
+	kt := keytab.New()
+	for _, e := range s.Keytab.Entries {
+		if e.Principal.Realm != realm || strings.Join(e.Principal.Components, "/") != pn.PrincipalNameString() {
+			continue
+		}
+		e.Principal.Realm = t.Realm
+		e.Principal.Components = t.SName.NameString
+		e.Principal.NumComponents = int16(len(t.SName.NameString))
+		kt.Entries = append(kt.Entries, e)
+	}
+	s.Log("using the keys of %s@%s for the alias %s@%s", pn.PrincipalNameString(), realm, name, t.Realm)
+	return kt, nil
+}
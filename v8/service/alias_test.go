@@ -0,0 +1,49 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyAPREQ_PrincipalAliases(t *testing.T) {
+	t.Parallel()
+	var tests = []struct {
+		name    string
+		alias   string
+		realm   string
+		aliases map[string]string
+		ok      bool
+	}{
+		{"no aliases", "HTTP/www.test.gokrb5", "TEST.GOKRB5", nil, false},
+		{"qualified", "HTTP/www.test.gokrb5", "TEST.GOKRB5", map[string]string{"HTTP/www.test.gokrb5@TEST.GOKRB5": "HTTP/host.test.gokrb5@TEST.GOKRB5"}, true},
+		{"any realm", "HTTP/www.test.gokrb5", "TEST.GOKRB5", map[string]string{"HTTP/www.test.gokrb5": "HTTP/host.test.gokrb5"}, true},
+		{"other realm", "HTTP/www.other.gokrb5", "OTHER.GOKRB5", map[string]string{"HTTP/www.other.gokrb5@OTHER.GOKRB5": "HTTP/host.test.gokrb5@TEST.GOKRB5"}, true},
+		{"realm not matched", "HTTP/www.other.gokrb5", "OTHER.GOKRB5", map[string]string{"HTTP/www.other.gokrb5@TEST.GOKRB5": "HTTP/host.test.gokrb5@TEST.GOKRB5"}, false},
+		{"principal not in keytab", "HTTP/www.test.gokrb5", "TEST.GOKRB5", map[string]string{"HTTP/www.test.gokrb5": "HTTP/missing.test.gokrb5"}, false},
+	}
+	for _, test := range tests {
+		kt, APReq := testAPReq(t, testService(test.alias, test.realm))
+		ok, creds, err := VerifyAPREQ(&APReq, NewSettings(kt, PrincipalAliases(test.aliases)))
+		if !test.ok {
+			assert.False(t, ok, "%s: AP_REQ should not be verified", test.name)
+			assert.Error(t, err, "%s: expected error", test.name)
+			continue
+		}
+		require.NoError(t, err, "%s: AP_REQ should be verified", test.name)
+		assert.True(t, ok, "%s: AP_REQ should be verified", test.name)
+		assert.Equal(t, "testuser1", creds.UserName(), "%s: client not as expected", test.name)
+	}
+}
+
+func TestSettings_PrincipalAliases(t *testing.T) {
+	t.Parallel()
+	m := map[string]string{"HTTP/www.test.gokrb5": "HTTP/host.test.gokrb5"}
+	s := NewSettings(nil, PrincipalAliases(m), PrincipalAliases(map[string]string{"HTTP/web.test.gokrb5": "HTTP/host.test.gokrb5"}))
+	m["HTTP/other.test.gokrb5"] = "HTTP/host.test.gokrb5"
+	assert.Equal(t, map[string]string{
+		"HTTP/www.test.gokrb5": "HTTP/host.test.gokrb5",
+		"HTTP/web.test.gokrb5": "HTTP/host.test.gokrb5",
+	}, s.PrincipalAliases(), "aliases not as expected")
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/pac"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// AuthzData is the authorization data of an authenticated client provided to a RoleMapper.
+// The SIDs and claims are taken from the PAC of the client's ticket and are empty if the ticket had no PAC or it
+// could not be processed.
+type AuthzData struct {
+	CName     types.PrincipalName
+	Realm     string
+	UserSID   string
+	GroupSIDs []string
+	// SIDNames maps the user and group SIDs to their names if a SIDResolver is configured.
+	SIDNames     map[string]string
+	ClientClaims []pac.Claim
+	DeviceClaims []pac.Claim
+}
+
+// Authorization is the application roles and attributes a RoleMapper assigns to a client.
+type Authorization struct {
+	// Roles are added to the credentials as authorization attributes to be checked with Authorized.
+	Roles []string
+	// Attributes are set on the credentials.
+	Attributes map[string]interface{}
+}
+
+// RoleMapper maps the authorization data of a client to its application roles and attributes.
+// If an error is returned the client is not authenticated.
+type RoleMapper func(AuthzData) (Authorization, error)
+
+// SIDResolver resolves SIDs to account names, for example by searching a directory over LDAP for objects with the
+// objectSid values and returning their sAMAccountName. SIDs that cannot be resolved are omitted from the map returned.
+type SIDResolver interface {
+	ResolveSIDs(sids []string) (map[string]string, error)
+}
+
+// SIDResolverFunc is an adapter to allow the use of a function as a SIDResolver.
+type SIDResolverFunc func(sids []string) (map[string]string, error)
+
+// ResolveSIDs calls f(sids).
+func (f SIDResolverFunc) ResolveSIDs(sids []string) (map[string]string, error) {
+	return f(sids)
+}
+
+// GroupRoles returns a RoleMapper assigning roles by group. The map's keys are group SIDs or, if a SIDResolver is
+// configured, group names and its values the roles granted to members of the group.
+func GroupRoles(m map[string][]string) RoleMapper {
+	return func(d AuthzData) (Authorization, error) {
+		var a Authorization
+		seen := make(map[string]bool)
+		add := func(k string) {
+			for _, r := range m[k] {
+				if !seen[r] {
+					seen[r] = true
+					a.Roles = append(a.Roles, r)
+				}
+			}
+		}
+		for _, sid := range d.GroupSIDs {
+			add(sid)
+			if n, ok := d.SIDNames[sid]; ok {
+				add(n)
+			}
+		}
+		return a, nil
+	}
+}
+
+// adCredentials returns the ADCredentials from the PAC.
+func adCredentials(p pac.PACType) credentials.ADCredentials {
+	k := p.KerbValidationInfo
+	a := credentials.ADCredentials{
+		GroupMembershipSIDs: k.GetGroupMembershipSIDs(),
+		LogOnTime:           k.LogOnTime.Time(),
+		LogOffTime:          k.LogOffTime.Time(),
+		PasswordLastSet:     k.PasswordLastSet.Time(),
+		EffectiveName:       k.EffectiveName.Value,
+		FullName:            k.FullName.Value,
+		UserID:              int(k.UserID),
+		PrimaryGroupID:      int(k.PrimaryGroupID),
+		LogonServer:         k.LogonServer.Value,
+		LogonDomainName:     k.LogonDomainName.Value,
+		LogonDomainID:       k.LogonDomainID.String(),
+	}
+	if p.UPNDNSInfo != nil {
+		a.UserPrincipalName = p.UPNDNSInfo.UPN
+		a.DNSDomainName = p.UPNDNSInfo.DNSDomain
+	}
+	return a
+}
+
+// mapRoles applies the configured RoleMapper to the credentials using the PAC provided, which may be nil.
+func mapRoles(s *Settings, p *pac.PACType, creds *credentials.Credentials) error {
+	if s.roleMapper == nil {
+		return nil
+	}
+	d := AuthzData{
+		CName: creds.CName(),
+		Realm: creds.Domain(),
+	}
+	if p != nil && p.KerbValidationInfo != nil {
+		k := p.KerbValidationInfo
+		d.UserSID = fmt.Sprintf("%s-%d", k.LogonDomainID.String(), k.UserID)
+		d.GroupSIDs = k.GetGroupMembershipSIDs()
+	}
+	if p != nil && p.ClientClaimsInfo != nil {
+		d.ClientClaims = p.ClientClaimsInfo.Claims()
+	}
+	if p != nil && p.DeviceClaimsInfo != nil {
+		d.DeviceClaims = p.DeviceClaimsInfo.Claims()
+	}
+	if s.sidResolver != nil && d.UserSID != "" {
+		names, err := s.sidResolver.ResolveSIDs(append([]string{d.UserSID}, d.GroupSIDs...))
+		if err != nil {
+			// Mapping continues by SID so that a directory outage does not prevent all authentication
+			s.Log("could not resolve SIDs of %s: %v", d.CName.PrincipalNameString(), err)
+		}
+		d.SIDNames = names
+	}
+	a, err := s.roleMapper(d)
+	if err != nil {
+		return fmt.Errorf("role mapping of %s failed: %w", d.CName.PrincipalNameString(), err)
+	}
+	for _, r := range a.Roles {
+		creds.AddAuthzAttribute(r)
+	}
+	for k, v := range a.Attributes {
+		creds.SetAttribute(k, v)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/pac"
+	"github.com/jcmturner/gokrb5/v8/test/testdata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testDomainSID = "S-1-5-21-2284869408-3503417140-1141177250"
+	testGroupSID  = testDomainSID + "-1110"
+	testOtherSID  = "S-1-5-21-3062750306-1230139592-1973306805-1107"
+)
+
+func testPACType(t *testing.T) *pac.PACType {
+	b, err := hex.DecodeString(testdata.MarshaledPAC_Kerb_Validation_Info_Trust)
+	require.NoError(t, err, "could not decode test data hex string")
+	var k pac.KerbValidationInfo
+	require.NoError(t, k.Unmarshal(b), "error unmarshaling test KerbValidationInfo")
+	b, err = hex.DecodeString(testdata.MarshaledPAC_ClientClaimsInfoStr)
+	require.NoError(t, err, "could not decode test data hex string")
+	var c pac.ClientClaimsInfo
+	require.NoError(t, c.Unmarshal(b), "error unmarshaling test ClientClaimsInfo")
+	return &pac.PACType{KerbValidationInfo: &k, ClientClaimsInfo: &c}
+}
+
+func TestMapRoles(t *testing.T) {
+	t.Parallel()
+	var d AuthzData
+	s := NewSettings(nil, RoleMapping(func(ad AuthzData) (Authorization, error) {
+		d = ad
+		return Authorization{
+			Roles:      []string{"reader"},
+			Attributes: map[string]interface{}{"department": "engineering"},
+		}, nil
+	}))
+	creds := credentials.New("testuser1", "TEST.GOKRB5")
+	err := mapRoles(s, testPACType(t), creds)
+	require.NoError(t, err, "error mapping roles")
+	assert.Equal(t, testDomainSID+"-1106", d.UserSID, "user SID not as expected")
+	assert.Contains(t, d.GroupSIDs, testGroupSID, "group SIDs not as expected")
+	assert.Equal(t, []pac.Claim{{ID: "ad://ext/sAMAccountName:88d5d9085ea5c0c0", Values: []string{"testuser1"}}}, d.ClientClaims, "client claims not as expected")
+	assert.Nil(t, d.SIDNames, "SIDs should not be resolved without a resolver")
+	assert.True(t, creds.Authorized("reader"), "role should be added to the credentials")
+	assert.Equal(t, "engineering", creds.Attributes()["department"], "attribute should be set on the credentials")
+
+	s = NewSettings(nil, RoleMapping(func(AuthzData) (Authorization, error) {
+		return Authorization{}, errors.New("denied")
+	}))
+	err = mapRoles(s, nil, credentials.New("testuser1", "TEST.GOKRB5"))
+	assert.Error(t, err, "a mapping error should be returned")
+
+	err = mapRoles(NewSettings(nil), testPACType(t), credentials.New("testuser1", "TEST.GOKRB5"))
+	assert.NoError(t, err, "no mapping should be applied without a mapper")
+}
+
+func TestGroupRoles(t *testing.T) {
+	t.Parallel()
+	resolver := SIDResolverFunc(func(sids []string) (map[string]string, error) {
+		return map[string]string{testOtherSID: "Trusted Admins"}, nil
+	})
+	s := NewSettings(nil,
+		RoleMapping(GroupRoles(map[string][]string{
+			testGroupSID:     {"reader"},
+			"Trusted Admins": {"admin", "reader"},
+			"S-1-1-0":        {"everyone"},
+		})),
+		ResolveSIDs(resolver),
+	)
+	creds := credentials.New("testuser1", "TEST.GOKRB5")
+	err := mapRoles(s, testPACType(t), creds)
+	require.NoError(t, err, "error mapping roles")
+	assert.True(t, creds.Authorized("reader"), "role mapped by group SID not granted")
+	assert.True(t, creds.Authorized("admin"), "role mapped by resolved group name not granted")
+	assert.False(t, creds.Authorized("everyone"), "role of a group not in the PAC granted")
+
+	failing := SIDResolverFunc(func(sids []string) (map[string]string, error) {
+		return nil, errors.New("directory unavailable")
+	})
+	s = NewSettings(nil, RoleMapping(s.RoleMapping()), ResolveSIDs(failing))
+	creds = credentials.New("testuser1", "TEST.GOKRB5")
+	err = mapRoles(s, testPACType(t), creds)
+	require.NoError(t, err, "a resolver error should not fail the mapping")
+	assert.True(t, creds.Authorized("reader"), "role mapped by SID should be granted without the resolver")
+	assert.False(t, creds.Authorized("admin"), "role mapped by name should not be granted without the resolver")
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/test/testdata"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+func BenchmarkVerifyAPREQ(b *testing.B) {
+	cl := getClient()
+	sname := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "HTTP/host.test.gokrb5")
+	kb, _ := hex.DecodeString(testdata.HTTP_KEYTAB)
+	kt := keytab.New()
+	kt.Unmarshal(kb)
+	st := time.Now().UTC()
+	tkt, sessionKey, err := messages.NewTicket(cl.Credentials.CName(), cl.Credentials.Domain(), sname, "TEST.GOKRB5",
+		types.NewKrbFlags(), kt, 18, 1, st, st, st.Add(time.Hour), time.Time{})
+	if err != nil {
+		b.Fatalf("error creating ticket: %v", err)
+	}
+	s := NewSettings(kt, DecodePAC(false), ReplayCache(NewMemoryReplayStore()))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Each authenticator has a distinct time so that it is not detected as a replay
+		b.StopTimer()
+		a := newTestAuthenticator(*cl.Credentials)
+		a.CTime = st
+		a.Cusec = i % 1000000
+		a.CTime = a.CTime.Add(time.Duration(i/1000000) * time.Second)
+		apReq, err := messages.NewAPReq(tkt, sessionKey, a)
+		if err != nil {
+			b.Fatalf("error creating AP_REQ: %v", err)
+		}
+		v, err := apReq.Marshal()
+		if err != nil {
+			b.Fatalf("error marshaling AP_REQ: %v", err)
+		}
+		b.StartTimer()
+		var r messages.APReq
+		if err := r.Unmarshal(v); err != nil {
+			b.Fatalf("error unmarshaling AP_REQ: %v", err)
+		}
+		if ok, _, err := VerifyAPREQ(&r, s); !ok {
+			b.Fatalf("AP_REQ not valid: %v", err)
+		}
+	}
+}
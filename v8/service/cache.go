@@ -2,6 +2,7 @@
 package service
 
 import (
+	"github.com/jcmturner/gokrb5/v8/clock"
 	"github.com/jcmturner/gokrb5/v8/types"
 	"sync"
 	"time"
@@ -12,6 +13,7 @@ import (
 // Cache for tickets received from clients keyed by fully qualified client name. Used to track replay of tickets.
 type Cache struct {
 	entries map[string]clientEntries
+	clock   clock.Clock
 	mux     sync.RWMutex
 }
 
@@ -51,12 +53,14 @@ func (c *Cache) getClientEntry(cname types.PrincipalName, t time.Time) (replayCa
 var replayCache Cache
 var once sync.Once
 
-// GetReplayCache returns a pointer to the Cache singleton.
+// GetReplayCache returns a pointer to the Cache singleton. Entries are cleared once the duration provided to the first
+// call has passed. VerifyAPREQ does not use this cache, replays are detected with a ReplayStore.
 func GetReplayCache(d time.Duration) *Cache {
 	// Create a singleton of the ReplayCache and start a background thread to regularly clean out old entries
 	once.Do(func() {
 		replayCache = Cache{
 			entries: make(map[string]clientEntries),
+			clock:   clock.System,
 		}
 		go func() {
 			for {
@@ -76,7 +80,7 @@ func (c *Cache) AddEntry(sname types.PrincipalName, a types.Authenticator) {
 		c.mux.Lock()
 		defer c.mux.Unlock()
 		ce.replayMap[ct] = replayCacheEntry{
-			presentedTime: time.Now().UTC(),
+			presentedTime: c.clock.Now().UTC(),
 			sName:         sname,
 			cTime:         ct,
 		}
@@ -88,7 +92,7 @@ func (c *Cache) AddEntry(sname types.PrincipalName, a types.Authenticator) {
 		c.entries[a.CName.PrincipalNameString()] = clientEntries{
 			replayMap: map[time.Time]replayCacheEntry{
 				ct: {
-					presentedTime: time.Now().UTC(),
+					presentedTime: c.clock.Now().UTC(),
 					sName:         sname,
 					cTime:         ct,
 				},
@@ -105,7 +109,7 @@ func (c *Cache) ClearOldEntries(d time.Duration) {
 	defer c.mux.Unlock()
 	for ke, ce := range c.entries {
 		for k, e := range ce.replayMap {
-			if time.Now().UTC().Sub(e.presentedTime) > d {
+			if c.clock.Now().UTC().Sub(e.presentedTime) > d {
 				delete(ce.replayMap, k)
 			}
 		}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// ErrDelegationNotAllowed is the reason a gateway refuses to act on behalf of a user when its DelegationPolicy does
+// not allow the user to be delegated to the target service.
+var ErrDelegationNotAllowed = errors.New("delegation not allowed by policy")
+
+// DelegationPolicy limits the services a gateway, such as a reverse proxy, obtains tickets to on behalf of the users
+// authenticated to it and the users it acts for, so that a compromised upstream cannot use the gateway to reach
+// arbitrary services as any user. The KDC's constrained delegation configuration still applies.
+type DelegationPolicy struct {
+	// TargetSPNs are the SPNs tickets may be obtained for. No tickets are obtained if there are none.
+	TargetSPNs []string
+	// Users are the users, by name or name@REALM, that may be delegated. Any user may be if there are none.
+	Users []string
+	// Groups are the group SIDs, from the user's PAC, or the authorization attributes of which the user must have
+	// one to be delegated. Group membership is not required if there are none.
+	Groups []string
+}
+
+// Allow returns nil if the policy allows the user of the credentials to be delegated to the SPN, otherwise an error
+// wrapping ErrDelegationNotAllowed. It should be checked before obtaining a ticket for the user or forwarding their
+// delegated credentials.
+func (p DelegationPolicy) Allow(creds *credentials.Credentials, spn string) error {
+	if creds == nil || !creds.Authenticated() {
+		return fmt.Errorf("%w: user is not authenticated", ErrDelegationNotAllowed)
+	}
+	user := creds.CName().PrincipalNameString() + "@" + creds.Realm()
+	if !p.allowSPN(spn) {
+		return fmt.Errorf("%w: %s to %s", ErrDelegationNotAllowed, user, spn)
+	}
+	if len(p.Users) > 0 && !p.allowUser(creds) {
+		return fmt.Errorf("%w: %s is not an allowed user", ErrDelegationNotAllowed, user)
+	}
+	if len(p.Groups) > 0 && !p.allowGroups(creds) {
+		return fmt.Errorf("%w: %s is not a member of an allowed group", ErrDelegationNotAllowed, user)
+	}
+	return nil
+}
+
+// allowSPN reports if the SPN is one of the policy's targets. Host names are compared without regard to case.
+func (p DelegationPolicy) allowSPN(spn string) bool {
+	for _, s := range p.TargetSPNs {
+		if strings.EqualFold(s, spn) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowUser reports if the user of the credentials is one of the policy's users.
+func (p DelegationPolicy) allowUser(creds *credentials.Credentials) bool {
+	name := creds.CName().PrincipalNameString()
+	for _, u := range p.Users {
+		if i := strings.LastIndex(u, "@"); i >= 0 {
+			if u[:i] == name && strings.EqualFold(u[i+1:], creds.Realm()) {
+				return true
+			}
+		} else if u == name {
+			return true
+		}
+	}
+	return false
+}
+
+// allowGroups reports if the user of the credentials is a member of one of the policy's groups.
+func (p DelegationPolicy) allowGroups(creds *credentials.Credentials) bool {
+	sids := creds.GetADCredentials().GroupMembershipSIDs
+	for _, g := range p.Groups {
+		for _, sid := range sids {
+			if g == sid {
+				return true
+			}
+		}
+		if creds.Authorized(g) {
+			return true
+		}
+	}
+	return false
+}
+
+// Delegator obtains service tickets on behalf of the users authenticated to a gateway with S4U2Self and S4U2Proxy,
+// as allowed by its DelegationPolicy.
+type Delegator struct {
+	client userTicketGetter
+	policy DelegationPolicy
+}
+
+// userTicketGetter obtains service tickets on behalf of users, as client.Client and client.S4UCache do.
+type userTicketGetter interface {
+	GetServiceTicketForUser(user, spn string) (messages.Ticket, types.EncryptionKey, error)
+}
+
+// NewDelegator returns a Delegator obtaining tickets with the client, which must be logged in as the gateway's
+// service principal, as allowed by the policy.
+func NewDelegator(cl *client.Client, policy DelegationPolicy) *Delegator {
+	return &Delegator{
+		client: cl,
+		policy: policy,
+	}
+}
+
+// NewCachingDelegator returns a Delegator obtaining tickets with the S4UCache, so that the tickets of users making
+// repeated requests through the gateway are reused, as allowed by the policy.
+func NewCachingDelegator(cache *client.S4UCache, policy DelegationPolicy) *Delegator {
+	return &Delegator{
+		client: cache,
+		policy: policy,
+	}
+}
+
+// GetServiceTicket returns a ticket for the SPN on behalf of the user of the credentials if the policy allows it.
+func (d *Delegator) GetServiceTicket(creds *credentials.Credentials, spn string) (messages.Ticket, types.EncryptionKey, error) {
+	if err := d.policy.Allow(creds, spn); err != nil {
+		return messages.Ticket{}, types.EncryptionKey{}, err
+	}
+	return d.client.GetServiceTicketForUser(creds.CName().PrincipalNameString()+"@"+creds.Realm(), spn)
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func testDelegationCreds(user string, sids ...string) *credentials.Credentials {
+	c := credentials.NewFromPrincipalName(types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, user), "TEST.GOKRB5")
+	c.SetAuthenticated(true)
+	c.SetADCredentials(credentials.ADCredentials{GroupMembershipSIDs: sids})
+	return c
+}
+
+func TestDelegationPolicy_Allow(t *testing.T) {
+	t.Parallel()
+	spn := "HTTP/backend.test.gokrb5"
+	var tests = []struct {
+		name   string
+		policy DelegationPolicy
+		creds  *credentials.Credentials
+		spn    string
+		allow  bool
+	}{
+		{"no targets", DelegationPolicy{}, testDelegationCreds("user"), spn, false},
+		{"target", DelegationPolicy{TargetSPNs: []string{spn}}, testDelegationCreds("user"), spn, true},
+		{"target case", DelegationPolicy{TargetSPNs: []string{"HTTP/Backend.test.gokrb5"}}, testDelegationCreds("user"), spn, true},
+		{"other target", DelegationPolicy{TargetSPNs: []string{spn}}, testDelegationCreds("user"), "HTTP/other.test.gokrb5", false},
+		{"user", DelegationPolicy{TargetSPNs: []string{spn}, Users: []string{"user"}}, testDelegationCreds("user"), spn, true},
+		{"user realm", DelegationPolicy{TargetSPNs: []string{spn}, Users: []string{"user@test.gokrb5"}}, testDelegationCreds("user"), spn, true},
+		{"user other realm", DelegationPolicy{TargetSPNs: []string{spn}, Users: []string{"user@OTHER.GOKRB5"}}, testDelegationCreds("user"), spn, false},
+		{"other user", DelegationPolicy{TargetSPNs: []string{spn}, Users: []string{"admin"}}, testDelegationCreds("user"), spn, false},
+		{"group", DelegationPolicy{TargetSPNs: []string{spn}, Groups: []string{"S-1-5-21-1-2-3-513"}}, testDelegationCreds("user", "S-1-5-21-1-2-3-513"), spn, true},
+		{"not in group", DelegationPolicy{TargetSPNs: []string{spn}, Groups: []string{"S-1-5-21-1-2-3-512"}}, testDelegationCreds("user", "S-1-5-21-1-2-3-513"), spn, false},
+		{"unauthenticated", DelegationPolicy{TargetSPNs: []string{spn}}, credentials.New("user", "TEST.GOKRB5"), spn, false},
+		{"nil credentials", DelegationPolicy{TargetSPNs: []string{spn}}, nil, spn, false},
+	}
+	for _, test := range tests {
+		err := test.policy.Allow(test.creds, test.spn)
+		if test.allow {
+			assert.NoError(t, err, test.name)
+		} else {
+			assert.True(t, errors.Is(err, ErrDelegationNotAllowed), "%s: expected ErrDelegationNotAllowed: %v", test.name, err)
+		}
+	}
+
+	c := testDelegationCreds("user")
+	c.AddAuthzAttribute("gateway-users")
+	assert.NoError(t, DelegationPolicy{TargetSPNs: []string{spn}, Groups: []string{"gateway-users"}}.Allow(c, spn), "authorization attribute should match a group")
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/clock"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// Key is a key of the service supplied at runtime, such as from a secrets manager, rather than read from a keytab
+// file.
+type Key struct {
+	Principal types.PrincipalName
+	Realm     string
+	KVNO      int
+	Key       types.EncryptionKey
+}
+
+// NewKeytabFromKeys returns an in memory keytab holding the keys provided, for the settings of services whose keys
+// are not held in a keytab file. Tickets that do not name the version of the key they are encrypted with are
+// decrypted with the key of the highest version of their encryption type.
+func NewKeytabFromKeys(keys ...Key) *keytab.Keytab {
+	kt := keytab.New()
+	addKeys(kt, keys, clock.System.Now().UTC())
+	return kt
+}
+
+// addKeys adds the keys to the keytab with the timestamp provided. The keytab selects the entry of the highest version
+// of those with the same timestamp when no version is requested.
+func addKeys(kt *keytab.Keytab, keys []Key, ts time.Time) {
+	for _, k := range keys {
+		kt.AddKeyWithKVNO(k.Principal, k.Realm, k.Key, ts, uint32(k.KVNO))
+	}
+}
+
+// rotationKeytab returns a keytab holding the key that decrypts the ticket, found by trying the version the ticket
+// names and then the service's newest key of the ticket's encryption type and the configured number of previous
+// versions. The key is held under the version the ticket names so that the ticket and its PAC are decrypted with it.
+// If no key decrypts the ticket the keytab provided is returned so that the error is reported as without rotation.
+func (s *Settings) rotationKeytab(kt *keytab.Keytab, sname *types.PrincipalName, t *messages.Ticket) *keytab.Keytab {
+	if sname == nil {
+		sname = &t.SName
+	}
+	name := sname.PrincipalNameString()
+	keys := make(map[int]types.EncryptionKey)
+	var versions []int
+	for _, e := range kt.Entries {
+		if e.Principal.Realm != t.Realm || strings.Join(e.Principal.Components, "/") != name ||
+			e.Key.KeyType != t.EncPart.EType {
+			continue
+		}
+		if _, ok := keys[int(e.KVNO)]; !ok {
+			versions = append(versions, int(e.KVNO))
+		}
+		keys[int(e.KVNO)] = e.Key
+	}
+	if len(versions) < 1 {
+		return kt
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+	newest := versions[0]
+	if len(versions) > s.KeyRotation()+1 {
+		versions = versions[:s.KeyRotation()+1]
+	}
+	if _, ok := keys[t.EncPart.KVNO]; ok {
+		versions = append([]int{t.EncPart.KVNO}, versions...)
+	}
+	tried := make(map[int]bool)
+	for _, v := range versions {
+		if tried[v] {
+			continue
+		}
+		tried[v] = true
+		tkt := *t
+		if err := tkt.Decrypt(keys[v]); err != nil {
+			continue
+		}
+		if v != t.EncPart.KVNO && t.EncPart.KVNO != 0 {
+			s.Log("ticket for %s names key version %d but is encrypted with version %d", name, t.EncPart.KVNO, v)
+		}
+		if v != newest {
+			s.oldKeyUsed(name, v, newest)
+		}
+		r := keytab.New()
+		r.AddKeyWithKVNO(*sname, t.Realm, keys[v], s.Clock().Now().UTC(), uint32(t.EncPart.KVNO))
+		return r
+	}
+	return kt
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewKeytabFromKeys(t *testing.T) {
+	t.Parallel()
+	sname := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "HTTP/host.test.gokrb5")
+	key := func(b byte) types.EncryptionKey {
+		k := types.EncryptionKey{KeyType: etypeID.AES256_CTS_HMAC_SHA1_96, KeyValue: make([]byte, 32)}
+		k.KeyValue[0] = b
+		return k
+	}
+	kt := NewKeytabFromKeys(
+		Key{Principal: sname, Realm: "TEST.GOKRB5", KVNO: 300, Key: key(3)},
+		Key{Principal: sname, Realm: "TEST.GOKRB5", KVNO: 2, Key: key(2)},
+	)
+	k, kvno, err := kt.GetEncryptionKey(sname, "TEST.GOKRB5", 2, etypeID.AES256_CTS_HMAC_SHA1_96)
+	require.NoError(t, err, "key with the version requested not found")
+	assert.Equal(t, key(2), k)
+	assert.Equal(t, 2, kvno)
+	k, kvno, err = kt.GetEncryptionKey(sname, "TEST.GOKRB5", 0, etypeID.AES256_CTS_HMAC_SHA1_96)
+	require.NoError(t, err, "key without a version requested not found")
+	assert.Equal(t, key(3), k, "key of the highest version should be selected")
+	assert.Equal(t, 300, kvno, "version above 255 not kept")
+}
+
+func TestNewService_WithKeys(t *testing.T) {
+	t.Parallel()
+	kt, APReq := testAPReq(t)
+	sname := APReq.Ticket.SName
+	key, kvno, err := kt.GetEncryptionKey(sname, "TEST.GOKRB5", 1, APReq.Ticket.EncPart.EType)
+	require.NoError(t, err, "error getting service key")
+	s, err := NewService(WithKeys(Key{Principal: sname, Realm: "TEST.GOKRB5", KVNO: kvno, Key: key}), WithSettings(ClientAddress(testHostAddr())))
+	require.NoError(t, err, "error creating service with keys")
+	ok, creds, err := s.VerifyAPREQ(&APReq)
+	require.NoError(t, err, "validation of AP_REQ failed")
+	assert.True(t, ok, "validation of AP_REQ failed")
+	assert.Equal(t, "testuser1", creds.UserName(), "client name not as expected")
+
+	n := len(kt.Entries)
+	s, err = NewService(WithKeytab(kt), WithKeys(Key{Principal: sname, Realm: "TEST.GOKRB5", KVNO: kvno + 1, Key: key}))
+	require.NoError(t, err, "error creating service with a keytab and keys")
+	assert.Len(t, s.Settings().Keytab.Entries, n+1, "keys not combined with the keytab")
+	assert.Len(t, kt.Entries, n, "keytab provided should not be modified")
+
+	s, err = NewService(WithKeys(Key{Principal: sname, Realm: "TEST.GOKRB5", KVNO: kvno, Key: types.EncryptionKey{KeyType: key.KeyType, KeyValue: make([]byte, 32)}}), WithSettings(ClientAddress(testHostAddr())))
+	require.NoError(t, err, "error creating service with keys")
+	_, APReq = testAPReq(t)
+	ok, _, err = s.VerifyAPREQ(&APReq)
+	assert.False(t, ok, "AP_REQ should not be verified with the wrong key")
+	assert.Error(t, err, "AP_REQ should not be verified with the wrong key")
+}
+
+func testHostAddr() types.HostAddress {
+	h, _ := types.GetHostAddress("127.0.0.1:1234")
+	return h
+}
+
+func TestVerifyAPREQ_KeyRotation(t *testing.T) {
+	t.Parallel()
+	kt, APReq := testAPReq(t)
+	sname := APReq.Ticket.SName
+	key, _, err := kt.GetEncryptionKey(sname, "TEST.GOKRB5", 1, APReq.Ticket.EncPart.EType)
+	require.NoError(t, err, "error getting service key")
+	other := func(b byte) types.EncryptionKey {
+		k := types.EncryptionKey{KeyType: key.KeyType, KeyValue: make([]byte, len(key.KeyValue))}
+		k.KeyValue[0] = b
+		return k
+	}
+	type oldKey struct {
+		principal    string
+		kvno, newest int
+	}
+	var used []oldKey
+	newService := func(keys []Key, rotation int) *Service {
+		s, err := NewService(WithKeys(keys...), WithSettings(
+			ClientAddress(testHostAddr()),
+			ReplayCache(NewMemoryReplayStore()),
+			KeyRotation(rotation),
+			Metrics(MetricsHooks{OldKeyUsed: func(principal string, kvno, newest int) {
+				used = append(used, oldKey{principal, kvno, newest})
+			}}),
+		))
+		require.NoError(t, err, "error creating service")
+		return s
+	}
+	// The ticket names version 1 but the service's keys have been renumbered by a rotation
+	keys := []Key{
+		{Principal: sname, Realm: "TEST.GOKRB5", KVNO: 5, Key: key},
+		{Principal: sname, Realm: "TEST.GOKRB5", KVNO: 6, Key: other(6)},
+	}
+
+	ok, _, err := newService(keys, 0).VerifyAPREQ(&APReq)
+	assert.False(t, ok, "ticket should not be decrypted without rotation")
+	assert.Error(t, err, "ticket should not be decrypted without rotation")
+
+	_, APReq = testAPReq(t)
+	ok, creds, err := newService(keys, 1).VerifyAPREQ(&APReq)
+	require.NoError(t, err, "ticket should be decrypted with the previous key")
+	assert.True(t, ok, "ticket should be decrypted with the previous key")
+	assert.Equal(t, "testuser1", creds.UserName(), "client name not as expected")
+	assert.Equal(t, []oldKey{{"HTTP/host.test.gokrb5", 5, 6}}, used, "use of the old key not reported")
+
+	_, APReq = testAPReq(t)
+	keys = append(keys, Key{Principal: sname, Realm: "TEST.GOKRB5", KVNO: 7, Key: other(7)})
+	ok, _, err = newService(keys, 1).VerifyAPREQ(&APReq)
+	assert.False(t, ok, "keys older than the rotation window should not be tried")
+	assert.Error(t, err, "keys older than the rotation window should not be tried")
+
+	used = nil
+	_, APReq = testAPReq(t)
+	keys = []Key{
+		{Principal: sname, Realm: "TEST.GOKRB5", KVNO: 1, Key: key},
+		{Principal: sname, Realm: "TEST.GOKRB5", KVNO: 2, Key: other(2)},
+	}
+	ok, _, err = newService(keys, 1).VerifyAPREQ(&APReq)
+	require.NoError(t, err, "ticket should be decrypted with the version it names")
+	assert.True(t, ok, "ticket should be decrypted with the version it names")
+	assert.Equal(t, []oldKey{{"HTTP/host.test.gokrb5", 1, 2}}, used, "use of the old key not reported")
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/iana/flags"
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/jcmturner/gokrb5/v8/kadmin"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// maxKpasswdRequestSize is the largest kpasswd request accepted.
+const maxKpasswdRequestSize = 65535
+
+// PasswdChangeFunc changes the password of the target principal to the new password on behalf of the authenticated
+// client and returns a kadmin result code and a message for the client. The function decides whether the client may
+// change the password of a target other than itself and the quality of password required.
+type PasswdChangeFunc func(client *credentials.Credentials, target types.PrincipalName, targetRealm, newPasswd string) (uint16, string)
+
+// KpasswdServer responds to requests to change and set passwords (RFC 3244), verifying their AP_REQ with the keys of
+// the kadmin/changepw service and changing the passwords with a PasswdChangeFunc.
+type KpasswdServer struct {
+	settings     *Settings
+	changePasswd PasswdChangeFunc
+}
+
+// NewKpasswdServer returns a KpasswdServer for the keytab, which must hold the keys of the kadmin/changepw service,
+// changing passwords with the function provided.
+func NewKpasswdServer(kt *keytab.Keytab, f PasswdChangeFunc, settings ...func(*Settings)) *KpasswdServer {
+	return &KpasswdServer{
+		settings:     NewSettings(kt, settings...),
+		changePasswd: f,
+	}
+}
+
+// Respond processes the bytes of a kpasswd request and returns the bytes of the reply. Requests that cannot be
+// verified or decrypted are replied to with a KRB_ERROR carrying the result code.
+func (s *KpasswdServer) Respond(b []byte) []byte {
+	sname := types.PrincipalName{NameType: nametype.KRB_NT_PRINCIPAL, NameString: []string{"kadmin", "changepw"}}
+	var req kadmin.Request
+	if err := req.Unmarshal(b); err != nil {
+		s.settings.Log("kpasswd request could not be parsed: %v", err)
+		return s.marshal(kadmin.NewErrorReply(sname, "", kadmin.KRB5_KPASSWD_MALFORMED, "request could not be parsed"))
+	}
+	tkt := req.APREQ.Ticket
+	ok, creds, err := VerifyAPREQ(&req.APREQ, s.settings)
+	if err != nil || !ok {
+		s.settings.Log("kpasswd AP_REQ could not be verified: %v", err)
+		return s.marshal(kadmin.NewErrorReply(tkt.SName, tkt.Realm, kadmin.KRB5_KPASSWD_AUTHERROR, "authentication failed"))
+	}
+	key := req.APREQ.Authenticator.SubKey
+	if len(key.KeyValue) == 0 {
+		key = req.APREQ.Ticket.DecryptedEncPart.Key
+	}
+	if err := req.KRBPriv.DecryptEncPart(key); err != nil {
+		return s.marshal(kadmin.NewErrorReply(tkt.SName, tkt.Realm, kadmin.KRB5_KPASSWD_MALFORMED, "request data could not be decrypted"))
+	}
+	code, result := s.change(req, creds)
+	r, err := kadmin.NewReply(req, key, code, result, s.settings.Clock().Now().UTC())
+	if err != nil {
+		s.settings.Log("kpasswd reply could not be created: %v", err)
+		return s.marshal(kadmin.NewErrorReply(tkt.SName, tkt.Realm, kadmin.KRB5_KPASSWD_HARDERROR, "reply could not be created"))
+	}
+	return s.marshal(r)
+}
+
+// change determines the target and new password of the decrypted request and changes the password.
+func (s *KpasswdServer) change(req kadmin.Request, creds *credentials.Credentials) (uint16, string) {
+	a := req.APREQ.Authenticator
+	target, targetRealm := a.CName, a.CRealm
+	newPasswd := string(req.KRBPriv.DecryptedEncPart.UserData)
+	if req.Version == kadmin.SetPasswdVersion {
+		var d kadmin.ChangePasswdData
+		if err := d.Unmarshal(req.KRBPriv.DecryptedEncPart.UserData); err != nil {
+			return kadmin.KRB5_KPASSWD_MALFORMED, "request data could not be parsed"
+		}
+		newPasswd = string(d.NewPasswd)
+		if len(d.TargName.NameString) > 0 {
+			target = d.TargName
+		}
+		if d.TargRealm != "" {
+			targetRealm = d.TargRealm
+		}
+	}
+	if target.Equal(a.CName) && targetRealm == a.CRealm && !types.IsFlagSet(&req.APREQ.Ticket.DecryptedEncPart.Flags, flags.Initial) {
+		// Clients changing their own password must have just entered the old one
+		return kadmin.KRB5_KPASSWD_INITIAL_FLAG_NEEDED, "ticket must be obtained with the client's password"
+	}
+	return s.changePasswd(creds, target, targetRealm, newPasswd)
+}
+
+// marshal returns the bytes of the reply, or nil if it cannot be marshaled.
+func (s *KpasswdServer) marshal(r kadmin.Reply) []byte {
+	b, err := r.Marshal()
+	if err != nil {
+		s.settings.Log("kpasswd reply could not be marshaled: %v", err)
+		return nil
+	}
+	return b
+}
+
+// Serve accepts TCP connections on the listener and responds to the length prefixed requests sent on them. It returns
+// the error accepting a connection once the listener is closed.
+func (s *KpasswdServer) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+// handle responds to the requests sent on the connection.
+func (s *KpasswdServer) handle(conn net.Conn) {
+	defer conn.Close()
+	for {
+		conn.SetDeadline(time.Now().Add(10 * time.Second))
+		hb := make([]byte, 4)
+		if _, err := io.ReadFull(conn, hb); err != nil {
+			return
+		}
+		n := binary.BigEndian.Uint32(hb)
+		if n > maxKpasswdRequestSize {
+			return
+		}
+		b := make([]byte, n)
+		if _, err := io.ReadFull(conn, b); err != nil {
+			return
+		}
+		rb := s.Respond(b)
+		if rb == nil {
+			return
+		}
+		binary.BigEndian.PutUint32(hb, uint32(len(rb)))
+		if _, err := conn.Write(append(hb, rb...)); err != nil {
+			return
+		}
+	}
+}
+
+// ServePacket responds to the requests received on the packet connection, such as a UDP connection. It returns the
+// error reading from the connection once it is closed.
+func (s *KpasswdServer) ServePacket(conn net.PacketConn) error {
+	b := make([]byte, maxKpasswdRequestSize)
+	for {
+		n, addr, err := conn.ReadFrom(b)
+		if err != nil {
+			return err
+		}
+		if rb := s.Respond(append([]byte(nil), b[:n]...)); rb != nil {
+			conn.WriteTo(rb, addr)
+		}
+	}
+}
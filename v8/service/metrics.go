@@ -0,0 +1,136 @@
+package service
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// MetricsHooks are called with measurements taken while the service verifies AP_REQs, so that they can be recorded
+// by the application's metrics system. Hooks that are nil are not called. They are called synchronously and must
+// not block.
+type MetricsHooks struct {
+	// OldKeyUsed is called when a ticket is decrypted with a key older than the service's newest key of its
+	// encryption type, with the name of the service principal, the version of the key used and the newest version.
+	// Tickets continuing to be decrypted with old keys after a rotation show that the rollover has not completed.
+	OldKeyUsed func(principal string, kvno, newest int)
+	// ClockSkew is called with the offset of the client's clock from the service's, positive if the client is ahead,
+	// for each authenticator the service decrypts, including those rejected for exceeding the maximum clock skew.
+	// The client is named as principal@REALM. Offsets growing across many clients show NTP drift of the service or
+	// the fleet before it causes authentication to fail. A SkewHistogram's Observe method can be used as the hook.
+	ClockSkew func(client string, offset time.Duration)
+}
+
+// oldKeyUsed reports the use of an old key.
+func (s *Settings) oldKeyUsed(principal string, kvno, newest int) {
+	if s.metrics.OldKeyUsed != nil {
+		s.metrics.OldKeyUsed(principal, kvno, newest)
+	}
+}
+
+// clockSkew reports the clock offset of a client.
+func (s *Settings) clockSkew(client string, offset time.Duration) {
+	if s.metrics.ClockSkew != nil {
+		s.metrics.ClockSkew(client, offset)
+	}
+}
+
+// DefaultSkewBuckets are the upper bounds of the buckets of a SkewHistogram if none are provided. They extend to the
+// default maximum clock skew of five minutes.
+var DefaultSkewBuckets = []time.Duration{
+	time.Second,
+	5 * time.Second,
+	15 * time.Second,
+	30 * time.Second,
+	time.Minute,
+	2 * time.Minute,
+	5 * time.Minute,
+}
+
+// SkewBucket is a bucket of a SkewHistogram holding the number of offsets no larger than its upper bound.
+type SkewBucket struct {
+	UpperBound time.Duration
+	Count      uint64
+}
+
+// SkewHistogram records the size of the clock offsets of clients in buckets, for services without a metrics system
+// able to record a histogram from the ClockSkew hook, or to export to one periodically.
+//
+// s := NewSettings(kt, Metrics(MetricsHooks{ClockSkew: h.Observe}))
+type SkewHistogram struct {
+	bounds []time.Duration
+	counts []uint64
+	count  uint64
+	max    time.Duration
+	mux    sync.Mutex
+}
+
+// NewSkewHistogram returns an empty SkewHistogram with buckets of the upper bounds provided, or DefaultSkewBuckets if
+// none are.
+func NewSkewHistogram(bounds ...time.Duration) *SkewHistogram {
+	if len(bounds) < 1 {
+		bounds = DefaultSkewBuckets
+	}
+	b := append([]time.Duration(nil), bounds...)
+	sort.Slice(b, func(i, j int) bool { return b[i] < b[j] })
+	return &SkewHistogram{bounds: b, counts: make([]uint64, len(b))}
+}
+
+// Observe records the clock offset of a client. Offsets are bucketed by their absolute size.
+func (h *SkewHistogram) Observe(client string, offset time.Duration) {
+	d := absDuration(offset)
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	h.count++
+	if d > absDuration(h.max) {
+		h.max = offset
+	}
+	if i := sort.Search(len(h.bounds), func(i int) bool { return h.bounds[i] >= d }); i < len(h.bounds) {
+		h.counts[i]++
+	}
+}
+
+// absDuration returns the absolute size of the duration.
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// Buckets returns the buckets of the histogram. The counts are cumulative, so each includes the offsets counted by
+// the buckets of smaller bounds. Offsets larger than the largest bound are included only in Count.
+func (h *SkewHistogram) Buckets() []SkewBucket {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	b := make([]SkewBucket, len(h.bounds))
+	var c uint64
+	for i, u := range h.bounds {
+		c += h.counts[i]
+		b[i] = SkewBucket{UpperBound: u, Count: c}
+	}
+	return b
+}
+
+// Count returns the number of offsets observed.
+func (h *SkewHistogram) Count() uint64 {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	return h.count
+}
+
+// Max returns the offset observed of the largest absolute size, positive if the client was ahead.
+func (h *SkewHistogram) Max() time.Duration {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	return h.max
+}
+
+// Reset clears the offsets observed, for example after they have been exported.
+func (h *SkewHistogram) Reset() {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	h.counts = make([]uint64, len(h.bounds))
+	h.count = 0
+	h.max = 0
+}
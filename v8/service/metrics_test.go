@@ -0,0 +1,64 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyAPREQ_ClockSkew(t *testing.T) {
+	t.Parallel()
+	type skew struct {
+		client string
+		offset time.Duration
+	}
+	var skews []skew
+	hooks := MetricsHooks{ClockSkew: func(client string, offset time.Duration) {
+		skews = append(skews, skew{client, offset})
+	}}
+
+	verify := func(now time.Time) (bool, time.Duration, error) {
+		kt, APReq := testAPReq(t)
+		s := NewSettings(kt, ClientAddress(testHostAddr()), ReplayCache(NewMemoryReplayStore()), Metrics(hooks),
+			Clock(clock.NewMock(now)))
+		ok, _, err := VerifyAPREQ(&APReq, s)
+		a := APReq.Authenticator
+		return ok, a.CTime.Add(time.Duration(a.Cusec) * time.Microsecond).Sub(now), err
+	}
+	ok, offset, err := verify(time.Now().UTC().Add(30 * time.Second))
+	require.NoError(t, err, "AP_REQ should be valid")
+	assert.True(t, ok, "AP_REQ should be valid")
+	assert.InDelta(t, float64(-30*time.Second), float64(offset), float64(time.Second), "offset not as expected")
+
+	// An authenticator outside the maximum skew is still measured
+	ok, offset2, err := verify(time.Now().UTC().Add(10 * time.Minute))
+	assert.Error(t, err, "AP_REQ should be rejected for clock skew")
+	assert.False(t, ok, "AP_REQ should be rejected for clock skew")
+
+	assert.Equal(t, []skew{
+		{"testuser1@TEST.GOKRB5", offset},
+		{"testuser1@TEST.GOKRB5", offset2},
+	}, skews, "clock skews not as expected")
+}
+
+func TestSkewHistogram(t *testing.T) {
+	t.Parallel()
+	h := NewSkewHistogram(time.Minute, time.Second)
+	for _, d := range []time.Duration{0, 500 * time.Millisecond, -time.Second, 30 * time.Second, -2 * time.Minute} {
+		h.Observe("testuser1@TEST.GOKRB5", d)
+	}
+	assert.Equal(t, []SkewBucket{
+		{UpperBound: time.Second, Count: 3},
+		{UpperBound: time.Minute, Count: 4},
+	}, h.Buckets(), "buckets not as expected")
+	assert.Equal(t, uint64(5), h.Count(), "count not as expected")
+	assert.Equal(t, -2*time.Minute, h.Max(), "max not as expected")
+
+	h.Reset()
+	assert.Equal(t, uint64(0), h.Count(), "count should be reset")
+	assert.Equal(t, uint64(0), h.Buckets()[1].Count, "buckets should be reset")
+	assert.Len(t, NewSkewHistogram().Buckets(), len(DefaultSkewBuckets), "default buckets not used")
+}
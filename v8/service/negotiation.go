@@ -0,0 +1,161 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/clock"
+)
+
+// DefaultNegotiationTimeout is how long a client has to complete a SPNEGO negotiation that needs more than one round
+// trip if no timeout is configured.
+const DefaultNegotiationTimeout = time.Minute
+
+// NegotiationState is the state of a SPNEGO negotiation that needs more than one round trip, held in a
+// NegotiationStore between the requests carrying its tokens so that they can be served by different instances of a
+// stateless service.
+type NegotiationState struct {
+	// Expires is the time by which the client must complete the negotiation.
+	Expires time.Time `json:"expires"`
+	// MechTypes are the OIDs of the mechanisms offered by the client in its initial token.
+	MechTypes []string `json:"mech_types"`
+}
+
+// Marshal returns the encoding of the state held in a NegotiationStore.
+func (n NegotiationState) Marshal() ([]byte, error) {
+	return json.Marshal(n)
+}
+
+// ParseNegotiationState returns the state encoded in the bytes provided.
+func ParseNegotiationState(b []byte) (NegotiationState, error) {
+	var n NegotiationState
+	if err := json.Unmarshal(b, &n); err != nil {
+		return n, fmt.Errorf("negotiation state is not valid: %w", err)
+	}
+	return n, nil
+}
+
+// NegotiationStore holds the state of SPNEGO negotiations that need more than one round trip, keyed by the
+// correlation ID the client returns with each token. Implementations are typically backed by a store shared by all
+// the instances of the service, such as Redis, and evict the state once it has expired.
+type NegotiationStore interface {
+	// Put stores the state under the ID until the expiry time provided, replacing any state already stored.
+	Put(id string, state []byte, expires time.Time) error
+	// Take removes the state stored under the ID and returns it. Nil is returned if there is no state stored or it
+	// has expired, so that each state can only be used once.
+	Take(id string) ([]byte, error)
+}
+
+// MemoryNegotiationStore is a NegotiationStore held in memory, for services running a single instance and for testing.
+type MemoryNegotiationStore struct {
+	entries map[string]negotiationEntry
+	clock   clock.Clock
+	mux     sync.Mutex
+}
+
+type negotiationEntry struct {
+	state   []byte
+	expires time.Time
+}
+
+// NewMemoryNegotiationStore returns an empty MemoryNegotiationStore.
+func NewMemoryNegotiationStore() *MemoryNegotiationStore {
+	return &MemoryNegotiationStore{entries: make(map[string]negotiationEntry), clock: clock.System}
+}
+
+// useClock sets the clock by which negotiations not completed in time are discarded.
+func (m *MemoryNegotiationStore) useClock(c clock.Clock) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.clock = c
+}
+
+// Put stores the state under the ID until the expiry time provided. Expired entries are removed as state is added.
+func (m *MemoryNegotiationStore) Put(id string, state []byte, expires time.Time) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	now := m.clock.Now().UTC()
+	for k, e := range m.entries {
+		if now.After(e.expires) {
+			delete(m.entries, k)
+		}
+	}
+	b := make([]byte, len(state))
+	copy(b, state)
+	m.entries[id] = negotiationEntry{state: b, expires: expires}
+	return nil
+}
+
+// Take removes the state stored under the ID and returns it, or nil if there is none or it has expired.
+func (m *MemoryNegotiationStore) Take(id string) ([]byte, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	e, ok := m.entries[id]
+	if !ok {
+		return nil, nil
+	}
+	delete(m.entries, id)
+	if m.clock.Now().UTC().After(e.expires) {
+		return nil, nil
+	}
+	return e.state, nil
+}
+
+// Len returns the number of entries held, including any that have expired but not yet been removed.
+func (m *MemoryNegotiationStore) Len() int {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return len(m.entries)
+}
+
+// RedisConn is a connection to Redis that sends a command and returns its reply, such as a redigo redis.Conn.
+type RedisConn interface {
+	Do(commandName string, args ...interface{}) (interface{}, error)
+}
+
+// RedisNegotiationStore is a NegotiationStore backed by Redis, so that the instances of a service behind a load
+// balancer can complete negotiations started with another. State is stored with SET PX so that Redis evicts it once
+// expired, and taken with GETDEL, which needs Redis 6.2 or later.
+type RedisNegotiationStore struct {
+	conn   RedisConn
+	prefix string
+}
+
+// NewRedisNegotiationStore returns a RedisNegotiationStore sending commands on the connection provided, which must be
+// safe for concurrent use, such as a wrapper taking connections from a pool. The prefix is prepended to the IDs to
+// form the Redis keys.
+func NewRedisNegotiationStore(conn RedisConn, prefix string) *RedisNegotiationStore {
+	return &RedisNegotiationStore{conn: conn, prefix: prefix}
+}
+
+// Put stores the state under the ID until the expiry time provided.
+func (r *RedisNegotiationStore) Put(id string, state []byte, expires time.Time) error {
+	ms := time.Until(expires).Milliseconds()
+	if ms < 1 {
+		return errors.New("negotiation state has already expired")
+	}
+	if _, err := r.conn.Do("SET", r.prefix+id, state, "PX", ms); err != nil {
+		return fmt.Errorf("error storing negotiation state in Redis: %w", err)
+	}
+	return nil
+}
+
+// Take removes the state stored under the ID and returns it, or nil if there is none.
+func (r *RedisNegotiationStore) Take(id string) ([]byte, error) {
+	v, err := r.conn.Do("GETDEL", r.prefix+id)
+	if err != nil {
+		return nil, fmt.Errorf("error taking negotiation state from Redis: %w", err)
+	}
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	}
+	return nil, fmt.Errorf("unexpected Redis reply of type %T taking negotiation state", v)
+}
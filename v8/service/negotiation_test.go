@@ -0,0 +1,97 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryNegotiationStore(t *testing.T) {
+	t.Parallel()
+	m := NewMemoryNegotiationStore()
+	require.NoError(t, m.Put("a", []byte("state a"), time.Now().Add(time.Minute)), "error putting state")
+	require.NoError(t, m.Put("b", []byte("state b"), time.Now().Add(-time.Second)), "error putting state")
+
+	b, err := m.Take("a")
+	require.NoError(t, err, "error taking state")
+	assert.Equal(t, []byte("state a"), b, "state not as expected")
+	b, err = m.Take("a")
+	require.NoError(t, err, "error taking state")
+	assert.Nil(t, b, "state should only be taken once")
+	b, err = m.Take("b")
+	require.NoError(t, err, "error taking state")
+	assert.Nil(t, b, "expired state should not be returned")
+
+	require.NoError(t, m.Put("c", []byte("state c"), time.Now().Add(-time.Second)), "error putting state")
+	require.NoError(t, m.Put("d", []byte("state d"), time.Now().Add(time.Minute)), "error putting state")
+	assert.Equal(t, 1, m.Len(), "expired state should be removed as state is added")
+}
+
+func TestNegotiationState(t *testing.T) {
+	t.Parallel()
+	n := NegotiationState{
+		Expires:   time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		MechTypes: []string{"1.2.840.113554.1.2.2"},
+	}
+	b, err := n.Marshal()
+	require.NoError(t, err, "error marshaling state")
+	p, err := ParseNegotiationState(b)
+	require.NoError(t, err, "error parsing state")
+	assert.Equal(t, n, p, "parsed state not as expected")
+	_, err = ParseNegotiationState([]byte("not state"))
+	assert.Error(t, err, "invalid state should not be parsed")
+}
+
+// fakeRedis implements the SET PX, GETDEL and error replies used by the RedisNegotiationStore.
+type fakeRedis struct {
+	values map[string]interface{}
+	err    error
+	args   []interface{}
+}
+
+func (f *fakeRedis) Do(cmd string, args ...interface{}) (interface{}, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.args = append([]interface{}{cmd}, args...)
+	switch cmd {
+	case "SET":
+		f.values[args[0].(string)] = args[1]
+		return "OK", nil
+	case "GETDEL":
+		v := f.values[args[0].(string)]
+		delete(f.values, args[0].(string))
+		return v, nil
+	}
+	return nil, errors.New("unknown command")
+}
+
+func TestRedisNegotiationStore(t *testing.T) {
+	t.Parallel()
+	conn := &fakeRedis{values: make(map[string]interface{})}
+	r := NewRedisNegotiationStore(conn, "spnego:")
+	require.NoError(t, r.Put("a", []byte("state a"), time.Now().Add(time.Minute)), "error putting state")
+	require.Len(t, conn.args, 5, "SET arguments not as expected")
+	assert.Equal(t, []interface{}{"SET", "spnego:a", []byte("state a"), "PX"}, conn.args[:4], "SET arguments not as expected")
+	assert.InDelta(t, int64(time.Minute/time.Millisecond), conn.args[4], 1000, "expiry not as expected")
+	assert.Error(t, r.Put("b", []byte("state b"), time.Now().Add(-time.Second)), "expired state should not be stored")
+
+	b, err := r.Take("a")
+	require.NoError(t, err, "error taking state")
+	assert.Equal(t, []byte("state a"), b, "state not as expected")
+	b, err = r.Take("a")
+	require.NoError(t, err, "error taking state")
+	assert.Nil(t, b, "state should only be taken once")
+
+	conn.values["spnego:c"] = "state c"
+	b, err = r.Take("c")
+	require.NoError(t, err, "error taking state")
+	assert.Equal(t, []byte("state c"), b, "string replies should be accepted")
+
+	conn.err = errors.New("connection refused")
+	_, err = r.Take("a")
+	assert.Error(t, err, "connection errors should be returned")
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/pac"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// PasswordVerifier verifies the passwords of users with the KDC, for applications that authenticate users with a
+// username and password in the manner of a PAM module.
+//
+// A successful AS exchange alone does not prove the password is correct, as an attacker able to spoof the KDC's
+// responses can return a TGT encrypted with a key derived from any password it chooses. The verifier therefore also
+// obtains a ticket for the service with the TGT and checks the ticket can be decrypted with the service's keytab,
+// which only the real KDC can have encrypted it for.
+type PasswordVerifier struct {
+	krb5conf *config.Config
+	settings *Settings
+}
+
+// NewPasswordVerifier returns a PasswordVerifier that obtains tickets with the configuration provided and verifies
+// them with the service settings' keytab.
+//
+// The ticket is requested for the settings' service name if one is set, otherwise for the keytab principal if that
+// is set and otherwise for host/<hostname>. The keytab must contain the key of the principal.
+func NewPasswordVerifier(krb5conf *config.Config, settings *Settings) *PasswordVerifier {
+	return &PasswordVerifier{
+		krb5conf: krb5conf,
+		settings: settings,
+	}
+}
+
+// VerifyUserPassword verifies the password of the user with the KDC and returns the user's authenticated
+// credentials. The username may be qualified with the realm as user@REALM or DOMAIN\user, otherwise the default
+// realm of the configuration is used.
+// An error is returned if the password could not be verified for any reason.
+func (v *PasswordVerifier) VerifyUserPassword(username, password string) (*credentials.Credentials, error) {
+	realm, username := splitUsername(username)
+	if realm == "" {
+		realm = v.krb5conf.LibDefaults.DefaultRealm
+	}
+	spn, err := v.spn()
+	if err != nil {
+		return nil, err
+	}
+	cl := client.NewWithPassword(username, realm, password, v.krb5conf, client.Clock(v.settings.Clock()))
+	defer cl.Destroy()
+	if err := cl.Login(); err != nil {
+		return nil, fmt.Errorf("error with user credentials during login: %v", err)
+	}
+	return authenticateLogin(cl, spn, v.settings)
+}
+
+// spn returns the SPN of the service to obtain the ticket for.
+func (v *PasswordVerifier) spn() (string, error) {
+	if v.settings.SName() != "" {
+		return v.settings.SName(), nil
+	}
+	if p := v.settings.KeytabPrincipal(); p != nil {
+		return p.PrincipalNameString(), nil
+	}
+	h, err := os.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("could not get host name for the service principal: %v", err)
+	}
+	return "host/" + strings.ToLower(h), nil
+}
+
+// authenticateLogin obtains a ticket for the SPN with the client's TGT and verifies it with the service's keytab,
+// returning the client's credentials authenticated with the authorization data of the ticket.
+func authenticateLogin(cl *client.Client, spn string, s *Settings) (*credentials.Credentials, error) {
+	tkt, key, err := cl.GetServiceTicket(spn)
+	if err != nil {
+		return nil, fmt.Errorf("could not get service ticket: %v", err)
+	}
+	if err := verifyServiceTicket(&tkt, key, cl, s); err != nil {
+		return nil, err
+	}
+	creds := cl.Credentials
+	creds.SetAuthTime(cl.Now())
+	creds.SetAuthenticated(true)
+	var authz *pac.PACType
+	kt, ktprinc := s.ticketKeytab(&tkt)
+	isPAC, pac, err := tkt.GetPACType(kt, ktprinc, s.Logger())
+	if isPAC && err != nil {
+		if !s.TolerateInvalidPAC() {
+			return nil, fmt.Errorf("error processing PAC: %v", err)
+		}
+		s.Log("PAC could not be processed, authenticating %s without its authorization data: %v", creds.UserName(), err)
+		creds.SetPACUnavailable(err)
+	} else if isPAC {
+		// There is a valid PAC. Adding attributes to creds
+		creds.SetADCredentials(adCredentials(pac))
+		authz = &pac
+	}
+	if err := mapRoles(s, authz, creds); err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
+
+// verifyServiceTicket decrypts the service ticket with the service's keytab and checks that it was issued to the
+// client with the session key the client received, so that a genuine ticket issued to another client cannot be
+// substituted by a spoofed KDC.
+func verifyServiceTicket(tkt *messages.Ticket, key types.EncryptionKey, cl *client.Client, s *Settings) error {
+	kt, ktprinc := s.ticketKeytab(tkt)
+	if err := tkt.DecryptEncPart(kt, ktprinc); err != nil {
+		return fmt.Errorf("could not decrypt service ticket: %v", err)
+	}
+	e := tkt.DecryptedEncPart
+	if !e.CName.Equal(cl.CName()) || !cl.Config.RealmEqual(e.CRealm, cl.Realm()) {
+		return fmt.Errorf("service ticket was issued to %s@%s not %s@%s", e.CName.PrincipalNameString(), e.CRealm, cl.CName().PrincipalNameString(), cl.Realm())
+	}
+	if e.Key.KeyType != key.KeyType || subtle.ConstantTimeCompare(e.Key.KeyValue, key.KeyValue) != 1 {
+		return errors.New("session key of the service ticket does not match that received from the KDC")
+	}
+	if ok, err := tkt.ValidAt(s.MaxClockSkew(), s.Clock().Now().UTC()); !ok {
+		return fmt.Errorf("service ticket is not valid: %v", err)
+	}
+	return nil
+}
+
+// splitUsername splits a username qualified with its domain as DOMAIN\user or user@DOMAIN.
+func splitUsername(s string) (domain, username string) {
+	// Domain and username can be specified in 2 formats:
+	// <Username> - no domain specified
+	// <Domain>\<Username>
+	// <Username>@<Domain>
+	if strings.Contains(s, `\`) {
+		u := strings.SplitN(s, `\`, 2)
+		return u[0], u[1]
+	}
+	if strings.Contains(s, `@`) {
+		u := strings.SplitN(s, `@`, 2)
+		return u[1], u[0]
+	}
+	return "", s
+}
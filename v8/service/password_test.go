@@ -0,0 +1,26 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitUsername(t *testing.T) {
+	t.Parallel()
+	var tests = []struct {
+		s        string
+		domain   string
+		username string
+	}{
+		{"user", "", "user"},
+		{"user@TEST.GOKRB5", "TEST.GOKRB5", "user"},
+		{`TEST\user`, "TEST", "user"},
+		{`TEST\user@other`, "TEST", "user@other"},
+	}
+	for _, test := range tests {
+		domain, username := splitUsername(test.s)
+		assert.Equal(t, test.domain, domain, "domain not as expected for %s", test.s)
+		assert.Equal(t, test.username, username, "username not as expected for %s", test.s)
+	}
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/iana/flags"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+var (
+	// ErrAnonymousClient is the reason a ticket is rejected when the client is anonymous and the service is not
+	// configured to accept anonymous clients.
+	ErrAnonymousClient = errors.New("anonymous clients are not accepted")
+	// ErrNoHostAddress is the reason a ticket is rejected when it has no host addresses and the service is configured
+	// to require them.
+	ErrNoHostAddress = errors.New("tickets without host addresses are not accepted")
+	// ErrAuthIndicator is the reason a ticket is rejected when it does not carry any of the authentication indicators
+	// the service is configured to require.
+	ErrAuthIndicator = errors.New("ticket does not carry a required authentication indicator")
+	// ErrAuthTooOld is the reason a ticket is rejected when the client authenticated to the KDC longer ago than the
+	// service is configured to accept.
+	ErrAuthTooOld = errors.New("client authenticated too long ago")
+)
+
+// PolicyError is returned when a ticket is rejected by the policy of the service rather than for being invalid.
+// The reason, one of the Err values of this package, can be tested for with errors.Is and the KRBError to return to
+// the client obtained with errors.As.
+type PolicyError struct {
+	Reason   error
+	KRBError messages.KRBError
+}
+
+// newPolicyError returns a PolicyError for the reason with a KRBError of the code provided.
+func newPolicyError(reason error, APReq *messages.APReq, code int32) PolicyError {
+	return PolicyError{
+		Reason:   reason,
+		KRBError: messages.NewKRBError(APReq.Ticket.SName, APReq.Ticket.Realm, code, reason.Error()),
+	}
+}
+
+// Error returns the error of the KRBError.
+func (e PolicyError) Error() string {
+	return e.KRBError.Error()
+}
+
+// Unwrap returns the reason the ticket was rejected.
+func (e PolicyError) Unwrap() error {
+	return e.Reason
+}
+
+// Is reports if the KRBError has the same error code as the target, so that the rejection can be tested for by
+// code as for other errors verifying tickets.
+func (e PolicyError) Is(target error) bool {
+	return e.KRBError.Is(target)
+}
+
+// As sets the target to the KRBError if it is a KRBError pointer.
+func (e PolicyError) As(target interface{}) bool {
+	if k, ok := target.(*messages.KRBError); ok {
+		*k = e.KRBError
+		return true
+	}
+	return false
+}
+
+// hasAuthIndicator reports if any of the ticket's authentication indicators is one of those required.
+func hasAuthIndicator(indicators, required []string) bool {
+	for _, i := range indicators {
+		for _, r := range required {
+			if i == r {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// authTooOld reports if the client authenticated to obtain the ticket longer ago than the maximum age, allowing for
+// the clock skew.
+func authTooOld(e messages.EncTicketPart, now time.Time, maxAge, skew time.Duration) bool {
+	return maxAge > 0 && now.Sub(e.AuthTime) > maxAge+skew
+}
+
+// isAnonymous reports if the ticket was issued to an anonymous client, either by the anonymous principal name or
+// with the anonymous flag set by the KDC. RFC 8062
+func isAnonymous(e messages.EncTicketPart) bool {
+	return e.CName.IsAnonymous() || e.CRealm == types.AnonymousRealm || types.IsFlagSet(&e.Flags, flags.Anonymous)
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/iana/errorcode"
+	"github.com/jcmturner/gokrb5/v8/iana/flags"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyAPREQ_Anonymous(t *testing.T) {
+	t.Parallel()
+	f := types.NewKrbFlags()
+	types.SetFlag(&f, flags.Anonymous)
+	kt, APReq := testAPReq(t, testClient(types.AnonymousPrincipalName(), types.AnonymousRealm), testTicketFlags(f))
+	ok, _, err := VerifyAPREQ(&APReq, NewSettings(kt))
+	assert.False(t, ok, "anonymous client should be rejected by default")
+	assert.True(t, errors.Is(err, ErrAnonymousClient), "error should be for an anonymous client: %v", err)
+	var pe PolicyError
+	assert.True(t, errors.As(err, &pe), "error should be a PolicyError: %v", err)
+	var e messages.KRBError
+	if assert.True(t, errors.As(err, &e), "error should provide a KRBError: %v", err) {
+		assert.Equal(t, errorcode.KDC_ERR_POLICY, e.ErrorCode, "error code not as expected")
+	}
+
+	kt, APReq = testAPReq(t, testClient(types.AnonymousPrincipalName(), types.AnonymousRealm), testTicketFlags(f))
+	ok, creds, err := VerifyAPREQ(&APReq, NewSettings(kt, AcceptAnonymous(true)))
+	require.NoError(t, err, "anonymous client should be accepted")
+	assert.True(t, ok, "anonymous client should be accepted")
+	assert.True(t, creds.CName().IsAnonymous(), "client should be the anonymous principal")
+}
+
+func TestVerifyAPREQ_RequireHostAddr(t *testing.T) {
+	t.Parallel()
+	kt, APReq := testAPReq(t)
+	ok, _, err := VerifyAPREQ(&APReq, NewSettings(kt, RequireHostAddr(true)))
+	assert.False(t, ok, "ticket without addresses should be rejected")
+	assert.True(t, errors.Is(err, ErrNoHostAddress), "error should be for a ticket without addresses: %v", err)
+	assert.False(t, errors.Is(err, ErrAnonymousClient), "error should not be for an anonymous client")
+	assert.True(t, errors.Is(err, messages.KRBError{ErrorCode: errorcode.KRB_AP_ERR_BADADDR}), "error code not as expected")
+
+	kt, APReq = testAPReq(t)
+	ok, _, err = VerifyAPREQ(&APReq, NewSettings(kt))
+	assert.NoError(t, err, "ticket without addresses should be accepted by default")
+	assert.True(t, ok, "ticket without addresses should be accepted by default")
+}
+
+func TestVerifyAPREQ_RequireAuthIndicators(t *testing.T) {
+	t.Parallel()
+	kt, APReq := testAPReq(t, testAuthIndicators("pkinit"))
+	ok, creds, err := VerifyAPREQ(&APReq, NewSettings(kt, RequireAuthIndicators("otp", "pkinit")))
+	require.NoError(t, err, "ticket with a required indicator should be accepted")
+	assert.True(t, ok, "ticket with a required indicator should be accepted")
+	ti, _ := creds.GetTicketInfo()
+	assert.Equal(t, []string{"pkinit"}, ti.AuthIndicators, "authentication indicators not as expected")
+
+	kt, APReq = testAPReq(t, testAuthIndicators("pkinit"))
+	ok, _, err = VerifyAPREQ(&APReq, NewSettings(kt, RequireAuthIndicators("FIDO")))
+	assert.False(t, ok, "ticket without a required indicator should be rejected")
+	assert.True(t, errors.Is(err, ErrAuthIndicator), "error should be for the authentication indicators: %v", err)
+	assert.True(t, errors.Is(err, messages.KRBError{ErrorCode: errorcode.KDC_ERR_POLICY}), "error code not as expected")
+
+	kt, APReq = testAPReq(t)
+	ok, _, err = VerifyAPREQ(&APReq, NewSettings(kt, RequireAuthIndicators("otp")))
+	assert.False(t, ok, "ticket without indicators should be rejected")
+	assert.True(t, errors.Is(err, ErrAuthIndicator), "error should be for the authentication indicators: %v", err)
+
+	kt, APReq = testAPReq(t)
+	ok, _, err = VerifyAPREQ(&APReq, NewSettings(kt))
+	assert.NoError(t, err, "ticket without indicators should be accepted by default")
+	assert.True(t, ok, "ticket without indicators should be accepted by default")
+}
+
+func TestVerifyAPREQ_MaxAuthAge(t *testing.T) {
+	t.Parallel()
+	kt, APReq := testAPReq(t, testAuthTime(time.Now().UTC().Add(-9*time.Hour)))
+	ok, _, err := VerifyAPREQ(&APReq, NewSettings(kt, MaxAuthAge(8*time.Hour)))
+	assert.False(t, ok, "ticket from an authentication older than the maximum should be rejected")
+	assert.True(t, errors.Is(err, ErrAuthTooOld), "error should be for the auth time: %v", err)
+	assert.True(t, errors.Is(err, messages.KRBError{ErrorCode: errorcode.KDC_ERR_POLICY}), "error code not as expected")
+
+	kt, APReq = testAPReq(t, testAuthTime(time.Now().UTC().Add(-9*time.Hour)))
+	ok, _, err = VerifyAPREQ(&APReq, NewSettings(kt))
+	assert.NoError(t, err, "ticket should be accepted regardless of its auth time by default")
+	assert.True(t, ok, "ticket should be accepted regardless of its auth time by default")
+
+	// The clock skew is allowed for
+	kt, APReq = testAPReq(t, testAuthTime(time.Now().UTC().Add(-8*time.Hour-time.Minute)))
+	ok, creds, err := VerifyAPREQ(&APReq, NewSettings(kt, MaxAuthAge(8*time.Hour)))
+	require.NoError(t, err, "ticket within the maximum age and clock skew should be accepted")
+	assert.True(t, ok, "ticket within the maximum age and clock skew should be accepted")
+	ti, _ := creds.GetTicketInfo()
+	assert.WithinDuration(t, time.Now().Add(-8*time.Hour-time.Minute), ti.AuthTime, time.Minute, "auth time not as expected")
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/clock"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// replayTokenVersion is the version of the encoding of ReplayTokens.
+const replayTokenVersion = 1
+
+// replayTokenLen is the length of an encoded ReplayToken without its signature: the version, the expiry as seconds
+// since the Unix epoch and the authenticator and ticket hashes.
+const replayTokenLen = 1 + 8 + sha256.Size*2
+
+// ReplayToken is the verification state of an authenticator presented to the service, from which verifiers that do
+// not share memory can detect its replay. It holds hashes of the authenticator and of the ticket it was presented
+// with, not their contents, and the time after which the authenticator can no longer be replayed as it is outside
+// the maximum clock skew.
+type ReplayToken struct {
+	Expires       time.Time
+	Authenticator [sha256.Size]byte
+	Ticket        [sha256.Size]byte
+}
+
+// NewReplayToken returns the ReplayToken of the authenticator presented to the service with the ticket provided.
+// Authenticators from the same client at the same time to different services are not replays of each other.
+func NewReplayToken(sname types.PrincipalName, tkt messages.Ticket, a types.Authenticator, maxSkew time.Duration) ReplayToken {
+	ct := a.CTime.Add(time.Duration(a.Cusec) * time.Microsecond)
+	h := sha256.New()
+	fmt.Fprintf(h, "%s@%s\x00%s\x00", a.CName.PrincipalNameString(), a.CRealm, sname.PrincipalNameString())
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(ct.UnixNano()))
+	h.Write(b[:])
+	t := ReplayToken{
+		Expires: ct.Add(maxSkew).Truncate(time.Second).Add(time.Second).UTC(),
+		Ticket:  sha256.Sum256(tkt.EncPart.Cipher),
+	}
+	copy(t.Authenticator[:], h.Sum(nil))
+	return t
+}
+
+// Key returns the key the token is held under in a ReplayStore. Tokens of the same authenticator presented with the
+// same ticket have the same key.
+func (t ReplayToken) Key() string {
+	b := make([]byte, 0, sha256.Size*2)
+	b = append(b, t.Authenticator[:]...)
+	b = append(b, t.Ticket[:]...)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Marshal returns the compact encoding of the token signed with the signer provided.
+func (t ReplayToken) Marshal(signer SessionSigner) ([]byte, error) {
+	b := make([]byte, 1+8, replayTokenLen)
+	b[0] = replayTokenVersion
+	binary.BigEndian.PutUint64(b[1:9], uint64(t.Expires.Unix()))
+	b = append(b, t.Authenticator[:]...)
+	b = append(b, t.Ticket[:]...)
+	sig, err := signer.Sign(b)
+	if err != nil {
+		return nil, fmt.Errorf("could not sign replay token: %w", err)
+	}
+	return append(b, sig...), nil
+}
+
+// ParseReplayToken verifies the signature of the encoded token with the signer provided and returns the token.
+func ParseReplayToken(b []byte, signer SessionSigner) (ReplayToken, error) {
+	var t ReplayToken
+	if len(b) <= replayTokenLen || b[0] != replayTokenVersion {
+		return t, errors.New("replay token is not valid")
+	}
+	if err := signer.Verify(b[:replayTokenLen], b[replayTokenLen:]); err != nil {
+		return t, fmt.Errorf("replay token signature is not valid: %w", err)
+	}
+	t.Expires = time.Unix(int64(binary.BigEndian.Uint64(b[1:9])), 0).UTC()
+	copy(t.Authenticator[:], b[9:9+sha256.Size])
+	copy(t.Ticket[:], b[9+sha256.Size:replayTokenLen])
+	return t, nil
+}
+
+// ReplayStore holds the signed ReplayTokens of the authenticators presented to a fleet of stateless verifiers so that
+// a replay to any of them is detected. Implementations are typically backed by a shared store such as Redis, with
+// Add implemented as an atomic set if not exists, and the entries evicted once they have expired.
+type ReplayStore interface {
+	// Add stores the token under the key until the expiry time provided. If there is already a token stored under
+	// the key it is not replaced and false is returned. The token is nil if the store is configured with ReplayCache
+	// rather than SharedReplayCache.
+	Add(key string, token []byte, expires time.Time) (bool, error)
+}
+
+// DefaultReplayStoreSize is the maximum number of entries of the MemoryReplayStore with which a service detects
+// replayed authenticators if no ReplayStore is configured.
+const DefaultReplayStoreSize = 1 << 18
+
+// defaultReplayStore detects replayed authenticators for the Settings not configured with a ReplayStore. It is shared
+// by all of them so that a replay is detected when the Settings are created for each request or connection.
+var defaultReplayStore = NewBoundedMemoryReplayStore(DefaultReplayStoreSize)
+
+// MemoryReplayStore is a ReplayStore held in memory, for services running a single verifier and for testing.
+type MemoryReplayStore struct {
+	entries map[string]*list.Element
+	expiry  *list.List
+	max     int
+	clock   clock.Clock
+	mux     sync.Mutex
+}
+
+// memoryReplayEntry is an entry of a MemoryReplayStore, held in a list ordered by expiry.
+type memoryReplayEntry struct {
+	key     string
+	expires time.Time
+}
+
+// NewMemoryReplayStore returns an empty MemoryReplayStore.
+func NewMemoryReplayStore() *MemoryReplayStore {
+	return NewBoundedMemoryReplayStore(0)
+}
+
+// NewBoundedMemoryReplayStore returns an empty MemoryReplayStore holding at most max entries, to bound the memory used
+// by a service under a flood of authenticators. Once full, the entries closest to expiry are removed to make room for
+// new ones, so their replay is no longer detected. The bound should exceed the authenticators the service expects to
+// receive within its replay window. A max of 0 is unbounded.
+func NewBoundedMemoryReplayStore(max int) *MemoryReplayStore {
+	return &MemoryReplayStore{
+		entries: make(map[string]*list.Element),
+		expiry:  list.New(),
+		max:     max,
+		clock:   clock.System,
+	}
+}
+
+// useClock sets the clock by which entries past their expiry are removed as keys are added.
+func (m *MemoryReplayStore) useClock(c clock.Clock) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.clock = c
+}
+
+// Add stores the key until the expiry time provided and reports if it was not already stored.
+// Expired entries are removed as tokens are added, and the entries closest to expiry if the store is full.
+func (m *MemoryReplayStore) Add(key string, token []byte, expires time.Time) (bool, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.add(key, expires, m.clock.Now().UTC()), nil
+}
+
+// addAt stores the key as Add does, removing the entries expired by the time provided rather than by the store's
+// clock.
+func (m *MemoryReplayStore) addAt(key string, expires, now time.Time) bool {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.add(key, expires, now)
+}
+
+// add stores the key and reports if it was not already stored. The caller must hold the lock.
+func (m *MemoryReplayStore) add(key string, expires, now time.Time) bool {
+	m.expire(now)
+	if _, ok := m.entries[key]; ok {
+		return false
+	}
+	for m.max > 0 && len(m.entries) >= m.max {
+		r := m.expiry.Remove(m.expiry.Front()).(memoryReplayEntry)
+		delete(m.entries, r.key)
+	}
+	m.entries[key] = m.insert(memoryReplayEntry{key: key, expires: expires})
+	return true
+}
+
+// expire removes the entries that have expired from the front of the list.
+func (m *MemoryReplayStore) expire(now time.Time) {
+	for e := m.expiry.Front(); e != nil; e = m.expiry.Front() {
+		r := e.Value.(memoryReplayEntry)
+		if !now.After(r.expires) {
+			return
+		}
+		m.expiry.Remove(e)
+		delete(m.entries, r.key)
+	}
+}
+
+// insert adds the entry to the list in order of expiry. Entries are mostly added in order of expiry so the position
+// is searched for back from the end of the list.
+func (m *MemoryReplayStore) insert(r memoryReplayEntry) *list.Element {
+	for e := m.expiry.Back(); e != nil; e = e.Prev() {
+		if !r.expires.Before(e.Value.(memoryReplayEntry).expires) {
+			return m.expiry.InsertAfter(r, e)
+		}
+	}
+	return m.expiry.PushFront(r)
+}
+
+// isReplay checks the AP_REQ against the configured ReplayStore, or the default store shared by the Settings without
+// one, adding its token if it is not a replay.
+func (s *Settings) isReplay(APReq *messages.APReq) (bool, error) {
+	t := NewReplayToken(APReq.Ticket.SName, APReq.Ticket, APReq.Authenticator, s.ReplayWindow())
+	if s.replayStore == nil {
+		return !defaultReplayStore.addAt(t.Key(), t.Expires, s.Clock().Now().UTC()), nil
+	}
+	var b []byte
+	if s.replaySigner != nil {
+		var err error
+		b, err = t.Marshal(s.replaySigner)
+		if err != nil {
+			return false, err
+		}
+	}
+	added, err := s.replayStore.Add(t.Key(), b, t.Expires)
+	if err != nil {
+		return false, fmt.Errorf("could not check replay store: %w", err)
+	}
+	return !added, nil
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/clock"
+	"github.com/jcmturner/gokrb5/v8/iana/errorcode"
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayToken(t *testing.T) {
+	t.Parallel()
+	_, APReq := testAPReq(t)
+	signer := NewHMACSigner([]byte("0123456789abcdef0123456789abcdef"))
+	rt := NewReplayToken(APReq.Ticket.SName, APReq.Ticket, APReq.Authenticator, 5*time.Minute)
+	assert.True(t, rt.Expires.After(APReq.Authenticator.CTime.Add(5*time.Minute)), "token should not expire before the authenticator is outside the clock skew")
+
+	b, err := rt.Marshal(signer)
+	require.NoError(t, err, "error marshaling replay token")
+	parsed, err := ParseReplayToken(b, signer)
+	require.NoError(t, err, "error parsing replay token")
+	assert.Equal(t, rt, parsed, "parsed replay token not as expected")
+
+	b[10] ^= 0xff
+	_, err = ParseReplayToken(b, signer)
+	assert.Error(t, err, "a modified token should not be parsed")
+	_, err = ParseReplayToken(b[:20], signer)
+	assert.Error(t, err, "a truncated token should not be parsed")
+
+	assert.Equal(t, rt.Key(), NewReplayToken(APReq.Ticket.SName, APReq.Ticket, APReq.Authenticator, 5*time.Minute).Key(), "key should be the same for the same authenticator")
+	other := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "HTTP/other.test.gokrb5")
+	assert.NotEqual(t, rt.Key(), NewReplayToken(other, APReq.Ticket, APReq.Authenticator, 5*time.Minute).Key(), "key should differ for another service")
+}
+
+func TestVerifyAPREQ_SharedReplayCache(t *testing.T) {
+	t.Parallel()
+	kt, APReq := testAPReq(t)
+	h, _ := types.GetHostAddress("127.0.0.1:1234")
+	store := NewMemoryReplayStore()
+	signer := NewHMACSigner([]byte("0123456789abcdef0123456789abcdef"))
+
+	// Two stateless instances of the service sharing the store
+	s1 := NewSettings(kt, ClientAddress(h), SharedReplayCache(store, signer))
+	s2 := NewSettings(kt, ClientAddress(h), SharedReplayCache(store, signer))
+	ok, _, err := VerifyAPREQ(&APReq, s1)
+	require.NoError(t, err, "validation of AP_REQ failed")
+	assert.True(t, ok, "validation of AP_REQ failed")
+
+	ok, _, err = VerifyAPREQ(&APReq, s2)
+	assert.False(t, ok, "replay to another instance should be detected")
+	var e messages.KRBError
+	if assert.True(t, errors.As(err, &e), "expected a KRBError: %v", err) {
+		assert.Equal(t, errorcode.KRB_AP_ERR_REPEAT, e.ErrorCode, "error code not as expected")
+	}
+
+	failing := NewSettings(kt, ClientAddress(h), SharedReplayCache(replayStoreFunc(func(string, []byte, time.Time) (bool, error) {
+		return false, errors.New("store unavailable")
+	}), signer))
+	_, APReq = testAPReq(t)
+	ok, _, err = VerifyAPREQ(&APReq, failing)
+	assert.False(t, ok, "authentication should fail if the store cannot be reached")
+	assert.Error(t, err, "an error should be returned if the store cannot be reached")
+}
+
+func TestVerifyAPREQ_DefaultReplayStore(t *testing.T) {
+	t.Parallel()
+	kt, APReq := testAPReq(t)
+	h := testHostAddr()
+
+	// Settings without a ReplayStore share the default store, so the replay is detected by Settings created for
+	// another request, whatever its replay window
+	ok, _, err := VerifyAPREQ(&APReq, NewSettings(kt, ClientAddress(h)))
+	require.NoError(t, err, "validation of AP_REQ failed")
+	assert.True(t, ok, "validation of AP_REQ failed")
+	for _, s := range []*Settings{
+		NewSettings(kt, ClientAddress(h)),
+		NewSettings(kt, ClientAddress(h), MaxClockSkew(10*time.Minute), ReplayWindow(time.Hour)),
+	} {
+		ok, _, err = VerifyAPREQ(&APReq, s)
+		assert.False(t, ok, "replay should be detected")
+		var e messages.KRBError
+		if assert.True(t, errors.As(err, &e), "expected a KRBError: %v", err) {
+			assert.Equal(t, errorcode.KRB_AP_ERR_REPEAT, e.ErrorCode, "error code not as expected")
+		}
+	}
+}
+
+func TestVerifyAPREQ_ReplayWindowClock(t *testing.T) {
+	t.Parallel()
+	kt, APReq := testAPReq(t)
+	m := clock.NewMock(time.Now().UTC())
+	store := NewMemoryReplayStore()
+	s := NewSettings(kt, ClientAddress(testHostAddr()), Clock(m), ReplayCache(store))
+	ok, _, err := VerifyAPREQ(&APReq, s)
+	require.NoError(t, err, "validation of AP_REQ failed")
+	assert.True(t, ok, "validation of AP_REQ failed")
+	ok, _, err = VerifyAPREQ(&APReq, s)
+	assert.False(t, ok, "replay should be detected")
+	var e messages.KRBError
+	if assert.True(t, errors.As(err, &e), "expected a KRBError: %v", err) {
+		assert.Equal(t, errorcode.KRB_AP_ERR_REPEAT, e.ErrorCode, "error code not as expected")
+	}
+
+	// Once the replay window has passed by the settings' clock the authenticator is outside the clock skew and
+	// forgotten by the replay store
+	m.Add(s.ReplayWindow() + 2*time.Second)
+	ok, _, err = VerifyAPREQ(&APReq, s)
+	assert.False(t, ok, "authenticator outside the clock skew should be rejected")
+	if assert.True(t, errors.As(err, &e), "expected a KRBError: %v", err) {
+		assert.Equal(t, errorcode.KRB_AP_ERR_SKEW, e.ErrorCode, "error code not as expected")
+	}
+	added, err := store.Add("key", nil, m.Now().Add(time.Minute))
+	require.NoError(t, err, "error adding to replay store")
+	assert.True(t, added, "key should be added")
+	assert.Len(t, store.entries, 1, "expired authenticator should have been removed from the replay store")
+}
+
+func TestReplayWindow_ShorterThanClockSkew(t *testing.T) {
+	t.Parallel()
+	kt, APReq := testAPReq(t)
+	s := NewSettings(kt, ClientAddress(testHostAddr()), ReplayWindow(time.Minute))
+	ok, _, err := VerifyAPREQ(&APReq, s)
+	assert.False(t, ok, "AP_REQ should not be verified with a replay window shorter than the clock skew")
+	assert.Error(t, err, "replay window shorter than the clock skew should be rejected")
+
+	_, err = NewService(WithKeytab(kt), WithSettings(ReplayWindow(time.Minute)))
+	assert.Error(t, err, "replay window shorter than the clock skew should be rejected")
+	_, err = NewService(WithKeytab(kt), WithSettings(MaxClockSkew(time.Minute), ReplayWindow(time.Minute)))
+	assert.NoError(t, err, "replay window equal to the clock skew should be accepted")
+}
+
+func TestBoundedMemoryReplayStore(t *testing.T) {
+	t.Parallel()
+	m := NewBoundedMemoryReplayStore(2)
+	now := time.Now()
+	for i, k := range []string{"a", "b"} {
+		added, err := m.Add(k, nil, now.Add(time.Duration(2-i)*time.Minute))
+		require.NoError(t, err, "error adding to replay store")
+		assert.True(t, added, "new key should be added")
+	}
+	added, err := m.Add("c", nil, now.Add(time.Hour))
+	require.NoError(t, err, "error adding to a full replay store")
+	assert.True(t, added, "key should be added to a full store")
+	assert.Len(t, m.entries, 2, "store should not exceed its bound")
+	added, _ = m.Add("a", nil, now.Add(time.Hour))
+	assert.False(t, added, "key held should be detected")
+	added, _ = m.Add("c", nil, now.Add(time.Hour))
+	assert.False(t, added, "key held should be detected")
+	_, ok := m.entries["b"]
+	assert.False(t, ok, "entry closest to expiry should have been removed")
+
+	// Entries are held in order of expiry and removed once expired
+	m = NewBoundedMemoryReplayStore(2)
+	m.Add("a", nil, now.Add(time.Minute))
+	m.Add("b", nil, now.Add(-time.Second))
+	assert.Equal(t, "b", m.expiry.Front().Value.(memoryReplayEntry).key, "entry closest to expiry should be first")
+	added, err = m.Add("c", nil, now.Add(time.Minute))
+	require.NoError(t, err, "error adding to replay store")
+	assert.True(t, added, "new key should be added")
+	_, ok = m.entries["a"]
+	assert.True(t, ok, "unexpired entry should be kept when an expired one makes room")
+	assert.Equal(t, m.expiry.Len(), len(m.entries), "entries and expiry list should be consistent")
+}
+
+type replayStoreFunc func(key string, token []byte, expires time.Time) (bool, error)
+
+func (f replayStoreFunc) Add(key string, token []byte, expires time.Time) (bool, error) {
+	return f(key, token, expires)
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/clock"
+)
+
+// minReplayFileCompaction is the number of records the file of a FileReplayStore holds before it is compacted.
+const minReplayFileCompaction = 1024
+
+// FileReplayStore is a ReplayStore that records the keys of the authenticators presented to the service in a file,
+// so that a restarted service continues to detect the replay of authenticators presented before it restarted. Each
+// key is written to the file before Add returns. Expired entries are removed as keys are added and the file is
+// compacted as they accumulate.
+type FileReplayStore struct {
+	path    string
+	f       *os.File
+	entries map[string]time.Time
+	records int
+	clock   clock.Clock
+	mux     sync.Mutex
+}
+
+// NewFileReplayStore returns a FileReplayStore recording to the file at the path provided, loading the unexpired
+// entries of an existing file. The store should be closed when it is no longer needed.
+func NewFileReplayStore(path string) (*FileReplayStore, error) {
+	s := &FileReplayStore{
+		path:    path,
+		entries: make(map[string]time.Time),
+		clock:   clock.System,
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Add records the key until the expiry time provided and reports if it was not already recorded.
+func (s *FileReplayStore) Add(key string, token []byte, expires time.Time) (bool, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.f == nil {
+		return false, fmt.Errorf("replay store %s is closed", s.path)
+	}
+	s.expire(s.clock.Now().UTC())
+	if _, ok := s.entries[key]; ok {
+		return false, nil
+	}
+	if _, err := fmt.Fprintf(s.f, "%d %s\n", expires.Unix(), key); err != nil {
+		return false, fmt.Errorf("could not write to replay store %s: %v", s.path, err)
+	}
+	if err := s.f.Sync(); err != nil {
+		return false, fmt.Errorf("could not write to replay store %s: %v", s.path, err)
+	}
+	s.entries[key] = expires
+	s.records++
+	if s.records >= minReplayFileCompaction && s.records > 2*len(s.entries) {
+		if err := s.compact(); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// useClock sets the clock by which authenticator keys recorded in the file are forgotten once past their expiry.
+func (s *FileReplayStore) useClock(c clock.Clock) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.clock = c
+}
+
+// Close closes the file of the store.
+func (s *FileReplayStore) Close() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
+
+// load reads the unexpired entries of any existing file. Malformed lines, such as one partially written when the
+// service stopped, are ignored.
+func (s *FileReplayStore) load() error {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not open replay store %s: %v", s.path, err)
+	}
+	defer f.Close()
+	now := s.clock.Now().UTC()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		sec, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		if e := time.Unix(sec, 0).UTC(); now.Before(e) {
+			s.entries[fields[1]] = e
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("could not read replay store %s: %v", s.path, err)
+	}
+	return nil
+}
+
+// expire removes the entries that have expired.
+func (s *FileReplayStore) expire(now time.Time) {
+	for k, e := range s.entries {
+		if now.After(e) {
+			delete(s.entries, k)
+		}
+	}
+}
+
+// compact replaces the file with one holding only the current entries and opens it for appending.
+func (s *FileReplayStore) compact() error {
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("could not compact replay store %s: %v", s.path, err)
+	}
+	w := bufio.NewWriter(tmp)
+	for k, e := range s.entries {
+		fmt.Fprintf(w, "%d %s\n", e.Unix(), k)
+	}
+	err = w.Flush()
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("could not compact replay store %s: %v", s.path, err)
+	}
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("could not open replay store %s: %v", s.path, err)
+	}
+	if s.f != nil {
+		s.f.Close()
+	}
+	s.f = f
+	s.records = len(s.entries)
+	return nil
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/iana/errorcode"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileReplayStore(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "replay")
+	require.NoError(t, err, "error creating temporary directory")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "replay")
+
+	s, err := NewFileReplayStore(path)
+	require.NoError(t, err, "error creating replay store")
+	exp := time.Now().Add(time.Hour)
+	added, err := s.Add("key1", nil, exp)
+	require.NoError(t, err, "error adding to replay store")
+	assert.True(t, added, "new key should be added")
+	added, err = s.Add("key1", nil, exp)
+	require.NoError(t, err, "error adding to replay store")
+	assert.False(t, added, "existing key should not be added")
+	added, err = s.Add("expired", nil, time.Now().Add(-time.Minute))
+	require.NoError(t, err, "error adding to replay store")
+	assert.True(t, added, "new key should be added")
+	require.NoError(t, s.Close(), "error closing replay store")
+	_, err = s.Add("key2", nil, exp)
+	assert.Error(t, err, "closed store should return an error")
+
+	// A partially written record is ignored when the store is reopened
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	require.NoError(t, err, "error opening replay store file")
+	fmt.Fprintf(f, "12345")
+	f.Close()
+
+	s, err = NewFileReplayStore(path)
+	require.NoError(t, err, "error reopening replay store")
+	defer s.Close()
+	added, err = s.Add("key1", nil, exp)
+	require.NoError(t, err, "error adding to replay store")
+	assert.False(t, added, "key added before the store was reopened should be detected")
+	added, err = s.Add("expired", nil, exp)
+	require.NoError(t, err, "error adding to replay store")
+	assert.True(t, added, "expired key should not be loaded")
+}
+
+func TestFileReplayStore_Compaction(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "replay")
+	require.NoError(t, err, "error creating temporary directory")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "replay")
+
+	s, err := NewFileReplayStore(path)
+	require.NoError(t, err, "error creating replay store")
+	defer s.Close()
+	for i := 0; i < minReplayFileCompaction; i++ {
+		_, err := s.Add(fmt.Sprintf("expired%d", i), nil, time.Now().Add(-time.Minute))
+		require.NoError(t, err, "error adding to replay store")
+	}
+	_, err = s.Add("key", nil, time.Now().Add(time.Hour))
+	require.NoError(t, err, "error adding to replay store")
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err, "error reading replay store file")
+	assert.True(t, len(b) < 100, "expired entries should have been compacted: %d bytes", len(b))
+	added, err := s.Add("key", nil, time.Now().Add(time.Hour))
+	require.NoError(t, err, "error adding to replay store")
+	assert.False(t, added, "key should be kept by compaction")
+}
+
+func TestVerifyAPREQ_FileReplayCache(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "replay")
+	require.NoError(t, err, "error creating temporary directory")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "replay")
+	kt, APReq := testAPReq(t)
+	h, _ := types.GetHostAddress("127.0.0.1:1234")
+
+	store, err := NewFileReplayStore(path)
+	require.NoError(t, err, "error creating replay store")
+	s := NewSettings(kt, ClientAddress(h), ReplayCache(store), ReplayWindow(10*time.Minute))
+	assert.Equal(t, 10*time.Minute, s.ReplayWindow(), "replay window not as configured")
+	ok, _, err := VerifyAPREQ(&APReq, s)
+	require.NoError(t, err, "validation of AP_REQ failed")
+	assert.True(t, ok, "validation of AP_REQ failed")
+	require.NoError(t, store.Close(), "error closing replay store")
+
+	// The replay is detected by the service once restarted
+	store, err = NewFileReplayStore(path)
+	require.NoError(t, err, "error reopening replay store")
+	defer store.Close()
+	s = NewSettings(kt, ClientAddress(h), ReplayCache(store))
+	ok, _, err = VerifyAPREQ(&APReq, s)
+	assert.False(t, ok, "replay after a restart should be detected")
+	var e messages.KRBError
+	if assert.True(t, errors.As(err, &e), "expected a KRBError: %v", err) {
+		assert.Equal(t, errorcode.KRB_AP_ERR_REPEAT, e.ErrorCode, "error code not as expected")
+	}
+	assert.Equal(t, s.MaxClockSkew(), s.ReplayWindow(), "replay window should default to the maximum clock skew")
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"errors"
+	"log"
+
+	"github.com/jcmturner/gokrb5/v8/clock"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/messages"
+)
+
+// Service verifies the AP_REQs sent to a Kerberos service.
+type Service struct {
+	settings *Settings
+}
+
+// Option configures a service created with NewService.
+type Option func(*options)
+
+// options holds the values provided to NewService.
+type options struct {
+	keytab   *keytab.Keytab
+	keys     []Key
+	settings []func(*Settings)
+}
+
+// NewService creates a new service from the options provided. WithKeytab or WithKeys must be provided.
+//
+// s, err := NewService(WithKeytab(kt), WithLogger(l))
+func NewService(opts ...Option) (*Service, error) {
+	o := new(options)
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.keytab == nil && len(o.keys) < 1 {
+		return nil, errors.New("a keytab or keys must be provided for the service")
+	}
+	kt := o.keytab
+	if len(o.keys) > 0 {
+		// Combine the keys with a copy of the keytab's entries so the keytab provided is not modified
+		kt = keytab.New()
+		if o.keytab != nil {
+			kt.Entries = append(kt.Entries, o.keytab.Entries...)
+		}
+	}
+	s := NewSettings(kt, o.settings...)
+	if len(o.keys) > 0 {
+		addKeys(kt, o.keys, s.Clock().Now().UTC())
+	}
+	if err := s.checkReplayWindow(); err != nil {
+		return nil, err
+	}
+	return &Service{
+		settings: s,
+	}, nil
+}
+
+// WithKeytab configures the keytab holding the service's keys.
+func WithKeytab(kt *keytab.Keytab) Option {
+	return func(o *options) {
+		o.keytab = kt
+	}
+}
+
+// WithKeys configures keys of the service supplied at runtime, such as from a secrets manager, removing the need for
+// a keytab file. The keys are used in addition to those of any keytab configured with WithKeytab.
+//
+// s, err := NewService(WithKeys(Key{Principal: spn, Realm: realm, KVNO: 3, Key: key}))
+func WithKeys(keys ...Key) Option {
+	return func(o *options) {
+		o.keys = append(o.keys, keys...)
+	}
+}
+
+// WithLogger configures the service's logger.
+func WithLogger(l *log.Logger) Option {
+	return WithSettings(Logger(l))
+}
+
+// WithClock configures the clock the service reads the current time from.
+func WithClock(c clock.Clock) Option {
+	return WithSettings(Clock(c))
+}
+
+// WithSettings configures the service with the settings provided.
+func WithSettings(settings ...func(*Settings)) Option {
+	return func(o *options) {
+		o.settings = append(o.settings, settings...)
+	}
+}
+
+// Settings returns the service's settings.
+func (s *Service) Settings() *Settings {
+	return s.settings
+}
+
+// VerifyAPREQ verifies an AP_REQ sent to the service. Returns a boolean for if the AP_REQ is valid and the client's
+// principal name and realm.
+func (s *Service) VerifyAPREQ(APReq *messages.APReq) (bool, *credentials.Credentials, error) {
+	return VerifyAPREQ(APReq, s.settings)
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/clock"
+)
+
+// SessionSigner signs the values of session cookies so that they cannot be forged or modified by clients.
+type SessionSigner interface {
+	// Sign returns the signature of the bytes provided.
+	Sign(b []byte) ([]byte, error)
+	// Verify returns an error if the signature is not valid for the bytes provided.
+	Verify(b, sig []byte) error
+}
+
+// HMACSigner is a SessionSigner that signs with HMAC-SHA256.
+type HMACSigner struct {
+	key []byte
+}
+
+// NewHMACSigner returns a SessionSigner that signs with HMAC-SHA256 using the key provided.
+// The key should be at least 32 random bytes and be shared by all instances of the service that accept the sessions.
+func NewHMACSigner(key []byte) *HMACSigner {
+	k := make([]byte, len(key))
+	copy(k, key)
+	return &HMACSigner{key: k}
+}
+
+// Sign returns the HMAC-SHA256 of the bytes provided.
+func (s *HMACSigner) Sign(b []byte) ([]byte, error) {
+	if len(s.key) == 0 {
+		return nil, errors.New("HMAC signer has no key")
+	}
+	m := hmac.New(sha256.New, s.key)
+	m.Write(b)
+	return m.Sum(nil), nil
+}
+
+// Verify returns an error if the signature is not the HMAC-SHA256 of the bytes provided.
+func (s *HMACSigner) Verify(b, sig []byte) error {
+	exp, err := s.Sign(b)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(exp, sig) {
+		return errors.New("session signature is not valid")
+	}
+	return nil
+}
+
+// CookieSessionMgr is a SessionMgr that holds the session value in a signed cookie, so no state needs to be kept by
+// the service. Once a client has authenticated with SPNEGO subsequent requests presenting the cookie are served
+// without a Negotiate round trip until the session expires.
+//
+// The cookie value is signed but not encrypted so the session value is visible to the client.
+// Browsers limit the size of cookies to around 4KB which may be exceeded by the credentials of users that are members
+// of a large number of groups.
+type CookieSessionMgr struct {
+	name   string
+	signer SessionSigner
+	maxAge time.Duration
+	clock  clock.Clock
+	mux    sync.RWMutex
+}
+
+// NewCookieSessionMgr returns a SessionMgr that issues cookies with the name provided, signed by the signer and
+// valid for maxAge.
+//
+// s := NewSettings(kt, SessionManager(NewCookieSessionMgr("krb5session", NewHMACSigner(key), time.Hour)))
+func NewCookieSessionMgr(name string, signer SessionSigner, maxAge time.Duration) *CookieSessionMgr {
+	return &CookieSessionMgr{
+		name:   name,
+		signer: signer,
+		maxAge: maxAge,
+		clock:  clock.System,
+	}
+}
+
+// useClock sets the clock the session manager reads the current time from to set and check the expiry of sessions.
+func (c *CookieSessionMgr) useClock(clk clock.Clock) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.clock = clk
+}
+
+// now returns the current time from the session manager's clock.
+func (c *CookieSessionMgr) now() time.Time {
+	c.mux.RLock()
+	defer c.mux.RUnlock()
+	return c.clock.Now().UTC()
+}
+
+// New issues a session cookie holding the value under the key provided.
+func (c *CookieSessionMgr) New(w http.ResponseWriter, r *http.Request, k string, v []byte) error {
+	exp := c.now().Add(c.maxAge)
+	p := sessionPayload(exp, k, v)
+	sig, err := c.signer.Sign(p)
+	if err != nil {
+		return fmt.Errorf("could not sign session cookie: %v", err)
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     c.name,
+		Value:    base64.RawURLEncoding.EncodeToString(p) + "." + base64.RawURLEncoding.EncodeToString(sig),
+		Path:     "/",
+		Expires:  exp,
+		MaxAge:   int(c.maxAge / time.Second),
+		Secure:   r.TLS != nil,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// Get returns the value under the key provided from the request's session cookie.
+// An error is returned if there is no cookie or it has expired or its signature is not valid.
+func (c *CookieSessionMgr) Get(r *http.Request, k string) ([]byte, error) {
+	ck, err := r.Cookie(c.name)
+	if err != nil {
+		return nil, err
+	}
+	i := strings.IndexByte(ck.Value, '.')
+	if i < 0 {
+		return nil, errors.New("session cookie malformed")
+	}
+	p, err := base64.RawURLEncoding.DecodeString(ck.Value[:i])
+	if err != nil {
+		return nil, fmt.Errorf("session cookie malformed: %v", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(ck.Value[i+1:])
+	if err != nil {
+		return nil, fmt.Errorf("session cookie malformed: %v", err)
+	}
+	if err := c.signer.Verify(p, sig); err != nil {
+		return nil, err
+	}
+	// The signature has been verified so the payload is as it was created by sessionPayload
+	if len(p) < 10 {
+		return nil, errors.New("session cookie malformed")
+	}
+	if c.now().After(time.Unix(int64(binary.BigEndian.Uint64(p[:8])), 0)) {
+		return nil, errors.New("session has expired")
+	}
+	l := int(binary.BigEndian.Uint16(p[8:10]))
+	if len(p) < 10+l || string(p[10:10+l]) != k {
+		return nil, fmt.Errorf("session does not contain the key %s", k)
+	}
+	return p[10+l:], nil
+}
+
+// sessionPayload returns the bytes to be signed for a session cookie: the expiry time, the length of the key, the key
+// then the value.
+func sessionPayload(exp time.Time, k string, v []byte) []byte {
+	b := make([]byte, 10, 10+len(k)+len(v))
+	binary.BigEndian.PutUint64(b[:8], uint64(exp.Unix()))
+	binary.BigEndian.PutUint16(b[8:10], uint16(len(k)))
+	b = append(b, k...)
+	return append(b, v...)
+}
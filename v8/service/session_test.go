@@ -0,0 +1,78 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCookieSessionMgr(t *testing.T) {
+	t.Parallel()
+	sm := NewCookieSessionMgr("session", NewHMACSigner([]byte("0123456789abcdef0123456789abcdef")), time.Hour)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	err := sm.New(w, r, "key", []byte("value"))
+	require.NoError(t, err, "error creating session")
+	cks := w.Result().Cookies()
+	require.Len(t, cks, 1, "a single session cookie should be set")
+	ck := cks[0]
+	assert.Equal(t, "session", ck.Name, "cookie name not as expected")
+	assert.True(t, ck.HttpOnly, "session cookie should be HttpOnly")
+
+	get := func(ck *http.Cookie, k string) ([]byte, error) {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.AddCookie(ck)
+		return sm.Get(r, k)
+	}
+	v, err := get(ck, "key")
+	require.NoError(t, err, "error getting session value")
+	assert.Equal(t, []byte("value"), v, "session value not as expected")
+
+	_, err = get(ck, "other")
+	assert.Error(t, err, "a key not in the session should not be returned")
+
+	i := strings.IndexByte(ck.Value, '.')
+	tampered := *ck
+	tampered.Value = ck.Value[:i-1] + "A" + ck.Value[i:]
+	_, err = get(&tampered, "key")
+	assert.Error(t, err, "a modified session cookie should be rejected")
+
+	other := NewCookieSessionMgr("session", NewHMACSigner([]byte("another key")), time.Hour)
+	r = httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(ck)
+	_, err = other.Get(r, "key")
+	assert.Error(t, err, "a session cookie signed with another key should be rejected")
+
+	_, err = sm.Get(httptest.NewRequest("GET", "/", nil), "key")
+	assert.Error(t, err, "a request without a session cookie should have no session")
+
+	expired := NewCookieSessionMgr("session", sm.signer, -time.Minute)
+	w = httptest.NewRecorder()
+	err = expired.New(w, httptest.NewRequest("GET", "/", nil), "key", []byte("value"))
+	require.NoError(t, err, "error creating session")
+	_, err = get(w.Result().Cookies()[0], "key")
+	assert.Error(t, err, "an expired session should be rejected")
+}
+
+func TestCookieSessionMgr_Clock(t *testing.T) {
+	t.Parallel()
+	m := clock.NewMock(time.Now().UTC())
+	sm := NewCookieSessionMgr("session", NewHMACSigner([]byte("0123456789abcdef0123456789abcdef")), time.Hour)
+	NewSettings(nil, Clock(m), SessionManager(sm))
+	w := httptest.NewRecorder()
+	require.NoError(t, sm.New(w, httptest.NewRequest("GET", "/", nil), "key", []byte("value")), "error creating session")
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(w.Result().Cookies()[0])
+	_, err := sm.Get(r, "key")
+	require.NoError(t, err, "error getting session value")
+
+	m.Add(time.Hour + time.Second)
+	_, err = sm.Get(r, "key")
+	assert.Error(t, err, "session should have expired by the settings' clock")
+}
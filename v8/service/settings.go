@@ -1,10 +1,12 @@
 package service
 
 import (
+	"fmt"
 	"log"
 	"net/http"
 	"time"
 
+	"github.com/jcmturner/gokrb5/v8/clock"
 	"github.com/jcmturner/gokrb5/v8/keytab"
 	"github.com/jcmturner/gokrb5/v8/types"
 )
@@ -13,15 +15,38 @@ import (
 type Settings struct {
 	Keytab             *keytab.Keytab
 	ktprinc            *types.PrincipalName
+	aliases            map[string]string
 	sname              string
 	requireHostAddr    bool
+	acceptAnonymous    bool
+	authIndicators     []string
 	disablePACDecoding bool
+	tolerateInvalidPAC bool
+	strictDecoding     bool
+	tolerateBER        bool
 	cAddr              types.HostAddress
 	maxClockSkew       time.Duration
+	maxAuthAge         time.Duration
 	logger             *log.Logger
 	sessionMgr         SessionMgr
+	clock              clock.Clock
+	roleMapper         RoleMapper
+	sidResolver        SIDResolver
+	replayStore        ReplayStore
+	replaySigner       SessionSigner
+	replayWindow       time.Duration
+	maxTokenSize       int
+	keyRotation        int
+	metrics            MetricsHooks
+	negotiationStore   NegotiationStore
+	negotiationTimeout time.Duration
+	accessRules        []AccessRule
 }
 
+// DefaultMaxTokenSize is the maximum size in bytes of the context tokens accepted from clients if none is configured.
+// It accommodates the tickets of users who are members of many groups, whose PACs are large.
+const DefaultMaxTokenSize = 64 * 1024
+
 // NewSettings creates a new service Settings.
 func NewSettings(kt *keytab.Keytab, settings ...func(*Settings)) *Settings {
 	s := new(Settings)
@@ -29,9 +54,22 @@ func NewSettings(kt *keytab.Keytab, settings ...func(*Settings)) *Settings {
 	for _, set := range settings {
 		set(s)
 	}
+	if s.clock != nil {
+		for _, v := range []interface{}{s.replayStore, s.negotiationStore, s.sessionMgr} {
+			if c, ok := v.(clocked); ok {
+				c.useClock(s.clock)
+			}
+		}
+	}
 	return s
 }
 
+// clocked is implemented by the stores and session managers of this package that read the current time, so that
+// they read it from the clock configured in the settings they are used with.
+type clocked interface {
+	useClock(c clock.Clock)
+}
+
 // RequireHostAddr used to configure service side to required host addresses to be specified in Kerberos tickets.
 //
 // s := NewSettings(kt, RequireHostAddr(true))
@@ -46,6 +84,38 @@ func (s *Settings) RequireHostAddr() bool {
 	return s.requireHostAddr
 }
 
+// AcceptAnonymous used to configure service side to accept tickets issued to anonymous clients, which are
+// authenticated as the principal WELLKNOWN/ANONYMOUS. Defaults to rejecting anonymous clients if not specified.
+//
+// s := NewSettings(kt, AcceptAnonymous(true))
+func AcceptAnonymous(b bool) func(*Settings) {
+	return func(s *Settings) {
+		s.acceptAnonymous = b
+	}
+}
+
+// AcceptAnonymous indicates if the service should accept tickets issued to anonymous clients.
+func (s *Settings) AcceptAnonymous() bool {
+	return s.acceptAnonymous
+}
+
+// RequireAuthIndicators used to configure service side to only accept tickets carrying at least one of the
+// authentication indicators provided, such as "pkinit" or "otp", which KDCs issue to record how the client
+// authenticated. This allows services to require stronger authentication than a password. Defaults to accepting
+// tickets regardless of their indicators if not specified.
+//
+// s := NewSettings(kt, RequireAuthIndicators("pkinit", "otp"))
+func RequireAuthIndicators(indicators ...string) func(*Settings) {
+	return func(s *Settings) {
+		s.authIndicators = append([]string(nil), indicators...)
+	}
+}
+
+// RequireAuthIndicators returns the authentication indicators one of which tickets must carry to be accepted.
+func (s *Settings) RequireAuthIndicators() []string {
+	return s.authIndicators
+}
+
 // DecodePAC used to configure service side to enable/disable PAC decoding if the PAC is present.
 // Defaults to enabled if not specified.
 //
@@ -61,6 +131,57 @@ func (s *Settings) DecodePAC() bool {
 	return !s.disablePACDecoding
 }
 
+// TolerateInvalidPAC used to configure service side to not fail authentication if a PAC is present but cannot be processed.
+// The client is authenticated without the authorization data from the PAC and the credentials are marked to indicate
+// the PAC was unavailable. Defaults to disabled if not specified.
+//
+// s := NewSettings(kt, TolerateInvalidPAC(true))
+func TolerateInvalidPAC(b bool) func(*Settings) {
+	return func(s *Settings) {
+		s.tolerateInvalidPAC = b
+	}
+}
+
+// TolerateInvalidPAC indicates whether the service should authenticate a client whose ticket contains a PAC that cannot be processed.
+func (s *Settings) TolerateInvalidPAC() bool {
+	return s.tolerateInvalidPAC
+}
+
+// StrictDecoding used to configure service side to reject tokens that are not strictly encoded.
+// In strict mode tokens with trailing bytes, non-DER encodings or that deviate from the specifications,
+// such as raw KRB5 tokens not wrapped in SPNEGO, are rejected. It applies to the SPNEGO and KRB5 context tokens accepted
+// over HTTP and by the SPNEGO mechanism and to AP_REQs accepted with Service.Accept. VerifyAPREQ is passed an AP_REQ
+// that has already been decoded so the encoding cannot be checked there.
+// Defaults to lenient decoding, which accepts the deviations of known implementations, if not specified.
+//
+// s := NewSettings(kt, StrictDecoding(true))
+func StrictDecoding(b bool) func(*Settings) {
+	return func(s *Settings) {
+		s.strictDecoding = b
+	}
+}
+
+// StrictDecoding indicates whether the service should reject tokens that are not strictly encoded.
+func (s *Settings) StrictDecoding() bool {
+	return s.strictDecoding
+}
+
+// TolerateBER used to configure service side to accept context tokens from clients that are BER rather than DER
+// encoded, such as with indefinite lengths. Tokens are converted to DER before they are decoded. It has no effect when
+// decoding strictly. Defaults to accepting only DER if not specified.
+//
+// s := NewSettings(kt, TolerateBER(true))
+func TolerateBER(b bool) func(*Settings) {
+	return func(s *Settings) {
+		s.tolerateBER = b
+	}
+}
+
+// TolerateBER indicates whether the service converts BER encoded context tokens to DER before decoding them.
+func (s *Settings) TolerateBER() bool {
+	return s.tolerateBER && !s.strictDecoding
+}
+
 // ClientAddress used to configure service side with the clients host address to be used during validation.
 //
 // s := NewSettings(kt, ClientAddress(h))
@@ -89,6 +210,13 @@ func (s *Settings) Logger() *log.Logger {
 	return s.logger
 }
 
+// Log will write to the service's logger if it is configured.
+func (s *Settings) Log(format string, v ...interface{}) {
+	if s.Logger() != nil {
+		s.Logger().Output(2, fmt.Sprintf(format, v...))
+	}
+}
+
 // KeytabPrincipal used to override the principal name used to find the key in the keytab.
 //
 // s := NewSettings(kt, KeytabPrincipal("someaccount"))
@@ -104,6 +232,34 @@ func (s *Settings) KeytabPrincipal() *types.PrincipalName {
 	return s.ktprinc
 }
 
+// PrincipalAliases used to configure the service to accept tickets for alias principals using the keys of the
+// principal in the keytab each alias maps to, so that a service reachable under many host names or realms does not
+// need an entry in the keytab for each of them.
+// Principals are given in the form name@REALM. An alias without a realm matches tickets of any realm, and the keys
+// are taken from the realm of the ticket if the principal it maps to has no realm.
+//
+// s := NewSettings(kt, PrincipalAliases(map[string]string{"HTTP/www.example.com": "HTTP/host.example.com@EXAMPLE.COM"}))
+func PrincipalAliases(aliases map[string]string) func(*Settings) {
+	return func(s *Settings) {
+		if s.aliases == nil {
+			s.aliases = make(map[string]string)
+		}
+		for k, v := range aliases {
+			s.aliases[k] = v
+		}
+	}
+}
+
+// PrincipalAliases returns the alias principals the service is configured with, mapped to the principals whose keys
+// are used for them.
+func (s *Settings) PrincipalAliases() map[string]string {
+	m := make(map[string]string, len(s.aliases))
+	for k, v := range s.aliases {
+		m[k] = v
+	}
+	return m
+}
+
 // MaxClockSkew used to configure service side with the maximum acceptable clock skew
 // between the service and the issue time of kerberos tickets
 //
@@ -123,6 +279,43 @@ func (s *Settings) MaxClockSkew() time.Duration {
 	return s.maxClockSkew
 }
 
+// MaxAuthAge used to configure service side to only accept tickets whose auth time, when the client authenticated to
+// the KDC, is within the duration provided, allowing for the maximum clock skew. This rejects tickets obtained with
+// an older authentication even though they are still valid, for services that require the client to have
+// authenticated recently. Defaults to accepting tickets regardless of their auth time if not specified.
+//
+// s := NewSettings(kt, MaxAuthAge(8*time.Hour))
+func MaxAuthAge(d time.Duration) func(*Settings) {
+	return func(s *Settings) {
+		s.maxAuthAge = d
+	}
+}
+
+// MaxAuthAge returns the maximum time since the client authenticated to the KDC of the tickets accepted. Zero
+// indicates no maximum.
+func (s *Settings) MaxAuthAge() time.Duration {
+	return s.maxAuthAge
+}
+
+// Clock used to configure the service with the clock it reads the current time from when checking the validity of
+// tickets and the clock skew with clients.
+// This is intended to enable deterministic testing of time dependent behaviour.
+//
+// s := NewSettings(kt, Clock(c))
+func Clock(c clock.Clock) func(*Settings) {
+	return func(s *Settings) {
+		s.clock = c
+	}
+}
+
+// Clock returns the clock the service reads the current time from.
+func (s *Settings) Clock() clock.Clock {
+	if s.clock == nil {
+		return clock.System
+	}
+	return s.clock
+}
+
 // SName used provide a specific service name to the service settings.
 //
 // s := NewSettings(kt, SName("HTTP/some.service.com"))
@@ -151,6 +344,193 @@ func (s *Settings) SessionManager() SessionMgr {
 	return s.sessionMgr
 }
 
+// RoleMapping configures a RoleMapper to assign application roles and attributes to clients from the SIDs and claims
+// in the PAC of their tickets when they are authenticated.
+//
+// s := NewSettings(kt, RoleMapping(GroupRoles(map[string][]string{"S-1-5-21-1-2-3-512": {"admin"}})))
+func RoleMapping(m RoleMapper) func(*Settings) {
+	return func(s *Settings) {
+		s.roleMapper = m
+	}
+}
+
+// RoleMapping returns any configured RoleMapper.
+func (s *Settings) RoleMapping() RoleMapper {
+	return s.roleMapper
+}
+
+// ResolveSIDs configures a SIDResolver to provide the names of the SIDs of clients to the RoleMapper.
+// Errors resolving SIDs are logged and the SIDs mapped without their names.
+//
+// s := NewSettings(kt, RoleMapping(m), ResolveSIDs(r))
+func ResolveSIDs(r SIDResolver) func(*Settings) {
+	return func(s *Settings) {
+		s.sidResolver = r
+	}
+}
+
+// ResolveSIDs returns any configured SIDResolver.
+func (s *Settings) ResolveSIDs() SIDResolver {
+	return s.sidResolver
+}
+
+// SharedReplayCache configures the service to detect replayed authenticators with a ReplayStore shared by all the
+// instances of the service, rather than the cache held in the memory of each, so that stateless instances behind a
+// load balancer detect a replay to any of them. The ReplayTokens added to the store are signed with the signer,
+// which should be shared by all the instances. If the store cannot be reached authentication fails.
+//
+// s := NewSettings(kt, SharedReplayCache(store, NewHMACSigner(key)))
+func SharedReplayCache(store ReplayStore, signer SessionSigner) func(*Settings) {
+	return func(s *Settings) {
+		s.replayStore = store
+		s.replaySigner = signer
+	}
+}
+
+// SharedReplayCache returns any configured ReplayStore.
+func (s *Settings) SharedReplayCache() ReplayStore {
+	return s.replayStore
+}
+
+// ReplayCache configures the service to detect replayed authenticators with the ReplayStore provided, such as a
+// FileReplayStore that persists across restarts of the service, rather than the MemoryReplayStore, bounded to
+// DefaultReplayStoreSize entries, shared by default by all Settings in the process. The tokens added to the store are
+// not signed. If the store cannot be reached authentication fails.
+//
+// s := NewSettings(kt, ReplayCache(store))
+func ReplayCache(store ReplayStore) func(*Settings) {
+	return func(s *Settings) {
+		s.replayStore = store
+		s.replaySigner = nil
+	}
+}
+
+// ReplayWindow used to configure how long the service remembers the authenticators presented to it to detect their
+// replay. Authenticators are rejected as outside the clock skew once this has passed, so a window shorter than the
+// maximum clock skew, which it defaults to, is rejected.
+//
+// s := NewSettings(kt, ReplayWindow(10*time.Minute))
+func ReplayWindow(d time.Duration) func(*Settings) {
+	return func(s *Settings) {
+		s.replayWindow = d
+	}
+}
+
+// ReplayWindow returns how long the service remembers authenticators presented to it.
+func (s *Settings) ReplayWindow() time.Duration {
+	if s.replayWindow == 0 {
+		return s.MaxClockSkew()
+	}
+	return s.replayWindow
+}
+
+// checkReplayWindow returns an error if the replay window is shorter than the maximum clock skew, as authenticators
+// could then be replayed once forgotten and before they are outside the clock skew.
+func (s *Settings) checkReplayWindow() error {
+	if s.ReplayWindow() < s.MaxClockSkew() {
+		return fmt.Errorf("replay window of %v is shorter than the maximum clock skew of %v", s.ReplayWindow(), s.MaxClockSkew())
+	}
+	return nil
+}
+
+// MaxTokenSize used to configure the maximum size in bytes of the decoded context tokens the service accepts from
+// clients. Larger tokens are rejected before they are decoded so that clients cannot make the service use unbounded
+// memory.
+//
+// s := NewSettings(kt, MaxTokenSize(128*1024))
+func MaxTokenSize(n int) func(*Settings) {
+	return func(s *Settings) {
+		s.maxTokenSize = n
+	}
+}
+
+// MaxTokenSize returns the maximum size in bytes of the context tokens the service accepts.
+// If none is defined DefaultMaxTokenSize is returned.
+func (s *Settings) MaxTokenSize() int {
+	if s.maxTokenSize <= 0 {
+		return DefaultMaxTokenSize
+	}
+	return s.maxTokenSize
+}
+
+// KeyRotation configures the service to decrypt tickets with the keys of the previous versions during a key rotation
+// window, when the version of the key a ticket is encrypted with is not in the keytab or is wrong, as happens when
+// KDC replicas have not all received the new key. The service's newest key of the ticket's encryption type and up to
+// previous older versions are tried, newest first. The use of an old key is reported to the OldKeyUsed metrics hook.
+//
+// s := NewSettings(kt, KeyRotation(2))
+func KeyRotation(previous int) func(*Settings) {
+	return func(s *Settings) {
+		s.keyRotation = previous
+	}
+}
+
+// KeyRotation returns the number of previous key versions tried to decrypt tickets, zero if tickets are only
+// decrypted with the version of the key they name.
+func (s *Settings) KeyRotation() int {
+	if s.keyRotation < 0 {
+		return 0
+	}
+	return s.keyRotation
+}
+
+// Metrics used to configure the hooks called with measurements taken while the service verifies AP_REQs.
+//
+// s := NewSettings(kt, Metrics(MetricsHooks{OldKeyUsed: f}))
+func Metrics(h MetricsHooks) func(*Settings) {
+	return func(s *Settings) {
+		s.metrics = h
+	}
+}
+
+// Metrics returns the metrics hooks configured.
+func (s *Settings) Metrics() MetricsHooks {
+	return s.metrics
+}
+
+// NegotiationCache configures the service to hold the state of SPNEGO negotiations that need more than one round
+// trip in the store provided, so that stateless front ends can complete them on any instance. Clients must complete
+// the negotiation within the timeout, or DefaultNegotiationTimeout if it is zero, after which it is rejected.
+//
+// s := NewSettings(kt, NegotiationCache(NewMemoryNegotiationStore(), 30*time.Second))
+func NegotiationCache(store NegotiationStore, timeout time.Duration) func(*Settings) {
+	return func(s *Settings) {
+		s.negotiationStore = store
+		s.negotiationTimeout = timeout
+	}
+}
+
+// NegotiationCache returns any configured NegotiationStore.
+func (s *Settings) NegotiationCache() NegotiationStore {
+	return s.negotiationStore
+}
+
+// NegotiationTimeout returns how long clients have to complete a SPNEGO negotiation held in the NegotiationStore.
+// If none is defined DefaultNegotiationTimeout is returned.
+func (s *Settings) NegotiationTimeout() time.Duration {
+	if s.negotiationTimeout <= 0 {
+		return DefaultNegotiationTimeout
+	}
+	return s.negotiationTimeout
+}
+
+// AccessRules configures rules declaring the clients allowed to access paths once authenticated, which are checked
+// by the SPNEGO HTTP handler so that simple services need no authorization code of their own. Clients not allowed by
+// the rule with the longest prefix of the request's path are denied, and requests to paths no rule applies to are
+// served to any authenticated client.
+//
+// s := NewSettings(kt, AccessRules(AccessRule{PathPrefix: "/admin/", SIDs: []string{"S-1-5-21-1-2-3-512"}}))
+func AccessRules(rules ...AccessRule) func(*Settings) {
+	return func(s *Settings) {
+		s.accessRules = append([]AccessRule(nil), rules...)
+	}
+}
+
+// AccessRules returns the configured AccessRules.
+func (s *Settings) AccessRules() []AccessRule {
+	return s.accessRules
+}
+
 // SessionMgr must provide a ways to:
 //
 // - Create new sessions and in the process add a value to the session under the key provided.
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/clock"
+)
+
+// wellKnownSIDs are the names of the SIDs that are the same in every domain, which are resolved without a lookup.
+var wellKnownSIDs = map[string]string{
+	"S-1-1-0":      "Everyone",
+	"S-1-2-0":      "LOCAL",
+	"S-1-5-2":      "NETWORK",
+	"S-1-5-4":      "INTERACTIVE",
+	"S-1-5-6":      "SERVICE",
+	"S-1-5-7":      "ANONYMOUS LOGON",
+	"S-1-5-9":      "ENTERPRISE DOMAIN CONTROLLERS",
+	"S-1-5-11":     "Authenticated Users",
+	"S-1-5-15":     "This Organization",
+	"S-1-5-18":     "SYSTEM",
+	"S-1-5-32-544": "Administrators",
+	"S-1-5-32-545": "Users",
+	"S-1-5-32-546": "Guests",
+	"S-1-18-1":     "Authentication authority asserted identity",
+	"S-1-18-2":     "Service asserted identity",
+}
+
+// SIDCache is a SIDResolver that caches the names resolved by another SIDResolver, such as one searching a directory
+// over LDAP or calling SAMR LsarLookupSids, so that the directory is not queried each time a client authenticates.
+// Resolved names are held for the TTL and SIDs that could not be resolved for the negative TTL. Well known SIDs are
+// resolved without calling the underlying resolver. It is safe for concurrent use.
+//
+// s := NewSettings(kt, ResolveSIDs(NewSIDCache(ldapResolver, time.Hour, 5*time.Minute, nil)))
+type SIDCache struct {
+	resolver    SIDResolver
+	ttl         time.Duration
+	negativeTTL time.Duration
+	clock       clock.Clock
+	entries     map[string]sidCacheEntry
+	lastPurge   time.Time
+	mux         sync.Mutex
+}
+
+// sidCacheEntry is the name of a SID, empty if it could not be resolved, and when the entry expires.
+type sidCacheEntry struct {
+	name    string
+	expires time.Time
+}
+
+// NewSIDCache returns a SIDCache in front of the resolver provided. If the clock is nil the system clock is used.
+func NewSIDCache(r SIDResolver, ttl, negativeTTL time.Duration, c clock.Clock) *SIDCache {
+	if c == nil {
+		c = clock.System
+	}
+	return &SIDCache{
+		resolver:    r,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		clock:       c,
+		entries:     make(map[string]sidCacheEntry),
+		lastPurge:   c.Now(),
+	}
+}
+
+// ResolveSIDs returns the names of the SIDs, calling the underlying resolver once for those not cached. If it
+// returns an error the names that are known are returned with the error and the failure is not cached.
+func (c *SIDCache) ResolveSIDs(sids []string) (map[string]string, error) {
+	names := make(map[string]string)
+	var missing []string
+	now := c.clock.Now()
+	c.mux.Lock()
+	for _, sid := range sids {
+		if n, ok := wellKnownSIDs[sid]; ok {
+			names[sid] = n
+			continue
+		}
+		if e, ok := c.entries[sid]; ok && now.Before(e.expires) {
+			if e.name != "" {
+				names[sid] = e.name
+			}
+			continue
+		}
+		missing = append(missing, sid)
+	}
+	c.mux.Unlock()
+	if len(missing) < 1 {
+		return names, nil
+	}
+	resolved, err := c.resolver.ResolveSIDs(missing)
+	for sid, n := range resolved {
+		names[sid] = n
+	}
+	if err != nil {
+		return names, err
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for _, sid := range missing {
+		if n, ok := resolved[sid]; ok && n != "" {
+			c.entries[sid] = sidCacheEntry{name: n, expires: now.Add(c.ttl)}
+		} else if c.negativeTTL > 0 {
+			c.entries[sid] = sidCacheEntry{expires: now.Add(c.negativeTTL)}
+		}
+	}
+	c.purge(now)
+	return names, nil
+}
+
+// Flush removes all the entries from the cache so that SIDs are resolved again, for example after group renames.
+func (c *SIDCache) Flush() {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.entries = make(map[string]sidCacheEntry)
+}
+
+// Len returns the number of SIDs cached, including those that could not be resolved.
+func (c *SIDCache) Len() int {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return len(c.entries)
+}
+
+// purge removes the expired entries at most once per TTL. The mutex must be held.
+func (c *SIDCache) purge(now time.Time) {
+	if now.Sub(c.lastPurge) < c.ttl {
+		return
+	}
+	for sid, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, sid)
+		}
+	}
+	c.lastPurge = now
+}
+
+// MarshalSID returns the binary encoding of a SID in its string form, as held in the objectSid attribute of
+// directory objects.
+func MarshalSID(sid string) ([]byte, error) {
+	parts := strings.Split(sid, "-")
+	if len(parts) < 3 || parts[0] != "S" || parts[1] != "1" {
+		return nil, fmt.Errorf("%q is not a valid SID", sid)
+	}
+	auth, err := strconv.ParseUint(parts[2], 10, 48)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a valid SID: identifier authority: %v", sid, err)
+	}
+	subs := parts[3:]
+	if len(subs) > 15 {
+		return nil, fmt.Errorf("%q is not a valid SID: too many sub authorities", sid)
+	}
+	b := make([]byte, 8, 8+4*len(subs))
+	b[0] = 1
+	b[1] = byte(len(subs))
+	var a [8]byte
+	binary.BigEndian.PutUint64(a[:], auth)
+	copy(b[2:8], a[2:])
+	for _, s := range subs {
+		v, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a valid SID: sub authority: %v", sid, err)
+		}
+		var sa [4]byte
+		binary.LittleEndian.PutUint32(sa[:], uint32(v))
+		b = append(b, sa[:]...)
+	}
+	return b, nil
+}
+
+// LDAPSIDFilter returns an LDAP search filter matching the directory objects with the objectSid of any of the SIDs
+// provided, for resolvers that search a directory and map the objectSid of each result to its sAMAccountName.
+func LDAPSIDFilter(sids []string) (string, error) {
+	if len(sids) < 1 {
+		return "", errors.New("no SIDs to search for")
+	}
+	var f strings.Builder
+	f.WriteString("(|")
+	for _, sid := range sids {
+		b, err := MarshalSID(sid)
+		if err != nil {
+			return "", err
+		}
+		f.WriteString("(objectSid=")
+		for _, c := range b {
+			fmt.Fprintf(&f, "\\%02x", c)
+		}
+		f.WriteString(")")
+	}
+	f.WriteString(")")
+	return f.String(), nil
+}
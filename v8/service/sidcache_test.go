@@ -0,0 +1,70 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSIDCache(t *testing.T) {
+	t.Parallel()
+	var calls [][]string
+	var fail bool
+	r := SIDResolverFunc(func(sids []string) (map[string]string, error) {
+		calls = append(calls, sids)
+		if fail {
+			return nil, errors.New("directory unavailable")
+		}
+		return map[string]string{testGroupSID: "Readers"}, nil
+	})
+	clk := clock.NewMock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	c := NewSIDCache(r, time.Hour, time.Minute, clk)
+
+	names, err := c.ResolveSIDs([]string{testGroupSID, testOtherSID, "S-1-1-0"})
+	require.NoError(t, err, "error resolving SIDs")
+	assert.Equal(t, map[string]string{testGroupSID: "Readers", "S-1-1-0": "Everyone"}, names, "names not as expected")
+	assert.Equal(t, [][]string{{testGroupSID, testOtherSID}}, calls, "well known SIDs should not be looked up")
+	assert.Equal(t, 2, c.Len(), "resolved and unresolved SIDs should be cached")
+
+	names, err = c.ResolveSIDs([]string{testGroupSID, testOtherSID})
+	require.NoError(t, err, "error resolving cached SIDs")
+	assert.Equal(t, map[string]string{testGroupSID: "Readers"}, names, "cached names not as expected")
+	assert.Len(t, calls, 1, "cached SIDs should not be looked up")
+
+	clk.Add(2 * time.Minute)
+	_, err = c.ResolveSIDs([]string{testGroupSID, testOtherSID})
+	require.NoError(t, err, "error resolving SIDs")
+	assert.Equal(t, []string{testOtherSID}, calls[1], "only the expired negative entry should be looked up")
+
+	clk.Add(2 * time.Hour)
+	fail = true
+	names, err = c.ResolveSIDs([]string{testGroupSID})
+	assert.Error(t, err, "the resolver's error should be returned")
+	assert.Empty(t, names, "no names should be returned")
+	fail = false
+	names, err = c.ResolveSIDs([]string{testGroupSID})
+	require.NoError(t, err, "error resolving SIDs")
+	assert.Equal(t, "Readers", names[testGroupSID], "failed lookups should not be cached")
+
+	c.Flush()
+	assert.Equal(t, 0, c.Len(), "cache should be empty after a flush")
+}
+
+func TestLDAPSIDFilter(t *testing.T) {
+	t.Parallel()
+	f, err := LDAPSIDFilter([]string{"S-1-5-21-1-2-3-500", "S-1-1-0"})
+	require.NoError(t, err, "error creating filter")
+	assert.Equal(t, `(|(objectSid=\01\05\00\00\00\00\00\05\15\00\00\00\01\00\00\00\02\00\00\00\03\00\00\00\f4\01\00\00)`+
+		`(objectSid=\01\01\00\00\00\00\00\01\00\00\00\00))`, f, "filter not as expected")
+
+	for _, sid := range []string{"", "S-1", "X-1-5-21", "S-1-5-abc", "S-1-5-4294967296"} {
+		_, err = MarshalSID(sid)
+		assert.Error(t, err, "%q should not be a valid SID", sid)
+	}
+	_, err = LDAPSIDFilter(nil)
+	assert.Error(t, err, "a filter without SIDs should not be created")
+}
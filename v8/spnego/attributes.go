@@ -0,0 +1,101 @@
+package spnego
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/gssapi"
+	"github.com/jcmturner/gokrb5/v8/iana/chksumtype"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// ContextAttributes describe the protections negotiated for an established security context, in the terms of the
+// GSS-API context flags and of the attributes SSPI's QueryContextAttributes reports, so that security reviews and
+// interop layers can verify them.
+type ContextAttributes struct {
+	Flags           int       // The GSS-API context flags in effect, a combination of the gssapi.ContextFlag values.
+	Confidentiality bool      // Messages can be sealed. SSPI's ISC_RET_CONFIDENTIALITY.
+	Integrity       bool      // Messages can be signed. SSPI's ISC_RET_INTEGRITY.
+	MutualAuth      bool      // The service authenticated itself to the client. SSPI's ISC_RET_MUTUAL_AUTH.
+	Delegation      bool      // The client delegated its credentials to the service. SSPI's ISC_RET_DELEGATE.
+	ReplayDetect    bool      // Replayed messages are detected. SSPI's ISC_RET_REPLAY_DETECT.
+	SequenceDetect  bool      // Out of sequence messages are detected. SSPI's ISC_RET_SEQUENCE_DETECT.
+	KeyType         int32     // The etype of the key protecting messages. SSPI's SECPKG_ATTR_KEY_INFO.
+	Expiry          time.Time // When the service ticket, and so the context, expires. SSPI's SECPKG_ATTR_LIFESPAN.
+	key             types.EncryptionKey
+}
+
+// newContextAttributes returns the attributes of a context with the flags in effect, protecting messages with the
+// key provided, until the expiry time.
+func newContextAttributes(f int, key types.EncryptionKey, expiry time.Time) ContextAttributes {
+	return ContextAttributes{
+		Flags:           f,
+		Confidentiality: f&gssapi.ContextFlagConf != 0,
+		Integrity:       f&gssapi.ContextFlagInteg != 0,
+		MutualAuth:      f&gssapi.ContextFlagMutual != 0,
+		Delegation:      f&gssapi.ContextFlagDeleg != 0,
+		ReplayDetect:    f&gssapi.ContextFlagReplay != 0,
+		SequenceDetect:  f&gssapi.ContextFlagSequence != 0,
+		KeyType:         key.KeyType,
+		Expiry:          expiry,
+		key:             key,
+	}
+}
+
+// UnsafeSessionKey returns the key protecting the messages of the context, as SSPI's SECPKG_ATTR_SESSION_KEY does.
+// It is unsafe as disclosing the key allows the messages of the context to be read and forged, it should only be
+// used to derive keys for channel binding or to interoperate with protocols that require it.
+func (a ContextAttributes) UnsafeSessionKey() types.EncryptionKey {
+	return a.key
+}
+
+// Attributes returns the attributes of the established security context. The context flags in effect are those
+// requested, as gokrb5 does not forward credentials the delegation flag is never in effect.
+func (i *Initiator) Attributes() (ContextAttributes, bool) {
+	if !i.established {
+		return ContextAttributes{}, false
+	}
+	var f int
+	for _, c := range i.flags {
+		f |= c
+	}
+	f &^= gssapi.ContextFlagDeleg
+	key := i.key.Key
+	if len(key.KeyValue) == 0 {
+		key = i.ap.sessionKey
+	}
+	return newContextAttributes(f, key, i.expiry), true
+}
+
+// Attributes returns the attributes of the established security context. The context flags in effect are those the
+// client requested in its authenticator, with delegation in effect only if the client forwarded its credentials.
+func (a *Acceptor) Attributes() (ContextAttributes, bool) {
+	if !a.established {
+		return ContextAttributes{}, false
+	}
+	return a.attrs, true
+}
+
+// acceptorAttributes returns the attributes of the context established with the verified AP_REQ.
+func acceptorAttributes(APReq *messages.APReq, mutual bool) ContextAttributes {
+	c := APReq.Authenticator.Cksum
+	var f int
+	if c.CksumType == chksumtype.GSSAPI && len(c.Checksum) >= 24 {
+		f = int(binary.LittleEndian.Uint32(c.Checksum[20:24]))
+		// The forwarded credentials follow the flags, RFC 4121 section 4.1.1
+		if len(c.Checksum) < 28 || binary.LittleEndian.Uint16(c.Checksum[24:26]) != 1 || binary.LittleEndian.Uint16(c.Checksum[26:28]) == 0 {
+			f &^= gssapi.ContextFlagDeleg
+		}
+	}
+	if mutual {
+		f |= gssapi.ContextFlagMutual
+	} else {
+		f &^= gssapi.ContextFlagMutual
+	}
+	key := APReq.Authenticator.SubKey
+	if len(key.KeyValue) == 0 {
+		key = APReq.Ticket.DecryptedEncPart.Key
+	}
+	return newContextAttributes(f, key, APReq.Ticket.DecryptedEncPart.EndTime)
+}
@@ -0,0 +1,125 @@
+package spnego
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/gssapi"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/service"
+)
+
+// ErrNotConfidential is returned by Channel.Unwrap when a message of a context providing confidentiality was not
+// sealed by the peer.
+var ErrNotConfidential = errors.New("message is not confidential")
+
+// Transport carries the context tokens of a security context between its initiator and acceptor, such as in the
+// frames of a custom RPC protocol. Each token sent must be received whole by the peer.
+type Transport interface {
+	// Send sends a context token to the peer.
+	Send(token []byte) error
+	// Receive returns the next context token sent by the peer.
+	Receive() ([]byte, error)
+}
+
+// Channel is an established security context between a client and a service, protecting the messages of the
+// application's protocol with Wrap and Unwrap.
+type Channel struct {
+	// Context is the security context protecting messages, for applications needing GetMIC, VerifyMIC or DeriveKey.
+	Context *gssapi.SecContext
+	// Attributes are the attributes of the security context.
+	Attributes ContextAttributes
+	// Credentials are those of the client authenticated by the service, nil for the client's Channel.
+	Credentials *credentials.Credentials
+}
+
+// EstablishChannel performs the AP exchange with the service of the SPN provided over the transport and returns the
+// established Channel. Mutual authentication is always requested, so the service's AP_REP is received before the
+// Channel is returned, in addition to any GSS-API context flags provided. If none are provided integrity,
+// confidentiality and sequence detection are requested.
+//
+// ch, err := spnego.EstablishChannel(cl, "rpc/host.example.com", conn, nil)
+func EstablishChannel(cl *client.Client, spn string, t Transport, contextFlags []int) (*Channel, error) {
+	if len(contextFlags) == 0 {
+		contextFlags = []int{gssapi.ContextFlagInteg, gssapi.ContextFlagConf, gssapi.ContextFlagSequence}
+	}
+	i := NewInitiator(cl, spn, append([]int{gssapi.ContextFlagMutual}, contextFlags...))
+	var input []byte
+	for {
+		b, cont, err := i.InitSecContext(input)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) > 0 {
+			if err := t.Send(b); err != nil {
+				return nil, fmt.Errorf("could not send context token: %w", err)
+			}
+		}
+		if !cont {
+			break
+		}
+		input, err = t.Receive()
+		if err != nil {
+			return nil, fmt.Errorf("could not receive context token: %w", err)
+		}
+	}
+	attrs, _ := i.Attributes()
+	return &Channel{
+		Context:    i.SecContextKey().InitiatorSecContext(attrs.Flags),
+		Attributes: attrs,
+	}, nil
+}
+
+// AcceptChannel accepts a security context from a client over the transport, verifying its AP_REQ with the keytab
+// and service settings provided, and returns the established Channel. If the context is rejected the rejection is
+// sent to the client before the error is returned.
+//
+// ch, err := spnego.AcceptChannel(kt, conn, service.ClientAddress(addr))
+func AcceptChannel(kt *keytab.Keytab, t Transport, options ...func(*service.Settings)) (*Channel, error) {
+	a := NewAcceptor(kt, options...)
+	for {
+		input, err := t.Receive()
+		if err != nil {
+			return nil, fmt.Errorf("could not receive context token: %w", err)
+		}
+		b, cont, err := a.AcceptSecContext(input)
+		if len(b) > 0 {
+			if serr := t.Send(b); serr != nil && err == nil {
+				return nil, fmt.Errorf("could not send context token: %w", serr)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !cont {
+			break
+		}
+	}
+	attrs, _ := a.Attributes()
+	creds, _ := a.Credentials()
+	return &Channel{
+		Context:     a.SecContextKey().AcceptorSecContext(attrs.Flags),
+		Attributes:  attrs,
+		Credentials: creds,
+	}, nil
+}
+
+// Wrap protects the message for the peer, sealing it if the context provides confidentiality.
+func (c *Channel) Wrap(msg []byte) ([]byte, error) {
+	return c.Context.Wrap(msg, c.Attributes.Confidentiality)
+}
+
+// Unwrap verifies a message protected by the peer and returns its content. ErrNotConfidential is returned if the
+// context provides confidentiality and the message was not sealed.
+func (c *Channel) Unwrap(token []byte) ([]byte, error) {
+	msg, conf, err := c.Context.Unwrap(token)
+	if err != nil {
+		return nil, err
+	}
+	if c.Attributes.Confidentiality && !conf {
+		return nil, ErrNotConfidential
+	}
+	return msg, nil
+}
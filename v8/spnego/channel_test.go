@@ -0,0 +1,132 @@
+package spnego_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jcmturner/gokrb5/v8/gssapi"
+	"github.com/jcmturner/gokrb5/v8/krbtest"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pipeTransport is one end of an in memory Transport.
+type pipeTransport struct {
+	in  <-chan []byte
+	out chan<- []byte
+}
+
+func (p pipeTransport) Send(b []byte) error {
+	p.out <- b
+	return nil
+}
+
+func (p pipeTransport) Receive() ([]byte, error) {
+	b, ok := <-p.in
+	if !ok {
+		return nil, errors.New("transport closed")
+	}
+	return b, nil
+}
+
+// transportPair returns the two ends of an in memory Transport.
+func transportPair() (pipeTransport, pipeTransport) {
+	a, b := make(chan []byte, 4), make(chan []byte, 4)
+	return pipeTransport{in: a, out: b}, pipeTransport{in: b, out: a}
+}
+
+type acceptResult struct {
+	ch  *spnego.Channel
+	err error
+}
+
+func TestEstablishAcceptChannel(t *testing.T) {
+	t.Parallel()
+	kdc, err := krbtest.NewKDC(krbtest.Realm, krbtest.KDCKeytab())
+	require.NoError(t, err, "error starting mock KDC")
+	defer kdc.Close()
+	cl := krbtest.NewClient(kdc.Config())
+
+	ct, st := transportPair()
+	res := make(chan acceptResult)
+	go func() {
+		ch, err := spnego.AcceptChannel(krbtest.ServiceKeytab(), st)
+		res <- acceptResult{ch, err}
+	}()
+	ich, err := spnego.EstablishChannel(cl, krbtest.ServicePrincipal, ct, nil)
+	require.NoError(t, err, "error establishing channel")
+	r := <-res
+	require.NoError(t, r.err, "error accepting channel")
+	ach := r.ch
+
+	assert.True(t, ich.Attributes.MutualAuth, "mutual authentication should be in effect")
+	assert.True(t, ich.Attributes.Confidentiality, "confidentiality should be in effect")
+	assert.Equal(t, ich.Attributes.Flags, ach.Attributes.Flags, "context flags of the peers should match")
+	assert.Nil(t, ich.Credentials, "client's channel should not hold credentials")
+	require.NotNil(t, ach.Credentials, "service's channel should hold the client's credentials")
+	assert.Equal(t, krbtest.ClientPrincipal, ach.Credentials.UserName(), "authenticated user not as expected")
+
+	w, err := ich.Wrap([]byte("request"))
+	require.NoError(t, err, "error wrapping request")
+	m, err := ach.Unwrap(w)
+	require.NoError(t, err, "error unwrapping request")
+	assert.Equal(t, "request", string(m), "request not as expected")
+	_, err = ach.Unwrap(w)
+	assert.Error(t, err, "replayed request should be rejected")
+
+	w, err = ach.Wrap([]byte("response"))
+	require.NoError(t, err, "error wrapping response")
+	m, err = ich.Unwrap(w)
+	require.NoError(t, err, "error unwrapping response")
+	assert.Equal(t, "response", string(m), "response not as expected")
+
+	w, err = ich.Context.Wrap([]byte("not sealed"), false)
+	require.NoError(t, err, "error wrapping message")
+	_, err = ach.Unwrap(w)
+	assert.True(t, errors.Is(err, spnego.ErrNotConfidential), "message that is not sealed should be rejected")
+}
+
+func TestEstablishChannel_IntegrityOnly(t *testing.T) {
+	t.Parallel()
+	kdc, err := krbtest.NewKDC(krbtest.Realm, krbtest.KDCKeytab())
+	require.NoError(t, err, "error starting mock KDC")
+	defer kdc.Close()
+	cl := krbtest.NewClient(kdc.Config())
+
+	ct, st := transportPair()
+	res := make(chan acceptResult)
+	go func() {
+		ch, err := spnego.AcceptChannel(krbtest.ServiceKeytab(), st)
+		res <- acceptResult{ch, err}
+	}()
+	ich, err := spnego.EstablishChannel(cl, krbtest.ServicePrincipal, ct, []int{gssapi.ContextFlagInteg})
+	require.NoError(t, err, "error establishing channel")
+	r := <-res
+	require.NoError(t, r.err, "error accepting channel")
+	assert.False(t, ich.Attributes.Confidentiality, "confidentiality should not be in effect")
+	assert.True(t, ich.Attributes.MutualAuth, "mutual authentication should always be requested")
+
+	w, err := ich.Wrap([]byte("request"))
+	require.NoError(t, err, "error wrapping request")
+	m, err := r.ch.Unwrap(w)
+	require.NoError(t, err, "error unwrapping request")
+	assert.Equal(t, "request", string(m), "request not as expected")
+}
+
+func TestAcceptChannel_Reject(t *testing.T) {
+	t.Parallel()
+	ct, st := transportPair()
+	require.NoError(t, ct.Send([]byte("not a context token")), "error sending token")
+	_, err := spnego.AcceptChannel(krbtest.ServiceKeytab(), st)
+	assert.Error(t, err, "invalid context token should be rejected")
+	b, err := ct.Receive()
+	require.NoError(t, err, "rejection should be sent to the client")
+	var nt spnego.NegTokenResp
+	require.NoError(t, nt.Unmarshal(b), "error unmarshaling rejection")
+	assert.Equal(t, spnego.NegStateReject, nt.State(), "negotiation state not as expected")
+
+	close(ct.out)
+	_, err = spnego.AcceptChannel(krbtest.ServiceKeytab(), st)
+	assert.Error(t, err, "transport errors should be returned")
+}
@@ -0,0 +1,111 @@
+package spnego
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jcmturner/gokrb5/v8/service"
+)
+
+// ErrTokenTooLarge is returned when decoding a context token larger than the maximum size permitted.
+var ErrTokenTooLarge = errors.New("context token exceeds maximum size")
+
+// maxTokenSize returns the maximum token size to apply, DefaultMaxTokenSize if none is provided.
+func maxTokenSize(max int) int {
+	if max <= 0 {
+		return service.DefaultMaxTokenSize
+	}
+	return max
+}
+
+// EncodeToken returns the base64 encoding of the context token.
+func EncodeToken(b []byte) string {
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// DecodeToken decodes the base64 encoded context token. Encodings of tokens larger than the maximum size in bytes are
+// rejected with ErrTokenTooLarge before they are decoded. If the maximum is not positive service.DefaultMaxTokenSize
+// applies.
+func DecodeToken(s string, max int) ([]byte, error) {
+	max = maxTokenSize(max)
+	if len(s) > base64.StdEncoding.EncodedLen(max) {
+		return nil, fmt.Errorf("%w: encoding of %d bytes is larger than %d bytes can be", ErrTokenTooLarge, len(s), max)
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("error in base64 decoding token: %v", err)
+	}
+	if len(b) > max {
+		return nil, fmt.Errorf("%w: size of %d bytes exceeds maximum of %d", ErrTokenTooLarge, len(b), max)
+	}
+	return b, nil
+}
+
+// EncodeNegotiateHeader returns the value of a Negotiate authorization header holding the context token.
+func EncodeNegotiateHeader(b []byte) string {
+	return HTTPHeaderAuthResponseValueKey + " " + EncodeToken(b)
+}
+
+// DecodeNegotiateHeader decodes the context token within the value of a Negotiate authorization header as
+// DecodeToken does.
+func DecodeNegotiateHeader(h string, max int) ([]byte, error) {
+	s := strings.SplitN(h, " ", 2)
+	if len(s) != 2 || !strings.EqualFold(s[0], HTTPHeaderAuthResponseValueKey) {
+		return nil, errors.New("not a Negotiate header")
+	}
+	return DecodeToken(strings.TrimSpace(s[1]), max)
+}
+
+// ReadBase64Token decodes the base64 encoded context token read from the reader, such as the body of a request, as
+// it is read. Reading stops with ErrTokenTooLarge once more than the maximum size in bytes has been decoded. If the
+// maximum is not positive service.DefaultMaxTokenSize applies.
+func ReadBase64Token(r io.Reader, max int) ([]byte, error) {
+	max = maxTokenSize(max)
+	d := base64.NewDecoder(base64.StdEncoding, r)
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, io.LimitReader(d, int64(max)+1))
+	if err != nil {
+		return nil, fmt.Errorf("error in base64 decoding token: %v", err)
+	}
+	if n > int64(max) {
+		return nil, fmt.Errorf("%w: maximum is %d bytes", ErrTokenTooLarge, max)
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteToken writes the context token to the writer preceded by its length as a 4 byte big endian integer, a framing
+// commonly used to exchange tokens over stream connections.
+func WriteToken(w io.Writer, b []byte) error {
+	if uint64(len(b)) > uint64(^uint32(0)) {
+		return fmt.Errorf("%w: size of %d bytes cannot be framed", ErrTokenTooLarge, len(b))
+	}
+	l := make([]byte, 4, 4+len(b))
+	binary.BigEndian.PutUint32(l, uint32(len(b)))
+	_, err := w.Write(append(l, b...))
+	return err
+}
+
+// ReadToken reads a context token written by WriteToken from the reader. Tokens larger than the maximum size in bytes
+// are rejected with ErrTokenTooLarge before they are read. If the maximum is not positive
+// service.DefaultMaxTokenSize applies.
+func ReadToken(r io.Reader, max int) ([]byte, error) {
+	max = maxTokenSize(max)
+	l := make([]byte, 4)
+	if _, err := io.ReadFull(r, l); err != nil {
+		return nil, fmt.Errorf("error reading token length: %w", err)
+	}
+	n := binary.BigEndian.Uint32(l)
+	if uint64(n) > uint64(max) {
+		return nil, fmt.Errorf("%w: size of %d bytes exceeds maximum of %d", ErrTokenTooLarge, n, max)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, fmt.Errorf("error reading token: %w", err)
+	}
+	return b, nil
+}
@@ -0,0 +1,92 @@
+package spnego
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/service"
+	"github.com/jcmturner/gokrb5/v8/test/testdata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeToken(t *testing.T) {
+	t.Parallel()
+	tkn := []byte("context token")
+	b, err := DecodeToken(EncodeToken(tkn), len(tkn))
+	require.NoError(t, err, "error decoding token")
+	assert.Equal(t, tkn, b, "token not as expected")
+
+	_, err = DecodeToken(EncodeToken(tkn), len(tkn)-1)
+	assert.True(t, errors.Is(err, ErrTokenTooLarge), "token over the maximum should be rejected: %v", err)
+	_, err = DecodeToken(strings.Repeat("A", service.DefaultMaxTokenSize*2), 0)
+	assert.True(t, errors.Is(err, ErrTokenTooLarge), "token over the default maximum should be rejected: %v", err)
+	_, err = DecodeToken("not base64!", 0)
+	assert.Error(t, err, "invalid encoding should be rejected")
+	assert.False(t, errors.Is(err, ErrTokenTooLarge), "invalid encoding is not too large")
+
+	b, err = DecodeNegotiateHeader(EncodeNegotiateHeader(tkn), 0)
+	require.NoError(t, err, "error decoding header")
+	assert.Equal(t, tkn, b, "token not as expected")
+	_, err = DecodeNegotiateHeader("Basic "+EncodeToken(tkn), 0)
+	assert.Error(t, err, "header of another scheme should be rejected")
+}
+
+func TestReadBase64Token(t *testing.T) {
+	t.Parallel()
+	tkn := bytes.Repeat([]byte{0x01}, 1000)
+	b, err := ReadBase64Token(strings.NewReader(EncodeToken(tkn)), len(tkn))
+	require.NoError(t, err, "error reading token")
+	assert.Equal(t, tkn, b, "token not as expected")
+
+	r := strings.NewReader(EncodeToken(bytes.Repeat(tkn, 100)))
+	_, err = ReadBase64Token(r, len(tkn))
+	assert.True(t, errors.Is(err, ErrTokenTooLarge), "token over the maximum should be rejected: %v", err)
+	assert.True(t, r.Len() > 0, "reading should stop once the maximum is exceeded")
+}
+
+func TestReadToken(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	tkn := []byte("context token")
+	require.NoError(t, WriteToken(&buf, tkn), "error writing token")
+	require.NoError(t, WriteToken(&buf, tkn), "error writing token")
+	for i := 0; i < 2; i++ {
+		b, err := ReadToken(&buf, 0)
+		require.NoError(t, err, "error reading token")
+		assert.Equal(t, tkn, b, "token not as expected")
+	}
+	_, err := ReadToken(&buf, 0)
+	assert.Error(t, err, "reading past the last token should fail")
+
+	// The length is checked before the token is read
+	_, err = ReadToken(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff}), 0)
+	assert.True(t, errors.Is(err, ErrTokenTooLarge), "token over the maximum should be rejected: %v", err)
+	_, err = ReadToken(bytes.NewReader([]byte{0x00, 0x00, 0x00, 0x10, 0x01}), 0)
+	assert.Error(t, err, "truncated token should be rejected")
+}
+
+func TestGetAuthorizationNegotiationHeaderAsSPNEGOToken_MaxTokenSize(t *testing.T) {
+	t.Parallel()
+	b, _ := hex.DecodeString(testdata.HTTP_KEYTAB)
+	kt := keytab.New()
+	kt.Unmarshal(b)
+	spnegoB, _ := hex.DecodeString(testGSSAPIInit)
+
+	r, _ := http.NewRequest("GET", "http://host.test.gokrb5", nil)
+	r.Header.Set(HTTPHeaderAuthRequest, EncodeNegotiateHeader(spnegoB))
+	_, err := getAuthorizationNegotiationHeaderAsSPNEGOToken(SPNEGOService(kt), r, httptest.NewRecorder())
+	assert.NoError(t, err, "token within the default maximum should be accepted")
+
+	w := httptest.NewRecorder()
+	_, err = getAuthorizationNegotiationHeaderAsSPNEGOToken(SPNEGOService(kt, service.MaxTokenSize(len(spnegoB)-1)), r, w)
+	assert.True(t, errors.Is(err, ErrTokenTooLarge), "token over the maximum should be rejected: %v", err)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "status code not as expected")
+	assert.Equal(t, spnegoNegTokenRespReject, w.Header().Get(HTTPHeaderAuthResponse), "token over the maximum should be rejected")
+}
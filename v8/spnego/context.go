@@ -0,0 +1,337 @@
+package spnego
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/asn1"
+	"github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/gssapi"
+	"github.com/jcmturner/gokrb5/v8/iana/chksumtype"
+	"github.com/jcmturner/gokrb5/v8/iana/flags"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/krberror"
+	"github.com/jcmturner/gokrb5/v8/service"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// Initiator establishes a SPNEGO security context with a service over any transport. The tokens it generates are
+// the raw bytes of the SPNEGO context tokens, without the base64 encoding or headers of HTTP, for the application to
+// send to the service in whatever way its protocol embeds them.
+type Initiator struct {
+	client      *client.Client
+	spn         string
+	flags       []int
+	ap          apExchange
+	key         SecContextKey
+	expiry      time.Time
+	sent        bool
+	established bool
+}
+
+// NewInitiator returns an Initiator establishing a security context for the Kerberos client with the service of the
+// SPN provided. The GSS-API context flags are requested of the service, if none are provided integrity,
+// confidentiality and mutual authentication are requested.
+func NewInitiator(cl *client.Client, spn string, contextFlags []int) *Initiator {
+	if len(contextFlags) == 0 {
+		contextFlags = []int{gssapi.ContextFlagInteg, gssapi.ContextFlagConf, gssapi.ContextFlagMutual}
+	}
+	return &Initiator{
+		client: cl,
+		spn:    spn,
+		flags:  contextFlags,
+	}
+}
+
+// InitSecContext is called with no input to generate the initial context token to send to the service, and then with
+// each token the service returns until the boolean returned is false, indicating that the context is established and
+// there is no further token to send. If mutual authentication was requested the service's token must contain the
+// AP_REP authenticating it.
+func (i *Initiator) InitSecContext(input []byte) ([]byte, bool, error) {
+	if i.established {
+		return nil, false, errors.New("security context is already established")
+	}
+	if !i.sent {
+		b, err := i.initialToken()
+		if err != nil {
+			return nil, false, err
+		}
+		i.sent = true
+		i.established = !i.mutual()
+		return b, !i.established, nil
+	}
+	var nt NegTokenResp
+	if err := nt.Unmarshal(input); err != nil {
+		return nil, false, krberror.Errorf(err, krberror.EncodingError, "could not unmarshal the service's SPNEGO response")
+	}
+	switch nt.State() {
+	case NegStateAcceptCompleted:
+	case NegStateReject:
+		return nil, false, krberror.NewErrorf(krberror.KRBMsgError, "service rejected the security context")
+	default:
+		return nil, false, krberror.NewErrorf(krberror.KRBMsgError, "service's negotiation state %d is not supported", nt.State())
+	}
+	if len(nt.ResponseToken) == 0 {
+		return nil, false, krberror.NewErrorf(krberror.KRBMsgError, "service did not return an AP_REP to mutually authenticate")
+	}
+	if err := verifyResponseToken(nt.ResponseToken, i.ap); err != nil {
+		return nil, false, err
+	}
+	i.established = true
+	return nil, false, nil
+}
+
+// Established reports if the security context is established.
+func (i *Initiator) Established() bool {
+	return i.established
+}
+
+// SecContextKey returns the SecContextKey established by the initiator's authenticator with which the messages of
+// the security context are protected.
+func (i *Initiator) SecContextKey() SecContextKey {
+	return i.key
+}
+
+// initialToken generates the marshaled NegTokenInit holding the AP_REQ for the service.
+func (i *Initiator) initialToken() ([]byte, error) {
+	st, err := i.initToken()
+	if err != nil {
+		return nil, err
+	}
+	b, err := st.Marshal()
+	if err != nil {
+		return nil, krberror.Errorf(err, krberror.EncodingError, "could not marshal SPNEGO")
+	}
+	return b, nil
+}
+
+// initToken generates the NegTokenInit holding the AP_REQ for the service, recording the AP exchange with which the
+// service's AP_REP is verified.
+func (i *Initiator) initToken() (*SPNEGOToken, error) {
+	if err := i.client.AffirmLogin(); err != nil {
+		return nil, fmt.Errorf("could not acquire client credential: %v", err)
+	}
+	tkt, key, err := i.client.GetServiceTicket(i.spn)
+	if err != nil {
+		return nil, fmt.Errorf("could not initialize context: %v", err)
+	}
+	mt, err := newKRB5TokenAPREQ(i.client, tkt, key, i.flags, []int{}, true)
+	if err != nil {
+		return nil, fmt.Errorf("could not initialize context: %v", err)
+	}
+	mtb, err := mt.Marshal()
+	if err != nil {
+		return nil, krberror.Errorf(err, krberror.EncodingError, "could not marshal KRB5 token")
+	}
+	st := &SPNEGOToken{
+		Init: true,
+		NegTokenInit: NegTokenInit{
+			MechTypes:      []asn1.ObjectIdentifier{gssapi.OIDKRB5.OID()},
+			MechTokenBytes: mtb,
+		},
+	}
+	i.ap = apExchange{
+		sessionKey:    key,
+		authenticator: mt.APReq.Authenticator,
+	}
+	i.key = SecContextKey{
+		Key:       mt.APReq.Authenticator.SubKey,
+		SeqNumber: mt.APReq.Authenticator.SeqNumber,
+	}
+	if e, ok := i.client.GetCacheEntry(i.spn); ok {
+		i.expiry = e.EndTime
+	}
+	return st, nil
+}
+
+// mutual reports if the initiator requests mutual authentication.
+func (i *Initiator) mutual() bool {
+	for _, f := range i.flags {
+		if f == gssapi.ContextFlagMutual {
+			return true
+		}
+	}
+	return false
+}
+
+// Acceptor accepts SPNEGO security contexts from clients over any transport. The tokens it consumes and generates are
+// the raw bytes of the context tokens, without the base64 encoding or headers of HTTP.
+type Acceptor struct {
+	spnego      *SPNEGO
+	ctx         context.Context
+	attrs       ContextAttributes
+	key         SecContextKey
+	raw         bool
+	established bool
+}
+
+// NewAcceptor returns an Acceptor verifying clients' AP_REQs with the keytab and service settings provided. As there
+// is no HTTP request to take it from, the client's address should be provided with service.ClientAddress if the
+// tickets presented to the service hold addresses.
+func NewAcceptor(kt *keytab.Keytab, options ...func(*service.Settings)) *Acceptor {
+	return &Acceptor{
+		spnego: SPNEGOService(kt, options...),
+	}
+}
+
+// AcceptSecContext is called with each token the client sends until the boolean returned is false, indicating that
+// the context is established or has been rejected. Any token returned should be sent to the client, including when
+// an error is returned, so that it knows the context has been rejected. Raw KRB5 context tokens are accepted as well
+// as SPNEGO tokens unless the service decodes strictly.
+func (a *Acceptor) AcceptSecContext(input []byte) ([]byte, bool, error) {
+	if a.established {
+		return nil, false, errors.New("security context is already established")
+	}
+	if max := a.spnego.serviceSettings.MaxTokenSize(); len(input) > max {
+		return rejectToken(), false, fmt.Errorf("%w: size of %d bytes exceeds maximum of %d", ErrTokenTooLarge, len(input), max)
+	}
+	st, err := a.spnego.unmarshalContextToken(input)
+	if err != nil {
+		return rejectToken(), false, krberror.Errorf(err, krberror.EncodingError, "could not unmarshal context token")
+	}
+	a.raw = st.raw
+	ok, ctx, status := a.spnego.AcceptSecContext(st)
+	if status.Code == gssapi.StatusContinueNeeded {
+		b, err := respToken(NegStateAcceptIncomplete, nil)
+		return b, true, err
+	}
+	if status.Code != gssapi.StatusComplete {
+		return a.reject(), false, status
+	}
+	if !ok {
+		return a.reject(), false, gssapi.Status{Code: gssapi.StatusDefectiveCredential, Message: "KRB5_AP_REQ token not valid"}
+	}
+	mt, _ := st.krb5Token()
+	mutual := mt != nil && mutualRequired(mt)
+	rt, err := st.mutualResponseToken()
+	if err != nil {
+		return a.reject(), false, err
+	}
+	b := rt
+	if !a.raw {
+		b, err = respToken(NegStateAcceptCompleted, rt)
+		if err != nil {
+			return rejectToken(), false, err
+		}
+	}
+	a.ctx = ctx
+	if mt != nil {
+		a.attrs = acceptorAttributes(&mt.APReq, mutual)
+		a.key = SecContextKey{
+			Key:       a.attrs.key,
+			SeqNumber: mt.APReq.Authenticator.SeqNumber,
+		}
+	}
+	a.established = true
+	return b, false, nil
+}
+
+// RawKRB5 reports if the client sent a raw KRB5 context token rather than negotiating the mechanism with SPNEGO. The
+// tokens returned to such a client are also raw KRB5 context tokens, so the AP_REP if mutual authentication was
+// requested and none otherwise.
+func (a *Acceptor) RawKRB5() bool {
+	return a.raw
+}
+
+// reject returns the token rejecting the security context, none for a client that sent a raw KRB5 context token.
+func (a *Acceptor) reject() []byte {
+	if a.raw {
+		return nil
+	}
+	return rejectToken()
+}
+
+// Established reports if the security context is established.
+func (a *Acceptor) Established() bool {
+	return a.established
+}
+
+// SecContextKey returns the SecContextKey with which the messages of the established security context are protected,
+// the subkey of the client's authenticator or the session key of its ticket if the authenticator has none.
+func (a *Acceptor) SecContextKey() SecContextKey {
+	return a.key
+}
+
+// Context returns the context of the established security context, holding the client's credentials, VerifiedAPReq
+// and SecContextKey.
+func (a *Acceptor) Context() context.Context {
+	return a.ctx
+}
+
+// Credentials returns the credentials of the client authenticated by the established security context.
+func (a *Acceptor) Credentials() (*credentials.Credentials, bool) {
+	if a.ctx == nil {
+		return nil, false
+	}
+	creds, ok := a.ctx.Value(ctxCredentials).(*credentials.Credentials)
+	return creds, ok
+}
+
+// krb5Token returns the verified KRB5 token within the SPNEGO token.
+func (s *SPNEGOToken) krb5Token() (*KRB5Token, bool) {
+	var mt gssapi.ContextToken
+	if s.Init {
+		mt = s.NegTokenInit.mechToken
+	}
+	if s.Resp {
+		mt = s.NegTokenResp.mechToken
+	}
+	k, ok := mt.(*KRB5Token)
+	return k, ok
+}
+
+// mutualResponseToken returns the marshaled KRB5 token holding the AP_REP for the verified KRB5 token within the SPNEGO
+// token, if the client requested mutual authentication, and nil otherwise.
+func (s *SPNEGOToken) mutualResponseToken() ([]byte, error) {
+	mt, ok := s.krb5Token()
+	if !ok || !mutualRequired(mt) {
+		return nil, nil
+	}
+	rep, err := service.NewAPRep(&mt.APReq)
+	if err != nil {
+		return nil, krberror.Errorf(err, krberror.EncryptingError, "could not create AP_REP")
+	}
+	rmt := NewKRB5TokenAPREP(rep)
+	b, err := rmt.Marshal()
+	if err != nil {
+		return nil, krberror.Errorf(err, krberror.EncodingError, "could not marshal KRB5 token")
+	}
+	return b, nil
+}
+
+// mutualRequired reports if the client's AP_REQ requests mutual authentication, either with the AP option or the
+// context flags of its authenticator's checksum. RFC 4121 section 4.1.1
+func mutualRequired(mt *KRB5Token) bool {
+	if types.IsFlagSet(&mt.APReq.APOptions, flags.APOptionMutualRequired) {
+		return true
+	}
+	c := mt.APReq.Authenticator.Cksum
+	if c.CksumType != chksumtype.GSSAPI || len(c.Checksum) < 24 {
+		return false
+	}
+	return binary.LittleEndian.Uint32(c.Checksum[20:24])&uint32(gssapi.ContextFlagMutual) != 0
+}
+
+// respToken marshals a NegTokenResp of the state provided with KRB5 as the supported mechanism.
+func respToken(state NegState, responseToken []byte) ([]byte, error) {
+	nt := NegTokenResp{
+		NegState:      asn1.Enumerated(state),
+		SupportedMech: gssapi.OIDKRB5.OID(),
+		ResponseToken: responseToken,
+	}
+	b, err := nt.Marshal()
+	if err != nil {
+		return nil, krberror.Errorf(err, krberror.EncodingError, "could not marshal SPNEGO response")
+	}
+	return b, nil
+}
+
+// rejectToken returns the NegTokenResp rejecting the security context.
+func rejectToken() []byte {
+	b, _ := (&NegTokenResp{NegState: asn1.Enumerated(NegStateReject)}).Marshal()
+	return b
+}
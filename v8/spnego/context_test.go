@@ -0,0 +1,269 @@
+package spnego_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jcmturner/goidentity/v6"
+	"github.com/jcmturner/gokrb5/v8/asn1"
+	"github.com/jcmturner/gokrb5/v8/gssapi"
+	"github.com/jcmturner/gokrb5/v8/krbtest"
+	"github.com/jcmturner/gokrb5/v8/service"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInitiatorAcceptor(t *testing.T) {
+	t.Parallel()
+	kdc, err := krbtest.NewKDC(krbtest.Realm, krbtest.KDCKeytab())
+	require.NoError(t, err, "error starting mock KDC")
+	defer kdc.Close()
+	cl := krbtest.NewClient(kdc.Config())
+
+	i := spnego.NewInitiator(cl, krbtest.ServicePrincipal, nil)
+	a := spnego.NewAcceptor(krbtest.ServiceKeytab())
+	ct, cont, err := i.InitSecContext(nil)
+	require.NoError(t, err, "error generating initial context token")
+	assert.True(t, cont, "mutual authentication should require the service's token")
+	rt, cont, err := a.AcceptSecContext(ct)
+	require.NoError(t, err, "error accepting context token")
+	assert.False(t, cont, "acceptor should not need another token")
+	assert.True(t, a.Established(), "acceptor's context should be established")
+	_, cont, err = i.InitSecContext(rt)
+	require.NoError(t, err, "error verifying the service's token")
+	assert.False(t, cont, "initiator should not need another token")
+	assert.True(t, i.Established(), "initiator's context should be established")
+	_, _, err = i.InitSecContext(rt)
+	assert.Error(t, err, "established context should not accept further tokens")
+
+	creds, ok := a.Credentials()
+	require.True(t, ok, "acceptor should have the client's credentials")
+	assert.Equal(t, krbtest.ClientPrincipal, creds.UserName(), "authenticated user not as expected")
+
+	// Messages are protected with the key established by the initiator's authenticator
+	ak, ok := spnego.SecContextKeyFromContext(a.Context())
+	require.True(t, ok, "acceptor should have the initiator's subkey")
+	ic := i.SecContextKey().InitiatorSecContext(gssapi.ContextFlagSequence)
+	ac := ak.AcceptorSecContext(gssapi.ContextFlagSequence)
+	w, err := ic.Wrap([]byte("hello"), true)
+	require.NoError(t, err, "error wrapping message")
+	m, conf, err := ac.Unwrap(w)
+	require.NoError(t, err, "error unwrapping message")
+	assert.True(t, conf, "message should be confidential")
+	assert.Equal(t, "hello", string(m), "message not as expected")
+}
+
+func TestInitiator_WithoutMutual(t *testing.T) {
+	t.Parallel()
+	kdc, err := krbtest.NewKDC(krbtest.Realm, krbtest.KDCKeytab())
+	require.NoError(t, err, "error starting mock KDC")
+	defer kdc.Close()
+	cl := krbtest.NewClient(kdc.Config())
+
+	i := spnego.NewInitiator(cl, krbtest.ServicePrincipal, []int{gssapi.ContextFlagInteg})
+	ct, cont, err := i.InitSecContext(nil)
+	require.NoError(t, err, "error generating initial context token")
+	assert.False(t, cont, "the service's token is not needed without mutual authentication")
+	assert.True(t, i.Established(), "initiator's context should be established")
+
+	rt, cont, err := spnego.NewAcceptor(krbtest.ServiceKeytab()).AcceptSecContext(ct)
+	require.NoError(t, err, "error accepting context token")
+	assert.False(t, cont, "acceptor should not need another token")
+	var nt spnego.NegTokenResp
+	require.NoError(t, nt.Unmarshal(rt), "error unmarshalling the service's token")
+	assert.Equal(t, spnego.NegStateAcceptCompleted, nt.State(), "negotiation state not as expected")
+	assert.Empty(t, nt.ResponseToken, "AP_REP should not be returned without mutual authentication")
+}
+
+func TestAcceptor_Reject(t *testing.T) {
+	t.Parallel()
+	a := spnego.NewAcceptor(krbtest.ServiceKeytab())
+	rt, cont, err := a.AcceptSecContext([]byte("not a token"))
+	assert.Error(t, err, "defective token should not be accepted")
+	assert.False(t, cont, "rejected context should not continue")
+	var nt spnego.NegTokenResp
+	require.NoError(t, nt.Unmarshal(rt), "error unmarshalling the rejection token")
+	assert.Equal(t, spnego.NegStateReject, nt.State(), "negotiation state not as expected")
+}
+
+// rawKRB5Token returns a raw KRB5 context token, not wrapped in SPNEGO, requesting mutual authentication of the test
+// service, along with the session key of its ticket.
+func rawKRB5Token(t *testing.T, kdc *krbtest.KDC) ([]byte, spnego.KRB5Token, types.EncryptionKey) {
+	cl := krbtest.NewClient(kdc.Config())
+	tkt, key, err := cl.GetServiceTicket(krbtest.ServicePrincipal)
+	require.NoError(t, err, "error getting service ticket")
+	mt, err := spnego.NewKRB5TokenAPREQ(cl, tkt, key, []int{gssapi.ContextFlagInteg, gssapi.ContextFlagMutual}, []int{})
+	require.NoError(t, err, "error creating KRB5 token")
+	b, err := mt.Marshal()
+	require.NoError(t, err, "error marshalling KRB5 token")
+	return b, mt, key
+}
+
+func TestAcceptor_RawKRB5Token(t *testing.T) {
+	t.Parallel()
+	kdc, err := krbtest.NewKDC(krbtest.Realm, krbtest.KDCKeytab())
+	require.NoError(t, err, "error starting mock KDC")
+	defer kdc.Close()
+	b, mt, key := rawKRB5Token(t, kdc)
+
+	a := spnego.NewAcceptor(krbtest.ServiceKeytab())
+	rt, cont, err := a.AcceptSecContext(b)
+	require.NoError(t, err, "raw KRB5 token should be accepted")
+	assert.False(t, cont, "acceptor should not need another token")
+	assert.True(t, a.RawKRB5(), "token should be detected as a raw KRB5 token")
+	creds, ok := a.Credentials()
+	require.True(t, ok, "acceptor should have the client's credentials")
+	assert.Equal(t, krbtest.ClientPrincipal, creds.UserName(), "authenticated user not as expected")
+
+	// The AP_REP is returned as a raw KRB5 token
+	var rep spnego.KRB5Token
+	require.NoError(t, rep.Unmarshal(rt), "response should be a raw KRB5 token")
+	require.True(t, rep.IsAPRep(), "response should hold an AP_REP")
+	require.NoError(t, rep.APRep.DecryptEncPart(key), "error decrypting AP_REP")
+	assert.NoError(t, rep.APRep.VerifyAuthenticator(mt.APReq.Authenticator), "AP_REP does not match the authenticator")
+
+	_, _, err = spnego.NewAcceptor(krbtest.ServiceKeytab(), service.StrictDecoding(true)).AcceptSecContext(b)
+	assert.Error(t, err, "raw KRB5 token should not be accepted when decoding strictly")
+}
+
+func TestSPNEGOKRB5Authenticate_RawKRB5Token(t *testing.T) {
+	t.Parallel()
+	kdc, err := krbtest.NewKDC(krbtest.Realm, krbtest.KDCKeytab())
+	require.NoError(t, err, "error starting mock KDC")
+	defer kdc.Close()
+	b, _, _ := rawKRB5Token(t, kdc)
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, goidentity.FromHTTPRequestContext(r).UserName())
+	})
+	s := httptest.NewServer(spnego.SPNEGOKRB5Authenticate(inner, krbtest.ServiceKeytab()))
+	defer s.Close()
+
+	r, _ := http.NewRequest("GET", s.URL, nil)
+	r.Header.Set(spnego.HTTPHeaderAuthRequest, spnego.EncodeNegotiateHeader(b))
+	resp, err := http.DefaultClient.Do(r)
+	require.NoError(t, err, "error sending request")
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "status code not as expected")
+	assert.Empty(t, resp.Header.Get(spnego.HTTPHeaderAuthResponse), "SPNEGO response token should not be sent to a raw KRB5 client")
+}
+
+func TestContextAttributes(t *testing.T) {
+	t.Parallel()
+	kdc, err := krbtest.NewKDC(krbtest.Realm, krbtest.KDCKeytab())
+	require.NoError(t, err, "error starting mock KDC")
+	defer kdc.Close()
+	cl := krbtest.NewClient(kdc.Config())
+
+	i := spnego.NewInitiator(cl, krbtest.ServicePrincipal, []int{gssapi.ContextFlagInteg, gssapi.ContextFlagConf, gssapi.ContextFlagMutual, gssapi.ContextFlagDeleg})
+	a := spnego.NewAcceptor(krbtest.ServiceKeytab())
+	_, ok := i.Attributes()
+	assert.False(t, ok, "attributes should not be available before the context is established")
+	ct, _, err := i.InitSecContext(nil)
+	require.NoError(t, err, "error generating initial context token")
+	rt, _, err := a.AcceptSecContext(ct)
+	require.NoError(t, err, "error accepting context token")
+	_, _, err = i.InitSecContext(rt)
+	require.NoError(t, err, "error verifying the service's token")
+
+	ia, ok := i.Attributes()
+	require.True(t, ok, "initiator's attributes should be available")
+	aa, ok := a.Attributes()
+	require.True(t, ok, "acceptor's attributes should be available")
+	for side, attrs := range map[string]spnego.ContextAttributes{"initiator": ia, "acceptor": aa} {
+		assert.True(t, attrs.Confidentiality, "%s's context should be confidential", side)
+		assert.True(t, attrs.Integrity, "%s's context should have integrity", side)
+		assert.True(t, attrs.MutualAuth, "%s's context should be mutually authenticated", side)
+		assert.False(t, attrs.Delegation, "%s's context should not have delegated credentials", side)
+		assert.Equal(t, gssapi.ContextFlagInteg|gssapi.ContextFlagConf|gssapi.ContextFlagMutual, attrs.Flags, "%s's context flags not as expected", side)
+		assert.Equal(t, i.SecContextKey().Key.KeyType, attrs.KeyType, "%s's key type not as expected", side)
+		assert.Equal(t, i.SecContextKey().Key, attrs.UnsafeSessionKey(), "%s's session key should be the initiator's subkey", side)
+		assert.False(t, attrs.Expiry.IsZero(), "%s's context expiry should be set", side)
+	}
+	assert.True(t, ia.Expiry.Equal(aa.Expiry), "both sides should expire with the ticket")
+
+	i = spnego.NewInitiator(cl, krbtest.ServicePrincipal, []int{gssapi.ContextFlagInteg})
+	a = spnego.NewAcceptor(krbtest.ServiceKeytab())
+	ct, _, err = i.InitSecContext(nil)
+	require.NoError(t, err, "error generating initial context token")
+	_, _, err = a.AcceptSecContext(ct)
+	require.NoError(t, err, "error accepting context token")
+	for side, attrs := range map[string]func() (spnego.ContextAttributes, bool){"initiator": i.Attributes, "acceptor": a.Attributes} {
+		ca, ok := attrs()
+		require.True(t, ok, "%s's attributes should be available", side)
+		assert.False(t, ca.MutualAuth, "%s's context should not be mutually authenticated", side)
+		assert.False(t, ca.Confidentiality, "%s's context should not be confidential", side)
+		assert.True(t, ca.Integrity, "%s's context should have integrity", side)
+	}
+}
+
+func TestSPNEGOKRB5Authenticate_AccessRules(t *testing.T) {
+	t.Parallel()
+	kdc, err := krbtest.NewKDC(krbtest.Realm, krbtest.KDCKeytab())
+	require.NoError(t, err, "error starting mock KDC")
+	defer kdc.Close()
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, goidentity.FromHTTPRequestContext(r).UserName())
+	})
+	s := httptest.NewServer(spnego.SPNEGOKRB5Authenticate(inner, krbtest.ServiceKeytab(), service.AccessRules(
+		service.AccessRule{PathPrefix: "/", Principals: []string{"*@" + krbtest.Realm}},
+		service.AccessRule{PathPrefix: "/admin/", Principals: []string{"admin@" + krbtest.Realm}},
+	)))
+	defer s.Close()
+
+	var tests = []struct {
+		path   string
+		status int
+	}{
+		{"/", http.StatusOK},
+		{"/reports", http.StatusOK},
+		{"/admin/users", http.StatusForbidden},
+	}
+	for _, test := range tests {
+		b, _, _ := rawKRB5Token(t, kdc)
+		r, _ := http.NewRequest("GET", s.URL+test.path, nil)
+		r.Header.Set(spnego.HTTPHeaderAuthRequest, spnego.EncodeNegotiateHeader(b))
+		resp, err := http.DefaultClient.Do(r)
+		require.NoError(t, err, "error sending request")
+		resp.Body.Close()
+		assert.Equal(t, test.status, resp.StatusCode, "status code for %s not as expected", test.path)
+	}
+}
+
+func TestSPNEGO_RekeySecContext(t *testing.T) {
+	t.Parallel()
+	kdc, err := krbtest.NewKDC(krbtest.Realm, krbtest.KDCKeytab())
+	require.NoError(t, err, "error starting mock KDC")
+	defer kdc.Close()
+	cl := krbtest.NewClient(kdc.Config())
+	s := spnego.SPNEGOClient(cl, krbtest.ServicePrincipal)
+
+	ct, rk, err := s.RekeySecContext()
+	require.NoError(t, err, "error generating rekeying context token")
+	b, err := ct.Marshal()
+	require.NoError(t, err, "error marshaling rekeying context token")
+	a := spnego.NewAcceptor(krbtest.ServiceKeytab())
+	rt, _, err := a.AcceptSecContext(b)
+	require.NoError(t, err, "error accepting rekeying context token")
+	k, err := rk.Confirm(rt)
+	require.NoError(t, err, "error confirming rekeying")
+	assert.Equal(t, a.SecContextKey(), k, "SecContextKey of the peers should match")
+	assert.NotEmpty(t, k.Key.KeyValue, "new subkey should be established")
+
+	// The new key is not returned if the service does not authenticate itself with an AP_REP
+	ct, rk, err = s.RekeySecContext()
+	require.NoError(t, err, "error generating rekeying context token")
+	b, err = ct.Marshal()
+	require.NoError(t, err, "error marshaling rekeying context token")
+	_, _, err = spnego.NewAcceptor(krbtest.ServiceKeytab()).AcceptSecContext(b)
+	require.NoError(t, err, "error accepting rekeying context token")
+	nt := spnego.NegTokenResp{NegState: asn1.Enumerated(spnego.NegStateAcceptCompleted), SupportedMech: gssapi.OIDKRB5.OID()}
+	rt, err = nt.Marshal()
+	require.NoError(t, err, "error marshaling response token")
+	k, err = rk.Confirm(rt)
+	assert.Error(t, err, "rekeying should not be confirmed without an AP_REP")
+	assert.Equal(t, spnego.SecContextKey{}, k, "SecContextKey should not be returned without confirmation")
+}
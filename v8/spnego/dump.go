@@ -0,0 +1,103 @@
+package spnego
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jcmturner/gokrb5/v8/asn1"
+	"github.com/jcmturner/gokrb5/v8/gssapi"
+)
+
+// String returns the name of the negotiation state.
+func (s NegState) String() string {
+	switch s {
+	case NegStateAcceptCompleted:
+		return "accept-completed"
+	case NegStateAcceptIncomplete:
+		return "accept-incomplete"
+	case NegStateReject:
+		return "reject"
+	case NegStateRequestMIC:
+		return "request-mic"
+	}
+	return fmt.Sprintf("unknown(%d)", int(s))
+}
+
+// String returns a description of the SPNEGO token for diagnostics. Secret values are not included.
+func (s SPNEGOToken) String() string {
+	if s.Init {
+		return "SPNEGO " + s.NegTokenInit.String()
+	}
+	if s.Resp {
+		return "SPNEGO " + s.NegTokenResp.String()
+	}
+	return "SPNEGO token (empty)"
+}
+
+// String returns a description of the NegTokenInit for diagnostics. Secret values are not included.
+func (n NegTokenInit) String() string {
+	mechs := make([]string, len(n.MechTypes))
+	for i, m := range n.MechTypes {
+		mechs[i] = mechString(m)
+	}
+	s := "NegTokenInit" + dumpField("MechTypes", strings.Join(mechs, ", "))
+	if len(n.MechTokenBytes) > 0 {
+		s += dumpField("MechToken", mechTokenString(n.MechTokenBytes))
+	}
+	if len(n.MechListMIC) > 0 {
+		s += dumpField("MechListMIC", fmt.Sprintf("%d bytes", len(n.MechListMIC)))
+	}
+	return s
+}
+
+// String returns a description of the NegTokenResp for diagnostics. Secret values are not included.
+func (n NegTokenResp) String() string {
+	s := "NegTokenResp" + dumpField("NegState", NegState(n.NegState))
+	if len(n.SupportedMech) > 0 {
+		s += dumpField("SupportedMech", mechString(n.SupportedMech))
+	}
+	if len(n.ResponseToken) > 0 {
+		s += dumpField("ResponseToken", mechTokenString(n.ResponseToken))
+	}
+	if len(n.MechListMIC) > 0 {
+		s += dumpField("MechListMIC", fmt.Sprintf("%d bytes", len(n.MechListMIC)))
+	}
+	return s
+}
+
+// String returns a description of the KRB5 token for diagnostics. Secret values are not included.
+func (m KRB5Token) String() string {
+	switch {
+	case m.IsAPReq():
+		return "KRB5Token " + m.APReq.String()
+	case m.IsAPRep():
+		return "KRB5Token " + m.APRep.String()
+	case m.IsKRBError():
+		return "KRB5Token KRB_ERROR: " + m.KRBError.Error()
+	}
+	return "KRB5Token (unknown type)"
+}
+
+// dumpField returns a named value on its own indented line, indenting any nested lines further.
+func dumpField(name string, v interface{}) string {
+	return fmt.Sprintf("\n  %s: %s", name, strings.Replace(fmt.Sprint(v), "\n", "\n  ", -1))
+}
+
+// mechString returns the name of a mechanism OID if it is known to gokrb5, otherwise the OID itself.
+func mechString(oid asn1.ObjectIdentifier) string {
+	for _, n := range []gssapi.OIDName{gssapi.OIDKRB5, gssapi.OIDMSLegacyKRB5, gssapi.OIDSPNEGO, gssapi.OIDGSSIAKerb} {
+		if oid.Equal(n.OID()) {
+			return fmt.Sprintf("%s(%s)", n, oid)
+		}
+	}
+	return oid.String()
+}
+
+// mechTokenString describes a mechanism token, decoding it if it is a KRB5 token.
+func mechTokenString(b []byte) string {
+	var m KRB5Token
+	if err := m.Unmarshal(b); err != nil {
+		return fmt.Sprintf("%d bytes", len(b))
+	}
+	return m.String()
+}
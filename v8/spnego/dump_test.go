@@ -0,0 +1,45 @@
+package spnego
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSPNEGOToken_String(t *testing.T) {
+	t.Parallel()
+	b, err := hex.DecodeString(testGSSAPIInit)
+	if err != nil {
+		t.Fatalf("Error converting hex string test data to bytes: %v", err)
+	}
+	var s SPNEGOToken
+	err = s.Unmarshal(b)
+	if err != nil {
+		t.Fatalf("Error unmarshalling SPNEGO with NegTokenInit: %v", err)
+	}
+	str := s.String()
+	for _, want := range []string{
+		"SPNEGO NegTokenInit",
+		"MechTypes: KRB5(1.2.840.113554.1.2.2), 1.3.5.1.5.2, MSLegacyKRB5(1.2.840.48018.1.2.2), GSSIAKerb(1.3.6.1.5.2.5)",
+		"MechToken: KRB5Token AP_REQ",
+		"SName: HTTP/host.test.gokrb5 (KRB_NT_SRV_HST)",
+		"EncryptedAuthenticator: aes256-cts-hmac-sha1-96(18)",
+	} {
+		assert.Contains(t, str, want, "description of SPNEGO token not as expected")
+	}
+
+	b, err = hex.DecodeString(testGSSAPIResp)
+	if err != nil {
+		t.Fatalf("Error converting hex string test data to bytes: %v", err)
+	}
+	s = SPNEGOToken{}
+	err = s.Unmarshal(b)
+	if err != nil {
+		t.Fatalf("Error unmarshalling SPNEGO with NegTokenResp: %v", err)
+	}
+	str = s.String()
+	assert.Contains(t, str, "SPNEGO NegTokenResp", "description of SPNEGO token not as expected")
+	assert.Contains(t, str, "NegState: accept-completed", "description of SPNEGO token not as expected")
+	assert.Contains(t, str, "SupportedMech: KRB5(1.2.840.113554.1.2.2)", "description of SPNEGO token not as expected")
+}
@@ -2,6 +2,8 @@ package spnego
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -11,14 +13,17 @@ import (
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
+	"os"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/jcmturner/gofork/encoding/asn1"
 	"github.com/jcmturner/goidentity/v6"
+	"github.com/jcmturner/gokrb5/v8/asn1"
 	"github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/config"
 	"github.com/jcmturner/gokrb5/v8/credentials"
 	"github.com/jcmturner/gokrb5/v8/gssapi"
-	"github.com/jcmturner/gokrb5/v8/iana/nametype"
 	"github.com/jcmturner/gokrb5/v8/keytab"
 	"github.com/jcmturner/gokrb5/v8/krberror"
 	"github.com/jcmturner/gokrb5/v8/service"
@@ -32,9 +37,28 @@ type Client struct {
 	*http.Client
 	krb5Client *client.Client
 	spn        string
+	spnFunc    func(*http.Request) (string, error)
+	mutual     bool
+	store      client.TicketStore
+	saved      []byte
+	storeMux   sync.Mutex
 	reqs       []*http.Request
 }
 
+// SPNSelector used to configure the client with a function that returns the SPN to request a service ticket for to
+// authenticate the request, in place of deriving it from the host of the request's URL. This allows the SPN to be
+// chosen where the host name does not identify the service's principal, for example when requests are sent through a
+// proxy, or to apply a policy on the use of CNAMEs.
+// If the function returns an empty string the SPN is derived from the host as usual. An SPN passed to NewClient takes
+// precedence over the function.
+//
+// c := NewClient(cl, nil, "", SPNSelector(f))
+func SPNSelector(f func(r *http.Request) (string, error)) func(*Client) {
+	return func(c *Client) {
+		c.spnFunc = f
+	}
+}
+
 type redirectErr struct {
 	reqTarget *http.Request
 }
@@ -48,12 +72,34 @@ type teeReadCloser struct {
 	io.Closer
 }
 
+// PersistTickets used to configure the client to save the service tickets it obtains to the store and to load those
+// saved when it is created, so that a restarted application does not need to request them from the KDC again.
+// Errors loading and saving the tickets are logged and do not prevent requests being made.
+//
+// c := NewClient(cl, nil, "", PersistTickets(store))
+func PersistTickets(store client.TicketStore) func(*Client) {
+	return func(c *Client) {
+		c.store = store
+	}
+}
+
+// RequireMutualAuth used to configure the client to request mutual authentication and fail requests for which the
+// service's response does not hold an AP_REP authenticating it. Services that complete the negotiation without an
+// AP_REP, as those of earlier releases of this package do, are then rejected.
+//
+// c := NewClient(cl, nil, "", RequireMutualAuth(true))
+func RequireMutualAuth(b bool) func(*Client) {
+	return func(c *Client) {
+		c.mutual = b
+	}
+}
+
 // NewClient returns a SPNEGO enabled HTTP client.
 // Be careful when passing in the *http.Client if it is beginning reused in multiple calls to this function.
 // Ensure reuse of the provided *http.Client is for the same user as a session cookie may have been added to
 // http.Client's cookie jar.
 // Incorrect reuse of the provided *http.Client could lead to access to the wrong user's session.
-func NewClient(krb5Cl *client.Client, httpCl *http.Client, spn string) *Client {
+func NewClient(krb5Cl *client.Client, httpCl *http.Client, spn string, options ...func(*Client)) *Client {
 	if httpCl == nil {
 		httpCl = &http.Client{}
 	}
@@ -72,15 +118,81 @@ func NewClient(krb5Cl *client.Client, httpCl *http.Client, spn string) *Client {
 		}
 		return redirectErr{reqTarget: req}
 	}
-	return &Client{
+	c := &Client{
 		Client:     httpCl,
 		krb5Client: krb5Cl,
 		spn:        spn,
 	}
+	for _, o := range options {
+		o(c)
+	}
+	c.loadTickets()
+	return c
+}
+
+// loadTickets imports the service tickets saved to the client's store.
+func (c *Client) loadTickets() {
+	if c.store == nil {
+		return
+	}
+	b, err := c.store.Load()
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			c.krb5Client.Log("could not load service tickets: %v", err)
+		}
+		return
+	}
+	if err := c.krb5Client.ImportServiceTickets(b); err != nil {
+		c.krb5Client.Log("could not import service tickets: %v", err)
+		return
+	}
+	c.storeMux.Lock()
+	c.saved = b
+	c.storeMux.Unlock()
+}
+
+// saveTickets saves the client's service tickets to its store if they have changed since they were last saved.
+func (c *Client) saveTickets() {
+	if c.store == nil {
+		return
+	}
+	b, err := c.krb5Client.ExportServiceTickets()
+	if err != nil {
+		c.krb5Client.Log("could not export service tickets: %v", err)
+		return
+	}
+	c.storeMux.Lock()
+	defer c.storeMux.Unlock()
+	if bytes.Equal(b, c.saved) {
+		return
+	}
+	if err := c.store.Save(b); err != nil {
+		c.krb5Client.Log("could not save service tickets: %v", err)
+		return
+	}
+	c.saved = b
 }
 
 // Do is the SPNEGO enabled HTTP client's equivalent of the http.Client's Do method.
+// If the service responds to the request with a SPNEGO challenge the request is sent once more with a SPNEGO
+// authorization header. Should the service's response to this include a Kerberos AP_REP it is verified to mutually
+// authenticate the service and an error is returned if this fails. A response without an AP_REP is only an error if
+// the client is configured with RequireMutualAuth.
 func (c *Client) Do(req *http.Request) (resp *http.Response, err error) {
+	return c.do(req, false)
+}
+
+func (c *Client) do(req *http.Request, negotiated bool) (resp *http.Response, err error) {
+	krb5Cl, override := KRB5ClientFromContext(req.Context())
+	httpCl := c.Client
+	if override {
+		// Do not share the cookie jar so a session established for one client's user cannot be used by another.
+		hc := *c.Client
+		hc.Jar = nil
+		httpCl = &hc
+	} else {
+		krb5Cl = c.krb5Client
+	}
 	var body bytes.Buffer
 	if req.Body != nil {
 		// Use a tee reader to capture any body sent in case we have to replay it again
@@ -88,7 +200,7 @@ func (c *Client) Do(req *http.Request) (resp *http.Response, err error) {
 		teeRC := teeReadCloser{teeR, req.Body}
 		req.Body = teeRC
 	}
-	resp, err = c.Client.Do(req)
+	resp, err = httpCl.Do(req)
 	if err != nil {
 		if ue, ok := err.(*url.Error); ok {
 			if e, ok := ue.Err.(redirectErr); ok {
@@ -102,23 +214,38 @@ func (c *Client) Do(req *http.Request) (resp *http.Response, err error) {
 					// Refresh the body reader so the body can be sent again
 					e.reqTarget.Body = ioutil.NopCloser(&body)
 				}
-				return c.Do(e.reqTarget)
+				return c.do(e.reqTarget, false)
 			}
 		}
 		return resp, err
 	}
-	if respUnauthorizedNegotiate(resp) {
-		err := SetSPNEGOHeader(c.krb5Client, req, c.spn)
+	if respUnauthorizedNegotiate(resp) && !negotiated {
+		spn, err := c.requestSPN(req)
+		if err != nil {
+			return resp, err
+		}
+		ap, err := setSPNEGOHeader(krb5Cl, req, spn, c.mutual)
 		if err != nil {
 			return resp, err
 		}
+		if !override {
+			c.saveTickets()
+		}
 		if req.Body != nil {
 			// Refresh the body reader so the body can be sent again
 			req.Body = ioutil.NopCloser(&body)
 		}
 		io.Copy(ioutil.Discard, resp.Body)
 		resp.Body.Close()
-		return c.Do(req)
+		resp, err = c.do(req, true)
+		if err != nil {
+			return resp, err
+		}
+		if err := verifyMutualAuth(resp, ap, c.mutual); err != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+			return nil, err
+		}
 	}
 	return resp, err
 }
@@ -156,6 +283,31 @@ func (c *Client) Head(url string) (resp *http.Response, err error) {
 	return c.Do(req)
 }
 
+// Get issues a GET to the URL specified authenticating with SPNEGO using the Kerberos client provided if the service
+// requests it. The SPN is derived from the URL's host. Mutual authentication is required of the service.
+func Get(cl *client.Client, url string) (resp *http.Response, err error) {
+	return NewClient(cl, nil, "", RequireMutualAuth(true)).Get(url)
+}
+
+// Post issues a POST to the URL specified authenticating with SPNEGO using the Kerberos client provided if the
+// service requests it. The SPN is derived from the URL's host. Mutual authentication is required of the service.
+func Post(cl *client.Client, url, contentType string, body io.Reader) (resp *http.Response, err error) {
+	return NewClient(cl, nil, "", RequireMutualAuth(true)).Post(url, contentType, body)
+}
+
+// requestSPN returns the SPN the client is configured to authenticate the request with.
+// An empty string is returned if the SPN should be derived from the request's URL.
+func (c *Client) requestSPN(r *http.Request) (string, error) {
+	if c.spn != "" || c.spnFunc == nil {
+		return c.spn, nil
+	}
+	spn, err := c.spnFunc(r)
+	if err != nil {
+		return "", fmt.Errorf("could not select SPN for %s: %w", r.URL, err)
+	}
+	return spn, nil
+}
+
 func respUnauthorizedNegotiate(resp *http.Response) bool {
 	if resp.StatusCode == http.StatusUnauthorized {
 		if resp.Header.Get(HTTPHeaderAuthResponse) == HTTPHeaderAuthResponseValueKey {
@@ -165,7 +317,7 @@ func respUnauthorizedNegotiate(resp *http.Response) bool {
 	return false
 }
 
-func setRequestSPN(r *http.Request) (types.PrincipalName, error) {
+func setRequestSPN(r *http.Request, res config.Resolver) (types.PrincipalName, error) {
 	h := strings.TrimSuffix(r.URL.Host, ".")
 	// This if statement checks if the host includes a port number
 	if strings.LastIndex(r.URL.Host, ":") > strings.LastIndex(r.URL.Host, "]") {
@@ -174,58 +326,173 @@ func setRequestSPN(r *http.Request) (types.PrincipalName, error) {
 		if err != nil {
 			return types.PrincipalName{}, err
 		}
-		name, err := net.LookupCNAME(h)
+		name, err := res.LookupCNAME(r.Context(), h)
 		if err == nil {
 			// Underlyng canonical name should be used for SPN
 			h = name
 		}
 		h = strings.TrimSuffix(h, ".")
 		r.Host = fmt.Sprintf("%s:%s", h, p)
-		return types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "HTTP/"+h), nil
+		return types.SPN{Service: "HTTP", Host: h}.PrincipalName(), nil
 	}
-	name, err := net.LookupCNAME(h)
+	name, err := res.LookupCNAME(r.Context(), h)
 	if err == nil {
 		// Underlyng canonical name should be used for SPN
 		h = name
 	}
 	h = strings.TrimSuffix(h, ".")
 	r.Host = h
-	return types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "HTTP/"+h), nil
+	return types.SPN{Service: "HTTP", Host: h}.PrincipalName(), nil
 }
 
 // SetSPNEGOHeader gets the service ticket and sets it as the SPNEGO authorization header on HTTP request object.
 // To auto generate the SPN from the request object pass a null string "".
 func SetSPNEGOHeader(cl *client.Client, r *http.Request, spn string) error {
+	_, err := setSPNEGOHeader(cl, r, spn, false)
+	return err
+}
+
+// apExchange holds the client side state of an AP exchange needed to verify the service's AP_REP.
+type apExchange struct {
+	sessionKey    types.EncryptionKey
+	authenticator types.Authenticator
+}
+
+// setSPNEGOHeader sets the SPNEGO authorization header on the request, requesting mutual authentication if specified.
+func setSPNEGOHeader(cl *client.Client, r *http.Request, spn string, mutual bool) (apExchange, error) {
 	if spn == "" {
-		pn, err := setRequestSPN(r)
+		pn, err := setRequestSPN(r, cl.Config.Resolver())
 		if err != nil {
-			return err
+			return apExchange{}, err
 		}
 		spn = pn.PrincipalNameString()
 	}
 	cl.Log("using SPN %s", spn)
-	s := SPNEGOClient(cl, spn)
-	err := s.AcquireCred()
+	err := cl.AffirmLogin()
+	if err != nil {
+		return apExchange{}, fmt.Errorf("could not acquire client credential: %v", err)
+	}
+	tkt, key, err := cl.GetServiceTicket(spn)
+	if err != nil {
+		return apExchange{}, fmt.Errorf("could not initialize context: %v", err)
+	}
+	flags := []int{gssapi.ContextFlagInteg, gssapi.ContextFlagConf}
+	if mutual {
+		flags = append(flags, gssapi.ContextFlagMutual)
+	}
+	mt, err := NewKRB5TokenAPREQ(cl, tkt, key, flags, []int{})
 	if err != nil {
-		return fmt.Errorf("could not acquire client credential: %v", err)
+		return apExchange{}, fmt.Errorf("could not initialize context: %v", err)
 	}
-	st, err := s.InitSecContext()
+	mtb, err := mt.Marshal()
 	if err != nil {
-		return fmt.Errorf("could not initialize context: %v", err)
+		return apExchange{}, krberror.Errorf(err, krberror.EncodingError, "could not marshal KRB5 token")
+	}
+	st := SPNEGOToken{
+		Init: true,
+		NegTokenInit: NegTokenInit{
+			MechTypes:      []asn1.ObjectIdentifier{gssapi.OIDKRB5.OID()},
+			MechTokenBytes: mtb,
+		},
 	}
 	nb, err := st.Marshal()
 	if err != nil {
-		return krberror.Errorf(err, krberror.EncodingError, "could not marshal SPNEGO")
+		return apExchange{}, krberror.Errorf(err, krberror.EncodingError, "could not marshal SPNEGO")
 	}
-	hs := "Negotiate " + base64.StdEncoding.EncodeToString(nb)
-	r.Header.Set(HTTPHeaderAuthRequest, hs)
-	return nil
+	r.Header.Set(HTTPHeaderAuthRequest, EncodeNegotiateHeader(nb))
+	return apExchange{
+		sessionKey:    key,
+		authenticator: mt.APReq.Authenticator,
+	}, nil
+}
+
+// verifyMutualAuth verifies any AP_REP within the service's SPNEGO response against the AP exchange the request was
+// authenticated with. If mutual authentication is required a response without an AP_REP is an error, unless the
+// service rejected the request and so has not established a context to authenticate.
+func verifyMutualAuth(resp *http.Response, ap apExchange, required bool) error {
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil
+	}
+	rt, err := responseToken(resp)
+	if err != nil && required {
+		return err
+	}
+	if len(rt) == 0 {
+		if required {
+			return krberror.NewErrorf(krberror.KRBMsgError, "service did not return an AP_REP to mutually authenticate")
+		}
+		return nil
+	}
+	return verifyResponseToken(rt, ap)
+}
+
+// responseToken returns the response token of the service's SPNEGO response, which is empty if there is none.
+func responseToken(resp *http.Response) ([]byte, error) {
+	h := strings.SplitN(resp.Header.Get(HTTPHeaderAuthResponse), " ", 2)
+	if len(h) != 2 || !strings.EqualFold(h[0], HTTPHeaderAuthResponseValueKey) {
+		return nil, nil
+	}
+	b, err := DecodeToken(h[1], 0)
+	if err != nil {
+		return nil, krberror.Errorf(err, krberror.EncodingError, "could not decode the service's SPNEGO response")
+	}
+	var nt NegTokenResp
+	err = nt.Unmarshal(b)
+	if err != nil {
+		return nil, krberror.Errorf(err, krberror.EncodingError, "could not unmarshal the service's SPNEGO response")
+	}
+	return nt.ResponseToken, nil
+}
+
+// verifyResponseToken verifies the AP_REP within the response token of the service's NegTokenResp against the AP
+// exchange.
+func verifyResponseToken(b []byte, ap apExchange) error {
+	var mt KRB5Token
+	err := mt.Unmarshal(b)
+	if err != nil {
+		return krberror.Errorf(err, krberror.EncodingError, "could not unmarshal the service's KRB5 token")
+	}
+	if mt.IsKRBError() {
+		return krberror.Errorf(mt.KRBError, krberror.KRBMsgError, "service returned an error")
+	}
+	if !mt.IsAPRep() {
+		return krberror.NewErrorf(krberror.KRBMsgError, "service's KRB5 token is not an AP_REP")
+	}
+	err = mt.APRep.DecryptEncPart(ap.sessionKey)
+	if err != nil {
+		return krberror.Errorf(err, krberror.DecryptingError, "could not decrypt the service's AP_REP")
+	}
+	return mt.APRep.VerifyAuthenticator(ap.authenticator)
+}
+
+// ctxKRB5Client is the context key holding a Kerberos client that overrides that of the SPNEGO HTTP client.
+const ctxKRB5Client = "github.com/jcmturner/gokrb5/v8/ctxKRB5Client"
+
+// WithKRB5Client returns a copy of the context holding the Kerberos client provided. Requests made by the SPNEGO HTTP
+// client with this context authenticate using this Kerberos client rather than the one the SPNEGO client was created
+// with, allowing a shared SPNEGO client to make requests on behalf of different users.
+//
+// Requests made with the Kerberos client from the context do not use the SPNEGO client's cookie jar so that a session
+// established by the service for one user is not used for another.
+//
+// req = req.WithContext(spnego.WithKRB5Client(req.Context(), cl))
+func WithKRB5Client(ctx context.Context, cl *client.Client) context.Context {
+	return context.WithValue(ctx, ctxKRB5Client, cl)
+}
+
+// KRB5ClientFromContext returns the Kerberos client held in the context by WithKRB5Client.
+func KRB5ClientFromContext(ctx context.Context) (*client.Client, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	cl, ok := ctx.Value(ctxKRB5Client).(*client.Client)
+	return cl, ok && cl != nil
 }
 
 // Service side functionality //
 
 const (
-	// spnegoNegTokenRespKRBAcceptCompleted - The response on successful authentication always has this header. Capturing as const so we don't have marshaling and encoding overhead.
+	// spnegoNegTokenRespKRBAcceptCompleted - The response on successful authentication without mutual authentication has this header. Capturing as const so we don't have marshaling and encoding overhead.
 	spnegoNegTokenRespKRBAcceptCompleted = "Negotiate oRQwEqADCgEAoQsGCSqGSIb3EgECAg=="
 	// spnegoNegTokenRespReject - The response on a failed authentication always has this rejection header. Capturing as const so we don't have marshaling and encoding overhead.
 	spnegoNegTokenRespReject = "Negotiate oQcwBaADCgEC"
@@ -235,6 +502,10 @@ const (
 	sessionCredentials = "github.com/jcmturner/gokrb5/v8/sessionCredentials"
 	// ctxCredentials is the SPNEGO context key holding the credentials jcmturner/goidentity/Identity object.
 	ctxCredentials = "github.com/jcmturner/gokrb5/v8/ctxCredentials"
+	// ctxSecContextKey is the SPNEGO context key holding the SecContextKey established by the client's authenticator.
+	ctxSecContextKey = "github.com/jcmturner/gokrb5/v8/ctxSecContextKey"
+	// ctxVerifiedAPReq is the SPNEGO context key holding the service.VerifiedAPReq of the client's AP_REQ.
+	ctxVerifiedAPReq = "github.com/jcmturner/gokrb5/v8/ctxVerifiedAPReq"
 	// HTTPHeaderAuthRequest is the header that will hold authn/z information.
 	HTTPHeaderAuthRequest = "Authorization"
 	// HTTPHeaderAuthResponse is the header that will hold SPNEGO data from the server.
@@ -243,6 +514,15 @@ const (
 	HTTPHeaderAuthResponseValueKey = "Negotiate"
 	// UnauthorizedMsg is the message returned in the body when authentication fails.
 	UnauthorizedMsg = "Unauthorised.\n"
+	// ForbiddenMsg is the message returned in the body when an authenticated client is denied access by the
+	// service's AccessRules.
+	ForbiddenMsg = "Forbidden.\n"
+	// HTTPHeaderNegotiationID is the header that holds the correlation ID of a SPNEGO negotiation that needs more than
+	// one round trip, when the service is configured with a service.NegotiationCache. It is set on the response to
+	// each leg of the negotiation and clients that do not return cookies must send it with the next leg.
+	HTTPHeaderNegotiationID = "X-Negotiation-Id"
+	// negotiationCookie is the cookie that holds the correlation ID of a SPNEGO negotiation.
+	negotiationCookie = "gokrb5-negotiation"
 )
 
 // SPNEGOKRB5Authenticate is a Kerberos SPNEGO authentication HTTP handler wrapper.
@@ -264,6 +544,10 @@ func SPNEGOKRB5Authenticate(inner http.Handler, kt *keytab.Keytab, settings ...f
 		id, err := getSessionCredentials(spnego, r)
 		if err == nil && id.Authenticated() {
 			// There is an established session so bypass auth and serve
+			if err := spnego.serviceSettings.CheckAccess(r.URL.Path, &id); err != nil {
+				spnegoForbidden(spnego, w, "%s - SPNEGO access to %s denied: %v", r.RemoteAddr, r.URL.Path, err)
+				return
+			}
 			spnego.Log("%s - SPNEGO request served under session %s", r.RemoteAddr, id.SessionID())
 			inner.ServeHTTP(w, goidentity.AddToHTTPRequestContext(&id, r))
 			return
@@ -275,6 +559,12 @@ func SPNEGOKRB5Authenticate(inner http.Handler, kt *keytab.Keytab, settings ...f
 			return
 		}
 
+		// A response token continues a negotiation started by an earlier request
+		ns, ok := takeNegotiation(spnego, r, w, st)
+		if !ok {
+			return
+		}
+
 		// Validate the context token
 		authed, ctx, status := spnego.AcceptSecContext(st)
 		if status.Code != gssapi.StatusComplete && status.Code != gssapi.StatusContinueNeeded {
@@ -282,6 +572,9 @@ func SPNEGOKRB5Authenticate(inner http.Handler, kt *keytab.Keytab, settings ...f
 			return
 		}
 		if status.Code == gssapi.StatusContinueNeeded {
+			if err := putNegotiation(spnego, w, st, ns); err != nil {
+				return
+			}
 			spnegoNegotiateKRB5MechType(spnego, w, "%s - SPNEGO GSS-API continue needed", r.RemoteAddr)
 			return
 		}
@@ -289,12 +582,29 @@ func SPNEGOKRB5Authenticate(inner http.Handler, kt *keytab.Keytab, settings ...f
 		if authed {
 			// Authentication successful; get user's credentials from the context
 			id := ctx.Value(ctxCredentials).(*credentials.Credentials)
+			if err := spnego.serviceSettings.CheckAccess(r.URL.Path, id); err != nil {
+				spnegoForbidden(spnego, w, "%s %s@%s - SPNEGO access to %s denied: %v", r.RemoteAddr, id.UserName(), id.Domain(), r.URL.Path, err)
+				return
+			}
 			// Create a new session if a session manager has been configured
 			err = newSession(spnego, r, w, id)
 			if err != nil {
 				return
 			}
-			spnegoResponseAcceptCompleted(spnego, w, "%s %s@%s - SPNEGO authentication succeeded", r.RemoteAddr, id.UserName(), id.Domain())
+			if st.raw {
+				// A client that sent a raw KRB5 context token cannot process an SPNEGO response token
+				spnego.Log("%s %s@%s - SPNEGO authentication with a raw KRB5 token succeeded", r.RemoteAddr, id.UserName(), id.Domain())
+			} else {
+				rt, err := st.mutualResponseToken()
+				if err != nil {
+					spnegoInternalServerError(spnego, w, "SPNEGO could not create the response token for mutual authentication: %v", err)
+					return
+				}
+				spnegoResponseAcceptCompleted(spnego, w, rt, "%s %s@%s - SPNEGO authentication succeeded", r.RemoteAddr, id.UserName(), id.Domain())
+			}
+			if v, ok := VerifiedAPReqFromContext(ctx); ok {
+				r = r.WithContext(context.WithValue(r.Context(), ctxVerifiedAPReq, v))
+			}
 			// Add the identity to the context and serve the inner/wrapped handler
 			inner.ServeHTTP(w, goidentity.AddToHTTPRequestContext(id, r))
 			return
@@ -315,30 +625,22 @@ func getAuthorizationNegotiationHeaderAsSPNEGOToken(spnego *SPNEGO, r *http.Requ
 	}
 
 	// Decode the header into an SPNEGO context token
-	b, err := base64.StdEncoding.DecodeString(s[1])
+	b, err := DecodeToken(s[1], spnego.serviceSettings.MaxTokenSize())
+	if errors.Is(err, ErrTokenTooLarge) {
+		spnegoResponseReject(spnego, w, "%s - SPNEGO %v", r.RemoteAddr, err)
+		return nil, err
+	}
 	if err != nil {
 		err = fmt.Errorf("error in base64 decoding negotiation header: %v", err)
 		spnegoNegotiateKRB5MechType(spnego, w, "%s - SPNEGO %v", r.RemoteAddr, err)
 		return nil, err
 	}
-	var st SPNEGOToken
-	err = st.Unmarshal(b)
+	st, err := spnego.unmarshalContextToken(b)
 	if err != nil {
-		// Check if this is a raw KRB5 context token - issue #347.
-		var k5t KRB5Token
-		if k5t.Unmarshal(b) != nil {
-			err = fmt.Errorf("error in unmarshaling SPNEGO token: %v", err)
-			spnegoNegotiateKRB5MechType(spnego, w, "%s - SPNEGO %v", r.RemoteAddr, err)
-			return nil, err
-		}
-		// Wrap it into an SPNEGO context token
-		st.Init = true
-		st.NegTokenInit = NegTokenInit{
-			MechTypes:      []asn1.ObjectIdentifier{k5t.OID},
-			MechTokenBytes: b,
-		}
+		spnegoNegotiateKRB5MechType(spnego, w, "%s - SPNEGO %v", r.RemoteAddr, err)
+		return nil, err
 	}
-	return &st, nil
+	return st, nil
 }
 
 func getSessionCredentials(spnego *SPNEGO, r *http.Request) (credentials.Credentials, error) {
@@ -376,6 +678,77 @@ func newSession(spnego *SPNEGO, r *http.Request, w http.ResponseWriter, id *cred
 	return nil
 }
 
+// takeNegotiation takes the state of the negotiation continued by a response token from the NegotiationStore, if one
+// is configured. The client is rejected if it is not continuing a negotiation that has not expired.
+func takeNegotiation(spnego *SPNEGO, r *http.Request, w http.ResponseWriter, st *SPNEGOToken) (*service.NegotiationState, bool) {
+	store := spnego.serviceSettings.NegotiationCache()
+	if store == nil || !st.Resp {
+		return nil, true
+	}
+	id := r.Header.Get(HTTPHeaderNegotiationID)
+	if c, err := r.Cookie(negotiationCookie); id == "" && err == nil {
+		id = c.Value
+	}
+	if id == "" {
+		spnegoResponseReject(spnego, w, "%s - SPNEGO response token does not continue a negotiation", r.RemoteAddr)
+		return nil, false
+	}
+	b, err := store.Take(id)
+	if err != nil {
+		spnegoInternalServerError(spnego, w, "SPNEGO could not get negotiation state: %v", err)
+		return nil, false
+	}
+	if b == nil {
+		spnegoResponseReject(spnego, w, "%s - SPNEGO negotiation %s unknown or timed out", r.RemoteAddr, id)
+		return nil, false
+	}
+	ns, err := service.ParseNegotiationState(b)
+	if err != nil {
+		spnegoInternalServerError(spnego, w, "SPNEGO could not get negotiation state: %v", err)
+		return nil, false
+	}
+	if spnego.serviceSettings.Clock().Now().After(ns.Expires) {
+		spnegoResponseReject(spnego, w, "%s - SPNEGO negotiation %s timed out", r.RemoteAddr, id)
+		return nil, false
+	}
+	// The negotiation is complete unless the client is asked for another token
+	http.SetCookie(w, &http.Cookie{Name: negotiationCookie, Path: "/", MaxAge: -1, HttpOnly: true})
+	return &ns, true
+}
+
+// putNegotiation stores the state of a negotiation that needs another token from the client in the NegotiationStore,
+// if one is configured, under a new correlation ID returned to the client. A continued negotiation keeps its expiry.
+func putNegotiation(spnego *SPNEGO, w http.ResponseWriter, st *SPNEGOToken, ns *service.NegotiationState) error {
+	store := spnego.serviceSettings.NegotiationCache()
+	if store == nil {
+		return nil
+	}
+	timeout := spnego.serviceSettings.NegotiationTimeout()
+	if ns == nil {
+		ns = &service.NegotiationState{Expires: spnego.serviceSettings.Clock().Now().Add(timeout).UTC()}
+		for _, oid := range st.NegTokenInit.MechTypes {
+			ns.MechTypes = append(ns.MechTypes, oid.String())
+		}
+	}
+	idb := make([]byte, 16)
+	if _, err := rand.Read(idb); err != nil {
+		spnegoInternalServerError(spnego, w, "SPNEGO could not create negotiation ID: %v", err)
+		return err
+	}
+	id := base64.RawURLEncoding.EncodeToString(idb)
+	b, err := ns.Marshal()
+	if err == nil {
+		err = store.Put(id, b, ns.Expires)
+	}
+	if err != nil {
+		spnegoInternalServerError(spnego, w, "SPNEGO could not store negotiation state: %v", err)
+		return err
+	}
+	w.Header().Set(HTTPHeaderNegotiationID, id)
+	http.SetCookie(w, &http.Cookie{Name: negotiationCookie, Value: id, Path: "/", MaxAge: int(timeout / time.Second), HttpOnly: true})
+	return nil
+}
+
 // Log and respond to client for error conditions
 
 func spnegoNegotiateKRB5MechType(s *SPNEGO, w http.ResponseWriter, format string, v ...interface{}) {
@@ -390,9 +763,26 @@ func spnegoResponseReject(s *SPNEGO, w http.ResponseWriter, format string, v ...
 	http.Error(w, UnauthorizedMsg, http.StatusUnauthorized)
 }
 
-func spnegoResponseAcceptCompleted(s *SPNEGO, w http.ResponseWriter, format string, v ...interface{}) {
+// spnegoResponseAcceptCompleted sets the header completing the negotiation, holding the AP_REP to mutually authenticate
+// the service if there is a response token.
+func spnegoResponseAcceptCompleted(s *SPNEGO, w http.ResponseWriter, responseToken []byte, format string, v ...interface{}) {
+	s.Log(format, v...)
+	if len(responseToken) == 0 {
+		w.Header().Set(HTTPHeaderAuthResponse, spnegoNegTokenRespKRBAcceptCompleted)
+		return
+	}
+	b, err := respToken(NegStateAcceptCompleted, responseToken)
+	if err != nil {
+		s.Log("SPNEGO could not marshal the response token for mutual authentication: %v", err)
+		w.Header().Set(HTTPHeaderAuthResponse, spnegoNegTokenRespKRBAcceptCompleted)
+		return
+	}
+	w.Header().Set(HTTPHeaderAuthResponse, EncodeNegotiateHeader(b))
+}
+
+func spnegoForbidden(s *SPNEGO, w http.ResponseWriter, format string, v ...interface{}) {
 	s.Log(format, v...)
-	w.Header().Set(HTTPHeaderAuthResponse, spnegoNegTokenRespKRBAcceptCompleted)
+	http.Error(w, ForbiddenMsg, http.StatusForbidden)
 }
 
 func spnegoInternalServerError(s *SPNEGO, w http.ResponseWriter, format string, v ...interface{}) {
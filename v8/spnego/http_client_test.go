@@ -0,0 +1,410 @@
+package spnego_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jcmturner/goidentity/v6"
+	"github.com/jcmturner/gokrb5/v8/asn1"
+	"github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/gssapi"
+	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/krbtest"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/service"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// localService returns a keytab for the SPN that will be derived for an httptest server and a mock KDC that can
+// issue tickets for it.
+func localService(t *testing.T) (*keytab.Keytab, *krbtest.KDC) {
+	kt := keytab.New()
+	for _, e := range []int32{etypeID.AES256_CTS_HMAC_SHA1_96, etypeID.AES128_CTS_HMAC_SHA1_96} {
+		err := kt.AddEntry("HTTP/127.0.0.1", krbtest.Realm, "servicepassword", time.Now(), 1, e)
+		require.NoError(t, err, "error creating service keytab")
+	}
+	kdcKt := krbtest.KDCKeytab()
+	kdcKt.Entries = append(kdcKt.Entries, kt.Entries...)
+	kdc, err := krbtest.NewKDC(krbtest.Realm, kdcKt)
+	require.NoError(t, err, "error starting mock KDC")
+	return kt, kdc
+}
+
+func TestGet(t *testing.T) {
+	t.Parallel()
+	kt, kdc := localService(t)
+	defer kdc.Close()
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, goidentity.FromHTTPRequestContext(r).UserName())
+	})
+	s := httptest.NewServer(spnego.SPNEGOKRB5Authenticate(inner, kt))
+	defer s.Close()
+
+	cl := krbtest.NewClient(kdc.Config())
+	resp, err := spnego.Get(cl, s.URL)
+	require.NoError(t, err, "error on GET")
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "status code not as expected")
+	b, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, krbtest.ClientPrincipal, string(b), "authenticated user not as expected")
+
+	resp, err = spnego.Post(cl, s.URL, "text/plain", strings.NewReader("data"))
+	require.NoError(t, err, "error on POST")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "status code not as expected")
+}
+
+func TestGet_RetryOnce(t *testing.T) {
+	t.Parallel()
+	_, kdc := localService(t)
+	defer kdc.Close()
+	var reqs int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&reqs, 1)
+		w.Header().Set(spnego.HTTPHeaderAuthResponse, spnego.HTTPHeaderAuthResponseValueKey)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer s.Close()
+
+	resp, err := spnego.Get(krbtest.NewClient(kdc.Config()), s.URL)
+	require.NoError(t, err, "error on GET")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "status code not as expected")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&reqs), "the request should only be retried once with authentication")
+}
+
+func TestSPNEGOKRB5Authenticate_Replay(t *testing.T) {
+	t.Parallel()
+	kt, kdc := localService(t)
+	defer kdc.Close()
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "OK")
+	})
+	s := httptest.NewServer(spnego.SPNEGOKRB5Authenticate(inner, kt))
+	defer s.Close()
+
+	r, err := http.NewRequest("GET", s.URL, nil)
+	require.NoError(t, err, "error creating request")
+	require.NoError(t, spnego.SetSPNEGOHeader(krbtest.NewClient(kdc.Config()), r, "HTTP/127.0.0.1"), "error setting SPNEGO header")
+	resp, err := http.DefaultClient.Do(r)
+	require.NoError(t, err, "error on GET")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "first use of the authenticator should be accepted")
+	resp, err = http.DefaultClient.Do(r)
+	require.NoError(t, err, "error on GET")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode, "replay of the authenticator in another request should be rejected")
+}
+
+func TestGet_MutualAuth(t *testing.T) {
+	t.Parallel()
+	kt, kdc := localService(t)
+	defer kdc.Close()
+	var cusecOffset int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := strings.TrimPrefix(r.Header.Get(spnego.HTTPHeaderAuthRequest), "Negotiate ")
+		if h == "" {
+			w.Header().Set(spnego.HTTPHeaderAuthResponse, spnego.HTTPHeaderAuthResponseValueKey)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		hb, _ := base64.StdEncoding.DecodeString(h)
+		var st spnego.SPNEGOToken
+		if err := st.Unmarshal(hb); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var mt spnego.KRB5Token
+		if err := mt.Unmarshal(st.NegTokenInit.MechTokenBytes); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := mt.APReq.Ticket.DecryptEncPart(kt, nil); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		key := mt.APReq.Ticket.DecryptedEncPart.Key
+		if err := mt.APReq.DecryptAuthenticator(key); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		rep := messages.NewAPRep(messages.EncAPRepPart{
+			CTime: mt.APReq.Authenticator.CTime,
+			Cusec: mt.APReq.Authenticator.Cusec + int(atomic.LoadInt32(&cusecOffset)),
+		})
+		if err := rep.EncryptEncPart(key); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		rt := spnego.NewKRB5TokenAPREP(rep)
+		rtb, err := rt.Marshal()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		nt := spnego.NegTokenResp{
+			NegState:      asn1.Enumerated(spnego.NegStateAcceptCompleted),
+			SupportedMech: gssapi.OIDKRB5.OID(),
+			ResponseToken: rtb,
+		}
+		nb, err := nt.Marshal()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set(spnego.HTTPHeaderAuthResponse, "Negotiate "+base64.StdEncoding.EncodeToString(nb))
+		fmt.Fprint(w, "OK")
+	}))
+	defer s.Close()
+
+	cl := krbtest.NewClient(kdc.Config())
+	resp, err := spnego.Get(cl, s.URL)
+	require.NoError(t, err, "error on GET with a valid AP_REP")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "status code not as expected")
+
+	atomic.StoreInt32(&cusecOffset, 1)
+	_, err = spnego.Get(cl, s.URL)
+	assert.Error(t, err, "an AP_REP not matching the authenticator should fail mutual authentication")
+}
+
+func TestGet_MutualAuth_NoAPRep(t *testing.T) {
+	t.Parallel()
+	_, kdc := localService(t)
+	defer kdc.Close()
+	var tests = []struct {
+		name   string
+		header string
+	}{
+		{"no header", ""},
+		{"no response token", "Negotiate oRQwEqADCgEAoQsGCSqGSIb3EgECAg=="},
+		{"not a token", "Negotiate b2s="},
+	}
+	for _, test := range tests {
+		header := test.header
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get(spnego.HTTPHeaderAuthRequest) == "" {
+				w.Header().Set(spnego.HTTPHeaderAuthResponse, spnego.HTTPHeaderAuthResponseValueKey)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if header != "" {
+				w.Header().Set(spnego.HTTPHeaderAuthResponse, header)
+			}
+			fmt.Fprint(w, "OK")
+		}))
+		_, err := spnego.Get(krbtest.NewClient(kdc.Config()), s.URL)
+		assert.Error(t, err, "mutual authentication should fail when the service returns %s", test.name)
+		// Clients not configured to require mutual authentication accept services that do not return an AP_REP
+		resp, err := spnego.NewClient(krbtest.NewClient(kdc.Config()), nil, "").Get(s.URL)
+		if assert.NoError(t, err, "request should succeed without mutual authentication when the service returns %s", test.name) {
+			assert.Equal(t, http.StatusOK, resp.StatusCode, "status code not as expected")
+			resp.Body.Close()
+		}
+		s.Close()
+	}
+}
+
+func TestVerifiedAPReqFromContext(t *testing.T) {
+	t.Parallel()
+	kt, kdc := localService(t)
+	defer kdc.Close()
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v, ok := spnego.VerifiedAPReqFromContext(r.Context())
+		if !ok {
+			http.Error(w, "no verified AP_REQ in context", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprintf(w, "%s %s", v.Ticket().CName.PrincipalNameString(), v.Authenticator().CName.PrincipalNameString())
+	})
+	s := httptest.NewServer(spnego.SPNEGOKRB5Authenticate(inner, kt))
+	defer s.Close()
+
+	resp, err := spnego.Get(krbtest.NewClient(kdc.Config()), s.URL)
+	require.NoError(t, err, "error on GET")
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "status code not as expected")
+	b, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, krbtest.ClientPrincipal+" "+krbtest.ClientPrincipal, string(b), "client names of the verified AP_REQ not as expected")
+
+	_, ok := spnego.VerifiedAPReqFromContext(nil)
+	assert.False(t, ok, "a nil context should not contain a verified AP_REQ")
+}
+
+func TestClient_KRB5ClientFromContext(t *testing.T) {
+	t.Parallel()
+	kt := keytab.New()
+	for _, e := range []int32{etypeID.AES256_CTS_HMAC_SHA1_96, etypeID.AES128_CTS_HMAC_SHA1_96} {
+		err := kt.AddEntry("HTTP/127.0.0.1", krbtest.Realm, "servicepassword", time.Now(), 1, e)
+		require.NoError(t, err, "error creating service keytab")
+	}
+	kdcKt := krbtest.KDCKeytab()
+	kdcKt.Entries = append(kdcKt.Entries, kt.Entries...)
+	err := kdcKt.AddEntry("otheruser", krbtest.Realm, "otherpassword", time.Now(), 1, etypeID.AES256_CTS_HMAC_SHA1_96)
+	require.NoError(t, err, "error adding other user to KDC keytab")
+	kdc, err := krbtest.NewKDC(krbtest.Realm, kdcKt)
+	require.NoError(t, err, "error starting mock KDC")
+	defer kdc.Close()
+
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, goidentity.FromHTTPRequestContext(r).UserName())
+	})
+	s := httptest.NewServer(spnego.SPNEGOKRB5Authenticate(inner, kt))
+	defer s.Close()
+
+	c := spnego.NewClient(krbtest.NewClient(kdc.Config()), nil, "")
+	get := func(r *http.Request) string {
+		resp, err := c.Do(r)
+		require.NoError(t, err, "error on GET")
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode, "status code not as expected")
+		b, _ := ioutil.ReadAll(resp.Body)
+		return string(b)
+	}
+
+	r, _ := http.NewRequest("GET", s.URL, nil)
+	assert.Equal(t, krbtest.ClientPrincipal, get(r), "SPNEGO client's user not authenticated")
+
+	other := client.NewWithPassword("otheruser", krbtest.Realm, "otherpassword", kdc.Config())
+	r, _ = http.NewRequest("GET", s.URL, nil)
+	r = r.WithContext(spnego.WithKRB5Client(r.Context(), other))
+	cl, ok := spnego.KRB5ClientFromContext(r.Context())
+	assert.True(t, ok && cl == other, "Kerberos client not held in the context")
+	assert.Equal(t, "otheruser", get(r), "user of the Kerberos client from the context not authenticated")
+}
+
+func TestCookieSession(t *testing.T) {
+	t.Parallel()
+	kt, kdc := localService(t)
+	defer kdc.Close()
+	var negotiated int32
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, goidentity.FromHTTPRequestContext(r).UserName())
+	})
+	sm := service.NewCookieSessionMgr("krb5session", service.NewHMACSigner([]byte("0123456789abcdef0123456789abcdef")), time.Hour)
+	h := spnego.SPNEGOKRB5Authenticate(inner, kt, service.SessionManager(sm))
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(spnego.HTTPHeaderAuthRequest) != "" {
+			atomic.AddInt32(&negotiated, 1)
+		}
+		h.ServeHTTP(w, r)
+	}))
+	defer s.Close()
+
+	c := spnego.NewClient(krbtest.NewClient(kdc.Config()), nil, "")
+	for i := 0; i < 3; i++ {
+		resp, err := c.Get(s.URL)
+		require.NoError(t, err, "error on GET")
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "status code not as expected")
+		assert.Equal(t, krbtest.ClientPrincipal, string(b), "authenticated user not as expected")
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&negotiated), "requests after the first should be served under the session")
+}
+
+func TestClient_SPNSelector(t *testing.T) {
+	t.Parallel()
+	kdc, err := krbtest.NewKDC(krbtest.Realm, krbtest.KDCKeytab())
+	require.NoError(t, err, "error starting mock KDC")
+	defer kdc.Close()
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, goidentity.FromHTTPRequestContext(r).UserName())
+	})
+	// The service's principal is not that derived from the host of the URL
+	s := httptest.NewServer(spnego.SPNEGOKRB5Authenticate(inner, krbtest.ServiceKeytab()))
+	defer s.Close()
+
+	var selected int32
+	c := spnego.NewClient(krbtest.NewClient(kdc.Config()), nil, "", spnego.SPNSelector(func(r *http.Request) (string, error) {
+		atomic.AddInt32(&selected, 1)
+		assert.Equal(t, s.URL, r.URL.String(), "request passed to the selector not as expected")
+		return krbtest.ServicePrincipal, nil
+	}))
+	resp, err := c.Get(s.URL)
+	require.NoError(t, err, "error on GET")
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "status code not as expected")
+	b, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, krbtest.ClientPrincipal, string(b), "authenticated user not as expected")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&selected), "selector should be called once")
+
+	// The SPN derived from the host is used when the selector returns an empty string
+	c = spnego.NewClient(krbtest.NewClient(kdc.Config()), nil, "", spnego.SPNSelector(func(r *http.Request) (string, error) {
+		return "", nil
+	}))
+	_, err = c.Get(s.URL)
+	assert.Error(t, err, "the KDC does not have a principal for the host of the URL")
+
+	selErr := fmt.Errorf("no SPN for host")
+	c = spnego.NewClient(krbtest.NewClient(kdc.Config()), nil, "", spnego.SPNSelector(func(r *http.Request) (string, error) {
+		return "", selErr
+	}))
+	_, err = c.Get(s.URL)
+	assert.True(t, errors.Is(err, selErr), "error of the selector not returned: %v", err)
+}
+
+type memTicketStore struct {
+	b     []byte
+	saves int32
+}
+
+func (s *memTicketStore) Load() ([]byte, error) {
+	if s.b == nil {
+		return nil, os.ErrNotExist
+	}
+	return s.b, nil
+}
+
+func (s *memTicketStore) Save(b []byte) error {
+	atomic.AddInt32(&s.saves, 1)
+	s.b = b
+	return nil
+}
+
+func TestClient_PersistTickets(t *testing.T) {
+	t.Parallel()
+	kt, kdc := localService(t)
+	defer kdc.Close()
+	s := httptest.NewServer(spnego.SPNEGOKRB5Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), kt))
+	defer s.Close()
+
+	var tgsReqs int32
+	countTGS := client.KDCRequestHooks(func(realm string, b []byte) ([]byte, error) {
+		var req messages.TGSReq
+		if req.Unmarshal(b) == nil {
+			atomic.AddInt32(&tgsReqs, 1)
+		}
+		return b, nil
+	})
+	store := new(memTicketStore)
+	c := spnego.NewClient(krbtest.NewClient(kdc.Config(), countTGS), &http.Client{}, "", spnego.PersistTickets(store))
+	for i := 0; i < 2; i++ {
+		resp, err := c.Get(s.URL)
+		require.NoError(t, err, "error on GET")
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode, "status code not as expected")
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&tgsReqs), "service ticket should be requested once")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&store.saves), "tickets should only be saved when they change")
+
+	// A client created as after a restart uses the saved ticket rather than requesting another
+	c = spnego.NewClient(krbtest.NewClient(kdc.Config(), countTGS), &http.Client{}, "", spnego.PersistTickets(store))
+	resp, err := c.Get(s.URL)
+	require.NoError(t, err, "error on GET")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "status code not as expected")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&tgsReqs), "saved service ticket should be used")
+}
@@ -439,3 +439,40 @@ func (smgr SessionMgr) New(w http.ResponseWriter, r *http.Request, k string, v [
 	s.Values[k] = v
 	return s.Save(r, w)
 }
+
+func TestGetAuthorizationNegotiationHeaderAsSPNEGOToken_StrictDecoding(t *testing.T) {
+	t.Parallel()
+	b, _ := hex.DecodeString(testdata.HTTP_KEYTAB)
+	kt := keytab.New()
+	kt.Unmarshal(b)
+	lenient := SPNEGOService(kt)
+	strict := SPNEGOService(kt, service.StrictDecoding(true))
+
+	spnegoB, _ := hex.DecodeString(testGSSAPIInit)
+	rawB, _ := hex.DecodeString(KRB5TokenHex)
+	var tests = []struct {
+		name         string
+		b            []byte
+		lenientValid bool
+		strictValid  bool
+	}{
+		{"SPNEGO token", spnegoB, true, true},
+		{"SPNEGO token with trailing bytes", append(append([]byte{}, spnegoB...), 0x00, 0x00), true, false},
+		{"raw KRB5 token", rawB, true, false},
+	}
+	for _, test := range tests {
+		for _, s := range []struct {
+			spnego *SPNEGO
+			valid  bool
+		}{{lenient, test.lenientValid}, {strict, test.strictValid}} {
+			r, _ := http.NewRequest("GET", "http://host.test.gokrb5", nil)
+			r.Header.Set(HTTPHeaderAuthRequest, HTTPHeaderAuthResponseValueKey+" "+base64.StdEncoding.EncodeToString(test.b))
+			_, err := getAuthorizationNegotiationHeaderAsSPNEGOToken(s.spnego, r, httptest.NewRecorder())
+			if s.valid {
+				assert.NoError(t, err, "%s should be accepted (strict: %v)", test.name, s.spnego.serviceSettings.StrictDecoding())
+			} else {
+				assert.Error(t, err, "%s should be rejected (strict: %v)", test.name, s.spnego.serviceSettings.StrictDecoding())
+			}
+		}
+	}
+}
@@ -6,11 +6,12 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"time"
 
-	"github.com/jcmturner/gofork/encoding/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1"
 	"github.com/jcmturner/gokrb5/v8/asn1tools"
 	"github.com/jcmturner/gokrb5/v8/client"
-	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/crypto"
 	"github.com/jcmturner/gokrb5/v8/gssapi"
 	"github.com/jcmturner/gokrb5/v8/iana/chksumtype"
 	"github.com/jcmturner/gokrb5/v8/iana/msgtype"
@@ -52,7 +53,10 @@ func (m *KRB5Token) Marshal() ([]byte, error) {
 			return []byte{}, fmt.Errorf("error marshalling AP_REQ for MechToken: %v", err)
 		}
 	case TOK_ID_KRB_AP_REP:
-		return []byte{}, errors.New("marshal of AP_REP GSSAPI MechToken not supported by gokrb5")
+		tb, err = m.APRep.Marshal()
+		if err != nil {
+			return []byte{}, fmt.Errorf("error marshalling AP_REP for MechToken: %v", err)
+		}
 	case TOK_ID_KRB_ERROR:
 		return []byte{}, errors.New("marshal of KRB_ERROR GSSAPI MechToken not supported by gokrb5")
 	}
@@ -65,6 +69,16 @@ func (m *KRB5Token) Marshal() ([]byte, error) {
 
 // Unmarshal a KRB5Token.
 func (m *KRB5Token) Unmarshal(b []byte) error {
+	l := asn1tools.DefaultLimits()
+	if l.MaxBytes > 0 && len(b) > l.MaxBytes {
+		return fmt.Errorf("error unmarshalling KRB5Token: %w: size of %d bytes exceeds maximum of %d", asn1tools.ErrLimitExceeded, len(b), l.MaxBytes)
+	}
+	strict := m.settings != nil && m.settings.StrictDecoding()
+	if strict {
+		if err := asn1tools.CheckDERHeader(b); err != nil {
+			return fmt.Errorf("error unmarshalling KRB5Token: %w", err)
+		}
+	}
 	var oid asn1.ObjectIdentifier
 	r, err := asn1.UnmarshalWithParams(b, &oid, fmt.Sprintf("application,explicit,tag:%v", 0))
 	if err != nil {
@@ -78,6 +92,15 @@ func (m *KRB5Token) Unmarshal(b []byte) error {
 		return fmt.Errorf("krb5token too short")
 	}
 	m.tokID = r[0:2]
+	// The token ID is not ASN.1 encoded so the message that follows it is checked on its own
+	if err := asn1tools.CheckLimits(r[2:], l); err != nil {
+		return fmt.Errorf("error unmarshalling KRB5Token: %w", err)
+	}
+	if strict {
+		if err := asn1tools.CheckDER(r[2:]); err != nil {
+			return fmt.Errorf("error unmarshalling KRB5Token: %w", err)
+		}
+	}
 	switch hex.EncodeToString(m.tokID) {
 	case TOK_ID_KRB_AP_REQ:
 		var a messages.APReq
@@ -117,6 +140,13 @@ func (m *KRB5Token) Verify() (bool, gssapi.Status) {
 		}
 		m.context = context.Background()
 		m.context = context.WithValue(m.context, ctxCredentials, creds)
+		m.context = context.WithValue(m.context, ctxVerifiedAPReq, service.NewVerifiedAPReq(&m.APReq))
+		if len(m.APReq.Authenticator.SubKey.KeyValue) > 0 {
+			m.context = context.WithValue(m.context, ctxSecContextKey, SecContextKey{
+				Key:       m.APReq.Authenticator.SubKey,
+				SeqNumber: m.APReq.Authenticator.SeqNumber,
+			})
+		}
 		return true, gssapi.Status{Code: gssapi.StatusComplete}
 	case TOK_ID_KRB_AP_REP:
 		// Client side
@@ -160,18 +190,47 @@ func (m *KRB5Token) Context() context.Context {
 	return m.context
 }
 
+// VerifiedAPReqFromContext returns the decrypted parts of the client's AP_REQ from the context returned by
+// AcceptSecContext, or the request context of an HTTP handler wrapped by SPNEGOKRB5Authenticate.
+// The boolean is false if the client was not authenticated by an AP_REQ in this context, such as when an HTTP request
+// is served under an established session.
+func VerifiedAPReqFromContext(ctx context.Context) (service.VerifiedAPReq, bool) {
+	if ctx == nil {
+		return service.VerifiedAPReq{}, false
+	}
+	v, ok := ctx.Value(ctxVerifiedAPReq).(service.VerifiedAPReq)
+	return v, ok
+}
+
 // NewKRB5TokenAPREQ creates a new KRB5 token with AP_REQ
 func NewKRB5TokenAPREQ(cl *client.Client, tkt messages.Ticket, sessionKey types.EncryptionKey, GSSAPIFlags []int, APOptions []int) (KRB5Token, error) {
+	return newKRB5TokenAPREQ(cl, tkt, sessionKey, GSSAPIFlags, APOptions, false)
+}
+
+// newKRB5TokenAPREQ creates a new KRB5 token with AP_REQ optionally generating a subkey and sequence number within
+// the authenticator.
+func newKRB5TokenAPREQ(cl *client.Client, tkt messages.Ticket, sessionKey types.EncryptionKey, GSSAPIFlags []int, APOptions []int, subKey bool) (KRB5Token, error) {
 	// TODO consider providing the SPN rather than the specific tkt and key and get these from the krb client.
 	var m KRB5Token
 	m.OID = gssapi.OIDKRB5.OID()
 	tb, _ := hex.DecodeString(TOK_ID_KRB_AP_REQ)
 	m.tokID = tb
 
-	auth, err := krb5TokenAuthenticator(cl.Credentials, GSSAPIFlags)
+	auth, err := krb5TokenAuthenticator(cl.Realm(), cl.CName(), GSSAPIFlags, cl.Now())
 	if err != nil {
 		return m, err
 	}
+	if subKey {
+		et, err := crypto.GetEtype(sessionKey.KeyType)
+		if err != nil {
+			return m, krberror.Errorf(err, krberror.EncryptingError, "error getting etype for authenticator subkey")
+		}
+		err = auth.GenerateSeqNumberAndSubKey(sessionKey.KeyType, et.GetKeyByteSize())
+		if err != nil {
+			return m, krberror.Errorf(err, krberror.KRBMsgError, "error generating authenticator subkey")
+		}
+		cl.ExportSessionKey(tkt.SName, tkt.Realm, auth.SubKey)
+	}
 	APReq, err := messages.NewAPReq(
 		tkt,
 		sessionKey,
@@ -183,14 +242,27 @@ func NewKRB5TokenAPREQ(cl *client.Client, tkt messages.Ticket, sessionKey types.
 	for _, o := range APOptions {
 		types.SetFlag(&APReq.APOptions, o)
 	}
+	// Retain the authenticator so the initiator has the subkey and sequence number it contains and can verify any AP_REP
+	APReq.Authenticator = auth
 	m.APReq = APReq
 	return m, nil
 }
 
+// NewKRB5TokenAPREP creates a new KRB5 token with the AP_REP provided, as returned by a service to complete mutual
+// authentication. The AP_REP's encrypted part must already have been encrypted.
+func NewKRB5TokenAPREP(rep messages.APRep) KRB5Token {
+	tb, _ := hex.DecodeString(TOK_ID_KRB_AP_REP)
+	return KRB5Token{
+		OID:   gssapi.OIDKRB5.OID(),
+		tokID: tb,
+		APRep: rep,
+	}
+}
+
 // krb5TokenAuthenticator creates a new kerberos authenticator for kerberos MechToken
-func krb5TokenAuthenticator(creds *credentials.Credentials, flags []int) (types.Authenticator, error) {
+func krb5TokenAuthenticator(realm string, cname types.PrincipalName, flags []int, t time.Time) (types.Authenticator, error) {
 	//RFC 4121 Section 4.1.1
-	auth, err := types.NewAuthenticator(creds.Domain(), creds.CName())
+	auth, err := types.NewAuthenticatorAt(realm, cname, t)
 	if err != nil {
 		return auth, krberror.Errorf(err, krberror.KRBMsgError, "error generating new authenticator")
 	}
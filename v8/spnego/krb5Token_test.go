@@ -2,19 +2,24 @@ package spnego
 
 import (
 	"encoding/hex"
+	"errors"
 	"math"
 	"testing"
+	"time"
 
-	"github.com/jcmturner/gofork/encoding/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1tools"
 	"github.com/jcmturner/gokrb5/v8/client"
 	"github.com/jcmturner/gokrb5/v8/credentials"
 	"github.com/jcmturner/gokrb5/v8/gssapi"
 	"github.com/jcmturner/gokrb5/v8/iana/msgtype"
 	"github.com/jcmturner/gokrb5/v8/iana/nametype"
 	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/service"
 	"github.com/jcmturner/gokrb5/v8/test/testdata"
 	"github.com/jcmturner/gokrb5/v8/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -57,7 +62,7 @@ func TestKRB5Token_newAuthenticatorWithSubkeyGeneration(t *testing.T) {
 	creds.SetCName(types.PrincipalName{NameType: nametype.KRB_NT_PRINCIPAL, NameString: testdata.TEST_PRINCIPALNAME_NAMESTRING})
 	var etypeID int32 = 18
 	keyLen := 32 // etypeID 18 refers to AES256 -> 32 bytes key
-	a, err := krb5TokenAuthenticator(creds, []int{gssapi.ContextFlagInteg, gssapi.ContextFlagConf})
+	a, err := krb5TokenAuthenticator(creds.Domain(), creds.CName(), []int{gssapi.ContextFlagInteg, gssapi.ContextFlagConf}, time.Now().UTC())
 	if err != nil {
 		t.Fatalf("Error creating authenticator: %v", err)
 	}
@@ -85,7 +90,7 @@ func TestKRB5Token_newAuthenticator(t *testing.T) {
 	t.Parallel()
 	creds := credentials.New("hftsai", testdata.TEST_REALM)
 	creds.SetCName(types.PrincipalName{NameType: nametype.KRB_NT_PRINCIPAL, NameString: testdata.TEST_PRINCIPALNAME_NAMESTRING})
-	a, err := krb5TokenAuthenticator(creds, []int{gssapi.ContextFlagInteg, gssapi.ContextFlagConf})
+	a, err := krb5TokenAuthenticator(creds.Domain(), creds.CName(), []int{gssapi.ContextFlagInteg, gssapi.ContextFlagConf}, time.Now().UTC())
 	if err != nil {
 		t.Fatalf("Error creating authenticator: %v", err)
 	}
@@ -144,3 +149,23 @@ func TestNewAPREQKRB5Token_and_Marshal(t *testing.T) {
 	assert.Equal(t, testdata.TEST_PRINCIPALNAME_NAMESTRING, mt.APReq.Ticket.SName.NameString, "SName in ticket within the AP_REQ of the KRB5Token not as expected.")
 	assert.Equal(t, int32(18), mt.APReq.EncryptedAuthenticator.EType, "Authenticator within AP_REQ does not have the etype expected.")
 }
+
+func TestKRB5Token_Unmarshal_Limits(t *testing.T) {
+	t.Parallel()
+	b, err := hex.DecodeString(KRB5TokenHex)
+	require.NoError(t, err, "error decoding KRB5Token hex")
+	var mt KRB5Token
+	mt.settings = service.NewSettings(nil, service.StrictDecoding(true))
+	require.NoError(t, mt.Unmarshal(b), "token within limits should be accepted when decoding strictly")
+
+	// A message nested beyond the maximum depth following the token ID
+	oid, _ := asn1.Marshal(gssapi.OIDKRB5.OID())
+	inner, _ := asn1.Marshal(1)
+	for i := 0; i < 40; i++ {
+		inner, _ = asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSequence, IsCompound: true, Bytes: inner})
+	}
+	tb := append(append(oid, 0x01, 0x00), inner...)
+	tb = asn1tools.AddASNAppTag(tb, 0)
+	err = new(KRB5Token).Unmarshal(tb)
+	assert.True(t, errors.Is(err, asn1tools.ErrLimitExceeded), "depth limit of the message not enforced: %v", err)
+}
@@ -5,7 +5,8 @@ import (
 	"errors"
 	"fmt"
 
-	"github.com/jcmturner/gofork/encoding/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1tools"
 	"github.com/jcmturner/gokrb5/v8/client"
 	"github.com/jcmturner/gokrb5/v8/gssapi"
 	"github.com/jcmturner/gokrb5/v8/messages"
@@ -247,6 +248,9 @@ func (n *NegTokenResp) Context() context.Context {
 // The boolean indicates if the response is a NegTokenInit.
 // If error is nil and the boolean is false the response is a NegTokenResp.
 func UnmarshalNegToken(b []byte) (bool, interface{}, error) {
+	if err := asn1tools.CheckLimits(b, asn1tools.DefaultLimits()); err != nil {
+		return false, nil, fmt.Errorf("error unmarshalling NegotiationToken: %w", err)
+	}
 	var a asn1.RawValue
 	_, err := asn1.Unmarshal(b, &a)
 	if err != nil {
@@ -0,0 +1,122 @@
+package spnego_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jcmturner/goidentity/v6"
+	"github.com/jcmturner/gokrb5/v8/asn1"
+	"github.com/jcmturner/gokrb5/v8/clock"
+	"github.com/jcmturner/gokrb5/v8/gssapi"
+	"github.com/jcmturner/gokrb5/v8/krbtest"
+	"github.com/jcmturner/gokrb5/v8/service"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// negotiationLegs returns the header values of an initial token offering KRB5 without a mechanism token, and of a
+// response token carrying the KRB5 token that completes the negotiation.
+func negotiationLegs(t *testing.T, kdc *krbtest.KDC) (string, string) {
+	it := spnego.SPNEGOToken{
+		Init:         true,
+		NegTokenInit: spnego.NegTokenInit{MechTypes: []asn1.ObjectIdentifier{gssapi.OIDKRB5.OID()}},
+	}
+	ib, err := it.Marshal()
+	require.NoError(t, err, "error marshaling initial token")
+	b, _, _ := rawKRB5Token(t, kdc)
+	rt := spnego.SPNEGOToken{
+		Resp: true,
+		NegTokenResp: spnego.NegTokenResp{
+			NegState:      asn1.Enumerated(spnego.NegStateAcceptIncomplete),
+			SupportedMech: gssapi.OIDKRB5.OID(),
+			ResponseToken: b,
+		},
+	}
+	rb, err := rt.Marshal()
+	require.NoError(t, err, "error marshaling response token")
+	return spnego.EncodeNegotiateHeader(ib), spnego.EncodeNegotiateHeader(rb)
+}
+
+func negotiationRequest(t *testing.T, url, hdr, id string) *http.Response {
+	r, _ := http.NewRequest("GET", url, nil)
+	r.Header.Set(spnego.HTTPHeaderAuthRequest, hdr)
+	if id != "" {
+		r.Header.Set(spnego.HTTPHeaderNegotiationID, id)
+	}
+	resp, err := http.DefaultClient.Do(r)
+	require.NoError(t, err, "error sending request")
+	resp.Body.Close()
+	return resp
+}
+
+func TestSPNEGOKRB5Authenticate_NegotiationCache(t *testing.T) {
+	t.Parallel()
+	kdc, err := krbtest.NewKDC(krbtest.Realm, krbtest.KDCKeytab())
+	require.NoError(t, err, "error starting mock KDC")
+	defer kdc.Close()
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, goidentity.FromHTTPRequestContext(r).UserName())
+	})
+	store := service.NewMemoryNegotiationStore()
+	clk := clock.NewMock(time.Now().UTC())
+	s := httptest.NewServer(spnego.SPNEGOKRB5Authenticate(inner, krbtest.ServiceKeytab(),
+		service.NegotiationCache(store, time.Minute), service.Clock(clk)))
+	defer s.Close()
+
+	initHdr, respHdr := negotiationLegs(t, kdc)
+	r := negotiationRequest(t, s.URL, initHdr, "")
+	assert.Equal(t, http.StatusUnauthorized, r.StatusCode, "status code of the first leg not as expected")
+	id := r.Header.Get(spnego.HTTPHeaderNegotiationID)
+	require.NotEmpty(t, id, "negotiation ID should be returned")
+	assert.Equal(t, 1, store.Len(), "negotiation state should be stored")
+
+	r = negotiationRequest(t, s.URL, respHdr, id)
+	assert.Equal(t, http.StatusOK, r.StatusCode, "status code of the final leg not as expected")
+	assert.Equal(t, 0, store.Len(), "negotiation state should be removed once complete")
+
+	// The state cannot be used again
+	r = negotiationRequest(t, s.URL, respHdr, id)
+	assert.Equal(t, http.StatusUnauthorized, r.StatusCode, "a completed negotiation should not be continued")
+
+	// Negotiations not started by the service are rejected
+	initHdr, respHdr = negotiationLegs(t, kdc)
+	r = negotiationRequest(t, s.URL, respHdr, "")
+	assert.Equal(t, http.StatusUnauthorized, r.StatusCode, "a response token without a negotiation ID should be rejected")
+
+	// Negotiations not completed within the timeout are rejected
+	r = negotiationRequest(t, s.URL, initHdr, "")
+	id = r.Header.Get(spnego.HTTPHeaderNegotiationID)
+	require.NotEmpty(t, id, "negotiation ID should be returned")
+	clk.Add(2 * time.Minute)
+	r = negotiationRequest(t, s.URL, respHdr, id)
+	assert.Equal(t, http.StatusUnauthorized, r.StatusCode, "a timed out negotiation should be rejected")
+}
+
+func TestSPNEGOKRB5Authenticate_NegotiationCookie(t *testing.T) {
+	t.Parallel()
+	kdc, err := krbtest.NewKDC(krbtest.Realm, krbtest.KDCKeytab())
+	require.NoError(t, err, "error starting mock KDC")
+	defer kdc.Close()
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	s := httptest.NewServer(spnego.SPNEGOKRB5Authenticate(inner, krbtest.ServiceKeytab(),
+		service.NegotiationCache(service.NewMemoryNegotiationStore(), 0)))
+	defer s.Close()
+
+	initHdr, respHdr := negotiationLegs(t, kdc)
+	r := negotiationRequest(t, s.URL, initHdr, "")
+	cs := r.Cookies()
+	require.Len(t, cs, 1, "negotiation cookie should be set")
+	assert.Equal(t, int(service.DefaultNegotiationTimeout/time.Second), cs[0].MaxAge, "cookie should expire with the negotiation")
+
+	req, _ := http.NewRequest("GET", s.URL, nil)
+	req.Header.Set(spnego.HTTPHeaderAuthRequest, respHdr)
+	req.AddCookie(cs[0])
+	r, err = http.DefaultClient.Do(req)
+	require.NoError(t, err, "error sending request")
+	r.Body.Close()
+	assert.Equal(t, http.StatusOK, r.StatusCode, "the negotiation should be continued with the cookie")
+}
@@ -0,0 +1,75 @@
+package spnego
+
+import (
+	"context"
+
+	"github.com/jcmturner/gokrb5/v8/crypto"
+	"github.com/jcmturner/gokrb5/v8/gssapi"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// SecContextKey holds the per-message protection state, the subkey and initial sequence number, established by the
+// authenticator of an AP exchange.
+type SecContextKey struct {
+	Key       types.EncryptionKey
+	SeqNumber int64
+}
+
+// InitiatorSecContext returns the client's security context protecting messages with the SecContextKey. The context
+// flags determine whether replayed and out of sequence tokens from the service are detected.
+func (k SecContextKey) InitiatorSecContext(flags int) *gssapi.SecContext {
+	return gssapi.NewInitiatorSecContext(k.Key, k.SeqNumber, flags)
+}
+
+// AcceptorSecContext returns the service's security context protecting messages with the SecContextKey. The context
+// flags determine whether replayed and out of sequence tokens from the client are detected.
+func (k SecContextKey) AcceptorSecContext(flags int) *gssapi.SecContext {
+	return gssapi.NewAcceptorSecContext(k.Key, k.SeqNumber, flags)
+}
+
+// DeriveKey returns a key derived from the SecContextKey's key for the application key usage number provided.
+// See crypto.DeriveKey.
+func (k SecContextKey) DeriveKey(usage uint32) (types.EncryptionKey, error) {
+	return crypto.DeriveKey(k.Key, usage)
+}
+
+// RekeySecContext is used by the client to establish fresh per-message protection state on a long lived security
+// context. The AP exchange is performed again generating a context token with an authenticator containing a new subkey
+// and sequence number, requesting mutual authentication. The token must be sent to the service, which accepts it with
+// an Acceptor and returns its response token holding the AP_REP. The new SecContextKey is only returned once the
+// response is verified with the Rekey's Confirm method, until then both parties should continue to use the existing
+// SecContextKey.
+// The service ticket is renewed or replaced as required by the client.
+func (s *SPNEGO) RekeySecContext() (gssapi.ContextToken, *Rekey, error) {
+	i := NewInitiator(s.client, s.spn, []int{gssapi.ContextFlagInteg, gssapi.ContextFlagConf, gssapi.ContextFlagMutual})
+	st, err := i.initToken()
+	if err != nil {
+		return &SPNEGOToken{}, nil, err
+	}
+	i.sent = true
+	return st, &Rekey{initiator: i}, nil
+}
+
+// Rekey is a rekeying of a security context by the client that is awaiting the service's confirmation.
+type Rekey struct {
+	initiator *Initiator
+}
+
+// Confirm verifies the service's response to the rekeying context token, which must hold the AP_REP mutually
+// authenticating the service, and returns the SecContextKey with which subsequent messages should be protected.
+func (r *Rekey) Confirm(response []byte) (SecContextKey, error) {
+	if _, _, err := r.initiator.InitSecContext(response); err != nil {
+		return SecContextKey{}, err
+	}
+	return r.initiator.SecContextKey(), nil
+}
+
+// SecContextKeyFromContext returns the SecContextKey established by the client's authenticator from the context
+// returned by AcceptSecContext. The boolean indicates if the client's authenticator contained a subkey.
+func SecContextKeyFromContext(ctx context.Context) (SecContextKey, bool) {
+	if ctx == nil {
+		return SecContextKey{}, false
+	}
+	k, ok := ctx.Value(ctxSecContextKey).(SecContextKey)
+	return k, ok
+}
@@ -0,0 +1,90 @@
+package spnego
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/gssapi"
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/service"
+	"github.com/jcmturner/gokrb5/v8/test/testdata"
+	"github.com/jcmturner/gokrb5/v8/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKRB5Token_SecContextKey(t *testing.T) {
+	t.Parallel()
+	b, _ := hex.DecodeString(testdata.KEYTAB_TESTUSER1_TEST_GOKRB5)
+	ckt := keytab.New()
+	ckt.Unmarshal(b)
+	c, _ := config.NewFromString(testdata.KRB5_CONF)
+	cl := client.NewWithKeytab("testuser1", "TEST.GOKRB5", ckt, c)
+
+	b, _ = hex.DecodeString(testdata.HTTP_KEYTAB)
+	kt := keytab.New()
+	kt.Unmarshal(b)
+	sname := types.PrincipalName{
+		NameType:   nametype.KRB_NT_PRINCIPAL,
+		NameString: []string{"HTTP", "host.test.gokrb5"},
+	}
+	st := time.Now().UTC()
+	tkt, sessionKey, err := messages.NewTicket(cl.Credentials.CName(), cl.Credentials.Domain(),
+		sname, "TEST.GOKRB5",
+		types.NewKrbFlags(),
+		kt,
+		18,
+		1,
+		st,
+		st,
+		st.Add(time.Duration(24)*time.Hour),
+		st.Add(time.Duration(48)*time.Hour),
+	)
+	if err != nil {
+		t.Fatalf("Error getting test ticket: %v", err)
+	}
+
+	for _, subKey := range []bool{false, true} {
+		mt, err := newKRB5TokenAPREQ(cl, tkt, sessionKey, []int{gssapi.ContextFlagInteg, gssapi.ContextFlagConf}, []int{}, subKey)
+		if err != nil {
+			t.Fatalf("Error creating KRB5Token: %v", err)
+		}
+		mb, err := mt.Marshal()
+		if err != nil {
+			t.Fatalf("Error marshalling KRB5Token: %v", err)
+		}
+		var at KRB5Token
+		err = at.Unmarshal(mb)
+		if err != nil {
+			t.Fatalf("Error unmarshalling KRB5Token: %v", err)
+		}
+		at.settings = service.NewSettings(kt)
+		ok, status := at.Verify()
+		if !ok {
+			t.Fatalf("KRB5Token did not verify: %v", status)
+		}
+		k, ok := SecContextKeyFromContext(at.Context())
+		assert.Equal(t, subKey, ok, "presence of the SecContextKey in the context not as expected")
+		if subKey {
+			assert.Equal(t, int32(18), k.Key.KeyType, "subkey etype not as expected")
+			assert.Len(t, k.Key.KeyValue, 32, "subkey length not as expected")
+			assert.Equal(t, mt.APReq.Authenticator.SubKey, k.Key, "acceptor subkey does not match initiator's")
+			assert.Equal(t, mt.APReq.Authenticator.SeqNumber, k.SeqNumber, "acceptor sequence number does not match initiator's")
+
+			ik := SecContextKey{Key: mt.APReq.Authenticator.SubKey, SeqNumber: mt.APReq.Authenticator.SeqNumber}
+			wb, err := ik.InitiatorSecContext(gssapi.ContextFlagReplay).Wrap([]byte("hello"), true)
+			if err != nil {
+				t.Fatalf("Error wrapping message: %v", err)
+			}
+			m, _, err := k.AcceptorSecContext(gssapi.ContextFlagReplay).Unwrap(wb)
+			if err != nil {
+				t.Fatalf("Error unwrapping message: %v", err)
+			}
+			assert.Equal(t, []byte("hello"), m, "unwrapped message not as expected")
+		}
+	}
+}
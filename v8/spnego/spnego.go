@@ -6,7 +6,7 @@ import (
 	"errors"
 	"fmt"
 
-	"github.com/jcmturner/gofork/encoding/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1"
 	"github.com/jcmturner/gokrb5/v8/asn1tools"
 	"github.com/jcmturner/gokrb5/v8/client"
 	"github.com/jcmturner/gokrb5/v8/gssapi"
@@ -89,6 +89,43 @@ func (s *SPNEGO) AcceptSecContext(ct gssapi.ContextToken) (bool, context.Context
 	return ok, ctx, status
 }
 
+// unmarshalContextToken unmarshals the context token sent by a client. A raw KRB5 context token, sent by clients
+// that do not negotiate the mechanism with SPNEGO, is wrapped into a NegTokenInit - issue #347.
+// This is a deviation from RFC 4559 so is not accepted when decoding strictly.
+func (s *SPNEGO) unmarshalContextToken(b []byte) (*SPNEGOToken, error) {
+	if s.serviceSettings.TolerateBER() {
+		der, err := asn1.NormalizeBER(b)
+		if err != nil {
+			return nil, fmt.Errorf("error in unmarshaling SPNEGO token: %v", err)
+		}
+		b = der
+	}
+	if s.serviceSettings.StrictDecoding() {
+		if err := asn1tools.CheckDER(b); err != nil {
+			return nil, fmt.Errorf("error in unmarshaling SPNEGO token: %v", err)
+		}
+	}
+	st := &SPNEGOToken{settings: s.serviceSettings}
+	err := st.Unmarshal(b)
+	if err == nil {
+		return st, nil
+	}
+	var k5t KRB5Token
+	if s.serviceSettings.StrictDecoding() || k5t.Unmarshal(b) != nil {
+		return nil, fmt.Errorf("error in unmarshaling SPNEGO token: %v", err)
+	}
+	st = &SPNEGOToken{
+		Init: true,
+		NegTokenInit: NegTokenInit{
+			MechTypes:      []asn1.ObjectIdentifier{k5t.OID},
+			MechTokenBytes: b,
+		},
+		settings: s.serviceSettings,
+		raw:      true,
+	}
+	return st, nil
+}
+
 // Log will write to the service's logger if it is configured.
 func (s *SPNEGO) Log(format string, v ...interface{}) {
 	if s.serviceSettings.Logger() != nil {
@@ -104,6 +141,7 @@ type SPNEGOToken struct {
 	NegTokenResp NegTokenResp
 	settings     *service.Settings
 	context      context.Context
+	raw          bool
 }
 
 // Marshal SPNEGO context token
@@ -136,6 +174,9 @@ func (s *SPNEGOToken) Unmarshal(b []byte) error {
 	if len(b) < 1 {
 		return fmt.Errorf("provided byte array is empty")
 	}
+	if err := asn1tools.CheckLimits(b, asn1tools.DefaultLimits()); err != nil {
+		return fmt.Errorf("not a valid SPNEGO token: %w", err)
+	}
 	if b[0] != byte(161) {
 		// Not a NegTokenResp/Targ could be a NegTokenInit
 		var oid asn1.ObjectIdentifier
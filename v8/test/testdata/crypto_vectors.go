@@ -0,0 +1,169 @@
+package testdata
+
+// Published test vectors for the Kerberos cryptosystems.
+// These are exported so that alternative encryption type implementations can be validated against the same data used
+// to test this library. All binary values are hex encoded.
+
+// NFoldVector is an n-fold test vector.
+type NFoldVector struct {
+	N      int
+	Input  string
+	Folded string
+}
+
+// RFC3961NFoldVectors are the n-fold test vectors from RFC 3961 Appendix A.1.
+var RFC3961NFoldVectors = []NFoldVector{
+	{64, "012345", "be072631276b1955"},
+	{56, "password", "78a07b6caf85fa"},
+	{64, "Rough Consensus, and Running Code", "bb6ed30870b7f0e0"},
+	{168, "password", "59e4a8ca7c0385c3c37b3f6d2000247cb6e6bd5b3e"},
+	{192, "MASSACHVSETTS INSTITVTE OF TECHNOLOGY", "db3b0d8f0b061e603282b308a50841229ad798fab9540c1b"},
+	{168, "Q", "518a54a215a8452a518a54a215a8452a518a54a215"},
+	{168, "ba", "fb25d531ae8974499f52fd92ea9857c4ba24cf297e"},
+}
+
+// DeriveKeyVector is a key derivation test vector giving the derived random (DR) and derived key (DK) for a usage
+// constant.
+type DeriveKeyVector struct {
+	Key   string
+	Usage string
+	DR    string
+	DK    string
+}
+
+// RFC3961DES3DeriveKeyVectors are the des3-cbc-sha1-kd key derivation test vectors from RFC 3961 Appendix A.3.
+var RFC3961DES3DeriveKeyVectors = []DeriveKeyVector{
+	{"dce06b1f64c857a11c3db57c51899b2cc1791008ce973b92", "0000000155", "935079d14490a75c3093c4a6e8c3b049c71e6ee705", "925179d04591a79b5d3192c4a7e9c289b049c71f6ee604cd"},
+	{"5e13d31c70ef765746578531cb51c15bf11ca82c97cee9f2", "00000001aa", "9f58e5a047d894101c469845d67ae3c5249ed812f2", "9e58e5a146d9942a101c469845d67a20e3c4259ed913f207"},
+	{"98e6fd8a04a4b6859b75a176540b9752bad3ecd610a252bc", "0000000155", "12fff90c773f956d13fc2ca0d0840349dbd39908eb", "13fef80d763e94ec6d13fd2ca1d085070249dad39808eabf"},
+	{"622aec25a2fe2cad7094680b7c64940280084c1a7cec92b5", "00000001aa", "f8debf05b097e7dc0603686aca35d91fd9a5516a70", "f8dfbf04b097e6d9dc0702686bcb3489d91fd9a4516b703e"},
+	{"d3f8298ccb166438dcb9b93ee5a7629286a491f838f802fb", "6b65726265726f73", "2270db565d2a3d64cfbfdc5305d4f778a6de42d9da", "2370da575d2a3da864cebfdc5204d56df779a7df43d9da43"},
+	{"c1081649ada74362e6a1459d01dfd30d67c2234c940704da", "0000000155", "348056ec98fcc517171d2b4d7a9493af482d999175", "348057ec98fdc48016161c2a4c7a943e92ae492c989175f7"},
+	{"5d154af238f46713155719d55e2f1f790dd661f279a7917c", "00000001aa", "a8818bc367dadacbe9a6c84627fb60c294b01215e5", "a8808ac267dada3dcbe9a7c84626fbc761c294b01315e5c1"},
+	{"798562e049852f57dc8c343ba17f2ca1d97394efc8adc443", "0000000155", "c813f88b3be2b2f75424ce9175fbc8483b88c8713a", "c813f88a3be3b334f75425ce9175fbe3c8493b89c8703b49"},
+	{"26dce334b545292f2feab9a8701a89a4b99eb9942cecd016", "00000001aa", "f58efc6f83f93e55e695fd252cf8fe59f7d5ba37ec", "f48ffd6e83f83e7354e694fd252cf83bfe58f7d5ba37ec5d"},
+}
+
+// StringToKeyVector is a string-to-key test vector.
+// Iterations and PBKDF2 are only used by the PBKDF2 based encryption types.
+type StringToKeyVector struct {
+	EType      int32
+	Iterations uint32
+	Phrase     string
+	Salt       string
+	PBKDF2     string
+	Key        string
+}
+
+// RFC3961DES3StringToKeyVectors are the des3-cbc-sha1-kd string-to-key test vectors from RFC 3961 Appendix A.4.
+var RFC3961DES3StringToKeyVectors = []StringToKeyVector{
+	{16, 0, "password", "ATHENA.MIT.EDUraeburn", "", "850bb51358548cd05e86768c313e3bfef7511937dcf72c3e"},
+	{16, 0, "potatoe", "WHITEHOUSE.GOVdanny", "", "dfcd233dd0a43204ea6dc437fb15e061b02979c1f74f377a"},
+	{16, 0, "penny", "EXAMPLE.COMbuckaroo", "", "6d2fcdf2d6fbbc3ddcadb5da5710a23489b0d3b69d5d9d4a"},
+	{16, 0, "ß", "ATHENA.MIT.EDUJurišić", "", "16d5a40e1ce3bacb61b9dce00470324c831973a7b952feb0"},
+	{16, 0, "\U0001D11E", "EXAMPLE.COMpianist", "", "85763726585dbc1cce6ec43e1f751f07f1c4cbb098f40b19"},
+}
+
+// RFC3962StringToKeyVectors are the aes128-cts-hmac-sha1-96 and aes256-cts-hmac-sha1-96 string-to-key test vectors
+// from RFC 3962 Appendix B.
+var RFC3962StringToKeyVectors = []StringToKeyVector{
+	{17, 1, "password", "ATHENA.MIT.EDUraeburn", "cdedb5281bb2f801565a1122b2563515", "42263c6e89f4fc28b8df68ee09799f15"},
+	{17, 2, "password", "ATHENA.MIT.EDUraeburn", "01dbee7f4a9e243e988b62c73cda935d", "c651bf29e2300ac27fa469d693bdda13"},
+	{17, 1200, "password", "ATHENA.MIT.EDUraeburn", "5c08eb61fdf71e4e4ec3cf6ba1f5512b", "4c01cd46d632d01e6dbe230a01ed642a"},
+	{17, 5, "password", "\x12\x34\x56\x78\x78\x56\x34\x12", "d1daa78615f287e6a1c8b120d7062a49", "e9b23d52273747dd5c35cb55be619d8e"},
+	{17, 1200, "XXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXX", "pass phrase equals block size", "139c30c0966bc32ba55fdbf212530ac9", "59d1bb789a828b1aa54ef9c2883f69ed"},
+	{17, 1200, "XXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXX", "pass phrase exceeds block size", "9ccad6d468770cd51b10e6a68721be61", "cb8005dc5f90179a7f02104c0018751d"},
+	{17, 50, "\U0001D11E", "EXAMPLE.COMpianist", "6b9cf26d45455a43a5b8bb276a403b39", "f149c1f2e154a73452d43e7fe62a56e5"},
+	{18, 1, "password", "ATHENA.MIT.EDUraeburn", "cdedb5281bb2f801565a1122b25635150ad1f7a04bb9f3a333ecc0e2e1f70837", "fe697b52bc0d3ce14432ba036a92e65bbb52280990a2fa27883998d72af30161"},
+	{18, 2, "password", "ATHENA.MIT.EDUraeburn", "01dbee7f4a9e243e988b62c73cda935da05378b93244ec8f48a99e61ad799d86", "a2e16d16b36069c135d5e9d2e25f896102685618b95914b467c67622225824ff"},
+	{18, 1200, "password", "ATHENA.MIT.EDUraeburn", "5c08eb61fdf71e4e4ec3cf6ba1f5512ba7e52ddbc5e5142f708a31e2e62b1e13", "55a6ac740ad17b4846941051e1e8b0a7548d93b0ab30a8bc3ff16280382b8c2a"},
+	{18, 5, "password", "\x12\x34\x56\x78\x78\x56\x34\x12", "d1daa78615f287e6a1c8b120d7062a493f98d203e6be49a6adf4fa574b6e64ee", "97a4e786be20d81a382d5ebc96d5909cabcdadc87ca48f574504159f16c36e31"},
+	{18, 1200, "XXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXX", "pass phrase equals block size", "139c30c0966bc32ba55fdbf212530ac9c5ec59f1a452f5cc9ad940fea0598ed1", "89adee3608db8bc71f1bfbfe459486b05618b70cbae22092534e56c553ba4b34"},
+	{18, 1200, "XXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXX", "pass phrase exceeds block size", "9ccad6d468770cd51b10e6a68721be611a8b4d282601db3b36be9246915ec82a", "d78c5c9cb872a8c9dad4697f0bb5b2d21496c82beb2caeda2112fceea057401b"},
+	{18, 50, "\U0001D11E", "EXAMPLE.COMpianist", "6b9cf26d45455a43a5b8bb276a403b39e7fe37a0c41e02c281ff3069e1e94f52", "4b6d9839f84406df1f09cc166db4b83c571848b784a3d6bdc346589a3e393f9e"},
+}
+
+// RFC4757StringToKeyVectors are rc4-hmac string-to-key test vectors.
+// The key is the MD4 hash of the UTF-16LE encoded phrase so no salt is used.
+var RFC4757StringToKeyVectors = []StringToKeyVector{
+	{23, 0, "foo", "", "", "ac8e657f83df82beea5d43bdaf7800cc"},
+}
+
+// RFC8009StringToKeyVector is a string-to-key test vector from RFC 8009 Appendix A.
+// SaltP is the salt with the encryption type name prefixed as used by the PBKDF2 function.
+type RFC8009StringToKeyVector struct {
+	StringToKeyVector
+	SaltP string
+}
+
+// RFC8009StringToKeyVectors are the string-to-key test vectors from RFC 8009 Appendix A.
+var RFC8009StringToKeyVectors = []RFC8009StringToKeyVector{
+	{
+		StringToKeyVector: StringToKeyVector{19, 32768, "password", "\x10\xdf\x9d\xd7\x83\xe5\xbc\x8a\xce\xa1\x73\x0e\x74\x35\x5f\x61ATHENA.MIT.EDUraeburn", "", "089bca48b105ea6ea77ca5d2f39dc5e7"},
+		SaltP:             "6165733132382d6374732d686d61632d7368613235362d3132380010df9dd783e5bc8acea1730e74355f61415448454e412e4d49542e4544557261656275726e",
+	},
+	{
+		StringToKeyVector: StringToKeyVector{20, 32768, "password", "\x10\xdf\x9d\xd7\x83\xe5\xbc\x8a\xce\xa1\x73\x0e\x74\x35\x5f\x61ATHENA.MIT.EDUraeburn", "", "45bd806dbf6a833a9cffc1c94589a222367a79bc21c413718906e9f578a78467"},
+		SaltP:             "6165733235362d6374732d686d61632d7368613338342d3139320010df9dd783e5bc8acea1730e74355f61415448454e412e4d49542e4544557261656275726e",
+	},
+}
+
+// KeyUsageVector gives the checksum (Kc), encryption (Ke) and integrity (Ki) keys derived from a protocol base key for
+// a key usage.
+type KeyUsageVector struct {
+	EType   int32
+	BaseKey string
+	Usage   uint32
+	Kc      string
+	Ke      string
+	Ki      string
+}
+
+// RFC8009KeyUsageVectors are the key derivation test vectors from RFC 8009 Appendix A.
+var RFC8009KeyUsageVectors = []KeyUsageVector{
+	{19, "3705d96080c17728a0e800eab6e0d23c", 2, "b31a018a48f54776f403e9a396325dc3", "9b197dd1e8c5609d6e67c3e37c62c72e", "9fda0e56ab2d85e1569a688696c26a6c"},
+	{20, "6d404d37faf79f9df0d33568d320669800eb4836472ea8a026d16b7182460c52", 2, "ef5718be86cc84963d8bbb5031e9f5c4ba41f28faf69e73d", "56ab22bee63d82d7bc5227f6773f8ea7a5eb1c825160c38312980c442e5c7e49", "69b16514e3cd8e56b82010d5c73012b622c4d00ffc23ed1f"},
+}
+
+// EncryptionVector is an encryption test vector.
+// Encrypted is the output of the cipher over the confounder and plaintext, Hash is the truncated HMAC and Cipher is
+// the complete ciphertext message.
+type EncryptionVector struct {
+	EType      int32
+	BaseKey    string
+	Usage      uint32
+	Plain      string
+	Confounder string
+	Ke         string
+	Ki         string
+	Encrypted  string
+	Hash       string
+	Cipher     string
+}
+
+// RFC8009EncryptionVectors are the encryption test vectors from RFC 8009 Appendix A.
+var RFC8009EncryptionVectors = []EncryptionVector{
+	{19, "3705d96080c17728a0e800eab6e0d23c", 2, "", "7e5895eaf2672435bad817f545a37148", "9b197dd1e8c5609d6e67c3e37c62c72e", "9fda0e56ab2d85e1569a688696c26a6c", "ef85fb890bb8472f4dab20394dca781d", "ad877eda39d50c870c0d5a0a8e48c718", "ef85fb890bb8472f4dab20394dca781dad877eda39d50c870c0d5a0a8e48c718"},
+	{19, "3705d96080c17728a0e800eab6e0d23c", 2, "000102030405", "7bca285e2fd4130fb55b1a5c83bc5b24", "9b197dd1e8c5609d6e67c3e37c62c72e", "9fda0e56ab2d85e1569a688696c26a6c", "84d7f30754ed987bab0bf3506beb09cfb55402cef7e6", "877ce99e247e52d16ed4421dfdf8976c", "84d7f30754ed987bab0bf3506beb09cfb55402cef7e6877ce99e247e52d16ed4421dfdf8976c"},
+	{19, "3705d96080c17728a0e800eab6e0d23c", 2, "000102030405060708090a0b0c0d0e0f", "56ab21713ff62c0a1457200f6fa9948f", "9b197dd1e8c5609d6e67c3e37c62c72e", "9fda0e56ab2d85e1569a688696c26a6c", "3517d640f50ddc8ad3628722b3569d2ae07493fa8263254080ea65c1008e8fc2", "95fb4852e7d83e1e7c48c37eebe6b0d3", "3517d640f50ddc8ad3628722b3569d2ae07493fa8263254080ea65c1008e8fc295fb4852e7d83e1e7c48c37eebe6b0d3"},
+	{19, "3705d96080c17728a0e800eab6e0d23c", 2, "000102030405060708090a0b0c0d0e0f1011121314", "a7a4e29a4728ce10664fb64e49ad3fac", "9b197dd1e8c5609d6e67c3e37c62c72e", "9fda0e56ab2d85e1569a688696c26a6c", "720f73b18d9859cd6ccb4346115cd336c70f58edc0c4437c5573544c31c813bce1e6d072c1", "86b39a413c2f92ca9b8334a287ffcbfc", "720f73b18d9859cd6ccb4346115cd336c70f58edc0c4437c5573544c31c813bce1e6d072c186b39a413c2f92ca9b8334a287ffcbfc"},
+	{20, "6d404d37faf79f9df0d33568d320669800eb4836472ea8a026d16b7182460c52", 2, "", "f764e9fa15c276478b2c7d0c4e5f58e4", "56ab22bee63d82d7bc5227f6773f8ea7a5eb1c825160c38312980c442e5c7e49", "69b16514e3cd8e56b82010d5c73012b622c4d00ffc23ed1f", "41f53fa5bfe7026d91faf9be959195a0", "58707273a96a40f0a01960621ac612748b9bbfbe7eb4ce3c", "41f53fa5bfe7026d91faf9be959195a058707273a96a40f0a01960621ac612748b9bbfbe7eb4ce3c"},
+	{20, "6d404d37faf79f9df0d33568d320669800eb4836472ea8a026d16b7182460c52", 2, "000102030405", "b80d3251c1f6471494256ffe712d0b9a", "56ab22bee63d82d7bc5227f6773f8ea7a5eb1c825160c38312980c442e5c7e49", "69b16514e3cd8e56b82010d5c73012b622c4d00ffc23ed1f", "4ed7b37c2bcac8f74f23c1cf07e62bc7b75fb3f637b9", "f559c7f664f69eab7b6092237526ea0d1f61cb20d69d10f2", "4ed7b37c2bcac8f74f23c1cf07e62bc7b75fb3f637b9f559c7f664f69eab7b6092237526ea0d1f61cb20d69d10f2"},
+	{20, "6d404d37faf79f9df0d33568d320669800eb4836472ea8a026d16b7182460c52", 2, "000102030405060708090a0b0c0d0e0f", "53bf8a0d105265d4e276428624ce5e63", "56ab22bee63d82d7bc5227f6773f8ea7a5eb1c825160c38312980c442e5c7e49", "69b16514e3cd8e56b82010d5c73012b622c4d00ffc23ed1f", "bc47ffec7998eb91e8115cf8d19dac4bbbe2e163e87dd37f49beca92027764f6", "8cf51f14d798c2273f35df574d1f932e40c4ff255b36a266", "bc47ffec7998eb91e8115cf8d19dac4bbbe2e163e87dd37f49beca92027764f68cf51f14d798c2273f35df574d1f932e40c4ff255b36a266"},
+	{20, "6d404d37faf79f9df0d33568d320669800eb4836472ea8a026d16b7182460c52", 2, "000102030405060708090a0b0c0d0e0f1011121314", "763e65367e864f02f55153c7e3b58af1", "56ab22bee63d82d7bc5227f6773f8ea7a5eb1c825160c38312980c442e5c7e49", "69b16514e3cd8e56b82010d5c73012b622c4d00ffc23ed1f", "40013e2df58e8751957d2878bcd2d6fe101ccfd556cb1eae79db3c3ee86429f2b2a602ac86", "fef6ecb647d6295fae077a1feb517508d2c16b4192e01f62", "40013e2df58e8751957d2878bcd2d6fe101ccfd556cb1eae79db3c3ee86429f2b2a602ac86fef6ecb647d6295fae077a1feb517508d2c16b4192e01f62"},
+}
+
+// ChecksumVector is a keyed checksum test vector.
+type ChecksumVector struct {
+	EType    int32
+	BaseKey  string
+	Usage    uint32
+	Plain    string
+	Checksum string
+}
+
+// RFC8009ChecksumVectors are the checksum test vectors from RFC 8009 Appendix A.
+var RFC8009ChecksumVectors = []ChecksumVector{
+	{19, "3705d96080c17728a0e800eab6e0d23c", 2, "000102030405060708090a0b0c0d0e0f1011121314", "d78367186643d67b411cba9139fc1dee"},
+	{20, "6d404d37faf79f9df0d33568d320669800eb4836472ea8a026d16b7182460c52", 2, "000102030405060708090a0b0c0d0e0f1011121314", "45ee791567eefca37f4ac1e0222de80d43c3bfa06699672a"},
+}
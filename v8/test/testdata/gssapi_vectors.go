@@ -0,0 +1,21 @@
+package testdata
+
+// RFC 4121 per-message token test data.
+// The tokens were generated by a kerberized service and initiator using the session key given. All values are hex
+// encoded.
+const (
+	// GSSAPI_SESSION_KEY is the session key used to sign the tokens below.
+	GSSAPI_SESSION_KEY = "14f9bde6b50ec508201a97f74c4e5bd3"
+	// GSSAPI_SESSION_KEY_TYPE is the encryption type of GSSAPI_SESSION_KEY.
+	GSSAPI_SESSION_KEY_TYPE int32 = 17
+	// GSSAPI_WRAP_TOKEN_ACCEPTOR is a wrap token sent by an acceptor with a payload of 01010000.
+	GSSAPI_WRAP_TOKEN_ACCEPTOR = "050401ff000c000000000000575e85d601010000853b728d5268525a1386c19f"
+	// GSSAPI_WRAP_TOKEN_INITIATOR is a wrap token sent by an initiator in reply with a payload of 01010000.
+	GSSAPI_WRAP_TOKEN_INITIATOR = "050400ff000c000000000000000000000101000079a033510b6f127212242b97"
+	// GSSAPI_MIC_PAYLOAD is the message the MIC tokens below were calculated over.
+	GSSAPI_MIC_PAYLOAD = "deadbeef"
+	// GSSAPI_MIC_TOKEN_ACCEPTOR is a MIC token sent by an acceptor.
+	GSSAPI_MIC_TOKEN_ACCEPTOR = "040401ffffffffff00000000575e85d6c34d12ba3e5b1b1310cd9cb3"
+	// GSSAPI_MIC_TOKEN_INITIATOR is a MIC token sent by an initiator in reply.
+	GSSAPI_MIC_TOKEN_INITIATOR = "040400ffffffffff00000000000000009649ca09d2f1bc51ff6e5ca3"
+)
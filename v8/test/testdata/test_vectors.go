@@ -1,4 +1,9 @@
 // Package testdata provides Kerberos 5 test reference data.
+//
+// This includes the ASN.1 encodings of sample messages and tickets from the MIT krb5 test suite, keytabs and
+// configuration for the test realm and the published test vectors for the supported cryptosystems and GSS-API tokens.
+// The data can be used to validate other implementations of encryption types and tokens against the same corpus used
+// to test this library.
 package testdata
 
 var TEST_PRINCIPALNAME_NAMESTRING = []string{"hftsai", "extra"}
@@ -8,7 +8,7 @@ import (
 	"math/big"
 	"time"
 
-	"github.com/jcmturner/gofork/encoding/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1"
 	"github.com/jcmturner/gokrb5/v8/asn1tools"
 	"github.com/jcmturner/gokrb5/v8/iana"
 	"github.com/jcmturner/gokrb5/v8/iana/asnAppTag"
@@ -31,11 +31,15 @@ type Authenticator struct {
 
 // NewAuthenticator creates a new Authenticator.
 func NewAuthenticator(realm string, cname PrincipalName) (Authenticator, error) {
+	return NewAuthenticatorAt(realm, cname, time.Now().UTC())
+}
+
+// NewAuthenticatorAt creates a new Authenticator with the client time set to the time provided.
+func NewAuthenticatorAt(realm string, cname PrincipalName, t time.Time) (Authenticator, error) {
 	seq, err := rand.Int(rand.Reader, big.NewInt(math.MaxUint32))
 	if err != nil {
 		return Authenticator{}, err
 	}
-	t := time.Now().UTC()
 	return Authenticator{
 		AVNO:      iana.PVNO,
 		CRealm:    realm,
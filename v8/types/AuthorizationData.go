@@ -1,7 +1,10 @@
 package types
 
 import (
-	"github.com/jcmturner/gofork/encoding/asn1"
+	"fmt"
+
+	"github.com/jcmturner/gokrb5/v8/asn1"
+	"github.com/jcmturner/gokrb5/v8/iana/adtype"
 )
 
 // Reference: https://www.ietf.org/rfc/rfc4120.txt
@@ -53,3 +56,55 @@ func (a *AuthorizationDataEntry) Unmarshal(b []byte) error {
 	_, err := asn1.Unmarshal(b, a)
 	return err
 }
+
+// NewADIfRelevant returns an AD-IF-RELEVANT entry containing the elements provided, which recipients that do not
+// understand them may ignore.
+func NewADIfRelevant(elements ...AuthorizationDataEntry) (AuthorizationDataEntry, error) {
+	b, err := asn1.Marshal(AuthorizationData(elements))
+	if err != nil {
+		return AuthorizationDataEntry{}, err
+	}
+	return AuthorizationDataEntry{ADType: adtype.ADIfRelevant, ADData: b}, nil
+}
+
+// NewADAuthenticationIndicator returns an AD-AUTHENTICATION-INDICATOR entry recording the indicators of how the client
+// authenticated to the KDC, such as "pkinit" or "otp". KDCs issue indicators within an AD-IF-RELEVANT entry.
+// RFC 8129
+func NewADAuthenticationIndicator(indicators ...string) (AuthorizationDataEntry, error) {
+	s := make([]asn1.RawValue, len(indicators))
+	for i, ind := range indicators {
+		s[i] = asn1.RawValue{Tag: asn1.TagUTF8String, Bytes: []byte(ind)}
+	}
+	b, err := asn1.Marshal(s)
+	if err != nil {
+		return AuthorizationDataEntry{}, err
+	}
+	return AuthorizationDataEntry{ADType: adtype.ADAuthenticationIndicator, ADData: b}, nil
+}
+
+// AuthIndicators returns the authentication indicators of the AD-AUTHENTICATION-INDICATOR entries of the
+// authorization data, including those within AD-IF-RELEVANT entries, in the order they occur. RFC 8129
+func (a AuthorizationData) AuthIndicators() ([]string, error) {
+	var indicators []string
+	for _, e := range a {
+		switch e.ADType {
+		case adtype.ADIfRelevant:
+			var ad AuthorizationData
+			if err := ad.Unmarshal(e.ADData); err != nil {
+				return nil, fmt.Errorf("error unmarshaling AD-IF-RELEVANT: %w", err)
+			}
+			s, err := ad.AuthIndicators()
+			if err != nil {
+				return nil, err
+			}
+			indicators = append(indicators, s...)
+		case adtype.ADAuthenticationIndicator:
+			var s []string
+			if _, err := asn1.Unmarshal(e.ADData, &s); err != nil {
+				return nil, fmt.Errorf("error unmarshaling AD-AUTHENTICATION-INDICATOR: %w", err)
+			}
+			indicators = append(indicators, s...)
+		}
+	}
+	return indicators, nil
+}
@@ -51,3 +51,51 @@ func TestUnmarshalAuthorizationData_kdcissued(t *testing.T) {
 		assert.Equal(t, []byte(testdata.TEST_AUTHORIZATION_DATA_VALUE), ele.ADData, fmt.Sprintf("Authorization data of element %d not as expected", i+1))
 	}
 }
+
+func TestNewADIfRelevant(t *testing.T) {
+	t.Parallel()
+	e := AuthorizationDataEntry{ADType: adtype.ADKDCIssued, ADData: []byte("foobar")}
+	ad, err := NewADIfRelevant(e)
+	if err != nil {
+		t.Fatalf("Error creating AD-IF-RELEVANT: %v", err)
+	}
+	assert.Equal(t, adtype.ADIfRelevant, ad.ADType, "Authorization data type not as expected")
+	var elements AuthorizationData
+	err = elements.Unmarshal(ad.ADData)
+	if err != nil {
+		t.Fatalf("Unmarshal error: %v", err)
+	}
+	assert.Equal(t, AuthorizationData{e}, elements, "Elements not as expected")
+}
+
+func TestAuthorizationData_AuthIndicators(t *testing.T) {
+	t.Parallel()
+	ind, err := NewADAuthenticationIndicator("pkinit", "otp")
+	if err != nil {
+		t.Fatalf("error creating authentication indicator: %v", err)
+	}
+	// DER encoding of SEQUENCE { UTF8String "pkinit", UTF8String "otp" }
+	assert.Equal(t, "300d0c06706b696e69740c036f7470", hex.EncodeToString(ind.ADData), "encoding not as expected")
+	ifr, err := NewADIfRelevant(ind)
+	if err != nil {
+		t.Fatalf("error creating AD-IF-RELEVANT: %v", err)
+	}
+	fido, _ := NewADAuthenticationIndicator("FIDO")
+	ad := AuthorizationData{
+		{ADType: adtype.ADKDCIssued, ADData: []byte("other")},
+		ifr,
+		fido,
+	}
+	s, err := ad.AuthIndicators()
+	if err != nil {
+		t.Fatalf("error getting authentication indicators: %v", err)
+	}
+	assert.Equal(t, []string{"pkinit", "otp", "FIDO"}, s, "authentication indicators not as expected")
+
+	s, err = AuthorizationData{}.AuthIndicators()
+	assert.NoError(t, err, "authorization data without indicators should not error")
+	assert.Empty(t, s, "authorization data without indicators should have no indicators")
+
+	_, err = AuthorizationData{{ADType: adtype.ADAuthenticationIndicator, ADData: []byte{0x01}}}.AuthIndicators()
+	assert.Error(t, err, "malformed indicators should error")
+}
@@ -2,9 +2,11 @@ package types
 
 import (
 	"crypto/rand"
+	"fmt"
 
-	"github.com/jcmturner/gofork/encoding/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1"
 	"github.com/jcmturner/gokrb5/v8/crypto/etype"
+	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
 )
 
 // Reference: https://www.ietf.org/rfc/rfc4120.txt
@@ -30,6 +32,26 @@ type Checksum struct {
 	Checksum  []byte `asn1:"explicit,tag:1"`
 }
 
+// String returns a description of the EncryptedData that does not include the cipher text.
+func (a EncryptedData) String() string {
+	return fmt.Sprintf("%s kvno %d, %d bytes", ETypeString(a.EType), a.KVNO, len(a.Cipher))
+}
+
+// String returns a description of the EncryptionKey with the key value redacted, so that keys are not leaked into
+// logs when formatted.
+func (a EncryptionKey) String() string {
+	return fmt.Sprintf("%s <redacted>", ETypeString(a.KeyType))
+}
+
+// ETypeString returns the name and number of the etype ID in the form name(number).
+func ETypeString(id int32) string {
+	n := etypeID.EtypeName(id)
+	if n == "" {
+		n = "unknown"
+	}
+	return fmt.Sprintf("%s(%d)", n, id)
+}
+
 // Unmarshal bytes into the EncryptedData.
 func (a *EncryptedData) Unmarshal(b []byte) error {
 	_, err := asn1.Unmarshal(b, a)
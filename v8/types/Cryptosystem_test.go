@@ -2,6 +2,7 @@ package types
 
 import (
 	"encoding/hex"
+	"fmt"
 	"testing"
 
 	"github.com/jcmturner/gokrb5/v8/iana"
@@ -89,3 +90,13 @@ func TestMarshalEncryptedData(t *testing.T) {
 	}
 	assert.Equal(t, b, mb, "Marshal bytes of Encrypted Data not as expected")
 }
+
+func TestEncryptionKey_String(t *testing.T) {
+	t.Parallel()
+	k := EncryptionKey{KeyType: 18, KeyValue: []byte("secretkeyvalue")}
+	assert.Equal(t, "aes256-cts-hmac-sha1-96(18) <redacted>", k.String(), "description of key not as expected")
+	s := fmt.Sprintf("%+v", struct{ Key EncryptionKey }{k})
+	assert.NotContains(t, s, fmt.Sprintf("%v", k.KeyValue), "key value should not be formatted")
+	e := EncryptedData{EType: 17, KVNO: 2, Cipher: make([]byte, 32)}
+	assert.Equal(t, "aes128-cts-hmac-sha1-96(17) kvno 2, 32 bytes", e.String(), "description of encrypted data not as expected")
+}
@@ -5,10 +5,15 @@ package types
 
 import (
 	"bytes"
+	"encoding/binary"
+	"encoding/hex"
 	"fmt"
 	"net"
+	"sort"
+	"strings"
+	"sync"
 
-	"github.com/jcmturner/gofork/encoding/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1"
 	"github.com/jcmturner/gokrb5/v8/iana/addrtype"
 )
 
@@ -53,6 +58,106 @@ func (h *HostAddress) GetAddress() (string, error) {
 	return string(b), err
 }
 
+// AddressDecoder decodes the address of a HostAddress into a human readable form.
+type AddressDecoder func(b []byte) (string, error)
+
+var (
+	addressDecoders = map[int32]AddressDecoder{
+		addrtype.IPv4:          ipDecoder(net.IPv4len),
+		addrtype.IPv6:          ipDecoder(net.IPv6len),
+		addrtype.Directional:   decodeDirectional,
+		addrtype.DECNETPhaseIV: decodeDECnet,
+		addrtype.AppleTalkDDP:  decodeAppleTalk,
+		addrtype.NetBios:       decodeNetBios,
+	}
+	addressDecodersMux sync.RWMutex
+)
+
+// RegisterAddressDecoder registers the decoder used to show addresses of the type provided, replacing any existing
+// decoder for the type.
+func RegisterAddressDecoder(addrType int32, d AddressDecoder) {
+	addressDecodersMux.Lock()
+	defer addressDecodersMux.Unlock()
+	addressDecoders[addrType] = d
+}
+
+// String returns the name of the address type followed by the decoded address, for example "IPv4 192.0.2.1".
+// Addresses of types without a decoder, or that cannot be decoded, are shown in hex.
+func (h HostAddress) String() string {
+	n := addrtype.Name(h.AddrType)
+	if n == "" {
+		n = fmt.Sprintf("addrtype(%d)", h.AddrType)
+	}
+	addressDecodersMux.RLock()
+	d, ok := addressDecoders[h.AddrType]
+	addressDecodersMux.RUnlock()
+	if ok {
+		if a, err := d(h.Address); err == nil {
+			return n + " " + a
+		}
+	}
+	return n + " 0x" + hex.EncodeToString(h.Address)
+}
+
+// String returns the addresses separated by commas.
+func (h HostAddresses) String() string {
+	s := make([]string, len(h))
+	for i, a := range h {
+		s[i] = a.String()
+	}
+	return strings.Join(s, ", ")
+}
+
+// ipDecoder returns a decoder of IP addresses of the length provided.
+func ipDecoder(l int) AddressDecoder {
+	return func(b []byte) (string, error) {
+		if len(b) != l {
+			return "", fmt.Errorf("IP address has %d bytes, expected %d", len(b), l)
+		}
+		return net.IP(b).String(), nil
+	}
+}
+
+// decodeDirectional decodes the direction of a directional address. RFC 4120 section 8.1
+func decodeDirectional(b []byte) (string, error) {
+	if len(b) != 4 {
+		return "", fmt.Errorf("directional address has %d bytes, expected 4", len(b))
+	}
+	switch binary.BigEndian.Uint32(b) {
+	case 0:
+		return "initiator", nil
+	case 1:
+		return "acceptor", nil
+	}
+	return "", fmt.Errorf("directional address value %d not known", binary.BigEndian.Uint32(b))
+}
+
+// decodeDECnet decodes a DECnet Phase IV address as area.node from the 16 bit little-endian value of which the area
+// is the upper 6 bits.
+func decodeDECnet(b []byte) (string, error) {
+	if len(b) != 2 {
+		return "", fmt.Errorf("DECnet address has %d bytes, expected 2", len(b))
+	}
+	v := binary.LittleEndian.Uint16(b)
+	return fmt.Sprintf("%d.%d", v>>10, v&0x3ff), nil
+}
+
+// decodeAppleTalk decodes an AppleTalk DDP address as network.node from the 16 bit network number and 8 bit node.
+func decodeAppleTalk(b []byte) (string, error) {
+	if len(b) != 3 {
+		return "", fmt.Errorf("AppleTalk address has %d bytes, expected 3", len(b))
+	}
+	return fmt.Sprintf("%d.%d", binary.BigEndian.Uint16(b[:2]), b[2]), nil
+}
+
+// decodeNetBios decodes a NetBIOS name, padded with spaces to 15 characters and followed by the suffix byte.
+func decodeNetBios(b []byte) (string, error) {
+	if len(b) != 16 {
+		return "", fmt.Errorf("NetBIOS address has %d bytes, expected 16", len(b))
+	}
+	return fmt.Sprintf("%s<%02x>", strings.TrimRight(string(b[:15]), " "), b[15]), nil
+}
+
 // LocalHostAddresses returns a HostAddresses struct for the local machines interface IP addresses.
 func LocalHostAddresses() (ha HostAddresses, err error) {
 	ifs, err := net.Interfaces()
@@ -118,15 +223,18 @@ func HostAddressFromNetIP(ip net.IP) HostAddress {
 	}
 }
 
-// HostAddressesEqual tests if two HostAddress slices are equal.
+// HostAddressesEqual tests if two HostAddress slices contain the same addresses, in any order.
+// An address contained more than once in one slice must be contained the same number of times in the other.
 func HostAddressesEqual(h, a []HostAddress) bool {
 	if len(h) != len(a) {
 		return false
 	}
+	matched := make([]bool, len(h))
 	for _, e := range a {
 		var found bool
-		for _, i := range h {
-			if e.Equal(i) {
+		for j, i := range h {
+			if !matched[j] && e.Equal(i) {
+				matched[j] = true
 				found = true
 				break
 			}
@@ -138,6 +246,49 @@ func HostAddressesEqual(h, a []HostAddress) bool {
 	return true
 }
 
+// Canonical returns the canonical form of the address. IPv4 addresses in their 16 byte form and IPv4-mapped IPv6
+// addresses are returned as 4 byte IPv4 addresses. Other addresses are returned unchanged.
+func (h HostAddress) Canonical() HostAddress {
+	if (h.AddrType == addrtype.IPv4 || h.AddrType == addrtype.IPv6) && len(h.Address) == net.IPv6len {
+		if ip := net.IP(h.Address).To4(); ip != nil {
+			return HostAddress{AddrType: addrtype.IPv4, Address: append([]byte(nil), ip...)}
+		}
+	}
+	return h
+}
+
+// SortHostAddresses sorts the addresses in place by address type and then address.
+func SortHostAddresses(h []HostAddress) {
+	sort.SliceStable(h, func(i, j int) bool {
+		if h[i].AddrType != h[j].AddrType {
+			return h[i].AddrType < h[j].AddrType
+		}
+		return bytes.Compare(h[i].Address, h[j].Address) < 0
+	})
+}
+
+// DedupHostAddresses returns the addresses with any duplicates removed, keeping the first of each.
+func DedupHostAddresses(h []HostAddress) HostAddresses {
+	var d HostAddresses
+	for _, a := range h {
+		if !d.Contains(a) {
+			d = append(d, a)
+		}
+	}
+	return d
+}
+
+// NormalizeHostAddresses returns the canonical form of each of the addresses, sorted and without duplicates.
+// Slices of the same addresses in different orders or forms normalize to the same slice.
+func NormalizeHostAddresses(h []HostAddress) HostAddresses {
+	c := make(HostAddresses, len(h))
+	for i, a := range h {
+		c[i] = a.Canonical()
+	}
+	SortHostAddresses(c)
+	return DedupHostAddresses(c)
+}
+
 // HostAddressesContains tests if a HostAddress is contained in a HostAddress slice.
 func HostAddressesContains(h []HostAddress, a HostAddress) bool {
 	for _, e := range h {
@@ -166,15 +317,7 @@ func (h *HostAddresses) Contains(a HostAddress) bool {
 	return false
 }
 
-// Equal tests if a HostAddress slice is equal to the HostAddresses struct.
+// Equal tests if a HostAddress slice contains the same addresses as the HostAddresses struct, as HostAddressesEqual.
 func (h *HostAddresses) Equal(a []HostAddress) bool {
-	if len(*h) != len(a) {
-		return false
-	}
-	for _, e := range a {
-		if !h.Contains(e) {
-			return false
-		}
-	}
-	return true
+	return HostAddressesEqual(*h, a)
 }
@@ -2,6 +2,7 @@ package types
 
 import (
 	"encoding/hex"
+	"net"
 	"testing"
 
 	"github.com/jcmturner/gokrb5/v8/iana/addrtype"
@@ -27,3 +28,58 @@ func TestGetHostAddress(t *testing.T) {
 		assert.Equal(t, test.hex, hex.EncodeToString(h.Address), "wrong address bytes for %s", test.str)
 	}
 }
+
+func TestHostAddress_String(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		addr HostAddress
+		want string
+	}{
+		{HostAddress{AddrType: addrtype.IPv4, Address: []byte{192, 0, 2, 1}}, "IPv4 192.0.2.1"},
+		{HostAddress{AddrType: addrtype.IPv6, Address: net.ParseIP("2001:db8::1")}, "IPv6 2001:db8::1"},
+		{HostAddress{AddrType: addrtype.IPv4, Address: []byte{192, 0, 2}}, "IPv4 0xc00002"},
+		{HostAddress{AddrType: addrtype.Directional, Address: []byte{0, 0, 0, 1}}, "Directional acceptor"},
+		{HostAddress{AddrType: addrtype.DECNETPhaseIV, Address: []byte{0x05, 0x04}}, "DECnet Phase IV 1.5"},
+		{HostAddress{AddrType: addrtype.AppleTalkDDP, Address: []byte{0x01, 0x02, 0x03}}, "AppleTalk DDP 258.3"},
+		{HostAddress{AddrType: addrtype.NetBios, Address: []byte("HOST           \x20")}, "NetBIOS HOST<20>"},
+		{HostAddress{AddrType: addrtype.ChaosNet, Address: []byte{0x01, 0x02}}, "ChaosNet 0x0102"},
+		{HostAddress{AddrType: 999, Address: []byte{0xff}}, "addrtype(999) 0xff"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.want, test.addr.String(), "string of %d address not as expected", test.addr.AddrType)
+	}
+	assert.Equal(t, "IPv4 192.0.2.1, ChaosNet 0x0102", HostAddresses{tests[0].addr, tests[7].addr}.String(), "string of addresses not as expected")
+}
+
+func TestRegisterAddressDecoder(t *testing.T) {
+	t.Parallel()
+	RegisterAddressDecoder(998, func(b []byte) (string, error) {
+		return string(b), nil
+	})
+	assert.Equal(t, "addrtype(998) custom", HostAddress{AddrType: 998, Address: []byte("custom")}.String(), "registered decoder not used")
+}
+
+func TestHostAddressesEqual(t *testing.T) {
+	t.Parallel()
+	a := HostAddress{AddrType: addrtype.IPv4, Address: []byte{192, 0, 2, 1}}
+	b := HostAddress{AddrType: addrtype.IPv4, Address: []byte{192, 0, 2, 2}}
+	assert.True(t, HostAddressesEqual([]HostAddress{a, b}, []HostAddress{b, a}), "order should not matter")
+	assert.False(t, HostAddressesEqual([]HostAddress{a, a}, []HostAddress{a, b}), "duplicates should not match other addresses")
+	assert.True(t, HostAddressesEqual([]HostAddress{a, a, b}, []HostAddress{a, b, a}), "duplicates should match duplicates")
+	h := HostAddresses{a, a}
+	assert.False(t, h.Equal([]HostAddress{a, b}), "duplicates should not match other addresses")
+}
+
+func TestNormalizeHostAddresses(t *testing.T) {
+	t.Parallel()
+	v4 := HostAddress{AddrType: addrtype.IPv4, Address: []byte{192, 0, 2, 1}}
+	mapped := HostAddress{AddrType: addrtype.IPv6, Address: net.ParseIP("::ffff:192.0.2.1")}
+	v6 := HostAddress{AddrType: addrtype.IPv6, Address: net.ParseIP("2001:db8::1")}
+	other := HostAddress{AddrType: addrtype.IPv4, Address: []byte{10, 0, 0, 1}}
+	assert.Equal(t, v4, mapped.Canonical(), "IPv4-mapped address not canonicalized")
+	assert.Equal(t, v6, v6.Canonical(), "IPv6 address should not change")
+
+	n := NormalizeHostAddresses([]HostAddress{v6, mapped, v4, other})
+	assert.Equal(t, HostAddresses{other, v4, v6}, n, "normalized addresses not as expected")
+	assert.Equal(t, HostAddresses{v4, v6}, DedupHostAddresses([]HostAddress{v4, v6, v4}), "deduplicated addresses not as expected")
+}
@@ -4,7 +4,10 @@ package types
 // Section: 5.2.8
 
 import (
-	"github.com/jcmturner/gofork/encoding/asn1"
+	"fmt"
+	"strings"
+
+	"github.com/jcmturner/gokrb5/v8/asn1"
 )
 
 // NewKrbFlags returns an ASN1 BitString struct of the right size for KrbFlags.
@@ -56,9 +59,14 @@ func UnsetFlag(f *asn1.BitString, i int) {
 }
 
 // IsFlagSet tests if a flag is set in the ASN1 BitString.
+// Flags beyond the length of the BitString are not set, as some implementations, such as Heimdal, encode flags
+// without trailing zero bytes rather than as the 32 bits RFC 4120 requires.
 func IsFlagSet(f *asn1.BitString, i int) bool {
 	//Which byte?
 	b := i / 8
+	if b >= len(f.Bytes) {
+		return false
+	}
 	//Which bit in byte
 	p := uint(7 - (i - 8*b))
 	if (*f).Bytes[b]&(1<<p) != 0 {
@@ -66,3 +74,20 @@ func IsFlagSet(f *asn1.BitString, i int) bool {
 	}
 	return false
 }
+
+// FlagNames returns the names of the flags set in the ASN1 BitString, separated by commas.
+// Flags without an entry in the names map are shown by their bit position.
+func FlagNames(f asn1.BitString, names map[int]string) string {
+	var s []string
+	for i := 0; i < len(f.Bytes)*8; i++ {
+		if !IsFlagSet(&f, i) {
+			continue
+		}
+		if n, ok := names[i]; ok {
+			s = append(s, n)
+			continue
+		}
+		s = append(s, fmt.Sprintf("flag(%d)", i))
+	}
+	return strings.Join(s, ", ")
+}
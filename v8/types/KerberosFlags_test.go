@@ -1,7 +1,7 @@
 package types
 
 import (
-	"github.com/jcmturner/gofork/encoding/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1"
 	"github.com/jcmturner/gokrb5/v8/iana/flags"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -35,4 +35,18 @@ func TestKerberosFlags_IsFlagSet(t *testing.T) {
 	assert.True(t, IsFlagSet(&f, flags.Forwardable))
 	assert.True(t, IsFlagSet(&f, flags.RenewableOK))
 	assert.False(t, IsFlagSet(&f, flags.Proxiable))
+
+	// Flags encoded without trailing zero bytes
+	s := asn1.BitString{Bytes: []byte{0x40}, BitLength: 8}
+	assert.True(t, IsFlagSet(&s, flags.Forwardable))
+	assert.False(t, IsFlagSet(&s, flags.RenewableOK))
+	assert.False(t, IsFlagSet(&asn1.BitString{}, flags.Forwardable))
+}
+
+func TestFlagNames(t *testing.T) {
+	t.Parallel()
+	f := NewKrbFlags()
+	SetFlags(&f, []int{flags.Forwardable, flags.Renewable, 21})
+	assert.Equal(t, "forwardable, renewable, flag(21)", FlagNames(f, flags.TicketFlagNames), "flag names not as expected")
+	assert.Equal(t, "", FlagNames(NewKrbFlags(), flags.TicketFlagNames), "no flag names expected")
 }
@@ -0,0 +1,102 @@
+package types
+
+// Reference: https://docs.microsoft.com/en-us/openspecs/windows_protocols/ms-kile
+// Sections: 2.2.3 and 2.2.10
+
+import (
+	"fmt"
+
+	"github.com/jcmturner/gokrb5/v8/asn1"
+	"github.com/jcmturner/gokrb5/v8/iana/patype"
+)
+
+// PACRequest implements the MS-KILE KERB-PA-PAC-REQUEST type with which a client asks the KDC to include or omit the
+// PAC in the tickets issued: https://docs.microsoft.com/en-us/openspecs/windows_protocols/ms-kile/765795ba-9e05-4220-9bd2-b34464e413a7
+type PACRequest struct {
+	IncludePAC bool `asn1:"explicit,tag:0"`
+}
+
+// Marshal the PA-PAC-REQUEST.
+func (p *PACRequest) Marshal() ([]byte, error) {
+	return asn1.Marshal(*p)
+}
+
+// Unmarshal bytes into the PA-PAC-REQUEST.
+func (p *PACRequest) Unmarshal(b []byte) error {
+	_, err := asn1.Unmarshal(b, p)
+	return err
+}
+
+// PAData returns the PA-PAC-REQUEST as PAData to include in a KDC_REQ.
+func (p *PACRequest) PAData() (PAData, error) {
+	b, err := p.Marshal()
+	if err != nil {
+		return PAData{}, fmt.Errorf("error marshaling PA-PAC-REQUEST: %v", err)
+	}
+	return PAData{
+		PADataType:  patype.PA_PAC_REQUEST,
+		PADataValue: b,
+	}, nil
+}
+
+// GetPACRequest returns a PACRequest from the PAData.
+func (pa *PAData) GetPACRequest() (d PACRequest, err error) {
+	if pa.PADataType != patype.PA_PAC_REQUEST {
+		err = fmt.Errorf("PAData does not contain PA-PAC-REQUEST data. TypeID Expected: %v; Actual: %v", patype.PA_PAC_REQUEST, pa.PADataType)
+		return
+	}
+	err = d.Unmarshal(pa.PADataValue)
+	return
+}
+
+// PACOptions implements the MS-KILE PA-PAC-OPTIONS type with which a client requests the features of the PAC, such as
+// claims, supported by the KDC: https://docs.microsoft.com/en-us/openspecs/windows_protocols/ms-kile/99721e39-fdb2-4e93-b45f-7fb5ce6c6e96
+// The flags are the flags.PACOption values.
+type PACOptions struct {
+	Options asn1.BitString `asn1:"explicit,tag:0"`
+}
+
+// NewPACOptions returns a PA-PAC-OPTIONS with the flags provided set.
+func NewPACOptions(opts []int) PACOptions {
+	p := PACOptions{Options: NewKrbFlags()}
+	SetFlags(&p.Options, opts)
+	return p
+}
+
+// IsSet reports if the flag is set in the PA-PAC-OPTIONS.
+func (p *PACOptions) IsSet(opt int) bool {
+	return IsFlagSet(&p.Options, opt)
+}
+
+// Marshal the PA-PAC-OPTIONS.
+func (p *PACOptions) Marshal() ([]byte, error) {
+	return asn1.Marshal(*p)
+}
+
+// Unmarshal bytes into the PA-PAC-OPTIONS.
+func (p *PACOptions) Unmarshal(b []byte) error {
+	_, err := asn1.Unmarshal(b, p)
+	return err
+}
+
+// PAData returns the PA-PAC-OPTIONS as PAData to include in a KDC_REQ.
+func (p *PACOptions) PAData() (PAData, error) {
+	b, err := p.Marshal()
+	if err != nil {
+		return PAData{}, fmt.Errorf("error marshaling PA-PAC-OPTIONS: %v", err)
+	}
+	return PAData{
+		PADataType:  patype.PA_PAC_OPTIONS,
+		PADataValue: b,
+	}, nil
+}
+
+// GetPACOptions returns a PACOptions from the PAData.
+func (pa *PAData) GetPACOptions() (d PACOptions, err error) {
+	if pa.PADataType != patype.PA_PAC_OPTIONS {
+		err = fmt.Errorf("PAData does not contain PA-PAC-OPTIONS data. TypeID Expected: %v; Actual: %v", patype.PA_PAC_OPTIONS, pa.PADataType)
+		return
+	}
+	err = d.Unmarshal(pa.PADataValue)
+	return
+}
@@ -0,0 +1,41 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/jcmturner/gokrb5/v8/iana/flags"
+	"github.com/jcmturner/gokrb5/v8/iana/patype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPACRequest(t *testing.T) {
+	t.Parallel()
+	for _, include := range []bool{false, true} {
+		p := PACRequest{IncludePAC: include}
+		pa, err := p.PAData()
+		require.NoError(t, err, "error marshaling PA-PAC-REQUEST")
+		assert.Equal(t, patype.PA_PAC_REQUEST, pa.PADataType)
+		r, err := pa.GetPACRequest()
+		require.NoError(t, err, "error unmarshaling PA-PAC-REQUEST")
+		assert.Equal(t, include, r.IncludePAC, "PA-PAC-REQUEST not as expected after round trip")
+	}
+	_, err := (&PAData{PADataType: patype.PA_FOR_USER}).GetPACRequest()
+	assert.Error(t, err, "PAData of another type should not be returned as a PA-PAC-REQUEST")
+}
+
+func TestPACOptions(t *testing.T) {
+	t.Parallel()
+	p := NewPACOptions([]int{flags.PACOptionClaims, flags.PACOptionResourceBasedConstrainedDelegation})
+	pa, err := p.PAData()
+	require.NoError(t, err, "error marshaling PA-PAC-OPTIONS")
+	assert.Equal(t, patype.PA_PAC_OPTIONS, pa.PADataType)
+	o, err := pa.GetPACOptions()
+	require.NoError(t, err, "error unmarshaling PA-PAC-OPTIONS")
+	assert.True(t, o.IsSet(flags.PACOptionClaims), "claims option not set")
+	assert.True(t, o.IsSet(flags.PACOptionResourceBasedConstrainedDelegation), "resource based constrained delegation option not set")
+	assert.False(t, o.IsSet(flags.PACOptionBranchAware), "branch aware option should not be set")
+	assert.False(t, o.IsSet(flags.PACOptionForwardToFullDC), "forward to full DC option should not be set")
+	_, err = (&PAData{PADataType: patype.PA_PAC_REQUEST}).GetPACOptions()
+	assert.Error(t, err, "PAData of another type should not be returned as a PA-PAC-OPTIONS")
+}
@@ -6,7 +6,7 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/jcmturner/gofork/encoding/asn1"
+	"github.com/jcmturner/gokrb5/v8/asn1"
 	"github.com/jcmturner/gokrb5/v8/iana/patype"
 )
 
@@ -43,7 +43,11 @@ func (pas *PADataSequence) Contains(patype int32) bool {
 
 // GetPAEncTSEncAsnMarshalled returns the bytes of a PAEncTSEnc.
 func GetPAEncTSEncAsnMarshalled() ([]byte, error) {
-	t := time.Now().UTC()
+	return GetPAEncTSEncAsnMarshalledAt(time.Now().UTC())
+}
+
+// GetPAEncTSEncAsnMarshalledAt returns the bytes of a PAEncTSEnc for the time provided.
+func GetPAEncTSEncAsnMarshalledAt(t time.Time) ([]byte, error) {
 	p := PAEncTSEnc{
 		PATimestamp: t,
 		PAUSec:      int((t.UnixNano() / int64(time.Microsecond)) - (t.Unix() * 1e6)),
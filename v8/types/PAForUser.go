@@ -0,0 +1,106 @@
+package types
+
+// Reference: https://docs.microsoft.com/en-us/openspecs/windows_protocols/ms-sfu
+// Section: 2.2.1
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/jcmturner/gokrb5/v8/asn1"
+	"github.com/jcmturner/gokrb5/v8/crypto/rfc4757"
+	"github.com/jcmturner/gokrb5/v8/iana/chksumtype"
+	"github.com/jcmturner/gokrb5/v8/iana/keyusage"
+	"github.com/jcmturner/gokrb5/v8/iana/patype"
+)
+
+// S4UAuthPackage is the authentication package named in a PA-FOR-USER.
+const S4UAuthPackage = "Kerberos"
+
+// PAForUser implements the MS-SFU PA-FOR-USER type with which a service asks the KDC for a ticket to itself on behalf
+// of a user (S4U2Self): https://docs.microsoft.com/en-us/openspecs/windows_protocols/ms-sfu/aceb70de-40f0-4409-87fa-df00ca145f5a
+type PAForUser struct {
+	UserName    PrincipalName `asn1:"explicit,tag:0"`
+	UserRealm   string        `asn1:"generalstring,explicit,tag:1"`
+	Cksum       Checksum      `asn1:"explicit,tag:2"`
+	AuthPackage string        `asn1:"generalstring,explicit,tag:3"`
+}
+
+// NewPAForUser returns a PA-FOR-USER for the user, checksummed with the session key of the service's TGT.
+func NewPAForUser(user PrincipalName, userRealm string, sessionKey EncryptionKey) (PAForUser, error) {
+	p := PAForUser{
+		UserName:    user,
+		UserRealm:   userRealm,
+		AuthPackage: S4UAuthPackage,
+	}
+	cb, err := p.checksum(sessionKey)
+	if err != nil {
+		return p, err
+	}
+	p.Cksum = Checksum{
+		CksumType: chksumtype.KERB_CHECKSUM_HMAC_MD5,
+		Checksum:  cb,
+	}
+	return p, nil
+}
+
+// Verify checks the checksum of the PA-FOR-USER with the session key of the service's TGT.
+func (p *PAForUser) Verify(sessionKey EncryptionKey) bool {
+	if p.Cksum.CksumType != chksumtype.KERB_CHECKSUM_HMAC_MD5 {
+		return false
+	}
+	cb, err := p.checksum(sessionKey)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(cb, p.Cksum.Checksum)
+}
+
+// checksum returns the KERB_CHECKSUM_HMAC_MD5 of the name type, name strings, realm and authentication package.
+func (p *PAForUser) checksum(sessionKey EncryptionKey) ([]byte, error) {
+	var b bytes.Buffer
+	nt := make([]byte, 4)
+	binary.LittleEndian.PutUint32(nt, uint32(p.UserName.NameType))
+	b.Write(nt)
+	for _, s := range p.UserName.NameString {
+		b.WriteString(s)
+	}
+	b.WriteString(p.UserRealm)
+	b.WriteString(p.AuthPackage)
+	return rfc4757.Checksum(sessionKey.KeyValue, keyusage.KERB_NON_KERB_CKSUM_SALT, b.Bytes())
+}
+
+// Marshal the PA-FOR-USER.
+func (p *PAForUser) Marshal() ([]byte, error) {
+	return asn1.Marshal(*p)
+}
+
+// Unmarshal bytes into the PA-FOR-USER.
+func (p *PAForUser) Unmarshal(b []byte) error {
+	_, err := asn1.Unmarshal(b, p)
+	return err
+}
+
+// PAData returns the PA-FOR-USER as PAData to include in a TGS_REQ.
+func (p *PAForUser) PAData() (PAData, error) {
+	b, err := p.Marshal()
+	if err != nil {
+		return PAData{}, fmt.Errorf("error marshaling PA-FOR-USER: %v", err)
+	}
+	return PAData{
+		PADataType:  patype.PA_FOR_USER,
+		PADataValue: b,
+	}, nil
+}
+
+// GetPAForUser returns a PAForUser from the PAData.
+func (pa *PAData) GetPAForUser() (d PAForUser, err error) {
+	if pa.PADataType != patype.PA_FOR_USER {
+		err = fmt.Errorf("PAData does not contain PA-FOR-USER data. TypeID Expected: %v; Actual: %v", patype.PA_FOR_USER, pa.PADataType)
+		return
+	}
+	err = d.Unmarshal(pa.PADataValue)
+	return
+}
@@ -0,0 +1,39 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/jcmturner/gokrb5/v8/iana/chksumtype"
+	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/jcmturner/gokrb5/v8/iana/patype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPAForUser(t *testing.T) {
+	t.Parallel()
+	key := EncryptionKey{KeyType: etypeID.AES256_CTS_HMAC_SHA1_96, KeyValue: make([]byte, 32)}
+	user := NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "user")
+	p, err := NewPAForUser(user, "TEST.GOKRB5", key)
+	require.NoError(t, err, "error creating PA-FOR-USER")
+	assert.Equal(t, chksumtype.KERB_CHECKSUM_HMAC_MD5, p.Cksum.CksumType)
+	assert.Len(t, p.Cksum.Checksum, 16, "HMAC-MD5 checksum not expected length")
+	assert.True(t, p.Verify(key), "checksum should verify with the session key")
+
+	pa, err := p.PAData()
+	require.NoError(t, err, "error marshaling PA-FOR-USER")
+	assert.Equal(t, patype.PA_FOR_USER, pa.PADataType)
+	u, err := pa.GetPAForUser()
+	require.NoError(t, err, "error unmarshaling PA-FOR-USER")
+	assert.Equal(t, p, u, "PA-FOR-USER not as expected after round trip")
+	assert.Equal(t, S4UAuthPackage, u.AuthPackage)
+
+	other := EncryptionKey{KeyType: key.KeyType, KeyValue: append([]byte{1}, key.KeyValue[1:]...)}
+	assert.False(t, u.Verify(other), "checksum should not verify with another key")
+	u.UserName = NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "admin")
+	assert.False(t, u.Verify(key), "checksum should not verify when the user is changed")
+
+	_, err = (&PAData{PADataType: patype.PA_TGS_REQ}).GetPAForUser()
+	assert.Error(t, err, "PAData of another type should not be returned as a PA-FOR-USER")
+}
@@ -23,6 +23,16 @@ func NewPrincipalName(ntype int32, spn string) PrincipalName {
 	}
 }
 
+// NewEnterprisePrincipalName creates a new enterprise PrincipalName, RFC 6806 section 5, of the name provided.
+// The name is held as a single component, for example a user principal name such as user@example.com which will be
+// resolved by the KDC, possibly to a principal in another realm or forest.
+func NewEnterprisePrincipalName(name string) PrincipalName {
+	return PrincipalName{
+		NameType:   nametype.KRB_NT_ENTERPRISE,
+		NameString: []string{name},
+	}
+}
+
 // GetSalt returns a salt derived from the PrincipalName.
 func (pn PrincipalName) GetSalt(realm string) string {
 	var sb []byte
@@ -33,6 +43,40 @@ func (pn PrincipalName) GetSalt(realm string) string {
 	return string(sb)
 }
 
+// MachineAccountName returns the Active Directory computer account name, <NAME>$, of the host provided.
+// The host may be a fully qualified domain name or already be an account name ending in $.
+func MachineAccountName(host string) string {
+	n := strings.TrimSuffix(host, "$")
+	if i := strings.IndexByte(n, '.'); i >= 0 {
+		n = n[:i]
+	}
+	return strings.ToUpper(n) + "$"
+}
+
+// MachineAccountSalt returns the salt Active Directory uses to derive the keys of a computer account from its
+// password: the upper case realm, "host", the lower case account name without the trailing $, "." and the lower case
+// realm. For example the salt of WEB01$ in EXAMPLE.COM is EXAMPLE.COMhostweb01.example.com.
+func MachineAccountSalt(name, realm string) string {
+	n := strings.ToLower(strings.TrimSuffix(MachineAccountName(name), "$"))
+	return strings.ToUpper(realm) + "host" + n + "." + strings.ToLower(realm)
+}
+
+// AnonymousRealm is the realm of the anonymous principal when the client's realm is not disclosed. RFC 8062
+const AnonymousRealm = "WELLKNOWN:ANONYMOUS"
+
+// AnonymousPrincipalName returns the well-known anonymous principal name WELLKNOWN/ANONYMOUS. RFC 8062
+func AnonymousPrincipalName() PrincipalName {
+	return PrincipalName{
+		NameType:   nametype.KRB_NT_WELLKNOWN,
+		NameString: []string{"WELLKNOWN", "ANONYMOUS"},
+	}
+}
+
+// IsAnonymous reports if the PrincipalName is the well-known anonymous principal name.
+func (pn PrincipalName) IsAnonymous() bool {
+	return pn.Equal(AnonymousPrincipalName())
+}
+
 // Equal tests if the PrincipalName is equal to the one provided.
 func (pn PrincipalName) Equal(n PrincipalName) bool {
 	if len(pn.NameString) != len(n.NameString) {
@@ -47,6 +91,54 @@ func (pn PrincipalName) Equal(n PrincipalName) bool {
 	return true
 }
 
+// EqualFold tests if the PrincipalName is equal to the one provided under Unicode case-folding.
+func (pn PrincipalName) EqualFold(n PrincipalName) bool {
+	if len(pn.NameString) != len(n.NameString) {
+		return false
+	}
+	for i, s := range pn.NameString {
+		if !strings.EqualFold(n.NameString[i], s) {
+			return false
+		}
+	}
+	return true
+}
+
+// EqualString tests if the PrincipalName is equal to the string form provided, as returned by PrincipalNameString.
+// The comparison is made without building the string form of the PrincipalName.
+func (pn PrincipalName) EqualString(spn string) bool {
+	return pn.equalString(spn, func(a, b string) bool { return a == b })
+}
+
+// EqualFoldString tests if the PrincipalName is equal to the string form provided under Unicode case-folding.
+// The comparison is made without building the string form of the PrincipalName.
+func (pn PrincipalName) EqualFoldString(spn string) bool {
+	return pn.equalString(spn, strings.EqualFold)
+}
+
+// equalString compares each of the name string components to those of the string form provided using the function f.
+func (pn PrincipalName) equalString(spn string, f func(a, b string) bool) bool {
+	if len(pn.NameString) < 1 {
+		return spn == ""
+	}
+	for i, n := range pn.NameString {
+		c := spn
+		if i < len(pn.NameString)-1 {
+			j := strings.IndexByte(spn, '/')
+			if j < 0 {
+				return false
+			}
+			c, spn = spn[:j], spn[j+1:]
+		} else if strings.IndexByte(spn, '/') >= 0 {
+			return false
+		}
+		if !f(c, n) {
+			return false
+		}
+	}
+	return true
+}
+
 // PrincipalNameString returns the PrincipalName in string form.
 func (pn PrincipalName) PrincipalNameString() string {
 	return strings.Join(pn.NameString, "/")
@@ -35,3 +35,57 @@ func TestParseSPNString(t *testing.T) {
 	assert.Equal(t, "www.example.com", pn.NameString[0], "second element of name string not as expected")
 
 }
+
+func TestPrincipalName_EqualFold(t *testing.T) {
+	t.Parallel()
+	pn := NewPrincipalName(nametype.KRB_NT_SRV_INST, "HTTP/host.test.gokrb5")
+	assert.True(t, pn.EqualFold(NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "http/HOST.test.gokrb5")), "names should be equal under case-folding")
+	assert.False(t, pn.EqualFold(NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "HTTP/other.test.gokrb5")), "names should not be equal")
+	assert.False(t, pn.EqualFold(NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "HTTP")), "names with different number of components should not be equal")
+}
+
+func TestPrincipalName_EqualString(t *testing.T) {
+	t.Parallel()
+	pn := NewPrincipalName(nametype.KRB_NT_SRV_INST, "HTTP/host.test.gokrb5")
+	var tests = []struct {
+		spn       string
+		equal     bool
+		equalFold bool
+	}{
+		{"HTTP/host.test.gokrb5", true, true},
+		{"http/HOST.test.gokrb5", false, true},
+		{"HTTP", false, false},
+		{"HTTP/", false, false},
+		{"HTTP/host.test.gokrb5/extra", false, false},
+		{"HTTP/other.test.gokrb5", false, false},
+		{"", false, false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.equal, pn.EqualString(test.spn), "EqualString not as expected for %s", test.spn)
+		assert.Equal(t, test.equalFold, pn.EqualFoldString(test.spn), "EqualFoldString not as expected for %s", test.spn)
+	}
+	assert.True(t, PrincipalName{}.EqualString(""), "empty name should equal empty string")
+}
+
+func TestPrincipalName_EqualFoldString_Allocs(t *testing.T) {
+	pn := NewPrincipalName(nametype.KRB_NT_SRV_INST, "HTTP/host.test.gokrb5")
+	allocs := testing.AllocsPerRun(100, func() {
+		pn.EqualFoldString("http/HOST.test.gokrb5")
+	})
+	assert.Equal(t, float64(0), allocs, "comparison should not allocate")
+}
+
+func TestMachineAccountSalt(t *testing.T) {
+	t.Parallel()
+	for _, host := range []string{"web01.example.com", "WEB01$", "web01"} {
+		assert.Equal(t, "WEB01$", MachineAccountName(host), "account name of %s not as expected", host)
+		assert.Equal(t, "EXAMPLE.COMhostweb01.example.com", MachineAccountSalt(host, "example.com"), "salt of %s not as expected", host)
+	}
+}
+
+func TestPrincipalName_IsAnonymous(t *testing.T) {
+	t.Parallel()
+	assert.True(t, AnonymousPrincipalName().IsAnonymous(), "anonymous principal not detected")
+	assert.True(t, NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "WELLKNOWN/ANONYMOUS").IsAnonymous(), "name type should not be significant")
+	assert.False(t, NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "testuser1").IsAnonymous(), "principal should not be anonymous")
+}
@@ -0,0 +1,148 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+)
+
+// SPN is a service principal name of a service running on a host, in the Kerberos form service/host@REALM or the
+// GSS-API host based service form service@host.
+//
+// The host may be qualified with a port or, for services such as MSSQLSvc, an instance name in the form host:port,
+// for example HTTP/www.example.com:8443 or MSSQLSvc/db.example.com:1433.
+type SPN struct {
+	Service string
+	Host    string
+	Port    string
+	Realm   string
+}
+
+// ParseSPN parses a service principal name in the form <service>/<host> or <service>/<host>@<realm>, or the GSS-API
+// host based service form <service>@<host>, and validates its components. The host may be qualified as <host>:<port>.
+func ParseSPN(s string) (SPN, error) {
+	var spn SPN
+	orig := s
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		spn.Service, s = s[:i], s[i+1:]
+		if j := strings.LastIndexByte(s, '@'); j >= 0 {
+			s, spn.Realm = s[:j], s[j+1:]
+			if spn.Realm == "" {
+				return SPN{}, fmt.Errorf("invalid SPN %q: empty realm", orig)
+			}
+		}
+	} else if i := strings.IndexByte(s, '@'); i >= 0 {
+		spn.Service, s = s[:i], s[i+1:]
+	} else {
+		return SPN{}, fmt.Errorf("invalid SPN %q: no host component", orig)
+	}
+	var ok bool
+	spn.Host, spn.Port, ok = splitSPNHost(s)
+	if ok && spn.Port == "" {
+		return SPN{}, fmt.Errorf("invalid SPN %q: empty port", orig)
+	}
+	if err := spn.Validate(); err != nil {
+		return SPN{}, err
+	}
+	return spn, nil
+}
+
+// SPNFromPrincipalName returns the SPN of the PrincipalName and realm provided.
+// The PrincipalName must have a service and a host component.
+func SPNFromPrincipalName(pn PrincipalName, realm string) (SPN, error) {
+	if len(pn.NameString) != 2 {
+		return SPN{}, fmt.Errorf("invalid SPN %q: expected 2 name components, found %d", pn.PrincipalNameString(), len(pn.NameString))
+	}
+	spn := SPN{
+		Service: pn.NameString[0],
+		Realm:   realm,
+	}
+	spn.Host, spn.Port, _ = splitSPNHost(pn.NameString[1])
+	if err := spn.Validate(); err != nil {
+		return SPN{}, err
+	}
+	return spn, nil
+}
+
+// Validate checks that the service and host components are present and that no component contains characters that
+// would be ambiguous in the string form.
+func (s SPN) Validate() error {
+	if s.Service == "" {
+		return fmt.Errorf("invalid SPN %q: empty service", s.String())
+	}
+	if s.Host == "" {
+		return fmt.Errorf("invalid SPN %q: empty host", s.String())
+	}
+	for _, c := range []string{s.Service, s.Host, s.Realm} {
+		if i := strings.IndexFunc(c, invalidSPNRune); i >= 0 {
+			return fmt.Errorf("invalid SPN %q: invalid character %q", s.String(), c[i])
+		}
+	}
+	if i := strings.IndexFunc(s.Port, func(r rune) bool { return r == ':' || invalidSPNRune(r) }); i >= 0 {
+		return fmt.Errorf("invalid SPN %q: invalid character %q", s.String(), s.Port[i])
+	}
+	return nil
+}
+
+// splitSPNHost splits the host component of an SPN into the host and any port or instance name qualifying it.
+// A bracketed IPv6 address may be qualified, an unbracketed one is returned as the host. ok reports if the host
+// component was qualified, in which case the port may still be empty if the host ended with the separator.
+func splitSPNHost(h string) (host, port string, ok bool) {
+	if strings.HasPrefix(h, "[") {
+		if i := strings.Index(h, "]:"); i >= 0 {
+			return h[:i+1], h[i+2:], true
+		}
+		return h, "", false
+	}
+	if strings.Count(h, ":") != 1 {
+		return h, "", false
+	}
+	i := strings.IndexByte(h, ':')
+	return h[:i], h[i+1:], true
+}
+
+// invalidSPNRune reports if the rune is a separator or not printable and so cannot appear in an SPN component.
+func invalidSPNRune(r rune) bool {
+	return r == '/' || r == '@' || r == '\\' || unicode.IsSpace(r) || !unicode.IsPrint(r)
+}
+
+// String returns the SPN in the form <service>/<host>:<port>@<realm>. If the port or realm are not set the ":<port>"
+// and "@<realm>" parts are omitted.
+func (s SPN) String() string {
+	if s.Realm == "" {
+		return s.Service + "/" + s.hostPort()
+	}
+	return s.Service + "/" + s.hostPort() + "@" + s.Realm
+}
+
+// HostbasedString returns the SPN in the GSS-API host based service form <service>@<host>, or <service>@<host>:<port>
+// if the port is set.
+func (s SPN) HostbasedString() string {
+	return s.Service + "@" + s.hostPort()
+}
+
+// WithoutPort returns the SPN with any port or instance name removed.
+// This is the SPN commonly registered for a service on a well known port, and can be used as a fallback if a KDC does
+// not know the port qualified SPN.
+func (s SPN) WithoutPort() SPN {
+	s.Port = ""
+	return s
+}
+
+// hostPort returns the host component of the SPN qualified by the port if set.
+func (s SPN) hostPort() string {
+	if s.Port == "" {
+		return s.Host
+	}
+	return s.Host + ":" + s.Port
+}
+
+// PrincipalName returns the SPN as a PrincipalName with the name type KRB_NT_SRV_HST.
+func (s SPN) PrincipalName() PrincipalName {
+	return PrincipalName{
+		NameType:   nametype.KRB_NT_SRV_HST,
+		NameString: []string{s.Service, s.hostPort()},
+	}
+}
@@ -0,0 +1,84 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSPN(t *testing.T) {
+	t.Parallel()
+	var tests = []struct {
+		in   string
+		spn  SPN
+		str  string
+		hbsv string
+	}{
+		{"HTTP/www.example.com", SPN{Service: "HTTP", Host: "www.example.com"}, "HTTP/www.example.com", "HTTP@www.example.com"},
+		{"HTTP/www.example.com@EXAMPLE.COM", SPN{Service: "HTTP", Host: "www.example.com", Realm: "EXAMPLE.COM"}, "HTTP/www.example.com@EXAMPLE.COM", "HTTP@www.example.com"},
+		{"HTTP@www.example.com", SPN{Service: "HTTP", Host: "www.example.com"}, "HTTP/www.example.com", "HTTP@www.example.com"},
+		{"HTTP/www.example.com:8443@EXAMPLE.COM", SPN{Service: "HTTP", Host: "www.example.com", Port: "8443", Realm: "EXAMPLE.COM"}, "HTTP/www.example.com:8443@EXAMPLE.COM", "HTTP@www.example.com:8443"},
+		{"MSSQLSvc/db.example.com:SQLEXPRESS", SPN{Service: "MSSQLSvc", Host: "db.example.com", Port: "SQLEXPRESS"}, "MSSQLSvc/db.example.com:SQLEXPRESS", "MSSQLSvc@db.example.com:SQLEXPRESS"},
+		{"HTTP/[2001:db8::1]:8443", SPN{Service: "HTTP", Host: "[2001:db8::1]", Port: "8443"}, "HTTP/[2001:db8::1]:8443", "HTTP@[2001:db8::1]:8443"},
+		{"HTTP/2001:db8::1", SPN{Service: "HTTP", Host: "2001:db8::1"}, "HTTP/2001:db8::1", "HTTP@2001:db8::1"},
+	}
+	for _, test := range tests {
+		spn, err := ParseSPN(test.in)
+		if err != nil {
+			t.Errorf("error parsing %s: %v", test.in, err)
+			continue
+		}
+		assert.Equal(t, test.spn, spn, "SPN parsed from %s not as expected", test.in)
+		assert.Equal(t, test.str, spn.String(), "string form of %s not as expected", test.in)
+		assert.Equal(t, test.hbsv, spn.HostbasedString(), "host based form of %s not as expected", test.in)
+	}
+
+	for _, in := range []string{
+		"",
+		"HTTP",
+		"/www.example.com",
+		"HTTP/",
+		"HTTP/www.example.com@",
+		"@www.example.com",
+		"HTTP/www.example.com/extra",
+		"HTTP/www example.com",
+		"HTTP@www.example.com@EXAMPLE.COM",
+		"HTTP/www.example.com:",
+		"HTTP/[2001:db8::1]:",
+	} {
+		_, err := ParseSPN(in)
+		assert.Error(t, err, "parsing %q should fail", in)
+	}
+}
+
+func TestSPN_PrincipalName(t *testing.T) {
+	t.Parallel()
+	spn := SPN{Service: "HTTP", Host: "www.example.com", Realm: "EXAMPLE.COM"}
+	pn := spn.PrincipalName()
+	assert.Equal(t, nametype.KRB_NT_SRV_HST, pn.NameType, "name type not as expected")
+	assert.Equal(t, []string{"HTTP", "www.example.com"}, pn.NameString, "name string not as expected")
+
+	s, err := SPNFromPrincipalName(pn, "EXAMPLE.COM")
+	if err != nil {
+		t.Fatalf("error getting SPN from principal name: %v", err)
+	}
+	assert.Equal(t, spn, s, "SPN from principal name not as expected")
+
+	_, err = SPNFromPrincipalName(NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "user"), "EXAMPLE.COM")
+	assert.Error(t, err, "a principal name without a host should not be an SPN")
+}
+
+func TestSPN_Port(t *testing.T) {
+	t.Parallel()
+	spn := SPN{Service: "MSSQLSvc", Host: "db.example.com", Port: "1433", Realm: "EXAMPLE.COM"}
+	assert.Equal(t, []string{"MSSQLSvc", "db.example.com:1433"}, spn.PrincipalName().NameString, "name string not as expected")
+	s, err := SPNFromPrincipalName(spn.PrincipalName(), "EXAMPLE.COM")
+	require.NoError(t, err, "error getting SPN from principal name")
+	assert.Equal(t, spn, s, "SPN from principal name not as expected")
+	assert.Equal(t, "MSSQLSvc/db.example.com@EXAMPLE.COM", spn.WithoutPort().String(), "SPN without port not as expected")
+	assert.Equal(t, "1433", spn.Port, "WithoutPort should not modify the SPN")
+
+	assert.Error(t, SPN{Service: "HTTP", Host: "www.example.com", Port: "84:43"}.Validate(), "a port containing a separator should be invalid")
+}
@@ -1,6 +1,6 @@
 package types
 
-import "github.com/jcmturner/gofork/encoding/asn1"
+import "github.com/jcmturner/gokrb5/v8/asn1"
 
 // TypedData implements RFC 4120 type: https://tools.ietf.org/html/rfc4120#section-5.9.1
 type TypedData struct {